@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"subtitle-hunter/internal/handlers"
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/wanted"
+)
+
+// command is a one-shot subcommand run instead of the web server, for cron
+// jobs and CI. Commands share the server's configuration and data
+// directory, so their jobs show up in the same history.
+type command struct {
+	name  string
+	usage string
+	// run parses args with flags, which is set up to print the usage.
+	run func(ctx context.Context, h *handlers.Handler, flags *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"serve", "serve                  run the web server (the default)", nil},
+	{"scan", "scan                   list library items still missing a subtitle", scanCommand},
+	{"process", "process --item <id>    hunt subtitles for one item\nprocess --all          hunt subtitles for every item missing one", processCommand},
+	{"process-path", "process-path <path>    hunt subtitles for the videos at a path, without Jellyfin", processPathCommand},
+	{"history", "history [--limit N]    show the most recent jobs", historyCommand},
+}
+
+// errUsage reports invalid arguments; the flag package has already
+// explained what is wrong.
+var errUsage = errors.New("invalid arguments")
+
+// findCommand returns the subcommand called name.
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: subtitle-hunter [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		for _, line := range strings.Split(cmd.usage, "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+}
+
+// runCommand runs cmd and returns the process exit code: 0 on success,
+// 1 when the command failed and 2 for invalid arguments.
+func runCommand(ctx context.Context, h *handlers.Handler, cmd command, args []string) int {
+	err := cmd.run(ctx, h, newFlagSet(cmd), args)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(os.Stderr, "subtitle-hunter %s: %v\n", cmd.name, err)
+		return 1
+	}
+}
+
+func newFlagSet(cmd command) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage:\n  subtitle-hunter %s\n", strings.ReplaceAll(cmd.usage, "\n", "\n  subtitle-hunter "))
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// missingRows returns the wanted list rows of the items that still need a
+// subtitle, from a fresh library scan.
+func missingRows(ctx context.Context, h *handlers.Handler) ([]wanted.Row, error) {
+	items, err := h.Library.Rescan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch media: %w", err)
+	}
+	rows, err := h.Wanted.Compute(items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute subtitle status: %w", err)
+	}
+
+	var missing []wanted.Row
+	for _, row := range rows {
+		if row.Missing() {
+			missing = append(missing, row)
+		}
+	}
+	return missing, nil
+}
+
+func scanCommand(ctx context.Context, h *handlers.Handler, flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	rows, err := missingRows(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tITEM\tMISSING")
+	for _, row := range rows {
+		var targets []string
+		for _, cell := range row.Cells {
+			if cell.Status == wanted.StatusMissing {
+				targets = append(targets, cell.Target.String())
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", row.Item.ID, h.JellyfinClient.GetSearchQuery(row.Item), strings.Join(targets, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d item(s) missing subtitles\n", len(rows))
+	return nil
+}
+
+// processCommand hunts subtitles for one item, or for every item missing
+// one. --all leaves paused series alone like the scheduler does; a single
+// item is hunted regardless, like the "Find Subtitle" button. The jobs run
+// in the worker pool, so WORKER_POOL_SIZE bounds how many run at once.
+func processCommand(ctx context.Context, h *handlers.Handler, flags *flag.FlagSet, args []string) error {
+	itemID := flags.String("item", "", "Jellyfin ID of the item to process")
+	all := flags.Bool("all", false, "process every item missing a subtitle")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if (*itemID == "") == !*all || flags.NArg() > 0 {
+		fmt.Fprintln(flags.Output(), "Exactly one of --item or --all is required")
+		flags.Usage()
+		return errUsage
+	}
+
+	var items []*jellyfin.MediaItem
+	if *itemID != "" {
+		item, err := h.JellyfinClient.GetItem(ctx, *itemID)
+		if err != nil {
+			return fmt.Errorf("failed to get item details: %w", err)
+		}
+		items = append(items, item)
+	} else {
+		rows, err := missingRows(ctx, h)
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			item := &rows[i].Item
+			if h.HuntingPaused(item) {
+				fmt.Printf("skipped  %s: hunting is paused for this series\n", h.JellyfinClient.GetSearchQuery(*item))
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+
+	return huntItems(ctx, h, items, func(item *jellyfin.MediaItem) string {
+		return h.JellyfinClient.GetSearchQuery(*item)
+	})
+}
+
+// processPathCommand hunts subtitles for the videos at a path, reading
+// what they are from their file names instead of asking Jellyfin.
+func processPathCommand(ctx context.Context, h *handlers.Handler, flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(flags.Output(), "Exactly one path is required")
+		flags.Usage()
+		return errUsage
+	}
+
+	path, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	items, err := h.LocalItems(path)
+	if err != nil {
+		return err
+	}
+	return huntItems(ctx, h, items, func(item *jellyfin.MediaItem) string {
+		return item.Path
+	})
+}
+
+// huntItems hunts subtitles for items as CLI jobs in the worker pool,
+// printing a line for each as it finishes, and fails when any of them did.
+func huntItems(ctx context.Context, h *handlers.Handler, items []*jellyfin.MediaItem, nameOf func(*jellyfin.MediaItem) string) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failed   int
+		deferred int
+	)
+	for _, item := range items {
+		wg.Add(1)
+		go func(item *jellyfin.MediaItem) {
+			defer wg.Done()
+			jobID, result, err := h.Hunt(ctx, item, jobs.TriggerCLI)
+
+			mu.Lock()
+			defer mu.Unlock()
+			name := nameOf(item)
+			if jobID != "" {
+				name += " [" + jobID + "]"
+			}
+			if errors.Is(err, handlers.ErrBudgetExhausted) {
+				deferred++
+				fmt.Printf("deferred %s: %v\n", name, err)
+				return
+			}
+			if err != nil {
+				failed++
+				fmt.Printf("failed   %s: %v\n", name, err)
+				return
+			}
+			fmt.Printf("done     %s: %s\n", name, result.Message(h.Config().SubtitleDirectory))
+		}(item)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if deferred > 0 {
+		fmt.Printf("%d item(s) left for tomorrow, the daily budget is used up\n", deferred)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d item(s) failed", failed, len(items))
+	}
+	return nil
+}
+
+func historyCommand(ctx context.Context, h *handlers.Handler, flags *flag.FlagSet, args []string) error {
+	limit := flags.Int("limit", 20, "number of jobs to show")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *limit <= 0 {
+		fmt.Fprintln(flags.Output(), "--limit must be a positive number")
+		return errUsage
+	}
+
+	reports, err := h.Jobs.Recent(*limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tTRIGGER\tITEM\tTIME\tJOB\tRESULT")
+	for _, report := range reports {
+		result := "ok"
+		if report.Source != "" {
+			result += " (" + report.Source + ")"
+		}
+		if !report.Succeeded {
+			result = "failed: " + report.Error
+		}
+		wallTime := time.Duration(report.WallTimeMs) * time.Millisecond
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			report.StartedAt.Local().Format("2006-01-02 15:04"), report.Trigger, report.ItemName, wallTime, report.ID, result)
+	}
+	return w.Flush()
+}
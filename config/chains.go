@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"subtitle-hunter/internal/lang"
+)
+
+// Steps of a fallback chain. The convert and translate steps name the
+// language of the subtitle they start from, as in "translate-from-en".
+const (
+	StepEmbedded      = "embedded"
+	StepOpenSubtitles = "opensubtitles"
+	StepConvertFrom   = "convert-from-"
+	StepTranslateFrom = "translate-from-"
+	StepWhisper       = "whisper"
+)
+
+// unsupportedProviders are subtitle sites that are sometimes named in
+// chains but can't be downloaded from: OpenSubtitles is the only provider.
+var unsupportedProviders = map[string]string{
+	"subscene":  "Subscene",
+	"addic7ed":  "Addic7ed",
+	"podnapisi": "Podnapisi",
+	"subhd":     "SubHD",
+	"zimuku":    "Zimuku",
+}
+
+// ChainStep is one parsed step of a fallback chain. Source is the language
+// a convert or translate step starts from.
+type ChainStep struct {
+	Name   string
+	Kind   string
+	Source lang.Tag
+}
+
+// ParseStep reads one step of a fallback chain for subtitles in target.
+// Only Traditional Chinese can be converted or translated into, so the
+// chains of other languages can only download.
+func ParseStep(step string, target lang.Tag) (ChainStep, error) {
+	name := strings.ToLower(strings.TrimSpace(step))
+	parsed := ChainStep{Name: name, Kind: name}
+
+	switch {
+	case name == StepEmbedded, name == StepOpenSubtitles, name == StepWhisper:
+	case strings.HasPrefix(name, StepConvertFrom), strings.HasPrefix(name, StepTranslateFrom):
+		parsed.Kind = StepConvertFrom
+		source := strings.TrimPrefix(name, StepConvertFrom)
+		if strings.HasPrefix(name, StepTranslateFrom) {
+			parsed.Kind = StepTranslateFrom
+			source = strings.TrimPrefix(name, StepTranslateFrom)
+		}
+		parsed.Source = lang.Normalize(source)
+		if parsed.Source.IsZero() {
+			return parsed, fmt.Errorf("fallback step %q names no language", step)
+		}
+		if parsed.Source == target {
+			return parsed, fmt.Errorf("fallback step %q starts from the language it is for", step)
+		}
+		if parsed.Kind == StepConvertFrom && !parsed.Source.IsChinese() {
+			return parsed, fmt.Errorf("fallback step %q can only convert from Chinese; use %s%s", step, StepTranslateFrom, source)
+		}
+		// Keep the name canonical, so "convert-from-zh-CN" is recorded
+		// as the step it runs
+		parsed.Name = parsed.Kind + parsed.Source.String()
+	case unsupportedProviders[name] != "":
+		return parsed, fmt.Errorf("fallback step %q is not supported: subtitles can't be downloaded from %s, only from OpenSubtitles (%s)",
+			step, unsupportedProviders[name], StepOpenSubtitles)
+	default:
+		return parsed, fmt.Errorf("unknown fallback step %q (steps are %s, %s, %s{language}, %s{language} and %s)",
+			step, StepEmbedded, StepOpenSubtitles, StepConvertFrom, StepTranslateFrom, StepWhisper)
+	}
+
+	if parsed.Kind != StepOpenSubtitles && target != lang.TraditionalChinese {
+		return parsed, fmt.Errorf("fallback step %q only makes Traditional Chinese subtitles, so it can't be used for %s", step, target)
+	}
+	return parsed, nil
+}
+
+// FallbackChain returns the steps configured for subtitles in language, or
+// nil when its chain isn't configured.
+func (c *Config) FallbackChain(language lang.Tag) []ChainStep {
+	steps := c.FallbackChains[language.Normalize().String()]
+	if len(steps) == 0 {
+		return nil
+	}
+	chain := make([]ChainStep, 0, len(steps))
+	for _, step := range steps {
+		// Checked when the configuration was loaded
+		if parsed, err := ParseStep(step, language.Normalize()); err == nil {
+			chain = append(chain, parsed)
+		}
+	}
+	return chain
+}
+
+// loadFallbackChains reads FALLBACK_CHAINS, a semicolon-separated list of
+// language: step > step > ... chains, such as
+// "zh-TW: opensubtitles > convert-from-zh-CN > translate-from-en". Steps may
+// also be separated with "->", "→" or commas.
+func loadFallbackChains() (map[string][]string, error) {
+	entries := getListEnv("FALLBACK_CHAINS", ";", nil)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	separators := strings.NewReplacer("->", ",", "→", ",", ">", ",")
+	chains := make(map[string][]string)
+	for _, entry := range entries {
+		language, steps, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid FALLBACK_CHAINS entry %q: want language: step > step", entry)
+		}
+		language = strings.TrimSpace(language)
+		if _, ok := chains[language]; ok {
+			return nil, fmt.Errorf("invalid FALLBACK_CHAINS: the chain for %s is set twice", language)
+		}
+		chains[language] = []string{}
+		for _, step := range strings.Split(separators.Replace(steps), ",") {
+			if step = strings.TrimSpace(step); step != "" {
+				chains[language] = append(chains[language], step)
+			}
+		}
+	}
+	return chains, nil
+}
+
+// validateChains checks every step of the fallback chains and keys them by
+// their languages' canonical tags, so "zh-TW" and "zh-Hant" are one chain.
+func (c *Config) validateChains() error {
+	chains := make(map[string][]string, len(c.FallbackChains))
+	for language, steps := range c.FallbackChains {
+		target := lang.Normalize(language)
+		if target.IsZero() {
+			return fmt.Errorf("fallback chain set for unknown language %q", language)
+		}
+		if _, ok := chains[target.String()]; ok {
+			return fmt.Errorf("fallback chain for %s set twice", target)
+		}
+		if len(steps) == 0 {
+			return fmt.Errorf("fallback chain for %s has no steps", target)
+		}
+		for _, step := range steps {
+			if _, err := ParseStep(step, target); err != nil {
+				return fmt.Errorf("fallback chain for %s: %w", target, err)
+			}
+		}
+		chains[target.String()] = steps
+	}
+	c.FallbackChains = chains
+	return nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"subtitle-hunter/internal/lang"
+)
+
+func TestParseStep(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    string
+		target  lang.Tag
+		want    ChainStep
+		wantErr string
+	}{
+		{name: "download", step: " OpenSubtitles ", target: lang.TraditionalChinese, want: ChainStep{Name: "opensubtitles", Kind: StepOpenSubtitles}},
+		{name: "download for another language", step: "opensubtitles", target: lang.English, want: ChainStep{Name: "opensubtitles", Kind: StepOpenSubtitles}},
+		{
+			name:   "convert",
+			step:   "convert-from-zh-CN",
+			target: lang.TraditionalChinese,
+			want:   ChainStep{Name: "convert-from-zh-Hans", Kind: StepConvertFrom, Source: lang.SimplifiedChinese},
+		},
+		{
+			name:   "translate",
+			step:   "translate-from-en",
+			target: lang.TraditionalChinese,
+			want:   ChainStep{Name: "translate-from-en", Kind: StepTranslateFrom, Source: lang.English},
+		},
+		{name: "subscene", step: "subscene", target: lang.TraditionalChinese, wantErr: "not supported: subtitles can't be downloaded from Subscene"},
+		{name: "other site", step: "Zimuku", target: lang.TraditionalChinese, wantErr: "can't be downloaded from Zimuku"},
+		{name: "unknown", step: "somewhere", target: lang.TraditionalChinese, wantErr: "unknown fallback step"},
+		{name: "convert from a language that isn't Chinese", step: "convert-from-en", target: lang.TraditionalChinese, wantErr: "can only convert from Chinese"},
+		{name: "from the target itself", step: "translate-from-zh-TW", target: lang.TraditionalChinese, wantErr: "starts from the language it is for"},
+		{name: "no language", step: "translate-from-", target: lang.TraditionalChinese, wantErr: "names no language"},
+		{name: "translate into another language", step: "translate-from-en", target: lang.Tag{Language: "ja"}, wantErr: "only makes Traditional Chinese"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStep(tt.step, tt.target)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseStep(%q) error = %v, want one containing %q", tt.step, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStep(%q): %v", tt.step, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStep(%q) = %+v, want %+v", tt.step, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateChains(t *testing.T) {
+	tests := []struct {
+		name    string
+		chains  map[string][]string
+		want    map[string][]string
+		wantErr string
+	}{
+		{
+			name:   "keyed by canonical tag",
+			chains: map[string][]string{"zh-TW": {"opensubtitles", "translate-from-en"}, "eng": {"opensubtitles"}},
+			want:   map[string][]string{"zh-Hant": {"opensubtitles", "translate-from-en"}, "en": {"opensubtitles"}},
+		},
+		{
+			name:    "subscene",
+			chains:  map[string][]string{"zh-TW": {"opensubtitles", "subscene", "convert-from-zh-CN"}},
+			wantErr: `fallback chain for zh-Hant: fallback step "subscene" is not supported`,
+		},
+		{
+			name:    "same language twice",
+			chains:  map[string][]string{"zh-TW": {"opensubtitles"}, "zh-Hant": {"opensubtitles"}},
+			wantErr: "set twice",
+		},
+		{
+			name:    "no steps",
+			chains:  map[string][]string{"zh-TW": {}},
+			wantErr: "has no steps",
+		},
+		{
+			name:    "undetermined language",
+			chains:  map[string][]string{"und": {"opensubtitles"}},
+			wantErr: "unknown language",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{FallbackChains: tt.chains}
+			err := c.validateChains()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validateChains() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.FallbackChains, tt.want) {
+				t.Errorf("validateChains() left %v, want %v", c.FallbackChains, tt.want)
+			}
+		})
+	}
+}
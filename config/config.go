@@ -1,28 +1,265 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// OpenSubtitlesInstance is one set of OpenSubtitles credentials. Several
+// instances can be configured to combine their daily download quotas.
+type OpenSubtitlesInstance struct {
+	Name     string `json:"name" yaml:"name"`
+	APIKey   string `json:"api_key" yaml:"api_key"`
+	Username string `json:"username" yaml:"username,omitempty"`
+	Password string `json:"password" yaml:"password,omitempty"`
+	Priority int    `json:"priority" yaml:"priority"`
+}
+
+// PathMapping translates a media path as Jellyfin sees it to the same path
+// inside this container.
+type PathMapping struct {
+	Jellyfin  string `json:"jellyfin" yaml:"jellyfin"`
+	Container string `json:"container" yaml:"container"`
+}
+
 type Config struct {
-	JellyfinURL         string
-	JellyfinAPIKey      string
-	JellyfinUserID      string
-	OpenSubtitlesKey    string
-	Port                int
-	SubtitleDirectory   string
-	EnableDirectSave    bool
-	JellyfinPathPrefix  string
-	ContainerPathPrefix string
-}
-
-func Load() *Config {
+	ConfigFile               string
+	JellyfinURL              string
+	JellyfinAPIKey           string
+	JellyfinUserID           string
+	JellyfinUsername         string
+	JellyfinPassword         string
+	OpenSubtitlesKey         string
+	OpenSubtitlesInstances   []OpenSubtitlesInstance
+	Port                     int
+	SubtitleDirectory        string
+	DataDirectory            string
+	GlossaryFile             string
+	NormalizationFile        string
+	TempDirectory            string
+	EnableDirectSave         bool
+	PathMappings             []PathMapping
+	TargetLanguages          []string
+	EnableCleaning           bool
+	CleanPatterns            []string
+	TranslationFallback      string
+	FallbackMarker           string
+	MaxFailurePercent        float64
+	TranslationContextCues   int
+	TranslationCharBudget    int
+	HearingImpaired          string
+	StripSDH                 bool
+	MinCueRatio              float64
+	EnableEmbeddedExtraction bool
+	EmbeddedSourceLanguages  []string
+	FFmpegPath               string
+	SearchCacheTTL           time.Duration
+	AutoHuntInterval         time.Duration
+	AutoHuntWindowDays       int
+	EnableWhisper            bool
+	WhisperURL               string
+	WhisperModel             string
+	WhisperLanguage          string
+	OutputBOM                bool
+	OutputLineEndings        string
+	// OutputProfiles override the BOM and line endings for subtitles of
+	// one language, keyed by the tag used in file names (e.g. "zh-Hant").
+	OutputProfiles map[string]OutputProfile
+	// AutoHuntFullScanInterval is how often auto-hunt re-reads the whole
+	// library; in between it only checks items Jellyfin saved since the
+	// last scan. Zero makes every scan a full one.
+	AutoHuntFullScanInterval time.Duration
+	// AutoHuntReplaced has auto-hunt fetch the subtitles of items whose
+	// video file was replaced since they were saved again, whether or not
+	// the item still lists a Chinese subtitle.
+	AutoHuntReplaced bool
+	// ForcedLanguages are the languages whose forced subtitles (covering
+	// only foreign-language dialogue) are hunted as well.
+	ForcedLanguages []string
+	// TitleAliases maps a series name to other titles it may be listed
+	// under, e.g. its romanized Japanese title. Only the config file sets it.
+	TitleAliases map[string][]string
+	// HTTPRateLimits caps requests per second to external API hosts,
+	// keyed by host name.
+	HTTPRateLimits map[string]float64
+	// HTTPMaxRetries is how often a request failing with 429, a 5xx status
+	// or a network error is retried.
+	HTTPMaxRetries int
+	// CircuitBreakerThreshold consecutive failed requests to a host stop
+	// requests to it for CircuitBreakerCooldown.
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+	// CanaryBackend names a translation backend being tried out; it
+	// translates CanaryPercent percent of the cues of every job.
+	CanaryBackend string
+	CanaryPercent float64
+	// StageTimeouts bound each stage of a subtitle job (search, download,
+	// extract, transcribe, translate, refresh), keyed by stage name. Zero
+	// means no limit.
+	StageTimeouts map[string]time.Duration
+	// WorkerPoolSize is how many items are processed at once, across
+	// manual, search and scheduled jobs.
+	WorkerPoolSize int
+	// LibraryCacheTTL is how long the library listing shown by the web
+	// interface is kept before it is fetched from Jellyfin again. Zero
+	// fetches it on every page load.
+	LibraryCacheTTL time.Duration
+	// ThemeDirectory holds templates/ and static/ files that replace the
+	// built-in web interface files of the same name.
+	ThemeDirectory string
+	// SafeMode keeps direct saves out of media directories whose library
+	// root hasn't been approved on the settings page; subtitles for them go
+	// to the downloads directory instead.
+	SafeMode bool
+	// InterfaceLanguage is the language of the web interface, such as
+	// "zh-Hant". Empty follows the browser's Accept-Language.
+	InterfaceLanguage string
+	// BilingualSubtitles writes translated subtitles with the original text
+	// above the translation in each cue. Hunts and candidate downloads can
+	// override it per request.
+	BilingualSubtitles bool
+	// MaxLineChars and MaxLines limit the text of translated cues: longer
+	// lines are wrapped and cues needing more lines are split in two or
+	// more. Zero turns either limit off.
+	MaxLineChars int
+	MaxLines     int
+	// NormalizeCues drops duplicate and zero-length cues, puts cues in time
+	// order and renumbers them before a subtitle is saved.
+	NormalizeCues bool
+	// ScoringWeights override the weights that rank search results.
+	// LanguageScoringWeights and ProviderScoringWeights override them in
+	// turn for one language or one OpenSubtitles instance. They can only be
+	// set in the config file.
+	ScoringWeights         ScoringWeights
+	LanguageScoringWeights map[string]ScoringWeights
+	ProviderScoringWeights map[string]ScoringWeights
+	// DailyDownloadBudget and DailyTranslationBudget cap how many subtitles
+	// scheduled and command-line hunts download and translate per day, to
+	// spare the provider and translation quotas during big backfills. Zero
+	// means no cap.
+	DailyDownloadBudget    int
+	DailyTranslationBudget int
+	// SearchStoreTTL is how long search results, including searches that
+	// found nothing, are kept in the store and reused by automatic hunts.
+	// Zero keeps them in memory only, for SearchCacheTTL.
+	SearchStoreTTL time.Duration
+	// BasePath is the path the web interface is served under, such as
+	// "/subhunter" behind a reverse proxy. It starts with a slash and has
+	// none at the end; empty serves from the root.
+	BasePath string
+	// TLSCertFile and TLSKeyFile serve the web interface over HTTPS when
+	// both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// AdminPassword, when set, limits visitors who haven't logged in to
+	// browsing the wanted list and requesting subtitles; everything else
+	// takes logging in as the admin. Empty leaves the interface open.
+	AdminPassword string
+	// GRPCPort serves the gRPC API next to the web interface, with the same
+	// TLS certificate and admin password. Zero turns it off.
+	GRPCPort int
+	// DownloadsMoveInterval is how often subtitles that fell back to the
+	// downloads directory are tried again next to their videos, when
+	// direct saves are on. Zero turns the retries off.
+	DownloadsMoveInterval time.Duration
+	// SubtitleFileMode is the permission bits written subtitles get.
+	// SubtitleUID and SubtitleGID, unless -1, are the owner and group they
+	// are given, so that a Jellyfin running as another user can read them.
+	SubtitleFileMode os.FileMode
+	SubtitleUID      int
+	SubtitleGID      int
+	// SubtitleBackups keeps a timestamped ".bak" copy of a subtitle before
+	// it is overwritten with different content.
+	SubtitleBackups bool
+	// PreferredVersion picks, for items with several versions (media
+	// sources), the one whose name or file name contains it to get
+	// subtitles. Empty saves them next to every version.
+	PreferredVersion string
+	// PartialFailurePercent marks a translated subtitle as partial when
+	// more than this percentage of its cues kept the fallback text.
+	PartialFailurePercent float64
+	// DatabaseURL names the database the store is kept in; empty keeps it
+	// in a SQLite database in the data directory.
+	DatabaseURL string
+	// FallbackChains lists, by target language, the steps tried in order
+	// to get a subtitle in it (see ParseStep). Languages without one use
+	// the built-in chain.
+	FallbackChains map[string][]string
+	// TranslationMode is TranslationAuto, TranslationConfirm or
+	// TranslationOff. A series' settings and a request's own choice come
+	// before it.
+	TranslationMode string
+	// ApprovalTimeout is how long a job waits for its translation to be
+	// approved in TranslationConfirm mode before it gives up on it. Zero
+	// waits until the job is cancelled.
+	ApprovalTimeout time.Duration
+	// AITranslated and MachineTranslated say how OpenSubtitles results
+	// flagged as AI-translated or machine-translated are treated:
+	// "include", "avoid" (ranked below subtitles made by people) or
+	// "exclude".
+	AITranslated      string
+	MachineTranslated string
+	// TranslatorChain names the translator backends tried for each cue, in
+	// order (TranslatorGoogle, TranslatorDeepL). A backend that fails
+	// several times in a row or runs out of quota is skipped for
+	// TranslatorCooldown.
+	TranslatorChain    []string
+	TranslatorCooldown time.Duration
+	DeepLAPIKey        string
+	// PreserveASSStyles translates subtitles that come as ASS or SSA
+	// scripts in place and saves them as ASS, keeping their styles and
+	// override tags, instead of converting them to SRT first.
+	PreserveASSStyles bool
+	// HTTPTimeout bounds how long a request to an external API waits for a
+	// connection and for the response to start. Zero means no limit.
+	HTTPTimeout time.Duration
+	// ProxyURL sends requests to external APIs through an HTTP, HTTPS or
+	// SOCKS5 proxy, except those to the hosts in ProxyBypass, Jellyfin and
+	// the whisper server. Empty uses the HTTPS_PROXY and NO_PROXY
+	// variables, as before.
+	ProxyURL    string
+	ProxyBypass []string
+	// HTTPCAFile is a PEM file of extra certificate authorities to trust,
+	// for providers behind a proxy or server with a private CA.
+	HTTPCAFile string
+	// WatchDirectories are watched for new video files, such as those a
+	// torrent client finishes, which are hunted by path before Jellyfin
+	// has scanned them. WatchSettleDelay is how long a file must go
+	// unchanged before it counts as finished.
+	WatchDirectories []string
+	WatchSettleDelay time.Duration
+}
+
+// defaultRateLimits keep within the providers' published limits
+// (OpenSubtitles allows 5 requests per second).
+var defaultRateLimits = map[string]float64{
+	"api.opensubtitles.com":    5,
+	"translate.googleapis.com": 10,
+}
+
+// defaultStageTimeouts leave room for slow providers and long episodes while
+// still ending jobs stuck on a hung connection.
+var defaultStageTimeouts = map[string]time.Duration{
+	"lookup":     time.Minute,
+	"search":     2 * time.Minute,
+	"download":   2 * time.Minute,
+	"extract":    10 * time.Minute,
+	"transcribe": time.Hour,
+	"translate":  30 * time.Minute,
+	"refresh":    time.Minute,
+}
+
+// Load reads the configuration from the environment (and .env) and then
+// applies the YAML config file on top, if it exists.
+func Load() (*Config, error) {
 	godotenv.Load()
-	
+
 	port := 8080
 	if p := os.Getenv("PORT"); p != "" {
 		if parsed, err := strconv.Atoi(p); err == nil {
@@ -30,17 +267,283 @@ func Load() *Config {
 		}
 	}
 
-	return &Config{
-		JellyfinURL:         getEnv("JELLYFIN_URL", "http://localhost:8096"),
-		JellyfinAPIKey:      getEnv("JELLYFIN_API_KEY", ""),
-		JellyfinUserID:      getEnv("JELLYFIN_USER_ID", ""),
-		OpenSubtitlesKey:    getEnv("OPENSUBTITLES_API_KEY", ""),
-		SubtitleDirectory:   getEnv("SUBTITLE_DIRECTORY", "./downloads"),
-		EnableDirectSave:    getBoolEnv("ENABLE_DIRECT_SAVE", true),
-		JellyfinPathPrefix:  getEnv("JELLYFIN_PATH_PREFIX", ""),
-		ContainerPathPrefix: getEnv("CONTAINER_PATH_PREFIX", ""),
-		Port:                port,
+	dataDirectory := getEnv("DATA_DIRECTORY", "./data")
+
+	cfg := &Config{
+		ConfigFile:               getEnv("CONFIG_FILE", filepath.Join(dataDirectory, "config.yaml")),
+		JellyfinURL:              getEnv("JELLYFIN_URL", "http://localhost:8096"),
+		JellyfinAPIKey:           getEnv("JELLYFIN_API_KEY", ""),
+		JellyfinUserID:           getEnv("JELLYFIN_USER_ID", ""),
+		JellyfinUsername:         getEnv("JELLYFIN_USERNAME", ""),
+		JellyfinPassword:         getEnv("JELLYFIN_PASSWORD", ""),
+		OpenSubtitlesKey:         getEnv("OPENSUBTITLES_API_KEY", ""),
+		OpenSubtitlesInstances:   loadOpenSubtitlesInstances(),
+		SubtitleDirectory:        getEnv("SUBTITLE_DIRECTORY", "./downloads"),
+		DataDirectory:            dataDirectory,
+		GlossaryFile:             getEnv("GLOSSARY_FILE", filepath.Join(dataDirectory, "glossary.yaml")),
+		NormalizationFile:        getEnv("NORMALIZATION_FILE", filepath.Join(dataDirectory, "normalization.yaml")),
+		TempDirectory:            getEnv("TEMP_DIRECTORY", filepath.Join(dataDirectory, "tmp")),
+		EnableDirectSave:         getBoolEnv("ENABLE_DIRECT_SAVE", true),
+		PathMappings:             loadPathMappings(),
+		TargetLanguages:          getListEnv("TARGET_LANGUAGES", ",", []string{"zh-Hant"}),
+		EnableCleaning:           getBoolEnv("ENABLE_SUBTITLE_CLEANING", true),
+		CleanPatterns:            getListEnv("SUBTITLE_CLEAN_PATTERNS", ";;", nil),
+		TranslationFallback:      getEnv("TRANSLATION_FALLBACK", "original"),
+		FallbackMarker:           getEnv("TRANSLATION_FALLBACK_MARKER", "[?]"),
+		MaxFailurePercent:        getFloatEnv("TRANSLATION_MAX_FAILURE_PERCENT", 100),
+		TranslationContextCues:   getIntEnv("TRANSLATION_CONTEXT_CUES", 0),
+		TranslationCharBudget:    getIntEnv("TRANSLATION_MONTHLY_CHAR_BUDGET", 0),
+		HearingImpaired:          getEnv("HEARING_IMPAIRED", "include"),
+		StripSDH:                 getBoolEnv("STRIP_SDH", false),
+		MinCueRatio:              getFloatEnv("SUBTITLE_MIN_CUE_RATIO", 0.9),
+		EnableEmbeddedExtraction: getBoolEnv("ENABLE_EMBEDDED_EXTRACTION", false),
+		EmbeddedSourceLanguages:  getListEnv("EMBEDDED_SOURCE_LANGUAGES", ",", []string{"zh-CN", "en"}),
+		FFmpegPath:               getEnv("FFMPEG_PATH", "ffmpeg"),
+		SearchCacheTTL:           getDurationEnv("SEARCH_CACHE_TTL", 5*time.Minute),
+		AutoHuntInterval:         getDurationEnv("AUTO_HUNT_INTERVAL", 0),
+		AutoHuntWindowDays:       getIntEnv("AUTO_HUNT_WINDOW_DAYS", 90),
+		AutoHuntFullScanInterval: getDurationEnv("AUTO_HUNT_FULL_SCAN_INTERVAL", 24*time.Hour),
+		AutoHuntReplaced:         getBoolEnv("AUTO_HUNT_REPLACED", false),
+		ForcedLanguages:          getListEnv("FORCED_LANGUAGES", ",", nil),
+		EnableWhisper:            getBoolEnv("ENABLE_WHISPER", false),
+		WhisperURL:               getEnv("WHISPER_URL", "http://localhost:8178/inference"),
+		WhisperModel:             getEnv("WHISPER_MODEL", ""),
+		WhisperLanguage:          getEnv("WHISPER_LANGUAGE", ""),
+		OutputBOM:                getBoolEnv("SUBTITLE_BOM", false),
+		OutputLineEndings:        strings.ToLower(getEnv("SUBTITLE_LINE_ENDINGS", LineEndingsLF)),
+		Port:                     port,
+		HTTPMaxRetries:           getIntEnv("HTTP_MAX_RETRIES", 3),
+		CircuitBreakerThreshold:  getIntEnv("CIRCUIT_BREAKER_THRESHOLD", 5),
+		CircuitBreakerCooldown:   getDurationEnv("CIRCUIT_BREAKER_COOLDOWN", time.Minute),
+		CanaryBackend:            getEnv("TRANSLATION_CANARY_BACKEND", ""),
+		CanaryPercent:            getFloatEnv("TRANSLATION_CANARY_PERCENT", 5),
+		WorkerPoolSize:           getIntEnv("WORKER_POOL_SIZE", 2),
+		LibraryCacheTTL:          getDurationEnv("LIBRARY_CACHE_TTL", 10*time.Minute),
+		ThemeDirectory:           getEnv("THEME_DIRECTORY", ""),
+		SafeMode:                 getBoolEnv("SAFE_MODE", true),
+		InterfaceLanguage:        getEnv("INTERFACE_LANGUAGE", ""),
+		BilingualSubtitles:       getBoolEnv("BILINGUAL_SUBTITLES", false),
+		MaxLineChars:             getIntEnv("SUBTITLE_MAX_LINE_CHARS", 20),
+		MaxLines:                 getIntEnv("SUBTITLE_MAX_LINES", 2),
+		NormalizeCues:            getBoolEnv("ENABLE_SUBTITLE_NORMALIZE", true),
+		DailyDownloadBudget:      getIntEnv("DAILY_DOWNLOAD_BUDGET", 0),
+		DailyTranslationBudget:   getIntEnv("DAILY_TRANSLATION_BUDGET", 0),
+		SearchStoreTTL:           getDurationEnv("SEARCH_STORE_TTL", 24*time.Hour),
+		BasePath:                 normalizeBasePath(getEnv("BASE_PATH", "")),
+		TLSCertFile:              getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:               getEnv("TLS_KEY_FILE", ""),
+		AdminPassword:            getEnv("ADMIN_PASSWORD", ""),
+		GRPCPort:                 getIntEnv("GRPC_PORT", 0),
+		DownloadsMoveInterval:    getDurationEnv("DOWNLOADS_MOVE_INTERVAL", time.Hour),
+		SubtitleUID:              getIntEnv("SUBTITLE_UID", -1),
+		SubtitleGID:              getIntEnv("SUBTITLE_GID", -1),
+		SubtitleBackups:          getBoolEnv("SUBTITLE_BACKUPS", false),
+		PreferredVersion:         getEnv("PREFERRED_VERSION", ""),
+		PartialFailurePercent:    getFloatEnv("TRANSLATION_PARTIAL_PERCENT", 0),
+		DatabaseURL:              getEnv("DATABASE_URL", ""),
+		TranslationMode:          getEnv("TRANSLATION_MODE", TranslationAuto),
+		ApprovalTimeout:          getDurationEnv("TRANSLATION_APPROVAL_TIMEOUT", 24*time.Hour),
+		AITranslated:             getEnv("AI_TRANSLATED", "include"),
+		MachineTranslated:        getEnv("MACHINE_TRANSLATED", "exclude"),
+		TranslatorChain:          getListEnv("TRANSLATOR_CHAIN", ",", []string{TranslatorGoogle}),
+		TranslatorCooldown:       getDurationEnv("TRANSLATOR_COOLDOWN", 10*time.Minute),
+		DeepLAPIKey:              getEnv("DEEPL_API_KEY", ""),
+		PreserveASSStyles:        getBoolEnv("PRESERVE_ASS_STYLES", false),
+		HTTPTimeout:              getDurationEnv("HTTP_TIMEOUT", 30*time.Second),
+		ProxyURL:                 getEnv("PROXY_URL", ""),
+		ProxyBypass:              getListEnv("PROXY_BYPASS", ",", nil),
+		HTTPCAFile:               getEnv("HTTP_CA_FILE", ""),
+		WatchDirectories:         getListEnv("WATCH_DIRECTORIES", ",", nil),
+		WatchSettleDelay:         getDurationEnv("WATCH_SETTLE_DELAY", 2*time.Minute),
+	}
+
+	rateLimits, err := loadRateLimits()
+	if err != nil {
+		return nil, err
+	}
+	cfg.HTTPRateLimits = rateLimits
+
+	stageTimeouts, err := loadStageTimeouts()
+	if err != nil {
+		return nil, err
+	}
+	cfg.StageTimeouts = stageTimeouts
+
+	fileMode, err := parseFileMode(getEnv("SUBTITLE_FILE_MODE", "0644"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SUBTITLE_FILE_MODE: %w", err)
+	}
+	cfg.SubtitleFileMode = fileMode
+
+	fallbackChains, err := loadFallbackChains()
+	if err != nil {
+		return nil, err
+	}
+	cfg.FallbackChains = fallbackChains
+
+	if err := cfg.applyFile(cfg.ConfigFile); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateOutput(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateScoring(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateChains(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateTranslation(); err != nil {
+		return nil, err
 	}
+	if err := cfg.validateHTTP(); err != nil {
+		return nil, err
+	}
+	for _, dir := range cfg.WatchDirectories {
+		if !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("watch directory %q must be an absolute path", dir)
+		}
+	}
+	if cfg.WatchSettleDelay < 0 {
+		return nil, fmt.Errorf("watch settle delay must not be negative, got %s", cfg.WatchSettleDelay)
+	}
+	if cfg.WorkerPoolSize < 1 {
+		return nil, fmt.Errorf("worker pool size must be at least 1, got %d", cfg.WorkerPoolSize)
+	}
+	if cfg.CanaryPercent < 0 || cfg.CanaryPercent > 100 {
+		return nil, fmt.Errorf("canary percent must be between 0 and 100, got %g", cfg.CanaryPercent)
+	}
+	if cfg.DailyDownloadBudget < 0 || cfg.DailyTranslationBudget < 0 {
+		return nil, fmt.Errorf("daily budgets must not be negative (downloads %d, translations %d)", cfg.DailyDownloadBudget, cfg.DailyTranslationBudget)
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.GRPCPort < 0 || cfg.GRPCPort > 65535 || (cfg.GRPCPort != 0 && cfg.GRPCPort == cfg.Port) {
+		return nil, fmt.Errorf("gRPC port must be 0 or a port other than the web interface's, got %d", cfg.GRPCPort)
+	}
+	if cfg.SubtitleUID < -1 || cfg.SubtitleGID < -1 {
+		return nil, fmt.Errorf("subtitle owner must be a user and group ID, or -1 to keep it (uid %d, gid %d)", cfg.SubtitleUID, cfg.SubtitleGID)
+	}
+	if cfg.PartialFailurePercent < 0 || cfg.PartialFailurePercent > 100 {
+		return nil, fmt.Errorf("partial translation percent must be between 0 and 100, got %g", cfg.PartialFailurePercent)
+	}
+
+	return cfg, nil
+}
+
+// normalizeBasePath turns "subhunter", "/subhunter/" and the like into
+// "/subhunter", and "/" into "".
+func normalizeBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
+
+// parseFileMode reads octal permission bits such as "0644" or "664".
+func parseFileMode(value string) (os.FileMode, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "0o")
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("%q is not octal permission bits such as 0644", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// loadPathMappings reads the single JELLYFIN_PATH_PREFIX/CONTAINER_PATH_PREFIX
+// pair. More mappings can be listed in the config file.
+func loadPathMappings() []PathMapping {
+	jellyfinPrefix := getEnv("JELLYFIN_PATH_PREFIX", "")
+	containerPrefix := getEnv("CONTAINER_PATH_PREFIX", "")
+	if jellyfinPrefix == "" || containerPrefix == "" {
+		return nil
+	}
+	return []PathMapping{{Jellyfin: jellyfinPrefix, Container: containerPrefix}}
+}
+
+// loadOpenSubtitlesInstances reads the primary OPENSUBTITLES_* credentials
+// plus numbered extras (OPENSUBTITLES_2_API_KEY, OPENSUBTITLES_2_USERNAME, ...).
+func loadOpenSubtitlesInstances() []OpenSubtitlesInstance {
+	var instances []OpenSubtitlesInstance
+
+	if apiKey := getEnv("OPENSUBTITLES_API_KEY", ""); apiKey != "" {
+		instances = append(instances, OpenSubtitlesInstance{
+			Name:     getEnv("OPENSUBTITLES_NAME", "default"),
+			APIKey:   apiKey,
+			Username: getEnv("OPENSUBTITLES_USERNAME", ""),
+			Password: getEnv("OPENSUBTITLES_PASSWORD", ""),
+			Priority: getIntEnv("OPENSUBTITLES_PRIORITY", 1),
+		})
+	}
+
+	for i := 2; i <= 9; i++ {
+		prefix := fmt.Sprintf("OPENSUBTITLES_%d_", i)
+		apiKey := getEnv(prefix+"API_KEY", "")
+		if apiKey == "" {
+			continue
+		}
+		instances = append(instances, OpenSubtitlesInstance{
+			Name:     getEnv(prefix+"NAME", fmt.Sprintf("instance-%d", i)),
+			APIKey:   apiKey,
+			Username: getEnv(prefix+"USERNAME", ""),
+			Password: getEnv(prefix+"PASSWORD", ""),
+			Priority: getIntEnv(prefix+"PRIORITY", i),
+		})
+	}
+
+	return instances
+}
+
+// loadRateLimits reads HTTP_RATE_LIMITS, a comma-separated list of
+// host=requests-per-second pairs. Unset, the default limits apply.
+func loadRateLimits() (map[string]float64, error) {
+	entries := getListEnv("HTTP_RATE_LIMITS", ",", nil)
+	if len(entries) == 0 {
+		return defaultRateLimits, nil
+	}
+
+	limits := make(map[string]float64)
+	for _, entry := range entries {
+		host, value, ok := strings.Cut(entry, "=")
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid HTTP_RATE_LIMITS entry %q: want host=requests-per-second", entry)
+		}
+		limits[strings.TrimSpace(host)] = rate
+	}
+	return limits, nil
+}
+
+// loadStageTimeouts reads STAGE_TIMEOUTS, a comma-separated list of
+// stage=duration pairs overriding the default timeouts of those stages.
+func loadStageTimeouts() (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration, len(defaultStageTimeouts))
+	for stage, timeout := range defaultStageTimeouts {
+		timeouts[stage] = timeout
+	}
+
+	for _, entry := range getListEnv("STAGE_TIMEOUTS", ",", nil) {
+		stage, value, ok := strings.Cut(entry, "=")
+		stage = strings.TrimSpace(stage)
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid STAGE_TIMEOUTS entry %q: want stage=duration", entry)
+		}
+		if err := checkStage(stage); err != nil {
+			return nil, err
+		}
+		timeouts[stage] = timeout
+	}
+	return timeouts, nil
+}
+
+func checkStage(stage string) error {
+	if _, ok := defaultStageTimeouts[stage]; !ok {
+		return fmt.Errorf("unknown job stage %q in timeouts", stage)
+	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -57,17 +560,64 @@ func getBoolEnv(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getListEnv splits a variable on sep, dropping empty entries. Regex lists
+// use ";;" since commas and pipes are meaningful inside patterns.
+func getListEnv(key, sep string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), sep) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+// MapJellyfinPathToContainer rewrites a Jellyfin path using the path mapping
+// with the longest matching prefix. Paths no mapping covers are returned as-is.
 func (c *Config) MapJellyfinPathToContainer(jellyfinPath string) string {
-	// If no path mapping is configured, return the original path
-	if c.JellyfinPathPrefix == "" || c.ContainerPathPrefix == "" {
-		return jellyfinPath
+	var best *PathMapping
+	for i, mapping := range c.PathMappings {
+		if mapping.Jellyfin == "" || !strings.HasPrefix(jellyfinPath, mapping.Jellyfin) {
+			continue
+		}
+		if best == nil || len(mapping.Jellyfin) > len(best.Jellyfin) {
+			best = &c.PathMappings[i]
+		}
 	}
-	
-	// Replace the Jellyfin path prefix with the container path prefix
-	if strings.HasPrefix(jellyfinPath, c.JellyfinPathPrefix) {
-		return strings.Replace(jellyfinPath, c.JellyfinPathPrefix, c.ContainerPathPrefix, 1)
+
+	if best == nil {
+		return jellyfinPath
 	}
-	
-	// If the path doesn't match the expected prefix, return as-is
-	return jellyfinPath
-}
\ No newline at end of file
+
+	return best.Container + strings.TrimPrefix(jellyfinPath, best.Jellyfin)
+}
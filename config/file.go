@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the layout of the YAML config file. Every section is
+// optional; values present in the file override the environment.
+type fileConfig struct {
+	Jellyfin struct {
+		URL      string `yaml:"url"`
+		APIKey   string `yaml:"api_key"`
+		UserID   string `yaml:"user_id"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"jellyfin"`
+	TargetLanguages []string      `yaml:"target_languages"`
+	ForcedLanguages []string      `yaml:"forced_languages"`
+	PathMappings    []PathMapping `yaml:"path_mappings"`
+	Providers       struct {
+		OpenSubtitles []OpenSubtitlesInstance `yaml:"opensubtitles"`
+	} `yaml:"providers"`
+	Scoring struct {
+		HearingImpaired   string                    `yaml:"hearing_impaired"`
+		AITranslated      string                    `yaml:"ai_translated"`
+		MachineTranslated string                    `yaml:"machine_translated"`
+		Weights           ScoringWeights            `yaml:"weights"`
+		Languages         map[string]ScoringWeights `yaml:"languages"`
+		Providers         map[string]ScoringWeights `yaml:"providers"`
+	} `yaml:"scoring"`
+	Search struct {
+		TitleAliases map[string][]string `yaml:"title_aliases"`
+		StoreTTL     *time.Duration      `yaml:"store_ttl"`
+	} `yaml:"search"`
+	Schedule struct {
+		AutoHuntInterval   *time.Duration `yaml:"auto_hunt_interval"`
+		AutoHuntWindowDays *int           `yaml:"auto_hunt_window_days"`
+		FullScanInterval   *time.Duration `yaml:"full_scan_interval"`
+		Replaced           *bool          `yaml:"replaced"`
+	} `yaml:"schedule"`
+	Translation struct {
+		Mode            *string        `yaml:"mode"`
+		ApprovalTimeout *time.Duration `yaml:"approval_timeout"`
+		Chain           []string       `yaml:"chain"`
+		Cooldown        *time.Duration `yaml:"cooldown"`
+		Canary          struct {
+			Backend *string  `yaml:"backend"`
+			Percent *float64 `yaml:"percent"`
+		} `yaml:"canary"`
+	} `yaml:"translation"`
+	Jobs struct {
+		Workers *int `yaml:"workers"`
+	} `yaml:"jobs"`
+	Budget struct {
+		DailyDownloads    *int `yaml:"daily_downloads"`
+		DailyTranslations *int `yaml:"daily_translations"`
+	} `yaml:"budget"`
+	Library struct {
+		CacheTTL *time.Duration `yaml:"cache_ttl"`
+	} `yaml:"library"`
+	// FallbackChains set the steps tried for a target language
+	FallbackChains map[string][]string `yaml:"fallback_chains"`
+	// Timeouts override the timeouts of single job stages
+	Timeouts map[string]time.Duration `yaml:"timeouts"`
+	Saving   struct {
+		DirectSave   *bool          `yaml:"direct_save"`
+		SafeMode     *bool          `yaml:"safe_mode"`
+		MoveInterval *time.Duration `yaml:"move_interval"`
+		// FileMode is octal, e.g. "0664"
+		FileMode *string `yaml:"file_mode"`
+		UID      *int    `yaml:"uid"`
+		GID      *int    `yaml:"gid"`
+		Backups  *bool   `yaml:"backups"`
+		// PreferredVersion limits items with several versions to one
+		PreferredVersion *string `yaml:"preferred_version"`
+	} `yaml:"saving"`
+	Interface struct {
+		Language *string `yaml:"language"`
+	} `yaml:"interface"`
+	Output struct {
+		BOM          *bool                    `yaml:"bom"`
+		LineEndings  string                   `yaml:"line_endings"`
+		Languages    map[string]OutputProfile `yaml:"languages"`
+		Bilingual    *bool                    `yaml:"bilingual"`
+		MaxLineChars *int                     `yaml:"max_line_chars"`
+		MaxLines     *int                     `yaml:"max_lines"`
+		// PreserveASS keeps ASS scripts as ASS when translating them
+		PreserveASS *bool `yaml:"preserve_ass_styles"`
+	} `yaml:"output"`
+}
+
+// applyFile overlays the YAML config file at path. A missing file is not an
+// error, so the file stays optional.
+func (c *Config) applyFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file fileConfig
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if file.Jellyfin.URL != "" {
+		c.JellyfinURL = file.Jellyfin.URL
+	}
+	if file.Jellyfin.APIKey != "" {
+		c.JellyfinAPIKey = file.Jellyfin.APIKey
+	}
+	if file.Jellyfin.UserID != "" {
+		c.JellyfinUserID = file.Jellyfin.UserID
+	}
+	if file.Jellyfin.Username != "" {
+		c.JellyfinUsername = file.Jellyfin.Username
+		c.JellyfinPassword = file.Jellyfin.Password
+	}
+
+	if len(file.TargetLanguages) > 0 {
+		c.TargetLanguages = file.TargetLanguages
+	}
+	// An empty list turns off forced subtitles set in the environment
+	if file.ForcedLanguages != nil {
+		c.ForcedLanguages = file.ForcedLanguages
+	}
+
+	if len(file.PathMappings) > 0 {
+		c.PathMappings = nil
+		for _, mapping := range file.PathMappings {
+			if mapping.Jellyfin == "" || mapping.Container == "" {
+				return fmt.Errorf("config file %s: path mappings need both jellyfin and container paths", path)
+			}
+			c.PathMappings = append(c.PathMappings, mapping)
+		}
+	}
+
+	if len(file.Providers.OpenSubtitles) > 0 {
+		c.OpenSubtitlesInstances = nil
+		for i, provider := range file.Providers.OpenSubtitles {
+			if provider.APIKey == "" {
+				return fmt.Errorf("config file %s: opensubtitles provider %d has no api_key", path, i+1)
+			}
+			instance := provider
+			if instance.Name == "" {
+				instance.Name = fmt.Sprintf("instance-%d", i+1)
+			}
+			if instance.Priority == 0 {
+				instance.Priority = i + 1
+			}
+			c.OpenSubtitlesInstances = append(c.OpenSubtitlesInstances, instance)
+		}
+		c.OpenSubtitlesKey = c.OpenSubtitlesInstances[0].APIKey
+	}
+
+	if file.Scoring.HearingImpaired != "" {
+		c.HearingImpaired = file.Scoring.HearingImpaired
+	}
+	if file.Scoring.AITranslated != "" {
+		c.AITranslated = file.Scoring.AITranslated
+	}
+	if file.Scoring.MachineTranslated != "" {
+		c.MachineTranslated = file.Scoring.MachineTranslated
+	}
+	c.ScoringWeights = c.ScoringWeights.overlay(file.Scoring.Weights)
+	if file.Scoring.Languages != nil {
+		c.LanguageScoringWeights = file.Scoring.Languages
+	}
+	if file.Scoring.Providers != nil {
+		c.ProviderScoringWeights = file.Scoring.Providers
+	}
+
+	if len(file.Search.TitleAliases) > 0 {
+		c.TitleAliases = file.Search.TitleAliases
+	}
+	if file.Search.StoreTTL != nil {
+		c.SearchStoreTTL = *file.Search.StoreTTL
+	}
+
+	if file.Schedule.AutoHuntInterval != nil {
+		c.AutoHuntInterval = *file.Schedule.AutoHuntInterval
+	}
+	if file.Schedule.AutoHuntWindowDays != nil {
+		c.AutoHuntWindowDays = *file.Schedule.AutoHuntWindowDays
+	}
+	if file.Schedule.FullScanInterval != nil {
+		c.AutoHuntFullScanInterval = *file.Schedule.FullScanInterval
+	}
+	if file.Schedule.Replaced != nil {
+		c.AutoHuntReplaced = *file.Schedule.Replaced
+	}
+
+	// An empty backend ends a canary started in the environment
+	if file.Translation.Mode != nil {
+		c.TranslationMode = *file.Translation.Mode
+	}
+	if file.Translation.ApprovalTimeout != nil {
+		c.ApprovalTimeout = *file.Translation.ApprovalTimeout
+	}
+	if file.Translation.Chain != nil {
+		c.TranslatorChain = file.Translation.Chain
+	}
+	if file.Translation.Cooldown != nil {
+		c.TranslatorCooldown = *file.Translation.Cooldown
+	}
+	if file.Translation.Canary.Backend != nil {
+		c.CanaryBackend = *file.Translation.Canary.Backend
+	}
+	if file.Translation.Canary.Percent != nil {
+		c.CanaryPercent = *file.Translation.Canary.Percent
+	}
+
+	if file.Jobs.Workers != nil {
+		c.WorkerPoolSize = *file.Jobs.Workers
+	}
+
+	if file.Budget.DailyDownloads != nil {
+		c.DailyDownloadBudget = *file.Budget.DailyDownloads
+	}
+	if file.Budget.DailyTranslations != nil {
+		c.DailyTranslationBudget = *file.Budget.DailyTranslations
+	}
+
+	if file.Library.CacheTTL != nil {
+		c.LibraryCacheTTL = *file.Library.CacheTTL
+	}
+
+	if len(file.FallbackChains) > 0 {
+		c.FallbackChains = file.FallbackChains
+	}
+
+	for stage, timeout := range file.Timeouts {
+		if err := checkStage(stage); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		c.StageTimeouts[stage] = timeout
+	}
+
+	if file.Saving.DirectSave != nil {
+		c.EnableDirectSave = *file.Saving.DirectSave
+	}
+	if file.Saving.SafeMode != nil {
+		c.SafeMode = *file.Saving.SafeMode
+	}
+	if file.Saving.MoveInterval != nil {
+		c.DownloadsMoveInterval = *file.Saving.MoveInterval
+	}
+	if file.Saving.FileMode != nil {
+		mode, err := parseFileMode(*file.Saving.FileMode)
+		if err != nil {
+			return fmt.Errorf("config file %s: invalid file_mode: %w", path, err)
+		}
+		c.SubtitleFileMode = mode
+	}
+	if file.Saving.UID != nil {
+		c.SubtitleUID = *file.Saving.UID
+	}
+	if file.Saving.GID != nil {
+		c.SubtitleGID = *file.Saving.GID
+	}
+	if file.Saving.Backups != nil {
+		c.SubtitleBackups = *file.Saving.Backups
+	}
+	if file.Saving.PreferredVersion != nil {
+		c.PreferredVersion = *file.Saving.PreferredVersion
+	}
+
+	if file.Interface.Language != nil {
+		c.InterfaceLanguage = *file.Interface.Language
+	}
+
+	if file.Output.BOM != nil {
+		c.OutputBOM = *file.Output.BOM
+	}
+	if file.Output.LineEndings != "" {
+		c.OutputLineEndings = file.Output.LineEndings
+	}
+	if len(file.Output.Languages) > 0 {
+		c.OutputProfiles = file.Output.Languages
+	}
+	if file.Output.Bilingual != nil {
+		c.BilingualSubtitles = *file.Output.Bilingual
+	}
+	if file.Output.MaxLineChars != nil {
+		c.MaxLineChars = *file.Output.MaxLineChars
+	}
+	if file.Output.MaxLines != nil {
+		c.MaxLines = *file.Output.MaxLines
+	}
+	if file.Output.PreserveASS != nil {
+		c.PreserveASSStyles = *file.Output.PreserveASS
+	}
+
+	return nil
+}
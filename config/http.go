@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// proxySchemes are the proxy URL schemes the HTTP transport understands.
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks5": true}
+
+// validateHTTP checks the request timeout and the proxy URL.
+func (c *Config) validateHTTP() error {
+	if c.HTTPTimeout < 0 {
+		return fmt.Errorf("HTTP timeout must not be negative, got %s", c.HTTPTimeout)
+	}
+	if c.ProxyURL == "" {
+		return nil
+	}
+	proxy, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid PROXY_URL: %w", err)
+	}
+	if !proxySchemes[proxy.Scheme] || proxy.Host == "" {
+		return fmt.Errorf("PROXY_URL must be an http://, https:// or socks5:// URL with a host, got %q", proxy.Redacted())
+	}
+	return nil
+}
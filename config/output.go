@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line ending styles for written subtitle files.
+const (
+	LineEndingsLF   = "lf"
+	LineEndingsCRLF = "crlf"
+)
+
+// OutputProfile overrides the output style for subtitles of one language.
+// Unset fields fall back to the global SUBTITLE_BOM/SUBTITLE_LINE_ENDINGS.
+type OutputProfile struct {
+	BOM         *bool  `yaml:"bom"`
+	LineEndings string `yaml:"line_endings"`
+}
+
+// OutputFor returns whether subtitles in language are written with a UTF-8
+// byte order mark and with CRLF line endings.
+func (c *Config) OutputFor(language string) (bom bool, crlf bool) {
+	bom, lineEndings := c.OutputBOM, c.OutputLineEndings
+	for key, profile := range c.OutputProfiles {
+		if !strings.EqualFold(key, language) {
+			continue
+		}
+		if profile.BOM != nil {
+			bom = *profile.BOM
+		}
+		if profile.LineEndings != "" {
+			lineEndings = profile.LineEndings
+		}
+	}
+	return bom, strings.EqualFold(lineEndings, LineEndingsCRLF)
+}
+
+func (c *Config) validateOutput() error {
+	if err := checkLineEndings(c.OutputLineEndings); err != nil {
+		return err
+	}
+	if c.MaxLineChars < 0 || c.MaxLines < 0 {
+		return fmt.Errorf("line limits must not be negative (max line chars %d, max lines %d)", c.MaxLineChars, c.MaxLines)
+	}
+	for language, profile := range c.OutputProfiles {
+		if profile.LineEndings == "" {
+			continue
+		}
+		if err := checkLineEndings(profile.LineEndings); err != nil {
+			return fmt.Errorf("output profile %s: %w", language, err)
+		}
+	}
+	return nil
+}
+
+func checkLineEndings(value string) error {
+	switch strings.ToLower(value) {
+	case LineEndingsLF, LineEndingsCRLF:
+		return nil
+	}
+	return fmt.Errorf("invalid line endings %q (use %q or %q)", value, LineEndingsLF, LineEndingsCRLF)
+}
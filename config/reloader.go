@@ -0,0 +1,108 @@
+package config
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Reloader holds the current configuration and reloads it when the process
+// receives SIGHUP or the config file changes on disk. Components that keep
+// their own copy of a setting register a callback with OnReload.
+type Reloader struct {
+	current  atomic.Pointer[Config]
+	interval time.Duration
+
+	mu        sync.Mutex
+	callbacks []func(*Config)
+	modTime   time.Time
+}
+
+func NewReloader(cfg *Config) *Reloader {
+	r := &Reloader{interval: 10 * time.Second}
+	r.current.Store(cfg)
+	r.modTime = fileModTime(cfg.ConfigFile)
+	return r
+}
+
+// Current returns the configuration in effect. Callers should fetch it once
+// per operation rather than hold on to it.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after every
+// successful reload.
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callbacks = append(r.callbacks, fn)
+}
+
+// Reload reads the environment and config file again. On error the current
+// configuration is kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := Load()
+	if err != nil {
+		// Don't retry a broken file on every poll, only after the next edit
+		r.modTime = fileModTime(r.Current().ConfigFile)
+		return err
+	}
+
+	r.current.Store(cfg)
+	r.modTime = fileModTime(cfg.ConfigFile)
+	for _, fn := range r.callbacks {
+		fn(cfg)
+	}
+
+	log.Printf("Configuration reloaded from %s", cfg.ConfigFile)
+	return nil
+}
+
+// Start watches for SIGHUP and polls the config file for changes.
+func (r *Reloader) Start() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-hup:
+				log.Printf("Received SIGHUP, reloading configuration")
+			case <-ticker.C:
+				if !r.fileChanged() {
+					continue
+				}
+				log.Printf("Config file changed, reloading configuration")
+			}
+
+			if err := r.Reload(); err != nil {
+				log.Printf("Failed to reload configuration, keeping the current one: %v", err)
+			}
+		}
+	}()
+}
+
+func (r *Reloader) fileChanged() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !fileModTime(r.Current().ConfigFile).Equal(r.modTime)
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
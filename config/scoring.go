@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+
+	"subtitle-hunter/internal/lang"
+)
+
+// ScoringWeights override how much each signal counts when a subtitle is
+// picked from search results. Unset fields keep the built-in weight.
+type ScoringWeights struct {
+	HashMatch       *float64 `yaml:"hash_match"`
+	ReleaseMatch    *float64 `yaml:"release_match"`
+	DownloadCount   *float64 `yaml:"download_count"`
+	Rating          *float64 `yaml:"rating"`
+	HearingImpaired *float64 `yaml:"hearing_impaired"`
+	ProviderTrust   *float64 `yaml:"provider_trust"`
+	HumanMade       *float64 `yaml:"human_made"`
+}
+
+// overlay returns w with the fields set in other replaced.
+func (w ScoringWeights) overlay(other ScoringWeights) ScoringWeights {
+	if other.HashMatch != nil {
+		w.HashMatch = other.HashMatch
+	}
+	if other.ReleaseMatch != nil {
+		w.ReleaseMatch = other.ReleaseMatch
+	}
+	if other.DownloadCount != nil {
+		w.DownloadCount = other.DownloadCount
+	}
+	if other.Rating != nil {
+		w.Rating = other.Rating
+	}
+	if other.HearingImpaired != nil {
+		w.HearingImpaired = other.HearingImpaired
+	}
+	if other.ProviderTrust != nil {
+		w.ProviderTrust = other.ProviderTrust
+	}
+	if other.HumanMade != nil {
+		w.HumanMade = other.HumanMade
+	}
+	return w
+}
+
+// ScoringWeightsFor returns the weight overrides for searches in language,
+// a key of LanguageScoringWeights, through the named provider instance: the
+// global weights, then those set for the language, then those set for the
+// provider. An empty language leaves out the language's weights.
+func (c *Config) ScoringWeightsFor(provider, language string) ScoringWeights {
+	weights := c.ScoringWeights.overlay(c.LanguageScoringWeights[language])
+	return weights.overlay(c.ProviderScoringWeights[provider])
+}
+
+func (c *Config) validateScoring() error {
+	for language := range c.LanguageScoringWeights {
+		if lang.Parse(language).IsZero() {
+			return fmt.Errorf("scoring weights set for unknown language %q", language)
+		}
+	}
+	for name := range c.ProviderScoringWeights {
+		known := false
+		for _, instance := range c.OpenSubtitlesInstances {
+			known = known || instance.Name == name
+		}
+		if !known {
+			return fmt.Errorf("scoring weights set for unknown provider %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuntimeSettings are the settings that can be changed from the browser
+// without restarting. They are saved to the config file and take effect on
+// the next reload.
+type RuntimeSettings struct {
+	TargetLanguages    []string                `json:"target_languages"`
+	AutoHuntInterval   string                  `json:"auto_hunt_interval"`
+	AutoHuntWindowDays int                     `json:"auto_hunt_window_days"`
+	EnableDirectSave   bool                    `json:"enable_direct_save"`
+	InterfaceLanguage  string                  `json:"interface_language"`
+	BilingualSubtitles bool                    `json:"bilingual_subtitles"`
+	PathMappings       []PathMapping           `json:"path_mappings"`
+	Providers          []OpenSubtitlesInstance `json:"providers"`
+	TranslationMode    string                  `json:"translation_mode,omitempty"`
+}
+
+const secretMask = "********"
+
+// RuntimeSettings returns the editable part of the configuration.
+func (c *Config) RuntimeSettings() RuntimeSettings {
+	return RuntimeSettings{
+		TargetLanguages:    append([]string{}, c.TargetLanguages...),
+		AutoHuntInterval:   c.AutoHuntInterval.String(),
+		AutoHuntWindowDays: c.AutoHuntWindowDays,
+		EnableDirectSave:   c.EnableDirectSave,
+		InterfaceLanguage:  c.InterfaceLanguage,
+		BilingualSubtitles: c.BilingualSubtitles,
+		TranslationMode:    c.TranslationMode,
+		PathMappings:       append([]PathMapping{}, c.PathMappings...),
+		Providers:          append([]OpenSubtitlesInstance{}, c.OpenSubtitlesInstances...),
+	}
+}
+
+// Masked returns a copy with API keys and passwords hidden, for display.
+func (s RuntimeSettings) Masked() RuntimeSettings {
+	masked := s
+	masked.Providers = make([]OpenSubtitlesInstance, len(s.Providers))
+	for i, provider := range s.Providers {
+		if len(provider.APIKey) > 4 {
+			provider.APIKey = secretMask + provider.APIKey[len(provider.APIKey)-4:]
+		} else if provider.APIKey != "" {
+			provider.APIKey = secretMask
+		}
+		if provider.Password != "" {
+			provider.Password = secretMask
+		}
+		masked.Providers[i] = provider
+	}
+	return masked
+}
+
+// KeepSecrets fills in API keys and passwords that were left blank or still
+// masked from the provider of the same name in current, so a masked form can
+// be submitted without re-entering every secret.
+func (s *RuntimeSettings) KeepSecrets(current RuntimeSettings) {
+	existing := make(map[string]OpenSubtitlesInstance)
+	for _, provider := range current.Providers {
+		existing[provider.Name] = provider
+	}
+
+	for i := range s.Providers {
+		provider := &s.Providers[i]
+		old, ok := existing[provider.Name]
+		if !ok {
+			continue
+		}
+		if provider.APIKey == "" || strings.HasPrefix(provider.APIKey, secretMask) {
+			provider.APIKey = old.APIKey
+		}
+		if provider.Password == "" || provider.Password == secretMask {
+			provider.Password = old.Password
+		}
+	}
+}
+
+// Validate checks the settings before they are saved.
+func (s RuntimeSettings) Validate() error {
+	if len(s.TargetLanguages) == 0 {
+		return fmt.Errorf("at least one target language is required")
+	}
+
+	interval, err := time.ParseDuration(s.AutoHuntInterval)
+	if err != nil || interval < 0 {
+		return fmt.Errorf("invalid auto-hunt interval %q", s.AutoHuntInterval)
+	}
+	if interval > 0 && interval < time.Minute {
+		return fmt.Errorf("auto-hunt interval must be at least one minute")
+	}
+	if s.AutoHuntWindowDays < 0 {
+		return fmt.Errorf("auto-hunt window cannot be negative")
+	}
+
+	if s.TranslationMode != "" && !validTranslationMode(s.TranslationMode) {
+		return fmt.Errorf("unknown translation mode %q", s.TranslationMode)
+	}
+
+	for _, mapping := range s.PathMappings {
+		if mapping.Jellyfin == "" || mapping.Container == "" {
+			return fmt.Errorf("path mappings need both a Jellyfin and a container path")
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, provider := range s.Providers {
+		if provider.Name == "" || provider.APIKey == "" {
+			return fmt.Errorf("every provider needs a name and an API key")
+		}
+		if names[provider.Name] {
+			return fmt.Errorf("duplicate provider name %q", provider.Name)
+		}
+		names[provider.Name] = true
+	}
+
+	return nil
+}
+
+// SaveRuntimeSettings writes the settings into the config file at path,
+// creating it if needed. Only the affected keys are replaced, so comments
+// and other sections of a hand-written file are kept.
+func SaveRuntimeSettings(path string, s RuntimeSettings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+
+	if err := setKey(root, "target_languages", s.TargetLanguages); err != nil {
+		return err
+	}
+	if err := setKey(root, "path_mappings", s.PathMappings); err != nil {
+		return err
+	}
+	if len(s.Providers) > 0 {
+		if err := setKey(childMapping(root, "providers"), "opensubtitles", s.Providers); err != nil {
+			return err
+		}
+	}
+	schedule := childMapping(root, "schedule")
+	if err := setKey(schedule, "auto_hunt_interval", s.AutoHuntInterval); err != nil {
+		return err
+	}
+	if err := setKey(schedule, "auto_hunt_window_days", s.AutoHuntWindowDays); err != nil {
+		return err
+	}
+	if err := setKey(childMapping(root, "saving"), "direct_save", s.EnableDirectSave); err != nil {
+		return err
+	}
+	if err := setKey(childMapping(root, "interface"), "language", s.InterfaceLanguage); err != nil {
+		return err
+	}
+	if err := setKey(childMapping(root, "output"), "bilingual", s.BilingualSubtitles); err != nil {
+		return err
+	}
+	if s.TranslationMode != "" {
+		if err := setKey(childMapping(root, "translation"), "mode", s.TranslationMode); err != nil {
+			return err
+		}
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+
+	return writeFileAtomic(path, out.Bytes())
+}
+
+// setKey replaces or appends key in a YAML mapping node.
+func setKey(mapping *yaml.Node, key string, value interface{}) error {
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+
+	setKeyNode(mapping, key, &valueNode)
+	return nil
+}
+
+// childMapping returns the mapping stored under key, creating it if missing.
+func childMapping(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key && mapping.Content[i+1].Kind == yaml.MappingNode {
+			return mapping.Content[i+1]
+		}
+	}
+
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	setKeyNode(mapping, key, child)
+	return child
+}
+
+func setKeyNode(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// writeFileAtomic replaces path with data. The file holds provider
+// credentials, so it is only readable by its owner.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Credentials are what the service can't run without: how to reach
+// Jellyfin and as which user, and an OpenSubtitles API key. Jellyfin takes
+// an API key or a username and password.
+type Credentials struct {
+	JellyfinURL      string `json:"jellyfin_url"`
+	JellyfinAPIKey   string `json:"jellyfin_api_key"`
+	JellyfinUserID   string `json:"jellyfin_user_id"`
+	JellyfinUsername string `json:"jellyfin_username"`
+	JellyfinPassword string `json:"jellyfin_password"`
+	OpenSubtitlesKey string `json:"opensubtitles_api_key"`
+}
+
+// Credentials returns the configured credentials.
+func (c *Config) Credentials() Credentials {
+	return Credentials{
+		JellyfinURL:      c.JellyfinURL,
+		JellyfinAPIKey:   c.JellyfinAPIKey,
+		JellyfinUserID:   c.JellyfinUserID,
+		JellyfinUsername: c.JellyfinUsername,
+		JellyfinPassword: c.JellyfinPassword,
+		OpenSubtitlesKey: c.OpenSubtitlesKey,
+	}
+}
+
+// Missing names the environment variables of the credentials that are not
+// set. Signing in to Jellyfin with a username needs neither the API key nor
+// the user ID, and Jellyfin users may have no password.
+func (c Credentials) Missing() []string {
+	type credential struct{ value, name string }
+	required := []credential{{c.JellyfinAPIKey, "JELLYFIN_API_KEY"}, {c.JellyfinUserID, "JELLYFIN_USER_ID"}}
+	if c.JellyfinUsername != "" {
+		required = nil
+	}
+	required = append(required, credential{c.OpenSubtitlesKey, "OPENSUBTITLES_API_KEY"})
+
+	var missing []string
+	for _, required := range required {
+		if required.value == "" {
+			missing = append(missing, required.name)
+		}
+	}
+	return missing
+}
+
+// Validate checks that every credential is there and the Jellyfin URL is
+// an http or https URL.
+func (c Credentials) Validate() error {
+	parsed, err := url.Parse(c.JellyfinURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("the Jellyfin URL must be an http or https URL such as http://jellyfin:8096")
+	}
+	if missing := c.Missing(); len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// SaveCredentials writes the credentials into the config file at path,
+// creating it if needed, where they take precedence over the environment.
+// The OpenSubtitles key replaces that of the first configured account, or
+// adds a "default" account. The rest of the file is kept.
+func SaveCredentials(path string, c Credentials, instances []OpenSubtitlesInstance) error {
+	c.JellyfinURL = strings.TrimSuffix(strings.TrimSpace(c.JellyfinURL), "/")
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+
+	jellyfin := childMapping(root, "jellyfin")
+	for _, field := range []struct{ key, value string }{
+		{"url", c.JellyfinURL},
+		{"api_key", c.JellyfinAPIKey},
+		{"user_id", c.JellyfinUserID},
+		{"username", c.JellyfinUsername},
+		{"password", c.JellyfinPassword},
+	} {
+		if err := setKey(jellyfin, field.key, field.value); err != nil {
+			return err
+		}
+	}
+
+	instances = append([]OpenSubtitlesInstance{}, instances...)
+	if len(instances) == 0 {
+		instances = append(instances, OpenSubtitlesInstance{Name: "default", Priority: 1})
+	}
+	instances[0].APIKey = c.OpenSubtitlesKey
+	if err := setKey(childMapping(root, "providers"), "opensubtitles", instances); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+
+	return writeFileAtomic(path, out.Bytes())
+}
@@ -0,0 +1,64 @@
+package config
+
+import "fmt"
+
+// Translation modes decide whether the machine translation fallback runs.
+const (
+	// TranslationAuto translates when no subtitle in the language is found.
+	TranslationAuto = "auto"
+	// TranslationConfirm pauses a job before it translates until someone
+	// approves the translation.
+	TranslationConfirm = "confirm"
+	// TranslationOff never translates; only subtitles in the language are
+	// downloaded.
+	TranslationOff = "off"
+)
+
+// Translator backends that can take part in the translator chain.
+const (
+	TranslatorGoogle = "google"
+	TranslatorDeepL  = "deepl"
+)
+
+// TranslationModes lists the translation modes in display order.
+var TranslationModes = []string{TranslationAuto, TranslationConfirm, TranslationOff}
+
+func validTranslationMode(mode string) bool {
+	for _, known := range TranslationModes {
+		if mode == known {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTranslation checks the translation mode, the time a translation
+// waits for approval and the translator chain.
+func (c *Config) validateTranslation() error {
+	if !validTranslationMode(c.TranslationMode) {
+		return fmt.Errorf("unknown translation mode %q (expected %s, %s or %s)", c.TranslationMode, TranslationAuto, TranslationConfirm, TranslationOff)
+	}
+	if c.ApprovalTimeout < 0 {
+		return fmt.Errorf("translation approval timeout must not be negative, got %s", c.ApprovalTimeout)
+	}
+
+	if len(c.TranslatorChain) == 0 {
+		return fmt.Errorf("the translator chain needs at least one backend")
+	}
+	seen := make(map[string]bool)
+	for _, name := range c.TranslatorChain {
+		switch {
+		case name != TranslatorGoogle && name != TranslatorDeepL:
+			return fmt.Errorf("unknown translator %q in the translator chain (expected %s or %s)", name, TranslatorGoogle, TranslatorDeepL)
+		case name == TranslatorDeepL && c.DeepLAPIKey == "":
+			return fmt.Errorf("the translator chain uses %s, which needs DEEPL_API_KEY", TranslatorDeepL)
+		case seen[name]:
+			return fmt.Errorf("translator %q is in the translator chain twice", name)
+		}
+		seen[name] = true
+	}
+	if c.TranslatorCooldown < 0 {
+		return fmt.Errorf("translator cooldown must not be negative, got %s", c.TranslatorCooldown)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package events pushes changes to the library and the service's state to
+// connected browsers over WebSocket, so open pages stay current without
+// reloading.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	// JobFinished: an item was hunted, from any page, the scheduler or a
+	// batch. Data is a JobFinishedData.
+	JobFinished = "job-finished"
+	// RunStarted and RunFinished bracket a scheduled hunt.
+	RunStarted  = "run-started"
+	RunFinished = "run-finished"
+	// SchedulerPaused and SchedulerResumed follow the automatic hunting
+	// switch on the quota page.
+	SchedulerPaused  = "scheduler-paused"
+	SchedulerResumed = "scheduler-resumed"
+	// QuotaExhausted: every OpenSubtitles account is out of downloads.
+	QuotaExhausted = "quota-exhausted"
+	// BudgetExhausted: today's download or translation budget is used up.
+	// Data names the counter.
+	BudgetExhausted = "budget-exhausted"
+	// LibraryRescanned: the library listing was fetched from Jellyfin again.
+	LibraryRescanned = "library-rescanned"
+	// ApprovalRequested: a job waits for its translation to be approved.
+	// Data is an ApprovalData.
+	ApprovalRequested = "approval-requested"
+)
+
+// Event is a change pushed to subscribers, sent as JSON.
+type Event struct {
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// JobFinishedData describes a finished job.
+type JobFinishedData struct {
+	JobID   string `json:"job_id"`
+	ItemID  string `json:"item_id"`
+	Name    string `json:"name"`
+	Trigger string `json:"trigger"`
+	// Succeeded is set when a subtitle was saved.
+	Succeeded bool   `json:"succeeded"`
+	Source    string `json:"source,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ApprovalData describes a job waiting for approval.
+type ApprovalData struct {
+	JobID    string `json:"job_id"`
+	ItemID   string `json:"item_id"`
+	Name     string `json:"name"`
+	Approval string `json:"approval"`
+}
+
+// RunData describes a scheduled hunt; the counts are set when it finishes.
+type RunData struct {
+	Full      bool `json:"full"`
+	Items     int  `json:"items"`
+	Processed int  `json:"processed"`
+	Failed    int  `json:"failed"`
+	Skipped   int  `json:"skipped"`
+	Deferred  int  `json:"deferred"`
+}
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further events are dropped for it.
+const subscriberBuffer = 64
+
+// Hub hands every published event to all subscribers. A nil Hub drops
+// events, so code publishing them doesn't need to check.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[chan Event]struct{})}
+}
+
+// Publish sends an event to every subscriber without waiting for them.
+func (h *Hub) Publish(eventType string, data interface{}) {
+	if h == nil {
+		return
+	}
+	event := Event{Type: eventType, Time: time.Now(), Data: data}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Rather miss an update than hold up the job that published it
+		}
+	}
+}
+
+// Subscribe returns a channel receiving published events and the function
+// that ends the subscription and closes it.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
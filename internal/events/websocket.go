@@ -0,0 +1,240 @@
+package events
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the client's key to prove the handshake was
+// understood (RFC 6455, section 1.3).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+const (
+	// pingInterval keeps idle connections open through proxies and notices
+	// browsers that went away without closing.
+	pingInterval = 30 * time.Second
+	// readTimeout allows for one missed pong.
+	readTimeout  = 2*pingInterval + 10*time.Second
+	writeTimeout = 10 * time.Second
+	// maxClientFrame caps what a browser may send; it has nothing to say
+	// beyond pongs and close frames.
+	maxClientFrame = 4 << 10
+)
+
+// ServeHTTP upgrades the request to a WebSocket and sends every published
+// event as a JSON text message until the browser disconnects or the
+// request's context is done. Messages from the browser are ignored.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusBadRequest)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "Missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return
+	}
+	// Browsers let any site open a WebSocket to us; only our own pages may
+	// listen in
+	if !sameOrigin(r) {
+		http.Error(w, "Cross-origin WebSocket refused", http.StatusForbidden)
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("WebSocket upgrade failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(accept[:]))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	events, unsubscribe := h.Subscribe()
+	defer unsubscribe()
+
+	ws := &websocketConn{conn: conn}
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		if err := ws.readLoop(rw.Reader); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+			log.Printf("WebSocket %s: %v", r.RemoteAddr, err)
+		}
+	}()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case event := <-events:
+			message, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("Warning: failed to encode %s event: %v", event.Type, err)
+				continue
+			}
+			if ws.write(opText, message) != nil {
+				return
+			}
+		case <-ping.C:
+			if ws.write(opPing, nil) != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			ws.write(opClose, closePayload(1001))
+			return
+		}
+	}
+}
+
+// websocketConn writes frames to a connection; reads happen in readLoop
+// alone.
+type websocketConn struct {
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+// write sends an unmasked final frame, as servers do.
+func (c *websocketConn) write(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// readLoop reads the browser's frames, answering pings and close frames,
+// until the connection ends.
+func (c *websocketConn) readLoop(reader *bufio.Reader) error {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		var header [2]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return err
+		}
+		opcode := header[0] & 0x0F
+		masked := header[1]&0x80 != 0
+		length := uint64(header[1] & 0x7F)
+		switch length {
+		case 126:
+			var extended [2]byte
+			if _, err := io.ReadFull(reader, extended[:]); err != nil {
+				return err
+			}
+			length = uint64(binary.BigEndian.Uint16(extended[:]))
+		case 127:
+			var extended [8]byte
+			if _, err := io.ReadFull(reader, extended[:]); err != nil {
+				return err
+			}
+			length = binary.BigEndian.Uint64(extended[:])
+		}
+		if !masked {
+			return fmt.Errorf("unmasked frame from client")
+		}
+		if length > maxClientFrame {
+			c.write(opClose, closePayload(1009))
+			return fmt.Errorf("%d byte frame from client is too large", length)
+		}
+
+		var mask [4]byte
+		if _, err := io.ReadFull(reader, mask[:]); err != nil {
+			return err
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+
+		switch opcode {
+		case opClose:
+			c.write(opClose, payload)
+			return io.EOF
+		case opPing:
+			if err := c.write(opPong, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func closePayload(code uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, code)
+}
+
+// headerContains reports whether a comma-separated header lists token.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether the request comes from a page served by this
+// host, or from something other than a browser, which sends no Origin.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return strings.EqualFold(parsed.Host, host)
+}
@@ -0,0 +1,153 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+)
+
+// Text-based subtitle codecs ffmpeg can convert to SRT. Bitmap formats such
+// as PGS and VobSub would need OCR and are skipped.
+var textSubtitleCodecs = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"mov_text": true,
+	"webvtt":   true,
+	"text":     true,
+}
+
+type Extractor struct {
+	FFmpegPath string
+	Timeout    time.Duration
+	// TempDir receives intermediate files such as extracted audio.
+	TempDir string
+}
+
+func NewExtractor(ffmpegPath string) *Extractor {
+	return &Extractor{
+		FFmpegPath: ffmpegPath,
+		Timeout:    5 * time.Minute,
+	}
+}
+
+// Available reports whether the ffmpeg binary can be found.
+func (e *Extractor) Available() bool {
+	_, err := exec.LookPath(e.FFmpegPath)
+	return err == nil
+}
+
+func IsTextCodec(codec string) bool {
+	return textSubtitleCodecs[strings.ToLower(codec)]
+}
+
+// MatchesLanguage reports whether an embedded stream carries the given
+// language. Stream and target are compared as language tags, so "eng",
+// "english" and "en" are equivalent, as are "chs", "zh-SG" and "zh-Hans".
+func MatchesLanguage(stream jellyfin.MediaStream, language string) bool {
+	streamTag, target := lang.Normalize(stream.Language), lang.Normalize(language)
+	if streamTag.IsZero() || streamTag.Language != target.Language {
+		return false
+	}
+
+	targetScript := target.InferredScript()
+	if targetScript == "" {
+		return true
+	}
+	if script := streamTag.InferredScript(); script != "" {
+		return script == targetScript
+	}
+
+	// Generic Chinese tracks only count when their title names the script
+	return jellyfin.StreamScript(stream) == targetScript
+}
+
+// SelectTrack returns the first embedded text subtitle stream matching the
+// languages in preference order, along with the language it matched.
+// Forced tracks are skipped since they only cover part of the dialogue.
+func SelectTrack(streams []jellyfin.MediaStream, languages []string) (jellyfin.MediaStream, string, bool) {
+	for _, language := range languages {
+		for _, stream := range streams {
+			if stream.Type != "Subtitle" || stream.IsExternal || stream.IsForced || !IsTextCodec(stream.Codec) {
+				continue
+			}
+			if MatchesLanguage(stream, language) {
+				return stream, language, true
+			}
+		}
+	}
+	return jellyfin.MediaStream{}, "", false
+}
+
+// ExtractSRT pulls the stream at streamIndex out of the video file and
+// converts it to SRT.
+func (e *Extractor) ExtractSRT(ctx context.Context, videoPath string, streamIndex int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.FFmpegPath,
+		"-v", "error",
+		"-nostdin",
+		"-i", videoPath,
+		"-map", fmt.Sprintf("0:%d", streamIndex),
+		"-f", "srt",
+		"-",
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed to extract stream %d: %w (%s)", streamIndex, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no output for stream %d", streamIndex)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// ExtractAudio writes the first audio track of the video to a 16 kHz mono
+// WAV file suitable for speech recognition. The caller removes the file.
+func (e *Extractor) ExtractAudio(ctx context.Context, videoPath string) (string, error) {
+	tempFile, err := os.CreateTemp(e.TempDir, "audio-*.wav")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempFile.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, e.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.FFmpegPath,
+		"-v", "error",
+		"-nostdin",
+		"-y",
+		"-i", videoPath,
+		"-vn",
+		"-ac", "1",
+		"-ar", "16000",
+		"-f", "wav",
+		tempFile.Name(),
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.Remove(tempFile.Name())
+		return "", fmt.Errorf("ffmpeg failed to extract audio: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return tempFile.Name(), nil
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"subtitle-hunter/config"
+	"subtitle-hunter/internal/events"
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+)
+
+// approveTranslation waits for a translation, described by what, to be
+// approved when translations need confirming (TRANSLATION_MODE=confirm)
+// and the request didn't ask for translation itself. A translation that is
+// rejected or not approved in time skips the step. Jobs run from the
+// command line can't be approved, so they skip it straight away.
+func (h *Handler) approveTranslation(ctx context.Context, item *jellyfin.MediaItem, what string) error {
+	cfg := h.Config()
+	if cfg.TranslationMode != config.TranslationConfirm || (h.translate != nil && *h.translate) {
+		return nil
+	}
+	if h.job.Trigger() == jobs.TriggerCLI {
+		return fmt.Errorf("%w: translations need approval, which can't be given from the command line", errStepSkipped)
+	}
+
+	h.job.Logf("Job %s is waiting for approval: %s", h.job.ID(), what)
+	h.Events.Publish(events.ApprovalRequested, events.ApprovalData{JobID: h.job.ID(), ItemID: item.ID, Name: item.Name, Approval: what})
+	approved, err := h.job.AwaitApproval(ctx, what, cfg.ApprovalTimeout)
+	switch {
+	case errors.Is(err, jobs.ErrApprovalTimeout):
+		return fmt.Errorf("%w: the translation wasn't approved within %s", errStepSkipped, cfg.ApprovalTimeout)
+	case err != nil:
+		return err
+	case !approved:
+		h.job.Logf("Job %s: translation rejected", h.job.ID())
+		return fmt.Errorf("%w: the translation was rejected", errStepSkipped)
+	}
+	h.job.Logf("Job %s: translation approved", h.job.ID())
+	return nil
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/opensubtitles"
+	"subtitle-hunter/internal/wanted"
+)
+
+// maxArchiveUploadSize caps uploaded season archives, which hold a
+// subtitle for each episode.
+const maxArchiveUploadSize = 20 << 20
+
+const seasonArchiveSource = "OpenSubtitles season archive"
+
+// archiveEpisode is what became of one file in a season archive.
+type archiveEpisode struct {
+	File    string `json:"file"`
+	Episode string `json:"episode,omitempty"`
+	ItemID  string `json:"item_id,omitempty"`
+	Path    string `json:"path,omitempty"`
+	// Skipped says why the file wasn't saved.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+type archiveResult struct {
+	Saved   []archiveEpisode `json:"saved"`
+	Skipped []archiveEpisode `json:"skipped"`
+}
+
+func (r archiveResult) Message() string {
+	message := fmt.Sprintf("Saved %d of %d subtitles from the archive", len(r.Saved), len(r.Saved)+len(r.Skipped))
+	var skipped []string
+	for _, file := range r.Skipped {
+		skipped = append(skipped, fmt.Sprintf("%s (%s)", file.File, file.Skipped))
+	}
+	if len(skipped) > 0 {
+		message += ". Skipped: " + strings.Join(skipped, ", ")
+	}
+	return message
+}
+
+// fileForItem picks item's subtitle out of the files of a download. From a
+// season archive that is the file named after the episode; when no file is
+// named after any episode, or the item isn't an episode, it is the largest.
+func fileForItem(files []opensubtitles.ArchiveFile, item *jellyfin.MediaItem) (opensubtitles.ArchiveFile, error) {
+	if len(files) == 1 || item.Type != "Episode" {
+		return opensubtitles.Largest(files), nil
+	}
+
+	named := false
+	for _, file := range files {
+		season, number, ok := opensubtitles.EpisodeInName(file.Name)
+		if ok && season == item.ParentIndexNumber && number == item.IndexNumber {
+			return file, nil
+		}
+		named = named || ok
+	}
+	if !named {
+		return opensubtitles.Largest(files), nil
+	}
+	return opensubtitles.ArchiveFile{}, fmt.Errorf("none of the %d files in the archive is for S%02dE%02d", len(files), item.ParentIndexNumber, item.IndexNumber)
+}
+
+// saveSeasonArchive saves the files of a season archive downloaded for
+// item as the target subtitle of the other episodes of its series they are
+// named after, so a season is downloaded once rather than per episode.
+// Episodes that already have the subtitle are left alone.
+func (h *Handler) saveSeasonArchive(ctx context.Context, item *jellyfin.MediaItem, files []opensubtitles.ArchiveFile, target wanted.Target, source string) archiveResult {
+	var result archiveResult
+	if item.Type != "Episode" || item.SeriesID == "" {
+		return result
+	}
+
+	episodes, err := h.seriesEpisodes(ctx, item.SeriesID)
+	if err != nil {
+		h.job.Logf("Warning: the other files of the season archive were not saved: %v", err)
+		return result
+	}
+
+	h.job.Logf("The download is a season archive of %d files, saving them to the other episodes", len(files))
+	for _, file := range files {
+		season, number, ok := opensubtitles.EpisodeInName(file.Name)
+		if ok && season == item.ParentIndexNumber && number == item.IndexNumber {
+			continue
+		}
+		saved := h.saveArchiveFile(ctx, episodes, file, target, source)
+		if saved.Skipped != "" {
+			h.job.Logf("Skipped %s: %s", saved.File, saved.Skipped)
+			result.Skipped = append(result.Skipped, saved)
+			continue
+		}
+		h.job.Logf("Saved %s for %s", saved.File, saved.Episode)
+		result.Saved = append(result.Saved, saved)
+	}
+	return result
+}
+
+// seriesEpisodes returns the episodes of a series in the library, keyed by
+// season and episode number.
+func (h *Handler) seriesEpisodes(ctx context.Context, seriesID string) (map[[2]int]jellyfin.MediaItem, error) {
+	items, err := h.Library.Items(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list episodes: %w", err)
+	}
+	episodes := make(map[[2]int]jellyfin.MediaItem)
+	for _, item := range items {
+		if item.Type == "Episode" && item.SeriesID == seriesID {
+			episodes[[2]int{item.ParentIndexNumber, item.IndexNumber}] = item
+		}
+	}
+	return episodes, nil
+}
+
+// saveArchiveFile saves one file of a season archive for the episode it is
+// named after, if that episode still lacks the target subtitle.
+func (h *Handler) saveArchiveFile(ctx context.Context, episodes map[[2]int]jellyfin.MediaItem, file opensubtitles.ArchiveFile, target wanted.Target, source string) archiveEpisode {
+	saved := archiveEpisode{File: file.Name}
+	season, number, ok := opensubtitles.EpisodeInName(file.Name)
+	if !ok {
+		saved.Skipped = "not named after an episode"
+		return saved
+	}
+	saved.Episode = fmt.Sprintf("S%02dE%02d", season, number)
+	episode, ok := episodes[[2]int{season, number}]
+	if !ok {
+		saved.Skipped = "episode not in the library"
+		return saved
+	}
+	saved.ItemID = episode.ID
+
+	status, err := h.Wanted.Status(episode, target)
+	if err != nil {
+		saved.Skipped = err.Error()
+		return saved
+	}
+	if status != wanted.StatusMissing {
+		saved.Skipped = fmt.Sprintf("subtitle already %s", status)
+		return saved
+	}
+
+	content, err := opensubtitles.ToSRT(file.Content)
+	if err != nil {
+		saved.Skipped = err.Error()
+		return saved
+	}
+	location, err := h.saveDownloadedSubtitle(&episode, target.String(), content)
+	if err != nil {
+		saved.Skipped = err.Error()
+		return saved
+	}
+	saved.Path = location
+
+	record := wanted.NewResult(episode, wanted.StatusDownloaded, source, location)
+	if err := h.Wanted.RecordResult(episode.ID, target, record); err != nil {
+		h.job.Logf("Warning: %v", err)
+	}
+	h.refreshMetadata(ctx, &episode)
+	return saved
+}
+
+// uploadSeasonArchive handles POST /api/v1/series/{id}/archive, which takes
+// a multipart "file" holding a zip of subtitles named after the episodes,
+// a "language" tag and "forced", and saves each subtitle to the episode of
+// the series it is named after that still lacks it.
+func (h *Handler) uploadSeasonArchive(w http.ResponseWriter, r *http.Request, seriesID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveUploadSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("Archive file required: %v", err))
+		return
+	}
+	defer file.Close()
+
+	language := lang.TraditionalChinese
+	if value := r.FormValue("language"); value != "" {
+		language = lang.Normalize(value)
+		if language.IsZero() {
+			respond(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown language %q", value))
+			return
+		}
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("Failed to read upload: %v", err))
+		return
+	}
+	files, err := opensubtitles.UnpackArchive(content)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid archive: %v", err))
+		return
+	}
+	// A single subtitle is matched by the name it was uploaded under
+	if len(files) == 1 && files[0].Name == "" {
+		files[0].Name = header.Filename
+	}
+
+	episodes, err := h.seriesEpisodes(r.Context(), seriesID)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(episodes) == 0 {
+		respond(w, r, http.StatusNotFound, "No episodes of this series in the library")
+		return
+	}
+
+	target := wanted.Target{Language: language, Forced: r.FormValue("forced") == "true"}
+	log.Printf("Saving uploaded %s season archive %q for series %s", target, header.Filename, seriesID)
+	var result archiveResult
+	for _, archived := range files {
+		saved := h.saveArchiveFile(r.Context(), episodes, archived, target, "manual upload")
+		if saved.Skipped != "" {
+			result.Skipped = append(result.Skipped, saved)
+		} else {
+			result.Saved = append(result.Saved, saved)
+		}
+	}
+
+	if wantsHTML(r) {
+		status := http.StatusOK
+		if len(result.Saved) == 0 {
+			status = http.StatusBadRequest
+		}
+		respond(w, r, status, result.Message())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
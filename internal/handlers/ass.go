@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/subtitle"
+)
+
+// translateAndSaveScript translates the dialogue of an ASS script in place
+// and saves it as an ASS file, so styles, positioning tags such as {\an8}
+// and karaoke lines stay as they were. The script isn't cleaned up, wrapped
+// or split, and the editor and cue retries only handle SRT files, so the
+// failed cues aren't offered for a retry.
+func (h *Handler) translateAndSaveScript(ctx context.Context, item *jellyfin.MediaItem, content []byte, videoPath string, source lang.Tag) (string, subtitle.TranslationReport, error) {
+	stopParse := h.job.Stage(jobs.StageParse)
+	script, err := subtitle.ParseASSScript(content)
+	stopParse()
+	if err != nil {
+		return "", subtitle.TranslationReport{}, fmt.Errorf("failed to parse subtitle: %w", err)
+	}
+	if err := h.chargeBudget(translationsCounter, h.Config().DailyTranslationBudget); err != nil {
+		return "", subtitle.TranslationReport{}, err
+	}
+
+	offset := h.rememberedOffset(item, videoPath)
+	if offset != 0 {
+		script.Shift(offset)
+		h.job.Logf("Shifted subtitle by the %s remembered for this release", formatOffset(offset))
+	}
+
+	h, textTranslator := h.translatorFrom(source)
+	entries := script.Entries()
+	h.job.Logf("Translating the dialogue of %d ASS lines, keeping their styles and override tags...", len(entries))
+	translateCtx, stopTranslate := h.stage(ctx, jobs.StageTranslate)
+	translated, report, err := h.translateEntries(translateCtx, item, entries, h.wrapTranslator(item, textTranslator))
+	stopTranslate()
+	if flushErr := h.Usage.Flush(); flushErr != nil {
+		h.job.Logf("Warning: %v", flushErr)
+	}
+	if err != nil {
+		return "", report, fmt.Errorf("failed to translate subtitle: %w", err)
+	}
+	h.job.Logf("Translation completed")
+
+	if h.bilingualOutput() {
+		translated = subtitle.Bilingual(entries, translated, report.FailedIndexes)
+	}
+	script.Apply(translated)
+
+	saveLocation, err := h.saveSubtitle(item, lang.TraditionalChinese.String(), []byte(script.Format()), len(entries))
+	if err != nil {
+		return "", report, err
+	}
+	h.recordApplied(item, videoPath, lang.TraditionalChinese.String(), offset)
+
+	if report.Failed > 0 {
+		h.job.Logf("%s; failed lines: %v", report, report.FailedIndexes)
+		h.job.TranslationFailed(jobs.TranslationReport{
+			Subtitle: saveLocation,
+			Total:    report.Total,
+			Failed:   report.Failed,
+			Partial:  report.Partial,
+		})
+		report.FailedIndexes = nil
+	}
+	return saveLocation, report, nil
+}
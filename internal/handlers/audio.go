@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+)
+
+// withTranslation returns a view of the handler that allows machine
+// translation, or not, whatever the series settings say.
+func (h *Handler) withTranslation(on bool) *Handler {
+	view := *h
+	view.translate = &on
+	return &view
+}
+
+// translationOverride reads the request's "translate" value ("true" or
+// "false"). It returns nil when the request leaves it to the series settings.
+func translationOverride(r *http.Request) (*bool, error) {
+	value := r.FormValue("translate")
+	if value == "" {
+		return nil, nil
+	}
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid translate value %q (expected true or false)", value)
+	}
+	return &on, nil
+}
+
+// chineseAudio reports whether item was made in Chinese, going by the
+// language Jellyfin reports for its audio.
+func chineseAudio(item *jellyfin.MediaItem) bool {
+	return item.AudioLanguage().IsChinese()
+}
+
+// englishTranslationAllowed reports whether an English subtitle may be
+// machine translated for item. For Chinese audio the English subtitle is
+// itself a translation of the dialogue, so it is only translated back when
+// the request or the series settings turn translation on explicitly.
+func (h *Handler) englishTranslationAllowed(item *jellyfin.MediaItem) bool {
+	if !h.translationAllowed(item) {
+		return false
+	}
+	return !chineseAudio(item) || h.translate != nil || h.seriesSettings(item).Translate != nil
+}
+
+// embeddedSourceLanguages returns the embedded subtitle languages that may
+// be converted or translated for item: only the Chinese ones unless English
+// may be translated.
+func (h *Handler) embeddedSourceLanguages(item *jellyfin.MediaItem) []string {
+	languages := h.Config().EmbeddedSourceLanguages
+	if h.englishTranslationAllowed(item) {
+		return languages
+	}
+	var chinese []string
+	for _, language := range languages {
+		if lang.Parse(language).IsChinese() {
+			chinese = append(chinese, language)
+		}
+	}
+	return chinese
+}
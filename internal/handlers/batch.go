@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/wanted"
+)
+
+// itemLanguagesBucket holds the target languages set for single items,
+// keyed by item ID.
+const itemLanguagesBucket = "item-languages"
+
+type batchRequest struct {
+	ItemIDs   []string `json:"item_ids"`
+	Languages []string `json:"languages"`
+}
+
+type batchResponse struct {
+	Action string `json:"action"`
+	Items  int    `json:"items"`
+}
+
+// BatchHandler applies an action to many items at once:
+// POST /api/v1/batch/process hunts them in the background,
+// POST /api/v1/batch/ignore marks the given languages as not wanted for
+// them, and POST /api/v1/batch/languages sets their target languages (no
+// languages goes back to the series' or the configured ones).
+// It takes a JSON body, or "item_id" form fields (one per item) and a
+// comma-separated "languages" field, in which case the browser gets a
+// result page.
+func (h *Handler) BatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	action := strings.TrimPrefix(r.URL.Path, "/api/v1/batch/")
+	if action != "process" && action != "ignore" && action != "languages" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req batchRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.ItemIDs = r.Form["item_id"]
+		req.Languages = splitList(r.FormValue("languages"))
+	}
+	if len(req.ItemIDs) == 0 {
+		respond(w, r, http.StatusBadRequest, "No items selected")
+		return
+	}
+	for _, value := range req.Languages {
+		if lang.Parse(value).IsZero() {
+			respond(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown language %q", value))
+			return
+		}
+	}
+	languages := parseTargets(req.Languages)
+
+	status := http.StatusOK
+	var message string
+	switch action {
+	case "process":
+		go h.huntBatch(req.ItemIDs)
+		status = http.StatusAccepted
+		message = fmt.Sprintf("Hunting %d items in the background; their jobs show up in the job history as they finish", len(req.ItemIDs))
+
+	case "ignore":
+		if len(languages) == 0 {
+			respond(w, r, http.StatusBadRequest, "Language required")
+			return
+		}
+		for _, itemID := range req.ItemIDs {
+			for _, language := range languages {
+				if err := h.Wanted.SetIgnored(itemID, wanted.Target{Language: language}, true); err != nil {
+					respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to update item: %v", err))
+					return
+				}
+			}
+		}
+		message = fmt.Sprintf("Ignored %s for %d items", joinTags(languages), len(req.ItemIDs))
+
+	case "languages":
+		for _, itemID := range req.ItemIDs {
+			if err := h.setItemLanguages(itemID, languages); err != nil {
+				respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to update item: %v", err))
+				return
+			}
+		}
+		if len(languages) == 0 {
+			message = fmt.Sprintf("%d items use the default target languages again", len(req.ItemIDs))
+		} else {
+			message = fmt.Sprintf("Set the target languages of %d items to %s", len(req.ItemIDs), joinTags(languages))
+		}
+	}
+
+	if wantsHTML(r) {
+		respond(w, r, status, message)
+		return
+	}
+	writeJSON(w, status, batchResponse{Action: action, Items: len(req.ItemIDs)})
+}
+
+// huntBatch hunts the items as batch jobs, as many at once as the worker
+// pool allows. Items of series whose hunting is paused are skipped, and
+// the rest are left alone once the daily budget is used up.
+func (h *Handler) huntBatch(itemIDs []string) {
+	ctx := h.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	fed := jobs.RunEach(ctx, h.Config().WorkerPoolSize, len(itemIDs), func(i int) bool {
+		return !errors.Is(h.huntBatchItem(ctx, itemIDs[i]), ErrBudgetExhausted)
+	})
+	if fed < len(itemIDs) {
+		log.Printf("Batch hunt stopped, %d of %d items left for later", len(itemIDs)-fed, len(itemIDs))
+	} else {
+		log.Printf("Batch hunt of %d items finished", len(itemIDs))
+	}
+}
+
+func (h *Handler) huntBatchItem(ctx context.Context, itemID string) error {
+	item, err := h.JellyfinClient.GetItem(ctx, itemID)
+	if err != nil {
+		log.Printf("Batch hunt: failed to get item %s: %v", itemID, err)
+		return err
+	}
+	if h.HuntingPaused(item) {
+		log.Printf("Batch hunt: skipping %s, hunting is paused for %s", item.Name, item.SeriesName)
+		return nil
+	}
+	_, _, err = h.Hunt(ctx, item, jobs.TriggerBatch)
+	if err != nil {
+		log.Printf("Batch hunt: %s: %v", item.Name, err)
+	}
+	return err
+}
+
+// itemLanguages returns the target languages set for the item, if any.
+func (h *Handler) itemLanguages(itemID string) []string {
+	var languages []string
+	if _, err := h.Store.Get(itemLanguagesBucket, itemID, &languages); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return languages
+}
+
+// setItemLanguages sets the item's target languages. No languages removes
+// the item's own setting.
+func (h *Handler) setItemLanguages(itemID string, languages []lang.Tag) error {
+	if len(languages) == 0 {
+		return h.Store.Delete(itemLanguagesBucket, itemID)
+	}
+	values := make([]string, len(languages))
+	for i, language := range languages {
+		values[i] = language.String()
+	}
+	return h.Store.Put(itemLanguagesBucket, itemID, values)
+}
+
+func joinTags(tags []lang.Tag) string {
+	values := make([]string, len(tags))
+	for i, tag := range tags {
+		values[i] = tag.String()
+	}
+	return strings.Join(values, ", ")
+}
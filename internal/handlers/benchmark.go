@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/internal/translator"
+)
+
+const maxBenchmarkSamples = 50
+
+var defaultBenchmarkSamples = []string{
+	"Where were you last night?",
+	"I told you, I'm not going back there.",
+	"He's been acting strange ever since the accident.",
+	"Wait for me! I'll be right there.",
+}
+
+type benchmarkRow struct {
+	Source  string
+	Results []translator.CueResult
+}
+
+type benchmarkView struct {
+	Samples  string
+	Backends []translator.BenchmarkResult
+	Rows     []benchmarkRow
+}
+
+// BenchmarkHandler runs a sample of cues through every configured translator
+// backend and renders the output side by side.
+func (h *Handler) BenchmarkHandler(w http.ResponseWriter, r *http.Request) {
+	view := benchmarkView{
+		Samples: strings.Join(defaultBenchmarkSamples, "\n"),
+	}
+
+	if r.Method == http.MethodPost {
+		input := r.FormValue("samples")
+		samples := h.benchmarkSamples(input)
+		if len(samples) == 0 {
+			http.Error(w, "At least one sample cue is required", http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("Benchmarking %d backends with %d sample cues", len(h.Backends), len(samples))
+		view.Samples = input
+		view.Backends = translator.Benchmark(r.Context(), h.Backends, samples)
+
+		for i, sample := range samples {
+			row := benchmarkRow{Source: sample}
+			for _, backend := range view.Backends {
+				row.Results = append(row.Results, backend.Cues[i])
+			}
+			view.Rows = append(view.Rows, row)
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	render(w, r, http.StatusOK, "benchmark", view)
+}
+
+// benchmarkSamples accepts either pasted SRT content or one cue per line.
+func (h *Handler) benchmarkSamples(input string) []string {
+	var samples []string
+
+	if strings.Contains(input, "-->") {
+		entries, err := h.Parser.Parse([]byte(input))
+		if err == nil {
+			for _, entry := range entries {
+				samples = append(samples, entry.Text)
+			}
+		}
+	} else {
+		for _, line := range strings.Split(input, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				samples = append(samples, line)
+			}
+		}
+	}
+
+	if len(samples) > maxBenchmarkSamples {
+		samples = samples[:maxBenchmarkSamples]
+	}
+
+	return samples
+}
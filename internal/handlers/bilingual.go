@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// withBilingual returns a view of the handler that writes translated
+// subtitles bilingual, or not, whatever the configuration says.
+func (h *Handler) withBilingual(on bool) *Handler {
+	view := *h
+	view.bilingual = &on
+	return &view
+}
+
+// bilingualOutput reports whether translations keep the original text
+// above the translated text in each cue.
+func (h *Handler) bilingualOutput() bool {
+	if h.bilingual != nil {
+		return *h.bilingual
+	}
+	return h.Config().BilingualSubtitles
+}
+
+// bilingualOverride reads the request's "bilingual" value ("true" or
+// "false"). It returns nil when the request leaves it to the configuration.
+func bilingualOverride(r *http.Request) (*bool, error) {
+	value := r.FormValue("bilingual")
+	if value == "" {
+		return nil, nil
+	}
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid bilingual value %q (expected true or false)", value)
+	}
+	return &on, nil
+}
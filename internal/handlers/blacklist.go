@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/wanted"
+)
+
+// rejectedSubtitlesBucket is the subtitle blacklist: the subtitles rejected
+// for each item, keyed by item ID.
+const rejectedSubtitlesBucket = "rejected-subtitles"
+
+// opensubtitlesProvider names OpenSubtitles in the blacklist. Subtitle IDs
+// are the same whichever account finds them.
+const opensubtitlesProvider = "opensubtitles"
+
+// rejectedSubtitle is a subtitle rejected for an item, by discarding it or
+// through the API. Searches for the item never pick it on their own again;
+// it can still be picked by hand from the custom search.
+type rejectedSubtitle struct {
+	Provider   string `json:"provider"`
+	SubtitleID string `json:"subtitle_id"`
+	// Target is what the subtitle was rejected as, such as "zh-Hant" or
+	// "zh-Hant.forced", when known.
+	Target     string    `json:"target,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	RejectedAt time.Time `json:"rejected_at"`
+}
+
+var errNotRejected = errors.New("subtitle is not rejected for this item")
+
+// rejectedSubtitles returns the subtitles rejected for an item, oldest
+// first.
+func (h *Handler) rejectedSubtitles(itemID string) ([]rejectedSubtitle, error) {
+	var rejected []rejectedSubtitle
+	if _, err := h.Store.Get(rejectedSubtitlesBucket, itemID, &rejected); err != nil {
+		return nil, fmt.Errorf("failed to load rejected subtitles: %w", err)
+	}
+	for i := range rejected {
+		// Entries from before providers were recorded
+		if rejected[i].Provider == "" {
+			rejected[i].Provider = opensubtitlesProvider
+		}
+	}
+	return rejected, nil
+}
+
+// rejectedIDs returns the IDs of the provider's subtitles rejected for an
+// item, for leaving them out of searches.
+func (h *Handler) rejectedIDs(itemID, provider string) (map[string]bool, error) {
+	rejected, err := h.rejectedSubtitles(itemID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(rejected))
+	for _, entry := range rejected {
+		if entry.Provider == provider {
+			ids[entry.SubtitleID] = true
+		}
+	}
+	return ids, nil
+}
+
+// rejectSubtitle adds a subtitle to those rejected for an item. Rejecting
+// one again updates its reason and time.
+func (h *Handler) rejectSubtitle(itemID string, entry rejectedSubtitle) error {
+	rejected, err := h.rejectedSubtitles(itemID)
+	if err != nil {
+		return err
+	}
+	if entry.RejectedAt.IsZero() {
+		entry.RejectedAt = time.Now()
+	}
+	replaced := false
+	for i, existing := range rejected {
+		if existing.Provider == entry.Provider && existing.SubtitleID == entry.SubtitleID {
+			if entry.Target == "" {
+				entry.Target = existing.Target
+			}
+			rejected[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		rejected = append(rejected, entry)
+	}
+	if err := h.Store.Put(rejectedSubtitlesBucket, itemID, rejected); err != nil {
+		return fmt.Errorf("failed to save rejected subtitles: %w", err)
+	}
+	return nil
+}
+
+// unrejectSubtitle takes a subtitle off those rejected for an item.
+func (h *Handler) unrejectSubtitle(itemID, provider, subtitleID string) error {
+	rejected, err := h.rejectedSubtitles(itemID)
+	if err != nil {
+		return err
+	}
+	var kept []rejectedSubtitle
+	for _, entry := range rejected {
+		if entry.Provider != provider || entry.SubtitleID != subtitleID {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == len(rejected) {
+		return errNotRejected
+	}
+	if len(kept) == 0 {
+		err = h.Store.Delete(rejectedSubtitlesBucket, itemID)
+	} else {
+		err = h.Store.Put(rejectedSubtitlesBucket, itemID, kept)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save rejected subtitles: %w", err)
+	}
+	return nil
+}
+
+type blacklistRequest struct {
+	Provider   string `json:"provider"`
+	SubtitleID string `json:"subtitle_id"`
+	Language   string `json:"language"`
+	Forced     bool   `json:"forced"`
+	Reason     string `json:"reason"`
+}
+
+// blacklistAPI handles the subtitle blacklist of an item:
+// GET /api/v1/items/{id}/blacklist lists the rejected subtitles, POST
+// /api/v1/items/{id}/blacklist rejects "subtitle_id" (of "provider", which
+// defaults to OpenSubtitles) with an optional "reason" and the "language"
+// and "forced" it was rejected as, and POST
+// /api/v1/items/{id}/blacklist/remove takes one off the list. The POSTs
+// take a JSON body or the same fields as a form, in which case the browser
+// is sent back to the item page.
+func (h *Handler) blacklistAPI(w http.ResponseWriter, r *http.Request, itemID string, remove bool) {
+	if !remove && r.Method == http.MethodGet {
+		rejected, err := h.rejectedSubtitles(itemID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if rejected == nil {
+			rejected = []rejectedSubtitle{}
+		}
+		writeJSON(w, http.StatusOK, rejected)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req blacklistRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Provider = r.FormValue("provider")
+		req.SubtitleID = r.FormValue("subtitle_id")
+		req.Language = r.FormValue("language")
+		req.Forced = r.FormValue("forced") == "true"
+		req.Reason = r.FormValue("reason")
+	}
+	if req.SubtitleID == "" {
+		respond(w, r, http.StatusBadRequest, "A subtitle_id is required")
+		return
+	}
+	if req.Provider == "" {
+		req.Provider = opensubtitlesProvider
+	}
+
+	var err error
+	status := http.StatusOK
+	if remove {
+		err = h.unrejectSubtitle(itemID, req.Provider, req.SubtitleID)
+		if errors.Is(err, errNotRejected) {
+			respond(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+	} else {
+		entry := rejectedSubtitle{Provider: req.Provider, SubtitleID: req.SubtitleID, Reason: strings.TrimSpace(req.Reason)}
+		if req.Language != "" {
+			language := lang.Normalize(req.Language)
+			if language.IsZero() {
+				respond(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown language %q", req.Language))
+				return
+			}
+			entry.Target = wanted.Target{Language: language, Forced: req.Forced}.String()
+		}
+		err = h.rejectSubtitle(itemID, entry)
+		status = http.StatusCreated
+	}
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/items/"+itemID))
+		return
+	}
+	rejected, err := h.rejectedSubtitles(itemID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rejected == nil {
+		rejected = []rejectedSubtitle{}
+	}
+	writeJSON(w, status, rejected)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"subtitle-hunter/internal/events"
+	"subtitle-hunter/internal/jobs"
+)
+
+// ErrBudgetExhausted is returned by scheduled and command-line jobs that
+// would download or translate more subtitles than the daily budget allows.
+var ErrBudgetExhausted = errors.New("daily budget used up")
+
+// Usage counters charged against the daily budget.
+const (
+	downloadsCounter    = "downloads"
+	translationsCounter = "translations"
+)
+
+type budgetUsage struct {
+	Used int `json:"used"`
+	// Limit is 0 when no budget is set.
+	Limit   int     `json:"limit"`
+	Percent float64 `json:"percent"`
+}
+
+type budgetStatus struct {
+	Downloads    budgetUsage `json:"downloads"`
+	Translations budgetUsage `json:"translations"`
+	// Exhausted is set while scheduled hunts are held back until ResetAt.
+	Exhausted bool      `json:"exhausted"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+func (h *Handler) budgetStatus() budgetStatus {
+	usage := func(counter string, limit int) budgetUsage {
+		u := budgetUsage{Used: h.Usage.Today(counter), Limit: limit}
+		if limit > 0 {
+			u.Percent = min(float64(u.Used)/float64(limit)*100, 100)
+		}
+		return u
+	}
+
+	now := time.Now()
+	status := budgetStatus{
+		Downloads:    usage(downloadsCounter, h.Config().DailyDownloadBudget),
+		Translations: usage(translationsCounter, h.Config().DailyTranslationBudget),
+		ResetAt:      time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()),
+	}
+	for _, u := range []budgetUsage{status.Downloads, status.Translations} {
+		status.Exhausted = status.Exhausted || (u.Limit > 0 && u.Used >= u.Limit)
+	}
+	return status
+}
+
+// BudgetExhausted reports whether today's download or translation budget is
+// used up, so a scheduled run can leave the rest of its items for the next.
+func (h *Handler) BudgetExhausted() bool {
+	return h.budgetStatus().Exhausted
+}
+
+// budgeted reports whether the job is held to the daily budget. Jobs
+// started by hand are counted but never refused, except those of a batch
+// or campaign, which are meant for working through a backlog.
+func (h *Handler) budgeted() bool {
+	switch h.job.Trigger() {
+	case jobs.TriggerAuto, jobs.TriggerCLI, jobs.TriggerBatch, jobs.TriggerCampaign:
+		return true
+	}
+	return false
+}
+
+// checkBudget returns ErrBudgetExhausted when the job is held to the budget
+// and today's budget for counter is used up, without charging it.
+func (h *Handler) checkBudget(counter string, limit int) error {
+	if h.budgeted() && limit > 0 && h.Usage.Today(counter) >= limit {
+		h.Events.Publish(events.BudgetExhausted, counter)
+		return fmt.Errorf("%w: %d %s today", ErrBudgetExhausted, limit, counter)
+	}
+	return nil
+}
+
+// chargeBudget counts one subtitle against today's budget for counter,
+// failing with ErrBudgetExhausted instead when the job is held to the
+// budget and it is used up. The count is saved right away so a restart
+// doesn't hand out the budget again.
+func (h *Handler) chargeBudget(counter string, limit int) error {
+	if !h.budgeted() {
+		h.Usage.Add(counter, 1)
+	} else if !h.Usage.AddWithin(counter, 1, limit) {
+		h.Events.Publish(events.BudgetExhausted, counter)
+		return fmt.Errorf("%w: %d %s today", ErrBudgetExhausted, limit, counter)
+	}
+	if err := h.Usage.Flush(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,683 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"subtitle-hunter/internal/events"
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/wanted"
+)
+
+// campaignsBucket holds the backfill campaigns, keyed by ID.
+const campaignsBucket = "campaigns"
+
+// campaignCheckInterval is how often campaigns are worked on besides after
+// each scheduled run, so they carry on after midnight while automatic
+// hunting is off.
+const campaignCheckInterval = time.Hour
+
+// States of a campaign.
+const (
+	campaignActive    = "active"
+	campaignPaused    = "paused"
+	campaignFinished  = "finished"
+	campaignCancelled = "cancelled"
+)
+
+// States of an item of a campaign.
+const (
+	campaignPending = "pending"
+	campaignDone    = "done"
+	campaignFailed  = "failed"
+	campaignSkipped = "skipped"
+)
+
+// campaignScope is what a campaign covers: the items of the library, or of
+// one series, still missing a subtitle, or only those missing Language.
+type campaignScope struct {
+	SeriesID   string `json:"series_id,omitempty"`
+	SeriesName string `json:"series_name,omitempty"`
+	Language   string `json:"language,omitempty"`
+}
+
+type campaignItem struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	JobID  string `json:"job_id,omitempty"`
+	// Error says why the item failed or was skipped.
+	Error string `json:"error,omitempty"`
+}
+
+// campaignDay is what a campaign got through and used up on one day.
+type campaignDay struct {
+	Date         string `json:"date"`
+	Done         int    `json:"done"`
+	Failed       int    `json:"failed"`
+	Downloads    int    `json:"downloads"`
+	Translations int    `json:"translations"`
+	Characters   int    `json:"characters"`
+}
+
+// campaign works through a backlog of items over as many days as the daily
+// budget needs, in the background.
+type campaign struct {
+	ID         string         `json:"id"`
+	Scope      campaignScope  `json:"scope"`
+	State      string         `json:"state"`
+	CreatedAt  time.Time      `json:"created_at"`
+	FinishedAt time.Time      `json:"finished_at,omitempty"`
+	Items      []campaignItem `json:"items"`
+	// Days are in order, oldest first.
+	Days []campaignDay `json:"days"`
+}
+
+// day returns the record of date, adding it when it is new.
+func (c *campaign) day(date string) *campaignDay {
+	if n := len(c.Days); n > 0 && c.Days[n-1].Date == date {
+		return &c.Days[n-1]
+	}
+	c.Days = append(c.Days, campaignDay{Date: date})
+	return &c.Days[len(c.Days)-1]
+}
+
+var errCampaignNotFound = errors.New("campaign not found")
+
+// campaignState coordinates the campaign runner with the API.
+type campaignState struct {
+	// mu guards reading and saving campaigns.
+	mu sync.Mutex
+	// running is held while the runner works through campaigns.
+	running sync.Mutex
+	wake    chan struct{}
+}
+
+func newCampaignState() *campaignState {
+	return &campaignState{wake: make(chan struct{}, 1)}
+}
+
+// campaignProgress is a campaign's counts with its plan: PerDay items a day
+// by the daily budget (0 without one) and the day it should be done by.
+type campaignProgress struct {
+	Total   int     `json:"total"`
+	Pending int     `json:"pending"`
+	Done    int     `json:"done"`
+	Failed  int     `json:"failed"`
+	Skipped int     `json:"skipped"`
+	Percent float64 `json:"percent"`
+	PerDay  int     `json:"per_day"`
+	// ETA is zero while it can't be told, before a campaign without a
+	// budget has run.
+	ETA time.Time `json:"eta,omitempty"`
+}
+
+type campaignView struct {
+	campaign
+	Progress campaignProgress `json:"progress"`
+}
+
+type campaignsView struct {
+	Campaigns []campaignView
+	Series    []campaignScope
+	Languages []wanted.Target
+	Budget    budgetStatus
+}
+
+type campaignRequest struct {
+	SeriesID string `json:"series_id"`
+	Language string `json:"language"`
+}
+
+// campaignCapacity returns how many items campaigns can get through in a
+// day, and today, by the daily budget: each item takes at least one
+// download and, when translated, one translation. It is 0 without a budget.
+func (h *Handler) campaignCapacity() (perDay, today int) {
+	status := h.budgetStatus()
+	for _, u := range []budgetUsage{status.Downloads, status.Translations} {
+		if u.Limit <= 0 {
+			continue
+		}
+		if perDay == 0 || u.Limit < perDay {
+			perDay = u.Limit
+			today = max(u.Limit-u.Used, 0)
+		}
+	}
+	return perDay, today
+}
+
+func (h *Handler) campaignView(c campaign) campaignView {
+	view := campaignView{campaign: c}
+	p := &view.Progress
+	p.Total = len(c.Items)
+	for _, item := range c.Items {
+		switch item.Status {
+		case campaignPending:
+			p.Pending++
+		case campaignDone:
+			p.Done++
+		case campaignFailed:
+			p.Failed++
+		case campaignSkipped:
+			p.Skipped++
+		}
+	}
+	if p.Total > 0 {
+		p.Percent = float64(p.Total-p.Pending) / float64(p.Total) * 100
+	}
+
+	perDay, today := h.campaignCapacity()
+	p.PerDay = perDay
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case c.State == campaignFinished:
+		p.ETA = c.FinishedAt
+	case c.State == campaignCancelled || p.Pending == 0:
+	case perDay > 0:
+		// The soonest it can be, as other hunts share the budget
+		days := (max(p.Pending-today, 0) + perDay - 1) / perDay
+		p.ETA = midnight.AddDate(0, 0, days+1)
+	case len(c.Days) > 0:
+		// Without a budget, go by how much a day it got through so far
+		processed := p.Done + p.Failed
+		rate := max(processed/len(c.Days), 1)
+		p.ETA = midnight.AddDate(0, 0, (p.Pending+rate-1)/rate)
+	}
+	return view
+}
+
+func (h *Handler) loadCampaign(id string) (campaign, bool, error) {
+	var c campaign
+	ok, err := h.Store.Get(campaignsBucket, id, &c)
+	if err != nil {
+		return c, false, fmt.Errorf("failed to load campaign: %w", err)
+	}
+	return c, ok, nil
+}
+
+// listCampaigns returns every campaign, oldest first.
+func (h *Handler) listCampaigns() ([]campaign, error) {
+	ids, err := h.Store.Keys(campaignsBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	sort.Strings(ids)
+
+	var all []campaign
+	for _, id := range ids {
+		c, ok, err := h.loadCampaign(id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			all = append(all, c)
+		}
+	}
+	return all, nil
+}
+
+// updateCampaign changes a saved campaign with fn, which returns false to
+// leave it as it was.
+func (h *Handler) updateCampaign(id string, fn func(c *campaign) bool) (campaign, error) {
+	h.campaignState.mu.Lock()
+	defer h.campaignState.mu.Unlock()
+
+	c, ok, err := h.loadCampaign(id)
+	if err != nil {
+		return c, err
+	}
+	if !ok {
+		return c, errCampaignNotFound
+	}
+	if !fn(&c) {
+		return c, nil
+	}
+	if err := h.Store.Put(campaignsBucket, c.ID, c); err != nil {
+		return c, fmt.Errorf("failed to save campaign: %w", err)
+	}
+	return c, nil
+}
+
+// planCampaign lists the items of the library in scope that still need a
+// subtitle. Items of series whose hunting is paused are left out.
+func (h *Handler) planCampaign(ctx context.Context, scope campaignScope) (campaign, error) {
+	items, err := h.Library.Items(ctx)
+	if err != nil {
+		return campaign{}, fmt.Errorf("failed to fetch media: %w", err)
+	}
+	rows, err := h.Wanted.Compute(items)
+	if err != nil {
+		return campaign{}, fmt.Errorf("failed to compute subtitle status: %w", err)
+	}
+
+	now := time.Now()
+	c := campaign{ID: fmt.Sprintf("%019d", now.UnixNano()), Scope: scope, State: campaignActive, CreatedAt: now}
+	for _, row := range rows {
+		item := row.Item
+		if scope.SeriesID != "" && item.SeriesID != scope.SeriesID {
+			continue
+		}
+		if !campaignWants(row, scope.Language) || h.HuntingPaused(&item) {
+			continue
+		}
+		if scope.SeriesID != "" {
+			c.Scope.SeriesName = item.SeriesName
+		}
+		name := item.Name
+		if item.SeriesName != "" {
+			name = fmt.Sprintf("%s S%02dE%02d %s", item.SeriesName, item.ParentIndexNumber, item.IndexNumber, item.Name)
+		}
+		c.Items = append(c.Items, campaignItem{ID: item.ID, Name: name, Status: campaignPending})
+	}
+	return c, nil
+}
+
+// campaignWants reports whether row still needs a subtitle in language, or
+// in any target language when language is empty.
+func campaignWants(row wanted.Row, language string) bool {
+	if language == "" {
+		return row.Missing()
+	}
+	for _, cell := range row.Cells {
+		if cell.Language.String() == language && !cell.Forced && cell.Status == wanted.StatusMissing {
+			return true
+		}
+	}
+	return false
+}
+
+// RunCampaigns works through the active campaigns after each scheduled run
+// and every campaignCheckInterval, until ctx is done. Each goes on until
+// it is done, automatic hunting is paused or the daily budget runs out, so
+// a big backlog is spread over as many days as the budget needs.
+func (h *Handler) RunCampaigns(ctx context.Context) {
+	runs, unsubscribe := h.Events.Subscribe()
+	defer unsubscribe()
+
+	for {
+		h.advanceCampaigns(ctx)
+
+		timer := time.NewTimer(campaignCheckInterval)
+	wait:
+		for {
+			select {
+			case event, ok := <-runs:
+				if !ok {
+					timer.Stop()
+					return
+				}
+				if event.Type == events.RunFinished {
+					break wait
+				}
+			case <-h.campaignState.wake:
+				break wait
+			case <-timer.C:
+				break wait
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+		timer.Stop()
+	}
+}
+
+// wakeCampaigns has the runner look at the campaigns now.
+func (h *Handler) wakeCampaigns() {
+	select {
+	case h.campaignState.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (h *Handler) advanceCampaigns(ctx context.Context) {
+	if !h.campaignState.running.TryLock() {
+		return
+	}
+	defer h.campaignState.running.Unlock()
+
+	all, err := h.listCampaigns()
+	if err != nil {
+		log.Printf("Campaigns: %v", err)
+		return
+	}
+	for _, c := range all {
+		if c.State != campaignActive {
+			continue
+		}
+		if !h.advanceCampaign(ctx, c) {
+			return
+		}
+	}
+}
+
+// advanceCampaign hunts the pending items of c, as many at once as the
+// worker pool allows. It returns false when the rest must wait: the budget
+// is used up, hunting is paused or ctx is done.
+func (h *Handler) advanceCampaign(ctx context.Context, c campaign) bool {
+	items, err := h.Library.Items(ctx)
+	if err != nil {
+		log.Printf("Campaign %s: failed to fetch media: %v", c.ID, err)
+		return false
+	}
+	library := make(map[string]*jellyfin.MediaItem, len(items))
+	for i := range items {
+		library[items[i].ID] = &items[i]
+	}
+
+	var pending []int
+	for i, item := range c.Items {
+		if item.Status == campaignPending {
+			pending = append(pending, i)
+		}
+	}
+	log.Printf("Campaign %s: %d of %d items left", c.ID, len(pending), len(c.Items))
+
+	var stopped atomic.Bool
+	jobs.RunEach(ctx, h.Config().WorkerPoolSize, len(pending), func(i int) bool {
+		current, _, err := h.loadCampaign(c.ID)
+		if err != nil || current.State != campaignActive || h.SchedulerPaused() || h.BudgetExhausted() {
+			stopped.Store(true)
+			return false
+		}
+		index := pending[i]
+		if !h.huntCampaignItem(ctx, c.ID, index, c.Items[index], library[c.Items[index].ID], c.Scope.Language) {
+			stopped.Store(true)
+			return false
+		}
+		return true
+	})
+
+	finished, err := h.updateCampaign(c.ID, func(c *campaign) bool {
+		for _, item := range c.Items {
+			if item.Status == campaignPending {
+				return false
+			}
+		}
+		c.State = campaignFinished
+		c.FinishedAt = time.Now()
+		return true
+	})
+	if err != nil {
+		log.Printf("Campaign %s: %v", c.ID, err)
+	} else if finished.State == campaignFinished {
+		log.Printf("Campaign %s finished", c.ID)
+	}
+	return !stopped.Load() && ctx.Err() == nil
+}
+
+// huntCampaignItem hunts one item of a campaign as a campaign job and
+// records the outcome. It returns false once the budget is used up.
+func (h *Handler) huntCampaignItem(ctx context.Context, campaignID string, index int, entry campaignItem, item *jellyfin.MediaItem, language string) bool {
+	record := func(status, jobID, reason string, report *jobs.Report) {
+		_, err := h.updateCampaign(campaignID, func(c *campaign) bool {
+			c.Items[index].Status = status
+			c.Items[index].JobID = jobID
+			c.Items[index].Error = reason
+			if status != campaignDone && status != campaignFailed {
+				return true
+			}
+			day := c.day(time.Now().Format("2006-01-02"))
+			if status == campaignDone {
+				day.Done++
+			} else {
+				day.Failed++
+			}
+			if report != nil {
+				day.Downloads += report.ProviderDownloads
+				day.Characters += report.CharactersTranslated
+				if report.CharactersTranslated > 0 {
+					day.Translations++
+				}
+			}
+			return true
+		})
+		if err != nil {
+			log.Printf("Campaign %s: %v", campaignID, err)
+		}
+	}
+
+	if item == nil {
+		record(campaignSkipped, "", "no longer in the library", nil)
+		return true
+	}
+	if h.HuntingPaused(item) {
+		record(campaignSkipped, "", "hunting is paused for the series", nil)
+		return true
+	}
+	rows, err := h.Wanted.Compute([]jellyfin.MediaItem{*item})
+	if err == nil && len(rows) == 1 && !campaignWants(rows[0], language) {
+		record(campaignSkipped, "", "already has its subtitle", nil)
+		return true
+	}
+
+	jobID, _, err := h.Hunt(ctx, item, jobs.TriggerCampaign)
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, ErrBudgetExhausted) {
+		// Left pending for the next day
+		return false
+	}
+	var report *jobs.Report
+	if jobID != "" {
+		if saved, ok, _ := h.Jobs.Get(jobID); ok {
+			report = &saved
+		}
+	}
+	if err != nil {
+		record(campaignFailed, jobID, err.Error(), report)
+	} else {
+		record(campaignDone, jobID, "", report)
+	}
+	return true
+}
+
+func (h *Handler) campaignsView(ctx context.Context) (campaignsView, error) {
+	all, err := h.listCampaigns()
+	if err != nil {
+		return campaignsView{}, err
+	}
+	view := campaignsView{Budget: h.budgetStatus()}
+	// Newest first
+	for i := len(all) - 1; i >= 0; i-- {
+		view.Campaigns = append(view.Campaigns, h.campaignView(all[i]))
+	}
+
+	for _, target := range h.Wanted.Targets() {
+		if !target.Forced {
+			view.Languages = append(view.Languages, target)
+		}
+	}
+	items, err := h.Library.Items(ctx)
+	if err != nil {
+		return view, fmt.Errorf("failed to fetch media: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item.SeriesID != "" && !seen[item.SeriesID] {
+			seen[item.SeriesID] = true
+			view.Series = append(view.Series, campaignScope{SeriesID: item.SeriesID, SeriesName: item.SeriesName})
+		}
+	}
+	sort.Slice(view.Series, func(i, j int) bool { return view.Series[i].SeriesName < view.Series[j].SeriesName })
+	return view, nil
+}
+
+// CampaignsHandler serves the campaigns page: each campaign's progress,
+// the day it should be done by and what it used up each day, with a form
+// to start a new one.
+func (h *Handler) CampaignsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	view, err := h.campaignsView(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	render(w, r, http.StatusOK, "campaigns", view)
+}
+
+// CampaignsAPIHandler serves GET /api/v1/campaigns, the campaigns with
+// their progress, POST /api/v1/campaigns, which plans and starts a
+// campaign, GET /api/v1/campaigns/{id}, one campaign with its items, and
+// POST /api/v1/campaigns/{id}/{pause,resume,cancel}.
+func (h *Handler) CampaignsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/campaigns"), "/")
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			all, err := h.listCampaigns()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			views := []campaignView{}
+			for _, c := range all {
+				view := h.campaignView(c)
+				// The items are only listed for a single campaign
+				view.Items = nil
+				views = append(views, view)
+			}
+			writeJSON(w, http.StatusOK, views)
+		case http.MethodPost:
+			h.startCampaign(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, action, _ := strings.Cut(path, "/")
+	if action == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		c, ok, err := h.loadCampaign(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, h.campaignView(c))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var from []string
+	var to string
+	switch action {
+	case "pause":
+		from, to = []string{campaignActive}, campaignPaused
+	case "resume":
+		from, to = []string{campaignPaused}, campaignActive
+	case "cancel":
+		from, to = []string{campaignActive, campaignPaused}, campaignCancelled
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	changed := false
+	c, err := h.updateCampaign(id, func(c *campaign) bool {
+		for _, state := range from {
+			if c.State == state {
+				c.State = to
+				changed = true
+			}
+		}
+		return changed
+	})
+	if errors.Is(err, errCampaignNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !changed {
+		respond(w, r, http.StatusConflict, fmt.Sprintf("The campaign is %s", c.State))
+		return
+	}
+	if to == campaignActive {
+		h.wakeCampaigns()
+	}
+
+	if wantsHTML(r) {
+		redirect(w, r, "/campaigns")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.campaignView(c))
+}
+
+func (h *Handler) startCampaign(w http.ResponseWriter, r *http.Request) {
+	var req campaignRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.SeriesID = r.FormValue("series_id")
+		req.Language = r.FormValue("language")
+	}
+
+	scope := campaignScope{SeriesID: req.SeriesID}
+	if req.Language != "" {
+		language := lang.Normalize(req.Language)
+		if language.IsZero() {
+			respond(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown language %q", req.Language))
+			return
+		}
+		scope.Language = language.String()
+	}
+
+	h.campaignState.mu.Lock()
+	c, err := h.planCampaign(r.Context(), scope)
+	if err == nil && len(c.Items) > 0 {
+		err = h.Store.Put(campaignsBucket, c.ID, c)
+	}
+	h.campaignState.mu.Unlock()
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(c.Items) == 0 {
+		respond(w, r, http.StatusBadRequest, "Nothing in this scope needs a subtitle")
+		return
+	}
+
+	log.Printf("Campaign %s started for %d items", c.ID, len(c.Items))
+	h.wakeCampaigns()
+
+	if wantsHTML(r) {
+		redirect(w, r, "/campaigns")
+		return
+	}
+	view := h.campaignView(c)
+	view.Items = nil
+	writeJSON(w, http.StatusCreated, view)
+}
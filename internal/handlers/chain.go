@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"subtitle-hunter/config"
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/wanted"
+)
+
+// errStepSkipped is returned by steps that don't apply to an item.
+var errStepSkipped = errors.New("skipped")
+
+// fallbackChain returns the steps tried for a subtitle in target: the chain
+// configured for the language, or else the built-in one. Traditional Chinese
+// is extracted from an embedded track (when enabled), downloaded, converted
+// from Simplified Chinese for Chinese audio, translated from English and
+// finally transcribed (when whisper is enabled); other languages are only
+// downloaded.
+func (h *Handler) fallbackChain(item *jellyfin.MediaItem, target lang.Tag) []config.ChainStep {
+	cfg := h.Config()
+	if chain := cfg.FallbackChain(target); len(chain) > 0 {
+		return chain
+	}
+	if target != lang.TraditionalChinese {
+		return []config.ChainStep{{Name: config.StepOpenSubtitles, Kind: config.StepOpenSubtitles}}
+	}
+
+	var chain []config.ChainStep
+	if cfg.EnableEmbeddedExtraction {
+		chain = append(chain, config.ChainStep{Name: config.StepEmbedded, Kind: config.StepEmbedded})
+	}
+	chain = append(chain, config.ChainStep{Name: config.StepOpenSubtitles, Kind: config.StepOpenSubtitles})
+	if chineseAudio(item) {
+		chain = append(chain, config.ChainStep{Name: config.StepConvertFrom + lang.SimplifiedChinese.String(), Kind: config.StepConvertFrom, Source: lang.SimplifiedChinese})
+	}
+	chain = append(chain, config.ChainStep{Name: config.StepTranslateFrom + lang.English.String(), Kind: config.StepTranslateFrom, Source: lang.English})
+	if cfg.EnableWhisper {
+		chain = append(chain, config.ChainStep{Name: config.StepWhisper, Kind: config.StepWhisper})
+	}
+	return chain
+}
+
+// processItem runs the fallback chain for a subtitle in target, one step at
+// a time until one saves a subtitle. Each step's outcome goes into the job
+// report. Series that disallow machine translation skip every step but the
+// download.
+func (h *Handler) processItem(ctx context.Context, item *jellyfin.MediaItem, target lang.Tag) (*ProcessResult, error) {
+	var outcomes []string
+	for _, step := range h.fallbackChain(item, target) {
+		result, err := h.runStep(ctx, item, target, step)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		report := jobs.StepReport{Language: target.String(), Step: step.Name}
+		switch {
+		case err == nil:
+			report.Outcome = jobs.StepSucceeded
+			report.Detail = result.Source
+		case errors.Is(err, errStepSkipped):
+			report.Outcome = jobs.StepSkipped
+			report.Detail = strings.TrimPrefix(err.Error(), errStepSkipped.Error()+": ")
+		default:
+			report.Outcome = jobs.StepFailed
+			report.Detail = err.Error()
+		}
+		h.job.Step(report)
+		h.job.Logf("Fallback step %s for %s subtitle of %s %s: %s", step.Name, target, item.Name, report.Outcome, report.Detail)
+
+		if err == nil {
+			return result, nil
+		}
+		if errors.Is(err, ErrBudgetExhausted) {
+			return nil, err
+		}
+		outcomes = append(outcomes, fmt.Sprintf("%s %s: %s", step.Name, report.Outcome, report.Detail))
+	}
+
+	return nil, fmt.Errorf("%w in %s (%s)", errNoSubtitles, target.DisplayName(), strings.Join(outcomes, "; "))
+}
+
+// runStep runs one step of a fallback chain, returning an error wrapping
+// errStepSkipped when the step doesn't apply to the item.
+func (h *Handler) runStep(ctx context.Context, item *jellyfin.MediaItem, target lang.Tag, step config.ChainStep) (*ProcessResult, error) {
+	videoPath := h.itemVideoPath(item)
+
+	if step.Kind != config.StepOpenSubtitles && !h.translationAllowed(item) {
+		return nil, fmt.Errorf("%w: machine translation is turned off", errStepSkipped)
+	}
+	if step.Kind == config.StepTranslateFrom && !step.Source.IsChinese() && !h.englishTranslationAllowed(item) {
+		return nil, fmt.Errorf("%w: the audio is Chinese, so %s subtitles are only translated when asked for", errStepSkipped, step.Source.DisplayName())
+	}
+
+	switch step.Kind {
+	case config.StepEmbedded:
+		return h.processEmbeddedTrack(ctx, item, videoPath, h.embeddedSourceLanguages(item))
+	case config.StepWhisper:
+		return h.processWhisper(ctx, item, videoPath)
+	case config.StepOpenSubtitles:
+		return h.processDirectDownload(ctx, item, wanted.Target{Language: target})
+	}
+
+	sub, err := h.findSubtitle(ctx, item, wanted.Target{Language: step.Source})
+	if err != nil {
+		return nil, fmt.Errorf("no %s subtitle: %w", step.Source.DisplayName(), err)
+	}
+	if step.Kind == config.StepTranslateFrom {
+		if err := h.approveTranslation(ctx, item, fmt.Sprintf("Translate the %s subtitle into %s", step.Source.DisplayName(), target.DisplayName())); err != nil {
+			return nil, err
+		}
+	}
+	location, report, err := h.translateAndSaveSubtitleFrom(ctx, item, sub, videoPath, step.Source)
+	if err != nil {
+		return nil, err
+	}
+	return &ProcessResult{SaveLocation: location, Source: stepSource(step), Report: &report, SubtitleID: sub.ID}, nil
+}
+
+// stepSource names where a subtitle converted or translated by step came
+// from.
+func stepSource(step config.ChainStep) string {
+	switch step.Source {
+	case lang.English:
+		return "OpenSubtitles (translated)"
+	case lang.SimplifiedChinese:
+		return "OpenSubtitles (converted from Simplified)"
+	}
+	if step.Kind == config.StepConvertFrom {
+		return fmt.Sprintf("OpenSubtitles (converted from %s)", step.Source.DisplayName())
+	}
+	return fmt.Sprintf("OpenSubtitles (translated from %s)", step.Source.DisplayName())
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/release"
+	"subtitle-hunter/internal/wanted"
+)
+
+// itemHistoryLimit is the number of an item's past jobs its page shows.
+const itemHistoryLimit = 20
+
+// subtitleFileExtensions are the files listed as an item's subtitles.
+var subtitleFileExtensions = map[string]bool{".srt": true, ".ass": true, ".ssa": true, ".vtt": true, ".sub": true}
+
+// subtitleFile is a subtitle file named after an item's video, next to the
+// video or in the downloads directory.
+type subtitleFile struct {
+	Name string
+	Path string
+	// Location is "media" or "downloads".
+	Location string
+	// Target is the part of the name between the video's name and the
+	// extension, such as "zh-Hant" or "zh-Hant.forced".
+	Target   string
+	Size     int64
+	Modified time.Time
+	// Listed is set when Jellyfin reports the file as one of the item's
+	// subtitle streams.
+	Listed bool
+}
+
+// matches reports whether the file is a subtitle for target.
+func (f subtitleFile) matches(target wanted.Target) bool {
+	language := strings.TrimSuffix(f.Target, wanted.ForcedSuffix)
+	forced := language != f.Target
+	return forced == target.Forced && lang.Normalize(language).Matches(target.Language)
+}
+
+// itemCell is the status of one target on the item page, with the reason
+// the item has it.
+type itemCell struct {
+	wanted.Cell
+	Reason string
+	// Requested is set while a viewer's request for the subtitle waits
+	// for approval.
+	Requested bool
+}
+
+type itemView struct {
+	Item *jellyfin.MediaItem
+	// Video is the video file subtitles are saved for, and VideoPath where
+	// this container finds it, when it differs from the path Jellyfin
+	// reports.
+	Video     string
+	VideoPath string
+	// Versions are the item's other versions.
+	Versions []itemVersion
+	// Release is what the video's file name says about its release.
+	Release   release.Info
+	Audio     []jellyfin.MediaStream
+	Subtitles []jellyfin.MediaStream
+	Files     []subtitleFile
+	// Rejected are the subtitles searches for the item leave out.
+	Rejected []rejectedSubtitle
+	Cells    []itemCell
+	Running  []jobs.Report
+	History  []jobs.Report
+	Paused   bool
+	// Replaced is set when a subtitle was saved for a video file the item
+	// no longer has.
+	Replaced  bool
+	Translate bool
+	Return    string
+}
+
+// itemVersion is another version of an item than the one subtitles are
+// saved for. Skipped is set when it doesn't get them because another
+// version is preferred.
+type itemVersion struct {
+	Path    string
+	Skipped bool
+}
+
+// itemPage serves GET /items/{id}: where the item's video is, the audio
+// and subtitle streams Jellyfin reports, the subtitle files on disk, the
+// status of each target with the reason for it, the jobs run for the item
+// and the actions that can be taken, so it is plain why an item is listed
+// as missing a subtitle.
+func (h *Handler) itemPage(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+
+	view := itemView{
+		Item:      item,
+		Video:     h.itemVideoPath(item),
+		Release:   release.Parse(h.itemVideoPath(item)),
+		Files:     h.subtitleFiles(item),
+		Paused:    h.HuntingPaused(item),
+		Translate: h.translationAllowed(item),
+		Return:    "/items/" + item.ID,
+	}
+	if containerPath := h.containerPath(item, h.itemVideoPath(item)); containerPath != h.itemVideoPath(item) {
+		view.VideoPath = containerPath
+	}
+	saved := h.Wanted.Versions(*item)
+	for _, version := range item.Versions("") {
+		if version.Path != view.Video {
+			view.Versions = append(view.Versions, itemVersion{Path: version.Path, Skipped: len(saved) == 1})
+		}
+	}
+	for _, stream := range item.MediaStreams {
+		switch stream.Type {
+		case "Audio":
+			view.Audio = append(view.Audio, stream)
+		case "Subtitle":
+			view.Subtitles = append(view.Subtitles, stream)
+		}
+	}
+
+	rows, err := h.Wanted.Compute([]jellyfin.MediaItem{*item})
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to compute subtitle status: %v", err))
+		return
+	}
+	requested := h.requestedTargets()
+	for _, cell := range rows[0].Cells {
+		view.Cells = append(view.Cells, itemCell{Cell: cell, Reason: cellReason(cell, view.Files), Requested: requested[item.ID+"/"+cell.Target.String()]})
+	}
+	view.Replaced = rows[0].Replaced()
+
+	for _, report := range h.Jobs.RunningReports() {
+		if report.ItemID == item.ID {
+			view.Running = append(view.Running, report)
+		}
+	}
+	if view.Rejected, err = h.rejectedSubtitles(item.ID); err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if view.History, err = h.Jobs.ForItem(item.ID, itemHistoryLimit); err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to load job reports: %v", err))
+		return
+	}
+
+	render(w, r, http.StatusOK, "item", view)
+}
+
+// cellReason explains the status of a target. A missing subtitle whose file
+// is on disk hasn't been picked up by Jellyfin yet.
+func cellReason(cell wanted.Cell, files []subtitleFile) string {
+	switch cell.Status {
+	case wanted.StatusIgnored:
+		return "Marked as not wanted"
+	case wanted.StatusEmbedded:
+		return "Jellyfin reports a subtitle stream in the video"
+	case wanted.StatusExternal:
+		return "Jellyfin reports a subtitle file next to the video"
+	case wanted.StatusDownloaded:
+		return "Downloaded by Subtitle Hunter"
+	case wanted.StatusTranslated:
+		return "Translated by Subtitle Hunter"
+	}
+	if len(cell.NewVersions) > 0 {
+		return "Saved by Subtitle Hunter, but versions of the video were added since; a hunt saves it next to them too"
+	}
+
+	for _, file := range files {
+		if file.matches(cell.Target) {
+			return "A subtitle file is on disk, but Jellyfin doesn't list it yet; it counts once Jellyfin scans the item again"
+		}
+	}
+	return "Jellyfin reports no subtitle in this language and Subtitle Hunter hasn't saved one"
+}
+
+// subtitleFiles lists the subtitle files named after the item's video, next
+// to the video and in the downloads directory. Directories that can't be
+// read are left out.
+func (h *Handler) subtitleFiles(item *jellyfin.MediaItem) []subtitleFile {
+	videoPath := h.itemVideoPath(item)
+	if videoPath == "" {
+		return nil
+	}
+	cfg := h.Config()
+	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+
+	listed := make(map[string]bool)
+	for _, stream := range item.MediaStreams {
+		if stream.IsExternal && stream.Path != "" {
+			listed[h.containerPath(item, stream.Path)] = true
+		}
+	}
+
+	mediaDir := filepath.Dir(h.containerPath(item, videoPath))
+	locations := []struct{ dir, name string }{{mediaDir, "media"}}
+	if filepath.Clean(cfg.SubtitleDirectory) != filepath.Clean(mediaDir) {
+		locations = append(locations, struct{ dir, name string }{cfg.SubtitleDirectory, "downloads"})
+	}
+
+	var files []subtitleFile
+	for _, location := range locations {
+		entries, err := os.ReadDir(location.dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			if !entry.Type().IsRegular() || !strings.HasPrefix(name, base+".") || !subtitleFileExtensions[strings.ToLower(ext)] {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(location.dir, name)
+			files = append(files, subtitleFile{
+				Name:     name,
+				Path:     path,
+				Location: location.name,
+				Target:   strings.TrimPrefix(strings.TrimPrefix(strings.TrimSuffix(name, ext), base), "."),
+				Size:     info.Size(),
+				Modified: info.ModTime(),
+				Listed:   listed[path],
+			})
+		}
+	}
+	return files
+}
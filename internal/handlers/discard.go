@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/wanted"
+)
+
+// errNothingToDiscard is returned when an item has no saved subtitle for
+// the target.
+var errNothingToDiscard = errors.New("nothing to discard")
+
+type discardRequest struct {
+	Language string `json:"language"`
+	Forced   bool   `json:"forced"`
+	// Delete removes the file rather than keeping it under another name.
+	Delete bool `json:"delete"`
+	// Reason is kept with the rejected subtitle.
+	Reason string `json:"reason"`
+}
+
+type discardResponse struct {
+	JobID     string   `json:"job_id"`
+	Discarded []string `json:"discarded"`
+	// Rejected is the subtitle searches for the item leave out from now
+	// on, when the discarded one came from a search.
+	Rejected string `json:"rejected,omitempty"`
+	Replaced bool   `json:"replaced"`
+	Message  string `json:"message"`
+}
+
+// discardAPI handles POST /api/v1/items/{id}/discard, for a saved subtitle
+// that turned out to be wrong. It archives the item's subtitle files for
+// "language" (plus "forced"), or deletes them with "delete" set, forgets
+// the recorded result, rejects the subtitle it was made from for the item
+// and hunts the target again, leaving out every subtitle rejected so far.
+// It takes a JSON body or the same fields as a form, in which case the
+// browser is sent back to the item page.
+func (h *Handler) discardAPI(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req discardRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Language = r.FormValue("language")
+		req.Forced = r.FormValue("forced") == "true"
+		req.Delete = r.FormValue("delete") == "true"
+		req.Reason = r.FormValue("reason")
+	}
+	if req.Reason = strings.TrimSpace(req.Reason); req.Reason == "" {
+		req.Reason = "Discarded as wrong"
+	}
+	language := lang.TraditionalChinese
+	if req.Language != "" {
+		if language = lang.Normalize(req.Language); language.IsZero() {
+			respond(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown language %q", req.Language))
+			return
+		}
+	}
+	target := wanted.Target{Language: language, Forced: req.Forced}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		log.Printf("Error getting item details for %s: %v", itemID, err)
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+
+	response := discardResponse{Discarded: []string{}}
+	discarded := false
+	jobID, result, err := h.RunJob(r.Context(), item, jobs.TriggerManual, func(h *Handler, ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error) {
+		files, rejected, err := h.discardSubtitle(item, target, req.Delete, req.Reason)
+		if err != nil {
+			return nil, err
+		}
+		discarded = true
+		response.Discarded = files
+		response.Rejected = rejected
+		// Jellyfin drops the discarded file whether or not a new one is found
+		defer h.refreshMetadata(ctx, item)
+		return h.huntTarget(ctx, item, target)
+	})
+	response.JobID = jobID
+	if jobID != "" {
+		w.Header().Set("X-Job-ID", jobID)
+	}
+	if !discarded {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errNothingToDiscard) {
+			status = http.StatusNotFound
+		}
+		respond(w, r, status, err.Error())
+		return
+	}
+
+	if err != nil {
+		response.Message = fmt.Sprintf("Discarded the %s subtitle, but found no other: %v", target, err)
+	} else {
+		response.Replaced = true
+		response.Message = result.Message(h.Config().SubtitleDirectory)
+	}
+	if wantsHTML(r) {
+		if response.Replaced {
+			redirect(w, r, returnPath(r, "/items/"+item.ID))
+			return
+		}
+		respond(w, r, http.StatusOK, response.Message)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// discardSubtitle archives or deletes the item's subtitle files for target,
+// forgets the recorded result and rejects the subtitle it was made from for
+// reason. It returns the files discarded and the subtitle rejected, if any.
+func (h *Handler) discardSubtitle(item *jellyfin.MediaItem, target wanted.Target, remove bool, reason string) ([]string, string, error) {
+	result, recorded, err := h.Wanted.Result(item.ID, target)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var discarded []string
+	for _, file := range h.subtitleFiles(item) {
+		if file.Target != target.String() {
+			continue
+		}
+		if remove {
+			err = os.Remove(file.Path)
+		} else {
+			// Players and Jellyfin only see subtitle extensions, so the
+			// renamed file is out of their way
+			err = os.Rename(file.Path, fmt.Sprintf("%s.%s.discarded", file.Path, time.Now().Format("20060102-150405")))
+		}
+		if err != nil {
+			return discarded, "", fmt.Errorf("failed to discard %s: %w", file.Name, err)
+		}
+		if remove {
+			h.job.Logf("Deleted %s", file.Path)
+		} else {
+			h.job.Logf("Archived %s", file.Path)
+		}
+		discarded = append(discarded, file.Path)
+	}
+	if len(discarded) == 0 && !recorded {
+		return nil, "", fmt.Errorf("%w: no saved %s subtitle", errNothingToDiscard, target)
+	}
+
+	if recorded {
+		if err := h.Wanted.ForgetResult(item.ID, target); err != nil {
+			return discarded, "", fmt.Errorf("failed to forget subtitle result: %w", err)
+		}
+	}
+	h.Library.Invalidate(item.ID)
+
+	if result.SubtitleID == "" {
+		h.job.Logf("The discarded subtitle didn't come from a search, so there is nothing to leave out")
+		return discarded, "", nil
+	}
+	entry := rejectedSubtitle{Provider: opensubtitlesProvider, SubtitleID: result.SubtitleID, Target: target.String(), Reason: reason}
+	if err := h.rejectSubtitle(item.ID, entry); err != nil {
+		return discarded, "", err
+	}
+	h.job.Logf("Rejected subtitle %s for this item", result.SubtitleID)
+	return discarded, result.SubtitleID, nil
+}
+
+// huntTarget hunts a single target of an item as HuntItem does, whatever
+// its status, and records the result.
+func (h *Handler) huntTarget(ctx context.Context, item *jellyfin.MediaItem, target wanted.Target) (*ProcessResult, error) {
+	var result *ProcessResult
+	var err error
+	if target.Forced {
+		result, err = h.processDirectDownload(ctx, item, target)
+	} else {
+		result, err = h.processItem(ctx, item, target.Language)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := h.Wanted.RecordResult(item.ID, target, result.Record(item)); err != nil {
+		h.job.Logf("Warning: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,477 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/wanted"
+)
+
+// DownloadedFile is a file in the downloads directory, where subtitles are
+// saved when the video's own directory can't be written.
+type DownloadedFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	// Item is the library item whose video the file is named after, if it
+	// is still in the library; Target is the rest of the name, e.g.
+	// "zh-Hant" or "zh-Hant.forced".
+	Item   *jellyfin.MediaItem `json:"-"`
+	ItemID string              `json:"item_id,omitempty"`
+	Target string              `json:"target,omitempty"`
+	// Video is the file of the item's version the file is named after.
+	Video string `json:"-"`
+	// Destination is where the file belongs next to the video, through the
+	// path mappings.
+	Destination string `json:"destination,omitempty"`
+	// InMedia is set when the video's directory already has a file of the
+	// same name.
+	InMedia bool `json:"in_media"`
+	// Movable is set when the file can be moved to Destination; otherwise
+	// Problem says why not.
+	Movable bool   `json:"movable"`
+	Problem string `json:"problem,omitempty"`
+}
+
+type downloadsView struct {
+	Directory string
+	Files     []DownloadedFile
+	Size      int64
+	SafeMode  bool
+}
+
+type downloadsList struct {
+	Directory string           `json:"directory"`
+	Files     []DownloadedFile `json:"files"`
+}
+
+type downloadsRequest struct {
+	Files []string `json:"files"`
+}
+
+type downloadsResponse struct {
+	Action string   `json:"action"`
+	Files  []string `json:"files"`
+	Failed []string `json:"failed,omitempty"`
+}
+
+// DownloadsHandler serves /downloads, the list of files in the downloads
+// directory, and /downloads?file={name}, which downloads one of them.
+func (h *Handler) DownloadsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if name := r.URL.Query().Get("file"); name != "" {
+		h.serveDownload(w, r, name)
+		return
+	}
+
+	files, err := h.downloadedFiles(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list downloads: %v", err), http.StatusInternalServerError)
+		return
+	}
+	view := downloadsView{Directory: h.Config().SubtitleDirectory, Files: files, SafeMode: h.Config().SafeMode}
+	for _, file := range files {
+		view.Size += file.Size
+	}
+	render(w, r, http.StatusOK, "downloads", view)
+}
+
+// serveDownload sends a file from the downloads directory as an attachment.
+func (h *Handler) serveDownload(w http.ResponseWriter, r *http.Request, name string) {
+	path, err := h.downloadPath(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, path)
+}
+
+// DownloadsAPIHandler serves /api/v1/downloads: GET lists the downloads
+// directory, POST /api/v1/downloads/move moves the given files next to
+// their videos, POST /api/v1/downloads/delete removes them and
+// POST /api/v1/downloads/cleanup removes the files that are no longer
+// needed: those the video's directory already has and those of videos that
+// left the library. It takes a JSON body {"files": [...]}, or "file" form
+// fields (one per file), in which case the browser is sent back to the list.
+func (h *Handler) DownloadsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/v1/downloads"), "/")
+	if action == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		files, err := h.downloadedFiles(r.Context())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list downloads: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if files == nil {
+			files = []DownloadedFile{}
+		}
+		writeJSON(w, http.StatusOK, downloadsList{Directory: h.Config().SubtitleDirectory, Files: files})
+		return
+	}
+	if action != "move" && action != "delete" && action != "cleanup" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req downloadsRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Files = r.Form["file"]
+	}
+	if action != "cleanup" && len(req.Files) == 0 {
+		respond(w, r, http.StatusBadRequest, "No files selected")
+		return
+	}
+
+	files, err := h.downloadedFiles(r.Context())
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to list downloads: %v", err))
+		return
+	}
+	byName := make(map[string]DownloadedFile, len(files))
+	for _, file := range files {
+		byName[file.Name] = file
+	}
+
+	var selected []DownloadedFile
+	if action == "cleanup" {
+		// A file newer than the library listing may belong to a video that
+		// isn't listed yet
+		scannedAt := h.Library.ScannedAt()
+		for _, file := range files {
+			if file.InMedia || (file.Item == nil && file.Modified.Before(scannedAt)) {
+				selected = append(selected, file)
+			}
+		}
+	} else {
+		for _, name := range req.Files {
+			file, ok := byName[name]
+			if !ok {
+				respond(w, r, http.StatusNotFound, fmt.Sprintf("%s is not in the downloads directory", name))
+				return
+			}
+			selected = append(selected, file)
+		}
+	}
+
+	resp := downloadsResponse{Action: action, Files: []string{}}
+	var problems []string
+	for _, file := range selected {
+		if action == "move" {
+			err = h.moveDownload(r.Context(), file)
+		} else {
+			err = h.deleteDownload(file)
+		}
+		if err != nil {
+			log.Printf("Failed to %s %s: %v", action, file.Name, err)
+			resp.Failed = append(resp.Failed, file.Name)
+			problems = append(problems, fmt.Sprintf("%s: %v", file.Name, err))
+			continue
+		}
+		resp.Files = append(resp.Files, file.Name)
+	}
+
+	if wantsHTML(r) {
+		if len(problems) > 0 {
+			respond(w, r, http.StatusConflict, strings.Join(problems, "\n"))
+			return
+		}
+		redirect(w, r, returnPath(r, "/downloads"))
+		return
+	}
+	status := http.StatusOK
+	if len(resp.Failed) > 0 {
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, resp)
+}
+
+// downloadedFiles lists the files in the downloads directory, newest first,
+// with the library item each belongs to and whether it can be moved there.
+func (h *Handler) downloadedFiles(ctx context.Context) ([]DownloadedFile, error) {
+	entries, err := os.ReadDir(h.Config().SubtitleDirectory)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Saved subtitles are named after the video: "{video name}.{target}.srt"
+	// (or ".ass")
+	items, err := h.Library.Items(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch media: %w", err)
+	}
+	type video struct {
+		item jellyfin.MediaItem
+		path string
+	}
+	videos := make(map[string]video, len(items))
+	for _, item := range items {
+		for _, version := range h.Wanted.Versions(item) {
+			if version.Path == "" {
+				continue
+			}
+			videos[strings.TrimSuffix(filepath.Base(version.Path), filepath.Ext(version.Path))] = video{item, version.Path}
+		}
+	}
+
+	var files []DownloadedFile
+	for _, entry := range entries {
+		// Hidden files are write probes and partial writes
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		file := DownloadedFile{Name: entry.Name(), Size: info.Size(), Modified: info.ModTime()}
+
+		stem := strings.TrimSuffix(file.Name, filepath.Ext(file.Name))
+		for i := strings.LastIndex(stem, "."); i > 0; i = strings.LastIndex(stem[:i], ".") {
+			if video, ok := videos[stem[:i]]; ok {
+				file.Item = &video.item
+				file.ItemID = video.item.ID
+				file.Target = stem[i+1:]
+				file.Video = video.path
+				break
+			}
+		}
+		h.checkMove(&file)
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Modified.After(files[j].Modified) })
+	return files, nil
+}
+
+// checkMove works out where the file belongs and whether it can be moved
+// there.
+func (h *Handler) checkMove(file *DownloadedFile) {
+	if file.Item == nil {
+		file.Problem = "No video in the library has this name"
+		return
+	}
+	if ext := filepath.Ext(file.Name); !strings.EqualFold(ext, ".srt") && !strings.EqualFold(ext, ".ass") {
+		file.Problem = "Only subtitles are moved"
+		return
+	}
+
+	dir := filepath.Dir(h.containerPath(file.Item, file.Video))
+	file.Destination = filepath.Join(dir, file.Name)
+	if _, err := os.Stat(file.Destination); err == nil {
+		file.InMedia = true
+		file.Problem = "The media directory already has this file"
+		return
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		file.Problem = "The media directory can't be found here; check the path mappings"
+		return
+	}
+	if err := h.checkWriteTarget(file.Destination); err != nil {
+		file.Problem = "Safe mode: the media directory is not in an approved media root"
+		return
+	}
+	file.Movable = true
+}
+
+// downloadPath returns the path of a file in the downloads directory,
+// refusing names that would lead out of it.
+func (h *Handler) downloadPath(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	path := filepath.Join(h.Config().SubtitleDirectory, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a file", name)
+	}
+	return path, nil
+}
+
+// moveDownload moves a file next to its video, records the new location
+// and has Jellyfin pick it up.
+func (h *Handler) moveDownload(ctx context.Context, file DownloadedFile) error {
+	if !file.Movable {
+		return errors.New(file.Problem)
+	}
+
+	source := filepath.Join(h.Config().SubtitleDirectory, file.Name)
+	if err := h.moveFile(source, file.Destination); err != nil {
+		return err
+	}
+	log.Printf("Moved %s to %s", source, file.Destination)
+	if err := h.applyOwnership(file.Destination); err != nil {
+		return err
+	}
+
+	// The subtitles of the item's other versions are copies
+	if target, ok := fileTarget(file.Target); ok && file.Video == h.itemVideoPath(file.Item) {
+		result, recorded, err := h.Wanted.Result(file.ItemID, target)
+		if err == nil && recorded && result.Path == "downloads" {
+			result.Path = "media"
+			err = h.Wanted.RecordResult(file.ItemID, target, result)
+		}
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	h.refreshMetadata(ctx, file.Item)
+	return nil
+}
+
+// moverIdleCheck is how often a disabled mover looks whether a settings
+// reload turned it on.
+const moverIdleCheck = time.Minute
+
+// RunDownloadsMover moves subtitles that fell back to the downloads
+// directory next to their videos every DownloadsMoveInterval, since the
+// media directory often becomes writable later, until ctx is done. It only
+// runs while direct saves are on and picks up changes to the interval from
+// settings reloads.
+func (h *Handler) RunDownloadsMover(ctx context.Context) {
+	for {
+		wait := h.Config().DownloadsMoveInterval
+		if wait <= 0 || !h.Config().EnableDirectSave {
+			wait = moverIdleCheck
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+
+		if h.Config().DownloadsMoveInterval <= 0 || !h.Config().EnableDirectSave {
+			continue
+		}
+		if moved, err := h.MoveDownloads(ctx); err != nil {
+			log.Printf("Downloads mover: %v", err)
+		} else if moved > 0 {
+			log.Printf("Downloads mover: moved %d subtitles next to their videos", moved)
+		}
+	}
+}
+
+// MoveDownloads moves every file in the downloads directory that can go
+// next to its video and returns how many were moved. A directory that
+// can't be written is not tried again for the other files of this pass.
+func (h *Handler) MoveDownloads(ctx context.Context) (int, error) {
+	files, err := h.downloadedFiles(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list downloads: %w", err)
+	}
+
+	moved := 0
+	failed := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file.Destination)
+		if !file.Movable || failed[dir] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return moved, ctx.Err()
+		}
+		if err := h.moveDownload(ctx, file); err != nil {
+			log.Printf("Downloads mover: %s stays in the downloads directory: %v", file.Name, err)
+			failed[dir] = true
+			continue
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// deleteDownload removes a file from the downloads directory. A subtitle
+// recorded as saved there is wanted again afterwards, unless the video's
+// directory has a copy.
+func (h *Handler) deleteDownload(file DownloadedFile) error {
+	if err := os.Remove(filepath.Join(h.Config().SubtitleDirectory, file.Name)); err != nil {
+		return err
+	}
+	log.Printf("Deleted %s from the downloads directory", file.Name)
+
+	if file.Item == nil || file.InMedia || file.Video != h.itemVideoPath(file.Item) {
+		return nil
+	}
+	if target, ok := fileTarget(file.Target); ok {
+		result, recorded, err := h.Wanted.Result(file.ItemID, target)
+		if err == nil && recorded && result.Path == "downloads" {
+			err = h.Wanted.ForgetResult(file.ItemID, target)
+		}
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	h.Library.Invalidate(file.ItemID)
+	return nil
+}
+
+// fileTarget parses the target part of a subtitle file name, such as
+// "zh-Hant" or "zh-Hant.forced".
+func fileTarget(value string) (wanted.Target, bool) {
+	language, forced := strings.CutSuffix(value, wanted.ForcedSuffix)
+	tag := lang.Parse(language)
+	return wanted.Target{Language: tag, Forced: forced}, !tag.IsZero()
+}
+
+// moveFile renames source to destination, copying it when they are on
+// different file systems, as the downloads and media volumes usually are.
+func (h *Handler) moveFile(source, destination string) error {
+	if err := os.Rename(source, destination); err == nil {
+		return nil
+	}
+
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	if err := h.TempFiles.WriteFileAtomic(destination, content, h.subtitleFileMode()); err != nil {
+		return fmt.Errorf("failed to write subtitle file: %w", err)
+	}
+	return os.Remove(source)
+}
+
+// formatSize formats a file size for display, as in "42.1 KB".
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
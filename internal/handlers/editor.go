@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/subtitle"
+	"subtitle-hunter/internal/wanted"
+)
+
+// originalsDirectory, below the data directory, keeps the source cues of
+// each translated subtitle so the editor can show them next to the
+// translation.
+const originalsDirectory = "originals"
+
+// originalPath is where the source cues of the video file's subtitle for
+// target are kept.
+func (h *Handler) originalPath(videoPath, target string) string {
+	sum := sha256.Sum256([]byte(videoPath + "|" + target))
+	return filepath.Join(h.Config().DataDirectory, originalsDirectory, hex.EncodeToString(sum[:16])+".srt")
+}
+
+// keepOriginal stores the cues a subtitle was translated from.
+func (h *Handler) keepOriginal(videoPath, target string, entries []subtitle.SubtitleEntry) {
+	path := h.originalPath(videoPath, target)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("Warning: not keeping original subtitle: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(h.Parser.Format(entries)), 0644); err != nil {
+		log.Printf("Warning: not keeping original subtitle: %v", err)
+	}
+}
+
+// forgetOriginal drops the kept source cues once a subtitle is replaced.
+func (h *Handler) forgetOriginal(videoPath, target string) {
+	if err := os.Remove(h.originalPath(videoPath, target)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// originalEntries returns the kept source cues of the video file's
+// subtitle for target, or nil when the subtitle wasn't translated here.
+func (h *Handler) originalEntries(videoPath, target string) []subtitle.SubtitleEntry {
+	content, err := os.ReadFile(h.originalPath(videoPath, target))
+	if err != nil {
+		return nil
+	}
+	entries, err := h.Parser.Parse(content)
+	if err != nil {
+		log.Printf("Warning: failed to parse original subtitle: %v", err)
+		return nil
+	}
+	return entries
+}
+
+// matchingOriginals returns the kept source cues when they still line up
+// with entries cue for cue, otherwise nil.
+func (h *Handler) matchingOriginals(videoPath, target string, entries []subtitle.SubtitleEntry) []subtitle.SubtitleEntry {
+	originals := h.originalEntries(videoPath, target)
+	if len(originals) != len(entries) {
+		return nil
+	}
+	return originals
+}
+
+// splitPart reports whether the cue at position i is one part of a long
+// translated cue that was split, which all keep the same original.
+func splitPart(originals []subtitle.SubtitleEntry, i int) bool {
+	return (i > 0 && originals[i-1] == originals[i]) || (i+1 < len(originals) && originals[i+1] == originals[i])
+}
+
+type editorCue struct {
+	// Position is the cue's place in the file, as lint issues count it.
+	Position int
+	subtitle.SubtitleEntry
+	Original string
+	Issues   []subtitle.LintIssue
+	// Failed is set for cues that kept their fallback text when the
+	// subtitle was translated and can be translated again on their own;
+	// they start ticked.
+	Failed bool
+}
+
+type editorView struct {
+	Item   *jellyfin.MediaItem
+	Target wanted.Target
+	Path   string
+	// Version is the file's modification time when it was loaded; saving
+	// is refused when the file changed since.
+	Version     string
+	Cues        []editorCue
+	HasOriginal bool
+	Issues      int
+	// CanRetranslate is set when chosen cues can be translated again from
+	// their originals, with one of Backends.
+	CanRetranslate bool
+	Backends       []string
+	Saved          bool
+	Return         string
+	// Self is this page, which re-translations return to.
+	Self string
+}
+
+// editorTarget reads the subtitle the editor works on from the "language"
+// (default zh-Hant) and "forced" values.
+func editorTarget(r *http.Request) (wanted.Target, error) {
+	language, err := searchLanguage(r)
+	if err != nil {
+		return wanted.Target{}, err
+	}
+	return wanted.Target{Language: language, Forced: r.FormValue("forced") == "true"}, nil
+}
+
+// editorURL is the editor page for the item's subtitle for target.
+func editorURL(itemID string, target wanted.Target) string {
+	query := url.Values{"language": {target.Language.String()}}
+	if target.Forced {
+		query.Set("forced", "true")
+	}
+	return "/items/" + url.PathEscape(itemID) + "/edit?" + query.Encode()
+}
+
+func fileVersion(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(info.ModTime().UnixNano(), 10), nil
+}
+
+// editSubtitle serves /items/{id}/edit: GET shows the item's saved
+// subtitle with the original cues beside the translated ones, POST saves
+// the edited text back (see saveEdits).
+func (h *Handler) editSubtitle(w http.ResponseWriter, r *http.Request, itemID string) {
+	switch r.Method {
+	case http.MethodGet:
+		h.editorPage(w, r, itemID)
+	case http.MethodPost:
+		h.saveEdits(w, r, itemID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) editorPage(w http.ResponseWriter, r *http.Request, itemID string) {
+	target, err := editorTarget(r)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+
+	videoPath := h.itemVideoPath(item)
+	path, err := h.savedSubtitlePath(item, videoPath, target.String())
+	if err != nil {
+		respond(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	version, err := fileVersion(path)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to read subtitle: %v", err))
+		return
+	}
+	entries, err := h.Parser.Parse(content)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to parse subtitle: %v", err))
+		return
+	}
+
+	originals := h.matchingOriginals(videoPath, target.String(), entries)
+	issues := subtitle.Lint(entries, subtitle.DefaultLintRules)
+	view := editorView{
+		Item:        item,
+		Target:      target,
+		Path:        path,
+		Version:     version,
+		HasOriginal: originals != nil,
+		Issues:      len(issues),
+		Saved:       r.URL.Query().Get("saved") == "1",
+		Return:      returnPath(r, "/wanted"),
+	}
+	view.Self = editorURL(itemID, target) + "&return=" + url.QueryEscape(view.Return)
+	if originals != nil && target == (wanted.Target{Language: lang.TraditionalChinese}) {
+		view.CanRetranslate = true
+		for _, backend := range h.Backends {
+			view.Backends = append(view.Backends, backend.Name)
+		}
+	}
+	failed := make(map[int]bool)
+	if view.CanRetranslate {
+		if result, _, err := h.Wanted.Result(itemID, target); err != nil {
+			log.Printf("Warning: %v", err)
+		} else {
+			for _, index := range result.FailedCues {
+				failed[index] = true
+			}
+		}
+	}
+	for i, entry := range entries {
+		cue := editorCue{Position: i, SubtitleEntry: entry}
+		if originals != nil {
+			cue.Original = originals[i].Text
+			cue.Failed = failed[entry.Index] && !splitPart(originals, i)
+		}
+		for _, issue := range issues {
+			if issue.Cue == i {
+				cue.Issues = append(cue.Issues, issue)
+			}
+		}
+		view.Cues = append(view.Cues, cue)
+	}
+
+	render(w, r, http.StatusOK, "editor", view)
+}
+
+// saveEdits handles POST /items/{id}/edit: one "text" value per cue in
+// file order replaces the cues' text, and "fix" ("{position}:{fix}") applies
+// a lint fix on top. Corrected Traditional Chinese cues are remembered in
+// the translation memory when "remember" is set. The subtitle is rewritten
+// in place and Jellyfin refreshed.
+func (h *Handler) saveEdits(w http.ResponseWriter, r *http.Request, itemID string) {
+	target, err := editorTarget(r)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		log.Printf("Error getting item details for %s: %v", itemID, err)
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+
+	videoPath := h.itemVideoPath(item)
+	path, err := h.savedSubtitlePath(item, videoPath, target.String())
+	if err != nil {
+		respond(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if version, err := fileVersion(path); err != nil || version != r.FormValue("version") {
+		respond(w, r, http.StatusConflict, "The subtitle changed since the editor was opened. Reload the editor and make your changes again.")
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to read subtitle: %v", err))
+		return
+	}
+	entries, err := h.Parser.Parse(content)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to parse subtitle: %v", err))
+		return
+	}
+
+	texts := r.PostForm["text"]
+	if len(texts) != len(entries) {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("Expected the text of %d cues, got %d", len(entries), len(texts)))
+		return
+	}
+	edited := append([]subtitle.SubtitleEntry{}, entries...)
+	var changed []int
+	for i, text := range texts {
+		text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+		if text == "" {
+			respond(w, r, http.StatusBadRequest, fmt.Sprintf("Cue %d is empty", entries[i].Index))
+			return
+		}
+		if text != entries[i].Text {
+			edited[i].Text = text
+			changed = append(changed, i)
+		}
+	}
+
+	// Corrections of machine translations are remembered for later ones
+	originals := h.matchingOriginals(videoPath, target.String(), entries)
+	var corrections [][2]string
+	if r.FormValue("remember") == "true" && originals != nil && target == (wanted.Target{Language: lang.TraditionalChinese}) {
+		for _, i := range changed {
+			// A part of a split cue only translates part of the original
+			if splitPart(originals, i) {
+				continue
+			}
+			// Only the translated part of a bilingual cue is remembered
+			text, _ := subtitle.SplitBilingual(edited[i].Text, originals[i].Text)
+			corrections = append(corrections, [2]string{originals[i].Text, text})
+		}
+	}
+
+	var mergedOriginals []subtitle.SubtitleEntry
+	if fix := r.FormValue("fix"); fix != "" {
+		position, name, _ := strings.Cut(fix, ":")
+		cue, err := strconv.Atoi(position)
+		if err == nil {
+			edited, err = subtitle.ApplyFix(edited, cue, name, subtitle.DefaultLintRules)
+		}
+		if err != nil {
+			respond(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid fix %q: %v", fix, err))
+			return
+		}
+		// The original cues are merged along with the subtitle's to keep
+		// them lined up
+		if name == subtitle.FixMerge && originals != nil {
+			mergedOriginals, _ = subtitle.ApplyFix(originals, cue, name, subtitle.DefaultLintRules)
+		}
+	}
+
+	if err := h.writeSubtitle(path, target.String(), []byte(h.Parser.Format(edited)), len(entries)); err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if mergedOriginals != nil {
+		h.keepOriginal(videoPath, target.String(), mergedOriginals)
+	}
+	log.Printf("Saved %d edited cue(s) of %s", len(changed), path)
+
+	remembered := 0
+	for _, correction := range corrections {
+		if err := h.Memory.Remember(item.SeriesName, correction[0], correction[1]); err != nil {
+			log.Printf("Warning: %v", err)
+			break
+		}
+		remembered++
+	}
+	h.refreshMetadata(r.Context(), item)
+
+	if wantsHTML(r) {
+		redirect(w, r, editorURL(itemID, target)+"&saved=1&return="+url.QueryEscape(returnPath(r, "/wanted")))
+		return
+	}
+	message := fmt.Sprintf("Saved %d edited cue(s)", len(changed))
+	if remembered > 0 {
+		message += fmt.Sprintf(" and remembered %d correction(s) for future translations", remembered)
+	}
+	respond(w, r, http.StatusOK, message)
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"subtitle-hunter/internal/hunterpb"
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+)
+
+// progressInterval is how often a streamed job is looked at for news.
+const progressInterval = 500 * time.Millisecond
+
+// grpcService serves the SubtitleHunter gRPC service defined in
+// internal/hunterpb/hunter.proto with the handler's pipeline.
+type grpcService struct {
+	hunterpb.UnimplementedSubtitleHunterServer
+	h *Handler
+}
+
+// RegisterGRPC registers the SubtitleHunter service on server, which must
+// have been made with GRPCOptions.
+func (h *Handler) RegisterGRPC(server *grpc.Server) {
+	hunterpb.RegisterSubtitleHunterServer(server, &grpcService{h: h})
+}
+
+// GRPCOptions are the options of the gRPC server: calls need the admin
+// password when one is set, sent as basic auth in the "authorization"
+// metadata, as the web interface's API takes it.
+func (h *Handler) GRPCOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := h.authorizeRPC(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := h.authorizeRPC(stream.Context()); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	}
+}
+
+func (h *Handler) authorizeRPC(ctx context.Context) error {
+	password := h.Config().AdminPassword
+	if password == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		r := &http.Request{Header: http.Header{"Authorization": {value}}}
+		if authenticated(r, password) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "Only the admin can do this; send the admin password with basic auth")
+}
+
+func (s *grpcService) Search(ctx context.Context, req *hunterpb.SearchRequest) (*hunterpb.SearchResponse, error) {
+	language := lang.TraditionalChinese
+	if req.Language != "" {
+		language = lang.Normalize(req.Language)
+		if language.IsZero() {
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown language %q", req.Language)
+		}
+	}
+	item, err := s.item(ctx, req.ItemId)
+	if err != nil {
+		return nil, err
+	}
+
+	search, err := s.h.manualSearch(ctx, item, req.Query, language)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	rejected := make(map[string]bool)
+	for _, id := range search.Rejected {
+		rejected[id] = true
+	}
+	response := &hunterpb.SearchResponse{Query: search.Query, ImdbId: search.IMDbID, Language: search.Language}
+	for _, candidate := range search.Candidates {
+		response.Candidates = append(response.Candidates, &hunterpb.Candidate{
+			Id:                candidate.ID,
+			FileId:            int64(candidate.FileID),
+			Language:          candidate.Language,
+			FileName:          candidate.FileName,
+			Release:           candidate.Release,
+			DownloadCount:     int64(candidate.DownloadCount),
+			HashMatch:         candidate.HashMatch,
+			HearingImpaired:   candidate.HearingImpaired,
+			Forced:            candidate.ForeignPartsOnly,
+			Rating:            candidate.Rating,
+			FromTrusted:       candidate.FromTrusted,
+			MachineTranslated: candidate.MachineTranslated || candidate.AITranslated,
+			Rejected:          rejected[candidate.ID],
+		})
+	}
+	return response, nil
+}
+
+// Process hunts the item in the background and streams the progress of
+// the hunt job holding the item, which is the one the hunt runs or joins.
+func (s *grpcService) Process(req *hunterpb.ProcessRequest, stream hunterpb.SubtitleHunter_ProcessServer) error {
+	ctx := stream.Context()
+	item, err := s.item(ctx, req.ItemId)
+	if err != nil {
+		return err
+	}
+
+	type outcome struct {
+		jobID  string
+		result *ProcessResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		jobID, result, err := s.h.Hunt(ctx, item, jobs.TriggerManual)
+		done <- outcome{jobID, result, err}
+	}()
+
+	progress := &jobProgress{send: stream.Send, itemID: item.ID, itemName: item.Name}
+	if err := stream.Send(&hunterpb.JobProgress{ItemId: item.ID, ItemName: item.Name, State: "queued"}); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case o := <-done:
+			if o.jobID == "" {
+				if ctx.Err() != nil {
+					return status.FromContextError(ctx.Err()).Err()
+				}
+				return status.Error(codes.Unavailable, o.err.Error())
+			}
+			report, jobLog, err := s.finished(o.jobID)
+			if err != nil {
+				return err
+			}
+			result := jobResult(report)
+			if o.result != nil {
+				result.Message = o.result.Message(s.h.Config().SubtitleDirectory)
+			}
+			return progress.update(report, jobLog, result)
+		case <-ticker.C:
+			if job, ok := s.h.Jobs.Claimed(item.ID, jobs.WorkHunt); ok {
+				if err := progress.update(job.Snapshot(), job.Log(), nil); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// StreamJobProgress follows a running job until its report is saved.
+func (s *grpcService) StreamJobProgress(req *hunterpb.StreamJobProgressRequest, stream hunterpb.SubtitleHunter_StreamJobProgressServer) error {
+	ctx := stream.Context()
+	jobID := req.JobId
+	switch {
+	case jobID != "":
+		if _, running := s.h.Jobs.Running(jobID); !running {
+			if _, found, err := s.h.Jobs.Get(jobID); err != nil {
+				return status.Error(codes.Internal, err.Error())
+			} else if !found {
+				return status.Errorf(codes.NotFound, "Job %s not found", jobID)
+			}
+		}
+	case req.ItemId != "":
+		for _, report := range s.h.Jobs.RunningReports() {
+			if report.ItemID == req.ItemId {
+				jobID = report.ID
+				break
+			}
+		}
+		if jobID == "" {
+			return status.Errorf(codes.NotFound, "No job is running for item %s", req.ItemId)
+		}
+	default:
+		return status.Error(codes.InvalidArgument, "job_id or item_id required")
+	}
+
+	progress := &jobProgress{send: stream.Send}
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		if job, running := s.h.Jobs.Running(jobID); running {
+			if err := progress.update(job.Snapshot(), job.Log(), nil); err != nil {
+				return err
+			}
+		} else if report, found, err := s.h.Jobs.Get(jobID); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		} else if found {
+			jobLog, _, err := s.h.Jobs.Log(jobID)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			return progress.update(report, jobLog, jobResult(report))
+		}
+		// Between leaving the running jobs and being saved the job is in
+		// neither; it turns up on the next look
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *grpcService) item(ctx context.Context, itemID string) (*jellyfin.MediaItem, error) {
+	if itemID == "" {
+		return nil, status.Error(codes.InvalidArgument, "item_id required")
+	}
+	item, err := s.h.JellyfinClient.GetItem(ctx, itemID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get item details: %v", err)
+	}
+	return item, nil
+}
+
+// finished returns the saved report and log of a job that has finished.
+func (s *grpcService) finished(jobID string) (jobs.Report, jobs.Log, error) {
+	report, found, err := s.h.Jobs.Get(jobID)
+	if err == nil && !found {
+		err = errors.New("job report not saved")
+	}
+	if err != nil {
+		return report, jobs.Log{}, status.Errorf(codes.Internal, "Job %s: %v", jobID, err)
+	}
+	jobLog, _, err := s.h.Jobs.Log(jobID)
+	if err != nil {
+		return report, jobs.Log{}, status.Error(codes.Internal, err.Error())
+	}
+	return report, jobLog, nil
+}
+
+func jobResult(report jobs.Report) *hunterpb.JobResult {
+	return &hunterpb.JobResult{
+		Succeeded:   report.Succeeded,
+		Source:      report.Source,
+		Error:       report.Error,
+		Partial:     report.Partial,
+		WallTimeMs:  report.WallTimeMs,
+		QueueWaitMs: report.QueueWaitMs,
+	}
+}
+
+// jobProgress sends what changed in a job since it last sent.
+type jobProgress struct {
+	send     func(*hunterpb.JobProgress) error
+	itemID   string
+	itemName string
+
+	jobID  string
+	lines  int
+	state  string
+	stages int
+}
+
+// update sends the log lines added to the job since the last update and
+// its state and stages when they changed; with result set it sends the
+// last message even when nothing else changed.
+func (p *jobProgress) update(report jobs.Report, jobLog jobs.Log, result *hunterpb.JobResult) error {
+	if report.ID != p.jobID {
+		p.jobID, p.lines, p.state, p.stages = report.ID, 0, "", 0
+	}
+
+	state := "running"
+	if report.State != "" {
+		state = report.State
+	}
+	if result != nil {
+		state = "failed"
+		if result.Succeeded {
+			state = "succeeded"
+		} else if report.Cancelled {
+			state = "cancelled"
+		}
+	}
+	calls := 0
+	for _, stage := range report.Stages {
+		calls += stage.Calls
+	}
+
+	message := &hunterpb.JobProgress{JobId: report.ID, ItemId: report.ItemID, ItemName: report.ItemName, State: state, Approval: report.Approval, Result: result}
+	if message.ItemId == "" {
+		message.ItemId, message.ItemName = p.itemID, p.itemName
+	}
+	if p.lines < len(jobLog.Lines) {
+		for _, line := range jobLog.Lines[p.lines:] {
+			message.Lines = append(message.Lines, &hunterpb.LogLine{Time: timestamppb.New(line.Time), Message: line.Message})
+		}
+	}
+	if calls != p.stages || result != nil {
+		for _, stage := range report.Stages {
+			message.Stages = append(message.Stages, &hunterpb.Stage{Name: stage.Name, Calls: int64(stage.Calls), WallTimeMs: stage.WallTimeMs})
+		}
+	}
+	if len(message.Lines) == 0 && len(message.Stages) == 0 && state == p.state && result == nil {
+		return nil
+	}
+
+	if err := p.send(message); err != nil {
+		return err
+	}
+	p.lines = max(p.lines, len(jobLog.Lines))
+	p.state, p.stages = state, calls
+	return nil
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthCheckTimeout bounds every dependency check so the whole health
+// check answers within the Docker healthcheck timeout.
+const healthCheckTimeout = 2 * time.Second
+
+// translatorProbe is the text translated to check that a backend works.
+const translatorProbe = "Hello"
+
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+	healthDown     = "down"
+)
+
+type dependencyHealth struct {
+	Status string `json:"status"`
+	// Critical dependencies make the service unhealthy when they are down.
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+type healthReport struct {
+	Status       string                      `json:"status"`
+	Service      string                      `json:"service"`
+	Dependencies map[string]dependencyHealth `json:"dependencies"`
+}
+
+// checkHealth runs every dependency check concurrently.
+func (h *Handler) checkHealth(ctx context.Context) healthReport {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) dependencyHealth{
+		"jellyfin":           h.checkJellyfin,
+		"opensubtitles":      h.checkOpenSubtitles,
+		"translator":         h.checkTranslators,
+		"subtitle_directory": h.checkSubtitleDirectory,
+		"store":              h.checkStore,
+	}
+
+	report := healthReport{
+		Status:       healthOK,
+		Service:      "subtitle-hunter",
+		Dependencies: make(map[string]dependencyHealth, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) dependencyHealth) {
+			defer wg.Done()
+			result := check(ctx)
+			mu.Lock()
+			report.Dependencies[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Dependencies {
+		switch {
+		case result.Status == healthOK:
+		case result.Critical && result.Status == healthDown:
+			report.Status = healthDown
+		case report.Status == healthOK:
+			report.Status = healthDegraded
+		}
+	}
+	return report
+}
+
+func healthFromError(err error, critical bool) dependencyHealth {
+	if err != nil {
+		return dependencyHealth{Status: healthDown, Critical: critical, Error: err.Error()}
+	}
+	return dependencyHealth{Status: healthOK, Critical: critical}
+}
+
+// checkJellyfin verifies that Jellyfin answers and accepts the credentials.
+func (h *Handler) checkJellyfin(ctx context.Context) dependencyHealth {
+	return healthFromError(h.JellyfinClient.Ping(ctx), true)
+}
+
+// checkOpenSubtitles pings every account. The service still works without
+// OpenSubtitles (embedded tracks, transcription), so it is not critical.
+func (h *Handler) checkOpenSubtitles(ctx context.Context) dependencyHealth {
+	errs := h.OpenSubtitlesClient.Ping(ctx)
+	if len(errs) == 0 {
+		return dependencyHealth{Status: healthDown, Error: "no OpenSubtitles accounts configured"}
+	}
+
+	var failures, details []string
+	exhausted := 0
+	for _, status := range h.OpenSubtitlesClient.Status() {
+		if err := errs[status.Name]; err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", status.Name, err))
+			continue
+		}
+		switch {
+		case status.Exhausted:
+			exhausted++
+			details = append(details, fmt.Sprintf("%s: quota exhausted until %s", status.Name, status.ResetAt.Format(time.RFC3339)))
+		case status.Remaining >= 0:
+			details = append(details, fmt.Sprintf("%s: %d downloads left", status.Name, status.Remaining))
+		default:
+			details = append(details, fmt.Sprintf("%s: reachable", status.Name))
+		}
+	}
+	sort.Strings(failures)
+
+	result := dependencyHealth{Status: healthOK, Detail: strings.Join(details, "; ")}
+	switch {
+	case len(failures) == len(errs):
+		result.Status = healthDown
+	case len(failures) > 0 || exhausted == len(errs)-len(failures):
+		result.Status = healthDegraded
+	}
+	if len(failures) > 0 {
+		result.Error = strings.Join(failures, "; ")
+	}
+	return result
+}
+
+// checkTranslators translates a short probe with every backend. A failing
+// translator only affects translation jobs, so it is not critical.
+func (h *Handler) checkTranslators(ctx context.Context) dependencyHealth {
+	var failures []string
+	for _, backend := range h.Backends {
+		if _, err := backend.Translator.TranslateToChineseTraditional(ctx, translatorProbe); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", backend.Name, err))
+		}
+	}
+
+	switch {
+	case len(failures) == 0:
+		return dependencyHealth{Status: healthOK}
+	case len(failures) < len(h.Backends):
+		return dependencyHealth{Status: healthDegraded, Error: strings.Join(failures, "; ")}
+	default:
+		return dependencyHealth{Status: healthDown, Error: strings.Join(failures, "; ")}
+	}
+}
+
+// checkSubtitleDirectory verifies that the downloads directory, where
+// subtitles are saved when the media directory is not writable, accepts files.
+func (h *Handler) checkSubtitleDirectory(ctx context.Context) dependencyHealth {
+	dir := h.Config().SubtitleDirectory
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return healthFromError(err, true)
+	}
+	result := healthFromError(h.TempFiles.ProbeWritable(dir), true)
+	result.Detail = dir
+	return result
+}
+
+// checkStore verifies that the data store can still be saved, or its
+// database still answers.
+func (h *Handler) checkStore(ctx context.Context) dependencyHealth {
+	return healthFromError(h.Store.Check(), true)
+}
+
+// StatusHandler reports the health of the service and its dependencies. It
+// answers 503 when a critical dependency is down, so it can be used as a
+// Docker healthcheck.
+func (h *Handler) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	report := h.checkHealth(r.Context())
+
+	status := http.StatusOK
+	if report.Status == healthDown {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/opensubtitles"
+	"subtitle-hunter/internal/wanted"
+)
+
+// searchHintsBucket holds the opensubtitles.Hint that last worked for each
+// series and language.
+const searchHintsBucket = "search-hints"
+
+// findSubtitle searches for the item's subtitle for target. Episodes try
+// the strategy and instance that found the previous episode of the series
+// first and remember what worked this time. An account order set in the
+// series settings takes precedence over the remembered instance.
+func (h *Handler) findSubtitle(ctx context.Context, item *jellyfin.MediaItem, target wanted.Target) (*opensubtitles.Subtitle, error) {
+	h.job.Searched()
+
+	language := opensubtitles.LanguageCode(target.Language)
+	providers := h.providersFor(item)
+	video := h.videoFor(item)
+
+	if item.Type != "Episode" || item.SeriesName == "" {
+		ctx, stop := h.stage(ctx, jobs.StageSearch)
+		defer stop()
+		movie := h.movieFor(item)
+		h.job.Logf("Searching %s subtitles for: %s", target, movie)
+		return providers.FindBestSubtitle(ctx, movie, language, target.Forced, video)
+	}
+
+	// Looked up before the search starts, so it doesn't count as search time
+	episode := h.episodeFor(ctx, item)
+	ctx, stop := h.stage(ctx, jobs.StageSearch)
+	defer stop()
+	key := searchHintKey(item, language, target.Forced)
+
+	var hint opensubtitles.Hint
+	if _, err := h.Store.Get(searchHintsBucket, key, &hint); err != nil {
+		h.job.Logf("Warning: %v", err)
+	}
+	if hint.Strategy != "" {
+		h.job.Logf("Searching %s subtitles for: %s (trying %s via %s first)", target, episode, hint.Strategy, hint.Provider)
+	} else {
+		h.job.Logf("Searching %s subtitles for: %s", target, episode)
+	}
+
+	preferred := hint
+	if len(h.seriesSettings(item).Providers) > 0 {
+		preferred.Provider = ""
+	}
+	sub, found, err := providers.FindEpisodeSubtitle(ctx, episode, language, target.Forced, video, preferred)
+	if err != nil {
+		return nil, err
+	}
+
+	if found != hint {
+		if err := h.Store.Put(searchHintsBucket, key, found); err != nil {
+			h.job.Logf("Warning: %v", err)
+		}
+	}
+	return sub, nil
+}
+
+// videoFor describes the item's video file for ranking search results,
+// with the subtitles rejected for the item. The hash is left out when the
+// file can't be read from here.
+func (h *Handler) videoFor(item *jellyfin.MediaItem) opensubtitles.Video {
+	videoPath := h.containerPath(item, h.itemVideoPath(item))
+	video := opensubtitles.Video{FileName: filepath.Base(videoPath)}
+
+	rejected, err := h.rejectedIDs(item.ID, opensubtitlesProvider)
+	if err != nil {
+		h.job.Logf("Warning: %v", err)
+	}
+	for id := range rejected {
+		video.Exclude = append(video.Exclude, id)
+	}
+
+	hash, err := opensubtitles.FileHash(videoPath)
+	if err != nil {
+		h.job.Logf("Warning: could not hash %s, searching without it: %v", video.FileName, err)
+		return video
+	}
+	video.Hash = hash
+	return video
+}
+
+func searchHintKey(item *jellyfin.MediaItem, language string, forced bool) string {
+	series := item.SeriesID
+	if series == "" {
+		series = item.SeriesName
+	}
+	if forced {
+		language += wanted.ForcedSuffix
+	}
+	return fmt.Sprintf("%s/%s", series, language)
+}
+
+// movieFor describes a movie, or a video that isn't a known episode, for
+// the subtitle search. Movies are searched by their IMDb ID and year when
+// Jellyfin knows them.
+func (h *Handler) movieFor(item *jellyfin.MediaItem) opensubtitles.Movie {
+	if item.Type != "Movie" {
+		return opensubtitles.Movie{Title: h.JellyfinClient.GetSearchQuery(*item)}
+	}
+	return opensubtitles.Movie{Title: item.Name, Year: item.ProductionYear, IMDbID: itemIMDbID(item)}
+}
+
+// itemIMDbID returns the item's IMDb ID in the form the OpenSubtitles API
+// expects, or "" if Jellyfin doesn't have one.
+func itemIMDbID(item *jellyfin.MediaItem) string {
+	imdbID, ok := parseIMDbID(item.ProviderIds["Imdb"])
+	if !ok {
+		return ""
+	}
+	return imdbID
+}
+
+// episodeFor describes an episode for the subtitle search, with its
+// series' IMDb ID when Jellyfin knows it. Anime also gets its absolute
+// episode number and alternative titles (a romanized original title and
+// any configured aliases), so absolute-number queries are tried.
+func (h *Handler) episodeFor(ctx context.Context, item *jellyfin.MediaItem) opensubtitles.Episode {
+	episode := opensubtitles.Episode{Series: item.SeriesName, Season: item.ParentIndexNumber, Number: item.IndexNumber}
+	if item.SeriesID == "" {
+		return episode
+	}
+
+	ctx, stop := h.stage(ctx, jobs.StageLookup)
+	defer stop()
+
+	series, err := h.JellyfinClient.GetItem(ctx, item.SeriesID)
+	if err != nil {
+		h.job.Logf("Warning: could not look up series %s: %v", item.SeriesName, err)
+		return episode
+	}
+	episode.ParentIMDbID = itemIMDbID(series)
+	if !series.IsAnime() {
+		return episode
+	}
+
+	if title := series.OriginalTitle; title != "" && title != item.SeriesName && lang.DetectScript(title) == lang.ScriptLatin {
+		episode.Titles = append(episode.Titles, title)
+	}
+	episode.Titles = append(episode.Titles, h.Config().TitleAliases[item.SeriesName]...)
+
+	episodes, err := h.JellyfinClient.GetEpisodes(ctx, item.SeriesID)
+	if err != nil {
+		h.job.Logf("Warning: could not list episodes of %s: %v", item.SeriesName, err)
+		return episode
+	}
+	episode.Absolute = jellyfin.AbsoluteEpisodeNumber(episodes, item.ParentIndexNumber, item.IndexNumber)
+	if episode.Absolute > 0 {
+		h.job.Logf("%s is anime, also searching for absolute episode %d", episode, episode.Absolute)
+	}
+	return episode
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ItemsHandler serves the per-item pages and actions under /items/{id}/:
+// the item's own page shows its streams, subtitle files and jobs,
+// "subtitle" uploads a file, "search" shows a manual search, "download"
+// saves a candidate picked from it, "offset" shifts a saved subtitle's timing,
+// "edit" opens the subtitle editor, "merge" combines two of its subtitle
+// languages and "poster" returns the item's poster.
+func (h *Handler) ItemsHandler(w http.ResponseWriter, r *http.Request) {
+	itemID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/items/"), "/")
+	if itemID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch action {
+	case "":
+		h.itemPage(w, r, itemID)
+	case "subtitle":
+		h.uploadSubtitle(w, r, itemID)
+	case "search":
+		h.searchPage(w, r, itemID)
+	case "download":
+		h.downloadCandidate(w, r, itemID)
+	case "offset":
+		h.shiftSubtitle(w, r, itemID)
+	case "edit":
+		h.editSubtitle(w, r, itemID)
+	case "merge":
+		h.mergePage(w, r, itemID)
+	case "poster":
+		h.posterImage(w, r, itemID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// ItemsAPIHandler serves GET /api/v1/items, a filtered and sorted page of
+// the library, GET /api/v1/items/{id}/search, the manual search as JSON,
+// GET /api/v1/items/{id}/offset, the timing offset remembered for the
+// item's video file, POST /api/v1/items/{id}/retranslate, which translates
+// chosen cues of its subtitle again, GET /api/v1/items/{id}/merge, which
+// merges two of its subtitle languages, POST /api/v1/items/{id}/discard,
+// which replaces a wrong subtitle, and /api/v1/items/{id}/blacklist, the
+// subtitles searches for the item leave out.
+func (h *Handler) ItemsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	itemID, action, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/v1/items"), "/"), "/")
+	if itemID == "" {
+		h.itemsListAPI(w, r)
+		return
+	}
+
+	switch action {
+	case "search":
+		h.searchAPI(w, r, itemID)
+	case "offset":
+		h.offsetAPI(w, r, itemID)
+	case "retranslate":
+		h.retranslateAPI(w, r, itemID)
+	case "merge":
+		h.mergeAPI(w, r, itemID)
+	case "discard":
+		h.discardAPI(w, r, itemID)
+	case "recheck":
+		h.recheckAPI(w, r, itemID)
+	case "blacklist":
+		h.blacklistAPI(w, r, itemID, false)
+	case "blacklist/remove":
+		h.blacklistAPI(w, r, itemID, true)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/jobs"
+)
+
+const defaultJobListLimit = 50
+
+type jobList struct {
+	Jobs    []jobs.Report  `json:"jobs"`
+	Running []jobs.Report  `json:"running"`
+	Pool    jobs.PoolStats `json:"pool"`
+}
+
+// JobsAPIHandler serves the reports of subtitle jobs:
+// GET /api/v1/jobs lists the most recent ones (?limit=N, newest first)
+// together with the worker pool's load and the jobs still running,
+// GET /api/v1/jobs/{id}/report returns one and GET /api/v1/jobs/{id}/log
+// the lines it logged. Running jobs answer with what they have so far.
+// POST /api/v1/jobs/{id}/approve and /reject decide on a job waiting for
+// its translation to be approved, and POST /api/v1/jobs/{id}/cancel stops a
+// running job.
+func (h *Handler) JobsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/jobs"), "/")
+	switch jobID, action, _ := strings.Cut(path, "/"); action {
+	case "approve", "reject":
+		h.decideJob(w, r, jobID, action == "approve")
+		return
+	case "cancel":
+		h.cancelJob(w, r, jobID)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if path == "" {
+		limit := defaultJobListLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		reports, err := h.Jobs.Recent(limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, jobList{Jobs: reports, Running: h.Jobs.RunningReports(), Pool: h.Pool.Stats()})
+		return
+	}
+
+	jobID, action, _ := strings.Cut(path, "/")
+	if action != "report" && action != "log" {
+		http.NotFound(w, r)
+		return
+	}
+
+	view, found, err := h.jobView(jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if action == "log" {
+		writeJSON(w, http.StatusOK, view.Log)
+		return
+	}
+	writeJSON(w, http.StatusOK, view.Report)
+}
+
+// decideJob approves or rejects the translation a running job waits for.
+func (h *Handler) decideJob(w http.ResponseWriter, r *http.Request, jobID string, approved bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, ok := h.Jobs.Running(jobID)
+	if !ok {
+		respond(w, r, http.StatusNotFound, "Job not found")
+		return
+	}
+	if err := job.Decide(approved); err != nil {
+		if errors.Is(err, jobs.ErrNotAwaiting) {
+			respond(w, r, http.StatusConflict, err.Error())
+			return
+		}
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/jobs/"+jobID))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cancelJob stops a running job. It ends at its next network call or cue,
+// and the cues it translated are kept for the next time the item is
+// translated.
+func (h *Handler) cancelJob(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, ok := h.Jobs.Running(jobID)
+	if !ok {
+		respond(w, r, http.StatusNotFound, "Job not found")
+		return
+	}
+	if job.Cancel() {
+		job.Logf("Cancel requested")
+	}
+
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/jobs/"+jobID))
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type jobView struct {
+	Report  jobs.Report
+	Log     jobs.Log
+	Running bool
+	// HasLog is unset for jobs finished before their logs were kept.
+	HasLog bool
+}
+
+// jobView returns a job's report and log, from the running job when it
+// hasn't finished yet.
+func (h *Handler) jobView(id string) (jobView, bool, error) {
+	if job, ok := h.Jobs.Running(id); ok {
+		return jobView{Report: job.Snapshot(), Log: job.Log(), Running: true, HasLog: true}, true, nil
+	}
+
+	report, found, err := h.Jobs.Get(id)
+	if err != nil || !found {
+		return jobView{}, found, err
+	}
+	jobLog, hasLog, err := h.Jobs.Log(id)
+	if err != nil {
+		return jobView{}, false, err
+	}
+	return jobView{Report: report, Log: jobLog, HasLog: hasLog}, true, nil
+}
+
+type jobsView struct {
+	Running []jobs.Report
+	Recent  []jobs.Report
+}
+
+// JobsHandler shows the running and recent jobs at /jobs, and the report
+// and log of one job at /jobs/{id}, so a failed hunt can be looked into
+// without reading the container's logs. The page of a running job reloads
+// itself until the job finishes.
+func (h *Handler) JobsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs"), "/")
+	if jobID == "" {
+		recent, err := h.Jobs.Recent(defaultJobListLimit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		render(w, r, http.StatusOK, "jobs", jobsView{Running: h.Jobs.RunningReports(), Recent: recent})
+		return
+	}
+
+	view, found, err := h.jobView(jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	render(w, r, http.StatusOK, "job", view)
+}
+
+// formatDuration reads a job's ETA in milliseconds, rounded to seconds, or
+// to minutes once it is an hour or more.
+func formatDuration(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	if d >= time.Hour {
+		d = d.Round(time.Minute)
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	d = d.Round(time.Second)
+	if d < time.Second {
+		d = time.Second
+	}
+	return d.String()
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/events"
+)
+
+type rescanResponse struct {
+	Items     int       `json:"items"`
+	Missing   int       `json:"missing"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// LibraryHandler serves POST /api/v1/library/rescan, which fetches the
+// library from Jellyfin again instead of waiting for the cached listing to
+// expire. Form submissions are redirected back.
+func (h *Handler) LibraryHandler(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimPrefix(r.URL.Path, "/api/v1/library/") != "rescan" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	items, err := h.Library.Rescan(r.Context())
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to fetch media: %v", err))
+		return
+	}
+	h.Events.Publish(events.LibraryRescanned, nil)
+
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/"))
+		return
+	}
+	writeJSON(w, http.StatusOK, rescanResponse{
+		Items:     len(items),
+		Missing:   len(h.JellyfinClient.WithoutChineseSubtitles(items)),
+		ScannedAt: h.Library.ScannedAt(),
+	})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/internal/subtitle"
+)
+
+type lintRequest struct {
+	Content string `json:"content"`
+}
+
+type fixRequest struct {
+	Content string `json:"content"`
+	Cue     int    `json:"cue"`
+	Fix     string `json:"fix"`
+}
+
+type lintResponse struct {
+	Cues   int                  `json:"cues"`
+	Issues []subtitle.LintIssue `json:"issues"`
+}
+
+type fixResponse struct {
+	Content string `json:"content"`
+	lintResponse
+}
+
+// LintHandler checks an SRT subtitle against the readability rules and
+// returns the problems found, each with the fixes that can be applied.
+func (h *Handler) LintHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req lintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	entries, ok := h.parseLintContent(w, req.Content)
+	if !ok {
+		return
+	}
+
+	issues := subtitle.Lint(entries, subtitle.DefaultLintRules)
+	if issues == nil {
+		issues = []subtitle.LintIssue{}
+	}
+	writeJSON(w, http.StatusOK, lintResponse{Cues: len(entries), Issues: issues})
+}
+
+// FixHandler applies one lint fix to an SRT subtitle and returns the fixed
+// content together with the problems that remain.
+func (h *Handler) FixHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req fixRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	entries, ok := h.parseLintContent(w, req.Content)
+	if !ok {
+		return
+	}
+
+	fixed, err := subtitle.ApplyFix(entries, req.Cue, req.Fix, subtitle.DefaultLintRules)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	issues := subtitle.Lint(fixed, subtitle.DefaultLintRules)
+	if issues == nil {
+		issues = []subtitle.LintIssue{}
+	}
+	writeJSON(w, http.StatusOK, fixResponse{Content: h.Parser.Format(fixed), lintResponse: lintResponse{Cues: len(fixed), Issues: issues}})
+}
+
+func (h *Handler) parseLintContent(w http.ResponseWriter, content string) ([]subtitle.SubtitleEntry, bool) {
+	if strings.TrimSpace(content) == "" {
+		http.Error(w, "Content required", http.StatusBadRequest)
+		return nil, false
+	}
+	entries, err := h.Parser.Parse([]byte(content))
+	if err != nil || len(entries) == 0 {
+		http.Error(w, "Content is not a valid SRT subtitle", http.StatusBadRequest)
+		return nil, false
+	}
+	return entries, true
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+)
+
+const (
+	// defaultPerPage is how many items a page of GET /api/v1/items holds
+	// when per_page isn't given, and maxPerPage the most it can ask for.
+	defaultPerPage = 50
+	maxPerPage     = 500
+	// moviesPerPage is how many movies the index page lists at first; the
+	// rest are loaded a page at a time as the reader scrolls.
+	moviesPerPage = 60
+)
+
+// unknownSeries names the series of episodes Jellyfin doesn't give one for.
+const unknownSeries = "Unknown Series"
+
+// itemQuery is what GET /api/v1/items lists: the items matching every
+// filter given, in order, one page at a time.
+type itemQuery struct {
+	// Missing keeps the items with no full subtitle in the language.
+	Missing lang.Tag
+	// Type keeps "Episode"s or "Movie"s.
+	Type string
+	// Series keeps the episodes of a series, by ID or name.
+	Series string
+	// Search keeps the items whose name or series name contains it.
+	Search string
+	// Sort is "series", "name", "added" or "premiered".
+	Sort       string
+	Descending bool
+	Page       int
+	PerPage    int
+}
+
+// listedItem is an item as GET /api/v1/items lists it.
+type listedItem struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	SeriesID   string `json:"series_id,omitempty"`
+	SeriesName string `json:"series_name,omitempty"`
+	Season     int    `json:"season,omitempty"`
+	SeasonName string `json:"season_name,omitempty"`
+	Episode    int    `json:"episode,omitempty"`
+	// Languages are the item's own target languages, if it has any.
+	Languages []string `json:"languages,omitempty"`
+}
+
+type itemPage struct {
+	Items   []listedItem `json:"items"`
+	Total   int          `json:"total"`
+	Page    int          `json:"page"`
+	PerPage int          `json:"per_page"`
+	Pages   int          `json:"pages"`
+}
+
+// parseItemQuery reads an itemQuery from the parameters of a request.
+func parseItemQuery(values url.Values) (itemQuery, error) {
+	query := itemQuery{
+		Series:  strings.TrimSpace(values.Get("series")),
+		Search:  strings.TrimSpace(values.Get("q")),
+		Sort:    "series",
+		Page:    1,
+		PerPage: defaultPerPage,
+	}
+
+	if missing := values.Get("missing"); missing != "" {
+		query.Missing = lang.Normalize(missing)
+		if query.Missing.IsZero() {
+			return query, fmt.Errorf("unknown language %q", missing)
+		}
+	}
+
+	switch itemType := strings.ToLower(values.Get("type")); itemType {
+	case "":
+	case "episode":
+		query.Type = "Episode"
+	case "movie":
+		query.Type = "Movie"
+	default:
+		return query, fmt.Errorf("type must be Episode or Movie, not %q", values.Get("type"))
+	}
+
+	if sortBy := values.Get("sort"); sortBy != "" {
+		switch sortBy {
+		case "series", "name", "added", "premiered":
+			query.Sort = sortBy
+		default:
+			return query, fmt.Errorf("sort must be series, name, added or premiered, not %q", sortBy)
+		}
+	}
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, fmt.Errorf("order must be asc or desc, not %q", order)
+	}
+
+	if page := values.Get("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return query, fmt.Errorf("page must be a positive number, not %q", page)
+		}
+		query.Page = n
+	}
+	if perPage := values.Get("per_page"); perPage != "" {
+		n, err := strconv.Atoi(perPage)
+		if err != nil || n < 1 || n > maxPerPage {
+			return query, fmt.Errorf("per_page must be between 1 and %d, not %q", maxPerPage, perPage)
+		}
+		query.PerPage = n
+	}
+
+	return query, nil
+}
+
+// seriesName returns the name an episode's series is listed under.
+func seriesName(item jellyfin.MediaItem) string {
+	if item.SeriesName == "" {
+		return unknownSeries
+	}
+	return item.SeriesName
+}
+
+// matches reports whether the item passes the query's filters.
+func (q itemQuery) matches(item jellyfin.MediaItem) bool {
+	if q.Type != "" && item.Type != q.Type {
+		return false
+	}
+	if q.Series != "" && (item.Type != "Episode" || (item.SeriesID != q.Series && !strings.EqualFold(seriesName(item), q.Series))) {
+		return false
+	}
+	if q.Search != "" && !matchesSearch(item, strings.ToLower(q.Search)) {
+		return false
+	}
+	if !q.Missing.IsZero() {
+		embedded, external := item.SubtitleStatus(q.Missing, false)
+		if embedded || external {
+			return false
+		}
+	}
+	return true
+}
+
+// sortItems orders items the way the query asks. Ties are broken by name
+// and then ID, so every page of a listing comes out in the same order.
+func (q itemQuery) sortItems(items []jellyfin.MediaItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if q.Descending {
+			a, b = b, a
+		}
+		switch q.Sort {
+		case "series":
+			// Movies sort by their own name among the series
+			if an, bn := sortTitle(a), sortTitle(b); an != bn {
+				return an < bn
+			}
+			if as, bs := seasonNumber(a), seasonNumber(b); as != bs {
+				return as < bs
+			}
+			if a.IndexNumber != b.IndexNumber {
+				return a.IndexNumber < b.IndexNumber
+			}
+		case "added":
+			if at, bt := a.AddedAt(), b.AddedAt(); !at.Equal(bt) {
+				return at.Before(bt)
+			}
+		case "premiered":
+			if at, bt := a.PremieredAt(), b.PremieredAt(); !at.Equal(bt) {
+				return at.Before(bt)
+			}
+		}
+		return lessByName(a, b)
+	})
+}
+
+// sortTitle is what the "series" order sorts an item by.
+func sortTitle(item jellyfin.MediaItem) string {
+	if item.Type == "Episode" {
+		return seriesName(item)
+	}
+	return item.Name
+}
+
+// lessByName orders items by name, then by ID.
+func lessByName(a, b jellyfin.MediaItem) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ID < b.ID
+}
+
+// listItems filters, sorts and pages items as the query asks.
+func (h *Handler) listItems(items []jellyfin.MediaItem, query itemQuery) itemPage {
+	var matching []jellyfin.MediaItem
+	for _, item := range items {
+		if query.matches(item) {
+			matching = append(matching, item)
+		}
+	}
+	query.sortItems(matching)
+
+	page := itemPage{
+		Items:   []listedItem{},
+		Total:   len(matching),
+		Page:    query.Page,
+		PerPage: query.PerPage,
+		Pages:   (len(matching) + query.PerPage - 1) / query.PerPage,
+	}
+	start := (query.Page - 1) * query.PerPage
+	if start >= len(matching) {
+		return page
+	}
+	end := start + query.PerPage
+	if end > len(matching) {
+		end = len(matching)
+	}
+
+	for _, item := range matching[start:end] {
+		listed := listedItem{
+			ID:        item.ID,
+			Name:      item.Name,
+			Type:      item.Type,
+			Languages: h.itemLanguages(item.ID),
+		}
+		if item.Type == "Episode" {
+			listed.SeriesID = item.SeriesID
+			listed.SeriesName = seriesName(item)
+			listed.Season = seasonNumber(item)
+			listed.SeasonName = item.SeasonName
+			listed.Episode = item.IndexNumber
+		}
+		page.Items = append(page.Items, listed)
+	}
+	return page
+}
+
+// seasonNumber returns the season an episode is listed under, the first
+// when Jellyfin doesn't say.
+func seasonNumber(item jellyfin.MediaItem) int {
+	if item.ParentIndexNumber == 0 {
+		return 1
+	}
+	return item.ParentIndexNumber
+}
+
+// itemsListAPI serves GET /api/v1/items, a page of the library's movies and
+// episodes. The parameters missing={language}, type=Episode|Movie,
+// series={id or name} and q={text} filter it, sort=series|name|added|premiered
+// and order=asc|desc order it, and page and per_page choose the page.
+func (h *Handler) itemsListAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query, err := parseItemQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := h.Library.Items(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch media: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.listItems(items, query))
+}
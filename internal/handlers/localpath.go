@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/release"
+	"subtitle-hunter/internal/wanted"
+)
+
+// localItemPrefix starts the IDs of items made up from a file path for
+// videos Jellyfin doesn't know about.
+const localItemPrefix = "path:"
+
+// maxLocalItems caps how many videos one path may queue.
+const maxLocalItems = 1000
+
+// videoExtensions are the files taken as videos under a path.
+var videoExtensions = map[string]bool{
+	".mkv": true, ".mp4": true, ".avi": true, ".m4v": true, ".mov": true,
+	".ts": true, ".wmv": true, ".webm": true,
+}
+
+// IsVideoFile reports whether path names a video, by its extension.
+func IsVideoFile(path string) bool {
+	return videoExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// isLocalItem reports whether item was made up from a file path rather
+// than read from Jellyfin.
+func isLocalItem(item *jellyfin.MediaItem) bool {
+	return strings.HasPrefix(item.ID, localItemPrefix)
+}
+
+// containerPath returns path, the item's video or one of its subtitles as
+// Jellyfin reports it, as this service sees it. Items made up from a file
+// path already have paths as seen from here, so they are left alone.
+func (h *Handler) containerPath(item *jellyfin.MediaItem, path string) string {
+	if isLocalItem(item) {
+		return path
+	}
+	return h.Config().MapJellyfinPathToContainer(path)
+}
+
+// LocalItems returns an item for each video at path, a video file or a
+// directory searched recursively, as this service sees it. Jellyfin isn't
+// asked: the series, season and episode, or the movie title and year, are
+// read from the file name, and subtitles already named after a video count
+// as its external subtitles.
+func (h *Handler) LocalItems(path string) ([]*jellyfin.MediaItem, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("%q is not an absolute path", path)
+	}
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if !IsVideoFile(path) {
+			return nil, fmt.Errorf("%s is not a video file", filepath.Base(path))
+		}
+		return []*jellyfin.MediaItem{h.localItem(path)}, nil
+	}
+
+	var items []*jellyfin.MediaItem
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !IsVideoFile(file) {
+			return nil
+		}
+		if len(items) == maxLocalItems {
+			return fmt.Errorf("more than %d videos under %s; pick a smaller directory", maxLocalItems, path)
+		}
+		items = append(items, h.localItem(file))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no video files under %s", path)
+	}
+	return items, nil
+}
+
+func (h *Handler) localItem(path string) *jellyfin.MediaItem {
+	info := release.Parse(path)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	item := &jellyfin.MediaItem{ID: localItemPrefix + path, Name: name, Type: "Movie", Path: path}
+	if info.Title != "" {
+		item.Name = info.Title
+	}
+	if info.Episode > 0 {
+		item.Type = "Episode"
+		item.Name = name
+		item.SeriesName = info.Title
+		item.ParentIndexNumber = info.Season
+		item.IndexNumber = info.Episode
+	} else {
+		item.ProductionYear = info.Year
+	}
+
+	// Only the files next to the video are seen by players
+	for _, file := range h.subtitleFiles(item) {
+		if file.Location != "media" {
+			continue
+		}
+		target, forced := strings.CutSuffix(file.Target, wanted.ForcedSuffix)
+		tag := lang.Parse(target)
+		if tag.IsZero() {
+			continue
+		}
+		item.MediaStreams = append(item.MediaStreams, jellyfin.MediaStream{
+			Type:       "Subtitle",
+			Language:   tag.String(),
+			Codec:      strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Name)), "."),
+			IsExternal: true,
+			IsForced:   forced,
+			Path:       file.Path,
+			Local:      true,
+		})
+	}
+	return item
+}
+
+type processPathRequest struct {
+	Path string `json:"path"`
+}
+
+type processPathResponse struct {
+	Path  string   `json:"path"`
+	Files []string `json:"files"`
+}
+
+// ProcessPathHandler serves POST /api/v1/process-path, which hunts
+// subtitles for the video at "path", or every video under it, without
+// asking Jellyfin; see LocalItems. The path must lie within a media root
+// (see pathAllowed). It takes a JSON body or a form field and runs the
+// hunts in the background, like a batch.
+func (h *Handler) ProcessPathHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req processPathRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Path = r.FormValue("path")
+	}
+	if req.Path == "" {
+		respond(w, r, http.StatusBadRequest, "A path is required")
+		return
+	}
+	if !h.pathAllowed(r.Context(), req.Path) {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("%s is not within a media root or watched directory", req.Path))
+		return
+	}
+
+	items, err := h.LocalItems(req.Path)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, fs.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		respond(w, r, status, err.Error())
+		return
+	}
+
+	log.Printf("Hunting subtitles for %d video(s) under %s", len(items), req.Path)
+	go h.huntLocalItems(items)
+
+	if wantsHTML(r) {
+		respond(w, r, http.StatusAccepted, fmt.Sprintf("Hunting subtitles for %d video(s) in the background; follow them on the jobs page", len(items)))
+		return
+	}
+	response := processPathResponse{Path: req.Path}
+	for _, item := range items {
+		response.Files = append(response.Files, item.Path)
+	}
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// pathAllowed reports whether videos may be hunted at path from the API:
+// it must lie within a Jellyfin library's directory as seen from here, a
+// media root approved for safe mode or a watched directory, also once its
+// symlinks are followed. Anything else, like / or /proc, is refused.
+func (h *Handler) pathAllowed(ctx context.Context, path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	roots := append(h.trustedRoots(), h.Config().WatchDirectories...)
+	if libraries, err := h.mediaRoots(ctx); err == nil {
+		for _, root := range libraries {
+			roots = append(roots, root.Container)
+		}
+	} else {
+		log.Printf("Warning: failed to get the media roots, only approved and watched ones are allowed: %v", err)
+	}
+
+	paths := []string{filepath.Clean(path)}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		paths = append(paths, resolved)
+	}
+	for _, candidate := range paths {
+		allowed := false
+		for _, root := range roots {
+			if root == "" {
+				continue
+			}
+			if withinDir(candidate, root) {
+				allowed = true
+				break
+			}
+			if resolvedRoot, err := filepath.EvalSymlinks(root); err == nil && withinDir(candidate, resolvedRoot) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// huntLocalItems hunts the items one after another as batch jobs,
+// stopping when the daily budget runs out.
+func (h *Handler) huntLocalItems(items []*jellyfin.MediaItem) {
+	ctx := h.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for i, item := range items {
+		_, _, err := h.Hunt(ctx, item, jobs.TriggerBatch)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrBudgetExhausted) {
+			log.Printf("Path hunt stopped, %d of %d videos left for later: %v", len(items)-i-1, len(items), err)
+			return
+		}
+		if err != nil {
+			log.Printf("Path hunt: %s: %v", item.Path, err)
+		}
+	}
+	log.Printf("Path hunt of %d videos finished", len(items))
+}
+
+// HuntDroppedFile hunts subtitles for a video that turned up in a watched
+// directory, as an automatic job by path. Like the scheduler it leaves the
+// file alone while automatic hunting is paused or the daily budget is used
+// up, and when the video came with every subtitle it needs.
+func (h *Handler) HuntDroppedFile(ctx context.Context, path string) {
+	if h.SchedulerPaused() {
+		log.Printf("New video %s: automatic hunting is paused, skipping it", path)
+		return
+	}
+	if h.BudgetExhausted() {
+		log.Printf("New video %s: the daily budget is used up, skipping it", path)
+		return
+	}
+	items, err := h.LocalItems(path)
+	if err != nil {
+		log.Printf("New video %s: %v", path, err)
+		return
+	}
+	item := items[0]
+
+	missing := false
+	for _, target := range h.huntTargets(item) {
+		status, err := h.Wanted.Status(*item, target)
+		missing = missing || err != nil || status == wanted.StatusMissing
+	}
+	if !missing {
+		log.Printf("New video %s already has its subtitles", path)
+		return
+	}
+
+	log.Printf("New video %s, hunting subtitles for it", path)
+	jobID, _, err := h.Hunt(ctx, item, jobs.TriggerAuto)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("New video %s: job %s failed: %v", path, jobID, err)
+	}
+}
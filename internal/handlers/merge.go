@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/subtitle"
+)
+
+// mergeTrack is a subtitle file of an item that the merge tool can combine
+// with another language.
+type mergeTrack struct {
+	Language lang.Tag
+	Path     string
+}
+
+// mergeTrackList is the answer of the merge API without languages.
+type mergeTrackList struct {
+	Tracks []mergeTrackFile `json:"tracks"`
+}
+
+type mergeTrackFile struct {
+	Language string `json:"language"`
+	Path     string `json:"path"`
+}
+
+type mergeView struct {
+	Item   *jellyfin.MediaItem
+	Tracks []mergeTrack
+	// Top and Bottom are the languages picked when the page opens.
+	Top    string
+	Bottom string
+	Return string
+}
+
+// mergeTracks lists the item's full subtitle files, one per language:
+// subtitles saved here for its target languages and English, then external
+// SRT files Jellyfin found next to the video.
+func (h *Handler) mergeTracks(item *jellyfin.MediaItem) []mergeTrack {
+	videoPath := h.itemVideoPath(item)
+	seen := make(map[string]bool)
+	var tracks []mergeTrack
+
+	for _, language := range append([]lang.Tag{lang.English}, h.targetsFor(item)...) {
+		if seen[language.String()] {
+			continue
+		}
+		if path, err := h.savedSubtitlePath(item, videoPath, language.String()); err == nil {
+			seen[language.String()] = true
+			tracks = append(tracks, mergeTrack{Language: language, Path: path})
+		}
+	}
+
+	for _, stream := range item.MediaStreams {
+		if stream.Type != "Subtitle" || !stream.IsExternal || stream.IsForced || !strings.EqualFold(filepath.Ext(stream.Path), ".srt") {
+			continue
+		}
+		language := lang.Normalize(stream.Language)
+		if language.IsZero() || seen[language.String()] {
+			continue
+		}
+		seen[language.String()] = true
+		tracks = append(tracks, mergeTrack{Language: language, Path: h.containerPath(item, stream.Path)})
+	}
+	return tracks
+}
+
+// findTrack returns the track that can stand in for language.
+func findTrack(tracks []mergeTrack, language lang.Tag) (mergeTrack, bool) {
+	for _, track := range tracks {
+		if track.Language.Matches(language) {
+			return track, true
+		}
+	}
+	return mergeTrack{}, false
+}
+
+// mergePage serves GET /items/{id}/merge, which offers to combine two of
+// the item's subtitle languages into one bilingual file.
+func (h *Handler) mergePage(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+
+	view := mergeView{
+		Item:   item,
+		Tracks: h.mergeTracks(item),
+		Top:    lang.English.String(),
+		Bottom: lang.TraditionalChinese.String(),
+		Return: returnPath(r, "/wanted"),
+	}
+	if top := r.URL.Query().Get("top"); top != "" {
+		view.Top = top
+	}
+	if bottom := r.URL.Query().Get("bottom"); bottom != "" {
+		view.Bottom = bottom
+	}
+	render(w, r, http.StatusOK, "merge", view)
+}
+
+// mergeAPI handles GET /api/v1/items/{id}/merge. Without parameters it lists
+// the item's subtitle languages; with "top" and "bottom" languages it
+// returns the two subtitles merged into one bilingual file, as SRT or, with
+// format=ass, as an ASS script that sets the top language in smaller type.
+func (h *Handler) mergeAPI(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = "srt"
+	}
+	if format != "srt" && format != "ass" {
+		http.Error(w, fmt.Sprintf("Unknown format %q (expected srt or ass)", format), http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		log.Printf("Error getting item details for %s: %v", itemID, err)
+		http.Error(w, fmt.Sprintf("Failed to get item details: %v", err), http.StatusInternalServerError)
+		return
+	}
+	tracks := h.mergeTracks(item)
+
+	if query.Get("top") == "" && query.Get("bottom") == "" {
+		list := mergeTrackList{Tracks: []mergeTrackFile{}}
+		for _, track := range tracks {
+			list.Tracks = append(list.Tracks, mergeTrackFile{Language: track.Language.String(), Path: track.Path})
+		}
+		writeJSON(w, http.StatusOK, list)
+		return
+	}
+
+	var pair [2]mergeTrack
+	for i, name := range []string{"top", "bottom"} {
+		language := lang.Normalize(query.Get(name))
+		if language.IsZero() {
+			http.Error(w, fmt.Sprintf("Unknown %s language %q", name, query.Get(name)), http.StatusBadRequest)
+			return
+		}
+		track, ok := findTrack(tracks, language)
+		if !ok {
+			http.Error(w, fmt.Sprintf("No %s subtitle found for this item", language.DisplayName()), http.StatusNotFound)
+			return
+		}
+		pair[i] = track
+	}
+	if pair[0].Path == pair[1].Path {
+		http.Error(w, "Pick two different languages", http.StatusBadRequest)
+		return
+	}
+
+	var entries [2][]subtitle.SubtitleEntry
+	for i, track := range pair {
+		content, err := os.ReadFile(track.Path)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read subtitle: %v", err), http.StatusInternalServerError)
+			return
+		}
+		entries[i], err = h.Parser.Parse(content)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to parse %s subtitle: %v", track.Language, err), http.StatusInternalServerError)
+			return
+		}
+	}
+	cues := subtitle.MergeTracks(entries[0], entries[1])
+
+	var content, contentType string
+	if format == "ass" {
+		content, contentType = subtitle.FormatASS(item.Name, cues), "text/x-ssa; charset=utf-8"
+	} else {
+		content, contentType = h.Parser.Format(subtitle.MergedEntries(cues)), "application/x-subrip; charset=utf-8"
+	}
+	bom, crlf := h.Config().OutputFor(pair[1].Language.String())
+
+	videoPath := h.itemVideoPath(item)
+	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	fileName := fmt.Sprintf("%s.%s+%s.%s", base, pair[0].Language, pair[1].Language, format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	w.Write(subtitle.OutputStyle{BOM: bom, CRLF: crlf}.Apply([]byte(content)))
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// statusRecorder remembers the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += n
+	return n, err
+}
+
+// Hijack hands the connection over, e.g. to a WebSocket, which makes the
+// response a 101.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// LogRequests logs the method, path, status and duration of every request
+// as key=value pairs. A handler that panics is answered with a 500 and the
+// panic is logged with its stack trace, so one malformed item fails its own
+// request rather than the whole server.
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			if p := recover(); p != nil {
+				// The server's way of aborting a response quietly
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				log.Printf("panic method=%s path=%q error=%q stack=%q", r.Method, r.URL.Path, p, debug.Stack())
+				if recorder.status == 0 {
+					http.Error(recorder, "Internal server error", http.StatusInternalServerError)
+				}
+			}
+			log.Printf("request method=%s path=%q status=%d bytes=%d duration=%s", r.Method, r.URL.Path, recorder.status, recorder.bytes, time.Since(start).Round(time.Millisecond))
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/subtitle"
+	"subtitle-hunter/internal/wanted"
+)
+
+// timingOffsetsBucket holds the TimingOffset of each video file, keyed by
+// releaseKey.
+const timingOffsetsBucket = "timing-offsets"
+
+// maxTimingOffset bounds manual corrections; anything larger is a typo
+// rather than an out-of-sync release.
+const maxTimingOffset = 10 * time.Minute
+
+// TimingOffset remembers how far subtitles are out of sync with one video
+// file. All subtitles for a particular rip tend to need the same shift, so
+// once a subtitle for it has been corrected, subtitles fetched for it later
+// are shifted the same way before they are saved.
+type TimingOffset struct {
+	// OffsetMs is added to the timestamps of subtitles fetched for the file.
+	OffsetMs int64 `json:"offset_ms"`
+	// AppliedMs is the shift already in the saved subtitle of each target,
+	// so correcting it again adjusts OffsetMs by the difference.
+	AppliedMs map[string]int64 `json:"applied_ms,omitempty"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+func (o TimingOffset) Offset() time.Duration {
+	return time.Duration(o.OffsetMs) * time.Millisecond
+}
+
+// releaseKey identifies a video file by its path and size, so a replaced
+// file with the same name doesn't inherit the old one's offset. It fails
+// when the file can't be read from here.
+func (h *Handler) releaseKey(item *jellyfin.MediaItem, videoPath string) (string, error) {
+	info, err := os.Stat(h.containerPath(item, videoPath))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s|%d", videoPath, info.Size()), nil
+}
+
+// rememberedOffset returns the offset recorded for the video file, or zero
+// if there is none.
+func (h *Handler) rememberedOffset(item *jellyfin.MediaItem, videoPath string) time.Duration {
+	key, err := h.releaseKey(item, videoPath)
+	if err != nil {
+		return 0
+	}
+	var offset TimingOffset
+	if _, err := h.Store.Get(timingOffsetsBucket, key, &offset); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return offset.Offset()
+}
+
+// applyRememberedOffset shifts entries fetched for the video file by the
+// file's remembered offset and returns the shift it applied.
+func (h *Handler) applyRememberedOffset(item *jellyfin.MediaItem, videoPath string, entries []subtitle.SubtitleEntry) ([]subtitle.SubtitleEntry, time.Duration) {
+	offset := h.rememberedOffset(item, videoPath)
+	if offset == 0 {
+		return entries, 0
+	}
+
+	shifted := subtitle.Shift(entries, offset)
+	h.job.Logf("Shifted subtitle by the %s remembered for this release", formatOffset(offset))
+	return shifted, offset
+}
+
+// recordApplied notes the shift that is in the subtitle just saved for
+// target, when the file has a remembered offset.
+func (h *Handler) recordApplied(item *jellyfin.MediaItem, videoPath, target string, applied time.Duration) {
+	key, err := h.releaseKey(item, videoPath)
+	if err != nil {
+		return
+	}
+
+	var offset TimingOffset
+	found, err := h.Store.Get(timingOffsetsBucket, key, &offset)
+	if err != nil || !found {
+		return
+	}
+	if offset.AppliedMs == nil {
+		offset.AppliedMs = make(map[string]int64)
+	}
+	offset.AppliedMs[target] = applied.Milliseconds()
+	if err := h.Store.Put(timingOffsetsBucket, key, offset); err != nil {
+		log.Printf("Warning: failed to save timing offset: %v", err)
+	}
+}
+
+// parseOffset reads a correction in seconds ("1.5", "-0.8") or as a Go
+// duration ("1500ms").
+func parseOffset(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	offset, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, parseErr := strconv.ParseFloat(value, 64)
+		if parseErr != nil {
+			return 0, fmt.Errorf("offset must be a number of seconds, got %q", value)
+		}
+		offset = time.Duration(seconds * float64(time.Second))
+	}
+
+	offset = offset.Round(time.Millisecond)
+	if offset == 0 {
+		return 0, fmt.Errorf("offset must not be zero")
+	}
+	if offset > maxTimingOffset || offset < -maxTimingOffset {
+		return 0, fmt.Errorf("offset must be within %s", maxTimingOffset)
+	}
+	return offset, nil
+}
+
+func formatOffset(offset time.Duration) string {
+	return fmt.Sprintf("%+gs", offset.Seconds())
+}
+
+// savedSubtitlePath finds the saved subtitle of the video file for
+// language, next to the video or in the downloads directory.
+func (h *Handler) savedSubtitlePath(item *jellyfin.MediaItem, videoPath, language string) (string, error) {
+	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	fileName := fmt.Sprintf("%s.%s.srt", base, language)
+
+	candidates := []string{
+		filepath.Join(filepath.Dir(h.containerPath(item, videoPath)), fileName),
+		filepath.Join(h.Config().SubtitleDirectory, fileName),
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no saved %s subtitle found", language)
+}
+
+// shiftSubtitle handles POST /items/{id}/offset, which moves the item's
+// saved subtitle for "language" (plus "forced") by "offset" seconds and
+// remembers the shift for subtitles fetched for the same file later.
+func (h *Handler) shiftSubtitle(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	offset, err := parseOffset(r.FormValue("offset"))
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	language, err := searchLanguage(r)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	target := wanted.Target{Language: language, Forced: r.FormValue("forced") == "true"}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		log.Printf("Error getting item details for %s: %v", itemID, err)
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+
+	remembered, err := h.correctOffset(r.Context(), item, target, offset)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	message := fmt.Sprintf("Shifted the %s subtitle by %s.", target.DisplayName(), formatOffset(offset))
+	if remembered != 0 {
+		message += fmt.Sprintf(" Subtitles fetched for this file from now on are shifted by %s.", formatOffset(remembered))
+	}
+	respond(w, r, http.StatusOK, message)
+}
+
+// offsetAPI returns the TimingOffset remembered for the item's video file,
+// with a zero offset when none is.
+func (h *Handler) offsetAPI(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get item details: %v", err), http.StatusInternalServerError)
+		return
+	}
+	key, err := h.releaseKey(item, h.itemVideoPath(item))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Video file unavailable: %v", err), http.StatusNotFound)
+		return
+	}
+
+	var offset TimingOffset
+	if _, err := h.Store.Get(timingOffsetsBucket, key, &offset); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, offset)
+}
+
+// correctOffset shifts the saved subtitle of item for target and updates
+// the file's remembered offset. It returns the new remembered offset, which
+// is zero when the file can't be identified.
+func (h *Handler) correctOffset(ctx context.Context, item *jellyfin.MediaItem, target wanted.Target, offset time.Duration) (time.Duration, error) {
+	videoPath := h.itemVideoPath(item)
+	path, err := h.savedSubtitlePath(item, videoPath, target.String())
+	if err != nil {
+		return 0, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read subtitle: %w", err)
+	}
+	entries, err := h.Parser.Parse(content)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse subtitle: %w", err)
+	}
+	shifted := subtitle.Shift(entries, offset)
+	if err := h.writeSubtitle(path, target.String(), []byte(h.Parser.Format(shifted)), len(entries)); err != nil {
+		return 0, err
+	}
+	log.Printf("Shifted %s by %s", path, formatOffset(offset))
+	h.refreshMetadata(ctx, item)
+
+	key, err := h.releaseKey(item, videoPath)
+	if err != nil {
+		log.Printf("Warning: not remembering offset, video file unavailable: %v", err)
+		return 0, nil
+	}
+
+	var remembered TimingOffset
+	if _, err := h.Store.Get(timingOffsetsBucket, key, &remembered); err != nil {
+		return 0, err
+	}
+	if remembered.AppliedMs == nil {
+		remembered.AppliedMs = make(map[string]int64)
+	}
+	total := remembered.AppliedMs[target.String()] + offset.Milliseconds()
+	remembered.OffsetMs = total
+	remembered.AppliedMs[target.String()] = total
+	remembered.UpdatedAt = time.Now()
+	if err := h.Store.Put(timingOffsetsBucket, key, remembered); err != nil {
+		return 0, fmt.Errorf("failed to save timing offset: %w", err)
+	}
+	return remembered.Offset(), nil
+}
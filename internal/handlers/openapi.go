@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+
+	"subtitle-hunter/config"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/openapi"
+	"subtitle-hunter/web"
+)
+
+// APIRoute is a route of the JSON API with the operations it serves. main
+// registers the routes and the OpenAPI document at /api/docs is built from
+// the same list, so the two don't drift apart.
+type APIRoute struct {
+	Pattern string
+	Handler http.Handler
+	// Tag groups the route's operations in the document.
+	Tag        string
+	Operations []apiOperation
+}
+
+// apiOperation documents one method of a path served by a route.
+type apiOperation struct {
+	Method string
+	// Path is the path template, such as /api/v1/jobs/{jobId}/log.
+	Path    string
+	ID      string
+	Summary string
+	Query   []apiParam
+	// Body is a value of the type the handler decodes the JSON body into;
+	// with AlsoForm set the same fields are taken as a form. Form lists
+	// the fields of a handler that takes only a form.
+	Body     interface{}
+	AlsoForm bool
+	Form     []apiParam
+	// Response is a value of the type the handler answers with as JSON,
+	// with Status (200 when zero). Without one it answers with a message
+	// in plain text, or nothing with 204.
+	Response interface{}
+	Status   int
+}
+
+type apiParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// APIRoutes returns the routes of the JSON API.
+func (h *Handler) APIRoutes() []APIRoute {
+	itemID := "/api/v1/items/{itemId}"
+	return []APIRoute{
+		{Pattern: "/status", Handler: http.HandlerFunc(h.StatusHandler), Tag: "Service", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/status", ID: "getStatus", Summary: "Health of the service and each dependency; 503 when a critical one is down", Response: healthReport{}},
+		}},
+		{Pattern: "/process/", Handler: http.HandlerFunc(h.ProcessHandler), Tag: "Items", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/process/{itemId}", ID: "processItem", Summary: "Find, translate and save the item's missing subtitles, answering when the job is done (its ID in the X-Job-ID header)", Form: []apiParam{
+				{Name: "bilingual", Type: "boolean", Description: "Override BILINGUAL_SUBTITLES for this hunt"},
+				{Name: "translate", Type: "boolean", Description: "Override whether machine translation may be used"},
+			}},
+		}},
+		{Pattern: "/api/v1/items", Handler: http.HandlerFunc(h.ItemsAPIHandler), Tag: "Items", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/items", ID: "listItems", Summary: "A page of the library's movies and episodes", Response: itemPage{}, Query: []apiParam{
+				{Name: "missing", Description: "Only items missing a subtitle in this language"},
+				{Name: "type", Description: "Episode or Movie"},
+				{Name: "series", Description: "Series ID or name"},
+				{Name: "q", Description: "Text in the name"},
+				{Name: "sort", Description: "series, name, added or premiered"},
+				{Name: "order", Description: "asc or desc"},
+				{Name: "page", Type: "integer"},
+				{Name: "per_page", Type: "integer"},
+			}},
+		}},
+		{Pattern: "/api/v1/items/", Handler: http.HandlerFunc(h.ItemsAPIHandler), Tag: "Items", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: itemID + "/search", ID: "searchItem", Summary: "Search OpenSubtitles for the item with a custom query or IMDb ID", Response: searchResult{}, Query: []apiParam{
+				{Name: "q", Description: "Query or IMDb ID; the generated query when empty"},
+				{Name: "language", Description: "Language to search in, zh-Hant when empty"},
+			}},
+			{Method: http.MethodGet, Path: itemID + "/offset", ID: "getItemOffset", Summary: "The timing offset remembered for the item's video file", Response: TimingOffset{}},
+			{Method: http.MethodGet, Path: itemID + "/merge", ID: "mergeItemSubtitles", Summary: "Without languages, the item's subtitle files; with top and bottom, the two merged into one bilingual file", Response: mergeTrackList{}, Query: []apiParam{
+				{Name: "top", Description: "Language shown on top"},
+				{Name: "bottom", Description: "Language shown below"},
+				{Name: "format", Description: "srt (default) or ass"},
+			}},
+			{Method: http.MethodPost, Path: itemID + "/retranslate", ID: "retranslateCues", Summary: "Translate cues of the item's translated subtitle again", Body: retranslateRequest{}, AlsoForm: true, Response: retranslateResponse{}},
+			{Method: http.MethodPost, Path: itemID + "/discard", ID: "discardSubtitle", Summary: "Archive or delete a saved subtitle, reject it and hunt the target again", Body: discardRequest{}, AlsoForm: true, Response: discardResponse{}},
+			{Method: http.MethodPost, Path: itemID + "/recheck", ID: "recheckItem", Summary: "Hunt the subtitles saved for another video file again", Response: recheckResponse{}},
+			{Method: http.MethodGet, Path: itemID + "/blacklist", ID: "listRejectedSubtitles", Summary: "The subtitles rejected for the item", Response: []rejectedSubtitle{}},
+			{Method: http.MethodPost, Path: itemID + "/blacklist", ID: "rejectSubtitle", Summary: "Reject a subtitle for the item", Body: blacklistRequest{}, AlsoForm: true, Response: []rejectedSubtitle{}, Status: http.StatusCreated},
+			{Method: http.MethodPost, Path: itemID + "/blacklist/remove", ID: "unrejectSubtitle", Summary: "Allow a rejected subtitle again", Body: blacklistRequest{}, AlsoForm: true, Response: []rejectedSubtitle{}},
+		}},
+		{Pattern: "/api/v1/series/", Handler: http.HandlerFunc(h.SeriesAPIHandler), Tag: "Series", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/series/{seriesId}/settings", ID: "getSeriesSettings", Summary: "A series' settings; omitted fields use the global settings", Response: SeriesSettings{}},
+			{Method: http.MethodPut, Path: "/api/v1/series/{seriesId}/settings", ID: "putSeriesSettings", Summary: "Replace a series' settings; an empty object removes them", Body: SeriesSettings{}, Response: SeriesSettings{}},
+			{Method: http.MethodPost, Path: "/api/v1/series/{seriesId}/pause", ID: "pauseSeries", Summary: "Stop automatic hunting for a series", Status: http.StatusNoContent},
+			{Method: http.MethodPost, Path: "/api/v1/series/{seriesId}/resume", ID: "resumeSeries", Summary: "Hunt a paused series automatically again", Status: http.StatusNoContent},
+			{Method: http.MethodPost, Path: "/api/v1/series/{seriesId}/archive", ID: "uploadSeasonArchive", Summary: "Save each subtitle of a season archive to the episode it is named after", Response: archiveResult{}, Form: []apiParam{
+				{Name: "file", Type: "file", Description: "Zip of subtitles named after the episodes"},
+				{Name: "language", Description: "Language of the subtitles"},
+				{Name: "forced", Type: "boolean"},
+			}},
+		}},
+		{Pattern: "/api/v1/translate/alternatives", Handler: http.HandlerFunc(h.AlternativesHandler), Tag: "Subtitles", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/translate/alternatives", ID: "translateAlternatives", Summary: "Alternative renderings of a cue from the configured translators", Body: alternativesRequest{}, Response: alternativesResponse{}},
+		}},
+		{Pattern: "/api/v1/translate/memory", Handler: http.HandlerFunc(h.MemoryHandler), Tag: "Subtitles", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/translate/memory", ID: "rememberTranslation", Summary: "Store a preferred translation in the translation memory", Body: memoryRequest{}, Status: http.StatusNoContent},
+		}},
+		{Pattern: "/api/v1/subtitles/lint", Handler: http.HandlerFunc(h.LintHandler), Tag: "Subtitles", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/subtitles/lint", ID: "lintSubtitle", Summary: "Readability issues of an SRT subtitle", Body: lintRequest{}, Response: lintResponse{}},
+		}},
+		{Pattern: "/api/v1/subtitles/fix", Handler: http.HandlerFunc(h.FixHandler), Tag: "Subtitles", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/subtitles/fix", ID: "fixSubtitle", Summary: "Apply one lint fix to an SRT subtitle", Body: fixRequest{}, Response: fixResponse{}},
+		}},
+		{Pattern: "/api/v1/wanted/ignore", Handler: http.HandlerFunc(h.IgnoreHandler), Tag: "Items", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/wanted/ignore", ID: "ignoreTarget", Summary: "Stop or resume wanting a language for an item", Body: ignoreRequest{}, AlsoForm: true, Status: http.StatusNoContent},
+		}},
+		{Pattern: "/api/v1/settings", Handler: http.HandlerFunc(h.SettingsAPIHandler), Tag: "Service", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/settings", ID: "getSettings", Summary: "The runtime settings, with secrets masked", Response: config.RuntimeSettings{}},
+			{Method: http.MethodPut, Path: "/api/v1/settings", ID: "putSettings", Summary: "Replace the runtime settings; masked or empty secrets keep their value", Body: config.RuntimeSettings{}, Response: config.RuntimeSettings{}},
+		}},
+		{Pattern: "/api/v1/quota", Handler: http.HandlerFunc(h.QuotaAPIHandler), Tag: "Service", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/quota", ID: "getQuota", Summary: "OpenSubtitles downloads left, translator usage and budgets, and auto-hunt state", Response: quotaView{}},
+		}},
+		{Pattern: "/api/v1/providers", Handler: http.HandlerFunc(h.ProvidersAPIHandler), Tag: "Service", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/providers", ID: "getProviders", Summary: "Calls to each external service today and this month, and their failures", Response: []providerStatus{}},
+		}},
+		{Pattern: "/api/v1/scheduler/", Handler: http.HandlerFunc(h.SchedulerHandler), Tag: "Service", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/scheduler/pause", ID: "pauseScheduler", Summary: "Pause automatic hunting", Status: http.StatusNoContent},
+			{Method: http.MethodPost, Path: "/api/v1/scheduler/resume", ID: "resumeScheduler", Summary: "Resume automatic hunting", Status: http.StatusNoContent},
+		}},
+		{Pattern: "/api/v1/jobs", Handler: http.HandlerFunc(h.JobsAPIHandler), Tag: "Jobs", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/jobs", ID: "listJobs", Summary: "The most recent job reports, the running jobs and the worker pool's load", Response: jobList{}, Query: []apiParam{
+				{Name: "limit", Type: "integer", Description: "Number of reports, " + strconv.Itoa(defaultJobListLimit) + " when unset"},
+			}},
+		}},
+		{Pattern: "/api/v1/jobs/", Handler: http.HandlerFunc(h.JobsAPIHandler), Tag: "Jobs", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/jobs/{jobId}/report", ID: "getJobReport", Summary: "A job's report, so far for a running job", Response: jobs.Report{}},
+			{Method: http.MethodGet, Path: "/api/v1/jobs/{jobId}/log", ID: "getJobLog", Summary: "The lines a job logged, so far for a running job", Response: jobs.Log{}},
+			{Method: http.MethodPost, Path: "/api/v1/jobs/{jobId}/approve", ID: "approveJob", Summary: "Let a job awaiting approval go on with its translation", Status: http.StatusNoContent},
+			{Method: http.MethodPost, Path: "/api/v1/jobs/{jobId}/reject", ID: "rejectJob", Summary: "Skip the translation a job waits for", Status: http.StatusNoContent},
+			{Method: http.MethodPost, Path: "/api/v1/jobs/{jobId}/cancel", ID: "cancelJob", Summary: "Stop a running job, keeping the cues it translated for the next translation of the item", Status: http.StatusAccepted},
+		}},
+		{Pattern: "/api/v1/stats", Handler: http.HandlerFunc(h.StatsAPIHandler), Tag: "Jobs", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/stats", ID: "getStats", Summary: "Time finished jobs spent in each pipeline stage", Response: statsView{}, Query: []apiParam{
+				{Name: "trigger", Description: "Only jobs started this way, such as auto"},
+				{Name: "limit", Type: "integer", Description: "Only the most recent jobs"},
+			}},
+		}},
+		{Pattern: "/api/v1/library/", Handler: http.HandlerFunc(h.LibraryHandler), Tag: "Service", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/library/rescan", ID: "rescanLibrary", Summary: "Scan the Jellyfin library again", Response: rescanResponse{}},
+		}},
+		{Pattern: "/api/v1/media-roots", Handler: http.HandlerFunc(h.MediaRootsHandler), Tag: "Service", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/media-roots", ID: "listMediaRoots", Summary: "Jellyfin's library folders and where they resolve to", Response: mediaRootsResponse{}},
+			{Method: http.MethodPost, Path: "/api/v1/media-roots", ID: "approveMediaRoots", Summary: "Approve media roots for direct saves in safe mode, replacing the previous approval", Response: mediaRootsResponse{}, Form: []apiParam{
+				{Name: "root", Description: "A container path to approve; repeat for more"},
+			}},
+		}},
+		{Pattern: "/api/v1/downloads", Handler: http.HandlerFunc(h.DownloadsAPIHandler), Tag: "Downloads", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/downloads", ID: "listDownloads", Summary: "The files in the downloads directory", Response: downloadsList{}},
+		}},
+		{Pattern: "/api/v1/downloads/", Handler: http.HandlerFunc(h.DownloadsAPIHandler), Tag: "Downloads", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/downloads/move", ID: "moveDownloads", Summary: "Move files next to their videos; 409 with the failed ones", Body: downloadsRequest{}, Response: downloadsResponse{}},
+			{Method: http.MethodPost, Path: "/api/v1/downloads/delete", ID: "deleteDownloads", Summary: "Delete files from the downloads directory", Body: downloadsRequest{}, Response: downloadsResponse{}},
+			{Method: http.MethodPost, Path: "/api/v1/downloads/cleanup", ID: "cleanUpDownloads", Summary: "Delete the files no longer needed", Response: downloadsResponse{}},
+		}},
+		{Pattern: "/api/v1/batch/", Handler: http.HandlerFunc(h.BatchHandler), Tag: "Items", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/batch/process", ID: "batchProcess", Summary: "Hunt the items in the background as batch jobs", Body: batchRequest{}, Response: batchResponse{}, Status: http.StatusAccepted},
+			{Method: http.MethodPost, Path: "/api/v1/batch/ignore", ID: "batchIgnore", Summary: "Stop wanting the languages for the items", Body: batchRequest{}, Response: batchResponse{}},
+			{Method: http.MethodPost, Path: "/api/v1/batch/languages", ID: "batchLanguages", Summary: "Set the items' own target languages", Body: batchRequest{}, Response: batchResponse{}},
+		}},
+		{Pattern: "/api/v1/process-path", Handler: http.HandlerFunc(h.ProcessPathHandler), Tag: "Items", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/process-path", ID: "processPath", Summary: "Hunt subtitles for a video, or every video under a directory, without Jellyfin", Body: processPathRequest{}, AlsoForm: true, Response: processPathResponse{}, Status: http.StatusAccepted},
+		}},
+		{Pattern: "/api/v1/campaigns", Handler: http.HandlerFunc(h.CampaignsAPIHandler), Tag: "Campaigns", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/campaigns", ID: "listCampaigns", Summary: "The backfill campaigns with their progress", Response: []campaignView{}},
+			{Method: http.MethodPost, Path: "/api/v1/campaigns", ID: "startCampaign", Summary: "Plan and start a campaign for the items in scope missing a subtitle", Body: campaignRequest{}, AlsoForm: true, Response: campaignView{}, Status: http.StatusCreated},
+		}},
+		{Pattern: "/api/v1/campaigns/", Handler: http.HandlerFunc(h.CampaignsAPIHandler), Tag: "Campaigns", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/campaigns/{campaignId}", ID: "getCampaign", Summary: "A campaign with its items", Response: campaignView{}},
+			{Method: http.MethodPost, Path: "/api/v1/campaigns/{campaignId}/pause", ID: "pauseCampaign", Summary: "Pause a campaign", Response: campaignView{}},
+			{Method: http.MethodPost, Path: "/api/v1/campaigns/{campaignId}/resume", ID: "resumeCampaign", Summary: "Resume a paused campaign", Response: campaignView{}},
+			{Method: http.MethodPost, Path: "/api/v1/campaigns/{campaignId}/cancel", ID: "cancelCampaign", Summary: "Drop a campaign's remaining items", Response: campaignView{}},
+		}},
+		{Pattern: "/api/v1/requests", Handler: http.HandlerFunc(h.RequestsAPIHandler), Tag: "Requests", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/requests", ID: "listRequests", Summary: "Every subtitle request, newest first", Response: []subtitleRequest{}},
+			{Method: http.MethodPost, Path: "/api/v1/requests", ID: "createRequest", Summary: "Ask for a subtitle the item is missing; 200 with the waiting request when it was asked for already", Body: newRequest{}, AlsoForm: true, Response: subtitleRequest{}, Status: http.StatusCreated},
+		}},
+		{Pattern: "/api/v1/requests/", Handler: http.HandlerFunc(h.RequestsAPIHandler), Tag: "Requests", Operations: []apiOperation{
+			{Method: http.MethodPost, Path: "/api/v1/requests/{requestId}/approve", ID: "approveRequest", Summary: "Approve a waiting request and hunt the subtitle in the background", Response: subtitleRequest{}},
+			{Method: http.MethodPost, Path: "/api/v1/requests/{requestId}/reject", ID: "rejectRequest", Summary: "Turn down a waiting request", Response: subtitleRequest{}},
+			{Method: http.MethodDelete, Path: "/api/v1/requests/{requestId}", ID: "deleteRequest", Summary: "Delete a request", Status: http.StatusNoContent},
+		}},
+		{Pattern: "/api/v1/events", Handler: h.Events, Tag: "Service", Operations: []apiOperation{
+			{Method: http.MethodGet, Path: "/api/v1/events", ID: "events", Summary: "WebSocket sending a JSON message {type, time, data} for each change", Status: http.StatusSwitchingProtocols},
+		}},
+	}
+}
+
+// APIDocsHandler serves the API documentation: GET /api/docs shows it in
+// Swagger UI, GET /api/docs/openapi.json is the OpenAPI document and the
+// rest of /api/docs/ are Swagger UI's files.
+func (h *Handler) APIDocsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, "/api/docs") {
+	case "":
+		render(w, r, http.StatusOK, "apidocs", nil)
+	case "/openapi.json":
+		writeJSON(w, http.StatusOK, h.OpenAPI(h.APIRoutes()))
+	case "/", "/index.html", "/swagger-initializer.js":
+		// Swagger UI's own page shows its example API
+		redirect(w, r, "/api/docs")
+	default:
+		http.StripPrefix("/api/docs", http.FileServer(http.FS(swaggerFiles.FS))).ServeHTTP(w, r)
+	}
+}
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// OpenAPI returns the OpenAPI document of the routes.
+func (h *Handler) OpenAPI(routes []APIRoute) openapi.Document {
+	schemas := openapi.NewSchemas()
+	doc := openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "Subtitle Hunter",
+			Description: "Finds, downloads and translates subtitles for a Jellyfin library. When ADMIN_PASSWORD is set, calls need the admin password (HTTP basic auth, user admin) or the login cookie, except those marked as open to viewers.",
+			Version:     "v1",
+		},
+		Paths: make(map[string]openapi.PathItem),
+		Components: openapi.Components{SecuritySchemes: map[string]openapi.SecurityScheme{
+			"admin": {Type: "http", Scheme: "basic", Description: "User admin with ADMIN_PASSWORD"},
+		}},
+		Security: []openapi.SecurityRequirement{{"admin": {}}},
+	}
+	if base := web.BasePath(); base != "" {
+		doc.Servers = []openapi.Server{{URL: base}}
+	}
+
+	tags := make(map[string]bool)
+	for _, route := range routes {
+		if !tags[route.Tag] {
+			tags[route.Tag] = true
+			doc.Tags = append(doc.Tags, openapi.Tag{Name: route.Tag})
+		}
+		for _, op := range route.Operations {
+			item := doc.Paths[op.Path]
+			if item == nil {
+				item = make(openapi.PathItem)
+				doc.Paths[op.Path] = item
+			}
+			item[strings.ToLower(op.Method)] = apiOperationDoc(route, op, schemas)
+		}
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	doc.Components.Schemas = schemas.Components()
+	return doc
+}
+
+func apiOperationDoc(route APIRoute, op apiOperation, schemas *openapi.Schemas) *openapi.Operation {
+	doc := &openapi.Operation{
+		Tags:        []string{route.Tag},
+		Summary:     op.Summary,
+		OperationID: op.ID,
+		Responses: map[string]openapi.Response{
+			"default": {Description: "Error", Content: plainText()},
+		},
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		doc.Parameters = append(doc.Parameters, openapi.Parameter{Name: match[1], In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}})
+	}
+	for _, param := range op.Query {
+		doc.Parameters = append(doc.Parameters, openapi.Parameter{Name: param.Name, In: "query", Description: param.Description, Schema: paramSchema(param)})
+	}
+
+	switch {
+	case op.Body != nil:
+		schema := schemas.Of(op.Body)
+		doc.RequestBody = &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{"application/json": {Schema: schema}}}
+		if op.AlsoForm {
+			doc.RequestBody.Content["application/x-www-form-urlencoded"] = openapi.MediaType{Schema: schema}
+		}
+	case op.Form != nil:
+		form := &openapi.Schema{Type: "object", Properties: make(map[string]*openapi.Schema)}
+		contentType := "application/x-www-form-urlencoded"
+		for _, param := range op.Form {
+			form.Properties[param.Name] = paramSchema(param)
+			if param.Type == "file" {
+				contentType = "multipart/form-data"
+			}
+		}
+		doc.RequestBody = &openapi.RequestBody{Content: map[string]openapi.MediaType{contentType: {Schema: form}}}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := openapi.Response{Description: http.StatusText(status)}
+	switch {
+	case op.Response != nil:
+		response.Content = map[string]openapi.MediaType{"application/json": {Schema: schemas.Of(op.Response)}}
+	case status != http.StatusNoContent && status != http.StatusSwitchingProtocols:
+		response.Content = plainText()
+	}
+	doc.Responses[strconv.Itoa(status)] = response
+
+	if viewerAllowed(&http.Request{Method: op.Method, URL: &url.URL{Path: pathParamPattern.ReplaceAllString(op.Path, "id")}}) {
+		open := []openapi.SecurityRequirement{}
+		doc.Security = &open
+		doc.Summary += " (open to viewers)"
+	}
+	return doc
+}
+
+func paramSchema(param apiParam) *openapi.Schema {
+	schema := &openapi.Schema{Type: "string", Description: param.Description}
+	switch param.Type {
+	case "integer", "boolean":
+		schema.Type = param.Type
+	case "file":
+		schema.Format = "binary"
+	}
+	return schema
+}
+
+func plainText() map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}}
+}
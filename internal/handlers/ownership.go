@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// subtitleFileMode returns the permissions written subtitles get.
+func (h *Handler) subtitleFileMode() os.FileMode {
+	if mode := h.Config().SubtitleFileMode; mode != 0 {
+		return mode
+	}
+	return 0644
+}
+
+// writeSubtitleFile writes a subtitle atomically with the configured
+// permissions, so it never has other ones, and gives it the configured
+// owner.
+func (h *Handler) writeSubtitleFile(path string, content []byte) error {
+	if err := h.TempFiles.WriteFileAtomic(path, content, h.subtitleFileMode()); err != nil {
+		return err
+	}
+	return h.applyOwner(path)
+}
+
+// applyOwnership gives a subtitle moved into place the configured
+// permissions and owner, so that a Jellyfin running as another user than
+// this service can read it.
+func (h *Handler) applyOwnership(path string) error {
+	if mode := h.Config().SubtitleFileMode; mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			return fmt.Errorf("failed to set the permissions of %s: %w", path, err)
+		}
+	}
+	return h.applyOwner(path)
+}
+
+// applyOwner gives the file at path the configured owner. A symlink gets
+// it itself rather than the file it points to.
+func (h *Handler) applyOwner(path string) error {
+	cfg := h.Config()
+	if cfg.SubtitleUID == -1 && cfg.SubtitleGID == -1 {
+		return nil
+	}
+	if err := os.Lchown(path, cfg.SubtitleUID, cfg.SubtitleGID); err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("not permitted to give subtitles to uid %d, gid %d: changing owners takes root or CAP_CHOWN, so run the container as root, or as that user and unset SUBTITLE_UID and SUBTITLE_GID", cfg.SubtitleUID, cfg.SubtitleGID)
+		}
+		return fmt.Errorf("failed to change the owner of %s: %w", path, err)
+	}
+	return nil
+}
+
+// CheckOwnership reports at startup whether the configured owner can be
+// given to subtitles, by giving it to a temporary file.
+func (h *Handler) CheckOwnership() error {
+	if h.Config().SubtitleUID == -1 && h.Config().SubtitleGID == -1 {
+		return nil
+	}
+
+	file, err := h.TempFiles.CreateTemp("owner-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	return h.applyOwnership(file.Name())
+}
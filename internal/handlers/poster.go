@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"subtitle-hunter/internal/jellyfin"
+)
+
+// posterHeight is the height posters are fetched at: twice the size they
+// are shown at, for high-density screens.
+const posterHeight = 360
+
+// posterImage serves /items/{id}/poster, an item's or series' poster from
+// Jellyfin. It is fetched here rather than by the browser so the Jellyfin
+// API key stays on the server.
+func (h *Handler) posterImage(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	image, contentType, err := h.JellyfinClient.GetPrimaryImage(r.Context(), itemID, posterHeight)
+	if errors.Is(err, jellyfin.ErrNoImage) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(image)
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"subtitle-hunter/config"
+	"subtitle-hunter/internal/usage"
+)
+
+// lowQuotaPercent is the share of a quota left below which the providers
+// page warns that it is about to run out.
+const lowQuotaPercent = 10
+
+// Services whose calls are counted, besides the translator backends.
+const (
+	serviceJellyfin      = "Jellyfin"
+	serviceOpenSubtitles = "OpenSubtitles"
+)
+
+// serviceDomains maps the domains of the external APIs to the services
+// their calls are counted for. Subdomains count as well, so downloads from
+// www.opensubtitles.com count for OpenSubtitles.
+var serviceDomains = map[string]string{
+	"opensubtitles.com":        serviceOpenSubtitles,
+	"opensubtitles.org":        serviceOpenSubtitles,
+	"translate.googleapis.com": config.TranslatorGoogle,
+	"deepl.com":                config.TranslatorDeepL,
+}
+
+type providerQuota struct {
+	Used int `json:"used"`
+	// Remaining is -1 while unknown.
+	Remaining int       `json:"remaining"`
+	Limit     int       `json:"limit"`
+	Unit      string    `json:"unit"`
+	ResetAt   time.Time `json:"reset_at"`
+	// Low is set when less than lowQuotaPercent of the quota is left.
+	Low bool `json:"low"`
+}
+
+type providerStatus struct {
+	Service         string `json:"service"`
+	CallsToday      int    `json:"calls_today"`
+	CallsThisMonth  int    `json:"calls_this_month"`
+	ErrorsToday     int    `json:"errors_today"`
+	ErrorsThisMonth int    `json:"errors_this_month"`
+	// ErrorRate is the percentage of today's calls that failed.
+	ErrorRate float64 `json:"error_rate"`
+	usage.Calls
+	Quota *providerQuota `json:"quota,omitempty"`
+}
+
+// RecordCall counts an attempted call to an external API for the service
+// at host, for the shared HTTP transport's Observe hook. Calls answered
+// with an error status count as failed. Calls to other hosts aren't
+// counted.
+func (h *Handler) RecordCall(host string, resp *http.Response, err error) {
+	service := h.serviceForHost(host)
+	if service == "" {
+		return
+	}
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		err = fmt.Errorf("status %d", resp.StatusCode)
+	}
+	h.Usage.RecordCall(service, err)
+}
+
+func (h *Handler) serviceForHost(host string) string {
+	if h.JellyfinClient != nil {
+		if jellyfinURL, err := url.Parse(h.JellyfinClient.BaseURL); err == nil && jellyfinURL.Host == host {
+			return serviceJellyfin
+		}
+	}
+	name := strings.ToLower(host)
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	for domain, service := range serviceDomains {
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return service
+		}
+	}
+	return ""
+}
+
+func (h *Handler) providerStatus(service string) providerStatus {
+	status := providerStatus{
+		Service:         service,
+		CallsToday:      h.Usage.Today(usage.CallsCounter(service)),
+		CallsThisMonth:  h.Usage.ThisMonth(usage.CallsCounter(service)),
+		ErrorsToday:     h.Usage.Today(usage.ErrorsCounter(service)),
+		ErrorsThisMonth: h.Usage.ThisMonth(usage.ErrorsCounter(service)),
+		Calls:           h.Usage.Calls(service),
+	}
+	if status.CallsToday > 0 {
+		status.ErrorRate = float64(status.ErrorsToday) / float64(status.CallsToday) * 100
+	}
+	return status
+}
+
+// providersView lists the external services: Jellyfin, OpenSubtitles with
+// the downloads left across its accounts, and each translator backend with
+// the characters left of the monthly budget.
+func (h *Handler) providersView() []providerStatus {
+	providers := []providerStatus{h.providerStatus(serviceJellyfin)}
+
+	openSubtitles := h.providerStatus(serviceOpenSubtitles)
+	openSubtitles.Quota = h.openSubtitlesQuota()
+	providers = append(providers, openSubtitles)
+
+	budget := h.Config().TranslationCharBudget
+	for _, backend := range h.Backends {
+		status := h.providerStatus(backend.Name)
+		if budget > 0 {
+			used := h.Usage.ThisMonth(translatedCharsCounter(backend.Name))
+			now := time.Now()
+			status.Quota = &providerQuota{
+				Used:      used,
+				Remaining: max(budget-used, 0),
+				Limit:     budget,
+				Unit:      "characters",
+				ResetAt:   time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location()),
+			}
+			status.Quota.Low = status.Quota.Remaining*100 < budget*lowQuotaPercent
+		}
+		providers = append(providers, status)
+	}
+	return providers
+}
+
+// openSubtitlesQuota adds up the downloads of the OpenSubtitles accounts
+// whose counts are known; it is nil until one is.
+func (h *Handler) openSubtitlesQuota() *providerQuota {
+	var quota *providerQuota
+	for _, instance := range h.OpenSubtitlesClient.Status() {
+		if instance.Remaining < 0 {
+			continue
+		}
+		if quota == nil {
+			quota = &providerQuota{Unit: "downloads", ResetAt: instance.ResetAt}
+		}
+		quota.Used += instance.Used
+		quota.Remaining += instance.Remaining
+		quota.Limit += instance.Used + instance.Remaining
+		if instance.ResetAt.Before(quota.ResetAt) {
+			quota.ResetAt = instance.ResetAt
+		}
+	}
+	if quota != nil {
+		quota.Low = quota.Remaining*100 < quota.Limit*lowQuotaPercent
+	}
+	return quota
+}
+
+// ProvidersHandler shows, for each external service, the calls made today
+// and this month, how many failed, when a call last succeeded and failed,
+// and how much of its quota is left.
+func (h *Handler) ProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	render(w, r, http.StatusOK, "providers", h.providersView())
+}
+
+// ProvidersAPIHandler returns the same information as the providers page
+// as JSON.
+func (h *Handler) ProvidersAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.providersView())
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/events"
+	"subtitle-hunter/internal/opensubtitles"
+	"subtitle-hunter/internal/translator"
+)
+
+// deeplProCostPerMillionChars is what DeepL API Pro charges for a million
+// characters, in USD.
+const deeplProCostPerMillionChars = 25
+
+const schedulerPausedKey = "paused"
+
+func translatedCharsCounter(backend string) string {
+	return "translated-chars:" + backend
+}
+
+type translatorUsage struct {
+	Backend string `json:"backend"`
+	// Characters is this month's usage; Budget is 0 when no budget is set.
+	Characters int     `json:"characters"`
+	Budget     int     `json:"budget"`
+	Percent    float64 `json:"percent"`
+	// Chain is the backend's place in the translator chain, 0 when it
+	// isn't in it.
+	Chain  int                      `json:"chain,omitempty"`
+	Health translator.BackendHealth `json:"health"`
+	// Down is set while the chain skips the backend.
+	Down bool `json:"down"`
+}
+
+type schedulerStatus struct {
+	Enabled      bool      `json:"enabled"`
+	Paused       bool      `json:"paused"`
+	Running      bool      `json:"running"`
+	Interval     string    `json:"interval"`
+	LastRun      time.Time `json:"last_run"`
+	NextRun      time.Time `json:"next_run"`
+	LastFullScan time.Time `json:"last_full_scan"`
+}
+
+type quotaView struct {
+	OpenSubtitles []opensubtitles.InstanceStatus `json:"opensubtitles"`
+	Translators   []translatorUsage              `json:"translators"`
+	Budget        budgetStatus                   `json:"budget"`
+	Scheduler     *schedulerStatus               `json:"scheduler,omitempty"`
+}
+
+func (h *Handler) quotaView() quotaView {
+	view := quotaView{OpenSubtitles: h.OpenSubtitlesClient.Status(), Budget: h.budgetStatus()}
+
+	budget := h.Config().TranslationCharBudget
+	for _, backend := range h.Backends {
+		used := h.Usage.ThisMonth(translatedCharsCounter(backend.Name))
+		usage := translatorUsage{Backend: backend.Name, Characters: used, Budget: budget, Health: h.TranslatorHealth.Status(backend.Name)}
+		if budget > 0 {
+			usage.Percent = float64(used) / float64(budget) * 100
+		}
+		usage.Down = usage.Health.Down(time.Now())
+		for i, name := range h.translatorNames() {
+			if name == backend.Name {
+				usage.Chain = i + 1
+			}
+		}
+		view.Translators = append(view.Translators, usage)
+	}
+
+	if h.Scheduler != nil {
+		state := h.Scheduler.State()
+		view.Scheduler = &schedulerStatus{
+			Enabled:      state.Interval > 0,
+			Paused:       state.Paused,
+			Running:      state.Running,
+			Interval:     state.Interval.String(),
+			LastRun:      state.LastRun,
+			NextRun:      state.NextRun,
+			LastFullScan: state.LastFullScan,
+		}
+	}
+
+	return view
+}
+
+// QuotaHandler shows provider quotas, translator usage against the monthly
+// budget, today's downloads and translations against the daily budget and
+// the scheduler state.
+func (h *Handler) QuotaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	render(w, r, http.StatusOK, "quota", h.quotaView())
+}
+
+// QuotaAPIHandler returns the same information as the quota page as JSON.
+func (h *Handler) QuotaAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.quotaView())
+}
+
+// SchedulerHandler pauses or resumes automatic hunting:
+// POST /api/v1/scheduler/pause and POST /api/v1/scheduler/resume. Form
+// submissions are redirected back to the quota page.
+func (h *Handler) SchedulerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Scheduler == nil {
+		http.Error(w, "Scheduler not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var paused bool
+	switch strings.TrimPrefix(r.URL.Path, "/api/v1/scheduler/") {
+	case "pause":
+		h.Scheduler.Pause()
+		h.Events.Publish(events.SchedulerPaused, nil)
+		paused = true
+	case "resume":
+		h.Scheduler.Resume()
+		h.Events.Publish(events.SchedulerResumed, nil)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := h.Store.Put("scheduler", schedulerPausedKey, paused); err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to save scheduler state: %v", err))
+		return
+	}
+
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/quota"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SchedulerPaused reports whether auto-hunt was left paused, so the state
+// survives a restart.
+func (h *Handler) SchedulerPaused() bool {
+	var paused bool
+	h.Store.Get("scheduler", schedulerPausedKey, &paused)
+	return paused
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "—"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/wanted"
+)
+
+// errNothingReplaced is returned when none of an item's saved subtitles
+// were saved for another video file.
+var errNothingReplaced = errors.New("no subtitle was saved for another video file")
+
+type recheckResponse struct {
+	JobID string `json:"job_id"`
+	// Targets are the subtitles that were saved for the old video file.
+	Targets []string `json:"targets"`
+	Message string   `json:"message"`
+}
+
+// MediaReplaced reports whether any of the item's saved subtitles was
+// saved for a video file the item no longer has.
+func (h *Handler) MediaReplaced(item *jellyfin.MediaItem) bool {
+	targets, err := h.Wanted.ReplacedTargets(*item)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return len(targets) > 0
+}
+
+// Recheck fetches the subtitles saved for the item's old video file again
+// in a job, after the file was replaced by another release.
+func (h *Handler) Recheck(ctx context.Context, item *jellyfin.MediaItem, trigger string) (string, *ProcessResult, error) {
+	return h.RunJob(ctx, item, trigger, func(h *Handler, ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error) {
+		return h.recheckItem(ctx, item)
+	})
+}
+
+// recheckAPI handles POST /api/v1/items/{id}/recheck. It hunts every
+// target whose subtitle was saved for another video file again and answers
+// once the job is done. It takes a JSON body or a form, in which case the
+// browser is sent back to the item page.
+func (h *Handler) recheckAPI(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		log.Printf("Error getting item details for %s: %v", itemID, err)
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+	targets, err := h.Wanted.ReplacedTargets(*item)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(targets) == 0 {
+		respond(w, r, http.StatusConflict, errNothingReplaced.Error())
+		return
+	}
+
+	jobID, result, err := h.Recheck(r.Context(), item, jobs.TriggerManual)
+	if jobID != "" {
+		w.Header().Set("X-Job-ID", jobID)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errNoSubtitles) {
+			status = http.StatusNotFound
+		}
+		respond(w, r, status, err.Error())
+		return
+	}
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/items/"+item.ID))
+		return
+	}
+
+	response := recheckResponse{JobID: jobID, Message: result.Message(h.Config().SubtitleDirectory)}
+	for _, target := range targets {
+		response.Targets = append(response.Targets, target.String())
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// recheckItem hunts every target of the item whose subtitle was saved for
+// another video file again. Subtitles named after an old video file that is
+// gone are archived, since nothing plays them any more. A target for which
+// nothing new is found keeps its subtitle and stays flagged. The first
+// successful result is returned.
+func (h *Handler) recheckItem(ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error) {
+	targets, err := h.Wanted.ReplacedTargets(*item)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, errNothingReplaced
+	}
+
+	videoPath, _ := item.VideoFile()
+	var primary *ProcessResult
+	var firstErr error
+	for _, target := range targets {
+		old, _, err := h.Wanted.Result(item.ID, target)
+		if err != nil {
+			return nil, err
+		}
+		h.job.Logf("The %s subtitle was saved for %s (%d bytes); the video is now %s", target, old.VideoPath, old.VideoSize, videoPath)
+		if old.VideoPath != videoPath {
+			h.archiveOrphans(item, old.VideoPath, target)
+		}
+
+		result, err := h.huntTarget(ctx, item, target)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			h.job.Logf("Found no new %s subtitle, so it stays flagged: %v", target, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			if errors.Is(err, ErrBudgetExhausted) {
+				break
+			}
+			continue
+		}
+		if primary == nil {
+			primary = result
+		}
+	}
+
+	h.Library.Invalidate(item.ID)
+	h.refreshMetadata(ctx, item)
+	if primary == nil {
+		return nil, firstErr
+	}
+	return primary, nil
+}
+
+// archiveOrphans renames the subtitles for target named after a video file
+// that no longer exists, so players and Jellyfin stop seeing them. The
+// subtitles of a video that is still there are left alone, as another item
+// may be using them.
+func (h *Handler) archiveOrphans(item *jellyfin.MediaItem, oldVideoPath string, target wanted.Target) {
+	if _, err := os.Stat(h.containerPath(item, oldVideoPath)); !os.IsNotExist(err) {
+		return
+	}
+	for _, file := range h.subtitleFiles(&jellyfin.MediaItem{ID: item.ID, Path: oldVideoPath}) {
+		if !file.matches(target) {
+			continue
+		}
+		archived := fmt.Sprintf("%s.%s.replaced", file.Path, time.Now().Format("20060102-150405"))
+		if err := os.Rename(file.Path, archived); err != nil {
+			h.job.Logf("Warning: failed to archive %s: %v", file.Path, err)
+			continue
+		}
+		h.job.Logf("Archived %s, named after the old video", file.Path)
+	}
+}
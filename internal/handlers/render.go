@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/release"
+	"subtitle-hunter/web"
+)
+
+// templateFuncs are available to every page template.
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"when": formatTime,
+	"size": formatSize,
+	// duration reads milliseconds: {{duration .ETAMs}}
+	"duration": formatDuration,
+	// base goes before links to other pages: {{base}}/wanted
+	"base": web.BasePath,
+	// release reads a release or file name: {{(release .Release).Tags}}
+	"release": release.Parse,
+}
+
+// render writes the page template web/templates/{name}.html with data, in
+// the interface language for r. Templates are parsed on every call, so
+// edits to a theme's templates show up without a restart.
+func render(w http.ResponseWriter, r *http.Request, status int, name string, data interface{}) {
+	locale, catalog := web.Locale(r.Header.Get("Accept-Language"))
+	funcs := template.FuncMap{
+		// t translates a message; {{t "Found %d subtitles" .Count}}
+		"t":    catalog.HTML,
+		"lang": locale.String,
+		// name is a language's name as readers of the interface know it
+		"name": func(tag lang.Tag) string {
+			if locale == lang.English {
+				return tag.DisplayName()
+			}
+			return tag.NativeName()
+		},
+		// admin hides what viewers can't do: {{if admin}}
+		"admin": func() bool { return roleOf(r) == RoleAdmin },
+	}
+
+	t, err := template.New(name+".html").Funcs(templateFuncs).Funcs(funcs).ParseFS(web.FS(), "templates/"+name+".html")
+	if err != nil {
+		log.Printf("Error: failed to parse template: %v", err)
+		http.Error(w, "Template parsing failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("Error: failed to execute template %s: %v", name, err)
+	}
+}
@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/wanted"
+)
+
+// requestsBucket holds the subtitles asked for by viewers, keyed by ID.
+const requestsBucket = "subtitle-requests"
+
+// States of a subtitle request.
+const (
+	requestPending  = "pending"
+	requestApproved = "approved"
+	requestRejected = "rejected"
+)
+
+// subtitleRequest is a subtitle a viewer asked for, waiting for the admin
+// to approve it, which hunts it, or reject it.
+type subtitleRequest struct {
+	ID       string `json:"id"`
+	ItemID   string `json:"item_id"`
+	ItemName string `json:"item_name"`
+	Language string `json:"language"`
+	Forced   bool   `json:"forced,omitempty"`
+	// Name is who asked, as they gave it.
+	Name      string    `json:"name,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	DecidedAt time.Time `json:"decided_at,omitempty"`
+	// JobID and Outcome are the hunt approving the request started and
+	// what it came to, once it is done.
+	JobID   string `json:"job_id,omitempty"`
+	Outcome string `json:"outcome,omitempty"`
+}
+
+func (req subtitleRequest) target() wanted.Target {
+	return wanted.Target{Language: lang.Parse(req.Language), Forced: req.Forced}
+}
+
+// DisplayName is the requested language's name, marked "(forced)" for a
+// forced subtitle.
+func (req subtitleRequest) DisplayName() string {
+	return req.target().DisplayName()
+}
+
+type newRequest struct {
+	ItemID   string `json:"item_id"`
+	Language string `json:"language"`
+	Forced   bool   `json:"forced"`
+	Name     string `json:"name"`
+	Note     string `json:"note"`
+}
+
+type requestsView struct {
+	Pending []subtitleRequest
+	Decided []subtitleRequest
+	// Roles is set when an admin password is, so there is logging in.
+	Roles bool
+}
+
+var (
+	errRequestNotFound = errors.New("request not found")
+	errRequestDecided  = errors.New("the request was already decided on")
+)
+
+// maxRequestText bounds the name and note of a request.
+const maxRequestText = 500
+
+// listRequests returns every subtitle request, oldest first.
+func (h *Handler) listRequests() ([]subtitleRequest, error) {
+	ids, err := h.Store.Keys(requestsBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list requests: %w", err)
+	}
+	sort.Strings(ids)
+
+	var all []subtitleRequest
+	for _, id := range ids {
+		var req subtitleRequest
+		ok, err := h.Store.Get(requestsBucket, id, &req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load request: %w", err)
+		}
+		if ok {
+			all = append(all, req)
+		}
+	}
+	return all, nil
+}
+
+// PendingRequests returns the requests waiting for approval, oldest first.
+func (h *Handler) PendingRequests() ([]subtitleRequest, error) {
+	all, err := h.listRequests()
+	if err != nil {
+		return nil, err
+	}
+	var pending []subtitleRequest
+	for _, req := range all {
+		if req.Status == requestPending {
+			pending = append(pending, req)
+		}
+	}
+	return pending, nil
+}
+
+// requestedTargets returns the targets with a pending request, keyed by
+// item ID and target as "{id}/{target}".
+func (h *Handler) requestedTargets() map[string]bool {
+	pending, err := h.PendingRequests()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	requested := make(map[string]bool, len(pending))
+	for _, req := range pending {
+		requested[req.ItemID+"/"+req.target().String()] = true
+	}
+	return requested
+}
+
+// updateRequest changes a saved request with fn, which returns an error to
+// leave it as it was.
+func (h *Handler) updateRequest(id string, fn func(req *subtitleRequest) error) (subtitleRequest, error) {
+	h.requestsMu.Lock()
+	defer h.requestsMu.Unlock()
+
+	var req subtitleRequest
+	ok, err := h.Store.Get(requestsBucket, id, &req)
+	if err != nil {
+		return req, fmt.Errorf("failed to load request: %w", err)
+	}
+	if !ok {
+		return req, errRequestNotFound
+	}
+	if err := fn(&req); err != nil {
+		return req, err
+	}
+	if err := h.Store.Put(requestsBucket, req.ID, req); err != nil {
+		return req, fmt.Errorf("failed to save request: %w", err)
+	}
+	return req, nil
+}
+
+// RequestsHandler serves the requests page: the subtitles waiting for
+// approval, with buttons to approve or reject them for the admin, and the
+// ones decided on with how their hunt went.
+func (h *Handler) RequestsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	all, err := h.listRequests()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	view := requestsView{Roles: h.Config().AdminPassword != ""}
+	for _, req := range all {
+		if req.Status == requestPending {
+			view.Pending = append(view.Pending, req)
+		} else {
+			view.Decided = append(view.Decided, req)
+		}
+	}
+	// Pending requests oldest first, as they are dealt with, the decided
+	// ones latest first
+	sort.SliceStable(view.Decided, func(i, j int) bool { return view.Decided[i].DecidedAt.After(view.Decided[j].DecidedAt) })
+	render(w, r, http.StatusOK, "requests", view)
+}
+
+// RequestsAPIHandler serves GET /api/v1/requests, every subtitle request,
+// newest first, POST /api/v1/requests, which asks for a subtitle, and for
+// the admin POST /api/v1/requests/{id}/{approve,reject,delete} and DELETE
+// /api/v1/requests/{id}.
+func (h *Handler) RequestsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/requests"), "/")
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			all, err := h.listRequests()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			newest := make([]subtitleRequest, 0, len(all))
+			for i := len(all) - 1; i >= 0; i-- {
+				newest = append(newest, all[i])
+			}
+			writeJSON(w, http.StatusOK, newest)
+		case http.MethodPost:
+			h.createRequest(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, action, _ := strings.Cut(path, "/")
+	if r.Method == http.MethodDelete && action == "" {
+		action = "delete"
+	} else if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req subtitleRequest
+	var err error
+	switch action {
+	case "approve", "reject":
+		req, err = h.updateRequest(id, func(req *subtitleRequest) error {
+			if req.Status != requestPending {
+				return fmt.Errorf("%w: it was %s", errRequestDecided, req.Status)
+			}
+			req.Status = requestRejected
+			if action == "approve" {
+				req.Status = requestApproved
+			}
+			req.DecidedAt = time.Now()
+			return nil
+		})
+	case "delete":
+		h.requestsMu.Lock()
+		var ok bool
+		ok, err = h.Store.Get(requestsBucket, id, &req)
+		if err == nil && !ok {
+			err = errRequestNotFound
+		}
+		if err == nil {
+			err = h.Store.Delete(requestsBucket, id)
+		}
+		h.requestsMu.Unlock()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case errors.Is(err, errRequestNotFound):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, errRequestDecided):
+		respond(w, r, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch action {
+	case "approve":
+		log.Printf("Subtitle request %s approved: %s for %s", req.ID, req.target(), req.ItemName)
+		go h.fulfilRequest(h.Context, req)
+	case "reject":
+		log.Printf("Subtitle request %s rejected: %s for %s", req.ID, req.target(), req.ItemName)
+	}
+
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/requests"))
+		return
+	}
+	if action == "delete" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+// createRequest asks for a subtitle the item is missing. A request for the
+// same subtitle still waiting is answered instead of adding another.
+func (h *Handler) createRequest(w http.ResponseWriter, r *http.Request) {
+	var body newRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		body.ItemID = r.FormValue("item_id")
+		body.Language = r.FormValue("language")
+		body.Forced = r.FormValue("forced") == "true"
+		body.Name = r.FormValue("name")
+		body.Note = r.FormValue("note")
+	}
+	language := lang.Normalize(body.Language)
+	if body.ItemID == "" || language.IsZero() {
+		respond(w, r, http.StatusBadRequest, "Item ID and language required")
+		return
+	}
+	if len(body.Name) > maxRequestText || len(body.Note) > maxRequestText {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("The name and note can be at most %d bytes", maxRequestText))
+		return
+	}
+	target := wanted.Target{Language: language, Forced: body.Forced}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), body.ItemID)
+	if err != nil {
+		log.Printf("Error getting item details for %s: %v", body.ItemID, err)
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+	wantedTarget := false
+	for _, t := range h.huntTargets(item) {
+		wantedTarget = wantedTarget || t == target
+	}
+	if !wantedTarget {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("%s is not a language subtitles are hunted in for this item", target.DisplayName()))
+		return
+	}
+	status, err := h.Wanted.Status(*item, target)
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if status != wanted.StatusMissing {
+		respond(w, r, http.StatusConflict, fmt.Sprintf("The %s subtitle is not missing (%s)", target.DisplayName(), status))
+		return
+	}
+
+	h.requestsMu.Lock()
+	pending, err := h.PendingRequests()
+	var req subtitleRequest
+	created := true
+	for _, p := range pending {
+		if p.ItemID == item.ID && p.target() == target {
+			req, created = p, false
+		}
+	}
+	if err == nil && created {
+		now := time.Now()
+		name := item.Name
+		if item.SeriesName != "" {
+			name = fmt.Sprintf("%s S%02dE%02d %s", item.SeriesName, item.ParentIndexNumber, item.IndexNumber, item.Name)
+		}
+		req = subtitleRequest{
+			ID:        fmt.Sprintf("%019d", now.UnixNano()),
+			ItemID:    item.ID,
+			ItemName:  name,
+			Language:  language.String(),
+			Forced:    body.Forced,
+			Name:      strings.TrimSpace(body.Name),
+			Note:      strings.TrimSpace(body.Note),
+			Status:    requestPending,
+			CreatedAt: now,
+		}
+		err = h.Store.Put(requestsBucket, req.ID, req)
+	}
+	h.requestsMu.Unlock()
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to save request: %v", err))
+		return
+	}
+	if created {
+		log.Printf("Subtitle request %s: %s for %s", req.ID, target, req.ItemName)
+	}
+
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/requests"))
+		return
+	}
+	if created {
+		writeJSON(w, http.StatusCreated, req)
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+// fulfilRequest hunts the subtitle of an approved request as a request job
+// and records how it went. A subtitle found in the meantime is not hunted
+// again.
+func (h *Handler) fulfilRequest(ctx context.Context, req subtitleRequest) {
+	record := func(jobID, outcome string) {
+		_, err := h.updateRequest(req.ID, func(req *subtitleRequest) error {
+			req.JobID = jobID
+			req.Outcome = outcome
+			return nil
+		})
+		if err != nil && !errors.Is(err, errRequestNotFound) {
+			log.Printf("Subtitle request %s: %v", req.ID, err)
+		}
+	}
+
+	item, err := h.JellyfinClient.GetItem(ctx, req.ItemID)
+	if err != nil {
+		record("", fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+	target := req.target()
+	if status, err := h.Wanted.Status(*item, target); err == nil && status != wanted.StatusMissing {
+		record("", fmt.Sprintf("The subtitle is no longer missing (%s)", status))
+		return
+	}
+
+	jobID, result, err := h.RunJob(ctx, item, jobs.TriggerRequest, func(h *Handler, ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error) {
+		h.job.Logf("Hunting the %s subtitle requested%s", target, requestedBy(req))
+		result, err := h.huntTarget(ctx, item, target)
+		if err != nil {
+			return nil, err
+		}
+		h.Library.Invalidate(item.ID)
+		h.refreshMetadata(ctx, item)
+		return result, nil
+	})
+	if err != nil {
+		record(jobID, err.Error())
+		return
+	}
+	record(jobID, result.Message(h.Config().SubtitleDirectory))
+}
+
+// requestedBy names who made the request, if they said.
+func requestedBy(req subtitleRequest) string {
+	if req.Name == "" {
+		return ""
+	}
+	return " by " + req.Name
+}
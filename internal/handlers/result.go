@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/web"
+)
+
+type resultView struct {
+	Message string
+	Failed  bool
+	Back    string
+	// Job is the ID of the job that did the work, if one did.
+	Job string
+}
+
+// wantsHTML reports whether the request was submitted by a plain HTML form
+// rather than a script, in which case the response should be a page the
+// browser can show instead of bare text. fetch() sends "Accept: */*".
+func wantsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// returnPath is where a form submission should lead back to: the form's
+// "return" field when it is a local path, otherwise fallback.
+func returnPath(r *http.Request, fallback string) string {
+	path := r.FormValue("return")
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return fallback
+	}
+	return path
+}
+
+// redirect sends a form submission on to path, a path from the root of the
+// interface, under the base path the interface is served at.
+func redirect(w http.ResponseWriter, r *http.Request, path string) {
+	http.Redirect(w, r, web.URL(path), http.StatusSeeOther)
+}
+
+// respond finishes an action request. Scripts get the message as plain
+// text (or an error); form submissions get a small page with the message
+// and a link back to where they came from.
+func respond(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if !wantsHTML(r) {
+		if status >= http.StatusBadRequest {
+			http.Error(w, message, status)
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(message))
+		return
+	}
+
+	view := resultView{
+		Message: message,
+		Failed:  status >= http.StatusBadRequest,
+		Back:    returnPath(r, "/"),
+		Job:     w.Header().Get("X-Job-ID"),
+	}
+
+	render(w, r, status, "result", view)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/subtitle"
+)
+
+// resumeBucket keeps, by item, the cues translated by jobs that stopped
+// before they were done: cancelled, timed out or cut short by a shutdown.
+// Translating the item again picks up where they left off instead of
+// paying for the same cues twice.
+const resumeBucket = "translation-progress"
+
+// resumeTTL is how long the cues of a stopped translation are kept.
+const resumeTTL = 7 * 24 * time.Hour
+
+type translationProgress struct {
+	// Cues maps the text of each translated cue to its translation.
+	Cues    map[string]string `json:"cues"`
+	SavedAt time.Time         `json:"saved_at"`
+}
+
+// translateEntries translates entries for item with Parser.TranslateEntries,
+// starting from the cues an earlier job translated before it stopped. When
+// ctx ends before the translation is done, the cues translated so far are
+// kept for the next time; once one is done they are dropped.
+func (h *Handler) translateEntries(ctx context.Context, item *jellyfin.MediaItem, entries []subtitle.SubtitleEntry, textTranslator subtitle.Translator) ([]subtitle.SubtitleEntry, subtitle.TranslationReport, error) {
+	var progress translationProgress
+	if found, err := h.Store.Get(resumeBucket, item.ID, &progress); err != nil {
+		h.job.Logf("Warning: failed to read the cues translated earlier: %v", err)
+	} else if found && time.Since(progress.SavedAt) < resumeTTL && len(progress.Cues) > 0 {
+		h.job.Logf("Resuming the translation stopped earlier, with %d cues already translated", len(progress.Cues))
+		textTranslator = &resumedTranslator{cues: progress.Cues, next: textTranslator}
+	} else {
+		progress = translationProgress{}
+	}
+
+	translated, report, err := h.Parser.TranslateEntries(ctx, entries, textTranslator, h.Fallback, h.job)
+	if ctx.Err() == nil {
+		if err == nil && len(progress.Cues) > 0 {
+			if deleteErr := h.Store.Delete(resumeBucket, item.ID); deleteErr != nil {
+				h.job.Logf("Warning: %v", deleteErr)
+			}
+		}
+		return translated, report, err
+	}
+
+	failed := make(map[int]bool, len(report.FailedIndexes))
+	for _, index := range report.FailedIndexes {
+		failed[index] = true
+	}
+	if progress.Cues == nil {
+		progress.Cues = make(map[string]string)
+	}
+	for i, entry := range translated {
+		if !failed[entry.Index] {
+			progress.Cues[entries[i].Text] = entry.Text
+		}
+	}
+	if len(progress.Cues) == 0 {
+		return translated, report, err
+	}
+	progress.SavedAt = time.Now()
+	if putErr := h.Store.Put(resumeBucket, item.ID, progress); putErr != nil {
+		h.job.Logf("Warning: failed to keep the cues translated so far: %v", putErr)
+	} else {
+		h.job.Logf("Stopped after %d of %d cues; kept them to resume from when the item is translated again", len(translated), len(entries))
+	}
+	return translated, report, err
+}
+
+// resumedTranslator answers with the translations of a stopped job before
+// asking next.
+type resumedTranslator struct {
+	cues map[string]string
+	next subtitle.Translator
+}
+
+func (rt *resumedTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	if translation, ok := rt.cues[text]; ok {
+		return translation, nil
+	}
+	return rt.next.TranslateToChineseTraditional(ctx, text)
+}
+
+func (rt *resumedTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	if translation, ok := rt.cues[text]; ok {
+		return translation, nil
+	}
+	if next, ok := rt.next.(subtitle.ContextTranslator); ok {
+		return next.TranslateWithContext(ctx, before, text, after)
+	}
+	return rt.next.TranslateToChineseTraditional(ctx, text)
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/subtitle"
+	"subtitle-hunter/internal/translator"
+	"subtitle-hunter/internal/wanted"
+)
+
+type retranslateRequest struct {
+	// Cues are the cue numbers as they appear in the subtitle file.
+	Cues    []int  `json:"cues"`
+	Backend string `json:"backend"`
+	// Failed adds the cues whose translation failed when the subtitle was
+	// translated, as recorded in the wanted list.
+	Failed bool `json:"failed"`
+}
+
+type retranslatedCue struct {
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+}
+
+type retranslateResponse struct {
+	JobID   string            `json:"job_id"`
+	Backend string            `json:"backend"`
+	Cues    []retranslatedCue `json:"cues"`
+	// Failed are the cues that couldn't be translated this time either.
+	Failed []int `json:"failed"`
+}
+
+// retranslateAPI handles POST /api/v1/items/{id}/retranslate, which
+// translates the chosen cues of the item's translated Traditional Chinese
+// subtitle again from their kept originals and merges them back into the
+// file. It takes a JSON body with "cues" and an optional translator
+// "backend", or the same fields as a form ("cue" repeated), in which case
+// the browser is sent back to the editor. With "failed" set, the cues that
+// failed to translate the first time are tried again. The translation
+// memory is skipped, since a remembered translation is what the cue
+// already has.
+func (h *Handler) retranslateAPI(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req retranslateRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			respond(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid form: %v", err))
+			return
+		}
+		for _, value := range r.PostForm["cue"] {
+			index, err := strconv.Atoi(value)
+			if err != nil {
+				respond(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid cue %q", value))
+				return
+			}
+			req.Cues = append(req.Cues, index)
+		}
+		req.Backend = r.FormValue("backend")
+		req.Failed = r.FormValue("failed") == "true"
+	}
+	var failedCues []int
+	if req.Failed {
+		result, _, err := h.Wanted.Result(itemID, wanted.Target{Language: lang.TraditionalChinese})
+		if err != nil {
+			respond(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if len(result.FailedCues) == 0 && len(req.Cues) == 0 {
+			respond(w, r, http.StatusBadRequest, "No cues failed to translate")
+			return
+		}
+		failedCues = result.FailedCues
+	}
+	if len(req.Cues) == 0 && len(failedCues) == 0 {
+		respond(w, r, http.StatusBadRequest, "No cues selected")
+		return
+	}
+
+	backend, ok := h.backend(req.Backend)
+	if !ok {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown translator backend %q", req.Backend))
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		log.Printf("Error getting item details for %s: %v", itemID, err)
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+
+	var cues []retranslatedCue
+	var failed []int
+	jobID, _, err := h.RunJob(r.Context(), item, jobs.TriggerManual, func(h *Handler, ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error) {
+		var err error
+		cues, failed, err = h.retranslateCues(ctx, item, req.Cues, failedCues, backend)
+		if err != nil {
+			return nil, err
+		}
+		report := &subtitle.TranslationReport{Total: len(cues) + len(failed), Failed: len(failed), FailedIndexes: failed}
+		return &ProcessResult{Source: backend.Name, Report: report}, nil
+	})
+	if jobID != "" {
+		w.Header().Set("X-Job-ID", jobID)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errCueSelection) {
+			status = http.StatusBadRequest
+		}
+		respond(w, r, status, err.Error())
+		return
+	}
+
+	if wantsHTML(r) {
+		if len(cues) == 0 {
+			respond(w, r, http.StatusBadGateway, fmt.Sprintf("None of the %d cues could be translated", len(failed)))
+			return
+		}
+		redirect(w, r, returnPath(r, editorURL(itemID, wanted.Target{Language: lang.TraditionalChinese})))
+		return
+	}
+	if cues == nil {
+		cues = []retranslatedCue{}
+	}
+	if failed == nil {
+		failed = []int{}
+	}
+	writeJSON(w, http.StatusOK, retranslateResponse{JobID: jobID, Backend: backend.Name, Cues: cues, Failed: failed})
+}
+
+// errCueSelection is returned for cues that can't be translated again.
+var errCueSelection = errors.New("cannot translate these cues again")
+
+// backend returns the translator backend called name, or the primary one
+// when name is empty.
+func (h *Handler) backend(name string) (translator.Backend, bool) {
+	if name == "" {
+		name = h.primaryBackend()
+	}
+	for _, backend := range h.Backends {
+		if backend.Name == name {
+			return backend, true
+		}
+	}
+	return translator.Backend{}, false
+}
+
+// retranslateCues translates the cues numbered indexes of the item's saved
+// Traditional Chinese subtitle again with backend, along with the recorded
+// failed cues, and writes the results into the file. Failed cues that are
+// part of a split cue are skipped rather than refused. It returns the new
+// text of each cue and the cues that failed, which keep their current
+// text.
+func (h *Handler) retranslateCues(ctx context.Context, item *jellyfin.MediaItem, indexes, failedCues []int, backend translator.Backend) ([]retranslatedCue, []int, error) {
+	target := lang.TraditionalChinese.String()
+	videoPath := h.itemVideoPath(item)
+	path, err := h.savedSubtitlePath(item, videoPath, target)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read subtitle: %w", err)
+	}
+	entries, err := h.Parser.Parse(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse subtitle: %w", err)
+	}
+	originals := h.matchingOriginals(videoPath, target, entries)
+	if originals == nil {
+		return nil, nil, fmt.Errorf("%w: the original cues of this subtitle are not available", errCueSelection)
+	}
+
+	positions := make(map[int]int, len(entries))
+	for i, entry := range entries {
+		positions[entry.Index] = i
+	}
+	var selected []int
+	seen := make(map[int]bool)
+	for _, index := range indexes {
+		i, ok := positions[index]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: the subtitle has no cue %d", errCueSelection, index)
+		}
+		if splitPart(originals, i) {
+			return nil, nil, fmt.Errorf("%w: cue %d is part of a longer cue that was split", errCueSelection, index)
+		}
+		if !seen[i] {
+			seen[i] = true
+			selected = append(selected, i)
+		}
+	}
+	for _, index := range failedCues {
+		// The subtitle may have been edited since it was translated
+		i, ok := positions[index]
+		if !ok || splitPart(originals, i) || seen[i] {
+			continue
+		}
+		seen[i] = true
+		selected = append(selected, i)
+	}
+	if len(selected) == 0 {
+		return nil, nil, fmt.Errorf("%w: the failed cues were split into shorter cues", errCueSelection)
+	}
+	sort.Ints(selected)
+
+	h.job.Logf("Translating %d cue(s) of %s again with %s", len(selected), path, backend.Name)
+	textTranslator := h.withGlossary(item, h.withNormalization(h.countingTranslator(backend.Name, backend.Translator)))
+	translateCtx, stopTranslate := h.stage(ctx, jobs.StageTranslate)
+	translated, failed, err := h.Parser.TranslateSelected(translateCtx, originals, selected, textTranslator, h.job)
+	stopTranslate()
+	if flushErr := h.Usage.Flush(); flushErr != nil {
+		h.job.Logf("Warning: %v", flushErr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to translate cues: %w", err)
+	}
+	if len(translated) == 0 {
+		return nil, failed, nil
+	}
+
+	var cues []retranslatedCue
+	for _, i := range selected {
+		if text, ok := translated[i]; ok {
+			// A bilingual cue keeps its original line
+			if _, bilingual := subtitle.SplitBilingual(entries[i].Text, originals[i].Text); bilingual {
+				text = subtitle.BilingualText(originals[i].Text, text)
+			}
+			entries[i].Text = text
+			cues = append(cues, retranslatedCue{Index: entries[i].Index, Text: text})
+		}
+	}
+
+	defer h.job.Stage(jobs.StageSave)()
+	if err := h.writeSubtitle(path, target, []byte(h.Parser.Format(entries)), len(entries)); err != nil {
+		return nil, nil, err
+	}
+	h.job.Logf("Merged %d cue(s) translated again into %s", len(cues), path)
+	h.clearFailedCues(item.ID, cues, len(entries))
+	h.refreshMetadata(ctx, item)
+	return cues, failed, nil
+}
+
+// clearFailedCues drops the cues translated again from the failed cues
+// recorded for the item's Traditional Chinese subtitle, and clears the
+// partial mark once few enough are left.
+func (h *Handler) clearFailedCues(itemID string, cues []retranslatedCue, total int) {
+	target := wanted.Target{Language: lang.TraditionalChinese}
+	result, recorded, err := h.Wanted.Result(itemID, target)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	if !recorded || len(result.FailedCues) == 0 {
+		return
+	}
+
+	translated := make(map[int]bool, len(cues))
+	for _, cue := range cues {
+		translated[cue.Index] = true
+	}
+	var remaining []int
+	for _, index := range result.FailedCues {
+		if !translated[index] {
+			remaining = append(remaining, index)
+		}
+	}
+	result.FailedCues = remaining
+	result.Partial = h.Fallback.Partial(subtitle.TranslationReport{Total: total, Failed: len(remaining)})
+	result.UpdatedAt = time.Now()
+	if err := h.Wanted.RecordResult(itemID, target, result); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
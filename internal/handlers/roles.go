@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Role is what a visitor of the web interface may do.
+type Role int
+
+const (
+	// RoleAdmin may do everything. Every visitor is the admin while
+	// ADMIN_PASSWORD is unset.
+	RoleAdmin Role = iota
+	// RoleViewer may browse the wanted list and the item pages and request
+	// subtitles, which wait for the admin to approve them.
+	RoleViewer
+)
+
+type roleKey struct{}
+
+// roleOf returns the role the request was made with.
+func roleOf(r *http.Request) Role {
+	if role, ok := r.Context().Value(roleKey{}).(Role); ok {
+		return role
+	}
+	return RoleAdmin
+}
+
+// sessionCookie holds the admin's login: when it expires and a signature
+// made with the admin password, so changing the password logs everyone out.
+const sessionCookie = "session"
+
+// sessionLifetime is how long a login lasts.
+const sessionLifetime = 30 * 24 * time.Hour
+
+// Roles lets visitors who haven't logged in use only what viewerAllowed
+// permits, when an admin password is set. The admin logs in at /login,
+// or sends the password with HTTP basic auth (user "admin") from scripts.
+// Pages for the admin only send viewers to the login page; API calls and
+// forms get a 401.
+func (h *Handler) Roles(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		password := h.Config().AdminPassword
+		if password == "" || authenticated(r, password) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), roleKey{}, RoleViewer))
+		if r.URL.Path == "/" && r.Method == http.MethodGet {
+			redirect(w, r, "/wanted?filter=missing")
+			return
+		}
+		if viewerAllowed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method == http.MethodGet && wantsHTML(r) {
+			redirect(w, r, "/login?return="+url.QueryEscape(r.URL.RequestURI()))
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="Subtitle Hunter"`)
+		http.Error(w, "Only the admin can do this; log in first", http.StatusUnauthorized)
+	})
+}
+
+// viewerAllowed reports whether a visitor who hasn't logged in may make
+// the request: look at the wanted list, the item pages and the requests,
+// and ask for a subtitle.
+func viewerAllowed(r *http.Request) bool {
+	path := r.URL.Path
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		switch path {
+		case "/wanted", "/requests", "/login", "/status", "/api/v1/requests":
+			return true
+		}
+		if strings.HasPrefix(path, "/static/") {
+			return true
+		}
+		if itemPath, ok := strings.CutPrefix(path, "/items/"); ok {
+			_, action, _ := strings.Cut(itemPath, "/")
+			return action == "" || action == "poster"
+		}
+	case http.MethodPost:
+		return path == "/login" || path == "/logout" || path == "/api/v1/requests"
+	}
+	return false
+}
+
+// authenticated reports whether r carries the admin's session cookie or
+// password.
+func authenticated(r *http.Request, password string) bool {
+	if user, given, ok := r.BasicAuth(); ok {
+		return user == "admin" && samePassword(given, password)
+	}
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return false
+	}
+	expiry, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return false
+	}
+	seconds, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() > seconds {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(signSession(expiry, password)))
+}
+
+// samePassword compares passwords in constant time.
+func samePassword(given, password string) bool {
+	a := sha256.Sum256([]byte(given))
+	b := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
+}
+
+func signSession(expiry, password string) string {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write([]byte("admin:" + expiry))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type loginView struct {
+	Return string
+	Failed bool
+}
+
+// LoginHandler serves the admin login: GET /login shows the form and POST
+// /login checks the password, keeps the login in a cookie and sends the
+// admin on to the "return" page.
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	password := h.Config().AdminPassword
+	switch r.Method {
+	case http.MethodGet:
+		if password == "" || authenticated(r, password) {
+			redirect(w, r, returnPath(r, "/"))
+			return
+		}
+		render(w, r, http.StatusOK, "login", loginView{Return: returnPath(r, "/")})
+	case http.MethodPost:
+		if password == "" {
+			redirect(w, r, returnPath(r, "/"))
+			return
+		}
+		if !samePassword(r.FormValue("password"), password) {
+			log.Printf("Failed admin login from %s", r.RemoteAddr)
+			render(w, r, http.StatusUnauthorized, "login", loginView{Return: returnPath(r, "/"), Failed: true})
+			return
+		}
+
+		expires := time.Now().Add(sessionLifetime)
+		expiry := strconv.FormatInt(expires.Unix(), 10)
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    fmt.Sprintf("%s.%s", expiry, signSession(expiry, password)),
+			Path:     "/",
+			Expires:  expires,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+		redirect(w, r, returnPath(r, "/"))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// LogoutHandler handles POST /logout, which ends the admin's login in this
+// browser.
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	redirect(w, r, "/wanted?filter=missing")
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trustedRootsBucket holds the media roots approved for direct saves under
+// trustedRootsKey.
+const (
+	trustedRootsBucket = "safe-mode"
+	trustedRootsKey    = "trusted"
+)
+
+// TrustedRoots are the container directories the operator has checked
+// against their path mappings and approved for direct saves.
+type TrustedRoots struct {
+	Roots      []string  `json:"roots"`
+	ApprovedAt time.Time `json:"approved_at"`
+}
+
+// MediaRoot is a directory of a Jellyfin library and where it resolves to
+// through the path mappings.
+type MediaRoot struct {
+	Library   string `json:"library"`
+	Jellyfin  string `json:"jellyfin_path"`
+	Container string `json:"container_path"`
+	// Exists is false when the resolved directory can't be found here,
+	// which usually means a missing or wrong path mapping.
+	Exists  bool `json:"exists"`
+	Trusted bool `json:"trusted"`
+}
+
+// mediaRoots resolves the directories of every Jellyfin library to
+// container paths and marks the approved ones.
+func (h *Handler) mediaRoots(ctx context.Context) ([]MediaRoot, error) {
+	folders, err := h.JellyfinClient.GetLibraryFolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trusted := h.trustedRoots()
+	var roots []MediaRoot
+	for _, folder := range folders {
+		for _, location := range folder.Locations {
+			root := MediaRoot{
+				Library:   folder.Name,
+				Jellyfin:  location,
+				Container: filepath.Clean(h.Config().MapJellyfinPathToContainer(location)),
+			}
+			if info, err := os.Stat(root.Container); err == nil && info.IsDir() {
+				root.Exists = true
+			}
+			for _, dir := range trusted {
+				root.Trusted = root.Trusted || dir == root.Container
+			}
+			roots = append(roots, root)
+		}
+	}
+
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Container < roots[j].Container })
+	return roots, nil
+}
+
+func (h *Handler) trustedRoots() []string {
+	var trusted TrustedRoots
+	if _, err := h.Store.Get(trustedRootsBucket, trustedRootsKey, &trusted); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return trusted.Roots
+}
+
+// directSaveAllowed reports whether subtitles may be written to dir, which
+// in safe mode must lie within an approved media root.
+func (h *Handler) directSaveAllowed(dir string) bool {
+	if !h.Config().SafeMode {
+		return true
+	}
+	for _, root := range h.trustedRoots() {
+		if withinDir(dir, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWriteTarget refuses writes outside the downloads directory that
+// safe mode doesn't allow.
+func (h *Handler) checkWriteTarget(path string) error {
+	dir := filepath.Dir(path)
+	if withinDir(dir, h.Config().SubtitleDirectory) || h.directSaveAllowed(dir) {
+		return nil
+	}
+	return fmt.Errorf("safe mode: %s is not in an approved media root", dir)
+}
+
+// withinDir reports whether path is dir or lies below it.
+func withinDir(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+type mediaRootsResponse struct {
+	SafeMode bool        `json:"safe_mode"`
+	Roots    []MediaRoot `json:"roots"`
+}
+
+// MediaRootsHandler serves /api/v1/media-roots: GET lists the resolved
+// media roots, POST approves the "root" values given (replacing the previous
+// approval; none revokes it). Form submissions are redirected back.
+func (h *Handler) MediaRootsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := h.approveRoots(r.Context(), r); err != nil {
+			respond(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if wantsHTML(r) {
+			redirect(w, r, returnPath(r, "/settings"))
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	roots, err := h.mediaRoots(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch library folders: %v", err), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, mediaRootsResponse{SafeMode: h.Config().SafeMode, Roots: roots})
+}
+
+// approveRoots records the submitted roots as trusted. Only roots that
+// currently resolve to an existing directory can be approved.
+func (h *Handler) approveRoots(ctx context.Context, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("invalid form: %w", err)
+	}
+
+	roots, err := h.mediaRoots(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch library folders: %w", err)
+	}
+	resolved := make(map[string]MediaRoot)
+	for _, root := range roots {
+		resolved[root.Container] = root
+	}
+
+	trusted := TrustedRoots{Roots: []string{}, ApprovedAt: time.Now()}
+	for _, dir := range r.Form["root"] {
+		root, ok := resolved[filepath.Clean(dir)]
+		if !ok {
+			return fmt.Errorf("%s is not a media root", dir)
+		}
+		if !root.Exists {
+			return fmt.Errorf("%s does not exist here; check the path mappings", root.Container)
+		}
+		trusted.Roots = append(trusted.Roots, root.Container)
+	}
+
+	if err := h.Store.Put(trustedRootsBucket, trustedRootsKey, trusted); err != nil {
+		return fmt.Errorf("failed to save approved roots: %w", err)
+	}
+	if len(trusted.Roots) == 0 {
+		log.Printf("Revoked the approval of all media roots")
+	} else {
+		log.Printf("Approved media roots for direct saves: %s", strings.Join(trusted.Roots, ", "))
+	}
+	return nil
+}
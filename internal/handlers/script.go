@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+)
+
+// maxScriptSample is how much of a subtitle file DetectSubtitleScript reads;
+// the first part of a file tells its script as well as the whole.
+const maxScriptSample = 256 << 10
+
+// scriptCache remembers the script detected for each external subtitle
+// file, so the wanted list doesn't reread every file on each rebuild.
+type scriptCache struct {
+	mu      sync.Mutex
+	entries map[string]scriptEntry
+}
+
+// scriptEntry is a detected script, valid while the file keeps its size and
+// modification time.
+type scriptEntry struct {
+	size     int64
+	modified time.Time
+	script   string
+}
+
+func newScriptCache() *scriptCache {
+	return &scriptCache{entries: make(map[string]scriptEntry)}
+}
+
+// DetectSubtitleScript returns the Chinese script an external subtitle file
+// is written in, "Hant" or "Hans", judging by its text. It returns "" when
+// the file can't be read from here or its text doesn't tell. The path of a
+// stream read from Jellyfin is mapped to where the file is seen from here.
+func (h *Handler) DetectSubtitleScript(stream jellyfin.MediaStream) string {
+	containerPath := stream.Path
+	if !stream.Local {
+		containerPath = h.Config().MapJellyfinPathToContainer(stream.Path)
+	}
+	info, err := os.Stat(containerPath)
+	if err != nil || info.IsDir() {
+		return ""
+	}
+
+	if h.scripts != nil {
+		h.scripts.mu.Lock()
+		entry, ok := h.scripts.entries[containerPath]
+		h.scripts.mu.Unlock()
+		if ok && entry.size == info.Size() && entry.modified.Equal(info.ModTime()) {
+			return entry.script
+		}
+	}
+
+	file, err := os.Open(containerPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	sample, err := io.ReadAll(io.LimitReader(file, maxScriptSample))
+	if err != nil {
+		return ""
+	}
+	script := lang.DetectChineseScript(string(sample))
+
+	if h.scripts != nil {
+		h.scripts.mu.Lock()
+		h.scripts.entries[containerPath] = scriptEntry{size: info.Size(), modified: info.ModTime(), script: script}
+		h.scripts.mu.Unlock()
+	}
+	return script
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/opensubtitles"
+	"subtitle-hunter/internal/release"
+	"subtitle-hunter/internal/wanted"
+)
+
+var imdbIDPattern = regexp.MustCompile(`(?i)^(?:https?://(?:www\.|m\.)?imdb\.com/title/)?(?:tt)?0*(\d{1,10})/?$`)
+
+// parseIMDbID recognises an IMDb ID typed or pasted as "tt0944947", a bare
+// number of at least six digits, or a title URL, and returns it in the form
+// the OpenSubtitles API expects (digits without leading zeros).
+func parseIMDbID(query string) (string, bool) {
+	query = strings.TrimSpace(query)
+	match := imdbIDPattern.FindStringSubmatch(query)
+	if match == nil {
+		return "", false
+	}
+	if !strings.Contains(strings.ToLower(query), "tt") && len(query) < 6 {
+		return "", false
+	}
+	return match[1], true
+}
+
+type searchResult struct {
+	Query      string                   `json:"query"`
+	IMDbID     string                   `json:"imdb_id,omitempty"`
+	Language   string                   `json:"language"`
+	Candidates []opensubtitles.Subtitle `json:"candidates"`
+	// Rejected lists the candidates rejected for the item, which can
+	// still be picked here.
+	Rejected []string `json:"rejected,omitempty"`
+}
+
+// manualSearch runs a search for item with a query typed by the user
+// instead of the generated one. An empty query falls back to the generated
+// query; an IMDb ID searches by ID instead of by title.
+func (h *Handler) manualSearch(ctx context.Context, item *jellyfin.MediaItem, query string, language lang.Tag) (*searchResult, error) {
+	search := &searchResult{Query: strings.TrimSpace(query), Language: language.String()}
+	if search.Query == "" {
+		search.Query = h.JellyfinClient.GetSearchQuery(*item)
+	}
+
+	title := search.Query
+	if imdbID, ok := parseIMDbID(search.Query); ok {
+		search.IMDbID = imdbID
+		title = ""
+	}
+
+	ctx, stop := h.stage(ctx, jobs.StageSearch)
+	defer stop()
+
+	log.Printf("Manual %s search for %s: %q", language, item.Name, search.Query)
+	candidates, err := h.providersFor(item).SearchSubtitles(ctx, title, search.IMDbID, opensubtitles.LanguageCode(language))
+	if err != nil {
+		return search, fmt.Errorf("search failed: %w", err)
+	}
+	search.Candidates = candidates
+
+	rejected, err := h.rejectedIDs(item.ID, opensubtitlesProvider)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	for _, candidate := range candidates {
+		if rejected[candidate.ID] {
+			search.Rejected = append(search.Rejected, candidate.ID)
+		}
+	}
+	return search, nil
+}
+
+// searchLanguages are the languages offered by the manual search: the
+// item's target languages, plus English whose subtitles are translated.
+func (h *Handler) searchLanguages(item *jellyfin.MediaItem) []lang.Tag {
+	languages := append([]lang.Tag{}, h.targetsFor(item)...)
+	for _, language := range languages {
+		if language == lang.English {
+			return languages
+		}
+	}
+	return append(languages, lang.English)
+}
+
+func searchLanguage(r *http.Request) (lang.Tag, error) {
+	value := r.FormValue("language")
+	if value == "" {
+		return lang.TraditionalChinese, nil
+	}
+	language := lang.Normalize(value)
+	if language.IsZero() {
+		return language, fmt.Errorf("Unknown language %q", value)
+	}
+	return language, nil
+}
+
+type searchView struct {
+	Item *jellyfin.MediaItem
+	// VideoRelease is what the video's file name says about its release,
+	// for comparing with the candidates'.
+	VideoRelease release.Info
+	Languages    []lang.Tag
+	Search       *searchResult
+	// Rejected are the IDs of the candidates rejected for the item.
+	Rejected  map[string]bool
+	Searched  bool
+	Translate bool
+	Error     string
+	Return    string
+}
+
+// searchPage shows a form to search for an item's subtitle with a custom
+// query or IMDb ID, and the candidates found.
+func (h *Handler) searchPage(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get item details: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	view := searchView{
+		Item:         item,
+		VideoRelease: release.Parse(h.itemVideoPath(item)),
+		Languages:    h.searchLanguages(item),
+		Translate:    h.translationAllowed(item) && !h.isTargetLanguage(item, lang.English),
+		Search:       &searchResult{Query: h.JellyfinClient.GetSearchQuery(*item), Language: lang.TraditionalChinese.String()},
+		Return:       r.URL.RequestURI(),
+	}
+
+	if _, ok := r.URL.Query()["q"]; ok {
+		view.Searched = true
+		language, err := searchLanguage(r)
+		if err == nil {
+			view.Search, err = h.manualSearch(r.Context(), item, r.FormValue("q"), language)
+		}
+		if err != nil {
+			view.Error = err.Error()
+		}
+		view.Rejected = make(map[string]bool)
+		for _, id := range view.Search.Rejected {
+			view.Rejected[id] = true
+		}
+	}
+
+	render(w, r, http.StatusOK, "search", view)
+}
+
+// searchAPI returns the candidates of a manual search as JSON:
+// GET /api/v1/items/{id}/search?q=...&language=...
+func (h *Handler) searchAPI(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	language, err := searchLanguage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get item details: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	search, err := h.manualSearch(r.Context(), item, r.FormValue("q"), language)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, search)
+}
+
+// downloadCandidate saves a subtitle picked from a manual search:
+// POST /items/{id}/download with "file_id" and the "language" it was
+// searched in, plus "forced" for a forced subtitle. Full English picks are
+// translated to Traditional Chinese unless English is itself a target
+// language or the series disallows machine translation; "bilingual"
+// overrides whether the English text is kept above the translation.
+func (h *Handler) downloadCandidate(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fileID, err := strconv.Atoi(r.FormValue("file_id"))
+	if err != nil || fileID <= 0 {
+		respond(w, r, http.StatusBadRequest, "A numeric file_id is required")
+		return
+	}
+	language, err := searchLanguage(r)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	bilingual, err := bilingualOverride(r)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if bilingual != nil {
+		h = h.withBilingual(*bilingual)
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		log.Printf("Error getting item details for %s: %v", itemID, err)
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+
+	sub := &opensubtitles.Subtitle{ID: r.FormValue("subtitle_id"), FileID: fileID}
+	if sub.ID == "" {
+		sub.ID = strconv.Itoa(fileID)
+	}
+
+	target := wanted.Target{Language: language, Forced: r.FormValue("forced") == "true"}
+	jobID, result, err := h.RunJob(r.Context(), item, jobs.TriggerSearch, func(h *Handler, ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error) {
+		result, saved, err := h.saveCandidate(ctx, item, sub, target)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := h.Wanted.RecordResult(item.ID, saved, result.Record(item)); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+
+		h.refreshMetadata(ctx, item)
+		return result, nil
+	})
+	if jobID != "" {
+		w.Header().Set("X-Job-ID", jobID)
+	}
+	if err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusOK, result.Message(h.Config().SubtitleDirectory))
+}
+
+// saveCandidate downloads sub and saves it for target, or translates it
+// when it is a full English subtitle for a Traditional Chinese target and
+// the series allows machine translation. It returns the target the
+// subtitle was saved as.
+func (h *Handler) saveCandidate(ctx context.Context, item *jellyfin.MediaItem, sub *opensubtitles.Subtitle, target wanted.Target) (*ProcessResult, wanted.Target, error) {
+	videoPath := h.itemVideoPath(item)
+
+	if target == (wanted.Target{Language: lang.English}) && !h.isTargetLanguage(item, lang.English) && h.translationAllowed(item) {
+		location, report, err := h.translateAndSaveSubtitle(ctx, item, sub, videoPath)
+		if err != nil {
+			return nil, target, fmt.Errorf("Failed to translate subtitle: %w", err)
+		}
+		result := &ProcessResult{SaveLocation: location, Source: "manual search (translated)", Report: &report, SubtitleID: sub.ID}
+		return result, wanted.Target{Language: lang.TraditionalChinese}, nil
+	}
+
+	location, err := h.downloadAndSaveSubtitle(ctx, item, sub, target)
+	if err != nil {
+		return nil, target, fmt.Errorf("Failed to save %s subtitle: %w", target, err)
+	}
+	return &ProcessResult{SaveLocation: location, Source: "manual search", SubtitleID: sub.ID}, target, nil
+}
+
+func (h *Handler) isTargetLanguage(item *jellyfin.MediaItem, language lang.Tag) bool {
+	for _, target := range h.targetsFor(item) {
+		if target == language {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/config"
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/opensubtitles"
+)
+
+// seriesSettingsBucket holds the SeriesSettings of each series, keyed by
+// the Jellyfin series ID.
+const seriesSettingsBucket = "series-settings"
+
+// SeriesSettings override the global configuration for the episodes of one
+// series. Empty fields keep the global setting.
+type SeriesSettings struct {
+	// Languages replaces the target languages.
+	Languages []string `json:"languages,omitempty"`
+	// Providers names OpenSubtitles instances to try first, in order.
+	Providers []string `json:"providers,omitempty"`
+	// Translate turns machine translation (of English, embedded and
+	// transcribed subtitles) on or off.
+	Translate *bool `json:"translate,omitempty"`
+	// Paused keeps the series out of automatic hunting. Unlike ignoring,
+	// its episodes stay listed and can still be hunted by hand.
+	Paused bool `json:"paused,omitempty"`
+}
+
+func (s SeriesSettings) IsZero() bool {
+	return len(s.Languages) == 0 && len(s.Providers) == 0 && s.Translate == nil && !s.Paused
+}
+
+// seriesSettings returns the overrides for the series item belongs to.
+// Movies and episodes without a series ID have none.
+func (h *Handler) seriesSettings(item *jellyfin.MediaItem) SeriesSettings {
+	var settings SeriesSettings
+	if item.Type != "Episode" || item.SeriesID == "" {
+		return settings
+	}
+	if _, err := h.Store.Get(seriesSettingsBucket, item.SeriesID, &settings); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return settings
+}
+
+// targetsFor returns the target languages for item: its own, set by a
+// batch action, else its series', else the configured ones.
+func (h *Handler) targetsFor(item *jellyfin.MediaItem) []lang.Tag {
+	if targets := parseTargets(h.itemLanguages(item.ID)); len(targets) > 0 {
+		return targets
+	}
+	if targets := parseTargets(h.seriesSettings(item).Languages); len(targets) > 0 {
+		return targets
+	}
+	return h.Wanted.Languages()
+}
+
+// providersFor returns the OpenSubtitles instances in the order they should
+// be tried for item.
+func (h *Handler) providersFor(item *jellyfin.MediaItem) *opensubtitles.Registry {
+	return h.OpenSubtitlesClient.Prefer(h.seriesSettings(item).Providers)
+}
+
+// translationAllowed reports whether item's subtitle may be machine
+// translated when no subtitle in the target language exists. The request's
+// override comes before the series settings, and both before the
+// configured translation mode.
+func (h *Handler) translationAllowed(item *jellyfin.MediaItem) bool {
+	if h.translate != nil {
+		return *h.translate
+	}
+	if translate := h.seriesSettings(item).Translate; translate != nil {
+		return *translate
+	}
+	return h.Config().TranslationMode != config.TranslationOff
+}
+
+// HuntingPaused reports whether item belongs to a series whose hunting is
+// paused, in which case automatic hunting skips it.
+func (h *Handler) HuntingPaused(item *jellyfin.MediaItem) bool {
+	return h.seriesSettings(item).Paused
+}
+
+// normalizeSeriesSettings checks that every language is known and every
+// provider is configured, and drops duplicates.
+func (h *Handler) normalizeSeriesSettings(settings SeriesSettings) (SeriesSettings, error) {
+	normalized := SeriesSettings{Translate: settings.Translate, Paused: settings.Paused}
+
+	for _, value := range settings.Languages {
+		if lang.Parse(value).IsZero() {
+			return settings, fmt.Errorf("unknown language %q", value)
+		}
+	}
+	for _, tag := range parseTargets(settings.Languages) {
+		normalized.Languages = append(normalized.Languages, tag.String())
+	}
+
+	configured := make(map[string]bool)
+	for _, status := range h.OpenSubtitlesClient.Status() {
+		configured[status.Name] = true
+	}
+	seen := make(map[string]bool)
+	for _, name := range settings.Providers {
+		if !configured[name] {
+			return settings, fmt.Errorf("no OpenSubtitles account named %q", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized.Providers = append(normalized.Providers, name)
+		}
+	}
+
+	return normalized, nil
+}
+
+// saveSeriesSettings stores the overrides for a series, removing them when
+// nothing is overridden.
+func (h *Handler) saveSeriesSettings(seriesID string, settings SeriesSettings) (SeriesSettings, error) {
+	settings, err := h.normalizeSeriesSettings(settings)
+	if err != nil {
+		return settings, err
+	}
+
+	if settings.IsZero() {
+		err = h.Store.Delete(seriesSettingsBucket, seriesID)
+	} else {
+		err = h.Store.Put(seriesSettingsBucket, seriesID, settings)
+	}
+	if err != nil {
+		return settings, fmt.Errorf("failed to save series settings: %w", err)
+	}
+	log.Printf("Saved settings for series %s: %+v", seriesID, settings)
+	return settings, nil
+}
+
+func seriesSettingsFromForm(r *http.Request) (SeriesSettings, error) {
+	settings := SeriesSettings{
+		Languages: splitList(r.FormValue("languages")),
+		Providers: splitList(r.FormValue("providers")),
+		Paused:    r.FormValue("paused") == "on",
+	}
+
+	switch translate := r.FormValue("translate"); translate {
+	case "":
+	case "on", "off":
+		allowed := translate == "on"
+		settings.Translate = &allowed
+	default:
+		return settings, fmt.Errorf("translate must be empty, on or off")
+	}
+
+	return settings, nil
+}
+
+type seriesView struct {
+	Series    *jellyfin.MediaItem
+	Settings  SeriesSettings
+	Languages []string
+	Providers []string
+	Translate string
+	// TranslationMode is the global mode the default follows.
+	TranslationMode string
+	Saved           bool
+	Error           string
+}
+
+// SeriesHandler shows and saves the per-series overrides at
+// /series/{id}.
+func (h *Handler) SeriesHandler(w http.ResponseWriter, r *http.Request) {
+	seriesID := strings.TrimPrefix(r.URL.Path, "/series/")
+	if seriesID == "" || strings.Contains(seriesID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	series, err := h.JellyfinClient.GetItem(r.Context(), seriesID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get series details: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var settings SeriesSettings
+	if _, err := h.Store.Get(seriesSettingsBucket, seriesID, &settings); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	view := seriesView{Series: series, TranslationMode: h.Config().TranslationMode, Saved: r.URL.Query().Get("saved") == "1"}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		submitted, err := seriesSettingsFromForm(r)
+		if err == nil {
+			_, err = h.saveSeriesSettings(seriesID, submitted)
+		}
+		if err == nil {
+			redirect(w, r, "/series/"+seriesID+"?saved=1")
+			return
+		}
+		settings = submitted
+		view.Saved = false
+		view.Error = err.Error()
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	view.Settings = settings
+	for _, language := range h.Wanted.Languages() {
+		view.Languages = append(view.Languages, language.String())
+	}
+	for _, status := range h.OpenSubtitlesClient.Status() {
+		view.Providers = append(view.Providers, status.Name)
+	}
+	if settings.Translate != nil {
+		view.Translate = "off"
+		if *settings.Translate {
+			view.Translate = "on"
+		}
+	}
+
+	render(w, r, http.StatusOK, "series", view)
+}
+
+// SeriesAPIHandler returns a series' overrides as JSON on
+// GET /api/v1/series/{id}/settings and replaces them on PUT. POST
+// /api/v1/series/{id}/pause and /resume toggle hunting for the series; form
+// submissions are redirected back. POST /api/v1/series/{id}/archive saves
+// the subtitles of a season archive to the episodes.
+func (h *Handler) SeriesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	seriesID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/series/"), "/")
+	if seriesID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch action {
+	case "settings":
+	case "pause", "resume":
+		h.pauseSeries(w, r, seriesID, action == "pause")
+		return
+	case "archive":
+		h.uploadSeasonArchive(w, r, seriesID)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var settings SeriesSettings
+		if _, err := h.Store.Get(seriesSettingsBucket, seriesID, &settings); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, settings)
+	case http.MethodPut:
+		var settings SeriesSettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		settings, err := h.saveSeriesSettings(seriesID, settings)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, settings)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) pauseSeries(w http.ResponseWriter, r *http.Request, seriesID string, paused bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var settings SeriesSettings
+	if _, err := h.Store.Get(seriesSettingsBucket, seriesID, &settings); err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	settings.Paused = paused
+	if _, err := h.saveSeriesSettings(seriesID, settings); err != nil {
+		respond(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/series/"+seriesID))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subtitle-hunter/config"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/web"
+)
+
+type settingsView struct {
+	Settings   config.RuntimeSettings
+	ConfigFile string
+	Mappings   string
+	Saved      bool
+	Error      string
+	SafeMode   bool
+	MediaRoots []MediaRoot
+	RootsError string
+	Languages  []lang.Tag
+}
+
+// SettingsHandler shows the runtime settings and saves changes submitted
+// from the form.
+func (h *Handler) SettingsHandler(w http.ResponseWriter, r *http.Request) {
+	view := settingsView{
+		Settings:   h.Config().RuntimeSettings().Masked(),
+		ConfigFile: h.Config().ConfigFile,
+		Saved:      r.URL.Query().Get("saved") == "1",
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		settings, err := settingsFromForm(r)
+		if err == nil {
+			err = h.applySettings(settings)
+		}
+		if err == nil {
+			redirect(w, r, "/settings?saved=1")
+			return
+		}
+		view.Settings = settings.Masked()
+		view.Saved = false
+		view.Error = err.Error()
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var mappings []string
+	for _, mapping := range view.Settings.PathMappings {
+		mappings = append(mappings, mapping.Jellyfin+" => "+mapping.Container)
+	}
+	view.Mappings = strings.Join(mappings, "\n")
+
+	view.SafeMode = h.Config().SafeMode
+	view.Languages = web.Languages()
+	if roots, err := h.mediaRoots(r.Context()); err != nil {
+		view.RootsError = fmt.Sprintf("Failed to fetch library folders: %v", err)
+	} else {
+		view.MediaRoots = roots
+	}
+
+	render(w, r, http.StatusOK, "settings", view)
+}
+
+// SettingsAPIHandler returns the runtime settings as JSON (secrets masked)
+// on GET and replaces them on PUT.
+func (h *Handler) SettingsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, h.Config().RuntimeSettings().Masked())
+	case http.MethodPut:
+		var settings config.RuntimeSettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if err := h.applySettings(settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, h.Config().RuntimeSettings().Masked())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// applySettings saves the settings to the config file and reloads it, so
+// they take effect immediately.
+func (h *Handler) applySettings(settings config.RuntimeSettings) error {
+	cfg := h.Config()
+	settings.KeepSecrets(cfg.RuntimeSettings())
+
+	if err := config.SaveRuntimeSettings(cfg.ConfigFile, settings); err != nil {
+		return err
+	}
+	log.Printf("Settings saved to %s", cfg.ConfigFile)
+
+	if err := h.Settings.Reload(); err != nil {
+		return fmt.Errorf("settings saved but could not be applied: %w", err)
+	}
+	return nil
+}
+
+func settingsFromForm(r *http.Request) (config.RuntimeSettings, error) {
+	if err := r.ParseForm(); err != nil {
+		return config.RuntimeSettings{}, fmt.Errorf("invalid form: %w", err)
+	}
+
+	settings := config.RuntimeSettings{
+		AutoHuntInterval:   strings.TrimSpace(r.FormValue("auto_hunt_interval")),
+		EnableDirectSave:   r.FormValue("enable_direct_save") == "on",
+		InterfaceLanguage:  r.FormValue("interface_language"),
+		BilingualSubtitles: r.FormValue("bilingual_subtitles") == "on",
+		TranslationMode:    r.FormValue("translation_mode"),
+	}
+
+	settings.TargetLanguages = splitList(r.FormValue("target_languages"))
+
+	if settings.AutoHuntInterval == "" {
+		settings.AutoHuntInterval = "0s"
+	}
+
+	if days := strings.TrimSpace(r.FormValue("auto_hunt_window_days")); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil {
+			return settings, fmt.Errorf("invalid auto-hunt window %q", days)
+		}
+		settings.AutoHuntWindowDays = parsed
+	}
+
+	for _, line := range strings.Split(r.FormValue("path_mappings"), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=>", 2)
+		if len(parts) != 2 {
+			return settings, fmt.Errorf("path mapping %q must look like \"/jellyfin/path => /container/path\"", line)
+		}
+		settings.PathMappings = append(settings.PathMappings, config.PathMapping{
+			Jellyfin:  strings.TrimSpace(parts[0]),
+			Container: strings.TrimSpace(parts[1]),
+		})
+	}
+
+	names := r.Form["provider_name"]
+	for i, name := range names {
+		provider := config.OpenSubtitlesInstance{
+			Name:     strings.TrimSpace(name),
+			APIKey:   strings.TrimSpace(formIndex(r, "provider_api_key", i)),
+			Username: strings.TrimSpace(formIndex(r, "provider_username", i)),
+			Password: formIndex(r, "provider_password", i),
+		}
+		if provider.Name == "" && provider.APIKey == "" {
+			continue
+		}
+		provider.Priority, _ = strconv.Atoi(formIndex(r, "provider_priority", i))
+		if provider.Priority == 0 {
+			provider.Priority = i + 1
+		}
+		settings.Providers = append(settings.Providers, provider)
+	}
+
+	return settings, nil
+}
+
+// splitList splits a comma-separated form value, dropping empty entries.
+func splitList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+func formIndex(r *http.Request, key string, i int) string {
+	if values := r.Form[key]; i < len(values) {
+		return values[i]
+	}
+	return ""
+}
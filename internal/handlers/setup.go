@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"subtitle-hunter/config"
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/opensubtitles"
+)
+
+// setupCheckTimeout bounds the calls made to test the credentials.
+const setupCheckTimeout = 15 * time.Second
+
+// Setup serves the setup wizard in place of the interface while the
+// credentials are missing or rejected: it tests the Jellyfin URL and API
+// key, lists the Jellyfin users to pick from, tests the OpenSubtitles API
+// key and writes them to the config file. Done is closed once they are
+// saved, so the service can start.
+type Setup struct {
+	cfg *config.Config
+	// Problem says why setup is needed.
+	problem string
+
+	mu    sync.Mutex
+	saved bool
+	done  chan struct{}
+}
+
+// NewSetup returns the setup wizard for cfg, which is left unchanged.
+func NewSetup(cfg *config.Config, problem string) *Setup {
+	return &Setup{cfg: cfg, problem: problem, done: make(chan struct{})}
+}
+
+// Done is closed once working credentials are saved.
+func (s *Setup) Done() <-chan struct{} {
+	return s.done
+}
+
+// setupCheck is the outcome of testing credentials. Ready is set when all
+// of them work.
+type setupCheck struct {
+	Ready         bool            `json:"ready"`
+	Jellyfin      string          `json:"jellyfin_error,omitempty"`
+	Users         []jellyfin.User `json:"users"`
+	User          string          `json:"user_error,omitempty"`
+	OpenSubtitles string          `json:"opensubtitles_error,omitempty"`
+}
+
+type setupView struct {
+	Problem     string
+	ConfigFile  string
+	Credentials config.Credentials
+	// HasJellyfinKey, HasJellyfinPassword and HasOpenSubtitlesKey are set
+	// when a secret is already configured, which a blank field keeps.
+	HasJellyfinKey      bool
+	HasJellyfinPassword bool
+	HasOpenSubtitlesKey bool
+	Check               *setupCheck
+	Error               string
+	Saved               bool
+}
+
+// ServeHTTP serves GET and POST /setup, the wizard, POST
+// /api/v1/setup/check, which tests credentials given as JSON, and POST
+// /api/v1/setup, which tests and saves them. /status reports that setup is
+// pending, and every other page leads to the wizard. When an admin
+// password is set, only the admin may use the wizard: there is no login
+// page during setup, so the password is asked for with HTTP basic auth,
+// though a login cookie from before works too.
+func (s *Setup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if password := s.cfg.AdminPassword; password != "" && r.URL.Path != "/status" && !authenticated(r, password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Subtitle Hunter"`)
+		http.Error(w, "Only the admin can set up Subtitle Hunter; log in as user admin with the admin password", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/setup":
+		s.page(w, r)
+	case "/api/v1/setup/check", "/api/v1/setup":
+		s.api(w, r, r.URL.Path == "/api/v1/setup")
+	case "/status":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "setup", "service": "subtitle-hunter", "error": s.problem})
+	default:
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			http.Error(w, "Setup is required: "+s.problem, http.StatusServiceUnavailable)
+			return
+		}
+		redirect(w, r, "/setup")
+	}
+}
+
+func (s *Setup) page(w http.ResponseWriter, r *http.Request) {
+	current := s.cfg.Credentials()
+	view := setupView{
+		Problem:             s.problem,
+		ConfigFile:          s.cfg.ConfigFile,
+		Credentials:         config.Credentials{JellyfinURL: current.JellyfinURL, JellyfinUserID: current.JellyfinUserID, JellyfinUsername: current.JellyfinUsername},
+		HasJellyfinKey:      current.JellyfinAPIKey != "",
+		HasJellyfinPassword: current.JellyfinPassword != "",
+		HasOpenSubtitlesKey: current.OpenSubtitlesKey != "",
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		view.Saved = s.saved
+		s.mu.Unlock()
+		render(w, r, http.StatusOK, "setup", view)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	creds := config.Credentials{
+		JellyfinURL:      strings.TrimSpace(r.FormValue("jellyfin_url")),
+		JellyfinAPIKey:   strings.TrimSpace(r.FormValue("jellyfin_api_key")),
+		JellyfinUserID:   r.FormValue("jellyfin_user_id"),
+		JellyfinUsername: strings.TrimSpace(r.FormValue("jellyfin_username")),
+		JellyfinPassword: r.FormValue("jellyfin_password"),
+		OpenSubtitlesKey: strings.TrimSpace(r.FormValue("opensubtitles_api_key")),
+	}
+	// Typed keys are filled in again, so testing first doesn't lose them
+	view.Credentials = creds
+	creds = s.keepSecrets(creds)
+
+	check := s.check(r.Context(), creds)
+	view.Check = &check
+	if r.FormValue("save") == "true" && check.Ready {
+		if err := s.save(creds); err != nil {
+			view.Error = err.Error()
+		} else {
+			view.Saved = true
+		}
+	}
+	render(w, r, http.StatusOK, "setup", view)
+}
+
+func (s *Setup) api(w http.ResponseWriter, r *http.Request, save bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var creds config.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	creds = s.keepSecrets(creds)
+
+	check := s.check(r.Context(), creds)
+	if !save {
+		writeJSON(w, http.StatusOK, check)
+		return
+	}
+	if !check.Ready {
+		writeJSON(w, http.StatusBadRequest, check)
+		return
+	}
+	if err := s.save(creds); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, check)
+}
+
+// keepSecrets fills in the API keys left blank from the configured ones,
+// and the Jellyfin password when the username is the configured one. The
+// Jellyfin API key and password are only kept for the configured Jellyfin
+// URL: testing sends them to the URL given, so anyone reaching the wizard
+// could otherwise have them sent to a server of their own.
+func (s *Setup) keepSecrets(creds config.Credentials) config.Credentials {
+	current := s.cfg.Credentials()
+	if creds.JellyfinURL == "" {
+		creds.JellyfinURL = current.JellyfinURL
+	}
+	sameServer := strings.TrimSuffix(creds.JellyfinURL, "/") == strings.TrimSuffix(current.JellyfinURL, "/")
+	if creds.JellyfinAPIKey == "" && sameServer {
+		creds.JellyfinAPIKey = current.JellyfinAPIKey
+	}
+	if creds.JellyfinPassword == "" && creds.JellyfinUsername == current.JellyfinUsername && sameServer {
+		creds.JellyfinPassword = current.JellyfinPassword
+	}
+	if creds.OpenSubtitlesKey == "" {
+		creds.OpenSubtitlesKey = current.OpenSubtitlesKey
+	}
+	return creds
+}
+
+// check tests the credentials: Jellyfin by listing its users, which the
+// user ID must be one of, or by signing in with the username, and
+// OpenSubtitles by asking for its formats, which doesn't count against the
+// quota.
+func (s *Setup) check(ctx context.Context, creds config.Credentials) setupCheck {
+	ctx, cancel := context.WithTimeout(ctx, setupCheckTimeout)
+	defer cancel()
+
+	check := setupCheck{Users: []jellyfin.User{}}
+	if err := (config.Credentials{JellyfinURL: creds.JellyfinURL, JellyfinAPIKey: "-", JellyfinUserID: "-", OpenSubtitlesKey: "-"}).Validate(); err != nil {
+		check.Jellyfin = err.Error()
+	} else if creds.JellyfinUsername != "" {
+		// Only administrators may list the users, so signing in is tested
+		// on the user whose library is read: the signed-in one unless
+		// another is picked
+		client := jellyfin.NewClient(creds.JellyfinURL, "", creds.JellyfinUserID)
+		client.Username = creds.JellyfinUsername
+		client.Password = creds.JellyfinPassword
+		if err := client.Ping(ctx); err != nil {
+			check.Jellyfin = err.Error()
+		} else if users, err := client.GetUsers(ctx); err == nil {
+			check.Users = users
+		}
+	} else if creds.JellyfinAPIKey == "" {
+		check.Jellyfin = "An API key or a username is required"
+	} else if users, err := jellyfin.NewClient(creds.JellyfinURL, creds.JellyfinAPIKey, "").GetUsers(ctx); err != nil {
+		check.Jellyfin = err.Error()
+	} else {
+		check.Users = users
+		check.User = "Pick the user whose libraries to hunt subtitles for"
+		for _, user := range users {
+			if user.ID == creds.JellyfinUserID {
+				check.User = ""
+			}
+		}
+	}
+
+	if creds.OpenSubtitlesKey == "" {
+		check.OpenSubtitles = "An API key is required"
+	} else if err := opensubtitles.NewClient(creds.OpenSubtitlesKey).Ping(ctx); err != nil {
+		check.OpenSubtitles = err.Error()
+	}
+
+	check.Ready = check.Jellyfin == "" && check.User == "" && check.OpenSubtitles == ""
+	return check
+}
+
+// save writes the credentials to the config file and lets the service
+// start, once.
+func (s *Setup) save(creds config.Credentials) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saved {
+		return nil
+	}
+	if err := config.SaveCredentials(s.cfg.ConfigFile, creds, s.cfg.OpenSubtitlesInstances); err != nil {
+		return fmt.Errorf("failed to save the configuration: %w", err)
+	}
+	log.Printf("Setup: credentials saved to %s", s.cfg.ConfigFile)
+	s.saved = true
+	close(s.done)
+	return nil
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"subtitle-hunter/internal/jobs"
+)
+
+// jobTriggers are what jobs can be started by, for filtering the stats.
+var jobTriggers = []string{jobs.TriggerManual, jobs.TriggerAuto, jobs.TriggerSearch, jobs.TriggerCLI, jobs.TriggerBatch, jobs.TriggerCampaign, jobs.TriggerRequest}
+
+type statsView struct {
+	jobs.Stats
+	// Trigger and Limit are the filters applied, if any.
+	Trigger  string   `json:"trigger,omitempty"`
+	Limit    int      `json:"limit,omitempty"`
+	Triggers []string `json:"-"`
+}
+
+// jobStats sums up the stage timings of the kept job reports: the most
+// recent ?limit=N of them, and only those started by ?trigger= if given.
+func (h *Handler) jobStats(r *http.Request) (statsView, int, error) {
+	view := statsView{Trigger: r.URL.Query().Get("trigger"), Triggers: jobTriggers}
+	if view.Trigger != "" {
+		known := false
+		for _, trigger := range jobTriggers {
+			known = known || trigger == view.Trigger
+		}
+		if !known {
+			return view, http.StatusBadRequest, fmt.Errorf("unknown trigger %q", view.Trigger)
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return view, http.StatusBadRequest, fmt.Errorf("limit must be a positive number")
+		}
+		view.Limit = limit
+	}
+
+	reports, err := h.Jobs.Recent(0)
+	if err != nil {
+		return view, http.StatusInternalServerError, err
+	}
+	var matching []jobs.Report
+	for _, report := range reports {
+		if view.Trigger != "" && report.Trigger != view.Trigger {
+			continue
+		}
+		matching = append(matching, report)
+		if view.Limit > 0 && len(matching) == view.Limit {
+			break
+		}
+	}
+	view.Stats = jobs.Summarize(matching)
+	return view, http.StatusOK, nil
+}
+
+// StatsHandler shows how long finished jobs spent in each stage of the
+// pipeline, with the median and 95th percentile per job, so it can be seen
+// whether provider calls or translation take most of the time.
+func (h *Handler) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	view, status, err := h.jobStats(r)
+	if err != nil {
+		respond(w, r, status, err.Error())
+		return
+	}
+	render(w, r, http.StatusOK, "stats", view)
+}
+
+// StatsAPIHandler returns the same statistics as the stats page as JSON.
+func (h *Handler) StatsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	view, status, err := h.jobStats(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
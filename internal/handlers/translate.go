@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/internal/translator"
+)
+
+const maxAlternatives = 3
+
+type alternativesRequest struct {
+	Text string `json:"text"`
+	// Series is the series the cue belongs to, if any, so the translation
+	// remembered for it is offered.
+	Series string `json:"series,omitempty"`
+}
+
+type alternativesResponse struct {
+	Text         string                   `json:"text"`
+	Alternatives []translator.Alternative `json:"alternatives"`
+}
+
+type memoryRequest struct {
+	Source      string `json:"source"`
+	Translation string `json:"translation"`
+	// Series limits the translation to one series; without it the
+	// translation is reused everywhere.
+	Series string `json:"series,omitempty"`
+}
+
+// AlternativesHandler returns a few alternative renderings of a single cue
+// from the configured translator backends.
+func (h *Handler) AlternativesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req alternativesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		http.Error(w, "Text required", http.StatusBadRequest)
+		return
+	}
+
+	alternatives := translator.Alternatives(r.Context(), h.Backends, req.Text, maxAlternatives)
+	// Offer them as they would be saved
+	if rules, err := translator.LoadNormalizationRules(h.Config().NormalizationFile); err != nil {
+		log.Printf("Warning: %v", err)
+	} else if !rules.Empty() {
+		normalizer := translator.NewNormalizer(rules)
+		for i := range alternatives {
+			alternatives[i].Text = normalizer.Normalize(alternatives[i].Text)
+		}
+	}
+	if remembered, ok := h.Memory.Lookup(req.Series, req.Text); ok {
+		alternatives = append([]translator.Alternative{{Backend: "memory", Text: remembered}}, alternatives...)
+	}
+
+	writeJSON(w, http.StatusOK, alternativesResponse{Text: req.Text, Alternatives: alternatives})
+}
+
+// MemoryHandler stores the translation picked for a cue in the translation
+// memory so future translations of the same text reuse it.
+func (h *Handler) MemoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req memoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Source) == "" || strings.TrimSpace(req.Translation) == "" {
+		http.Error(w, "Source and translation required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Memory.Remember(req.Series, req.Source, req.Translation); err != nil {
+		log.Printf("Error saving translation memory: %v", err)
+		http.Error(w, "Failed to save translation memory", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Saved translation memory entry (%d total)", h.Memory.Len())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
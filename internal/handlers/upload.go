@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"unicode/utf8"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/wanted"
+)
+
+// maxUploadSize caps uploaded subtitle files. Even a feature-length SRT is a
+// few hundred kilobytes.
+const maxUploadSize = 5 << 20
+
+var errInvalidUpload = errors.New("invalid subtitle file")
+
+// uploadSubtitle handles POST /items/{id}/subtitle, which takes a
+// multipart "file" and a "language" tag and saves the file as that item's
+// subtitle, or as its forced subtitle when "forced" is "true".
+func (h *Handler) uploadSubtitle(w http.ResponseWriter, r *http.Request, itemID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("Subtitle file required: %v", err))
+		return
+	}
+	defer file.Close()
+
+	language := lang.TraditionalChinese
+	if value := r.FormValue("language"); value != "" {
+		language = lang.Normalize(value)
+		if language.IsZero() {
+			respond(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown language %q", value))
+			return
+		}
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, fmt.Sprintf("Failed to read upload: %v", err))
+		return
+	}
+
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
+	if err != nil {
+		log.Printf("Error getting item details for %s: %v", itemID, err)
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
+		return
+	}
+
+	target := wanted.Target{Language: language, Forced: r.FormValue("forced") == "true"}
+	log.Printf("Saving uploaded %s subtitle %q for %s", target, header.Filename, item.Name)
+	result, err := h.saveUploadedSubtitle(r.Context(), item, target, content)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errInvalidUpload) {
+			status = http.StatusBadRequest
+		}
+		respond(w, r, status, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusOK, result.Message(h.Config().SubtitleDirectory))
+}
+
+// saveUploadedSubtitle checks that content is a usable SRT file, runs the
+// configured clean-up passes over it and saves it like a downloaded
+// subtitle, then records it and asks Jellyfin to pick it up.
+func (h *Handler) saveUploadedSubtitle(ctx context.Context, item *jellyfin.MediaItem, target wanted.Target, content []byte) (*ProcessResult, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("%w: the file must be UTF-8 encoded", errInvalidUpload)
+	}
+
+	entries, err := h.Parser.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidUpload, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no SRT cues found", errInvalidUpload)
+	}
+
+	entries = h.prepareEntries(entries)
+	formatted := []byte(h.Parser.Format(entries))
+
+	location, err := h.saveSubtitle(item, target.String(), formatted, len(entries))
+	if err != nil {
+		return nil, err
+	}
+	h.recordApplied(item, h.itemVideoPath(item), target.String(), 0)
+	result := &ProcessResult{SaveLocation: location, Source: "manual upload"}
+
+	record := wanted.NewResult(*item, wanted.StatusDownloaded, result.Source, result.SaveLocation)
+	if err := h.Wanted.RecordResult(item.ID, target, record); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	h.refreshMetadata(ctx, item)
+
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/wanted"
+)
+
+// saveForVersions puts the subtitle saved at subtitlePath for the item's
+// first version next to its other versions, so whichever one is played has
+// it. It is linked to where the versions share a directory and copied
+// otherwise. Versions with an embedded subtitle in the language are left
+// alone. A version that can't get it is logged and skipped: the subtitle
+// is saved either way.
+func (h *Handler) saveForVersions(item *jellyfin.MediaItem, language, subtitlePath string) {
+	versions := h.Wanted.Versions(*item)
+	if len(versions) < 2 {
+		return
+	}
+	target, _ := fileTarget(language)
+
+	for _, version := range versions[1:] {
+		if embedded, _ := version.SubtitleStatus(target.Language, target.Forced); embedded {
+			h.job.Logf("Version %s has an embedded %s subtitle, not adding one", version.Path, language)
+			continue
+		}
+		path, _ := h.generateSubtitlePath(item, version.Path, language, filepath.Ext(subtitlePath))
+		if path == subtitlePath {
+			continue
+		}
+		if err := h.linkSubtitle(subtitlePath, path); err != nil {
+			h.job.Logf("Warning: failed to save the subtitle for version %s: %v", version.Path, err)
+			continue
+		}
+		h.job.Logf("Saved the subtitle for version %s as well", version.Path)
+	}
+}
+
+// linkSubtitle makes path a symlink to the subtitle at target when both
+// are in one media directory, and a copy of it otherwise: in the downloads
+// directory each file is moved next to its own video later, and some file
+// systems have no symlinks. A symlinked subtitle follows later edits of the
+// one it points to; a copy is made again the next time the item gets a
+// subtitle. Whatever was at path is replaced, after a backup if configured.
+func (h *Handler) linkSubtitle(target, path string) error {
+	if err := h.checkWriteTarget(path); err != nil {
+		return err
+	}
+	content, err := os.ReadFile(target)
+	if err != nil {
+		return err
+	}
+	if h.Config().SubtitleBackups {
+		if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
+			if err := h.backupSubtitle(path, content); err != nil {
+				return fmt.Errorf("failed to back up the existing subtitle: %w", err)
+			}
+		}
+	}
+
+	if dir := filepath.Dir(path); dir == filepath.Dir(target) && filepath.Clean(dir) != filepath.Clean(h.Config().SubtitleDirectory) {
+		link := path + ".link"
+		os.Remove(link)
+		if err := os.Symlink(filepath.Base(target), link); err == nil {
+			if err := h.applyOwner(link); err != nil {
+				os.Remove(link)
+				return err
+			}
+			if err := os.Rename(link, path); err != nil {
+				os.Remove(link)
+				return err
+			}
+			return nil
+		}
+	}
+
+	if err := h.writeSubtitleFile(path, content); err != nil {
+		return fmt.Errorf("failed to write subtitle file: %w", err)
+	}
+	return nil
+}
+
+// saveForNewVersions gives the versions added to the item since its
+// subtitle for target was saved that subtitle, instead of hunting a new
+// one. It reports false when no version was added or the saved subtitle
+// can't be found, so the target is hunted as usual.
+func (h *Handler) saveForNewVersions(item *jellyfin.MediaItem, target wanted.Target) (*ProcessResult, bool) {
+	cell, err := h.Wanted.Cell(*item, target)
+	if err != nil || cell.Result == nil || len(cell.NewVersions) == 0 {
+		return nil, false
+	}
+	record := *cell.Result
+
+	known := record.Versions
+	if len(known) == 0 {
+		known = []string{record.VideoPath}
+	}
+	var saved string
+	for _, videoPath := range known {
+		if path, err := h.savedSubtitlePath(item, videoPath, target.String()); err == nil {
+			saved = path
+			break
+		}
+	}
+	if saved == "" {
+		h.job.Logf("The %s subtitle saved for %s is gone, hunting it for the new versions", target, item.Name)
+		return nil, false
+	}
+
+	for _, videoPath := range cell.NewVersions {
+		path, _ := h.generateSubtitlePath(item, videoPath, target.String(), filepath.Ext(saved))
+		if err := h.linkSubtitle(saved, path); err != nil {
+			h.job.Logf("Failed to save the %s subtitle for new version %s: %v", target, videoPath, err)
+			return nil, false
+		}
+		h.job.Logf("Saved the %s subtitle for new version %s", target, videoPath)
+	}
+
+	record.Versions = nil
+	for _, version := range item.Versions("") {
+		record.Versions = append(record.Versions, version.Path)
+	}
+	record.UpdatedAt = time.Now()
+	if err := h.Wanted.RecordResult(item.ID, target, record); err != nil {
+		h.job.Logf("Warning: %v", err)
+	}
+	return &ProcessResult{SaveLocation: record.Path, Source: record.Source}, true
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/wanted"
+)
+
+type wantedSummary struct {
+	Status wanted.Status
+	Count  int
+}
+
+type wantedView struct {
+	Targets     []wanted.Target
+	Rows        []wanted.Row
+	Summary     []wantedSummary
+	MissingOnly bool
+	// ReplacedOnly limits the list to items whose video file was replaced
+	// since a subtitle was saved for it.
+	ReplacedOnly bool
+	Replaced     int
+	// Requested are the subtitles viewers asked for that wait for
+	// approval, keyed by "{item ID}/{target}".
+	Requested map[string]bool
+}
+
+type ignoreRequest struct {
+	ItemID   string `json:"item_id"`
+	Language string `json:"language"`
+	Forced   bool   `json:"forced"`
+	Ignored  bool   `json:"ignored"`
+}
+
+// WantedHandler shows every library item with the status of each target
+// language (and forced-subtitle language), optionally limited to items that
+// still need a subtitle or whose video file was replaced.
+func (h *Handler) WantedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	items, err := h.Library.Items(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch media: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.Wanted.Compute(items)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compute subtitle status: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	view := wantedView{
+		Targets:      h.Wanted.Targets(),
+		MissingOnly:  r.URL.Query().Get("filter") == "missing",
+		ReplacedOnly: r.URL.Query().Get("filter") == "replaced",
+		Requested:    h.requestedTargets(),
+	}
+
+	counts := wanted.Summary(rows)
+	for _, status := range wanted.Statuses {
+		view.Summary = append(view.Summary, wantedSummary{Status: status, Count: counts[status]})
+	}
+
+	for _, row := range rows {
+		if row.Replaced() {
+			view.Replaced++
+		}
+		if view.MissingOnly && !row.Missing() || view.ReplacedOnly && !row.Replaced() {
+			continue
+		}
+		view.Rows = append(view.Rows, row)
+	}
+
+	render(w, r, http.StatusOK, "wanted", view)
+}
+
+// IgnoreHandler marks or unmarks a target language, or its forced subtitle
+// when "forced" is set, as not wanted for an item.
+// It takes a JSON body, or the same fields as a form submission, in which
+// case the browser is sent back to the wanted list.
+func (h *Handler) IgnoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ignoreRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.ItemID = r.FormValue("item_id")
+		req.Language = r.FormValue("language")
+		req.Forced = r.FormValue("forced") == "true"
+		req.Ignored = r.FormValue("ignored") == "true"
+	}
+	language := lang.Normalize(req.Language)
+	if req.ItemID == "" || language.IsZero() {
+		respond(w, r, http.StatusBadRequest, "Item ID and language required")
+		return
+	}
+
+	target := wanted.Target{Language: language, Forced: req.Forced}
+	if err := h.Wanted.SetIgnored(req.ItemID, target, req.Ignored); err != nil {
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to update item: %v", err))
+		return
+	}
+
+	if wantsHTML(r) {
+		redirect(w, r, returnPath(r, "/wanted"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
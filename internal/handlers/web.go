@@ -1,29 +1,80 @@
 package handlers
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"subtitle-hunter/config"
+	"subtitle-hunter/internal/events"
+	"subtitle-hunter/internal/extractor"
 	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
 	"subtitle-hunter/internal/opensubtitles"
+	"subtitle-hunter/internal/scheduler"
+	"subtitle-hunter/internal/store"
 	"subtitle-hunter/internal/subtitle"
+	"subtitle-hunter/internal/tempfiles"
 	"subtitle-hunter/internal/translator"
+	"subtitle-hunter/internal/usage"
+	"subtitle-hunter/internal/wanted"
+	"subtitle-hunter/internal/whisper"
 )
 
 type Handler struct {
 	JellyfinClient      *jellyfin.Client
-	OpenSubtitlesClient *opensubtitles.Client
+	OpenSubtitlesClient *opensubtitles.Registry
 	Translator          *translator.GoogleTranslator
+	Backends            []translator.Backend
+	// TranslatorHealth tracks the backends of the translator chain across
+	// jobs, so one that keeps failing is skipped.
+	TranslatorHealth    *translator.Health
+	Memory              *translator.Memory
 	Parser              *subtitle.SRTParser
-	Config              *config.Config
+	Cleaner             *subtitle.Cleaner
+	Fallback            subtitle.FallbackPolicy
+	Extractor           *extractor.Extractor
+	Whisper             *whisper.Client
+	TempFiles           *tempfiles.Manager
+	Store               *store.Store
+	Wanted              *wanted.Service
+	Usage               *usage.Tracker
+	Scheduler           *scheduler.Scheduler
+	Settings            *config.Reloader
+	Jobs                *jobs.History
+	Pool                *jobs.Pool
+	Library             *jellyfin.LibraryCache
+	Events              *events.Hub
+	// Context is cancelled on shutdown. Work that outlives the request that
+	// started it, such as a batch hunt, runs under it.
+	Context             context.Context
+
+	// job records the work of the current job in a view made by forJob.
+	job *jobs.Job
+	// bilingual overrides the configured bilingual output in a view made by
+	// withBilingual.
+	bilingual *bool
+	// translate overrides whether machine translation is allowed in a view
+	// made by withTranslation.
+	translate *bool
+	// scripts caches the scripts DetectSubtitleScript found.
+	scripts *scriptCache
+	// campaignState coordinates the backfill campaign runner.
+	campaignState *campaignState
+	// requestsMu guards reading and saving subtitle requests.
+	requestsMu *sync.Mutex
 }
 
 type MediaItemView struct {
@@ -35,11 +86,59 @@ type MediaItemView struct {
 	SeasonName   string
 	SeasonNumber int
 	EpisodeNumber int
+	// Languages are the item's own target languages, if it has any.
+	Languages []string
 }
 
 type SeriesGroup struct {
+	ID      string
 	Name    string
+	Paused  bool
 	Seasons map[int]*SeasonGroup
+	// Total counts the series' episodes in the library and Covered those
+	// that already have a subtitle.
+	Total   int
+	Covered int
+	// SeasonCounts are the same counts for each of its seasons, in order.
+	SeasonCounts []SeasonCount
+}
+
+// SeasonCount is how many of a season's episodes the library has and how
+// many of them have no subtitle.
+type SeasonCount struct {
+	Number  int
+	Total   int
+	Missing int
+}
+
+// NextEpisode returns the first listed episode, by season and episode
+// number.
+func (s *SeriesGroup) NextEpisode() MediaItemView {
+	first := -1
+	for number, season := range s.Seasons {
+		if len(season.Episodes) > 0 && (first == -1 || number < first) {
+			first = number
+		}
+	}
+	if first == -1 {
+		return MediaItemView{}
+	}
+	return s.Seasons[first].Episodes[0]
+}
+
+// Missing counts the series' episodes without a subtitle.
+func (s *SeriesGroup) Missing() int {
+	return s.Total - s.Covered
+}
+
+// SeasonCount returns the counts of one of the series' seasons.
+func (s *SeriesGroup) SeasonCount(number int) SeasonCount {
+	for _, count := range s.SeasonCounts {
+		if count.Number == number {
+			return count
+		}
+	}
+	return SeasonCount{Number: number}
 }
 
 type SeasonGroup struct {
@@ -49,24 +148,308 @@ type SeasonGroup struct {
 }
 
 type OrganizedMedia struct {
-	Series map[string]*SeriesGroup
-	Movies []MediaItemView
+	Series         map[string]*SeriesGroup
+	Movies         []MediaItemView
+	TargetLanguage lang.Tag
+	Query          string
+	// Grid shows posters instead of the list.
+	Grid bool
+	// ScannedAt is when the library listing was fetched from Jellyfin.
+	ScannedAt string
+	// Targets are suggested in the batch actions' language field.
+	Targets []lang.Tag
+	// Expanded lists every series' episodes, as a search or ?series= does;
+	// otherwise the page loads them when a series is opened.
+	Expanded bool
+	// NextMoviePage is the page of movies after those listed, 0 when all
+	// are.
+	NextMoviePage int
+	MoviesPerPage int
+	// Requests counts the subtitles viewers asked for that wait for
+	// approval.
+	Requests int
 }
 
-func NewHandler(jf *jellyfin.Client, os *opensubtitles.Client, cfg *config.Config) *Handler {
-	return &Handler{
+func NewHandler(jf *jellyfin.Client, os *opensubtitles.Registry, settings *config.Reloader) (*Handler, error) {
+	cfg := settings.Current()
+	googleTranslator := translator.NewGoogleTranslator()
+
+	cleaner, err := subtitle.NewCleaner(cfg.CleanPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	fallback, err := subtitle.NewFallbackPolicy(cfg.TranslationFallback, cfg.FallbackMarker, cfg.MaxFailurePercent, cfg.PartialFailurePercent)
+	if err != nil {
+		return nil, err
+	}
+
+	tempFiles, err := tempfiles.NewManager(cfg.TempDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if err := tempFiles.CleanupStale(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	ffmpeg := extractor.NewExtractor(cfg.FFmpegPath)
+	ffmpeg.TempDir = tempFiles.Dir()
+
+	memory, err := translator.LoadMemory(filepath.Join(cfg.DataDirectory, "translation-memory.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	dataStore, err := store.Connect(cfg.DatabaseURL, cfg.DataDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := subtitle.NewSRTParser()
+	parser.ContextWindow = cfg.TranslationContextCues
+
+	backends := []translator.Backend{{
+		Name:       config.TranslatorGoogle,
+		Translator: googleTranslator,
+		From: func(sourceLang string) translator.TextTranslator {
+			return googleTranslator.From(sourceLang)
+		},
+	}}
+	if cfg.DeepLAPIKey != "" {
+		deepl := translator.NewDeepLTranslator(cfg.DeepLAPIKey)
+		backends = append(backends, translator.Backend{Name: config.TranslatorDeepL, CostPerMillionChars: deeplCost(cfg.DeepLAPIKey), Translator: deepl, From: deepl.From})
+	}
+
+	h := &Handler{
 		JellyfinClient:      jf,
 		OpenSubtitlesClient: os,
-		Translator:          translator.NewGoogleTranslator(),
-		Parser:              subtitle.NewSRTParser(),
-		Config:              cfg,
+		Translator:          googleTranslator,
+		Backends:            backends,
+		TranslatorHealth:    translator.NewHealth(cfg.TranslatorCooldown),
+		Memory:    memory,
+		Parser:    parser,
+		Cleaner:   cleaner,
+		Fallback:  fallback,
+		Extractor: ffmpeg,
+		Whisper:   whisper.NewClient(cfg.WhisperURL, cfg.WhisperModel, cfg.WhisperLanguage),
+		TempFiles: tempFiles,
+		Store:     dataStore,
+		Wanted:    wanted.NewService(dataStore, targetLanguages(cfg)),
+		Usage:     usage.NewTracker(dataStore),
+		Settings:  settings,
+		Jobs:      jobs.NewHistory(dataStore),
+		Pool:      jobs.NewPool(cfg.WorkerPoolSize),
+		Library:   jellyfin.NewLibraryCache(jf, cfg.LibraryCacheTTL),
+		Events:    events.NewHub(),
+		scripts:   newScriptCache(),
+		campaignState: newCampaignState(),
+		requestsMu:    new(sync.Mutex),
 	}
+
+	h.Wanted.SetForcedLanguages(parseTargets(cfg.ForcedLanguages))
+	h.Wanted.SetPreferredVersion(cfg.PreferredVersion)
+
+	settings.OnReload(func(cfg *config.Config) {
+		h.Wanted.SetLanguages(targetLanguages(cfg))
+		h.Wanted.SetForcedLanguages(parseTargets(cfg.ForcedLanguages))
+		h.Wanted.SetPreferredVersion(cfg.PreferredVersion)
+		h.Pool.SetSize(cfg.WorkerPoolSize)
+		h.Library.SetTTL(cfg.LibraryCacheTTL)
+		h.TranslatorHealth.SetCooldown(cfg.TranslatorCooldown)
+	})
+
+	return h, nil
 }
 
-func (h *Handler) organizeMedia(items []jellyfin.MediaItem) *OrganizedMedia {
+// forJob returns a view of the handler that records its work into job.
+func (h *Handler) forJob(job *jobs.Job) *Handler {
+	view := *h
+	view.job = job
+	return &view
+}
+
+// RunJob runs fn for item as a job started by trigger and stores the job's
+// report. The job waits for any other job working on the item to finish,
+// then for a free worker in the pool. The job ID is returned even when fn
+// fails, but there is none when ctx ended the wait. Cancelling ctx (a
+// closed browser request, shutdown) or the job itself stops the job at its
+// next network call.
+// While the job waits for a translation to be approved its worker is free
+// for other jobs.
+func (h *Handler) RunJob(ctx context.Context, item *jellyfin.MediaItem, trigger string, fn func(h *Handler, ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error)) (string, *ProcessResult, error) {
+	return h.runJob(ctx, item, trigger, "", fn)
+}
+
+// Hunt runs HuntItem for item as a job started by trigger, like RunJob. A
+// hunt already queued or running for the item is joined instead of hunting
+// twice: Hunt waits for it and returns its job ID and outcome. A hunt that
+// overrides the bilingual output or machine translation waits for it and
+// then runs on its own.
+func (h *Handler) Hunt(ctx context.Context, item *jellyfin.MediaItem, trigger string) (string, *ProcessResult, error) {
+	work := jobs.WorkHunt
+	if h.bilingual != nil || h.translate != nil {
+		work = ""
+	}
+	return h.runJob(ctx, item, trigger, work, (*Handler).HuntItem)
+}
+
+// jobOutcome is what callers joining a job get from it.
+type jobOutcome struct {
+	jobID  string
+	result *ProcessResult
+}
+
+func (h *Handler) runJob(ctx context.Context, item *jellyfin.MediaItem, trigger, work string, fn func(h *Handler, ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error)) (string, *ProcessResult, error) {
+	for {
+		claim, joined, err := h.Jobs.Claim(ctx, item.ID, work)
+		if err != nil {
+			return "", nil, fmt.Errorf("gave up waiting for the job working on %s: %w", item.Name, err)
+		}
+		if !joined {
+			jobID, result, err := h.claimedJob(ctx, claim, item, trigger, fn)
+			h.Jobs.Release(claim, jobOutcome{jobID, result}, err)
+			return jobID, result, err
+		}
+
+		if job := claim.Job(); job != nil {
+			job.Logf("Joined by another %s request for the same item", trigger)
+		}
+		value, err := claim.Wait(ctx)
+		// Its caller went away, not this one
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			continue
+		}
+		outcome, _ := value.(jobOutcome)
+		return outcome.jobID, outcome.result, err
+	}
+}
+
+// claimedJob runs fn as a job once the claim on the item is held.
+func (h *Handler) claimedJob(ctx context.Context, claim *jobs.Claim, item *jellyfin.MediaItem, trigger string, fn func(h *Handler, ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error)) (string, *ProcessResult, error) {
+	queued := time.Now()
+	release, err := h.Pool.Acquire(ctx, trigger)
+	if err != nil {
+		return "", nil, fmt.Errorf("gave up waiting for a worker: %w", err)
+	}
+	waited := time.Since(queued)
+
+	job := jobs.Start(item.ID, item.Name, trigger)
+	claim.Started(job)
+	job.SetWorker(release, func(ctx context.Context) (func(), error) {
+		return h.Pool.Acquire(ctx, trigger)
+	})
+	defer job.ReleaseWorker()
+	jobCtx, cancel := context.WithCancelCause(jobs.NewContext(ctx, job))
+	defer cancel(nil)
+	job.SetCancel(cancel)
+	h.Jobs.Begin(job)
+	job.Logf("Job %s started (%s) for %s", job.ID(), trigger, item.Name)
+	result, err := h.callJob(jobCtx, job, item, fn)
+	if err != nil && errors.Is(context.Cause(jobCtx), jobs.ErrCancelled) {
+		err = jobs.ErrCancelled
+	}
+
+	var source string
+	if result != nil {
+		source = result.Source
+	}
+	if errors.Is(err, jobs.ErrCancelled) {
+		job.Logf("Job %s cancelled", job.ID())
+	} else if err != nil {
+		job.Logf("Job %s failed: %v", job.ID(), err)
+	} else {
+		job.Logf("Job %s finished", job.ID())
+	}
+	report := job.Finish(source, err)
+	report.QueueWaitMs = waited.Milliseconds()
+	for _, backend := range h.Backends {
+		if backend.Name == h.primaryBackend() {
+			report.EstimatedCost = float64(report.CharactersTranslated) / 1_000_000 * backend.CostPerMillionChars
+		}
+	}
+	if saveErr := h.Jobs.Save(report, job.Log()); saveErr != nil {
+		log.Printf("Warning: %v", saveErr)
+	}
+
+	finished := events.JobFinishedData{JobID: job.ID(), ItemID: item.ID, Name: item.Name, Trigger: trigger, Succeeded: err == nil, Source: source}
+	if err != nil {
+		finished.Error = err.Error()
+	}
+	h.Events.Publish(events.JobFinished, finished)
+	return job.ID(), result, err
+}
+
+// callJob runs fn for the job. A panic in fn fails the job with an error,
+// logged with its stack trace, instead of taking the server down: jobs run
+// by the scheduler have no request handler to recover for them.
+func (h *Handler) callJob(ctx context.Context, job *jobs.Job, item *jellyfin.MediaItem, fn func(h *Handler, ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error)) (result *ProcessResult, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("panic job=%s item=%s error=%q stack=%q", job.ID(), item.ID, p, debug.Stack())
+			result, err = nil, fmt.Errorf("internal error: %v", p)
+		}
+	}()
+	return fn(h.forJob(job), ctx, item)
+}
+
+// stage starts timing a job stage and bounds the work done in it by the
+// stage's configured timeout. The returned function ends the stage.
+func (h *Handler) stage(ctx context.Context, name string) (context.Context, func()) {
+	stop := h.job.Stage(name)
+	timeout := h.Config().StageTimeouts[name]
+	if timeout <= 0 {
+		return ctx, stop
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// Config returns the configuration currently in effect.
+func (h *Handler) Config() *config.Config {
+	return h.Settings.Current()
+}
+
+// targetLanguages parses the configured target languages, falling back to
+// Traditional Chinese when none are usable.
+func targetLanguages(cfg *config.Config) []lang.Tag {
+	targets := parseTargets(cfg.TargetLanguages)
+	if len(targets) == 0 {
+		return []lang.Tag{lang.TraditionalChinese}
+	}
+	return targets
+}
+
+// parseTargets parses target language tags, dropping unknown ones and
+// duplicates.
+func parseTargets(values []string) []lang.Tag {
+	var targets []lang.Tag
+	seen := make(map[lang.Tag]bool)
+	for _, value := range values {
+		// "zh-TW" and "zh-Hant" name the same subtitle file
+		tag := lang.Normalize(value)
+		if tag.IsZero() || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		targets = append(targets, tag)
+	}
+	return targets
+}
+
+// organizeMedia groups episodes by series and season. Episodes are only
+// looked up in full when expanded, since otherwise the page loads them from
+// the items API.
+func (h *Handler) organizeMedia(items []jellyfin.MediaItem, expanded bool) *OrganizedMedia {
 	organized := &OrganizedMedia{
-		Series: make(map[string]*SeriesGroup),
-		Movies: []MediaItemView{},
+		Series:         make(map[string]*SeriesGroup),
+		Movies:         []MediaItemView{},
+		TargetLanguage: lang.TraditionalChinese,
+		Targets:        h.Wanted.Languages(),
+		Expanded:       expanded,
 	}
 
 	for _, item := range items {
@@ -80,24 +463,23 @@ func (h *Handler) organizeMedia(items []jellyfin.MediaItem) *OrganizedMedia {
 			SeasonNumber:  item.ParentIndexNumber,
 			EpisodeNumber: item.IndexNumber,
 		}
+		if item.Type != "Episode" || expanded {
+			viewItem.Languages = h.itemLanguages(item.ID)
+		}
 
 		if item.Type == "Episode" {
-			seriesName := item.SeriesName
-			if seriesName == "" {
-				seriesName = "Unknown Series"
-			}
+			seriesName := seriesName(item)
 
 			if organized.Series[seriesName] == nil {
 				organized.Series[seriesName] = &SeriesGroup{
+					ID:      item.SeriesID,
 					Name:    seriesName,
+					Paused:  h.HuntingPaused(&item),
 					Seasons: make(map[int]*SeasonGroup),
 				}
 			}
 
-			seasonNum := item.ParentIndexNumber
-			if seasonNum == 0 {
-				seasonNum = 1 // Default to season 1 if not specified
-			}
+			seasonNum := seasonNumber(item)
 
 			if organized.Series[seriesName].Seasons[seasonNum] == nil {
 				organized.Series[seriesName].Seasons[seasonNum] = &SeasonGroup{
@@ -123,264 +505,145 @@ func (h *Handler) organizeMedia(items []jellyfin.MediaItem) *OrganizedMedia {
 		}
 	}
 
-	// Sort movies by name
-	sort.Slice(organized.Movies, func(i, j int) bool {
-		return organized.Movies[i].Name < organized.Movies[j].Name
-	})
-
 	return organized
 }
 
+// countCoverage fills in how many of each listed series' episodes the
+// library has and how many of them have a subtitle, for the whole series
+// and for each season. missing are the items of all without one.
+func (o *OrganizedMedia) countCoverage(all, missing []jellyfin.MediaItem) {
+	isMissing := make(map[string]bool, len(missing))
+	for _, item := range missing {
+		isMissing[item.ID] = true
+	}
+
+	seasons := make(map[*SeriesGroup]map[int]*SeasonCount)
+	for _, item := range all {
+		if item.Type != "Episode" {
+			continue
+		}
+		series := o.Series[seriesName(item)]
+		if series == nil {
+			continue
+		}
+		if seasons[series] == nil {
+			seasons[series] = make(map[int]*SeasonCount)
+		}
+		number := seasonNumber(item)
+		season := seasons[series][number]
+		if season == nil {
+			season = &SeasonCount{Number: number}
+			seasons[series][number] = season
+		}
+
+		series.Total++
+		season.Total++
+		if isMissing[item.ID] {
+			season.Missing++
+		} else {
+			series.Covered++
+		}
+	}
+
+	for series, counts := range seasons {
+		series.SeasonCounts = nil
+		for _, count := range counts {
+			series.SeasonCounts = append(series.SeasonCounts, *count)
+		}
+		sort.Slice(series.SeasonCounts, func(i, j int) bool {
+			return series.SeasonCounts[i].Number < series.SeasonCounts[j].Number
+		})
+	}
+}
+
+// indexView returns whether the index page shows the poster grid. A choice
+// made with ?view=grid or ?view=list is remembered in a cookie.
+func indexView(w http.ResponseWriter, r *http.Request) bool {
+	view := r.URL.Query().Get("view")
+	switch view {
+	case "grid", "list":
+		http.SetCookie(w, &http.Cookie{
+			Name:     "view",
+			Value:    view,
+			Path:     "/",
+			MaxAge:   365 * 24 * 60 * 60,
+			SameSite: http.SameSiteLaxMode,
+		})
+	default:
+		if cookie, err := r.Cookie("view"); err == nil {
+			view = cookie.Value
+		}
+	}
+	return view == "grid"
+}
+
+// filterItems keeps the items whose name or series name contains query,
+// ignoring case. The index page filters as you type with JavaScript; this
+// is the same search for browsers without it.
+func filterItems(items []jellyfin.MediaItem, query string) []jellyfin.MediaItem {
+	query = strings.ToLower(query)
+
+	var filtered []jellyfin.MediaItem
+	for _, item := range items {
+		if matchesSearch(item, query) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// matchesSearch reports whether the item's name or series name contains
+// query, which must be lower case.
+func matchesSearch(item jellyfin.MediaItem, query string) bool {
+	return strings.Contains(strings.ToLower(item.Name), query) || strings.Contains(strings.ToLower(item.SeriesName), query)
+}
+
 func (h *Handler) IndexHandler(w http.ResponseWriter, r *http.Request) {
-	items, err := h.JellyfinClient.GetMediaWithoutChineseSubtitles()
+	all, err := h.Library.Items(r.Context())
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to fetch media: %v", err), http.StatusInternalServerError)
 		return
 	}
+	missing := h.JellyfinClient.WithoutChineseSubtitles(all)
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	series := strings.TrimSpace(r.URL.Query().Get("series"))
+	listing := itemQuery{Search: query, Series: series, Sort: "name"}
+	var episodes, movies []jellyfin.MediaItem
+	for _, item := range missing {
+		if !listing.matches(item) {
+			continue
+		}
+		if item.Type == "Episode" {
+			episodes = append(episodes, item)
+		} else {
+			movies = append(movies, item)
+		}
+	}
 
-	organized := h.organizeMedia(items)
-
-	tmpl := `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>Subtitle Hunter</title>
-    <style>
-        body { 
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif; 
-            margin: 0; padding: 20px; background-color: #f5f5f5; 
-        }
-        .container { max-width: 1200px; margin: 0 auto; background: white; padding: 20px; border-radius: 8px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); }
-        h1 { color: #333; margin-bottom: 30px; text-align: center; }
-        .search-box { 
-            width: 100%; padding: 12px; margin-bottom: 20px; border: 1px solid #ddd; 
-            border-radius: 6px; font-size: 16px; box-sizing: border-box;
-        }
-        .search-box:focus { outline: none; border-color: #4CAF50; }
-        
-        .series { margin-bottom: 30px; border: 1px solid #e1e1e1; border-radius: 6px; overflow: hidden; }
-        .series-header { 
-            background: #f8f9fa; padding: 15px; font-weight: bold; font-size: 18px; 
-            border-bottom: 1px solid #e1e1e1; cursor: pointer; user-select: none;
-            display: flex; justify-content: space-between; align-items: center;
-        }
-        .series-header:hover { background: #e9ecef; }
-        .toggle { font-size: 14px; color: #666; }
-        
-        .season { border-top: 1px solid #f0f0f0; }
-        .season-header { 
-            background: #fafafa; padding: 12px 15px; font-weight: 600; 
-            border-bottom: 1px solid #f0f0f0; font-size: 16px; color: #555;
-        }
-        
-        .episodes { background: white; }
-        .episode { 
-            display: flex; justify-content: space-between; align-items: center; 
-            padding: 12px 15px; border-bottom: 1px solid #f8f8f8; 
-        }
-        .episode:last-child { border-bottom: none; }
-        .episode:hover { background: #f9f9f9; }
-        
-        .episode-info { flex: 1; }
-        .episode-name { font-weight: 500; color: #333; margin-bottom: 4px; }
-        .episode-details { font-size: 14px; color: #666; }
-        
-        .movies-section { margin-top: 30px; }
-        .movies-grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(300px, 1fr)); gap: 15px; }
-        .movie-card { 
-            background: white; border: 1px solid #e1e1e1; border-radius: 6px; padding: 15px;
-            display: flex; justify-content: space-between; align-items: center;
-        }
-        .movie-card:hover { box-shadow: 0 2px 8px rgba(0,0,0,0.1); }
-        
-        .button { 
-            background-color: #4CAF50; color: white; padding: 8px 16px; 
-            border: none; border-radius: 4px; cursor: pointer; font-size: 14px;
-            transition: background-color 0.2s;
-        }
-        .button:hover { background-color: #45a049; }
-        .button:disabled { opacity: 0.6; cursor: not-allowed; }
-        
-        .hidden { display: none; }
-        .no-results { text-align: center; color: #666; padding: 40px; font-style: italic; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>Media Missing Traditional Chinese Subtitles</h1>
-        
-        <input type="text" class="search-box" placeholder="Search shows, movies, or episodes..." 
-               oninput="filterContent(this.value)">
-
-        <div id="content">
-            {{range $seriesName, $series := .Series}}
-            <div class="series" data-series="{{$seriesName}}">
-                <div class="series-header" onclick="toggleSeries(this)">
-                    <span>{{$seriesName}}</span>
-                    <span class="toggle">▼</span>
-                </div>
-                <div class="series-content">
-                    {{range $seasonNum, $season := $series.Seasons}}
-                    <div class="season">
-                        <div class="season-header">
-                            Season {{$season.Number}}{{if $season.Name}} - {{$season.Name}}{{end}}
-                        </div>
-                        <div class="episodes">
-                            {{range $season.Episodes}}
-                            <div class="episode" data-episode="{{.Name}}">
-                                <div class="episode-info">
-                                    <div class="episode-name">{{.EpisodeNumber}}. {{.Name}}</div>
-                                    <div class="episode-details">Episode {{.EpisodeNumber}}</div>
-                                </div>
-                                <button class="button" onclick="findSubtitle('{{.ID}}', this)">Find Subtitle</button>
-                            </div>
-                            {{end}}
-                        </div>
-                    </div>
-                    {{end}}
-                </div>
-            </div>
-            {{end}}
-            
-            {{if .Movies}}
-            <div class="movies-section">
-                <h2>Movies</h2>
-                <div class="movies-grid">
-                    {{range .Movies}}
-                    <div class="movie-card" data-movie="{{.Name}}">
-                        <div>
-                            <div class="episode-name">{{.Name}}</div>
-                            <div class="episode-details">Movie</div>
-                        </div>
-                        <button class="button" onclick="findSubtitle('{{.ID}}', this)">Find Subtitle</button>
-                    </div>
-                    {{end}}
-                </div>
-            </div>
-            {{end}}
-        </div>
-
-        <div id="no-results" class="no-results hidden">
-            No matching content found. Try a different search term.
-        </div>
-    </div>
-
-    <script>
-        // Toggle series expansion
-        function toggleSeries(header) {
-            const content = header.nextElementSibling;
-            const toggle = header.querySelector('.toggle');
-            
-            if (content.style.display === 'none') {
-                content.style.display = 'block';
-                toggle.textContent = '▼';
-            } else {
-                content.style.display = 'none';
-                toggle.textContent = '▶';
-            }
-        }
-
-        // Search functionality
-        function filterContent(searchTerm) {
-            const term = searchTerm.toLowerCase();
-            const series = document.querySelectorAll('.series');
-            const movies = document.querySelectorAll('.movie-card');
-            let hasResults = false;
-
-            // Filter series and episodes
-            series.forEach(seriesEl => {
-                const seriesName = seriesEl.dataset.series.toLowerCase();
-                const episodes = seriesEl.querySelectorAll('.episode');
-                let hasMatchingEpisodes = false;
-
-                episodes.forEach(episode => {
-                    const episodeName = episode.dataset.episode.toLowerCase();
-                    if (episodeName.includes(term) || seriesName.includes(term)) {
-                        episode.style.display = 'flex';
-                        hasMatchingEpisodes = true;
-                        hasResults = true;
-                    } else {
-                        episode.style.display = 'none';
-                    }
-                });
-
-                if (hasMatchingEpisodes || seriesName.includes(term)) {
-                    seriesEl.style.display = 'block';
-                    // Auto-expand if there's a match
-                    if (term) {
-                        const content = seriesEl.querySelector('.series-content');
-                        const toggle = seriesEl.querySelector('.toggle');
-                        content.style.display = 'block';
-                        toggle.textContent = '▼';
-                    }
-                } else {
-                    seriesEl.style.display = 'none';
-                }
-            });
-
-            // Filter movies
-            movies.forEach(movie => {
-                const movieName = movie.dataset.movie.toLowerCase();
-                if (movieName.includes(term)) {
-                    movie.style.display = 'flex';
-                    hasResults = true;
-                } else {
-                    movie.style.display = 'none';
-                }
-            });
-
-            // Show/hide no results message
-            document.getElementById('no-results').classList.toggle('hidden', hasResults || !term);
-        }
-
-        // Subtitle processing
-        async function findSubtitle(itemId, button) {
-            const originalText = button.textContent;
-            button.textContent = 'Processing...';
-            button.disabled = true;
-            
-            try {
-                const response = await fetch('/process/' + itemId, {
-                    method: 'POST'
-                });
-                
-                const result = await response.text();
-                
-                if (response.ok) {
-                    button.textContent = 'Success!';
-                    button.style.backgroundColor = '#28a745';
-                    setTimeout(() => {
-                        location.reload();
-                    }, 2000);
-                } else {
-                    button.textContent = 'Error: ' + result;
-                    button.style.backgroundColor = '#dc3545';
-                    button.disabled = false;
-                }
-            } catch (error) {
-                button.textContent = 'Network Error';
-                button.style.backgroundColor = '#dc3545';
-                button.disabled = false;
-            }
-        }
-
-        // Initialize - collapse all series by default
-        document.addEventListener('DOMContentLoaded', () => {
-            document.querySelectorAll('.series-content').forEach(content => {
-                content.style.display = 'none';
-            });
-            document.querySelectorAll('.toggle').forEach(toggle => {
-                toggle.textContent = '▶';
-            });
-        });
-    </script>
-</body>
-</html>`
-
-	t := template.Must(template.New("index").Parse(tmpl))
-	if err := t.Execute(w, organized); err != nil {
-		http.Error(w, "Template execution failed", http.StatusInternalServerError)
+	// The page loads further movies from the items API as it is scrolled.
+	// Without JavaScript, ?page=N lists the first N pages.
+	listing.sortItems(movies)
+	pages := 1
+	if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && page > 1 && page <= len(movies)/moviesPerPage+1 {
+		pages = page
+	}
+	nextPage := 0
+	if len(movies) > pages*moviesPerPage {
+		movies = movies[:pages*moviesPerPage]
+		nextPage = pages + 1
 	}
+
+	organized := h.organizeMedia(append(episodes, movies...), query != "" || series != "")
+	organized.Query = query
+	organized.NextMoviePage = nextPage
+	organized.MoviesPerPage = moviesPerPage
+	organized.Grid = indexView(w, r)
+	organized.countCoverage(all, missing)
+	organized.ScannedAt = formatTime(h.Library.ScannedAt())
+	organized.Requests = len(h.requestedTargets())
+
+	render(w, r, http.StatusOK, "index", organized)
 }
 
 func (h *Handler) ProcessHandler(w http.ResponseWriter, r *http.Request) {
@@ -395,151 +658,864 @@ func (h *Handler) ProcessHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	bilingual, err := bilingualOverride(r)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if bilingual != nil {
+		h = h.withBilingual(*bilingual)
+	}
+	translate, err := translationOverride(r)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if translate != nil {
+		h = h.withTranslation(*translate)
+	}
+
 	log.Printf("Processing subtitle for item: %s", itemID)
 
-	item, err := h.JellyfinClient.GetItem(itemID)
+	item, err := h.JellyfinClient.GetItem(r.Context(), itemID)
 	if err != nil {
 		log.Printf("Error getting item details for %s: %v", itemID, err)
-		http.Error(w, fmt.Sprintf("Failed to get item details: %v", err), http.StatusInternalServerError)
+		respond(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get item details: %v", err))
 		return
 	}
-	
-	var videoPath string
-	if len(item.MediaSources) > 0 {
-		videoPath = item.MediaSources[0].Path
+
+	// A browser without JavaScript follows the hunt already running instead
+	// of waiting for it
+	if job, running := h.Jobs.Claimed(item.ID, jobs.WorkHunt); running && wantsHTML(r) && h.bilingual == nil && h.translate == nil {
+		job.Logf("Joined by another manual request for the same item")
+		redirect(w, r, "/jobs/"+job.ID())
+		return
+	}
+
+	jobID, result, err := h.Hunt(r.Context(), item, jobs.TriggerManual)
+	if jobID != "" {
+		w.Header().Set("X-Job-ID", jobID)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errNoSubtitles) {
+			status = http.StatusNotFound
+		}
+		respond(w, r, status, err.Error())
+		return
+	}
+
+	respond(w, r, http.StatusOK, result.Message(h.Config().SubtitleDirectory))
+}
+
+var errNoSubtitles = errors.New("no subtitles found")
+
+type ProcessResult struct {
+	SaveLocation string
+	Source       string
+	Report       *subtitle.TranslationReport
+	// SubtitleID is the OpenSubtitles subtitle the saved file was made
+	// from, if any.
+	SubtitleID string
+}
+
+// Record returns what the wanted list remembers about the subtitle saved
+// for the item.
+func (r *ProcessResult) Record(item *jellyfin.MediaItem) wanted.Result {
+	record := wanted.NewResult(*item, wanted.StatusDownloaded, r.Source, r.SaveLocation)
+	record.SubtitleID = r.SubtitleID
+	if r.Report != nil {
+		record.Status = wanted.StatusTranslated
+		record.FailedCues = r.Report.FailedIndexes
+		record.Partial = r.Report.Partial
+	}
+	return record
+}
+
+func (r *ProcessResult) Message(downloadsDir string) string {
+	var message string
+	if r.SaveLocation == "media" {
+		message = "Subtitle processed successfully and saved to media directory (Jellyfin will detect automatically)"
 	} else {
-		videoPath = item.Path
+		message = fmt.Sprintf("Subtitle processed successfully and saved to downloads directory (%s)", downloadsDir)
+	}
+	if r.Source != "" {
+		message += fmt.Sprintf(" from %s", r.Source)
 	}
-	log.Printf("Got video path: %s", videoPath)
+	if r.Report != nil && r.Report.Failed > 0 {
+		message += fmt.Sprintf(". %s", r.Report)
+	}
+	return message
+}
 
-	searchQuery := h.JellyfinClient.GetSearchQuery(*item)
-	log.Printf("Searching subtitles for: %s", searchQuery)
+// huntTargets lists what HuntItem looks for: a full subtitle in each of the
+// item's target languages, then a forced one in each forced-subtitle
+// language.
+func (h *Handler) huntTargets(item *jellyfin.MediaItem) []wanted.Target {
+	var targets []wanted.Target
+	for _, language := range h.targetsFor(item) {
+		targets = append(targets, wanted.Target{Language: language})
+	}
+	for _, language := range h.Wanted.ForcedLanguages() {
+		targets = append(targets, wanted.Target{Language: language, Forced: true})
+	}
+	return targets
+}
 
-	var saveLocation string
-	
-	chineseSubtitle, err := h.OpenSubtitlesClient.FindBestSubtitle(searchQuery, "zh-TW")
-	if err == nil && chineseSubtitle != nil {
-		log.Printf("Found Chinese subtitle directly")
-		location, err := h.downloadAndSaveSubtitle(chineseSubtitle, videoPath, "zh-Hant")
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to save Chinese subtitle: %v", err), http.StatusInternalServerError)
-			return
+// HuntItem processes an item and asks Jellyfin to pick up the new subtitle.
+// The target languages come from the item's series settings when it has
+// any. Each is hunted through its fallback chain; forced subtitles are only
+// downloaded. Traditional Chinese is always hunted, other languages only
+// while they are still missing. The first successful result is returned.
+func (h *Handler) HuntItem(ctx context.Context, item *jellyfin.MediaItem) (*ProcessResult, error) {
+	var primary *ProcessResult
+	var firstErr error
+
+	for _, target := range h.huntTargets(item) {
+		var result *ProcessResult
+		var err error
+
+		if target != (wanted.Target{Language: lang.TraditionalChinese}) {
+			status, statusErr := h.Wanted.Status(*item, target)
+			if statusErr != nil || status != wanted.StatusMissing {
+				continue
+			}
 		}
-		saveLocation = location
-	} else {
-		log.Printf("Chinese subtitle not found, searching for English")
-		englishSubtitle, err := h.OpenSubtitlesClient.FindBestSubtitle(searchQuery, "en")
-		if err != nil {
-			log.Printf("Error finding English subtitle: %v", err)
-			http.Error(w, fmt.Sprintf("No subtitles found: %v", err), http.StatusNotFound)
-			return
+		if result, ok := h.saveForNewVersions(item, target); ok {
+			if primary == nil {
+				primary = result
+			}
+			continue
+		}
+		if target.Forced {
+			result, err = h.processDirectDownload(ctx, item, target)
+		} else {
+			result, err = h.processItem(ctx, item, target.Language)
 		}
 
-		log.Printf("Found English subtitle, starting translation process...")
-		location, err := h.translateAndSaveSubtitle(englishSubtitle, videoPath)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if err != nil {
-			log.Printf("Error in translation process: %v", err)
-			http.Error(w, fmt.Sprintf("Failed to translate subtitle: %v", err), http.StatusInternalServerError)
-			return
+			h.job.Logf("No %s subtitle for %s: %v", target, item.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			if errors.Is(err, ErrBudgetExhausted) {
+				break
+			}
+			continue
+		}
+
+		if err := h.Wanted.RecordResult(item.ID, target, result.Record(item)); err != nil {
+			h.job.Logf("Warning: %v", err)
+		}
+
+		if primary == nil {
+			primary = result
 		}
-		saveLocation = location
-		log.Printf("Translation completed successfully")
 	}
 
-	log.Printf("Refreshing Jellyfin metadata")
-	if err := h.JellyfinClient.RefreshMetadata(itemID); err != nil {
-		log.Printf("Warning: Failed to refresh metadata: %v", err)
+	if primary == nil {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%w: no target language needs a subtitle", errNoSubtitles)
+		}
+		return nil, firstErr
 	}
 
-	var successMessage string
-	if saveLocation == "media" {
-		successMessage = "Subtitle processed successfully and saved to media directory (Jellyfin will detect automatically)"
-	} else {
-		successMessage = fmt.Sprintf("Subtitle processed successfully and saved to downloads directory (%s)", h.Config.SubtitleDirectory)
+	h.refreshMetadata(ctx, item)
+
+	return primary, nil
+}
+
+// processDirectDownload downloads a subtitle for the target as-is.
+// Translation is only available into Traditional Chinese.
+func (h *Handler) processDirectDownload(ctx context.Context, item *jellyfin.MediaItem, target wanted.Target) (*ProcessResult, error) {
+	sub, err := h.findSubtitle(ctx, item, target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoSubtitles, err)
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(successMessage))
+	location, err := h.downloadAndSaveSubtitle(ctx, item, sub, target)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to save %s subtitle: %w", target, err)
+	}
+	return &ProcessResult{SaveLocation: location, Source: "OpenSubtitles", SubtitleID: sub.ID}, nil
+}
+
+// refreshMetadata asks Jellyfin to pick up a newly saved subtitle.
+func (h *Handler) refreshMetadata(ctx context.Context, item *jellyfin.MediaItem) {
+	if isLocalItem(item) {
+		return
+	}
+	ctx, stop := h.stage(ctx, jobs.StageRefresh)
+	defer stop()
+
+	h.job.Logf("Refreshing Jellyfin metadata")
+	if err := h.JellyfinClient.RefreshMetadata(ctx, item.ID); err != nil {
+		h.job.Logf("Warning: Failed to refresh metadata: %v", err)
+	}
+	// The item has a new subtitle, so its cached listing is out of date
+	h.Library.Invalidate(item.ID)
+}
+
+// itemVideoPath returns the video file subtitles are fetched for and saved
+// next to: the item's preferred version, or its first one when every
+// version gets them (see saveForVersions).
+func (h *Handler) itemVideoPath(item *jellyfin.MediaItem) string {
+	return h.Wanted.Versions(*item)[0].Path
 }
 
-func (h *Handler) downloadAndSaveSubtitle(subtitle *opensubtitles.Subtitle, videoPath, language string) (string, error) {
-	content, err := h.OpenSubtitlesClient.DownloadSubtitle(subtitle)
+// processEmbeddedTrack extracts a text subtitle stream already muxed into the
+// video and converts or translates it to Traditional Chinese.
+// Only tracks in sourceLanguages are used, in that order of preference.
+func (h *Handler) processEmbeddedTrack(ctx context.Context, item *jellyfin.MediaItem, videoPath string, sourceLanguages []string) (*ProcessResult, error) {
+	stream, language, ok := extractor.SelectTrack(item.MediaStreams, sourceLanguages)
+	if !ok {
+		return nil, fmt.Errorf("no embedded text subtitle track in %v", sourceLanguages)
+	}
+
+	containerPath := h.containerPath(item, videoPath)
+	h.job.Logf("Extracting embedded %s subtitle (stream %d, %s) from %s", language, stream.Index, stream.Codec, containerPath)
+
+	extractCtx, stopExtract := h.stage(ctx, jobs.StageExtract)
+	content, err := h.Extractor.ExtractSRT(extractCtx, containerPath, stream.Index)
+	stopExtract()
 	if err != nil {
-		return "", fmt.Errorf("failed to download subtitle: %w", err)
+		return nil, err
 	}
 
-	subtitlePath, saveLocation := h.generateSubtitlePath(videoPath, language)
-	if err := os.WriteFile(subtitlePath, content, 0644); err != nil {
-		return "", fmt.Errorf("failed to write subtitle file: %w", err)
+	stopParse := h.job.Stage(jobs.StageParse)
+	entries, err := h.Parser.Parse(content)
+	stopParse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse extracted subtitle: %w", err)
 	}
-	
-	log.Printf("Subtitle saved to %s directory: %s", saveLocation, subtitlePath)
-	return saveLocation, nil
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("extracted subtitle contains no cues")
+	}
+	if !lang.Parse(language).IsChinese() {
+		if err := h.approveTranslation(ctx, item, fmt.Sprintf("Translate the embedded %s track into %s", lang.Parse(language).DisplayName(), lang.TraditionalChinese.DisplayName())); err != nil {
+			return nil, err
+		}
+	}
+
+	h, textTranslator := h.translatorFrom(lang.Parse(language))
+	location, report, err := h.translateAndSaveEntries(ctx, item, entries, videoPath, textTranslator)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProcessResult{
+		SaveLocation: location,
+		Source:       fmt.Sprintf("embedded %s track", language),
+		Report:       &report,
+	}, nil
+}
+
+// processWhisper transcribes the audio track with the configured whisper
+// server and translates the transcription.
+func (h *Handler) processWhisper(ctx context.Context, item *jellyfin.MediaItem, videoPath string) (*ProcessResult, error) {
+	// Transcribing takes long, so don't start on what can't be translated
+	if err := h.checkBudget(translationsCounter, h.Config().DailyTranslationBudget); err != nil {
+		return nil, err
+	}
+	if err := h.approveTranslation(ctx, item, fmt.Sprintf("Transcribe the audio and translate it into %s", lang.TraditionalChinese.DisplayName())); err != nil {
+		return nil, err
+	}
+
+	containerPath := h.containerPath(item, videoPath)
+
+	h.job.Logf("Extracting audio from %s for transcription", containerPath)
+	extractCtx, stopExtract := h.stage(ctx, jobs.StageExtract)
+	audioPath, err := h.Extractor.ExtractAudio(extractCtx, containerPath)
+	stopExtract()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to extract audio: %w", err)
+	}
+	defer os.Remove(audioPath)
+
+	h.job.Logf("Transcribing audio with whisper server at %s", h.Config().WhisperURL)
+	transcribeCtx, stopTranscribe := h.stage(ctx, jobs.StageTranscribe)
+	content, err := h.Whisper.TranscribeToSRT(transcribeCtx, audioPath)
+	stopTranscribe()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to transcribe audio: %w", err)
+	}
+
+	stopParse := h.job.Stage(jobs.StageParse)
+	entries, err := h.Parser.Parse(content)
+	stopParse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transcription: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: transcription produced no cues", errNoSubtitles)
+	}
+	h.job.Logf("Transcribed %d cues", len(entries))
+
+	// Let Google detect the spoken language unless it was pinned
+	sourceLanguage := h.Config().WhisperLanguage
+	if sourceLanguage == "" {
+		sourceLanguage = "auto"
+	}
+
+	location, report, err := h.translateAndSaveEntries(ctx, item, entries, videoPath, h.translatorChain(sourceLanguage))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProcessResult{SaveLocation: location, Source: "whisper transcription", Report: &report}, nil
 }
 
-func (h *Handler) translateAndSaveSubtitle(subtitle *opensubtitles.Subtitle, videoPath string) (string, error) {
-	log.Printf("Downloading English subtitle...")
-	content, err := h.OpenSubtitlesClient.DownloadSubtitle(subtitle)
+// downloadAndSaveSubtitle downloads sub and saves it as item's subtitle for
+// target. When the download is a season archive, the other episodes'
+// files are saved as well.
+func (h *Handler) downloadAndSaveSubtitle(ctx context.Context, item *jellyfin.MediaItem, sub *opensubtitles.Subtitle, target wanted.Target) (string, error) {
+	files, err := h.downloadSubtitleFiles(ctx, item, sub)
+	if err != nil {
+		return "", fmt.Errorf("failed to download subtitle: %w", err)
+	}
+	file, err := fileForItem(files, item)
 	if err != nil {
-		return "", fmt.Errorf("failed to download English subtitle: %w", err)
+		return "", err
 	}
-	log.Printf("Downloaded %d bytes of subtitle content", len(content))
+	content, err := opensubtitles.ToSRT(file.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert subtitle: %w", err)
+	}
+
+	location, err := h.saveDownloadedSubtitle(item, target.String(), content)
+	if err != nil {
+		return "", err
+	}
+	if len(files) > 1 {
+		h.saveSeasonArchive(ctx, item, files, target, seasonArchiveSource)
+	}
+	return location, nil
+}
 
-	log.Printf("Parsing SRT content...")
+// saveDownloadedSubtitle runs the configured clean-up passes and the
+// remembered offset over an SRT subtitle and saves it for the item.
+func (h *Handler) saveDownloadedSubtitle(item *jellyfin.MediaItem, language string, content []byte) (string, error) {
+	videoPath := h.itemVideoPath(item)
+	stopParse := h.job.Stage(jobs.StageParse)
 	entries, err := h.Parser.Parse(content)
+	stopParse()
 	if err != nil {
 		return "", fmt.Errorf("failed to parse subtitle: %w", err)
 	}
-	log.Printf("Parsed %d subtitle entries", len(entries))
 
-	log.Printf("Starting translation of %d entries...", len(entries))
-	translatedEntries, err := h.Parser.TranslateEntries(entries, h.Translator)
+	if h.Config().EnableCleaning || h.Config().StripSDH || h.Config().NormalizeCues {
+		entries = h.prepareEntries(entries)
+		content = []byte(h.Parser.Format(entries))
+	}
+
+	entries, offset := h.applyRememberedOffset(item, videoPath, entries)
+	if offset != 0 {
+		content = []byte(h.Parser.Format(entries))
+	}
+
+	location, err := h.saveSubtitle(item, language, content, len(entries))
+	if err != nil {
+		return "", err
+	}
+	h.recordApplied(item, videoPath, language, offset)
+	return location, nil
+}
+
+// downloadOriginalSubtitle downloads a subtitle through the item's
+// providers, leaving ASS scripts as they are. From a season archive the
+// item's episode is taken.
+func (h *Handler) downloadOriginalSubtitle(ctx context.Context, item *jellyfin.MediaItem, sub *opensubtitles.Subtitle) ([]byte, error) {
+	files, err := h.downloadSubtitleFiles(ctx, item, sub)
+	if err != nil {
+		return nil, err
+	}
+	file, err := fileForItem(files, item)
+	if err != nil {
+		return nil, err
+	}
+	return file.Content, nil
+}
+
+// downloadSubtitleFiles downloads a subtitle through the item's providers
+// and returns every subtitle file in it.
+func (h *Handler) downloadSubtitleFiles(ctx context.Context, item *jellyfin.MediaItem, sub *opensubtitles.Subtitle) ([]opensubtitles.ArchiveFile, error) {
+	content, err := h.fetchSubtitle(ctx, item, sub, (*opensubtitles.Registry).DownloadFile)
+	if err != nil {
+		return nil, err
+	}
+	files, err := opensubtitles.UnpackArchive(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack subtitle file %d: %w", sub.FileID, err)
+	}
+	return files, nil
+}
+
+func (h *Handler) fetchSubtitle(ctx context.Context, item *jellyfin.MediaItem, sub *opensubtitles.Subtitle, download func(*opensubtitles.Registry, context.Context, *opensubtitles.Subtitle) ([]byte, error)) ([]byte, error) {
+	if err := h.chargeBudget(downloadsCounter, h.Config().DailyDownloadBudget); err != nil {
+		return nil, err
+	}
+
+	ctx, stop := h.stage(ctx, jobs.StageDownload)
+	defer stop()
+
+	content, err := download(h.providersFor(item), ctx, sub)
+	if err != nil {
+		if errors.Is(err, opensubtitles.ErrQuotaExceeded) {
+			h.Events.Publish(events.QuotaExhausted, nil)
+		}
+		return nil, err
+	}
+	h.job.Downloaded(len(content))
+	return content, nil
+}
+
+func (h *Handler) translateAndSaveSubtitle(ctx context.Context, item *jellyfin.MediaItem, englishSubtitle *opensubtitles.Subtitle, videoPath string) (string, subtitle.TranslationReport, error) {
+	return h.translateAndSaveSubtitleFrom(ctx, item, englishSubtitle, videoPath, lang.English)
+}
+
+// translateAndSaveSubtitleFrom downloads a subtitle in the source language
+// and translates or converts it to Traditional Chinese. ASS scripts are
+// translated in place when their styles are to be preserved, and converted
+// to SRT otherwise.
+func (h *Handler) translateAndSaveSubtitleFrom(ctx context.Context, item *jellyfin.MediaItem, sourceSubtitle *opensubtitles.Subtitle, videoPath string, source lang.Tag) (string, subtitle.TranslationReport, error) {
+	h.job.Logf("Downloading %s subtitle...", source.DisplayName())
+	content, err := h.downloadOriginalSubtitle(ctx, item, sourceSubtitle)
+	if err != nil {
+		return "", subtitle.TranslationReport{}, fmt.Errorf("failed to download %s subtitle: %w", source.DisplayName(), err)
+	}
+	h.job.Logf("Downloaded %d bytes of subtitle content", len(content))
+
+	parse := h.Parser.Parse
+	if subtitle.IsASS(content) {
+		if h.Config().PreserveASSStyles {
+			return h.translateAndSaveScript(ctx, item, content, videoPath, source)
+		}
+		h.job.Logf("Converting ASS script to SRT...")
+		parse = subtitle.ParseASS
+	} else {
+		h.job.Logf("Parsing SRT content...")
+	}
+	stopParse := h.job.Stage(jobs.StageParse)
+	entries, err := parse(content)
+	stopParse()
 	if err != nil {
-		return "", fmt.Errorf("failed to translate subtitle: %w", err)
+		return "", subtitle.TranslationReport{}, fmt.Errorf("failed to parse subtitle: %w", err)
+	}
+	h.job.Logf("Parsed %d subtitle entries", len(entries))
+
+	entries, offset := h.applyRememberedOffset(item, videoPath, entries)
+	h, textTranslator := h.translatorFrom(source)
+	location, report, err := h.translateAndSaveEntries(ctx, item, entries, videoPath, textTranslator)
+	if err != nil {
+		return "", report, err
+	}
+	h.recordApplied(item, videoPath, lang.TraditionalChinese.String(), offset)
+	return location, report, nil
+}
+
+// translatorFrom returns the translator for subtitles in the source
+// language and the view of the handler to save them with.
+func (h *Handler) translatorFrom(source lang.Tag) (*Handler, subtitle.Translator) {
+	textTranslator := h.translatorChain(source.String())
+	// Simplified Chinese converted to Traditional would show nearly the
+	// same line twice
+	if source.Matches(lang.SimplifiedChinese) {
+		h = h.withBilingual(false)
+	}
+	return h, textTranslator
+}
+
+func (h *Handler) translateAndSaveEntries(ctx context.Context, item *jellyfin.MediaItem, entries []subtitle.SubtitleEntry, videoPath string, textTranslator subtitle.Translator) (string, subtitle.TranslationReport, error) {
+	if err := h.chargeBudget(translationsCounter, h.Config().DailyTranslationBudget); err != nil {
+		return "", subtitle.TranslationReport{}, err
+	}
+	entries = h.prepareEntries(entries)
+
+	canary := h.canaryTranslator(textTranslator)
+	if canary != nil {
+		textTranslator = canary
+	}
+	textTranslator = h.wrapTranslator(item, textTranslator)
+
+	h.job.Logf("Starting translation of %d entries...", len(entries))
+	translateCtx, stopTranslate := h.stage(ctx, jobs.StageTranslate)
+	translatedEntries, report, err := h.translateEntries(translateCtx, item, entries, textTranslator)
+	stopTranslate()
+	if flushErr := h.Usage.Flush(); flushErr != nil {
+		h.job.Logf("Warning: %v", flushErr)
+	}
+	if err != nil {
+		return "", report, fmt.Errorf("failed to translate subtitle: %w", err)
+	}
+	h.job.Logf("Translation completed")
+
+	wrapRules := subtitle.WrapRules{MaxLineChars: h.Config().MaxLineChars, MaxLines: h.Config().MaxLines, MinDuration: subtitle.DefaultLintRules.MinDuration}
+	if h.bilingualOutput() {
+		// The original stays with its translation, so cues are wrapped but
+		// never split
+		wrapRules.MaxLines = 0
+	}
+	translatedEntries, sources := subtitle.Wrap(translatedEntries, wrapRules)
+	originals := entries
+	if len(translatedEntries) != len(entries) {
+		h.job.Logf("Split long cues, %d cues became %d", len(entries), len(translatedEntries))
+		originals = make([]subtitle.SubtitleEntry, len(sources))
+		for i, source := range sources {
+			originals[i] = entries[source]
+		}
+	}
+	if h.bilingualOutput() {
+		translatedEntries = subtitle.Bilingual(entries, translatedEntries, report.FailedIndexes)
 	}
-	log.Printf("Translation completed")
 
-	log.Printf("Formatting translated content...")
+	h.job.Logf("Formatting translated content...")
 	translatedContent := h.Parser.Format(translatedEntries)
-	subtitlePath, saveLocation := h.generateSubtitlePath(videoPath, "zh-Hant")
-	
-	log.Printf("Saving translated subtitle to: %s", subtitlePath)
-	if err := os.WriteFile(subtitlePath, []byte(translatedContent), 0644); err != nil {
-		return "", fmt.Errorf("failed to write subtitle file: %w", err)
+
+	saveLocation, err := h.saveSubtitle(item, lang.TraditionalChinese.String(), []byte(translatedContent), len(entries))
+	if err != nil {
+		return "", report, err
 	}
-	
-	log.Printf("Subtitle saved successfully to %s directory", saveLocation)
+	// Parts of a split cue each keep the whole original
+	h.keepOriginal(videoPath, lang.TraditionalChinese.String(), originals)
+	if canary != nil {
+		h.recordCanary(canary, saveLocation, entries)
+	}
+
+	// From here on the report describes the saved file
+	report.FailedIndexes = failedCues(report.FailedIndexes, translatedEntries, originals)
+	if report.Failed > 0 {
+		h.job.Logf("%s; failed cues: %v", report, report.FailedIndexes)
+		h.job.TranslationFailed(jobs.TranslationReport{
+			Subtitle:   saveLocation,
+			Total:      report.Total,
+			Failed:     report.Failed,
+			FailedCues: report.FailedIndexes,
+			Partial:    report.Partial,
+		})
+	}
+	return saveLocation, report, nil
+}
+
+// failedCues returns the numbers in the saved file of the cues whose
+// translation failed, given the numbers of the cues they came from. Every
+// part of a split cue is listed.
+func failedCues(failed []int, saved, originals []subtitle.SubtitleEntry) []int {
+	if len(failed) == 0 {
+		return nil
+	}
+	isFailed := make(map[int]bool, len(failed))
+	for _, index := range failed {
+		isFailed[index] = true
+	}
+	var cues []int
+	for i, entry := range saved {
+		if isFailed[originals[i].Index] {
+			cues = append(cues, entry.Index)
+		}
+	}
+	return cues
+}
+
+// translatorChain returns the configured chain of translator backends for
+// text in sourceLang, a language tag or "auto", each counting the
+// characters sent to it. Backends that only translate English are left out
+// for other languages.
+func (h *Handler) translatorChain(sourceLang string) *translator.FailoverTranslator {
+	english := sourceLang != "auto" && lang.Parse(sourceLang).Matches(lang.English)
+	chain := &translator.FailoverTranslator{
+		Health: h.TranslatorHealth,
+		OnDown: func(backend string, err error) {
+			h.job.Logf("Translator %s is skipped for %s after failing: %v", backend, h.Config().TranslatorCooldown, err)
+		},
+	}
+	for _, name := range h.translatorNames() {
+		backend, ok := h.backend(name)
+		if !ok {
+			h.job.Logf("Warning: translator %s is not configured, leaving it out of the chain", name)
+			continue
+		}
+		if !english {
+			if backend.From == nil {
+				continue
+			}
+			backend.Translator = backend.From(sourceLang)
+		}
+		backend.Translator = h.countingTranslator(backend.Name, backend.Translator)
+		chain.Backends = append(chain.Backends, backend)
+	}
+	return chain
+}
+
+// translatorNames returns the configured translator chain.
+func (h *Handler) translatorNames() []string {
+	if chain := h.Config().TranslatorChain; len(chain) > 0 {
+		return chain
+	}
+	return []string{config.TranslatorGoogle}
+}
+
+// primaryBackend is the first backend of the translator chain.
+func (h *Handler) primaryBackend() string {
+	return h.translatorNames()[0]
+}
+
+// deeplCost is the price per million characters of the DeepL account the
+// key belongs to, in USD; free accounts' keys end in ":fx".
+func deeplCost(apiKey string) float64 {
+	if strings.HasSuffix(apiKey, ":fx") {
+		return 0
+	}
+	return deeplProCostPerMillionChars
+}
+
+// canaryTranslator splits the cues between the stable translator and the
+// configured canary backend. It returns nil when no canary is configured.
+func (h *Handler) canaryTranslator(stable subtitle.Translator) *translator.CanaryTranslator {
+	cfg := h.Config()
+	if cfg.CanaryBackend == "" || cfg.CanaryPercent == 0 {
+		return nil
+	}
+	primaryBackend := h.primaryBackend()
+	if cfg.CanaryBackend == primaryBackend {
+		h.job.Logf("Warning: canary backend %s is already the stable backend, ignoring it", cfg.CanaryBackend)
+		return nil
+	}
+
+	for _, backend := range h.Backends {
+		if backend.Name != cfg.CanaryBackend {
+			continue
+		}
+		h.job.Logf("Sending %g%% of cues to canary backend %s", cfg.CanaryPercent, backend.Name)
+		backend.Translator = h.countingTranslator(backend.Name, backend.Translator)
+		// The chain counts the characters of its backends itself
+		stableBackend := translator.Backend{Name: primaryBackend, Translator: stable}
+		return translator.NewCanaryTranslator(stableBackend, backend, cfg.CanaryPercent)
+	}
+
+	h.job.Logf("Warning: canary backend %s is not configured, translating with %s only", cfg.CanaryBackend, primaryBackend)
+	return nil
+}
+
+// recordCanary tags the cues of a saved subtitle with the backend that
+// translated them in the job report and logs the split.
+func (h *Handler) recordCanary(canary *translator.CanaryTranslator, saveLocation string, entries []subtitle.SubtitleEntry) {
+	report := jobs.CanaryReport{
+		Subtitle: saveLocation,
+		Backend:  canary.Canary.Name,
+		Percent:  canary.Percent,
+		Cues:     []int{},
+	}
+	for _, entry := range entries {
+		switch backend, _ := canary.ServedBy(entry.Text); backend {
+		case canary.Canary.Name:
+			report.Cues = append(report.Cues, entry.Index)
+		case canary.Stable.Name:
+			report.StableCues++
+		}
+	}
+
+	h.job.Logf("Canary backend %s translated %d cues and %s %d cues of %s", report.Backend, len(report.Cues), canary.Stable.Name, report.StableCues, saveLocation)
+	h.job.Canary(report)
+}
+
+// wrapTranslator layers normalization, the glossary and the translation
+// memory around a backend. Hand-picked translations from the memory take
+// precedence; the glossary protects names inside everything else, and the
+// backend's output is normalized before the names are put back. The
+// glossary and the normalization rules are reloaded for every job so edits
+// apply without a restart.
+func (h *Handler) wrapTranslator(item *jellyfin.MediaItem, textTranslator subtitle.Translator) subtitle.Translator {
+	textTranslator = h.withGlossary(item, h.withNormalization(textTranslator))
+	return &translator.MemoryTranslator{Memory: h.Memory, Series: item.SeriesName, Next: textTranslator}
+}
+
+// withGlossary protects the glossary terms of the item's series from
+// textTranslator, when there are any.
+func (h *Handler) withGlossary(item *jellyfin.MediaItem, textTranslator subtitle.Translator) subtitle.Translator {
+	glossary, err := translator.LoadGlossary(h.Config().GlossaryFile)
+	if err != nil {
+		h.job.Logf("Warning: %v", err)
+	} else if terms := glossary.TermsFor(item.SeriesName); len(terms) > 0 {
+		h.job.Logf("Applying %d glossary terms", len(terms))
+		return translator.NewGlossaryTranslator(terms, textTranslator)
+	}
+	return textTranslator
+}
+
+// withNormalization brings what textTranslator returns in line with the
+// Taiwanese conventions of the normalization rules.
+func (h *Handler) withNormalization(textTranslator subtitle.Translator) subtitle.Translator {
+	rules, err := translator.LoadNormalizationRules(h.Config().NormalizationFile)
+	if err != nil {
+		h.job.Logf("Warning: %v", err)
+		return textTranslator
+	}
+	if rules.Empty() {
+		return textTranslator
+	}
+	if len(rules.Terms) > 0 || len(rules.Units) > 0 {
+		h.job.Logf("Normalizing translations with %d term and %d unit rules", len(rules.Terms), len(rules.Units))
+	}
+	return &translator.NormalizingTranslator{Normalizer: translator.NewNormalizer(rules), Next: textTranslator}
+}
+
+// countingTranslator adds the characters sent to a backend to its monthly
+// usage and to the current job.
+func (h *Handler) countingTranslator(backend string, next translator.TextTranslator) translator.TextTranslator {
+	return &translator.CountingTranslator{
+		Next: next,
+		Count: func(chars int) {
+			h.Usage.Add(translatedCharsCounter(backend), chars)
+			h.job.Translated(chars)
+		},
+	}
+}
+
+// saveSubtitle verifies the formatted content against the number of cues it
+// was produced from and writes it next to the item's video (or to the
+// downloads directory) with the configured BOM and line endings for the
+// language, then puts it next to the item's other versions. ASS scripts are
+// saved as .ass files, everything else as .srt. Nothing is written if the
+// verification fails.
+func (h *Handler) saveSubtitle(item *jellyfin.MediaItem, language string, content []byte, sourceCues int) (string, error) {
+	defer h.job.Stage(jobs.StageSave)()
+
+	videoPath := h.itemVideoPath(item)
+
+	ext := ".srt"
+	if subtitle.IsASS(content) {
+		ext = ".ass"
+	}
+	subtitlePath, saveLocation := h.generateSubtitlePath(item, videoPath, language, ext)
+
+	h.job.Logf("Saving subtitle to: %s", subtitlePath)
+	if err := h.writeSubtitle(subtitlePath, language, content, sourceCues); err != nil {
+		return "", err
+	}
+
+	h.forgetOriginal(videoPath, language)
+	h.saveForVersions(item, language, subtitlePath)
+
+	h.job.Logf("Subtitle saved successfully to %s directory", saveLocation)
 	return saveLocation, nil
 }
 
-func (h *Handler) generateSubtitlePath(videoPath, language string) (string, string) {
+// writeSubtitle verifies content and writes it to subtitlePath in the
+// output style configured for language, with the configured permissions
+// and owner. The file is replaced atomically, after keeping a backup of the
+// previous one if configured.
+func (h *Handler) writeSubtitle(subtitlePath, language string, content []byte, sourceCues int) error {
+	if err := h.checkWriteTarget(subtitlePath); err != nil {
+		return err
+	}
+	if err := h.Parser.VerifyOutput(content, sourceCues, h.Config().MinCueRatio); err != nil {
+		return fmt.Errorf("refusing to save subtitle: %w", err)
+	}
+
+	bom, crlf := h.Config().OutputFor(strings.TrimSuffix(language, wanted.ForcedSuffix))
+	content = subtitle.OutputStyle{BOM: bom, CRLF: crlf}.Apply(content)
+
+	if h.Config().SubtitleBackups {
+		if err := h.backupSubtitle(subtitlePath, content); err != nil {
+			return fmt.Errorf("failed to back up the existing subtitle: %w", err)
+		}
+	}
+	if err := h.writeSubtitleFile(subtitlePath, content); err != nil {
+		return fmt.Errorf("failed to write subtitle file: %w", err)
+	}
+	return nil
+}
+
+// backupSubtitle keeps a copy of the subtitle at path, if there is one and
+// it differs from content, next to it as "{name}.{time}.bak", written and
+// owned like the subtitle itself.
+func (h *Handler) backupSubtitle(path string, content []byte) error {
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(existing, content) {
+		return nil
+	}
+
+	backupPath := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102-150405"))
+	if err := h.writeSubtitleFile(backupPath, existing); err != nil {
+		return err
+	}
+	log.Printf("Kept the previous subtitle as %s", backupPath)
+	return nil
+}
+
+// prepareEntries applies the configured clean-up passes to freshly
+// downloaded cues before they are translated or saved, normalizing them
+// last so the cues the other passes removed are renumbered too.
+func (h *Handler) prepareEntries(entries []subtitle.SubtitleEntry) []subtitle.SubtitleEntry {
+	if h.Config().EnableCleaning {
+		var removed int
+		entries, removed = h.Cleaner.Clean(entries)
+		if removed > 0 {
+			h.job.Logf("Removed %d advertising/spam cues", removed)
+		}
+	}
+
+	if h.Config().StripSDH {
+		var removed int
+		entries, removed = subtitle.StripSDH(entries)
+		h.job.Logf("Stripped SDH annotations (%d cues left empty and removed)", removed)
+	}
+
+	if h.Config().NormalizeCues {
+		var stats subtitle.NormalizeStats
+		entries, stats = subtitle.Normalize(entries)
+		if stats.Changed() {
+			h.job.Logf("Normalized cues: dropped %d duplicate and %d zero-length cues, renumbered %d", stats.Duplicates, stats.ZeroDuration, stats.Renumbered)
+			report := jobs.NormalizeReport{Duplicates: stats.Duplicates, ZeroDuration: stats.ZeroDuration, Renumbered: stats.Renumbered}
+			if stats.Reordered {
+				report.Reordered = 1
+			}
+			h.job.Normalized(report)
+		}
+	}
+
+	return entries
+}
+
+func (h *Handler) generateSubtitlePath(item *jellyfin.MediaItem, videoPath, language, ext string) (string, string) {
 	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
-	fileName := fmt.Sprintf("%s.%s.srt", base, language)
+	fileName := fmt.Sprintf("%s.%s%s", base, language, ext)
 	
 	// If direct save is enabled, try to save to the media directory first
-	if h.Config.EnableDirectSave {
+	if h.Config().EnableDirectSave {
 		// Map the Jellyfin path to container path
-		containerPath := h.Config.MapJellyfinPathToContainer(videoPath)
+		containerPath := h.containerPath(item, videoPath)
 		mediaDir := filepath.Dir(containerPath)
 		mediaSubtitlePath := filepath.Join(mediaDir, fileName)
 		
 		// Check if we can write to the media directory
-		if h.canWriteToDirectory(mediaDir) {
-			log.Printf("Will save subtitle to media directory: %s", mediaSubtitlePath)
+		if !h.directSaveAllowed(mediaDir) {
+			h.job.Logf("Safe mode: %s is not in an approved media root, saving to downloads", mediaDir)
+		} else if h.canWriteToDirectory(mediaDir) {
+			h.job.Logf("Will save subtitle to media directory: %s", mediaSubtitlePath)
 			return mediaSubtitlePath, "media"
 		} else {
-			log.Printf("Cannot write to media directory %s, falling back to downloads", mediaDir)
+			h.job.Logf("Cannot write to media directory %s, falling back to downloads", mediaDir)
 		}
 	}
 	
 	// Fallback: save to downloads directory
-	downloadsDir := h.Config.SubtitleDirectory
+	downloadsDir := h.Config().SubtitleDirectory
 	if err := os.MkdirAll(downloadsDir, 0755); err != nil {
-		log.Printf("Warning: Could not create downloads directory: %v", err)
+		h.job.Logf("Warning: Could not create downloads directory: %v", err)
 	}
 	
 	fallbackPath := filepath.Join(downloadsDir, fileName)
-	log.Printf("Will save subtitle to downloads directory: %s", fallbackPath)
+	h.job.Logf("Will save subtitle to downloads directory: %s", fallbackPath)
 	return fallbackPath, "downloads"
 }
 
@@ -548,20 +1524,16 @@ func (h *Handler) canWriteToDirectory(dirPath string) bool {
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 		// Try to create the directory
 		if err := os.MkdirAll(dirPath, 0755); err != nil {
-			log.Printf("Cannot create directory %s: %v", dirPath, err)
+			h.job.Logf("Cannot create directory %s: %v", dirPath, err)
 			return false
 		}
 	}
 	
-	// Try to create a temporary file to test write permissions
-	tempFile := filepath.Join(dirPath, ".subtitle-hunter-write-test")
-	file, err := os.Create(tempFile)
-	if err != nil {
-		log.Printf("Cannot write to directory %s: %v", dirPath, err)
+	// Try to create a probe file to test write permissions
+	if err := h.TempFiles.ProbeWritable(dirPath); err != nil {
+		h.job.Logf("Cannot write to directory %s: %v", dirPath, err)
 		return false
 	}
-	file.Close()
-	os.Remove(tempFile)
 	
 	return true
 }
@@ -575,13 +1547,3 @@ func extractMovieName(videoPath string) string {
 
 	return strings.TrimSpace(name)
 }
-
-func (h *Handler) StatusHandler(w http.ResponseWriter, r *http.Request) {
-	status := map[string]string{
-		"status": "ok",
-		"service": "subtitle-hunter",
-	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(status)
-}
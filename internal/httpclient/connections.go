@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Options say how the API clients connect.
+type Options struct {
+	// Timeout bounds connecting, the TLS handshake and the wait for the
+	// response headers of each attempt, so a hung provider fails and is
+	// retried instead of blocking. Zero means no limit.
+	Timeout time.Duration
+	// ProxyURL is an http://, https:// or socks5:// proxy requests go
+	// through, except those to the Bypass hosts (and their subdomains).
+	// Empty uses the HTTPS_PROXY and NO_PROXY variables.
+	ProxyURL string
+	Bypass   []string
+	// CAFile is a PEM file of certificate authorities trusted besides the
+	// system's.
+	CAFile string
+}
+
+// Configure makes t send its requests over connections set up as opts say.
+func (t *Transport) Configure(opts Options) error {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.Timeout > 0 {
+		dialer := &net.Dialer{Timeout: opts.Timeout, KeepAlive: 30 * time.Second}
+		base.DialContext = dialer.DialContext
+		base.TLSHandshakeTimeout = opts.Timeout
+		base.ResponseHeaderTimeout = opts.Timeout
+	}
+
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		bypass := opts.Bypass
+		base.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypassed(req.URL.Hostname(), bypass) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		}
+	}
+
+	if opts.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("CA file %s holds no PEM certificates", opts.CAFile)
+		}
+		base.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	t.Next = base
+	return nil
+}
+
+// Direct returns a client that connects like t, through the same proxy and
+// trusting the same authorities, but without rate limits, retries or a
+// limit on how long the response takes to start. It suits slow services
+// that answer once their work is done, such as a whisper server.
+func (t *Transport) Direct() *http.Client {
+	base, ok := t.Next.(*http.Transport)
+	if !ok {
+		return &http.Client{}
+	}
+	direct := base.Clone()
+	direct.ResponseHeaderTimeout = 0
+	return &http.Client{Transport: direct}
+}
+
+func bypassed(host string, bypass []string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range bypass {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
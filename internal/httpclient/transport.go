@@ -0,0 +1,239 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without sending the request while a host's
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Default is the transport shared by the Jellyfin, OpenSubtitles and
+// translator clients, so limits and breaker state are kept per host across
+// all of them.
+var Default = NewTransport()
+
+// Transport is an http.RoundTripper for calls to external APIs. It spaces
+// out requests to hosts with a rate limit, retries 429 and 5xx responses and
+// network errors with jittered exponential backoff (honouring Retry-After),
+// and opens a circuit breaker for a host after repeated failures so a
+// flapping provider fails fast instead of stalling every job.
+type Transport struct {
+	Next http.RoundTripper
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// FailureThreshold consecutive failed requests open the breaker for
+	// Cooldown, after which requests are let through again.
+	FailureThreshold int
+	Cooldown         time.Duration
+	// Observe, when set, is called after every attempt with the host and
+	// the response or error, retries included.
+	Observe func(host string, resp *http.Response, err error)
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	next      time.Time
+	failures  int
+	openUntil time.Time
+}
+
+func NewTransport() *Transport {
+	return &Transport{
+		Next:             http.DefaultTransport,
+		MaxRetries:       3,
+		BaseDelay:        500 * time.Millisecond,
+		MaxDelay:         10 * time.Second,
+		FailureThreshold: 5,
+		Cooldown:         time.Minute,
+		hosts:            make(map[string]*hostState),
+	}
+}
+
+// Client returns an HTTP client that sends its requests through t.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// SetRateLimit limits requests to host (as in URL.Host) to perSecond.
+// Zero removes the limit.
+func (t *Transport) SetRateLimit(host string, perSecond float64) {
+	var interval time.Duration
+	if perSecond > 0 {
+		interval = time.Duration(float64(time.Second) / perSecond)
+	}
+
+	state := t.host(host)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.interval = interval
+}
+
+func (t *Transport) host(host string) *hostState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.hosts[host]
+	if !ok {
+		state = &hostState{}
+		t.hosts[host] = state
+	}
+	return state
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	state := t.host(host)
+
+	if until, open := state.open(time.Now()); open {
+		return nil, fmt.Errorf("%s: %w until %s", host, ErrCircuitOpen, until.Format(time.RFC3339))
+	}
+
+	// A request body can only be sent again if it can be recreated.
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if err := sleep(req, state.reserve(time.Now())); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.Next.RoundTrip(attemptReq)
+		if t.Observe != nil {
+			t.Observe(host, resp, err)
+		}
+		if !retryable(resp, err) {
+			state.succeeded()
+			return resp, nil
+		}
+
+		if req.Context().Err() != nil {
+			return resp, err
+		}
+		if attempt >= t.MaxRetries || !replayable {
+			if resp == nil || resp.StatusCode >= http.StatusInternalServerError {
+				if state.failed(time.Now(), t.FailureThreshold, t.Cooldown) {
+					log.Printf("Circuit breaker for %s opened for %s after repeated failures", host, t.Cooldown)
+				}
+			}
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if err != nil {
+			log.Printf("Request to %s failed (%v), retrying in %s", host, err, delay.Round(time.Millisecond))
+		} else {
+			log.Printf("Request to %s returned %d, retrying in %s", host, resp.StatusCode, delay.Round(time.Millisecond))
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(req, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff returns the delay before retry attempt+1: the server's
+// Retry-After when it sent one, otherwise an exponential delay with jitter,
+// capped at MaxDelay.
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, t.MaxDelay)
+		}
+		if at, err := http.ParseTime(resp.Header.Get("Retry-After")); err == nil {
+			return min(max(time.Until(at), 0), t.MaxDelay)
+		}
+	}
+
+	delay := min(t.BaseDelay<<attempt, t.MaxDelay)
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func sleep(req *http.Request, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// reserve books the next request slot for the host and returns how long to
+// wait for it.
+func (s *hostState) reserve(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.interval == 0 {
+		return 0
+	}
+	if s.next.Before(now) {
+		s.next = now
+	}
+	wait := s.next.Sub(now)
+	s.next = s.next.Add(s.interval)
+	return wait
+}
+
+func (s *hostState) open(now time.Time) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.openUntil, now.Before(s.openUntil)
+}
+
+func (s *hostState) succeeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = 0
+}
+
+// failed counts a failed request and reports whether it opened the
+// breaker. The count is only reset by a success, so after the cooldown a
+// single failure opens the breaker again.
+func (s *hostState) failed(now time.Time, threshold int, cooldown time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures++
+	if threshold <= 0 || s.failures < threshold {
+		return false
+	}
+	s.openUntil = now.Add(cooldown)
+	return true
+}
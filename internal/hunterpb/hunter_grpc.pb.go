@@ -0,0 +1,235 @@
+// The gRPC API of Subtitle Hunter, served on GRPC_PORT next to the web
+// interface. Regenerate the Go code after changing this file with
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	  --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//	  internal/hunterpb/hunter.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: internal/hunterpb/hunter.proto
+
+package hunterpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SubtitleHunter_Search_FullMethodName            = "/subtitlehunter.v1.SubtitleHunter/Search"
+	SubtitleHunter_Process_FullMethodName           = "/subtitlehunter.v1.SubtitleHunter/Process"
+	SubtitleHunter_StreamJobProgress_FullMethodName = "/subtitlehunter.v1.SubtitleHunter/StreamJobProgress"
+)
+
+// SubtitleHunterClient is the client API for SubtitleHunter service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SubtitleHunter searches for, downloads and translates the subtitles of
+// Jellyfin items. When ADMIN_PASSWORD is set every call needs the
+// "authorization" metadata "Basic base64(admin:password)".
+type SubtitleHunterClient interface {
+	// Search runs a manual OpenSubtitles search for an item, like the
+	// item's search page.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// Process hunts an item's missing subtitles like POST /process/{id},
+	// streaming the job's progress until it finishes. A hunt already running
+	// for the item is followed instead of starting another.
+	Process(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobProgress], error)
+	// StreamJobProgress follows a job started elsewhere, by its ID or by the
+	// item it works on, until it finishes. A finished job answers with its
+	// whole log and outcome at once.
+	StreamJobProgress(ctx context.Context, in *StreamJobProgressRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobProgress], error)
+}
+
+type subtitleHunterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSubtitleHunterClient(cc grpc.ClientConnInterface) SubtitleHunterClient {
+	return &subtitleHunterClient{cc}
+}
+
+func (c *subtitleHunterClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, SubtitleHunter_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *subtitleHunterClient) Process(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobProgress], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SubtitleHunter_ServiceDesc.Streams[0], SubtitleHunter_Process_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProcessRequest, JobProgress]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SubtitleHunter_ProcessClient = grpc.ServerStreamingClient[JobProgress]
+
+func (c *subtitleHunterClient) StreamJobProgress(ctx context.Context, in *StreamJobProgressRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobProgress], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SubtitleHunter_ServiceDesc.Streams[1], SubtitleHunter_StreamJobProgress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamJobProgressRequest, JobProgress]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SubtitleHunter_StreamJobProgressClient = grpc.ServerStreamingClient[JobProgress]
+
+// SubtitleHunterServer is the server API for SubtitleHunter service.
+// All implementations must embed UnimplementedSubtitleHunterServer
+// for forward compatibility.
+//
+// SubtitleHunter searches for, downloads and translates the subtitles of
+// Jellyfin items. When ADMIN_PASSWORD is set every call needs the
+// "authorization" metadata "Basic base64(admin:password)".
+type SubtitleHunterServer interface {
+	// Search runs a manual OpenSubtitles search for an item, like the
+	// item's search page.
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// Process hunts an item's missing subtitles like POST /process/{id},
+	// streaming the job's progress until it finishes. A hunt already running
+	// for the item is followed instead of starting another.
+	Process(*ProcessRequest, grpc.ServerStreamingServer[JobProgress]) error
+	// StreamJobProgress follows a job started elsewhere, by its ID or by the
+	// item it works on, until it finishes. A finished job answers with its
+	// whole log and outcome at once.
+	StreamJobProgress(*StreamJobProgressRequest, grpc.ServerStreamingServer[JobProgress]) error
+	mustEmbedUnimplementedSubtitleHunterServer()
+}
+
+// UnimplementedSubtitleHunterServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSubtitleHunterServer struct{}
+
+func (UnimplementedSubtitleHunterServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedSubtitleHunterServer) Process(*ProcessRequest, grpc.ServerStreamingServer[JobProgress]) error {
+	return status.Errorf(codes.Unimplemented, "method Process not implemented")
+}
+func (UnimplementedSubtitleHunterServer) StreamJobProgress(*StreamJobProgressRequest, grpc.ServerStreamingServer[JobProgress]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamJobProgress not implemented")
+}
+func (UnimplementedSubtitleHunterServer) mustEmbedUnimplementedSubtitleHunterServer() {}
+func (UnimplementedSubtitleHunterServer) testEmbeddedByValue()                        {}
+
+// UnsafeSubtitleHunterServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SubtitleHunterServer will
+// result in compilation errors.
+type UnsafeSubtitleHunterServer interface {
+	mustEmbedUnimplementedSubtitleHunterServer()
+}
+
+func RegisterSubtitleHunterServer(s grpc.ServiceRegistrar, srv SubtitleHunterServer) {
+	// If the following call pancis, it indicates UnimplementedSubtitleHunterServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SubtitleHunter_ServiceDesc, srv)
+}
+
+func _SubtitleHunter_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubtitleHunterServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SubtitleHunter_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubtitleHunterServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubtitleHunter_Process_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProcessRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SubtitleHunterServer).Process(m, &grpc.GenericServerStream[ProcessRequest, JobProgress]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SubtitleHunter_ProcessServer = grpc.ServerStreamingServer[JobProgress]
+
+func _SubtitleHunter_StreamJobProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamJobProgressRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SubtitleHunterServer).StreamJobProgress(m, &grpc.GenericServerStream[StreamJobProgressRequest, JobProgress]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SubtitleHunter_StreamJobProgressServer = grpc.ServerStreamingServer[JobProgress]
+
+// SubtitleHunter_ServiceDesc is the grpc.ServiceDesc for SubtitleHunter service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SubtitleHunter_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "subtitlehunter.v1.SubtitleHunter",
+	HandlerType: (*SubtitleHunterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _SubtitleHunter_Search_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Process",
+			Handler:       _SubtitleHunter_Process_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamJobProgress",
+			Handler:       _SubtitleHunter_StreamJobProgress_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "internal/hunterpb/hunter.proto",
+}
@@ -0,0 +1,83 @@
+// Package i18n translates the web interface. Messages are looked up by
+// their English text, so templates stay readable and anything a catalog
+// doesn't translate is shown in English.
+package i18n
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"subtitle-hunter/internal/lang"
+)
+
+// Catalog maps English messages to their translation. Messages may contain
+// fmt verbs and trusted markup such as <code>.
+type Catalog map[string]string
+
+// ParseCatalog reads a catalog file: a YAML mapping of English messages to
+// translations.
+func ParseCatalog(data []byte) (Catalog, error) {
+	var catalog Catalog
+	if err := yaml.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog: %w", err)
+	}
+	return catalog, nil
+}
+
+// Text returns the translation of message with args formatted into it.
+func (c Catalog) Text(message string, args ...interface{}) string {
+	if translated := c[message]; translated != "" {
+		message = translated
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// HTML is Text for templates. The message itself may contain markup, so
+// only the args are escaped.
+func (c Catalog) HTML(message string, args ...interface{}) template.HTML {
+	escaped := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case int, int64, uint, uint64, float64, bool:
+			// Kept as they are for %d and friends; nothing to escape
+			escaped[i] = arg
+		default:
+			escaped[i] = template.HTMLEscapeString(fmt.Sprint(arg))
+		}
+	}
+	return template.HTML(c.Text(message, escaped...))
+}
+
+// Negotiate picks the locale for an Accept-Language header from available,
+// in the order of the header's preferences. It returns English when none of
+// them is available.
+func Negotiate(acceptLanguage string, available []lang.Tag) lang.Tag {
+	best, bestQ := lang.English, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if _, err := fmt.Sscanf(value, "%g", &q); err != nil {
+				continue
+			}
+		}
+		if q <= bestQ || tag == "" || tag == "*" {
+			continue
+		}
+
+		requested := lang.Parse(tag)
+		for _, locale := range available {
+			if requested.Matches(locale) {
+				best, bestQ = locale, q
+				break
+			}
+		}
+	}
+	return best
+}
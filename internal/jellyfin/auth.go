@@ -0,0 +1,128 @@
+package jellyfin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// authorization identifies subtitle-hunter as a Jellyfin client when it
+// signs in. The device ID stays the same, so Jellyfin replaces the previous
+// session on every sign-in instead of listing a new device each time.
+const authorization = `MediaBrowser Client="subtitle-hunter", Device="subtitle-hunter", DeviceId="subtitle-hunter", Version="1.0"`
+
+type authenticateRequest struct {
+	Username string `json:"Username"`
+	Pw       string `json:"Pw"`
+}
+
+type authenticateResponse struct {
+	AccessToken string `json:"AccessToken"`
+	User        User   `json:"User"`
+}
+
+// do sends req with the API key, or with a session token when the client
+// signs in. A token Jellyfin no longer accepts, because the session
+// expired or was signed out, is replaced by signing in again, and the
+// request is sent once more; the client's requests have no body, so they
+// can be.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Username == "" {
+		req.Header.Set("X-Emby-Token", c.APIKey)
+		return c.client.Do(req)
+	}
+
+	token, err := c.login(req.Context(), "")
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Emby-Token", token)
+	resp, err := c.client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	token, err = c.login(req.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	retry := req.Clone(req.Context())
+	retry.Header.Set("X-Emby-Token", token)
+	return c.client.Do(retry)
+}
+
+// login returns the session token, signing in with the username and
+// password when there is none yet or the one held is stale. Concurrent
+// requests whose token expired sign in once between them.
+func (c *Client) login(ctx context.Context, stale string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.token != stale {
+		return c.token, nil
+	}
+
+	jsonBody, err := json.Marshal(authenticateRequest{Username: c.Username, Pw: c.Password})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sign-in request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/Users/AuthenticateByName", bytes.NewReader(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach Jellyfin: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", fmt.Errorf("%w: username or password rejected (status %d)", ErrRejected, resp.StatusCode)
+	default:
+		return "", fmt.Errorf("sign-in failed (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var authResp authenticateResponse
+	if err := json.Unmarshal(body, &authResp); err != nil {
+		return "", fmt.Errorf("failed to parse sign-in response: %w", err)
+	}
+	if authResp.AccessToken == "" {
+		return "", fmt.Errorf("sign-in response has no access token")
+	}
+
+	c.token = authResp.AccessToken
+	c.userID = authResp.User.ID
+	return c.token, nil
+}
+
+// user returns the ID of the user whose library is read: UserID, or the
+// signed-in user when it is not set.
+func (c *Client) user(ctx context.Context) (string, error) {
+	if c.UserID != "" || c.Username == "" {
+		return c.UserID, nil
+	}
+	if _, err := c.login(ctx, ""); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.userID, nil
+}
@@ -0,0 +1,185 @@
+package jellyfin
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// LibraryCache keeps the last full library listing, which takes tens of
+// seconds to fetch on a large library, so pages that show the whole library
+// don't fetch it on every load. Items are refetched one by one after they
+// were invalidated, and the whole library once the listing is older than the
+// TTL or a rescan is asked for. Concurrent callers share one scan.
+type LibraryCache struct {
+	client *Client
+
+	mu        sync.Mutex
+	ttl       time.Duration
+	items     []MediaItem
+	scannedAt time.Time
+	// stale maps invalidated item IDs to when they were invalidated.
+	stale map[string]time.Time
+	scan  *libraryScan
+}
+
+type libraryScan struct {
+	done  chan struct{}
+	items []MediaItem
+	err   error
+}
+
+// NewLibraryCache returns a cache that keeps the listing for ttl. A zero
+// TTL fetches the library on every call.
+func NewLibraryCache(client *Client, ttl time.Duration) *LibraryCache {
+	return &LibraryCache{
+		client: client,
+		ttl:    ttl,
+		stale:  make(map[string]time.Time),
+	}
+}
+
+func (c *LibraryCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// ScannedAt returns when the cached listing was fetched, or the zero time
+// if the library hasn't been scanned yet.
+func (c *LibraryCache) ScannedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.scannedAt
+}
+
+// Invalidate marks an item as changed, for example after a subtitle was
+// saved for it, so the next call refetches it.
+func (c *LibraryCache) Invalidate(itemID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stale[itemID] = time.Now()
+}
+
+// Items returns every movie and episode in the library, like
+// Client.GetMediaItems, from the cache when the listing is recent enough.
+func (c *LibraryCache) Items(ctx context.Context) ([]MediaItem, error) {
+	return c.load(ctx, false)
+}
+
+// Rescan fetches the whole library again, or waits for a scan that is
+// already running.
+func (c *LibraryCache) Rescan(ctx context.Context) ([]MediaItem, error) {
+	return c.load(ctx, true)
+}
+
+func (c *LibraryCache) load(ctx context.Context, rescan bool) ([]MediaItem, error) {
+	for {
+		c.mu.Lock()
+		if !rescan && c.fresh(time.Now()) {
+			stale := make([]string, 0, len(c.stale))
+			for id := range c.stale {
+				stale = append(stale, id)
+			}
+			c.mu.Unlock()
+
+			c.refetch(ctx, stale)
+			return c.cached(), nil
+		}
+
+		scan := c.scan
+		if scan == nil {
+			scan = &libraryScan{done: make(chan struct{})}
+			c.scan = scan
+			c.mu.Unlock()
+			c.run(ctx, scan)
+		} else {
+			c.mu.Unlock()
+		}
+
+		select {
+		case <-scan.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		// The caller running the scan gave up; scan again unless this
+		// caller has too.
+		if isContextError(scan.err) && ctx.Err() == nil {
+			continue
+		}
+		if scan.err != nil {
+			return nil, scan.err
+		}
+		return append([]MediaItem(nil), scan.items...), nil
+	}
+}
+
+// fresh reports whether the cached listing can still be used. It must be
+// called with c.mu held.
+func (c *LibraryCache) fresh(now time.Time) bool {
+	return !c.scannedAt.IsZero() && now.Before(c.scannedAt.Add(c.ttl))
+}
+
+func (c *LibraryCache) cached() []MediaItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]MediaItem(nil), c.items...)
+}
+
+func (c *LibraryCache) run(ctx context.Context, scan *libraryScan) {
+	started := time.Now()
+	items, err := c.client.GetMediaItems(ctx)
+
+	c.mu.Lock()
+	if err == nil {
+		c.items = items
+		c.scannedAt = time.Now()
+		// Items invalidated while the scan ran may have been listed before
+		// they changed
+		for id, at := range c.stale {
+			if at.Before(started) {
+				delete(c.stale, id)
+			}
+		}
+	}
+	c.scan = nil
+	scan.items, scan.err = items, err
+	c.mu.Unlock()
+	close(scan.done)
+}
+
+// refetch replaces invalidated items in the listing with their current
+// state. Items that can't be fetched stay invalidated and are tried again
+// on the next call.
+func (c *LibraryCache) refetch(ctx context.Context, ids []string) {
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		fetched := time.Now()
+		item, err := c.client.GetItem(ctx, id)
+		if err != nil {
+			log.Printf("Warning: failed to refresh cached library item %s: %v", id, err)
+			continue
+		}
+
+		c.mu.Lock()
+		for i := range c.items {
+			if c.items[i].ID == id {
+				c.items[i] = *item
+				break
+			}
+		}
+		if c.stale[id].Before(fetched) {
+			delete(c.stale, id)
+		}
+		c.mu.Unlock()
+	}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
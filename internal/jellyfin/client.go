@@ -1,12 +1,20 @@
 package jellyfin
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"subtitle-hunter/internal/httpclient"
+	"subtitle-hunter/internal/lang"
 )
 
 type Client struct {
@@ -14,6 +22,15 @@ type Client struct {
 	APIKey  string
 	client  *http.Client
 	UserID  string
+	// Username and Password, when set, sign in to Jellyfin for a session
+	// token that is used instead of the API key. UserID then defaults to
+	// the signed-in user.
+	Username string
+	Password string
+
+	mu     sync.Mutex
+	token  string
+	userID string
 }
 
 type MediaItem struct {
@@ -22,27 +39,94 @@ type MediaItem struct {
 	Type         string `json:"Type"`
 	Path         string `json:"Path"`
 	SeriesName   string `json:"SeriesName"`
+	SeriesID     string `json:"SeriesId"`
+	OriginalTitle string `json:"OriginalTitle"`
+	Genres       []string `json:"Genres"`
+	ProviderIds  map[string]string `json:"ProviderIds"`
 	SeasonName   string `json:"SeasonName"`
 	IndexNumber  int    `json:"IndexNumber"`
 	ParentIndexNumber int `json:"ParentIndexNumber"`
 	ProductionYear int   `json:"ProductionYear"`
-	MediaSources []struct {
-		Path string `json:"Path"`
-	} `json:"MediaSources"`
-	MediaStreams []struct {
-		Type         string `json:"Type"`
-		Language     string `json:"Language"`
-		Codec        string `json:"Codec"`
-		IsExternal   bool   `json:"IsExternal"`
-		DisplayTitle string `json:"DisplayTitle"`
-		Title        string `json:"Title"`
-		Index        int    `json:"Index"`
-		IsDefault    bool   `json:"IsDefault"`
-		IsForced     bool   `json:"IsForced"`
-		Path         string `json:"Path"`
-	} `json:"MediaStreams"`
+	DateCreated    string `json:"DateCreated"`
+	PremiereDate   string `json:"PremiereDate"`
+	MediaSources []MediaSource `json:"MediaSources"`
+	MediaStreams []MediaStream `json:"MediaStreams"`
+}
+
+// MediaSource is one version of an item, such as its 1080p or its 4K file.
+type MediaSource struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+	Path string `json:"Path"`
+	// Size is the file's size in bytes, 0 when Jellyfin doesn't say.
+	Size int64 `json:"Size"`
+	// MediaStreams are the streams of this version's file, including the
+	// external subtitles Jellyfin found next to it.
+	MediaStreams []MediaStream `json:"MediaStreams"`
+}
+
+type MediaStream struct {
+	Type         string `json:"Type"`
+	Language     string `json:"Language"`
+	Codec        string `json:"Codec"`
+	IsExternal   bool   `json:"IsExternal"`
+	DisplayTitle string `json:"DisplayTitle"`
+	Title        string `json:"Title"`
+	Index        int    `json:"Index"`
+	IsDefault    bool   `json:"IsDefault"`
+	IsForced     bool   `json:"IsForced"`
+	Path         string `json:"Path"`
+	// Local is set on the subtitles of items made up from a file path
+	// rather than read from Jellyfin, whose Path is as this service sees it.
+	Local        bool   `json:"-"`
+}
+
+// VideoFile returns the path and size of the item's video file, from its
+// first media source when Jellyfin lists one. The size is 0 when unknown.
+func (item MediaItem) VideoFile() (string, int64) {
+	if len(item.MediaSources) > 0 {
+		return item.MediaSources[0].Path, item.MediaSources[0].Size
+	}
+	return item.Path, 0
+}
+
+// AddedAt returns when the item was added to the library, or the zero time
+// if Jellyfin did not report it.
+func (item MediaItem) AddedAt() time.Time {
+	return parseJellyfinDate(item.DateCreated)
 }
 
+// PremieredAt returns when the item first aired or was released.
+func (item MediaItem) PremieredAt() time.Time {
+	return parseJellyfinDate(item.PremiereDate)
+}
+
+// WithinWindow reports whether the item was added or premiered within the
+// given window before now.
+func (item MediaItem) WithinWindow(window time.Duration, now time.Time) bool {
+	cutoff := now.Add(-window)
+	return item.AddedAt().After(cutoff) || item.PremieredAt().After(cutoff)
+}
+
+func parseJellyfinDate(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.9999999"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+// ErrNoImage is returned by GetPrimaryImage for items without artwork.
+var ErrNoImage = errors.New("item has no image")
+
+// ErrRejected is returned by Ping and GetUsers when Jellyfin doesn't
+// accept the API key, the username and password or the user ID.
+var ErrRejected = errors.New("rejected by Jellyfin")
+
 type ItemsResponse struct {
 	Items []MediaItem `json:"Items"`
 }
@@ -52,21 +136,67 @@ func NewClient(baseURL, apiKey, userID string) *Client {
 		BaseURL: strings.TrimSuffix(baseURL, "/"),
 		APIKey:  apiKey,
 		UserID:  userID,
-		client:  &http.Client{},
+		client:  httpclient.Default.Client(),
 	}
 }
 
-func (c *Client) GetMediaWithoutChineseSubtitles() ([]MediaItem, error) {
-	url := fmt.Sprintf("%s/Users/%s/Items?Recursive=true&IncludeItemTypes=Movie,Episode&Fields=Path,MediaSources,MediaStreams", c.BaseURL, c.UserID)
+func (c *Client) GetMediaWithoutChineseSubtitles(ctx context.Context) ([]MediaItem, error) {
+	items, err := c.GetMediaItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.WithoutChineseSubtitles(items), nil
+}
+
+// GetMediaWithoutChineseSubtitlesSince is GetMediaWithoutChineseSubtitles
+// limited to items Jellyfin saved (added, rescanned or edited) since the
+// given time, so a periodic scan doesn't re-read the whole library.
+func (c *Client) GetMediaWithoutChineseSubtitlesSince(ctx context.Context, since time.Time) ([]MediaItem, error) {
+	items, err := c.GetMediaItemsSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	return c.WithoutChineseSubtitles(items), nil
+}
+
+// WithoutChineseSubtitles keeps the items that have no Chinese subtitle.
+func (c *Client) WithoutChineseSubtitles(items []MediaItem) []MediaItem {
+	var filtered []MediaItem
+	for _, item := range items {
+		if !c.hasChineseSubtitle(item) {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered
+}
+
+// GetMediaItems returns every movie and episode in the user's library.
+func (c *Client) GetMediaItems(ctx context.Context) ([]MediaItem, error) {
+	return c.getMediaItems(ctx, "")
+}
+
+// GetMediaItemsSince returns the movies and episodes Jellyfin saved (added,
+// rescanned or edited) since the given time.
+func (c *Client) GetMediaItemsSince(ctx context.Context, since time.Time) ([]MediaItem, error) {
+	return c.getMediaItems(ctx, "&MinDateLastSaved="+url.QueryEscape(since.UTC().Format(time.RFC3339)))
+}
+
+// getMediaItems lists movies and episodes, with extra query parameters
+// appended to the request.
+func (c *Client) getMediaItems(ctx context.Context, filters string) ([]MediaItem, error) {
+	userID, err := c.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/Users/%s/Items?Recursive=true&IncludeItemTypes=Movie,Episode&Fields=Path,MediaSources,MediaStreams,DateCreated,PremiereDate,ProviderIds%s", c.BaseURL, userID, filters)
 	
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	
-	req.Header.Set("X-Emby-Token", c.APIKey)
-	
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch items: %w", err)
 	}
@@ -86,46 +216,114 @@ func (c *Client) GetMediaWithoutChineseSubtitles() ([]MediaItem, error) {
 		return nil, fmt.Errorf("failed to parse response (response: %s): %w", string(body), err)
 	}
 
-	var filtered []MediaItem
-	for _, item := range itemsResp.Items {
-		if !c.hasChineseSubtitle(item) {
-			filtered = append(filtered, item)
+	return itemsResp.Items, nil
+}
+
+// GetEpisodes returns the episodes of a series.
+func (c *Client) GetEpisodes(ctx context.Context, seriesID string) ([]MediaItem, error) {
+	userID, err := c.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/Shows/%s/Episodes?UserId=%s", c.BaseURL, seriesID, userID)
+	
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch episodes: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var itemsResp ItemsResponse
+	if err := json.Unmarshal(body, &itemsResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response (response: %s): %w", string(body), err)
+	}
+
+	return itemsResp.Items, nil
+}
+
+// animeProviders are metadata providers that only list anime, so a series
+// with an ID from one of them is anime.
+var animeProviders = []string{"AniDB", "AniList", "AniSearch", "Kitsu", "MyAnimeList"}
+
+// IsAnime reports whether a series is anime, going by its genres and by
+// the metadata providers that know it.
+func (item MediaItem) IsAnime() bool {
+	for _, genre := range item.Genres {
+		if strings.EqualFold(genre, "Anime") {
+			return true
+		}
+	}
+	for _, provider := range animeProviders {
+		if item.ProviderIds[provider] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// AudioLanguage returns the language of the item's default audio stream, or
+// of its first audio stream with a language when none is marked default.
+// It is the zero tag when Jellyfin doesn't know the language.
+func (item MediaItem) AudioLanguage() lang.Tag {
+	var first lang.Tag
+	for _, stream := range item.MediaStreams {
+		if stream.Type != "Audio" {
+			continue
+		}
+		tag := lang.Normalize(stream.Language)
+		if stream.IsDefault && !tag.IsZero() {
+			return tag
+		}
+		if first.IsZero() {
+			first = tag
+		}
+	}
+	return first
+}
+
+// AbsoluteEpisodeNumber numbers an episode continuously from the start of
+// the series, the way anime releases usually are: the highest episode number
+// of every earlier regular season plus the episode's own number. Specials
+// (season 0) are not counted. It returns 0 for specials.
+func AbsoluteEpisodeNumber(episodes []MediaItem, season, episode int) int {
+	if season <= 0 || episode <= 0 {
+		return 0
+	}
+
+	lengths := make(map[int]int)
+	for _, item := range episodes {
+		if item.ParentIndexNumber > 0 && item.IndexNumber > lengths[item.ParentIndexNumber] {
+			lengths[item.ParentIndexNumber] = item.IndexNumber
 		}
 	}
 
-	return filtered, nil
+	absolute := episode
+	for s := 1; s < season; s++ {
+		absolute += lengths[s]
+	}
+	return absolute
 }
 
 func (c *Client) hasChineseSubtitle(item MediaItem) bool {
-	// Check MediaStreams for Chinese subtitles
-	chineseLanguageCodes := []string{"zh-TW", "zh-Hant", "chi"}
+	// Check MediaStreams for Chinese subtitles. A forced track only covers
+	// foreign dialogue, so it doesn't count.
 	for _, stream := range item.MediaStreams {
-		if stream.Type == "Subtitle" {
-			// Check exact matches (case sensitive)
-			for _, code := range chineseLanguageCodes {
-				if stream.Language == code {
-					return true
-				}
-			}
-			
-			// Check case-insensitive matches to catch potential case issues
-			streamLangLower := strings.ToLower(stream.Language)
-			for _, code := range chineseLanguageCodes {
-				if streamLangLower == strings.ToLower(code) {
-					return true
-				}
-			}
-			
-			// Check DisplayTitle and Title fields for Chinese indicators
-			displayTitleLower := strings.ToLower(stream.DisplayTitle)
-			titleLower := strings.ToLower(stream.Title)
-			
-			chineseIndicators := []string{"traditional chinese", "繁體中文", "繁体中文", "zh-hant", "zh-tw", "chinese (traditional)"}
-			for _, indicator := range chineseIndicators {
-				if strings.Contains(displayTitleLower, indicator) || strings.Contains(titleLower, indicator) {
-					return true
-				}
-			}
+		if !stream.IsForced && StreamMatches(stream, lang.TraditionalChinese) {
+			return true
 		}
 	}
 
@@ -135,14 +333,8 @@ func (c *Client) hasChineseSubtitle(item MediaItem) bool {
 		dir := filepath.Dir(videoPath)
 		base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
 		
-		chineseSubtitles := []string{
-			filepath.Join(dir, base+".zh-Hant.srt"),
-			filepath.Join(dir, base+".zh-TW.srt"),
-			filepath.Join(dir, base+".chi.srt"),
-		}
-		
-		for _, subPath := range chineseSubtitles {
-			if c.fileExists(subPath) {
+		for _, code := range lang.TraditionalChinese.Aliases() {
+			if c.fileExists(filepath.Join(dir, base+"."+code+".srt")) {
 				return true
 			}
 		}
@@ -151,21 +343,81 @@ func (c *Client) hasChineseSubtitle(item MediaItem) bool {
 	return false
 }
 
+// Title fragments that identify a subtitle stream's language when its
+// language code is missing or generic.
+var languageIndicators = map[lang.Tag][]string{
+	lang.TraditionalChinese: {"traditional chinese", "繁體中文", "繁体中文", "zh-hant", "zh-tw"},
+}
+
+// StreamMatches reports whether a subtitle stream carries the given
+// language, judging by its language code or, failing that, its titles. A
+// Chinese stream whose script is known (see StreamScript) only matches that
+// script, whatever its language code claims.
+func StreamMatches(stream MediaStream, tag lang.Tag) bool {
+	if stream.Type != "Subtitle" {
+		return false
+	}
+
+	tag = tag.Normalize()
+	if tag.Language == "zh" && tag.Script != "" {
+		if script := StreamScript(stream); script != "" {
+			return script == tag.Script
+		}
+	}
+
+	// Language codes such as "zh-TW", "zh-Hant", "chi" or "zho"
+	if lang.Normalize(stream.Language).Matches(tag) {
+		return true
+	}
+
+	// Check DisplayTitle and Title fields for language indicators
+	displayTitleLower := strings.ToLower(stream.DisplayTitle)
+	titleLower := strings.ToLower(stream.Title)
+
+	indicators := append([]string{strings.ToLower(tag.DisplayName())}, languageIndicators[tag]...)
+	for _, indicator := range indicators {
+		if strings.Contains(displayTitleLower, indicator) || strings.Contains(titleLower, indicator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SubtitleStatus reports whether the item already has an embedded and/or an
+// external subtitle stream in the given language. Forced streams are only
+// counted when forced is set, and full ones only when it isn't.
+func (item MediaItem) SubtitleStatus(tag lang.Tag, forced bool) (embedded, external bool) {
+	return subtitleStatus(item.MediaStreams, tag, forced)
+}
+
+func subtitleStatus(streams []MediaStream, tag lang.Tag, forced bool) (embedded, external bool) {
+	for _, stream := range streams {
+		if stream.IsForced != forced || !StreamMatches(stream, tag) {
+			continue
+		}
+		if stream.IsExternal {
+			external = true
+		} else {
+			embedded = true
+		}
+	}
+	return embedded, external
+}
+
 func (c *Client) fileExists(path string) bool {
 	return false
 }
 
-func (c *Client) RefreshMetadata(itemID string) error {
+func (c *Client) RefreshMetadata(ctx context.Context, itemID string) error {
 	url := fmt.Sprintf("%s/Items/%s/Refresh?metadataRefreshMode=FullRefresh&replaceAllMetadata=false", c.BaseURL, itemID)
 	
-	req, err := http.NewRequest("POST", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 	
-	req.Header.Set("X-Emby-Token", c.APIKey)
-
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to refresh metadata: %w", err)
 	}
@@ -178,20 +430,96 @@ func (c *Client) RefreshMetadata(itemID string) error {
 	return nil
 }
 
+// Ping checks that Jellyfin is reachable and accepts the API key, or the
+// username and password, and the user ID.
+func (c *Client) Ping(ctx context.Context) error {
+	userID, err := c.user(ctx)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/Users/%s", c.BaseURL, userID)
+	
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Jellyfin: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: API key rejected (status %d)", ErrRejected, resp.StatusCode)
+	case http.StatusNotFound, http.StatusBadRequest:
+		return fmt.Errorf("%w: user %s not found (status %d)", ErrRejected, userID, resp.StatusCode)
+	default:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// User is a Jellyfin user account.
+type User struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
+// GetUsers lists the server's users, which also checks that it is
+// reachable and accepts the API key before a user is picked.
+func (c *Client) GetUsers(ctx context.Context) ([]User, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/Users", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Jellyfin: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
 
-func (c *Client) GetItem(itemID string) (*MediaItem, error) {
-	url := fmt.Sprintf("%s/Users/%s/Items/%s", c.BaseURL, c.UserID, itemID)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("%w: API key rejected (status %d)", ErrRejected, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var users []User
+	if err := json.Unmarshal(body, &users); err != nil {
+		return nil, fmt.Errorf("not a Jellyfin server, or an unexpected answer: %w", err)
+	}
+	return users, nil
+}
+
+func (c *Client) GetItem(ctx context.Context, itemID string) (*MediaItem, error) {
+	userID, err := c.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/Users/%s/Items/%s", c.BaseURL, userID, itemID)
 	
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	
-	req.Header.Set("X-Emby-Token", c.APIKey)
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 	
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch item: %w", err)
 	}
@@ -214,8 +542,81 @@ func (c *Client) GetItem(itemID string) (*MediaItem, error) {
 	return &item, nil
 }
 
-func (c *Client) GetVideoPath(itemID string) (string, error) {
-	item, err := c.GetItem(itemID)
+// GetPrimaryImage returns an item's poster (or a series' poster, for a
+// series ID) scaled to at most maxHeight pixels, and its content type.
+func (c *Client) GetPrimaryImage(ctx context.Context, itemID string, maxHeight int) ([]byte, string, error) {
+	url := fmt.Sprintf("%s/Items/%s/Images/Primary?maxHeight=%d&quality=90", c.BaseURL, itemID, maxHeight)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", ErrNoImage
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// LibraryFolder is one of Jellyfin's libraries and the directories it
+// scans, as Jellyfin sees them.
+type LibraryFolder struct {
+	Name           string   `json:"Name"`
+	CollectionType string   `json:"CollectionType"`
+	Locations      []string `json:"Locations"`
+}
+
+// GetLibraryFolders lists the server's libraries with their directories.
+func (c *Client) GetLibraryFolders(ctx context.Context) ([]LibraryFolder, error) {
+	url := fmt.Sprintf("%s/Library/VirtualFolders", c.BaseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch library folders: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var folders []LibraryFolder
+	if err := json.Unmarshal(body, &folders); err != nil {
+		return nil, fmt.Errorf("failed to parse library folders: %w", err)
+	}
+
+	return folders, nil
+}
+
+func (c *Client) GetVideoPath(ctx context.Context, itemID string) (string, error) {
+	item, err := c.GetItem(ctx, itemID)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,76 @@
+package jellyfin
+
+import (
+	"strings"
+	"sync"
+
+	"subtitle-hunter/internal/lang"
+)
+
+var (
+	detectorMu     sync.RWMutex
+	scriptDetector func(stream MediaStream) string
+)
+
+// SetScriptDetector sets how the script of an external subtitle file is read
+// from its text. detect gets the file's stream, with its path as Jellyfin
+// reports it unless the stream is Local, and returns "Hant", "Hans" or ""
+// when it can't tell. Without one, external files are judged by their
+// language code and titles like embedded tracks.
+func SetScriptDetector(detect func(stream MediaStream) string) {
+	detectorMu.Lock()
+	defer detectorMu.Unlock()
+	scriptDetector = detect
+}
+
+// Title fragments that say which script a Chinese stream is written in.
+var scriptIndicators = []struct {
+	script     string
+	indicators []string
+}{
+	{lang.TraditionalChinese.Script, []string{"traditional", "繁體", "繁体", "繁中", "cht", "big5", "zh-hant", "zh-tw", "zh-hk"}},
+	{lang.SimplifiedChinese.Script, []string{"simplified", "简体", "簡體", "简中", "chs", "gb2312", "zh-hans", "zh-cn"}},
+}
+
+// StreamScript returns the script a Chinese subtitle stream is written in,
+// "Hant" or "Hans", or "" when it can't be told or the stream is in another
+// language. Language codes and titles are often wrong or missing, so an
+// external file's own text, read through the detector set with
+// SetScriptDetector, wins over both. Titles naming both scripts, as
+// bilingual tracks do, say nothing.
+func StreamScript(stream MediaStream) string {
+	code := lang.Normalize(stream.Language)
+	if !code.IsZero() && code.Language != "zh" {
+		return ""
+	}
+
+	if stream.IsExternal && stream.Path != "" {
+		detectorMu.RLock()
+		detect := scriptDetector
+		detectorMu.RUnlock()
+		if detect != nil {
+			if script := detect(stream); script != "" {
+				return script
+			}
+		}
+	}
+
+	if code.Script != "" {
+		return code.Script
+	}
+
+	title := strings.ToLower(stream.Title + " " + stream.DisplayTitle)
+	found := ""
+	for _, candidate := range scriptIndicators {
+		for _, indicator := range candidate.indicators {
+			if strings.Contains(title, indicator) {
+				if found != "" {
+					return ""
+				}
+				found = candidate.script
+				break
+			}
+		}
+	}
+	return found
+}
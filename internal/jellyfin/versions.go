@@ -0,0 +1,48 @@
+package jellyfin
+
+import (
+	"path/filepath"
+	"strings"
+
+	"subtitle-hunter/internal/lang"
+)
+
+// Versions returns the versions of the item that get subtitles. Jellyfin
+// lists a media source per version when several files of different
+// quality make up one movie or episode. With preferred empty every version
+// is returned, the first one first; otherwise only the first version whose
+// name or file name contains preferred (such as "2160p"), ignoring case, or
+// the first version when none does. An item without media sources has its
+// own path as its only version.
+func (item MediaItem) Versions(preferred string) []MediaSource {
+	var versions []MediaSource
+	seen := make(map[string]bool)
+	for _, source := range item.MediaSources {
+		if source.Path == "" || seen[source.Path] {
+			continue
+		}
+		seen[source.Path] = true
+		versions = append(versions, source)
+	}
+	if len(versions) == 0 {
+		return []MediaSource{{Path: item.Path, MediaStreams: item.MediaStreams}}
+	}
+
+	if preferred == "" {
+		return versions
+	}
+	preferred = strings.ToLower(preferred)
+	for _, version := range versions {
+		if strings.Contains(strings.ToLower(version.Name), preferred) || strings.Contains(strings.ToLower(filepath.Base(version.Path)), preferred) {
+			return []MediaSource{version}
+		}
+	}
+	return versions[:1]
+}
+
+// SubtitleStatus reports whether this version has an embedded and/or an
+// external subtitle stream in the given language, like
+// MediaItem.SubtitleStatus.
+func (source MediaSource) SubtitleStatus(tag lang.Tag, forced bool) (embedded, external bool) {
+	return subtitleStatus(source.MediaStreams, tag, forced)
+}
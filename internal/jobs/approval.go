@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// StateAwaitingApproval is the state of a running job that waits for its
+// translation to be approved.
+const StateAwaitingApproval = "awaiting-approval"
+
+var (
+	// ErrApprovalTimeout ends a wait for approval that took too long.
+	ErrApprovalTimeout = errors.New("not approved in time")
+	// ErrNotAwaiting is returned when deciding on a job that isn't waiting
+	// for approval.
+	ErrNotAwaiting = errors.New("the job is not waiting for approval")
+)
+
+type approval struct {
+	what     string
+	decision chan bool
+}
+
+// SetWorker hands the job its worker slot: release gives it back and
+// acquire waits for another. A job waiting for approval gives its slot
+// back, so it doesn't hold up the jobs behind it.
+func (j *Job) SetWorker(release func(), acquire func(context.Context) (func(), error)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.release = release
+	j.acquire = acquire
+}
+
+// ReleaseWorker gives back the worker slot the job holds, if it holds one.
+func (j *Job) ReleaseWorker() {
+	j.mu.Lock()
+	release := j.release
+	j.release = nil
+	j.mu.Unlock()
+	if release != nil {
+		release()
+	}
+}
+
+// AwaitApproval pauses the job until Decide is called for it, ctx ends or
+// timeout passes (zero waits for as long as ctx allows), and reports
+// whether it was approved. what says what is waiting to be approved. A nil
+// job is never approved.
+func (j *Job) AwaitApproval(ctx context.Context, what string, timeout time.Duration) (bool, error) {
+	if j == nil {
+		return false, nil
+	}
+
+	pending := &approval{what: what, decision: make(chan bool, 1)}
+	j.mu.Lock()
+	j.approval = pending
+	j.mu.Unlock()
+	j.ReleaseWorker()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var approved bool
+	var err error
+	select {
+	case approved = <-pending.decision:
+	case <-expired:
+		err = ErrApprovalTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	j.mu.Lock()
+	if j.approval == pending {
+		j.approval = nil
+	}
+	acquire := j.acquire
+	j.mu.Unlock()
+
+	// The job goes on with its next step unless it was cancelled
+	if ctx.Err() == nil && acquire != nil {
+		release, acquireErr := acquire(ctx)
+		if acquireErr != nil {
+			return false, acquireErr
+		}
+		j.mu.Lock()
+		j.release = release
+		j.mu.Unlock()
+	}
+	return approved, err
+}
+
+// Decide approves or rejects what the job is waiting for.
+func (j *Job) Decide(approved bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.approval == nil {
+		return ErrNotAwaiting
+	}
+	j.approval.decision <- approved
+	j.approval = nil
+	return nil
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+)
+
+// StateCancelling is the state of a running job that was cancelled and
+// hasn't stopped yet.
+const StateCancelling = "cancelling"
+
+// ErrCancelled is the error of a job stopped with Cancel.
+var ErrCancelled = errors.New("cancelled")
+
+// SetCancel hands the job the function that cancels its context.
+func (j *Job) SetCancel(cancel context.CancelCauseFunc) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cancel = cancel
+}
+
+// Cancel stops the job: its context ends with ErrCancelled as the cause,
+// so it stops at its next network call or cue, or right away while it
+// waits for approval. It reports false when the job was cancelled before.
+func (j *Job) Cancel() bool {
+	j.mu.Lock()
+	cancel := j.cancel
+	cancelled := j.cancelled
+	j.cancelled = true
+	j.mu.Unlock()
+
+	if cancelled {
+		return false
+	}
+	if cancel != nil {
+		cancel(ErrCancelled)
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+)
+
+// WorkHunt is the work of a job hunting an item's missing subtitles. A
+// second hunt for the same item joins the one already running instead of
+// running again.
+const WorkHunt = "hunt"
+
+// Claim gives one job at a time the right to work on an item, from before
+// it waits for a worker until it finishes, so two jobs never download and
+// write the same item's subtitles at once.
+type Claim struct {
+	itemID string
+	work   string
+	done   chan struct{}
+
+	mu     sync.Mutex
+	job    *Job
+	result interface{}
+	err    error
+}
+
+// Claim takes the claim on an item for work. While another job holds it,
+// Claim waits for that job to finish, unless both do the same work other
+// than "", in which case the claim held is returned with joined set: the
+// caller waits for that job's outcome instead of running its own. It fails
+// only when ctx ends the wait.
+func (h *History) Claim(ctx context.Context, itemID, work string) (claim *Claim, joined bool, err error) {
+	for {
+		h.mu.Lock()
+		held, ok := h.claims[itemID]
+		if !ok {
+			claim = &Claim{itemID: itemID, work: work, done: make(chan struct{})}
+			h.claims[itemID] = claim
+			h.mu.Unlock()
+			return claim, false, nil
+		}
+		h.mu.Unlock()
+
+		if work != "" && held.work == work {
+			return held, true, nil
+		}
+		select {
+		case <-held.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// Release gives up a claim taken with Claim, handing the job's outcome to
+// the callers that joined it.
+func (h *History) Release(claim *Claim, result interface{}, err error) {
+	h.mu.Lock()
+	if h.claims[claim.itemID] == claim {
+		delete(h.claims, claim.itemID)
+	}
+	h.mu.Unlock()
+
+	claim.mu.Lock()
+	claim.result, claim.err = result, err
+	claim.mu.Unlock()
+	close(claim.done)
+}
+
+// Claimed returns the job holding the claim on an item for work, once it
+// has started; one still waiting for a worker has no job yet.
+func (h *History) Claimed(itemID, work string) (*Job, bool) {
+	h.mu.Lock()
+	claim, ok := h.claims[itemID]
+	h.mu.Unlock()
+	if !ok || claim.work != work {
+		return nil, false
+	}
+	job := claim.Job()
+	return job, job != nil
+}
+
+// Started records the job working under the claim once it starts.
+func (c *Claim) Started(job *Job) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.job = job
+}
+
+// Job returns the job working under the claim, nil until it starts.
+func (c *Claim) Job() *Job {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.job
+}
+
+// Wait waits for the job holding a joined claim to finish and returns its
+// outcome, or ctx's error when ctx ends first.
+func (c *Claim) Wait(ctx context.Context) (interface{}, error) {
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.result, c.err
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+)
+
+// RunEach calls do for each index below n on up to workers goroutines at
+// once, in order, and returns how many it called it for. Once do returns
+// false or ctx is done no more are started; those already running finish
+// first. RunEach only spreads the work: the jobs do starts still wait for a
+// slot in the Pool.
+func RunEach(ctx context.Context, workers, n int, do func(index int) bool) int {
+	queue := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var mu sync.Mutex
+	ran := 0
+	var wg sync.WaitGroup
+	for worker := 0; worker < min(max(workers, 1), n); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				// The feeder may have handed this one over just as
+				// another worker stopped or ctx was done
+				select {
+				case <-stop:
+					continue
+				case <-ctx.Done():
+					continue
+				default:
+				}
+				mu.Lock()
+				ran++
+				mu.Unlock()
+				if !do(index) {
+					stopOnce.Do(func() { close(stop) })
+				}
+			}
+		}()
+	}
+
+feed:
+	for index := 0; index < n; index++ {
+		select {
+		case queue <- index:
+		case <-stop:
+			break feed
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return ran
+}
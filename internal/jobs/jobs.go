@@ -0,0 +1,513 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"subtitle-hunter/internal/store"
+)
+
+const bucket = "job-reports"
+
+// MaxReports is the number of job reports kept; older ones are dropped.
+const MaxReports = 1000
+
+// What started a job.
+const (
+	TriggerManual   = "manual"
+	TriggerAuto     = "auto"
+	TriggerSearch   = "search"
+	TriggerCLI      = "cli"
+	TriggerBatch    = "batch"
+	TriggerCampaign = "campaign"
+	TriggerRequest  = "request"
+)
+
+// Stages of the subtitle pipeline a job spends time in, in pipeline order.
+// StageLookup is the job's own Jellyfin lookups, such as an episode's
+// series.
+const (
+	StageLookup     = "lookup"
+	StageSearch     = "search"
+	StageDownload   = "download"
+	StageExtract    = "extract"
+	StageTranscribe = "transcribe"
+	StageParse      = "parse"
+	StageTranslate  = "translate"
+	StageSave       = "save"
+	StageRefresh    = "refresh"
+)
+
+type StageReport struct {
+	Name       string `json:"name"`
+	Calls      int    `json:"calls"`
+	WallTimeMs int64  `json:"wall_time_ms"`
+}
+
+// Report is the machine-readable breakdown of one finished job.
+type Report struct {
+	ID         string    `json:"id"`
+	ItemID     string    `json:"item_id"`
+	ItemName   string    `json:"item_name"`
+	Trigger    string    `json:"trigger"`
+	Succeeded  bool      `json:"succeeded"`
+	Source     string    `json:"source,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	WallTimeMs int64     `json:"wall_time_ms"`
+	// QueueWaitMs is how long the job waited for a worker before it
+	// started; it is not part of the wall time.
+	QueueWaitMs int64 `json:"queue_wait_ms"`
+	// ProviderSearches counts subtitle searches; one search may try
+	// several queries, some of them answered from the search cache.
+	ProviderSearches     int   `json:"provider_searches"`
+	ProviderDownloads    int   `json:"provider_downloads"`
+	BytesDownloaded      int64 `json:"bytes_downloaded"`
+	TranslationRequests  int   `json:"translation_requests"`
+	CharactersTranslated int   `json:"characters_translated"`
+	// EstimatedCost is the translation cost in USD at the backend's price.
+	EstimatedCost float64       `json:"estimated_cost"`
+	Stages        []StageReport `json:"stages"`
+	// Canary lists the subtitles a canary backend helped translate.
+	Canary []CanaryReport `json:"canary,omitempty"`
+	// Normalized counts the cues tidied up in the job's subtitles, when
+	// there were any.
+	Normalized *NormalizeReport `json:"normalized,omitempty"`
+	// Translations lists the subtitles the job translated with the cues
+	// that failed, and Partial is set when one of them is only partly
+	// translated.
+	Translations []TranslationReport `json:"translations,omitempty"`
+	Partial      bool                `json:"partial,omitempty"`
+	// Steps lists the fallback chain steps tried, in order.
+	Steps []StepReport `json:"steps,omitempty"`
+	// State is StateAwaitingApproval while a running job waits for what
+	// Approval describes to be approved, and StateCancelling once it is
+	// cancelled until it stops.
+	State    string `json:"state,omitempty"`
+	Approval string `json:"approval,omitempty"`
+	// Progress is how far the job got translating its last subtitle.
+	Progress *Progress `json:"progress,omitempty"`
+	// Cancelled is set when the job was stopped with Cancel.
+	Cancelled bool `json:"cancelled,omitempty"`
+}
+
+// Progress counts the cues of the subtitle a job is translating that are
+// done. ETAMs estimates how long the rest take at the average time the
+// cues so far took; it is 0 until the first cue is done and once the job
+// has finished.
+type Progress struct {
+	Cues      int     `json:"cues"`
+	TotalCues int     `json:"total_cues"`
+	Percent   float64 `json:"percent"`
+	ETAMs     int64   `json:"eta_ms"`
+}
+
+// Outcomes of a fallback chain step.
+const (
+	StepSucceeded = "succeeded"
+	StepFailed    = "failed"
+	StepSkipped   = "skipped"
+)
+
+// StepReport is the outcome of one fallback chain step, with why it failed
+// or was skipped.
+type StepReport struct {
+	Language string `json:"language"`
+	Step     string `json:"step"`
+	Outcome  string `json:"outcome"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// TranslationReport lists the cues of one translated subtitle that kept
+// their fallback text, by their numbers in the saved file. Total and Failed
+// count the cues as they were before long ones were split.
+type TranslationReport struct {
+	Subtitle   string `json:"subtitle"`
+	Total      int    `json:"total"`
+	Failed     int    `json:"failed"`
+	FailedCues []int  `json:"failed_cues"`
+	Partial    bool   `json:"partial"`
+}
+
+// NormalizeReport counts the cues the normalize pass dropped or renumbered.
+type NormalizeReport struct {
+	Duplicates   int `json:"duplicates"`
+	ZeroDuration int `json:"zero_duration"`
+	Renumbered   int `json:"renumbered"`
+	Reordered    int `json:"reordered_subtitles"`
+}
+
+// CanaryReport tags the cues of one translated subtitle by the backend that
+// translated them, so the canary's cues can be found and reviewed.
+type CanaryReport struct {
+	Subtitle string  `json:"subtitle"`
+	Backend  string  `json:"backend"`
+	Percent  float64 `json:"percent"`
+	// Cues are the indexes of the cues the canary translated.
+	Cues       []int `json:"cues"`
+	StableCues int   `json:"stable_cues"`
+}
+
+// Job collects the report of a running job. All methods may be called on a
+// nil *Job, which records nothing, so work done outside a job needs no
+// special casing.
+type Job struct {
+	mu     sync.Mutex
+	report Report
+	log    Log
+
+	approval *approval
+	release  func()
+	acquire  func(context.Context) (func(), error)
+
+	// translating is when the subtitle being translated was started on.
+	translating time.Time
+	cancel      context.CancelCauseFunc
+	cancelled   bool
+}
+
+// Start begins a job for an item.
+func Start(itemID, itemName, trigger string) *Job {
+	now := time.Now()
+	return &Job{report: Report{
+		// Zero-padded nanoseconds sort in start order.
+		ID:        fmt.Sprintf("%019d", now.UnixNano()),
+		ItemID:    itemID,
+		ItemName:  itemName,
+		Trigger:   trigger,
+		StartedAt: now,
+		Stages:    []StageReport{},
+	}, log: Log{Lines: []LogLine{}}}
+}
+
+func (j *Job) ID() string {
+	if j == nil {
+		return ""
+	}
+	return j.report.ID
+}
+
+// Trigger returns what started the job, such as TriggerAuto.
+func (j *Job) Trigger() string {
+	if j == nil {
+		return ""
+	}
+	return j.report.Trigger
+}
+
+// Stage starts timing a stage and returns the function that stops it:
+//
+//	defer job.Stage(jobs.StageSearch)()
+func (j *Job) Stage(name string) func() {
+	if j == nil {
+		return func() {}
+	}
+	started := time.Now()
+	return func() {
+		elapsed := time.Since(started)
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		for i := range j.report.Stages {
+			if j.report.Stages[i].Name == name {
+				j.report.Stages[i].Calls++
+				j.report.Stages[i].WallTimeMs += elapsed.Milliseconds()
+				return
+			}
+		}
+		j.report.Stages = append(j.report.Stages, StageReport{Name: name, Calls: 1, WallTimeMs: elapsed.Milliseconds()})
+	}
+}
+
+func (j *Job) Searched() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.report.ProviderSearches++
+}
+
+func (j *Job) Downloaded(bytes int) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.report.ProviderDownloads++
+	j.report.BytesDownloaded += int64(bytes)
+}
+
+// Translated records one request to a translation backend.
+func (j *Job) Translated(chars int) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.report.TranslationRequests++
+	j.report.CharactersTranslated += chars
+}
+
+// Translating starts counting the cues of a subtitle with total cues as
+// they are translated, for the job's progress.
+func (j *Job) Translating(total int) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.translating = time.Now()
+	j.report.Progress = &Progress{TotalCues: total}
+}
+
+// CueTranslated counts one more cue of the subtitle being translated.
+func (j *Job) CueTranslated() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.report.Progress != nil {
+		j.report.Progress.Cues++
+	}
+}
+
+func (j *Job) Canary(report CanaryReport) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.report.Canary = append(j.report.Canary, report)
+}
+
+// TranslationFailed adds the cues of one translated subtitle that failed.
+func (j *Job) TranslationFailed(report TranslationReport) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.report.Translations = append(j.report.Translations, report)
+	j.report.Partial = j.report.Partial || report.Partial
+}
+
+// Step records the outcome of a fallback chain step.
+func (j *Job) Step(report StepReport) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.report.Steps = append(j.report.Steps, report)
+}
+
+// Normalized adds the cues tidied up in one subtitle.
+func (j *Job) Normalized(report NormalizeReport) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.report.Normalized == nil {
+		j.report.Normalized = &NormalizeReport{}
+	}
+	j.report.Normalized.Duplicates += report.Duplicates
+	j.report.Normalized.ZeroDuration += report.ZeroDuration
+	j.report.Normalized.Renumbered += report.Renumbered
+	j.report.Normalized.Reordered += report.Reordered
+}
+
+// Finish ends the job and returns its report.
+func (j *Job) Finish(source string, err error) Report {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.report.FinishedAt = time.Now()
+	j.report.WallTimeMs = j.report.FinishedAt.Sub(j.report.StartedAt).Milliseconds()
+	j.report.Succeeded = err == nil
+	j.report.Cancelled = errors.Is(err, ErrCancelled)
+	j.report.Source = source
+	if err != nil {
+		j.report.Error = err.Error()
+	}
+
+	return j.snapshotLocked()
+}
+
+// Snapshot returns the report of a running job so far.
+func (j *Job) Snapshot() Report {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.snapshotLocked()
+}
+
+func (j *Job) snapshotLocked() Report {
+	report := j.report
+	report.Stages = append([]StageReport{}, j.report.Stages...)
+	report.Canary = append([]CanaryReport(nil), j.report.Canary...)
+	report.Steps = append([]StepReport(nil), j.report.Steps...)
+	if j.approval != nil {
+		report.State = StateAwaitingApproval
+		report.Approval = j.approval.what
+	}
+	if j.cancelled && j.report.FinishedAt.IsZero() {
+		report.State = StateCancelling
+	}
+	if j.report.Progress != nil {
+		progress := *j.report.Progress
+		if progress.TotalCues > 0 {
+			progress.Percent = float64(progress.Cues) / float64(progress.TotalCues) * 100
+		}
+		if progress.Cues > 0 && j.report.FinishedAt.IsZero() {
+			perCue := time.Since(j.translating) / time.Duration(progress.Cues)
+			progress.ETAMs = (perCue * time.Duration(progress.TotalCues-progress.Cues)).Milliseconds()
+		}
+		report.Progress = &progress
+	}
+	if j.report.Normalized != nil {
+		normalized := *j.report.Normalized
+		report.Normalized = &normalized
+	}
+	return report
+}
+
+// History keeps the reports and logs of finished jobs in the store, and
+// the jobs still running and the items claimed by jobs in memory.
+type History struct {
+	store *store.Store
+
+	mu      sync.Mutex
+	running map[string]*Job
+	claims  map[string]*Claim
+}
+
+func NewHistory(s *store.Store) *History {
+	return &History{store: s, running: make(map[string]*Job), claims: make(map[string]*Claim)}
+}
+
+// Begin lists a job as running until its report is saved, so its log can
+// be followed while it runs.
+func (h *History) Begin(job *Job) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.running[job.ID()] = job
+}
+
+// Running returns a job that is still running.
+func (h *History) Running(id string) (*Job, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	job, ok := h.running[id]
+	return job, ok
+}
+
+// RunningReports returns the reports so far of the running jobs, newest
+// first.
+func (h *History) RunningReports() []Report {
+	h.mu.Lock()
+	running := make([]*Job, 0, len(h.running))
+	for _, job := range h.running {
+		running = append(running, job)
+	}
+	h.mu.Unlock()
+
+	reports := make([]Report, 0, len(running))
+	for _, job := range running {
+		reports = append(reports, job.Snapshot())
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ID > reports[j].ID })
+	return reports
+}
+
+// Save stores a finished job's report and log, drops the oldest ones
+// beyond MaxReports and stops listing the job as running.
+func (h *History) Save(report Report, jobLog Log) error {
+	h.mu.Lock()
+	delete(h.running, report.ID)
+	h.mu.Unlock()
+
+	if err := h.store.Put(bucket, report.ID, report); err != nil {
+		return fmt.Errorf("failed to save job report: %w", err)
+	}
+	if err := h.store.Put(logBucket, report.ID, jobLog); err != nil {
+		return fmt.Errorf("failed to save job log: %w", err)
+	}
+
+	keys, err := h.store.Keys(bucket)
+	if err != nil {
+		return fmt.Errorf("failed to prune job reports: %w", err)
+	}
+	for _, key := range keys[:max(len(keys)-MaxReports, 0)] {
+		if err := h.store.Delete(bucket, key); err != nil {
+			return fmt.Errorf("failed to prune job reports: %w", err)
+		}
+		if err := h.store.Delete(logBucket, key); err != nil {
+			return fmt.Errorf("failed to prune job logs: %w", err)
+		}
+	}
+	return nil
+}
+
+// Log returns the log of a finished job and whether it was kept; jobs
+// from before logs were kept have none.
+func (h *History) Log(id string) (Log, bool, error) {
+	var jobLog Log
+	found, err := h.store.Get(logBucket, id, &jobLog)
+	return jobLog, found, err
+}
+
+// Get returns the report of a job and whether it exists.
+func (h *History) Get(id string) (Report, bool, error) {
+	var report Report
+	found, err := h.store.Get(bucket, id, &report)
+	return report, found, err
+}
+
+// Recent returns up to limit reports, newest first.
+func (h *History) Recent(limit int) ([]Report, error) {
+	keys, err := h.store.Keys(bucket)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	reports := make([]Report, 0, len(keys))
+	for _, key := range keys {
+		report, _, err := h.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// ForItem returns up to limit reports of the jobs run for an item, newest
+// first.
+func (h *History) ForItem(itemID string, limit int) ([]Report, error) {
+	reports, err := h.Recent(0)
+	if err != nil {
+		return nil, err
+	}
+	var matching []Report
+	for _, report := range reports {
+		if report.ItemID != itemID {
+			continue
+		}
+		matching = append(matching, report)
+		if limit > 0 && len(matching) == limit {
+			break
+		}
+	}
+	return matching, nil
+}
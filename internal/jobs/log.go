@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const logBucket = "job-logs"
+
+// MaxLogLines is the number of lines kept in a job's log; later lines
+// still go to the process log and are counted, but aren't kept.
+const MaxLogLines = 2000
+
+// Log is what a job logged while it ran: the searches it tried, the
+// candidates it scored, the providers' answers and each step it took.
+type Log struct {
+	Lines []LogLine `json:"lines"`
+	// Dropped counts the lines past MaxLogLines.
+	Dropped int `json:"dropped,omitempty"`
+}
+
+type LogLine struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Logf writes a line to the process log and adds it to the job's log.
+func (j *Job) Logf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Print(message)
+	j.add(message)
+}
+
+// Detailf adds a line to the job's log only, for details too many for the
+// process log, such as every candidate a search scored.
+func (j *Job) Detailf(format string, args ...interface{}) {
+	j.add(fmt.Sprintf(format, args...))
+}
+
+func (j *Job) add(message string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.log.Lines) >= MaxLogLines {
+		j.log.Dropped++
+		return
+	}
+	j.log.Lines = append(j.log.Lines, LogLine{Time: time.Now(), Message: message})
+}
+
+// Log returns the lines the job has logged so far.
+func (j *Job) Log() Log {
+	if j == nil {
+		return Log{Lines: []LogLine{}}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return Log{Lines: append([]LogLine{}, j.log.Lines...), Dropped: j.log.Dropped}
+}
+
+type contextKey struct{}
+
+// NewContext returns a context that carries job, so code that only gets a
+// context, such as the provider clients, can write to the job's log.
+func NewContext(ctx context.Context, job *Job) context.Context {
+	return context.WithValue(ctx, contextKey{}, job)
+}
+
+// FromContext returns the job ctx carries, or nil. Since a nil *Job records
+// nothing, FromContext(ctx).Logf works with or without a job.
+func FromContext(ctx context.Context) *Job {
+	job, _ := ctx.Value(contextKey{}).(*Job)
+	return job
+}
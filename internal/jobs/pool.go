@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool bounds how many jobs run at once across the whole service, so
+// clicking "Find Subtitle" on many items or a large scheduled run doesn't
+// flood the providers. Jobs waiting for a slot are queued per trigger and
+// the queues take turns, so a long automatic run can't hold back manual
+// requests; within a queue jobs start in arrival order.
+type Pool struct {
+	mu      sync.Mutex
+	size    int
+	running int
+	queues  map[string][]*waiter
+	// turns lists the triggers with waiting jobs in the order they are
+	// served.
+	turns []string
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// PoolStats describes the pool for status displays.
+type PoolStats struct {
+	Size    int            `json:"size"`
+	Running int            `json:"running"`
+	Queued  map[string]int `json:"queued"`
+}
+
+// NewPool returns a pool running up to size jobs at once. A size below one
+// is treated as one.
+func NewPool(size int) *Pool {
+	return &Pool{size: max(size, 1), queues: make(map[string][]*waiter)}
+}
+
+// SetSize changes the number of jobs run at once. Running jobs are not
+// interrupted when it shrinks.
+func (p *Pool) SetSize(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = max(size, 1)
+	p.dispatchLocked()
+}
+
+// Acquire waits for a slot for a job started by trigger and returns the
+// function that gives it back. It gives up when ctx is done.
+func (p *Pool) Acquire(ctx context.Context, trigger string) (func(), error) {
+	p.mu.Lock()
+	if p.running < p.size && len(p.turns) == 0 {
+		p.running++
+		p.mu.Unlock()
+		return p.release, nil
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	if len(p.queues[trigger]) == 0 {
+		p.turns = append(p.turns, trigger)
+	}
+	p.queues[trigger] = append(p.queues[trigger], w)
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return p.release, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if w.granted {
+			// The slot arrived while giving up; pass it on
+			p.running--
+			p.dispatchLocked()
+		} else {
+			p.removeLocked(trigger, w)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (p *Pool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running--
+	p.dispatchLocked()
+}
+
+// dispatchLocked hands free slots to waiting jobs, taking one job from each
+// trigger's queue in turn.
+func (p *Pool) dispatchLocked() {
+	for p.running < p.size && len(p.turns) > 0 {
+		trigger := p.turns[0]
+		queue := p.queues[trigger]
+		w := queue[0]
+
+		p.turns = p.turns[1:]
+		if len(queue) > 1 {
+			p.queues[trigger] = queue[1:]
+			p.turns = append(p.turns, trigger)
+		} else {
+			delete(p.queues, trigger)
+		}
+
+		p.running++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+func (p *Pool) removeLocked(trigger string, w *waiter) {
+	queue := p.queues[trigger]
+	for i, queued := range queue {
+		if queued == w {
+			queue = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) > 0 {
+		p.queues[trigger] = queue
+		return
+	}
+
+	delete(p.queues, trigger)
+	for i, turn := range p.turns {
+		if turn == trigger {
+			p.turns = append(p.turns[:i:i], p.turns[i+1:]...)
+			break
+		}
+	}
+}
+
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := PoolStats{Size: p.size, Running: p.running, Queued: make(map[string]int, len(p.queues))}
+	for trigger, queue := range p.queues {
+		stats.Queued[trigger] = len(queue)
+	}
+	return stats
+}
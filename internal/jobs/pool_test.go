@@ -0,0 +1,243 @@
+package jobs
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitQueued waits until the pool has queued jobs waiting in total.
+func waitQueued(t *testing.T, p *Pool, queued int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		total := 0
+		for _, n := range p.Stats().Queued {
+			total += n
+		}
+		if total == queued {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d jobs queued, want %d", total, queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPoolBoundsRunningJobs(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		jobs int
+		want int
+	}{
+		{name: "fewer jobs than slots", size: 4, jobs: 2, want: 2},
+		{name: "more jobs than slots", size: 3, jobs: 20, want: 3},
+		{name: "size below one", size: 0, jobs: 5, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPool(tt.size)
+			var mu sync.Mutex
+			running, peak := 0, 0
+			var wg sync.WaitGroup
+			for i := 0; i < tt.jobs; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					release, err := p.Acquire(context.Background(), TriggerManual)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					mu.Lock()
+					running++
+					peak = max(peak, running)
+					mu.Unlock()
+					time.Sleep(5 * time.Millisecond)
+					mu.Lock()
+					running--
+					mu.Unlock()
+					release()
+				}()
+			}
+			wg.Wait()
+			if peak != tt.want {
+				t.Errorf("at most %d jobs ran at once, want %d", peak, tt.want)
+			}
+			if stats := p.Stats(); stats.Running != 0 || len(stats.Queued) != 0 {
+				t.Errorf("Stats() = %+v after every job finished", stats)
+			}
+		})
+	}
+}
+
+func TestPoolTakesTurnsBetweenTriggers(t *testing.T) {
+	p := NewPool(1)
+	release, err := p.Acquire(context.Background(), TriggerAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three automatic jobs queue up before two manual ones
+	queued := []struct {
+		trigger string
+		name    string
+	}{
+		{TriggerAuto, "auto 1"}, {TriggerAuto, "auto 2"}, {TriggerAuto, "auto 3"},
+		{TriggerManual, "manual 1"}, {TriggerManual, "manual 2"},
+	}
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for i, job := range queued {
+		wg.Add(1)
+		go func(trigger, name string) {
+			defer wg.Done()
+			release, err := p.Acquire(context.Background(), trigger)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			release()
+		}(job.trigger, job.name)
+		waitQueued(t, p, i+1)
+	}
+
+	if got := p.Stats().Queued; !reflect.DeepEqual(got, map[string]int{TriggerAuto: 3, TriggerManual: 2}) {
+		t.Errorf("Stats().Queued = %v", got)
+	}
+	release()
+	wg.Wait()
+
+	want := []string{"auto 1", "manual 1", "auto 2", "manual 2", "auto 3"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("jobs ran in the order %v, want %v", order, want)
+	}
+}
+
+func TestPoolAcquireGivesUp(t *testing.T) {
+	p := NewPool(1)
+	release, err := p.Acquire(context.Background(), TriggerManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Acquire(ctx, TriggerBatch)
+		done <- err
+	}()
+	waitQueued(t, p, 1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Acquire() error = %v, want %v", err, context.Canceled)
+	}
+	if queued := p.Stats().Queued; len(queued) != 0 {
+		t.Errorf("Stats().Queued = %v after giving up, want none", queued)
+	}
+
+	// The slot is still handed on once the running job finishes
+	release()
+	release, err = p.Acquire(context.Background(), TriggerManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
+
+func TestPoolSetSizeStartsWaitingJobs(t *testing.T) {
+	p := NewPool(1)
+	release, err := p.Acquire(context.Background(), TriggerManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	started := make(chan func(), 1)
+	go func() {
+		release, err := p.Acquire(context.Background(), TriggerManual)
+		if err != nil {
+			t.Error(err)
+		}
+		started <- release
+	}()
+	waitQueued(t, p, 1)
+	p.SetSize(2)
+
+	select {
+	case release := <-started:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("the waiting job didn't start after the pool grew")
+	}
+}
+
+func TestRunEach(t *testing.T) {
+	tests := []struct {
+		name        string
+		workers     int
+		n           int
+		stopAt      int
+		wantStarted int
+	}{
+		{name: "all items", workers: 3, n: 10, stopAt: -1, wantStarted: 10},
+		{name: "no items", workers: 3, n: 0, stopAt: -1, wantStarted: 0},
+		{name: "no workers runs one at a time", workers: 0, n: 4, stopAt: -1, wantStarted: 4},
+		{name: "stops starting after false", workers: 1, n: 10, stopAt: 3, wantStarted: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var done []int
+			started := RunEach(context.Background(), tt.workers, tt.n, func(index int) bool {
+				mu.Lock()
+				defer mu.Unlock()
+				done = append(done, index)
+				return index != tt.stopAt
+			})
+			if started != tt.wantStarted || len(done) != tt.wantStarted {
+				t.Errorf("RunEach() started %d and ran %d items, want %d", started, len(done), tt.wantStarted)
+			}
+		})
+	}
+}
+
+func TestRunEachBoundsWorkers(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	RunEach(context.Background(), 3, 12, func(int) bool {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return true
+	})
+	if peak != 3 {
+		t.Errorf("at most %d items ran at once, want 3", peak)
+	}
+}
+
+func TestRunEachStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := RunEach(ctx, 1, 10, func(index int) bool {
+		if index == 1 {
+			cancel()
+		}
+		return true
+	})
+	if started != 2 {
+		t.Errorf("RunEach() started %d items, want 2", started)
+	}
+}
@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"sort"
+	"time"
+)
+
+// stageOrder is the order stages are listed in; stages not listed come
+// after these.
+var stageOrder = []string{StageLookup, StageSearch, StageDownload, StageExtract, StageTranscribe, StageParse, StageTranslate, StageSave, StageRefresh}
+
+// Durations sums up how long something took across jobs, in milliseconds.
+type Durations struct {
+	P50Ms int64 `json:"p50_ms"`
+	P95Ms int64 `json:"p95_ms"`
+	MaxMs int64 `json:"max_ms"`
+}
+
+// StageStats sums up the time jobs spent in one stage. The percentiles are
+// of each job's total time in the stage, over the jobs that went through
+// it.
+type StageStats struct {
+	Name    string `json:"name"`
+	Jobs    int    `json:"jobs"`
+	Calls   int    `json:"calls"`
+	TotalMs int64  `json:"total_ms"`
+	// Share is the percentage of the time spent in all stages that was
+	// spent in this one.
+	Share float64 `json:"share"`
+	Durations
+}
+
+// Stats sums up the stage timings of finished jobs, so it can be seen
+// which stage most of the time goes to.
+type Stats struct {
+	Jobs int `json:"jobs"`
+	// Since is when the oldest job counted started.
+	Since     time.Time    `json:"since,omitempty"`
+	WallTime  Durations    `json:"wall_time"`
+	QueueWait Durations    `json:"queue_wait"`
+	Stages    []StageStats `json:"stages"`
+	// Bottleneck is the stage with the most time spent in it.
+	Bottleneck string `json:"bottleneck,omitempty"`
+}
+
+// Summarize works out the stage timing statistics of reports.
+func Summarize(reports []Report) Stats {
+	stats := Stats{Jobs: len(reports), Stages: []StageStats{}}
+	var wallTimes, queueWaits []int64
+	perStage := make(map[string][]int64)
+	byName := make(map[string]*StageStats)
+	for _, report := range reports {
+		if stats.Since.IsZero() || report.StartedAt.Before(stats.Since) {
+			stats.Since = report.StartedAt
+		}
+		wallTimes = append(wallTimes, report.WallTimeMs)
+		queueWaits = append(queueWaits, report.QueueWaitMs)
+		for _, stage := range report.Stages {
+			summary, ok := byName[stage.Name]
+			if !ok {
+				summary = &StageStats{Name: stage.Name}
+				byName[stage.Name] = summary
+			}
+			summary.Jobs++
+			summary.Calls += stage.Calls
+			summary.TotalMs += stage.WallTimeMs
+			perStage[stage.Name] = append(perStage[stage.Name], stage.WallTimeMs)
+		}
+	}
+	stats.WallTime = durations(wallTimes)
+	stats.QueueWait = durations(queueWaits)
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := stageRank(names[i]), stageRank(names[j])
+		if a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+
+	var total, most int64
+	for _, summary := range byName {
+		total += summary.TotalMs
+	}
+	for _, name := range names {
+		summary := byName[name]
+		summary.Durations = durations(perStage[name])
+		if total > 0 {
+			summary.Share = float64(summary.TotalMs) / float64(total) * 100
+		}
+		if summary.TotalMs > most {
+			most = summary.TotalMs
+			stats.Bottleneck = name
+		}
+		stats.Stages = append(stats.Stages, *summary)
+	}
+	return stats
+}
+
+func stageRank(name string) int {
+	for i, stage := range stageOrder {
+		if stage == name {
+			return i
+		}
+	}
+	return len(stageOrder)
+}
+
+func durations(values []int64) Durations {
+	if len(values) == 0 {
+		return Durations{}
+	}
+	sorted := append([]int64{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return Durations{
+		P50Ms: percentile(sorted, 50),
+		P95Ms: percentile(sorted, 95),
+		MaxMs: sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted values.
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
@@ -0,0 +1,51 @@
+package lang
+
+// Common characters written differently in Simplified and Traditional
+// Chinese, as pairs at the same positions. Characters whose Simplified form
+// is also a Traditional character in its own right (后, 发, 干, 里, 只, 面)
+// are left out, since they say nothing about the script.
+const (
+	simplifiedChars  = "这们说个来时会为对过还没吗么样现点问让谁话见听觉认识该给经东两开关头长门车钱应实动从欢爱办将难请谢马妈儿与无却进边远运电买卖条总战杀钟万岁号场报帮带当导灯敌队飞风刚国汉红护华坏机级计记际间简讲节结紧尽惊旧军块蓝乐离历俩联练辆灵刘龙楼乱论满梦脑鸟农齐气亲轻庆热伤声胜师书术双顺虽孙态谈体铁厅图团卫习戏吓显险乡响写兴许选学压爷页业医亿义忆艺阴银优犹鱼语员园愿约杂张证种众专转庄准资组"
+	traditionalChars = "這們說個來時會為對過還沒嗎麼樣現點問讓誰話見聽覺認識該給經東兩開關頭長門車錢應實動從歡愛辦將難請謝馬媽兒與無卻進邊遠運電買賣條總戰殺鐘萬歲號場報幫帶當導燈敵隊飛風剛國漢紅護華壞機級計記際間簡講節結緊盡驚舊軍塊藍樂離歷倆聯練輛靈劉龍樓亂論滿夢腦鳥農齊氣親輕慶熱傷聲勝師書術雙順雖孫態談體鐵廳圖團衛習戲嚇顯險鄉響寫興許選學壓爺頁業醫億義憶藝陰銀優猶魚語員園願約雜張證種眾專轉莊準資組"
+)
+
+var simplifiedSet, traditionalSet = runeSet(simplifiedChars), runeSet(traditionalChars)
+
+func runeSet(chars string) map[rune]bool {
+	set := make(map[rune]bool)
+	for _, r := range chars {
+		set[r] = true
+	}
+	return set
+}
+
+// minScriptEvidence is how many telling characters DetectChineseScript needs
+// before it decides, so a line of Chinese in an English file decides nothing.
+const minScriptEvidence = 20
+
+// DetectChineseScript returns the script Chinese text is written in, "Hant"
+// or "Hans", judging by how many of its characters only exist in one of
+// them. It returns "" when the text has too few such characters or mixes
+// both, as bilingual Simplified and Traditional files do.
+func DetectChineseScript(text string) string {
+	simplified, traditional := 0, 0
+	for _, r := range text {
+		switch {
+		case simplifiedSet[r]:
+			simplified++
+		case traditionalSet[r]:
+			traditional++
+		}
+	}
+
+	total := simplified + traditional
+	switch {
+	case total < minScriptEvidence:
+		return ""
+	case traditional*10 >= total*9:
+		return TraditionalChinese.Script
+	case simplified*10 >= total*9:
+		return SimplifiedChinese.Script
+	}
+	return ""
+}
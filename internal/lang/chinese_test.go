@@ -0,0 +1,34 @@
+package lang
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectChineseScript(t *testing.T) {
+	traditional := strings.Repeat("這們說個來時會為對過", 2)
+	simplified := strings.Repeat("这们说个来时会为对过", 2)
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "Traditional", text: traditional, want: "Hant"},
+		{name: "Simplified", text: simplified, want: "Hans"},
+		{name: "Traditional with English", text: "Hello, " + traditional + " world", want: "Hant"},
+		{name: "a few Simplified characters in Traditional", text: strings.Repeat(traditional, 5) + "这们", want: "Hant"},
+		{name: "bilingual", text: traditional + "\n" + simplified, want: ""},
+		{name: "too little evidence", text: "這們說個來", want: ""},
+		{name: "shared characters only", text: strings.Repeat("我你他好的人大中", 5), want: ""},
+		{name: "English", text: "Hello world", want: ""},
+		{name: "empty", text: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectChineseScript(tt.text); got != tt.want {
+				t.Errorf("DetectChineseScript(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
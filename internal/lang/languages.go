@@ -0,0 +1,108 @@
+package lang
+
+import "strings"
+
+// Language holds the ISO 639 codes and display names of a language.
+type Language struct {
+	// Alpha2 is the ISO 639-1 code, empty for languages without one.
+	Alpha2 string
+	// Alpha3 is the ISO 639-2/T code, which is also the ISO 639-3 code.
+	Alpha3 string
+	// Alpha3B is the ISO 639-2/B (bibliographic) code when it differs.
+	Alpha3B     string
+	EnglishName string
+	NativeName  string
+}
+
+// Code returns the shortest code for the language, as used in BCP-47 tags.
+func (l Language) Code() string {
+	if l.Alpha2 != "" {
+		return l.Alpha2
+	}
+	return l.Alpha3
+}
+
+var languages = []Language{
+	{"ar", "ara", "", "Arabic", "العربية"},
+	{"bg", "bul", "", "Bulgarian", "Български"},
+	{"cs", "ces", "cze", "Czech", "Čeština"},
+	{"da", "dan", "", "Danish", "Dansk"},
+	{"de", "deu", "ger", "German", "Deutsch"},
+	{"el", "ell", "gre", "Greek", "Ελληνικά"},
+	{"en", "eng", "", "English", "English"},
+	{"es", "spa", "", "Spanish", "Español"},
+	{"fa", "fas", "per", "Persian", "فارسی"},
+	{"fi", "fin", "", "Finnish", "Suomi"},
+	{"fr", "fra", "fre", "French", "Français"},
+	{"he", "heb", "", "Hebrew", "עברית"},
+	{"hi", "hin", "", "Hindi", "हिन्दी"},
+	{"hr", "hrv", "", "Croatian", "Hrvatski"},
+	{"hu", "hun", "", "Hungarian", "Magyar"},
+	{"id", "ind", "", "Indonesian", "Bahasa Indonesia"},
+	{"it", "ita", "", "Italian", "Italiano"},
+	{"ja", "jpn", "", "Japanese", "日本語"},
+	{"ko", "kor", "", "Korean", "한국어"},
+	{"ms", "msa", "may", "Malay", "Bahasa Melayu"},
+	{"nl", "nld", "dut", "Dutch", "Nederlands"},
+	{"no", "nor", "", "Norwegian", "Norsk"},
+	{"pl", "pol", "", "Polish", "Polski"},
+	{"pt", "por", "", "Portuguese", "Português"},
+	{"ro", "ron", "rum", "Romanian", "Română"},
+	{"ru", "rus", "", "Russian", "Русский"},
+	{"sk", "slk", "slo", "Slovak", "Slovenčina"},
+	{"sr", "srp", "", "Serbian", "Српски"},
+	{"sv", "swe", "", "Swedish", "Svenska"},
+	{"th", "tha", "", "Thai", "ไทย"},
+	{"tl", "tgl", "", "Tagalog", "Tagalog"},
+	{"tr", "tur", "", "Turkish", "Türkçe"},
+	{"uk", "ukr", "", "Ukrainian", "Українська"},
+	{"vi", "vie", "", "Vietnamese", "Tiếng Việt"},
+	{"zh", "zho", "chi", "Chinese", "中文"},
+	{"", "yue", "", "Cantonese", "粵語"},
+	{"", "cmn", "", "Mandarin", "普通話"},
+}
+
+var byCode = func() map[string]Language {
+	index := make(map[string]Language)
+	for _, l := range languages {
+		for _, key := range []string{l.Alpha2, l.Alpha3, l.Alpha3B, strings.ToLower(l.EnglishName)} {
+			if key != "" {
+				index[key] = l
+			}
+		}
+	}
+	return index
+}()
+
+// Lookup finds a language by ISO 639-1, 639-2/T, 639-2/B or 639-3 code, or by
+// English name. Matching is case-insensitive.
+func Lookup(code string) (Language, bool) {
+	l, ok := byCode[strings.ToLower(strings.TrimSpace(code))]
+	return l, ok
+}
+
+// ToAlpha2 converts any known code to ISO 639-1, returning "" if the
+// language is unknown or has no two-letter code.
+func ToAlpha2(code string) string {
+	l, _ := Lookup(code)
+	return l.Alpha2
+}
+
+// ToAlpha3 converts any known code to ISO 639-2/T (= 639-3).
+func ToAlpha3(code string) string {
+	l, _ := Lookup(code)
+	return l.Alpha3
+}
+
+// ToAlpha3B converts any known code to ISO 639-2/B, which Jellyfin and many
+// containers use (e.g. "chi", "fre", "ger").
+func ToAlpha3B(code string) string {
+	l, ok := Lookup(code)
+	if !ok {
+		return ""
+	}
+	if l.Alpha3B != "" {
+		return l.Alpha3B
+	}
+	return l.Alpha3
+}
@@ -0,0 +1,74 @@
+package lang
+
+// undetermined are the ISO 639 codes that name no particular language:
+// undetermined, uncoded, multiple languages and no linguistic content.
+var undetermined = map[string]bool{"und": true, "mis": true, "mul": true, "zxx": true}
+
+// chineseRegions are the regions Chinese subtitles are commonly tagged
+// with in place of a script.
+var chineseRegions = []string{"TW", "HK", "MO", "CN", "SG"}
+
+// Normalize parses a tag as Parse does and reduces it to the one form a
+// language is stored and compared under, so that the many ways Jellyfin,
+// containers, release names and providers write it agree. See
+// Tag.Normalize.
+func Normalize(value string) Tag {
+	return Parse(value).Normalize()
+}
+
+// Normalize returns the canonical form of the tag:
+//   - codes naming no language, such as "und", give the zero tag
+//   - written Mandarin ("cmn") is Chinese ("zh")
+//   - Chinese carries its script instead of a region, so "zh-TW", "zh-HK"
+//     and "zh-Hant-TW" are all "zh-Hant", and "zh-CN" and "zh-SG" "zh-Hans"
+//
+// Other languages keep their script and region, since those can tell
+// subtitles apart (pt-BR and pt-PT, sr-Latn and sr-Cyrl).
+func (t Tag) Normalize() Tag {
+	if undetermined[t.Language] {
+		return Tag{}
+	}
+	if t.Language == "cmn" {
+		t.Language = "zh"
+	}
+	if t.Language == "zh" {
+		return Tag{Language: "zh", Script: t.InferredScript()}
+	}
+	return t
+}
+
+// Aliases returns the codes a subtitle in the tag's language may be named
+// with, canonical form first, such as "zh-Hant", "zh-TW", "zh-HK", "zh",
+// "zho" and "chi" for Traditional Chinese.
+func (t Tag) Aliases() []string {
+	t = t.Normalize()
+	if t.IsZero() {
+		return nil
+	}
+
+	aliases := []string{t.String()}
+	add := func(code string) {
+		for _, alias := range aliases {
+			if alias == code {
+				return
+			}
+		}
+		if code != "" {
+			aliases = append(aliases, code)
+		}
+	}
+
+	if t.Language == "zh" {
+		for _, region := range chineseRegions {
+			if script := (Tag{Language: "zh", Region: region}).InferredScript(); t.Script == "" || script == t.Script {
+				add("zh-" + region)
+			}
+		}
+	}
+	if l, ok := Lookup(t.Language); ok {
+		add(l.Alpha2)
+		add(l.Alpha3)
+		add(l.Alpha3B)
+	}
+	return aliases
+}
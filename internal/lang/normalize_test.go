@@ -0,0 +1,71 @@
+package lang
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want Tag
+	}{
+		{name: "zh-TW", in: "zh-TW", want: TraditionalChinese},
+		{name: "zh-HK", in: "zh-HK", want: TraditionalChinese},
+		{name: "zh-Hant", in: "zh-Hant", want: TraditionalChinese},
+		{name: "zh-Hant-TW", in: "zh-Hant-TW", want: TraditionalChinese},
+		{name: "zht", in: "zht", want: TraditionalChinese},
+		{name: "cht", in: "cht", want: TraditionalChinese},
+		{name: "zh-CN", in: "zh-CN", want: SimplifiedChinese},
+		{name: "zh-SG", in: "zh-SG", want: SimplifiedChinese},
+		{name: "chi", in: "chi", want: Tag{Language: "zh"}},
+		{name: "zho", in: "zho", want: Tag{Language: "zh"}},
+		{name: "chi with a region", in: "chi-TW", want: TraditionalChinese},
+		{name: "Mandarin", in: "cmn-Hant-TW", want: TraditionalChinese},
+		{name: "Mandarin without a script", in: "cmn", want: Tag{Language: "zh"}},
+		{name: "lower case", in: "zh-hant", want: TraditionalChinese},
+		{name: "upper case", in: "ZH-TW", want: TraditionalChinese},
+		{name: "underscore", in: "zh_TW", want: TraditionalChinese},
+		{name: "underscore and lower case", in: "zh_hans_cn", want: SimplifiedChinese},
+		{name: "Cantonese keeps its region", in: "yue-HK", want: Tag{Language: "yue", Region: "HK"}},
+		{name: "other languages keep their region", in: "pt_BR", want: Tag{Language: "pt", Region: "BR"}},
+		{name: "other languages keep their script", in: "sr-Latn", want: Tag{Language: "sr", Script: "Latn"}},
+		{name: "English", in: "eng", want: English},
+		{name: "undetermined", in: "und", want: Tag{}},
+		{name: "multiple languages", in: "mul", want: Tag{}},
+		{name: "no linguistic content", in: "zxx", want: Tag{}},
+		{name: "unknown", in: "Klingon", want: Tag{Language: "klingon"}},
+		{name: "empty", in: "", want: Tag{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliases(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{name: "Traditional Chinese", in: "zh-TW", want: []string{"zh-Hant", "zh-TW", "zh-HK", "zh-MO", "zh", "zho", "chi"}},
+		{name: "Simplified Chinese", in: "chs", want: []string{"zh-Hans", "zh-CN", "zh-SG", "zh", "zho", "chi"}},
+		{name: "Chinese without a script", in: "chi", want: []string{"zh", "zh-TW", "zh-HK", "zh-MO", "zh-CN", "zh-SG", "zho", "chi"}},
+		{name: "English", in: "en", want: []string{"en", "eng"}},
+		{name: "Cantonese", in: "yue", want: []string{"yue"}},
+		{name: "unknown", in: "xx", want: []string{"xx"}},
+		{name: "undetermined", in: "und", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.in).Aliases(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q).Aliases() = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
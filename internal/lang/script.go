@@ -0,0 +1,76 @@
+package lang
+
+import "unicode"
+
+// ISO 15924 codes returned by DetectScript.
+const (
+	ScriptLatin    = "Latn"
+	ScriptHan      = "Hani"
+	ScriptJapanese = "Jpan"
+	ScriptKorean   = "Kore"
+	ScriptCyrillic = "Cyrl"
+	ScriptArabic   = "Arab"
+	ScriptHebrew   = "Hebr"
+	ScriptGreek    = "Grek"
+	ScriptThai     = "Thai"
+)
+
+var scriptTables = []struct {
+	code  string
+	table *unicode.RangeTable
+}{
+	{ScriptLatin, unicode.Latin},
+	{ScriptHan, unicode.Han},
+	{ScriptCyrillic, unicode.Cyrillic},
+	{ScriptArabic, unicode.Arabic},
+	{ScriptHebrew, unicode.Hebrew},
+	{ScriptGreek, unicode.Greek},
+	{ScriptThai, unicode.Thai},
+}
+
+// DetectScript returns the dominant writing system of text, or "" when it
+// contains no letters. Any kana marks the text as Japanese and any Hangul as
+// Korean, since both are usually mixed with Han characters.
+func DetectScript(text string) string {
+	counts := make(map[string]int)
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			return ScriptJapanese
+		case unicode.Is(unicode.Hangul, r):
+			return ScriptKorean
+		}
+		for _, script := range scriptTables {
+			if unicode.Is(script.table, r) {
+				counts[script.code]++
+				break
+			}
+		}
+	}
+
+	best, bestCount := "", 0
+	for _, script := range scriptTables {
+		if counts[script.code] > bestCount {
+			best, bestCount = script.code, counts[script.code]
+		}
+	}
+	return best
+}
+
+// HanRatio returns the fraction of letters in text that are Han characters.
+func HanRatio(text string) float64 {
+	han, letters := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Han, r) {
+			han++
+		}
+	}
+	if letters == 0 {
+		return 0
+	}
+	return float64(han) / float64(letters)
+}
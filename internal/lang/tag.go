@@ -0,0 +1,192 @@
+package lang
+
+import (
+	"strings"
+)
+
+// Tag is a parsed BCP-47 language tag reduced to the parts that matter for
+// subtitles: language, script and region.
+type Tag struct {
+	// Language is the ISO 639-1 code when one exists, otherwise ISO 639-3.
+	Language string
+	// Script is an ISO 15924 code such as "Hant" or "Hans".
+	Script string
+	// Region is an ISO 3166-1 code such as "TW".
+	Region string
+}
+
+var (
+	English            = Tag{Language: "en"}
+	TraditionalChinese = Tag{Language: "zh", Script: "Hant"}
+	SimplifiedChinese  = Tag{Language: "zh", Script: "Hans"}
+)
+
+// Non-standard tags seen in the wild, mapped to what they mean.
+var legacyTags = map[string]Tag{
+	"cht":  {Language: "zh", Script: "Hant"},
+	"chs":  {Language: "zh", Script: "Hans"},
+	"zht":  {Language: "zh", Script: "Hant"},
+	"zhs":  {Language: "zh", Script: "Hans"},
+	"tc":   {Language: "zh", Script: "Hant"},
+	"sc":   {Language: "zh", Script: "Hans"},
+	"big5": {Language: "zh", Script: "Hant"},
+	"gb":   {Language: "zh", Script: "Hans"},
+	// Old .NET culture names and OCR language names
+	"zh-cht":  {Language: "zh", Script: "Hant"},
+	"zh-chs":  {Language: "zh", Script: "Hans"},
+	"chi_tra": {Language: "zh", Script: "Hant"},
+	"chi_sim": {Language: "zh", Script: "Hans"},
+	// Names some containers and players put in the language field
+	"traditional chinese":   {Language: "zh", Script: "Hant"},
+	"simplified chinese":    {Language: "zh", Script: "Hans"},
+	"chinese (traditional)": {Language: "zh", Script: "Hant"},
+	"chinese (simplified)":  {Language: "zh", Script: "Hans"},
+}
+
+// Regions whose Chinese is written in Traditional characters by default.
+var traditionalRegions = map[string]bool{"TW": true, "HK": true, "MO": true}
+
+// Parse reads a BCP-47-ish tag ("zh-Hant-TW", "zh_TW", "chi", "pt-BR") and
+// canonicalises its parts. Unknown languages are kept lowercased as-is.
+func Parse(tag string) Tag {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return Tag{}
+	}
+
+	if legacy, ok := legacyTags[strings.ToLower(tag)]; ok {
+		return legacy
+	}
+
+	parts := strings.FieldsFunc(tag, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) == 0 {
+		return Tag{}
+	}
+
+	var t Tag
+	if l, ok := Lookup(parts[0]); ok {
+		t.Language = l.Code()
+	} else {
+		t.Language = strings.ToLower(parts[0])
+	}
+
+	for _, part := range parts[1:] {
+		switch {
+		case len(part) == 4 && isAlpha(part):
+			t.Script = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		case (len(part) == 2 && isAlpha(part)) || (len(part) == 3 && isDigits(part)):
+			t.Region = strings.ToUpper(part)
+		}
+	}
+
+	return t
+}
+
+// String formats the tag in canonical BCP-47 form.
+func (t Tag) String() string {
+	parts := []string{t.Language}
+	if t.Script != "" {
+		parts = append(parts, t.Script)
+	}
+	if t.Region != "" {
+		parts = append(parts, t.Region)
+	}
+	return strings.Join(parts, "-")
+}
+
+// IsZero reports whether the tag is empty.
+func (t Tag) IsZero() bool {
+	return t.Language == ""
+}
+
+// InferredScript returns the explicit script, or the script implied by the
+// region for Chinese (TW/HK/MO are Traditional, other regions Simplified).
+func (t Tag) InferredScript() string {
+	if t.Script != "" || t.Language != "zh" || t.Region == "" {
+		return t.Script
+	}
+	if traditionalRegions[t.Region] {
+		return "Hant"
+	}
+	return "Hans"
+}
+
+// Matches reports whether the tag can stand in for target: the languages
+// must agree, and scripts must agree when both are known. A tag without a
+// script (e.g. a bare "chi" stream) matches any script of its language.
+func (t Tag) Matches(target Tag) bool {
+	if t.Language != target.Language {
+		return false
+	}
+	script, targetScript := t.InferredScript(), target.InferredScript()
+	return script == "" || targetScript == "" || script == targetScript
+}
+
+// chineseLanguages are the Chinese languages films and series are made in,
+// as ISO 639 codes; "zh" covers tags that don't say which.
+var chineseLanguages = map[string]bool{"zh": true, "yue": true, "cmn": true, "nan": true, "hak": true, "wuu": true}
+
+// IsChinese reports whether the tag is a Chinese language, such as Mandarin
+// or Cantonese, in any script.
+func (t Tag) IsChinese() bool {
+	return chineseLanguages[t.Language]
+}
+
+// DisplayName returns an English name such as "Chinese (Traditional)".
+func (t Tag) DisplayName() string {
+	return t.displayName(false)
+}
+
+// NativeName returns the name in the language itself, e.g. "中文 (繁體)".
+func (t Tag) NativeName() string {
+	return t.displayName(true)
+}
+
+var scriptNames = map[string][2]string{
+	"Hant": {"Traditional", "繁體"},
+	"Hans": {"Simplified", "简体"},
+	"Latn": {"Latin", "Latin"},
+	"Cyrl": {"Cyrillic", "Кириллица"},
+}
+
+func (t Tag) displayName(native bool) string {
+	l, ok := Lookup(t.Language)
+	if !ok {
+		return t.String()
+	}
+
+	name := l.EnglishName
+	if native {
+		name = l.NativeName
+	}
+
+	if names, ok := scriptNames[t.InferredScript()]; ok {
+		qualifier := names[0]
+		if native {
+			qualifier = names[1]
+		}
+		return name + " (" + qualifier + ")"
+	}
+	if t.Region != "" {
+		return name + " (" + t.Region + ")"
+	}
+	return name
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
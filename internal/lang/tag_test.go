@@ -0,0 +1,126 @@
+package lang
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want Tag
+	}{
+		{name: "language", in: "en", want: Tag{Language: "en"}},
+		{name: "script and region", in: "zh-Hant-TW", want: Tag{Language: "zh", Script: "Hant", Region: "TW"}},
+		{name: "region", in: "zh-TW", want: Tag{Language: "zh", Region: "TW"}},
+		{name: "numeric region", in: "es-419", want: Tag{Language: "es", Region: "419"}},
+		{name: "lower case", in: "zh-hant-tw", want: Tag{Language: "zh", Script: "Hant", Region: "TW"}},
+		{name: "upper case", in: "ZH-HANT", want: Tag{Language: "zh", Script: "Hant"}},
+		{name: "underscore", in: "zh_TW", want: Tag{Language: "zh", Region: "TW"}},
+		{name: "underscores and hyphens", in: "pt_br", want: Tag{Language: "pt", Region: "BR"}},
+		{name: "surrounding space", in: " en-US ", want: Tag{Language: "en", Region: "US"}},
+		{name: "ISO 639-2/T", in: "zho", want: Tag{Language: "zh"}},
+		{name: "ISO 639-2/B", in: "chi", want: Tag{Language: "zh"}},
+		{name: "ISO 639-2/B upper case", in: "CHI", want: Tag{Language: "zh"}},
+		{name: "ISO 639-2/B with a region", in: "chi_TW", want: Tag{Language: "zh", Region: "TW"}},
+		{name: "English name", in: "Chinese", want: Tag{Language: "zh"}},
+		{name: "no two-letter code", in: "yue-HK", want: Tag{Language: "yue", Region: "HK"}},
+		{name: "legacy zht", in: "zht", want: TraditionalChinese},
+		{name: "legacy cht upper case", in: "CHT", want: TraditionalChinese},
+		{name: "legacy chs", in: "chs", want: SimplifiedChinese},
+		{name: ".NET culture", in: "zh-CHT", want: TraditionalChinese},
+		{name: "OCR name", in: "chi_sim", want: SimplifiedChinese},
+		{name: "container name", in: "Traditional Chinese", want: TraditionalChinese},
+		{name: "unknown language", in: "XX", want: Tag{Language: "xx"}},
+		{name: "unknown subtags are dropped", in: "en-x-private", want: Tag{Language: "en"}},
+		{name: "separators only", in: "-_", want: Tag{}},
+		{name: "empty", in: "", want: Tag{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.in); got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagString(t *testing.T) {
+	tests := []struct {
+		tag  Tag
+		want string
+	}{
+		{tag: Tag{Language: "zh", Script: "Hant", Region: "TW"}, want: "zh-Hant-TW"},
+		{tag: Tag{Language: "pt", Region: "BR"}, want: "pt-BR"},
+		{tag: English, want: "en"},
+	}
+	for _, tt := range tests {
+		if got := tt.tag.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestInferredScript(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "zh-TW", want: "Hant"},
+		{in: "zh-HK", want: "Hant"},
+		{in: "zh-MO", want: "Hant"},
+		{in: "zh-CN", want: "Hans"},
+		{in: "zh-SG", want: "Hans"},
+		{in: "zh-Hans-TW", want: "Hans"},
+		{in: "zh", want: ""},
+		{in: "sr-Latn", want: "Latn"},
+		{in: "pt-BR", want: ""},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.in).InferredScript(); got != tt.want {
+			t.Errorf("Parse(%q).InferredScript() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		tag    string
+		target Tag
+		want   bool
+	}{
+		{tag: "zh-TW", target: TraditionalChinese, want: true},
+		{tag: "zh-Hant", target: TraditionalChinese, want: true},
+		{tag: "chi", target: TraditionalChinese, want: true},
+		{tag: "zh-CN", target: TraditionalChinese, want: false},
+		{tag: "chs", target: TraditionalChinese, want: false},
+		{tag: "en", target: TraditionalChinese, want: false},
+		{tag: "zh-HK", target: Tag{Language: "zh", Region: "TW"}, want: true},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.tag).Matches(tt.target); got != tt.want {
+			t.Errorf("Parse(%q).Matches(%v) = %v, want %v", tt.tag, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantName   string
+		wantNative string
+	}{
+		{in: "zh-TW", wantName: "Chinese (Traditional)", wantNative: "中文 (繁體)"},
+		{in: "zh-Hans", wantName: "Chinese (Simplified)", wantNative: "中文 (简体)"},
+		{in: "pt-BR", wantName: "Portuguese (BR)", wantNative: "Português (BR)"},
+		{in: "zh", wantName: "Chinese", wantNative: "中文"},
+		{in: "xx-YY", wantName: "xx-YY", wantNative: "xx-YY"},
+	}
+	for _, tt := range tests {
+		tag := Parse(tt.in)
+		if got := tag.DisplayName(); got != tt.wantName {
+			t.Errorf("Parse(%q).DisplayName() = %q, want %q", tt.in, got, tt.wantName)
+		}
+		if got := tag.NativeName(); got != tt.wantNative {
+			t.Errorf("Parse(%q).NativeName() = %q, want %q", tt.in, got, tt.wantNative)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package opensubtitles
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"subtitle-hunter/internal/subtitle"
+)
+
+// maxUnpackedSize caps how much an archive may unpack to, so a malformed or
+// hostile archive can't exhaust memory. Real subtitle files are far smaller.
+const maxUnpackedSize = 20 << 20
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// subtitleExtensions are the files taken from an archive.
+var subtitleExtensions = map[string]bool{".srt": true, ".ass": true, ".ssa": true}
+
+// ArchiveFile is one subtitle file out of a downloaded archive.
+type ArchiveFile struct {
+	// Name is the file's name inside the archive, or empty when the
+	// download was a single subtitle.
+	Name    string
+	Content []byte
+}
+
+// unpackSubtitle returns the subtitle in a downloaded file. Some download
+// links serve the subtitle gzipped or in a zip archive; gzip is
+// decompressed and from a zip the largest SRT or ASS file is taken.
+// Anything else is returned as it is.
+func unpackSubtitle(content []byte) ([]byte, error) {
+	files, err := UnpackArchive(content)
+	if err != nil {
+		return nil, err
+	}
+	return Largest(files).Content, nil
+}
+
+// UnpackArchive returns every subtitle file in a downloaded file: the SRT
+// and ASS files of a zip archive, such as a season pack with one file per
+// episode, or the file itself when it isn't an archive. Gzip is
+// decompressed first.
+func UnpackArchive(content []byte) ([]ArchiveFile, error) {
+	switch {
+	case bytes.HasPrefix(content, gzipMagic):
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip file: %w", err)
+		}
+		defer reader.Close()
+		unpacked, err := readLimited(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip file: %w", err)
+		}
+		// A gzipped zip is unusual but costs nothing to handle
+		return UnpackArchive(unpacked)
+
+	case bytes.HasPrefix(content, zipMagic):
+		return unzipSubtitles(content)
+	}
+	return []ArchiveFile{{Content: content}}, nil
+}
+
+// unzipSubtitles returns the subtitle files in a zip archive. Together they
+// may unpack to no more than maxUnpackedSize.
+func unzipSubtitles(content []byte) ([]ArchiveFile, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip file: %w", err)
+	}
+
+	var files []ArchiveFile
+	total := 0
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !subtitleExtensions[strings.ToLower(path.Ext(file.Name))] {
+			continue
+		}
+		unpacked, err := unzipFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if total += len(unpacked); total > maxUnpackedSize {
+			return nil, fmt.Errorf("zip file unpacks to more than %d MB", maxUnpackedSize>>20)
+		}
+		files = append(files, ArchiveFile{Name: file.Name, Content: unpacked})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("zip file contains no .srt or .ass subtitle")
+	}
+	return files, nil
+}
+
+func unzipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in zip file: %w", file.Name, err)
+	}
+	defer reader.Close()
+	unpacked, err := readLimited(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unzip %s: %w", file.Name, err)
+	}
+	return unpacked, nil
+}
+
+// Largest returns the largest of files, which must not be empty. When an
+// archive holds a subtitle and a few extras, the subtitle is the largest.
+func Largest(files []ArchiveFile) ArchiveFile {
+	largest := files[0]
+	for _, file := range files[1:] {
+		if len(file.Content) > len(largest.Content) {
+			largest = file
+		}
+	}
+	return largest
+}
+
+func readLimited(reader io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(reader, maxUnpackedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxUnpackedSize {
+		return nil, fmt.Errorf("unpacks to more than %d MB", maxUnpackedSize>>20)
+	}
+	return content, nil
+}
+
+// ToSRT converts an ASS script to SRT and leaves other content alone.
+func ToSRT(content []byte) ([]byte, error) {
+	if !subtitle.IsASS(content) {
+		return content, nil
+	}
+	entries, err := subtitle.ParseASS(content)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(subtitle.NewSRTParser().Format(entries)), nil
+}
@@ -0,0 +1,160 @@
+package opensubtitles
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSRT = "1\n00:00:01,000 --> 00:00:02,000\nHello\n"
+
+type testZipFile struct {
+	name    string
+	content string
+}
+
+func zipOf(t *testing.T, files ...testZipFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, file := range files {
+		entry, err := writer.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipOf(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUnpackArchive(t *testing.T) {
+	tooLarge := strings.Repeat("a", maxUnpackedSize+1)
+	half := strings.Repeat("a", maxUnpackedSize/2+1)
+	season := zipOf(t,
+		testZipFile{"Show/", ""},
+		testZipFile{"Show/Show.S01E01.srt", testSRT},
+		testZipFile{"Show/Show.S01E02.ASS", "[Script Info]\n"},
+		testZipFile{"Show/readme.txt", "not a subtitle"},
+	)
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    []ArchiveFile
+		wantErr string
+	}{
+		{
+			name:    "plain subtitle",
+			content: []byte(testSRT),
+			want:    []ArchiveFile{{Content: []byte(testSRT)}},
+		},
+		{
+			name:    "gzip",
+			content: gzipOf(t, []byte(testSRT)),
+			want:    []ArchiveFile{{Content: []byte(testSRT)}},
+		},
+		{
+			name:    "zip keeps only subtitles",
+			content: season,
+			want: []ArchiveFile{
+				{Name: "Show/Show.S01E01.srt", Content: []byte(testSRT)},
+				{Name: "Show/Show.S01E02.ASS", Content: []byte("[Script Info]\n")},
+			},
+		},
+		{
+			name:    "gzip-wrapped zip",
+			content: gzipOf(t, season),
+			want: []ArchiveFile{
+				{Name: "Show/Show.S01E01.srt", Content: []byte(testSRT)},
+				{Name: "Show/Show.S01E02.ASS", Content: []byte("[Script Info]\n")},
+			},
+		},
+		{
+			name:    "zip without subtitles",
+			content: zipOf(t, testZipFile{"readme.txt", "nothing here"}),
+			wantErr: "contains no .srt or .ass subtitle",
+		},
+		{
+			name:    "gzip over the cap",
+			content: gzipOf(t, []byte(tooLarge)),
+			wantErr: "more than 20 MB",
+		},
+		{
+			name:    "zip file over the cap",
+			content: zipOf(t, testZipFile{"big.srt", tooLarge}),
+			wantErr: "more than 20 MB",
+		},
+		{
+			name:    "zip files together over the cap",
+			content: zipOf(t, testZipFile{"a.srt", half}, testZipFile{"b.srt", half}),
+			wantErr: "more than 20 MB",
+		},
+		{
+			name:    "gzip-wrapped zip over the cap",
+			content: gzipOf(t, zipOf(t, testZipFile{"big.srt", tooLarge})),
+			wantErr: "more than 20 MB",
+		},
+		{
+			name:    "broken gzip",
+			content: append([]byte{}, gzipMagic...),
+			wantErr: "invalid gzip file",
+		},
+		{
+			name:    "broken zip",
+			content: append(append([]byte{}, zipMagic...), "garbage"...),
+			wantErr: "invalid zip file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnpackArchive(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("UnpackArchive() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnpackArchive(): %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnpackArchive() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnpackSubtitleTakesLargest(t *testing.T) {
+	content := zipOf(t,
+		testZipFile{"extra.srt", "1\n00:00:01,000 --> 00:00:02,000\nAd\n"},
+		testZipFile{"movie.srt", testSRT + "\n2\n00:00:03,000 --> 00:00:04,000\nWorld\n"},
+	)
+	got, err := unpackSubtitle(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "World") {
+		t.Errorf("unpackSubtitle() = %q, want the largest file", got)
+	}
+}
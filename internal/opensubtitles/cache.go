@@ -0,0 +1,180 @@
+package opensubtitles
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"subtitle-hunter/internal/store"
+)
+
+// searchStoreBucket holds search results kept across restarts.
+const searchStoreBucket = "search-results"
+
+// searchCache keeps search results for a short time and collapses
+// concurrent identical searches into a single API call, so processing a
+// whole season doesn't repeat the same show lookup for every episode.
+//
+// With a store set, results (including searches that found nothing) are
+// also kept there for a longer time, so scheduled runs don't ask again
+// every day for subtitles that didn't exist yesterday.
+type searchCache struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]cachedSearch
+	inflight map[string]*searchCall
+	store    *store.Store
+	storeTTL time.Duration
+}
+
+// storedSearch is a search result kept in the store.
+type storedSearch struct {
+	Subtitles []Subtitle `json:"subtitles"`
+	Expires   time.Time  `json:"expires"`
+}
+
+type cachedSearch struct {
+	subtitles []Subtitle
+	expires   time.Time
+}
+
+type searchCall struct {
+	done      chan struct{}
+	subtitles []Subtitle
+	err       error
+}
+
+func newSearchCache(ttl time.Duration) *searchCache {
+	return &searchCache{
+		ttl:      ttl,
+		entries:  make(map[string]cachedSearch),
+		inflight: make(map[string]*searchCall),
+	}
+}
+
+// setStore keeps search results in st for ttl, on top of the memory cache.
+// A nil store or a zero ttl keeps results in memory only. Expired results
+// are removed from the store.
+func (c *searchCache) setStore(st *store.Store, ttl time.Duration) {
+	c.mu.Lock()
+	c.store, c.storeTTL = st, ttl
+	c.mu.Unlock()
+
+	if st == nil {
+		return
+	}
+	keys, err := st.Keys(searchStoreBucket)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, key := range keys {
+		var stored storedSearch
+		if _, err := st.Get(searchStoreBucket, key, &stored); err == nil && now.Before(stored.Expires) {
+			continue
+		}
+		if err := st.Delete(searchStoreBucket, key); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// do returns the cached result for key, waits for an identical search that
+// is already running, or runs search itself. Errors are shared with waiting
+// callers but never cached. Callers get their own copy of the results.
+// Fresh searches skip the results kept in the store, but still replace
+// them.
+//
+// A waiting caller stops waiting when its own context is done. When the
+// caller running the search is cancelled, the callers still waiting run the
+// search again themselves instead of failing with its error.
+func (c *searchCache) do(ctx context.Context, key string, fresh bool, search func(ctx context.Context) ([]Subtitle, error)) ([]Subtitle, error) {
+	for {
+		c.mu.Lock()
+		if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expires) {
+			c.mu.Unlock()
+			return copySubtitles(entry.subtitles), nil
+		}
+		if subtitles, ok := c.storedLocked(key); ok && !fresh {
+			c.mu.Unlock()
+			return copySubtitles(subtitles), nil
+		}
+		call, ok := c.inflight[key]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if isContextError(call.err) && ctx.Err() == nil {
+			continue
+		}
+		return copySubtitles(call.subtitles), call.err
+	}
+
+	call := &searchCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.subtitles, call.err = search(ctx)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil && c.ttl > 0 {
+		c.pruneLocked()
+		c.entries[key] = cachedSearch{subtitles: call.subtitles, expires: time.Now().Add(c.ttl)}
+	}
+	st, storeTTL := c.store, c.storeTTL
+	c.mu.Unlock()
+	close(call.done)
+
+	if call.err == nil && st != nil && storeTTL > 0 {
+		stored := storedSearch{Subtitles: call.subtitles, Expires: time.Now().Add(storeTTL)}
+		if err := st.Put(searchStoreBucket, key, stored); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	return copySubtitles(call.subtitles), call.err
+}
+
+// storedLocked returns the unexpired result for key kept in the store.
+func (c *searchCache) storedLocked(key string) ([]Subtitle, bool) {
+	if c.store == nil || c.storeTTL <= 0 {
+		return nil, false
+	}
+	var stored storedSearch
+	found, err := c.store.Get(searchStoreBucket, key, &stored)
+	if err != nil || !found || !time.Now().Before(stored.Expires) {
+		return nil, false
+	}
+	return stored.Subtitles, true
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (c *searchCache) pruneLocked() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func copySubtitles(subtitles []Subtitle) []Subtitle {
+	if subtitles == nil {
+		return nil
+	}
+	return append([]Subtitle{}, subtitles...)
+}
@@ -2,22 +2,85 @@ package opensubtitles
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"subtitle-hunter/internal/httpclient"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/store"
+)
+
+const (
+	HearingImpairedInclude = "include"
+	HearingImpairedPrefer  = "prefer"
+	HearingImpairedAvoid   = "avoid"
+	HearingImpairedExclude = "exclude"
+	HearingImpairedOnly    = "only"
 )
 
+// How AI-translated and machine-translated results are treated.
+const (
+	TranslatedInclude = "include"
+	TranslatedAvoid   = "avoid"
+	TranslatedExclude = "exclude"
+)
+
+var ErrQuotaExceeded = errors.New("download quota exceeded")
+
+// ErrAPIKeyRejected is returned by Ping when OpenSubtitles doesn't accept
+// the API key.
+var ErrAPIKeyRejected = errors.New("API key rejected")
+
+var errNotFound = errors.New("no subtitles found")
+
 type Client struct {
 	APIKey string
-	client *http.Client
-	token  string
+	// Username and Password are optional; when set the client logs in so
+	// downloads count against that account's quota instead of the API key's.
+	Username string
+	Password string
+	// HearingImpaired controls how SDH subtitles are treated: "include",
+	// "exclude" and "only" are passed to the API, "prefer" and "avoid" only
+	// reorder the results.
+	HearingImpaired string
+	// AITranslated and MachineTranslated control how subtitles flagged as
+	// AI-translated or machine-translated are treated: "include", "avoid"
+	// (rank them below subtitles made by people) or "exclude". Searches
+	// always ask for them, so the filter works on cached results too.
+	AITranslated      string
+	MachineTranslated string
+	// Weights rank search results; LanguageWeights replace them for single
+	// languages, keyed by OpenSubtitles language code.
+	Weights         Weights
+	LanguageWeights map[string]Weights
+	client          *http.Client
+	token           string
+
+	mu        sync.Mutex
+	used      int
+	remaining int
+	resetAt   time.Time
+
+	cache *searchCache
 }
 
+// DefaultSearchCacheTTL is how long identical searches are answered from
+// memory unless SetSearchCacheTTL says otherwise.
+const DefaultSearchCacheTTL = 5 * time.Minute
+
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -35,6 +98,106 @@ type Subtitle struct {
 	MovieBytes int64  `json:"moviebytesize"`
 	FileName   string `json:"filename"`
 	URL        string `json:"url"`
+	HearingImpaired bool `json:"hearing_impaired"`
+	// ForeignPartsOnly marks forced subtitles, which only cover dialogue
+	// in a language other than the film's.
+	ForeignPartsOnly bool `json:"foreign_parts_only"`
+	Release       string `json:"release"`
+	DownloadCount int    `json:"download_count"`
+	// HashMatch is set when the subtitle was matched to the hash of the
+	// video searched for.
+	HashMatch   bool    `json:"moviehash_match"`
+	Rating      float64 `json:"ratings"`
+	FromTrusted bool    `json:"from_trusted"`
+	// AITranslated and MachineTranslated are set when OpenSubtitles flags
+	// the subtitle as translated by AI or by a machine.
+	AITranslated      bool `json:"ai_translated,omitempty"`
+	MachineTranslated bool `json:"machine_translated,omitempty"`
+	// Score is the subtitle's rank when it was picked from search results.
+	Score float64 `json:"score,omitempty"`
+	// Files lists every file of the upload. FileID and FileName refer to the
+	// one that will be downloaded, normally the first.
+	Files []SubtitleFile `json:"files"`
+}
+
+type SubtitleFile struct {
+	FileID   int    `json:"file_id"`
+	FileName string `json:"file_name"`
+}
+
+// searchParams are the filters of a single search request. Type, Year,
+// ParentIMDbID, Season, Episode and MovieHash are only sent when set;
+// Forced asks for foreign-parts-only subtitles. Fresh skips results kept
+// in the store.
+type searchParams struct {
+	Query    string
+	IMDbID   string
+	Language string
+	// Type is "movie" or "episode".
+	Type string
+	Year int
+	// ParentIMDbID is the IMDb ID of the series an episode belongs to.
+	ParentIMDbID string
+	Season       int
+	Episode      int
+	Forced       bool
+	MovieHash    string
+	Fresh        bool
+}
+
+func (p searchParams) key(hearingImpaired string) string {
+	return strings.Join([]string{p.IMDbID, strings.ToLower(p.Query), p.Type, strconv.Itoa(p.Year), p.ParentIMDbID, strconv.Itoa(p.Season), strconv.Itoa(p.Episode), p.Language, strconv.FormatBool(p.Forced), p.MovieHash, hearingImpaired}, "|")
+}
+
+// values returns the query parameters of the search request.
+func (p searchParams) values(hearingImpaired string) url.Values {
+	params := url.Values{}
+	if p.IMDbID != "" {
+		params.Add("imdb_id", p.IMDbID)
+	}
+	if p.Query != "" {
+		params.Add("query", p.Query)
+	}
+	if p.Type != "" {
+		params.Add("type", p.Type)
+	}
+	if p.Year > 0 {
+		params.Add("year", strconv.Itoa(p.Year))
+	}
+	if p.ParentIMDbID != "" {
+		params.Add("parent_imdb_id", p.ParentIMDbID)
+	}
+	if p.Season > 0 {
+		params.Add("season_number", strconv.Itoa(p.Season))
+	}
+	if p.Episode > 0 {
+		params.Add("episode_number", strconv.Itoa(p.Episode))
+	}
+	params.Add("languages", p.Language)
+	if p.Forced {
+		params.Add("foreign_parts_only", "only")
+	}
+	if p.MovieHash != "" {
+		params.Add("moviehash", p.MovieHash)
+	}
+	switch hearingImpaired {
+	case HearingImpairedExclude, HearingImpairedOnly:
+		params.Add("hearing_impaired", hearingImpaired)
+	}
+	// The API leaves machine-translated subtitles out unless asked; they are
+	// filtered here instead, by Client.humanMade
+	params.Add("ai_translated", "include")
+	params.Add("machine_translated", "include")
+	return params
+}
+
+// String describes the search for logs, as the parameters it sends.
+func (p searchParams) String() string {
+	encoded := p.values("").Encode()
+	if query, err := url.QueryUnescape(encoded); err == nil {
+		return query
+	}
+	return encoded
 }
 
 type SearchResponse struct {
@@ -45,40 +208,100 @@ type SearchResponse struct {
 			SubtitleID string `json:"subtitle_id"`
 			Language   string `json:"language"`
 			URL        string `json:"url"`
-			Files      []struct {
-				FileID   int    `json:"file_id"`
-				FileName string `json:"file_name"`
-			} `json:"files"`
+			Files      []SubtitleFile `json:"files"`
 			MovieHash string `json:"moviehash"`
 			Release   string `json:"release"`
+			DownloadCount int `json:"download_count"`
+			HearingImpaired bool `json:"hearing_impaired"`
+			ForeignPartsOnly bool `json:"foreign_parts_only"`
+			MovieHashMatch bool `json:"moviehash_match"`
+			Ratings float64 `json:"ratings"`
+			FromTrusted bool `json:"from_trusted"`
+			AITranslated bool `json:"ai_translated"`
+			MachineTranslated bool `json:"machine_translated"`
 		} `json:"attributes"`
 	} `json:"data"`
 }
 
 type DownloadResponse struct {
-	Link string `json:"link"`
+	Link         string `json:"link"`
+	Requests     int    `json:"requests"`
+	Remaining    int    `json:"remaining"`
+	ResetTimeUTC string `json:"reset_time_utc"`
 }
 
 func NewClient(apiKey string) *Client {
 	return &Client{
-		APIKey: apiKey,
-		client: &http.Client{},
+		APIKey:            apiKey,
+		HearingImpaired:   HearingImpairedInclude,
+		AITranslated:      TranslatedInclude,
+		MachineTranslated: TranslatedExclude,
+		Weights:           DefaultWeights,
+		client:            httpclient.Default.Client(),
+		used:              -1,
+		remaining:         -1,
+		cache:             newSearchCache(DefaultSearchCacheTTL),
 	}
 }
 
-func (c *Client) SearchSubtitles(movieName string, imdbID string, language string) ([]Subtitle, error) {
-	searchURL := "https://api.opensubtitles.com/api/v1/subtitles"
-	
-	params := url.Values{}
-	if imdbID != "" {
-		params.Add("imdb_id", imdbID)
+// SetSearchCacheTTL changes how long search results are cached. Zero
+// disables caching; concurrent identical searches are still deduplicated.
+// Call it before SetSearchStore.
+func (c *Client) SetSearchCacheTTL(ttl time.Duration) {
+	c.cache = newSearchCache(ttl)
+}
+
+// SetSearchStore keeps search results in st for ttl, so they survive a
+// restart and outlast the memory cache. Zero turns it off.
+func (c *Client) SetSearchStore(st *store.Store, ttl time.Duration) {
+	c.cache.setStore(st, ttl)
+}
+
+// LanguageCode maps a language tag to the code the OpenSubtitles API
+// expects. Chinese scripts and Portuguese variants carry a region, every
+// other language uses its ISO 639-1 code.
+func LanguageCode(tag lang.Tag) string {
+	tag = tag.Normalize()
+	switch tag.Language {
+	case "zh":
+		if tag.InferredScript() == "Hans" {
+			return "zh-CN"
+		}
+		return "zh-TW"
+	case "pt":
+		if tag.Region == "BR" {
+			return "pt-BR"
+		}
+		return "pt-PT"
 	}
-	if movieName != "" {
-		params.Add("query", movieName)
+	return tag.Language
+}
+
+// SearchSubtitles searches for subtitles, answering repeated identical
+// queries from the memory cache. Results kept in the store are skipped,
+// since the user asked to search now.
+func (c *Client) SearchSubtitles(ctx context.Context, movieName string, imdbID string, language string) ([]Subtitle, error) {
+	return c.search(ctx, searchParams{Query: movieName, IMDbID: imdbID, Language: language, Fresh: true})
+}
+
+func (c *Client) search(ctx context.Context, p searchParams) ([]Subtitle, error) {
+	searched := false
+	subtitles, err := c.cache.do(ctx, p.key(c.HearingImpaired), p.Fresh, func(ctx context.Context) ([]Subtitle, error) {
+		searched = true
+		return c.searchSubtitles(ctx, p)
+	})
+	if err == nil && !searched {
+		jobs.FromContext(ctx).Logf("OpenSubtitles search %s: %d results, from the cache", p, len(subtitles))
 	}
-	params.Add("languages", language)
+	return subtitles, err
+}
+
+func (c *Client) searchSubtitles(ctx context.Context, p searchParams) ([]Subtitle, error) {
+	searchURL := "https://api.opensubtitles.com/api/v1/subtitles"
 	
-	req, err := http.NewRequest("GET", searchURL+"?"+params.Encode(), nil)
+	params := p.values(c.HearingImpaired)
+	
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL+"?"+params.Encode(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -100,8 +323,10 @@ func (c *Client) SearchSubtitles(movieName string, imdbID string, language strin
 	
 	var searchResp SearchResponse
 	if err := json.Unmarshal(body, &searchResp); err != nil {
+		jobs.FromContext(ctx).Logf("OpenSubtitles search %s: HTTP %d, %d bytes that aren't a search result", p, resp.StatusCode, len(body))
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	jobs.FromContext(ctx).Logf("OpenSubtitles search %s: HTTP %d, %d results", p, resp.StatusCode, len(searchResp.Data))
 	
 	var subtitles []Subtitle
 	for _, item := range searchResp.Data {
@@ -109,6 +334,16 @@ func (c *Client) SearchSubtitles(movieName string, imdbID string, language strin
 			ID:       item.Attributes.SubtitleID,
 			Language: item.Attributes.Language,
 			URL:      item.Attributes.URL,
+			HearingImpaired: item.Attributes.HearingImpaired,
+			ForeignPartsOnly: item.Attributes.ForeignPartsOnly,
+			Release:  item.Attributes.Release,
+			DownloadCount: item.Attributes.DownloadCount,
+			HashMatch: item.Attributes.MovieHashMatch,
+			Rating: item.Attributes.Ratings,
+			FromTrusted: item.Attributes.FromTrusted,
+			AITranslated: item.Attributes.AITranslated,
+			MachineTranslated: item.Attributes.MachineTranslated,
+			Files:    item.Attributes.Files,
 		}
 		
 		if len(item.Attributes.Files) > 0 {
@@ -122,7 +357,61 @@ func (c *Client) SearchSubtitles(movieName string, imdbID string, language strin
 	return subtitles, nil
 }
 
-func (c *Client) DownloadSubtitle(subtitle *Subtitle) ([]byte, error) {
+// DownloadSubtitle requests a download link and fetches the file. Truncated
+// or corrupt transfers are retried, and if the link keeps failing a fresh
+// link is requested once before giving up. Zipped, gzipped and ASS files are
+// returned as plain SRT.
+func (c *Client) DownloadSubtitle(ctx context.Context, subtitle *Subtitle) ([]byte, error) {
+	content, err := c.DownloadOriginalSubtitle(ctx, subtitle)
+	if err != nil {
+		return nil, err
+	}
+	srt, err := ToSRT(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert subtitle file %d: %w", subtitle.FileID, err)
+	}
+	return srt, nil
+}
+
+// DownloadOriginalSubtitle downloads like DownloadSubtitle, but returns ASS
+// and SSA scripts as they are.
+func (c *Client) DownloadOriginalSubtitle(ctx context.Context, subtitle *Subtitle) ([]byte, error) {
+	content, err := c.DownloadFile(ctx, subtitle)
+	if err != nil {
+		return nil, err
+	}
+	unpacked, err := unpackSubtitle(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack subtitle file %d: %w", subtitle.FileID, err)
+	}
+	return unpacked, nil
+}
+
+// DownloadFile downloads the file as it is served, which may be a zip or
+// gzip archive; see UnpackArchive.
+func (c *Client) DownloadFile(ctx context.Context, subtitle *Subtitle) ([]byte, error) {
+	var lastErr error
+	for link := 0; link < linkAttempts; link++ {
+		downloadResp, err := c.requestDownload(ctx, subtitle)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := c.fetchFile(ctx, downloadResp.Link)
+		if err == nil {
+			return content, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		jobs.FromContext(ctx).Logf("Subtitle file %d could not be fetched from its download link: %v", subtitle.FileID, err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to download file: %w", lastErr)
+}
+
+// requestDownload asks the download API for a temporary file link.
+func (c *Client) requestDownload(ctx context.Context, subtitle *Subtitle) (*DownloadResponse, error) {
 	downloadURL := fmt.Sprintf("https://api.opensubtitles.com/api/v1/download")
 	
 	reqBody := map[string]interface{}{
@@ -137,7 +426,7 @@ func (c *Client) DownloadSubtitle(subtitle *Subtitle) ([]byte, error) {
 	
 	log.Printf("DEBUG: Download request body: %s", string(jsonBody))
 	
-	req, err := http.NewRequest("POST", downloadURL, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", downloadURL, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -146,6 +435,13 @@ func (c *Client) DownloadSubtitle(subtitle *Subtitle) ([]byte, error) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", "subtitle-hunter v1.0")
+	if c.Username != "" {
+		token, err := c.login(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 	
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -160,6 +456,18 @@ func (c *Client) DownloadSubtitle(subtitle *Subtitle) ([]byte, error) {
 	
 	fmt.Printf("DEBUG: Download response (status %d): %s\n", resp.StatusCode, string(body))
 	
+	if resp.StatusCode == http.StatusNotAcceptable || resp.StatusCode == http.StatusTooManyRequests {
+		c.markExhausted(body)
+		return nil, fmt.Errorf("%w (status %d): %s", ErrQuotaExceeded, resp.StatusCode, string(body))
+	}
+	
+	if resp.StatusCode == http.StatusUnauthorized && c.Username != "" {
+		// Force a fresh login on the next attempt
+		c.mu.Lock()
+		c.token = ""
+		c.mu.Unlock()
+	}
+	
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("download API error (status %d): %s", resp.StatusCode, string(body))
 	}
@@ -168,30 +476,267 @@ func (c *Client) DownloadSubtitle(subtitle *Subtitle) ([]byte, error) {
 	if err := json.Unmarshal(body, &downloadResp); err != nil {
 		return nil, fmt.Errorf("failed to parse download response (body: %s): %w", string(body), err)
 	}
+	c.updateQuota(downloadResp.Requests, downloadResp.Remaining, downloadResp.ResetTimeUTC)
 	
-	fileResp, err := c.client.Get(downloadResp.Link)
+	return &downloadResp, nil
+}
+
+// Ping checks that the OpenSubtitles API is reachable and accepts the API
+// key. It does not count against the download quota.
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.opensubtitles.com/api/v1/infos/formats", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download file: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Api-Key", c.APIKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "subtitle-hunter v1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach OpenSubtitles: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w (status %d)", ErrAPIKeyRejected, resp.StatusCode)
+	default:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
-	defer fileResp.Body.Close()
-	
-	return io.ReadAll(fileResp.Body)
 }
 
-func (c *Client) FindBestSubtitle(movieName string, language string) (*Subtitle, error) {
-	subtitles, err := c.SearchSubtitles(movieName, "", language)
+// login exchanges the account credentials for a token, reusing a token
+// obtained earlier.
+func (c *Client) login(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" {
+		return c.token, nil
+	}
+
+	jsonBody, err := json.Marshal(LoginRequest{Username: c.Username, Password: c.Password})
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to marshal login request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.opensubtitles.com/api/v1/login", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Api-Key", c.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "subtitle-hunter v1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("login failed (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var loginResp LoginResponse
+	if err := json.Unmarshal(body, &loginResp); err != nil {
+		return "", fmt.Errorf("failed to parse login response: %w", err)
+	}
+
+	c.token = loginResp.Token
+	return c.token, nil
+}
+
+func (c *Client) updateQuota(used, remaining int, resetTime string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.used = used
+	c.remaining = remaining
+	if parsed, err := time.Parse(time.RFC3339, resetTime); err == nil {
+		c.resetAt = parsed
+	}
+}
+
+func (c *Client) markExhausted(body []byte) {
+	var quotaResp DownloadResponse
+	json.Unmarshal(body, &quotaResp)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remaining = 0
+	if quotaResp.Requests > 0 {
+		c.used = quotaResp.Requests
+	}
+	if parsed, err := time.Parse(time.RFC3339, quotaResp.ResetTimeUTC); err == nil {
+		c.resetAt = parsed
+	} else {
+		c.resetAt = time.Now().Add(24 * time.Hour)
+	}
+}
+
+// Quota returns the remaining downloads reported by the last download (-1
+// if unknown) and when the quota resets.
+func (c *Client) Quota() (int, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remaining, c.resetAt
+}
+
+// Used returns the downloads counted against the quota in the current
+// period, as reported by the last download (-1 if unknown).
+func (c *Client) Used() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.used
+}
+
+// Exhausted reports whether the download quota is used up and has not reset yet.
+func (c *Client) Exhausted() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remaining == 0 && time.Now().Before(c.resetAt)
+}
+
+// Movie identifies a movie, or any video that isn't a known episode, to
+// search for.
+type Movie struct {
+	Title string
+	// Year and IMDbID, the IMDb ID as digits without leading zeros, are
+	// sent as search filters when known.
+	Year   int
+	IMDbID string
+}
+
+// String returns the movie's free-text query, its title and year.
+func (m Movie) String() string {
+	if m.Year > 0 {
+		return fmt.Sprintf("%s %d", m.Title, m.Year)
+	}
+	return m.Title
+}
+
+// FindBestSubtitle searches for a movie's subtitle. When its IMDb ID or
+// year is known, the search filters by them and by type, which keeps other
+// movies with similar titles out; the plain text query is only used when
+// that finds nothing, as it does for movies OpenSubtitles has filed wrongly.
+func (c *Client) FindBestSubtitle(ctx context.Context, movie Movie, language string, forced bool, video Video) (*Subtitle, error) {
+	var subtitles []Subtitle
+	if movie.IMDbID != "" || movie.Year > 0 {
+		p := searchParams{Type: "movie", IMDbID: movie.IMDbID, Year: movie.Year, Language: language, Forced: forced, MovieHash: video.Hash}
+		if movie.IMDbID == "" {
+			p.Query = movie.Title
+		}
+		var err error
+		subtitles, err = c.search(ctx, p)
+		if err != nil {
+			return nil, err
+		}
 	}
-	
 	if len(subtitles) == 0 {
-		return nil, fmt.Errorf("no subtitles found")
+		var err error
+		subtitles, err = c.search(ctx, searchParams{Query: movie.String(), Language: language, Forced: forced, MovieHash: video.Hash})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.best(ctx, subtitles, language, forced, video)
+}
+
+// loggedCandidates is how many of the best-scoring search results are
+// listed in the job log.
+const loggedCandidates = 10
+
+// best picks the subtitle to download from search results, the one that
+// scores highest under the weights for language. Forced searches only
+// accept forced subtitles; other searches prefer full ones whatever their
+// score.
+func (c *Client) best(ctx context.Context, subtitles []Subtitle, language string, forced bool, video Video) (*Subtitle, error) {
+	if forced {
+		subtitles = forcedOnly(subtitles)
+	}
+	if kept := c.humanMade(subtitles); len(kept) < len(subtitles) {
+		jobs.FromContext(ctx).Logf("Left out %d AI- or machine-translated results", len(subtitles)-len(kept))
+		subtitles = kept
+	}
+	if kept := notExcluded(subtitles, video.Exclude); len(kept) < len(subtitles) {
+		jobs.FromContext(ctx).Logf("Left out %d subtitles rejected for this video before", len(subtitles)-len(kept))
+		subtitles = kept
+	}
+	if len(subtitles) == 0 {
+		return nil, errNotFound
+	}
+
+	subtitles = append([]Subtitle{}, subtitles...)
+	c.rank(subtitles, language, video)
+	if !forced {
+		sort.SliceStable(subtitles, func(i, j int) bool {
+			return !subtitles[i].ForeignPartsOnly && subtitles[j].ForeignPartsOnly
+		})
 	}
 	
-	log.Printf("DEBUG: Found %d subtitles, using first one with ID: %s, FileID: %d", len(subtitles), subtitles[0].ID, subtitles[0].FileID)
+	job := jobs.FromContext(ctx)
+	for i, subtitle := range subtitles[:min(len(subtitles), loggedCandidates)] {
+		job.Detailf("Candidate %d: subtitle %s (%s, %d downloads, rating %.1f, hash match %t) scored %.1f", i+1, subtitle.ID, subtitle.Release, subtitle.DownloadCount, subtitle.Rating, subtitle.HashMatch, subtitle.Score)
+	}
+	job.Logf("DEBUG: Found %d subtitles, using first one with ID: %s, FileID: %d, score %.1f", len(subtitles), subtitles[0].ID, subtitles[0].FileID, subtitles[0].Score)
 	return &subtitles[0], nil
 }
 
+// humanMade leaves out the AI- and machine-translated subtitles the client
+// is set to exclude.
+func (c *Client) humanMade(subtitles []Subtitle) []Subtitle {
+	var kept []Subtitle
+	for _, subtitle := range subtitles {
+		if (subtitle.AITranslated && c.AITranslated == TranslatedExclude) ||
+			(subtitle.MachineTranslated && c.MachineTranslated == TranslatedExclude) {
+			continue
+		}
+		kept = append(kept, subtitle)
+	}
+	return kept
+}
+
+// notExcluded leaves out the subtitles whose IDs are in excluded.
+func notExcluded(subtitles []Subtitle, excluded []string) []Subtitle {
+	if len(excluded) == 0 {
+		return subtitles
+	}
+	skip := make(map[string]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+	var kept []Subtitle
+	for _, subtitle := range subtitles {
+		if !skip[subtitle.ID] {
+			kept = append(kept, subtitle)
+		}
+	}
+	return kept
+}
+
+func forcedOnly(subtitles []Subtitle) []Subtitle {
+	var forced []Subtitle
+	for _, subtitle := range subtitles {
+		if subtitle.ForeignPartsOnly {
+			forced = append(forced, subtitle)
+		}
+	}
+	return forced
+}
+
 func extractMovieName(videoPath string) string {
 	fileName := filepath.Base(videoPath)
 	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
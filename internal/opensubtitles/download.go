@@ -0,0 +1,124 @@
+package opensubtitles
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"subtitle-hunter/internal/jobs"
+)
+
+const (
+	// fetchAttempts is how often a single download link is tried.
+	fetchAttempts = 3
+	// linkAttempts is how many download links are requested per subtitle.
+	// Every link counts against the download quota.
+	linkAttempts = 2
+)
+
+var (
+	errIncomplete = errors.New("incomplete subtitle download")
+	// errLinkRejected means the link itself is no good (expired, missing), so
+	// retrying it is pointless and a fresh link is needed.
+	errLinkRejected = errors.New("download link rejected")
+)
+
+// A plain 32-hex-digit ETag, as set by S3-style storage, is the file's MD5.
+var md5ETagPattern = regexp.MustCompile(`^"?([0-9a-fA-F]{32})"?$`)
+
+// fetchFile downloads the file behind a download link, verifying it against
+// Content-Length and any checksum the server provides and retrying with a
+// short backoff when it comes back incomplete.
+func (c *Client) fetchFile(ctx context.Context, link string) ([]byte, error) {
+	var lastErr error
+	for attempt := 1; attempt <= fetchAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(time.Duration(attempt-1) * time.Second):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		content, err := c.fetchFileOnce(ctx, link)
+		if err == nil {
+			return content, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		jobs.FromContext(ctx).Logf("Subtitle file fetch attempt %d/%d failed: %v", attempt, fetchAttempts, err)
+		lastErr = err
+		if errors.Is(err, errLinkRejected) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) fetchFileOnce(ctx context.Context, link string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return nil, fmt.Errorf("%w: file server returned status %d", errLinkRejected, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("file server returned status %d", resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errIncomplete, err)
+	}
+
+	if err := verifyContent(resp, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// verifyContent checks the body against the length and checksum headers.
+// Checks are skipped when the transport decompressed the body, since the
+// headers then describe the compressed bytes.
+func verifyContent(resp *http.Response, content []byte) error {
+	if len(content) == 0 {
+		return fmt.Errorf("%w: empty body", errIncomplete)
+	}
+	if resp.Uncompressed {
+		return nil
+	}
+
+	if resp.ContentLength >= 0 && int64(len(content)) != resp.ContentLength {
+		return fmt.Errorf("%w: got %d of %d bytes", errIncomplete, len(content), resp.ContentLength)
+	}
+
+	sum := md5.Sum(content)
+	if header := resp.Header.Get("Content-MD5"); header != "" {
+		if expected, err := base64.StdEncoding.DecodeString(header); err == nil && string(expected) != string(sum[:]) {
+			return fmt.Errorf("%w: Content-MD5 mismatch", errIncomplete)
+		}
+	}
+	if match := md5ETagPattern.FindStringSubmatch(resp.Header.Get("ETag")); match != nil {
+		if hex.EncodeToString(sum[:]) != strings.ToLower(match[1]) {
+			return fmt.Errorf("%w: ETag checksum mismatch", errIncomplete)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package opensubtitles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"subtitle-hunter/internal/jobs"
+)
+
+// Strategy is one way of searching for a TV episode's subtitle.
+type Strategy string
+
+const (
+	// StrategyEpisodeQuery searches for "Series S01E02".
+	StrategyEpisodeQuery Strategy = "episode-query"
+	// StrategyEpisodeNumbers searches for the series name and passes the
+	// season and episode numbers as filters.
+	StrategyEpisodeNumbers Strategy = "episode-numbers"
+	// StrategySeasonPack searches for uploads covering the whole season and
+	// picks the episode's file out of the pack.
+	StrategySeasonPack Strategy = "season-pack"
+	// StrategyAbsolute searches for "Series 25" style queries using the
+	// absolute episode number, under every known title of the series. Anime
+	// is usually released and indexed this way.
+	StrategyAbsolute Strategy = "absolute-number"
+)
+
+// Strategies is the order strategies are tried in when nothing is known
+// about a series yet.
+var Strategies = []Strategy{StrategyEpisodeQuery, StrategyEpisodeNumbers, StrategySeasonPack, StrategyAbsolute}
+
+// Episode identifies a TV episode to search for.
+type Episode struct {
+	Series string
+	Season int
+	Number int
+	// Absolute is the episode number counted from the start of the series,
+	// or 0 when absolute numbering shouldn't be tried.
+	Absolute int
+	// Titles are other names the series may be listed under, such as a
+	// romanized original title.
+	Titles []string
+	// ParentIMDbID is the series' IMDb ID as digits without leading zeros,
+	// if known. The episode-numbers and season-pack strategies then search
+	// by it instead of by name, and the episode-numbers strategy is tried
+	// first.
+	ParentIMDbID string
+}
+
+func (e Episode) String() string {
+	return fmt.Sprintf("%s S%02dE%02d", e.Series, e.Season, e.Number)
+}
+
+// Hint remembers which strategy and instance last found a subtitle for a
+// series, so the next episode tries them first.
+type Hint struct {
+	Strategy Strategy `json:"strategy"`
+	Provider string   `json:"provider"`
+}
+
+// FindEpisodeSubtitle searches for an episode's subtitle, trying the hinted
+// strategy and instance before the others. It returns the hint that
+// describes what worked. A strategy that comes back empty is not repeated
+// on other instances, since they all search the same catalogue. Forced
+// searches look for foreign-parts-only subtitles. Results are ranked
+// against video.
+func (r *Registry) FindEpisodeSubtitle(ctx context.Context, episode Episode, language string, forced bool, video Video, hint Hint) (*Subtitle, Hint, error) {
+	instances := moveToFront(r.available(), func(instance *Instance) bool {
+		return instance.Name == hint.Provider
+	})
+	strategies := Strategies
+	if episode.ParentIMDbID != "" {
+		strategies = moveToFront(strategies, func(strategy Strategy) bool {
+			return strategy == StrategyEpisodeNumbers
+		})
+	}
+	strategies = moveToFront(strategies, func(strategy Strategy) bool {
+		return strategy == hint.Strategy
+	})
+
+	lastErr := errNotFound
+	for _, strategy := range strategies {
+		for _, instance := range instances {
+			subtitle, err := instance.Client.findEpisode(ctx, strategy, episode, language, forced, video)
+			if err == nil {
+				jobs.FromContext(ctx).Logf("Found %s subtitle for %s with %s strategy via instance %s", language, episode, strategy, instance.Name)
+				return subtitle, Hint{Strategy: strategy, Provider: instance.Name}, nil
+			}
+			if ctx.Err() != nil {
+				return nil, Hint{}, ctx.Err()
+			}
+			if errors.Is(err, errNotFound) {
+				break
+			}
+			jobs.FromContext(ctx).Logf("OpenSubtitles instance %s search failed: %v", instance.Name, err)
+			lastErr = err
+		}
+	}
+	return nil, Hint{}, lastErr
+}
+
+// seriesParams are the search filters naming the episode's series: its
+// IMDb ID when known, its name otherwise.
+func (e Episode) seriesParams(language string, forced bool, video Video) searchParams {
+	p := searchParams{Type: "episode", ParentIMDbID: e.ParentIMDbID, Language: language, Forced: forced, MovieHash: video.Hash}
+	if e.ParentIMDbID == "" {
+		p.Query = e.Series
+	}
+	return p
+}
+
+func (c *Client) findEpisode(ctx context.Context, strategy Strategy, episode Episode, language string, forced bool, video Video) (*Subtitle, error) {
+	switch strategy {
+	case StrategyEpisodeQuery:
+		subtitles, err := c.search(ctx, searchParams{Query: episode.String(), Type: "episode", Language: language, Forced: forced, MovieHash: video.Hash})
+		if err != nil {
+			return nil, err
+		}
+		return c.best(ctx, subtitles, language, forced, video)
+	case StrategyEpisodeNumbers:
+		p := episode.seriesParams(language, forced, video)
+		p.Season, p.Episode = episode.Season, episode.Number
+		subtitles, err := c.search(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		return c.best(ctx, subtitles, language, forced, video)
+	case StrategySeasonPack:
+		p := episode.seriesParams(language, forced, video)
+		p.Season = episode.Season
+		subtitles, err := c.search(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		return c.best(ctx, filesForEpisode(subtitles, episode), language, forced, video)
+	case StrategyAbsolute:
+		return c.findAbsolute(ctx, episode, language, forced, video)
+	}
+	return nil, fmt.Errorf("unknown search strategy %q", strategy)
+}
+
+// filesForEpisode returns the uploads holding a file named after the
+// episode, each narrowed down to that file. A multi-episode file such as
+// S01E01E02 is named after its first episode, as Jellyfin numbers the
+// video.
+func filesForEpisode(subtitles []Subtitle, episode Episode) []Subtitle {
+	pattern := regexp.MustCompile(fmt.Sprintf(`(?i)(s0*%d[ ._-]*e0*%d|\b0*%dx0*%d)(\D|$)`,
+		episode.Season, episode.Number, episode.Season, episode.Number))
+	return filesMatching(subtitles, pattern)
+}
+
+// filesMatching returns the uploads holding a file whose name matches
+// pattern, each narrowed down to that file.
+func filesMatching(subtitles []Subtitle, pattern *regexp.Regexp) []Subtitle {
+	var matches []Subtitle
+	for _, subtitle := range subtitles {
+		for _, file := range subtitle.Files {
+			if pattern.MatchString(file.FileName) {
+				subtitle.FileID = file.FileID
+				subtitle.FileName = file.FileName
+				matches = append(matches, subtitle)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// episodeNamePattern matches the season and episode numbers in a file name
+// written as S01E02 or 1x02.
+var episodeNamePattern = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?:s(\d{1,2})[ ._-]*e(\d{1,3})|(\d{1,2})x(\d{2,3}))(?:\D|$)`)
+
+// EpisodeInName returns the season and episode numbers a subtitle file is
+// named after, such as 1 and 2 for "Show.S01E02.srt" or "Show 1x02.srt",
+// and the first episode of a multi-episode file. Folders in name are
+// ignored.
+func EpisodeInName(name string) (season, number int, ok bool) {
+	match := episodeNamePattern.FindStringSubmatch(path.Base(name))
+	if match == nil {
+		return 0, 0, false
+	}
+	if match[1] == "" {
+		match = []string{match[0], match[3], match[4]}
+	}
+	season, _ = strconv.Atoi(match[1])
+	number, _ = strconv.Atoi(match[2])
+	return season, number, true
+}
+
+// findAbsolute tries each title of the series with a couple of query forms
+// for the absolute episode number. Results must name the episode number in
+// the file name, since a loose title search also returns other episodes.
+func (c *Client) findAbsolute(ctx context.Context, episode Episode, language string, forced bool, video Video) (*Subtitle, error) {
+	if episode.Absolute <= 0 {
+		return nil, errNotFound
+	}
+
+	pattern := absoluteNumberPattern(episode.Absolute)
+
+	var queries []string
+	seen := make(map[string]bool)
+	for _, title := range append([]string{episode.Series}, episode.Titles...) {
+		for _, format := range []string{"%s %02d", "%s %d"} {
+			query := fmt.Sprintf(format, title, episode.Absolute)
+			if key := strings.ToLower(query); title != "" && !seen[key] {
+				seen[key] = true
+				queries = append(queries, query)
+			}
+		}
+	}
+
+	for _, query := range queries {
+		subtitles, err := c.search(ctx, searchParams{Query: query, Type: "episode", Language: language, Forced: forced, MovieHash: video.Hash})
+		if err != nil {
+			return nil, err
+		}
+		if matches := filesMatching(subtitles, pattern); len(matches) > 0 {
+			jobs.FromContext(ctx).Logf("Absolute-number query %q matched %d subtitles", query, len(matches))
+			return c.best(ctx, matches, language, forced, video)
+		}
+	}
+	return nil, errNotFound
+}
+
+// absoluteNumberPattern matches file names carrying the absolute episode
+// number as a number of its own, such as "Show - 25 [1080p].ass",
+// "Show_025v2.srt" or "Show E25.srt".
+func absoluteNumberPattern(number int) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`(?i)(^|[\s._\-\[(#e])0*%d(v\d)?([\s._\-\])]|$)`, number))
+}
+
+// moveToFront returns a copy of items with the first match moved to the
+// front, or items unchanged if nothing matches.
+func moveToFront[T any](items []T, match func(T) bool) []T {
+	for i, item := range items {
+		if match(item) {
+			ordered := append([]T{item}, items[:i]...)
+			return append(ordered, items[i+1:]...)
+		}
+	}
+	return items
+}
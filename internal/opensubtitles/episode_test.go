@@ -0,0 +1,131 @@
+package opensubtitles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEpisodeInName(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		wantSeason int
+		wantNumber int
+		wantOK     bool
+	}{
+		{name: "SxxEyy", file: "Show.S01E02.1080p.WEB-DL.srt", wantSeason: 1, wantNumber: 2, wantOK: true},
+		{name: "lower case", file: "show.s03e14.srt", wantSeason: 3, wantNumber: 14, wantOK: true},
+		{name: "separated", file: "Show S01 E02.srt", wantSeason: 1, wantNumber: 2, wantOK: true},
+		{name: "three-digit episode", file: "Show.S01E102.srt", wantSeason: 1, wantNumber: 102, wantOK: true},
+		{name: "1x02", file: "Show 1x02.srt", wantSeason: 1, wantNumber: 2, wantOK: true},
+		{name: "12x105", file: "Show.12x105.ass", wantSeason: 12, wantNumber: 105, wantOK: true},
+		{name: "multi-episode is named after the first", file: "Show.S01E01E02.srt", wantSeason: 1, wantNumber: 1, wantOK: true},
+		{name: "multi-episode with a hyphen", file: "Show.S02E05-E06.srt", wantSeason: 2, wantNumber: 5, wantOK: true},
+		{name: "folders are ignored", file: "S09E09/Show.S01E02.srt", wantSeason: 1, wantNumber: 2, wantOK: true},
+		{name: "resolution is no 1x02", file: "Movie.1920x1080.srt", wantOK: false},
+		{name: "inside a word", file: "Boss01E02.srt", wantOK: false},
+		{name: "absolute number", file: "Show - 25 [1080p].ass", wantOK: false},
+		{name: "movie", file: "Movie.2019.1080p.srt", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			season, number, ok := EpisodeInName(tt.file)
+			if season != tt.wantSeason || number != tt.wantNumber || ok != tt.wantOK {
+				t.Errorf("EpisodeInName(%q) = %d, %d, %v, want %d, %d, %v", tt.file, season, number, ok, tt.wantSeason, tt.wantNumber, tt.wantOK)
+			}
+		})
+	}
+}
+
+// uploadsOf returns one upload per file name, in order, with file IDs
+// counting from 1.
+func uploadsOf(names ...string) []Subtitle {
+	var subtitles []Subtitle
+	for i, name := range names {
+		subtitles = append(subtitles, Subtitle{ID: name, Files: []SubtitleFile{{FileID: i + 1, FileName: name}}})
+	}
+	return subtitles
+}
+
+// matchedFiles returns the file names of the matched uploads.
+func matchedFiles(subtitles []Subtitle) []string {
+	var names []string
+	for _, subtitle := range subtitles {
+		names = append(names, subtitle.FileName)
+	}
+	return names
+}
+
+func TestFilesForEpisode(t *testing.T) {
+	uploads := uploadsOf(
+		"Show.S01E01.srt",
+		"Show.S01E02.srt",
+		"Show.S01E12.srt",
+		"Show.S11E02.srt",
+		"Show 1x02.srt",
+		"Show.1x20.srt",
+		"Show.S01E03E04.srt",
+		"Show.S01.E05.srt",
+		"Show.S01.Complete.srt",
+	)
+	tests := []struct {
+		name    string
+		episode Episode
+		want    []string
+	}{
+		{name: "SxxEyy and 1x02", episode: Episode{Season: 1, Number: 2}, want: []string{"Show.S01E02.srt", "Show 1x02.srt"}},
+		{name: "leading zeros", episode: Episode{Season: 1, Number: 1}, want: []string{"Show.S01E01.srt"}},
+		{name: "two-digit episode", episode: Episode{Season: 1, Number: 12}, want: []string{"Show.S01E12.srt"}},
+		{name: "two-digit season", episode: Episode{Season: 11, Number: 2}, want: []string{"Show.S11E02.srt"}},
+		{name: "multi-episode matches its first episode", episode: Episode{Season: 1, Number: 3}, want: []string{"Show.S01E03E04.srt"}},
+		{name: "multi-episode doesn't match its second", episode: Episode{Season: 1, Number: 4}, want: nil},
+		{name: "separated", episode: Episode{Season: 1, Number: 5}, want: []string{"Show.S01.E05.srt"}},
+		{name: "missing", episode: Episode{Season: 2, Number: 1}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchedFiles(filesForEpisode(uploads, tt.episode)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filesForEpisode(%v) = %q, want %q", tt.episode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilesForEpisodeNarrowsPacks(t *testing.T) {
+	pack := Subtitle{ID: "pack", FileID: 1, FileName: "Show.S01E01.srt", Files: []SubtitleFile{
+		{FileID: 1, FileName: "Show.S01E01.srt"},
+		{FileID: 2, FileName: "Show.S01E02.srt"},
+		{FileID: 3, FileName: "Show.S01E03.srt"},
+	}}
+	got := filesForEpisode([]Subtitle{pack}, Episode{Season: 1, Number: 2})
+	if len(got) != 1 || got[0].FileID != 2 || got[0].FileName != "Show.S01E02.srt" {
+		t.Fatalf("filesForEpisode() = %+v, want the pack narrowed down to its second file", got)
+	}
+	if pack.FileID != 1 {
+		t.Errorf("filesForEpisode() changed the upload it was given")
+	}
+}
+
+func TestAbsoluteNumberPattern(t *testing.T) {
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{file: "Show - 25 [1080p].ass", want: true},
+		{file: "[Group] Show - 025 (1080p).srt", want: true},
+		{file: "Show_25v2.srt", want: true},
+		{file: "Show E25.srt", want: true},
+		{file: "Show #25.srt", want: true},
+		{file: "25.srt", want: true},
+		{file: "Show - 125.srt", want: false},
+		{file: "Show - 250.srt", want: false},
+		{file: "Show - 26.srt", want: false},
+		{file: "Show [1080p].srt", want: false},
+	}
+	pattern := absoluteNumberPattern(25)
+	for _, tt := range tests {
+		if got := pattern.MatchString(tt.file); got != tt.want {
+			t.Errorf("absoluteNumberPattern(25) matches %q = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package opensubtitles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/store"
+)
+
+// Instance is one configured OpenSubtitles account.
+type Instance struct {
+	Name     string
+	Priority int
+	Client   *Client
+}
+
+type InstanceStatus struct {
+	Name      string    `json:"name"`
+	Priority  int       `json:"priority"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+	Exhausted bool      `json:"exhausted"`
+}
+
+// Registry spreads searches and downloads over several OpenSubtitles
+// instances. Instances are tried in priority order (lowest first) and an
+// instance whose daily download quota is used up is skipped until it resets,
+// so the combined allowance of all accounts is available.
+type Registry struct {
+	mu        sync.RWMutex
+	instances []*Instance
+}
+
+func NewRegistry(instances []*Instance) *Registry {
+	r := &Registry{}
+	r.SetInstances(instances)
+	return r
+}
+
+// SetInstances replaces the configured instances, e.g. after a config reload.
+func (r *Registry) SetInstances(instances []*Instance) {
+	sorted := append([]*Instance{}, instances...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instances = sorted
+}
+
+// SetSearchStore keeps the search results of every instance in st for ttl.
+// Instances share the store, since they search the same catalogue.
+func (r *Registry) SetSearchStore(st *store.Store, ttl time.Duration) {
+	for _, instance := range r.all() {
+		instance.Client.SetSearchStore(st, ttl)
+	}
+}
+
+func (r *Registry) all() []*Instance {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.instances
+}
+
+// Prefer returns a view of the registry that tries the named instances
+// first, in the given order, and the remaining ones after them in priority
+// order. The view shares the instances, so quotas are still combined.
+func (r *Registry) Prefer(names []string) *Registry {
+	if len(names) == 0 {
+		return r
+	}
+
+	instances := r.all()
+	preferred := make(map[string]bool, len(names))
+	ordered := make([]*Instance, 0, len(instances))
+	for _, name := range names {
+		for _, instance := range instances {
+			if instance.Name == name && !preferred[name] {
+				preferred[name] = true
+				ordered = append(ordered, instance)
+			}
+		}
+	}
+	for _, instance := range instances {
+		if !preferred[instance.Name] {
+			ordered = append(ordered, instance)
+		}
+	}
+	return &Registry{instances: ordered}
+}
+
+// available returns the instances that still have download quota left,
+// falling back to all instances when every quota is exhausted.
+func (r *Registry) available() []*Instance {
+	instances := r.all()
+
+	var available []*Instance
+	for _, instance := range instances {
+		if !instance.Client.Exhausted() {
+			available = append(available, instance)
+		}
+	}
+	if len(available) == 0 {
+		return instances
+	}
+	return available
+}
+
+func (r *Registry) SearchSubtitles(ctx context.Context, movieName string, imdbID string, language string) ([]Subtitle, error) {
+	var lastErr error
+	for _, instance := range r.available() {
+		subtitles, err := instance.Client.SearchSubtitles(ctx, movieName, imdbID, language)
+		if err == nil {
+			return subtitles, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		jobs.FromContext(ctx).Logf("OpenSubtitles instance %s search failed: %v", instance.Name, err)
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (r *Registry) FindBestSubtitle(ctx context.Context, movie Movie, language string, forced bool, video Video) (*Subtitle, error) {
+	instances := r.available()
+	return instances[0].Client.FindBestSubtitle(ctx, movie, language, forced, video)
+}
+
+// DownloadSubtitle downloads through the highest-priority instance with
+// quota left, moving on to the next instance when a quota runs out.
+func (r *Registry) DownloadSubtitle(ctx context.Context, subtitle *Subtitle) ([]byte, error) {
+	return r.download(ctx, subtitle, (*Client).DownloadSubtitle)
+}
+
+// DownloadFile downloads like DownloadSubtitle, but returns the file as it
+// is served, which may be an archive of several subtitles.
+func (r *Registry) DownloadFile(ctx context.Context, subtitle *Subtitle) ([]byte, error) {
+	return r.download(ctx, subtitle, (*Client).DownloadFile)
+}
+
+func (r *Registry) download(ctx context.Context, subtitle *Subtitle, download func(*Client, context.Context, *Subtitle) ([]byte, error)) ([]byte, error) {
+	var lastErr error
+	for _, instance := range r.available() {
+		content, err := download(instance.Client, ctx, subtitle)
+		if err == nil {
+			jobs.FromContext(ctx).Logf("Downloaded subtitle %s via OpenSubtitles instance %s", subtitle.ID, instance.Name)
+			return content, nil
+		}
+		if !errors.Is(err, ErrQuotaExceeded) {
+			return nil, err
+		}
+		jobs.FromContext(ctx).Logf("OpenSubtitles instance %s is out of quota, trying next instance", instance.Name)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all OpenSubtitles instances failed: %w", lastErr)
+}
+
+// Ping checks every instance against the OpenSubtitles API and returns the
+// error for each instance name, nil for the ones that are usable.
+func (r *Registry) Ping(ctx context.Context) map[string]error {
+	results := make(map[string]error)
+	for _, instance := range r.all() {
+		results[instance.Name] = instance.Client.Ping(ctx)
+	}
+	return results
+}
+
+// Status reports the quota state of every instance.
+func (r *Registry) Status() []InstanceStatus {
+	instances := r.all()
+	statuses := make([]InstanceStatus, 0, len(instances))
+	for _, instance := range instances {
+		remaining, resetAt := instance.Client.Quota()
+		statuses = append(statuses, InstanceStatus{
+			Name:      instance.Name,
+			Priority:  instance.Priority,
+			Used:      instance.Client.Used(),
+			Remaining: remaining,
+			ResetAt:   resetAt,
+			Exhausted: instance.Client.Exhausted(),
+		})
+	}
+	return statuses
+}
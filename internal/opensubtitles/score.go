@@ -0,0 +1,198 @@
+package opensubtitles
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"subtitle-hunter/internal/release"
+)
+
+// Weights set how much each signal counts when search results are ranked.
+// Every signal scores between 0 and 1 and is multiplied by its weight; the
+// result with the highest total is downloaded.
+type Weights struct {
+	// HashMatch counts results OpenSubtitles matched to the video's hash,
+	// which were timed against exactly this file.
+	HashMatch float64
+	// ReleaseMatch counts how much of the video's file name the result's
+	// release name shares, and how many of the video's release group,
+	// source, resolution and codec it agrees with (see release.Match).
+	ReleaseMatch float64
+	// DownloadCount counts downloads on a log scale, relative to the most
+	// downloaded result.
+	DownloadCount float64
+	// Rating counts the result's user rating out of 10.
+	Rating float64
+	// HearingImpaired counts results that suit the "prefer" or "avoid"
+	// hearing-impaired setting. It has no effect for other settings.
+	HearingImpaired float64
+	// ProviderTrust counts results uploaded by users the provider trusts.
+	ProviderTrust float64
+	// HumanMade counts results that are neither AI- nor machine-translated
+	// when either kind is to be avoided. It has no effect otherwise.
+	HumanMade float64
+}
+
+// DefaultWeights rank a hash match first, then subtitles made by people
+// when translated ones are avoided, then the hearing-impaired preference,
+// then how well the release matches, leaving popularity to decide between
+// otherwise equal results.
+var DefaultWeights = Weights{
+	HashMatch:       100,
+	ReleaseMatch:    30,
+	DownloadCount:   10,
+	Rating:          5,
+	HearingImpaired: 50,
+	ProviderTrust:   5,
+	HumanMade:       60,
+}
+
+// Video describes the file a subtitle is searched for, so that results made
+// for the same release rank first. The zero Video skips those signals.
+type Video struct {
+	// FileName is the video's file name, compared with release names.
+	FileName string
+	// Hash is the OpenSubtitles hash of the file; see FileHash.
+	Hash string
+	// Exclude lists the IDs of subtitles rejected for the video, which are
+	// never picked.
+	Exclude []string
+}
+
+// weightsFor returns the weights for searches in language, an OpenSubtitles
+// language code.
+func (c *Client) weightsFor(language string) Weights {
+	if weights, ok := c.LanguageWeights[language]; ok {
+		return weights
+	}
+	return c.Weights
+}
+
+// rank sorts subtitles by score, highest first. Results with equal scores
+// keep the order the API returned them in.
+func (c *Client) rank(subtitles []Subtitle, language string, video Video) {
+	weights := c.weightsFor(language)
+	avoidTranslated := c.AITranslated == TranslatedAvoid || c.MachineTranslated == TranslatedAvoid
+	videoRelease := release.Parse(video.FileName)
+	maxDownloads := 0
+	for _, subtitle := range subtitles {
+		maxDownloads = max(maxDownloads, subtitle.DownloadCount)
+	}
+
+	for i := range subtitles {
+		subtitle := &subtitles[i]
+		subtitle.Score = 0
+		if subtitle.HashMatch && video.Hash != "" {
+			subtitle.Score += weights.HashMatch
+		}
+		if video.FileName != "" {
+			similarity := max(releaseSimilarity(subtitle.Release, video.FileName), releaseSimilarity(subtitle.FileName, video.FileName))
+			if !videoRelease.IsZero() {
+				match := max(release.Match(videoRelease, release.Parse(subtitle.Release)), release.Match(videoRelease, release.Parse(subtitle.FileName)))
+				similarity = (similarity + match) / 2
+			}
+			subtitle.Score += weights.ReleaseMatch * similarity
+		}
+		if maxDownloads > 0 {
+			subtitle.Score += weights.DownloadCount * math.Log1p(float64(subtitle.DownloadCount)) / math.Log1p(float64(maxDownloads))
+		}
+		subtitle.Score += weights.Rating * min(max(subtitle.Rating, 0), 10) / 10
+		if (c.HearingImpaired == HearingImpairedPrefer && subtitle.HearingImpaired) ||
+			(c.HearingImpaired == HearingImpairedAvoid && !subtitle.HearingImpaired) {
+			subtitle.Score += weights.HearingImpaired
+		}
+		if subtitle.FromTrusted {
+			subtitle.Score += weights.ProviderTrust
+		}
+		if avoidTranslated && !(subtitle.AITranslated && c.AITranslated == TranslatedAvoid) &&
+			!(subtitle.MachineTranslated && c.MachineTranslated == TranslatedAvoid) {
+			subtitle.Score += weights.HumanMade
+		}
+	}
+
+	sort.SliceStable(subtitles, func(i, j int) bool {
+		return subtitles[i].Score > subtitles[j].Score
+	})
+}
+
+var (
+	releaseSeparators = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+	// fileExtensions are dropped from names before they are compared
+	fileExtensions = map[string]bool{"srt": true, "ass": true, "ssa": true, "sub": true, "mkv": true, "mp4": true, "avi": true, "m4v": true, "ts": true}
+)
+
+// releaseSimilarity returns the share of the words of the video's file
+// name that also appear in release, from 0 to 1.
+func releaseSimilarity(release, fileName string) float64 {
+	videoWords := releaseWords(fileName)
+	if len(videoWords) == 0 || release == "" {
+		return 0
+	}
+	releaseSet := make(map[string]bool)
+	for _, word := range releaseWords(release) {
+		releaseSet[word] = true
+	}
+
+	shared := 0
+	for _, word := range videoWords {
+		if releaseSet[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(videoWords))
+}
+
+// releaseWords splits a release or file name into distinct lower-case words.
+func releaseWords(name string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range releaseSeparators.Split(strings.ToLower(name), -1) {
+		if word == "" || fileExtensions[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// hashChunkSize is how much of each end of the file the hash reads.
+const hashChunkSize = 64 << 10
+
+// FileHash computes the OpenSubtitles hash of the video at path: the file
+// size plus the 64-bit little-endian words of its first and last 64 KiB.
+func FileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := info.Size()
+	if size < hashChunkSize {
+		return "", fmt.Errorf("%s is too small to hash", filepath.Base(path))
+	}
+
+	hash := uint64(size)
+	buffer := make([]byte, hashChunkSize)
+	for _, offset := range []int64{0, size - hashChunkSize} {
+		if _, err := file.ReadAt(buffer, offset); err != nil && err != io.EOF {
+			return "", err
+		}
+		for i := 0; i < hashChunkSize; i += 8 {
+			hash += binary.LittleEndian.Uint64(buffer[i:])
+		}
+	}
+	return fmt.Sprintf("%016x", hash), nil
+}
@@ -0,0 +1,203 @@
+// Package release reads what a scene or web release name says about the
+// video: Show.S01E02.1080p.WEB-DL.x265-GRP is 1080p, from a web download,
+// encoded with H.265 by the group GRP. Subtitles are timed against one
+// release, so the closer a subtitle's release is to the video's, the better
+// it fits.
+package release
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Info is what a release name says. Fields the name doesn't mention are
+// empty.
+type Info struct {
+	// Title is the name before the episode, year or first release tag.
+	Title   string `json:"title,omitempty"`
+	Year    int    `json:"year,omitempty"`
+	Season  int    `json:"season,omitempty"`
+	Episode int    `json:"episode,omitempty"`
+	// Resolution is such as "1080p" or "2160p".
+	Resolution string `json:"resolution,omitempty"`
+	// Source is "WEB-DL", "WEBRip", "BluRay", "Remux", "HDTV" or "DVD".
+	Source string `json:"source,omitempty"`
+	// Codec is "H.264", "H.265", "AV1" or "XviD".
+	Codec string `json:"codec,omitempty"`
+	// Group is the release group, from "-GRP" at the end of the name or
+	// "[GRP]" at its start.
+	Group string `json:"group,omitempty"`
+}
+
+// Tags lists the resolution, source, codec and group, for display.
+func (i Info) Tags() []string {
+	var tags []string
+	for _, tag := range []string{i.Resolution, i.Source, i.Codec, i.Group} {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (i Info) String() string {
+	return strings.Join(i.Tags(), " ")
+}
+
+// IsZero reports whether the name said nothing about the release.
+func (i Info) IsZero() bool {
+	return len(i.Tags()) == 0
+}
+
+var (
+	separators   = regexp.MustCompile(`[\s._]+`)
+	bracketGroup = regexp.MustCompile(`^\[([^\]]+)\]`)
+	trailingTags = regexp.MustCompile(`(\s*[\[(][^\])]*[\])])+$`)
+	episodeTag   = regexp.MustCompile(`(?i)^s(\d{1,2})e(\d{1,3})(?:-?e\d{1,3})*$`)
+	altEpisode   = regexp.MustCompile(`(?i)^(\d{1,2})x(\d{2,3})$`)
+	yearTag      = regexp.MustCompile(`^[(\[]?((?:19|20)\d{2})[)\]]?$`)
+	resolution   = regexp.MustCompile(`(?i)^[(\[]?(\d{3,4})[pi][)\]]?$`)
+)
+
+// sources maps the lower-case spellings of release sources to their usual
+// names.
+var sources = map[string]string{
+	"web-dl": "WEB-DL", "webdl": "WEB-DL", "web": "WEB-DL", "amzn": "WEB-DL", "nf": "WEB-DL",
+	"webrip": "WEBRip", "web-rip": "WEBRip",
+	"bluray": "BluRay", "blu-ray": "BluRay", "bdrip": "BluRay", "brrip": "BluRay", "bdremux": "Remux", "remux": "Remux",
+	"hdtv": "HDTV", "pdtv": "HDTV",
+	"dvdrip": "DVD", "dvd": "DVD", "dvd-rip": "DVD",
+}
+
+var codecs = map[string]string{
+	"x264": "H.264", "h264": "H.264", "h.264": "H.264", "avc": "H.264",
+	"x265": "H.265", "h265": "H.265", "h.265": "H.265", "hevc": "H.265",
+	"av1":  "AV1",
+	"xvid": "XviD",
+}
+
+// videoExtensions are dropped from the end of names.
+var videoExtensions = map[string]bool{
+	".mkv": true, ".mp4": true, ".avi": true, ".m4v": true, ".ts": true,
+	".srt": true, ".ass": true, ".ssa": true, ".sub": true,
+}
+
+// Parse reads a release or file name. Folders are ignored.
+func Parse(name string) Info {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if ext := strings.ToLower(path.Ext(name)); videoExtensions[ext] {
+		name = name[:len(name)-len(ext)]
+	}
+
+	info := parse(name, true)
+	if info.Resolution == "" && info.Source == "" && info.Codec == "" {
+		// Without any release tags, "Spider-Man" is a title, not a
+		// release by the group "Man"
+		info = parse(name, false)
+	}
+	return info
+}
+
+func parse(name string, trailingGroup bool) Info {
+	var info Info
+	if match := bracketGroup.FindStringSubmatch(name); match != nil {
+		info.Group = strings.TrimSpace(match[1])
+		name = name[len(match[0]):]
+	}
+
+	// "-GRP" at the end names the group, unless it is a tag such as
+	// "WEB-DL" or "Blu-ray"
+	if i := strings.LastIndex(name, "-"); i >= 0 && trailingGroup && info.Group == "" {
+		group := trailingTags.ReplaceAllString(name[i+1:], "")
+		if group != "" && !strings.ContainsAny(group, " .") && !isTag(name[max(0, strings.LastIndexAny(name[:i], " ._")+1):]) {
+			info.Group = group
+			name = name[:i]
+		}
+	}
+
+	titleDone := false
+	var title []string
+	for _, word := range separators.Split(name, -1) {
+		if word == "" || word == "-" {
+			continue
+		}
+		lower := strings.ToLower(strings.Trim(word, "[]()"))
+		switch {
+		case episodeTag.MatchString(word):
+			match := episodeTag.FindStringSubmatch(word)
+			info.Season, _ = strconv.Atoi(match[1])
+			info.Episode, _ = strconv.Atoi(match[2])
+		case altEpisode.MatchString(word):
+			match := altEpisode.FindStringSubmatch(word)
+			info.Season, _ = strconv.Atoi(match[1])
+			info.Episode, _ = strconv.Atoi(match[2])
+		case yearTag.MatchString(word) && len(title) > 0 && (!titleDone || info.Year != 0 && info.Season == 0 && info.Resolution == "" && info.Source == ""):
+			// In "Blade.Runner.2049.2017" the last year is the release's
+			if info.Year != 0 {
+				title = append(title, strconv.Itoa(info.Year))
+			}
+			info.Year, _ = strconv.Atoi(yearTag.FindStringSubmatch(word)[1])
+		case resolution.MatchString(word):
+			info.Resolution = resolution.FindStringSubmatch(word)[1] + "p"
+		case lower == "4k" || lower == "uhd":
+			info.Resolution = "2160p"
+		case sources[lower] != "":
+			if info.Source == "" || sources[lower] == "Remux" {
+				info.Source = sources[lower]
+			}
+		case codecs[lower] != "":
+			info.Codec = codecs[lower]
+		default:
+			if !titleDone {
+				title = append(title, word)
+			}
+			continue
+		}
+		titleDone = true
+	}
+	info.Title = strings.TrimSpace(strings.Trim(strings.Join(title, " "), "-"))
+	return info
+}
+
+// isTag reports whether word, such as "WEB-DL", is a release tag that
+// happens to hold a hyphen.
+func isTag(word string) bool {
+	word = strings.ToLower(word)
+	return sources[word] != "" || codecs[word] != ""
+}
+
+// sourceKinds groups sources cut from the same master, whose subtitles
+// are usually timed alike.
+var sourceKinds = map[string]string{
+	"WEB-DL": "web", "WEBRip": "web",
+	"BluRay": "disc", "Remux": "disc", "DVD": "dvd",
+	"HDTV": "tv",
+}
+
+// Match returns how well a subtitle made for release fits the video, from
+// 0 to 1: the share of what the video's name says about its release that
+// release agrees with. The group counts twice, since a subtitle timed for
+// the same group's release nearly always fits; sources count as the same
+// when they come from the same kind of master, such as WEB-DL and WEBRip.
+// It is 0 when the video's name says nothing about its release.
+func Match(video, release Info) float64 {
+	var total, matched float64
+	add := func(weight float64, known, same bool) {
+		if known {
+			total += weight
+			if same {
+				matched += weight
+			}
+		}
+	}
+	add(2, video.Group != "", strings.EqualFold(video.Group, release.Group))
+	add(1, video.Source != "", sourceKinds[video.Source] == sourceKinds[release.Source])
+	add(1, video.Resolution != "", video.Resolution == release.Resolution)
+	add(1, video.Codec != "", video.Codec == release.Codec)
+	if total == 0 {
+		return 0
+	}
+	return matched / total
+}
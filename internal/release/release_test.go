@@ -0,0 +1,136 @@
+package release
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want Info
+	}{
+		{
+			name: "episode",
+			in:   "Show.S01E02.1080p.WEB-DL.x265-GRP.mkv",
+			want: Info{Title: "Show", Season: 1, Episode: 2, Resolution: "1080p", Source: "WEB-DL", Codec: "H.265", Group: "GRP"},
+		},
+		{
+			name: "movie with a year",
+			in:   "Movie.Name.2019.2160p.BluRay.REMUX.HEVC-FGT.mkv",
+			want: Info{Title: "Movie Name", Year: 2019, Resolution: "2160p", Source: "Remux", Codec: "H.265", Group: "FGT"},
+		},
+		{
+			name: "year in the title",
+			in:   "Blade.Runner.2049.2017.BluRay.x264-SPARKS.mkv",
+			want: Info{Title: "Blade Runner 2049", Year: 2017, Source: "BluRay", Codec: "H.264", Group: "SPARKS"},
+		},
+		{
+			name: "spaces and brackets",
+			in:   "Movie Name (2019) [1080p] [WEBRip]",
+			want: Info{Title: "Movie Name", Year: 2019, Resolution: "1080p", Source: "WEBRip"},
+		},
+		{
+			name: "underscores",
+			in:   "Show_Name_S02E10_720p_HDTV_x264-KILLERS",
+			want: Info{Title: "Show Name", Season: 2, Episode: 10, Resolution: "720p", Source: "HDTV", Codec: "H.264", Group: "KILLERS"},
+		},
+		{
+			name: "repeated separators",
+			in:   "Show..Name  S01E02__1080p.WEB-DL-GRP",
+			want: Info{Title: "Show Name", Season: 1, Episode: 2, Resolution: "1080p", Source: "WEB-DL", Group: "GRP"},
+		},
+		{
+			name: "bracketed group at the start",
+			in:   "[SubsPlease] Anime Title - 1x05 (1080p).mkv",
+			want: Info{Title: "Anime Title", Season: 1, Episode: 5, Resolution: "1080p", Group: "SubsPlease"},
+		},
+		{
+			name: "multi-episode",
+			in:   "Show.S03E01E02.720p.WEBRip.x264-GRP",
+			want: Info{Title: "Show", Season: 3, Episode: 1, Resolution: "720p", Source: "WEBRip", Codec: "H.264", Group: "GRP"},
+		},
+		{
+			name: "tag with a hyphen at the end is no group",
+			in:   "Movie.2020.1080p.WEB-DL",
+			want: Info{Title: "Movie", Year: 2020, Resolution: "1080p", Source: "WEB-DL"},
+		},
+		{
+			name: "4k and xvid",
+			in:   "Old.Movie.1999.4K.DVDRip.XviD",
+			want: Info{Title: "Old Movie", Year: 1999, Resolution: "2160p", Source: "DVD", Codec: "XviD"},
+		},
+		{
+			name: "hyphen in the title without release tags",
+			in:   "Spider-Man.mkv",
+			want: Info{Title: "Spider-Man"},
+		},
+		{
+			name: "folders and backslashes are ignored",
+			in:   `D:\Media\Show\Show.S01E02.1080p.AMZN.WEB-DL-GRP.srt`,
+			want: Info{Title: "Show", Season: 1, Episode: 2, Resolution: "1080p", Source: "WEB-DL", Group: "GRP"},
+		},
+		{
+			name: "no separators",
+			in:   "Movie",
+			want: Info{Title: "Movie"},
+		},
+		{
+			name: "empty",
+			in:   "",
+			want: Info{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	tests := []struct {
+		info Info
+		want string
+		zero bool
+	}{
+		{info: Info{Resolution: "1080p", Source: "WEB-DL", Codec: "H.265", Group: "GRP"}, want: "1080p WEB-DL H.265 GRP"},
+		{info: Info{Source: "BluRay"}, want: "BluRay"},
+		{info: Info{Title: "Movie", Year: 2019}, want: "", zero: true},
+	}
+	for _, tt := range tests {
+		if got := tt.info.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.info, got, tt.want)
+		}
+		if got := tt.info.IsZero(); got != tt.zero {
+			t.Errorf("%+v.IsZero() = %v, want %v", tt.info, got, tt.zero)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	video := Info{Resolution: "1080p", Source: "WEB-DL", Codec: "H.265", Group: "GRP"}
+	tests := []struct {
+		name    string
+		video   Info
+		release Info
+		want    float64
+	}{
+		{name: "same release", video: video, release: video, want: 1},
+		{name: "same kind of source", video: video, release: Info{Resolution: "1080p", Source: "WEBRip", Codec: "H.265", Group: "grp"}, want: 1},
+		{name: "only the group", video: video, release: Info{Group: "GRP"}, want: 0.4},
+		{name: "everything but the group", video: video, release: Info{Resolution: "1080p", Source: "WEB-DL", Codec: "H.265", Group: "OTHER"}, want: 0.6},
+		{name: "nothing in common", video: video, release: Info{Resolution: "720p", Source: "HDTV", Codec: "H.264", Group: "OTHER"}, want: 0},
+		{name: "video says nothing", video: Info{Title: "Movie"}, release: video, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.video, tt.release); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
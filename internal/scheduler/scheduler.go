@@ -0,0 +1,419 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"subtitle-hunter/internal/events"
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/store"
+)
+
+const (
+	stateBucket     = "scheduler"
+	lastScanKey     = "last-scan"
+	lastFullScanKey = "last-full-scan"
+
+	// scanOverlap is subtracted from the last scan time when asking Jellyfin
+	// for changed items, so clock skew between the two servers and items
+	// saved while a scan was starting aren't missed.
+	scanOverlap = 5 * time.Minute
+)
+
+// HuntFunc processes a single item and saves a subtitle for it. It returns
+// ErrSkipped for items that must not be hunted automatically and ErrDeferred
+// when the run should stop for now.
+type HuntFunc func(ctx context.Context, item *jellyfin.MediaItem) error
+
+// RecheckFunc reports whether an item that has a Chinese subtitle needs to
+// be hunted again anyway, such as when its video file was replaced.
+type RecheckFunc func(item jellyfin.MediaItem) bool
+
+// ErrSkipped is returned by a HuntFunc that deliberately left an item alone.
+var ErrSkipped = errors.New("skipped")
+
+// ErrDeferred is returned by a HuntFunc that can't process any more items
+// today, such as when a daily budget is used up. The run stops feeding items
+// and isn't recorded as a scan, so the next run picks up the rest.
+var ErrDeferred = errors.New("deferred to the next run")
+
+// Scheduler periodically hunts subtitles for items missing them. Only items
+// added or premiered within the configured window are processed
+// automatically; older items are left for manual backfill.
+//
+// Items a RecheckFunc picks out are hunted too, whatever the window, since
+// the file that needs them is new.
+//
+// Most runs are differential: they only look at items Jellyfin saved since
+// the previous scan. A full scan of the library runs every full-scan
+// interval to retry items whose earlier hunts failed. Scan times are kept
+// in the store so a restart doesn't force a full scan.
+type Scheduler struct {
+	jellyfinClient *jellyfin.Client
+	store          *store.Store
+	hunt           HuntFunc
+	recheck        RecheckFunc
+	reconfigured   chan struct{}
+	events         *events.Hub
+
+	mu           sync.Mutex
+	interval     time.Duration
+	window       time.Duration
+	fullInterval time.Duration
+	concurrency  int
+	running      bool
+	paused       bool
+	lastRun      time.Time
+	nextRun      time.Time
+	lastScan     time.Time
+	lastFullScan time.Time
+}
+
+func New(jellyfinClient *jellyfin.Client, st *store.Store, hunt HuntFunc, interval, window time.Duration) *Scheduler {
+	s := &Scheduler{
+		jellyfinClient: jellyfinClient,
+		store:          st,
+		hunt:           hunt,
+		reconfigured:   make(chan struct{}, 1),
+		interval:       interval,
+		window:         window,
+		concurrency:    1,
+	}
+	if _, err := st.Get(stateBucket, lastScanKey, &s.lastScan); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if _, err := st.Get(stateBucket, lastFullScanKey, &s.lastFullScan); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return s
+}
+
+// SetFullScanInterval sets how often a run re-reads the whole library
+// instead of only the items changed since the last scan. Zero makes every
+// run a full scan.
+func (s *Scheduler) SetFullScanInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fullInterval = interval
+}
+
+// SetRecheck sets which items with a Chinese subtitle are hunted as well.
+// It must be called before Start.
+func (s *Scheduler) SetRecheck(recheck RecheckFunc) {
+	s.recheck = recheck
+}
+
+// SetEvents makes runs announce when they start and finish.
+func (s *Scheduler) SetEvents(hub *events.Hub) {
+	s.events = hub
+}
+
+// SetConcurrency sets how many items a run hunts at once. The hunt function
+// is expected to share a worker pool with manual jobs, which bounds the
+// total; this only keeps a run from queueing its whole backlog at once.
+func (s *Scheduler) SetConcurrency(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.concurrency = max(n, 1)
+}
+
+// Start runs the hunt loop in the background until ctx is done, which also
+// cancels a run in progress. While the interval is zero the loop idles until
+// Reconfigure enables it.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.logSchedule()
+
+	go func() {
+		for {
+			interval, _ := s.schedule()
+			if interval <= 0 {
+				s.mu.Lock()
+				s.nextRun = time.Time{}
+				s.mu.Unlock()
+				select {
+				case <-s.reconfigured:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			timer := time.NewTimer(interval)
+			s.mu.Lock()
+			s.nextRun = time.Now().Add(interval)
+			s.mu.Unlock()
+
+			select {
+			case <-timer.C:
+				s.RunOnce(ctx)
+			case <-s.reconfigured:
+				timer.Stop()
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Reconfigure changes the interval and window. The next run is scheduled a
+// full interval from now.
+func (s *Scheduler) Reconfigure(interval, window time.Duration) {
+	s.mu.Lock()
+	changed := interval != s.interval || window != s.window
+	s.interval = interval
+	s.window = window
+	s.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	s.logSchedule()
+
+	select {
+	case s.reconfigured <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) schedule() (time.Duration, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interval, s.window
+}
+
+func (s *Scheduler) logSchedule() {
+	interval, window := s.schedule()
+	if interval <= 0 {
+		log.Printf("Auto-hunt disabled")
+		return
+	}
+	log.Printf("Auto-hunt scheduled every %v (window: %s)", interval, windowDescription(window))
+}
+
+// RunOnce performs a single auto-hunt pass. Overlapping runs are skipped. A
+// run cancelled through ctx stops after the current item and is not
+// recorded as a scan, so the next run covers its items again.
+func (s *Scheduler) RunOnce(ctx context.Context) {
+	s.mu.Lock()
+	if s.paused {
+		s.mu.Unlock()
+		log.Printf("Auto-hunt is paused, skipping this tick")
+		return
+	}
+	if s.running {
+		s.mu.Unlock()
+		log.Printf("Auto-hunt already running, skipping this tick")
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.lastRun = time.Now()
+		s.mu.Unlock()
+	}()
+
+	started := time.Now()
+	full, since := s.scanScope(started)
+
+	var all []jellyfin.MediaItem
+	var err error
+	if full {
+		log.Printf("Auto-hunt: full library scan")
+		all, err = s.jellyfinClient.GetMediaItems(ctx)
+	} else {
+		log.Printf("Auto-hunt: checking items changed since %s", since.Format(time.RFC3339))
+		all, err = s.jellyfinClient.GetMediaItemsSince(ctx, since)
+	}
+	if err != nil {
+		log.Printf("Auto-hunt: failed to fetch media: %v", err)
+		return
+	}
+
+	items := s.jellyfinClient.WithoutChineseSubtitles(all)
+	_, window := s.schedule()
+	eligible := eligibleItems(items, window, started)
+	log.Printf("Auto-hunt: %d of %d items missing subtitles are within the window", len(eligible), len(items))
+	if rechecks := s.rechecks(all, eligible); len(rechecks) > 0 {
+		log.Printf("Auto-hunt: %d items need their subtitles fetched again", len(rechecks))
+		eligible = append(eligible, rechecks...)
+	}
+	s.events.Publish(events.RunStarted, events.RunData{Full: full, Items: len(eligible)})
+
+	s.mu.Lock()
+	concurrency := s.concurrency
+	s.mu.Unlock()
+
+	var mu sync.Mutex
+	processed, failed, skipped, deferred := 0, 0, 0, 0
+	fed := jobs.RunEach(ctx, concurrency, len(eligible), func(i int) bool {
+		item := &eligible[i]
+		err := s.hunt(ctx, item)
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case errors.Is(err, ErrSkipped):
+			skipped++
+		case errors.Is(err, ErrDeferred):
+			if deferred == 0 {
+				log.Printf("Auto-hunt: stopping early: %v", err)
+			}
+			deferred++
+			return false
+		case err != nil:
+			log.Printf("Auto-hunt: failed to process %s (%s): %v", item.Name, item.ID, err)
+			failed++
+		default:
+			processed++
+		}
+		return true
+	})
+	s.events.Publish(events.RunFinished, events.RunData{
+		Full:      full,
+		Items:     len(eligible),
+		Processed: processed,
+		Failed:    failed,
+		Skipped:   skipped,
+		Deferred:  len(eligible) - processed - failed - skipped,
+	})
+
+	if ctx.Err() != nil {
+		log.Printf("Auto-hunt cancelled after %d processed, %d failed, %d skipped: %v", processed, failed, skipped, ctx.Err())
+		return
+	}
+	if deferred > 0 {
+		log.Printf("Auto-hunt stopped: %d processed, %d failed, %d skipped, %d left for the next run", processed, failed, skipped, deferred+len(eligible)-fed)
+		return
+	}
+	log.Printf("Auto-hunt finished: %d processed, %d failed, %d skipped", processed, failed, skipped)
+	s.recordScan(started, full)
+}
+
+// scanScope decides whether a run starting at now scans the whole library
+// or only items saved since the returned time.
+func (s *Scheduler) scanScope(now time.Time) (bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fullInterval <= 0 || s.lastScan.IsZero() || now.Sub(s.lastFullScan) >= s.fullInterval {
+		return true, time.Time{}
+	}
+	return false, s.lastScan.Add(-scanOverlap)
+}
+
+// recordScan remembers when a completed scan started. Items saved while it
+// ran are picked up by the next differential scan.
+func (s *Scheduler) recordScan(started time.Time, full bool) {
+	s.mu.Lock()
+	s.lastScan = started
+	if full {
+		s.lastFullScan = started
+	}
+	s.mu.Unlock()
+
+	if err := s.store.Put(stateBucket, lastScanKey, started); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if full {
+		if err := s.store.Put(stateBucket, lastFullScanKey, started); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// Pause stops scheduled runs until Resume is called. A run already in
+// progress is not interrupted.
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+	log.Printf("Auto-hunt paused")
+}
+
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+	log.Printf("Auto-hunt resumed")
+}
+
+// State describes the scheduler for status displays.
+type State struct {
+	Interval     time.Duration
+	Window       time.Duration
+	Paused       bool
+	Running      bool
+	LastRun      time.Time
+	NextRun      time.Time
+	LastFullScan time.Time
+}
+
+func (s *Scheduler) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return State{
+		Interval:     s.interval,
+		Window:       s.window,
+		Paused:       s.paused,
+		Running:      s.running,
+		LastRun:      s.lastRun,
+		NextRun:      s.nextRun,
+		LastFullScan: s.lastFullScan,
+	}
+}
+
+// LastRun returns when the last auto-hunt pass finished.
+func (s *Scheduler) LastRun() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun
+}
+
+// rechecks returns the items the RecheckFunc picks out that aren't hunted
+// already.
+func (s *Scheduler) rechecks(all, hunted []jellyfin.MediaItem) []jellyfin.MediaItem {
+	if s.recheck == nil {
+		return nil
+	}
+	listed := make(map[string]bool, len(hunted))
+	for _, item := range hunted {
+		listed[item.ID] = true
+	}
+	var rechecks []jellyfin.MediaItem
+	for _, item := range all {
+		if !listed[item.ID] && s.recheck(item) {
+			rechecks = append(rechecks, item)
+		}
+	}
+	return rechecks
+}
+
+func eligibleItems(items []jellyfin.MediaItem, window time.Duration, now time.Time) []jellyfin.MediaItem {
+	if window <= 0 {
+		return items
+	}
+
+	var eligible []jellyfin.MediaItem
+	for _, item := range items {
+		if item.WithinWindow(window, now) {
+			eligible = append(eligible, item)
+		}
+	}
+	return eligible
+}
+
+func windowDescription(window time.Duration) string {
+	if window <= 0 {
+		return "entire library"
+	}
+	return fmt.Sprintf("items added or aired in the last %d days", int(window.Hours()/24))
+}
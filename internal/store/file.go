@@ -0,0 +1,149 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// legacyFileName is the JSON file the store was kept in before databases
+// were supported.
+const legacyFileName = "store.json"
+
+// fileBackend keeps the whole store in memory and in a single JSON file,
+// which is rewritten atomically on every change.
+type fileBackend struct {
+	path    string
+	mu      sync.RWMutex
+	buckets map[string]map[string]json.RawMessage
+}
+
+// Open loads the store kept in the JSON file at path, starting empty if the
+// file does not exist.
+func Open(path string) (*Store, error) {
+	backend, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(backend), nil
+}
+
+func openFile(path string) (*fileBackend, error) {
+	b := &fileBackend{
+		path:    path,
+		buckets: make(map[string]map[string]json.RawMessage),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &b.buckets); err != nil {
+		return nil, fmt.Errorf("failed to parse store %s: %w", path, err)
+	}
+
+	return b, nil
+}
+
+func (b *fileBackend) Get(bucket, key string) ([]byte, bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	raw, ok := b.buckets[bucket][key]
+	return raw, ok, nil
+}
+
+func (b *fileBackend) Put(bucket, key string, value []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buckets[bucket] == nil {
+		b.buckets[bucket] = make(map[string]json.RawMessage)
+	}
+	b.buckets[bucket][key] = value
+
+	return b.saveLocked()
+}
+
+func (b *fileBackend) Delete(bucket, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.buckets[bucket][key]; !ok {
+		return nil
+	}
+	delete(b.buckets[bucket], key)
+
+	return b.saveLocked()
+}
+
+func (b *fileBackend) Keys(bucket string) ([]string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	keys := make([]string, 0, len(b.buckets[bucket]))
+	for key := range b.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Check reports whether the store can still be persisted, by creating and
+// removing a temporary file next to it.
+func (b *fileBackend) Check() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	dir := filepath.Dir(b.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+	tempFile, err := os.CreateTemp(dir, filepath.Base(b.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("store directory is not writable: %w", err)
+	}
+	tempFile.Close()
+	return os.Remove(tempFile.Name())
+}
+
+func (b *fileBackend) Close() error {
+	return nil
+}
+
+func (b *fileBackend) saveLocked() error {
+	data, err := json.MarshalIndent(b.buckets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	dir := filepath.Dir(b.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(dir, filepath.Base(b.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), b.path); err != nil {
+		return fmt.Errorf("failed to replace store: %w", err)
+	}
+	return nil
+}
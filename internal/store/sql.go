@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// checkTimeout bounds Check, so a database that stopped answering shows up
+// as unhealthy instead of hanging the health check.
+const checkTimeout = 5 * time.Second
+
+const createTable = `CREATE TABLE IF NOT EXISTS store_values (
+	bucket TEXT NOT NULL,
+	name TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (bucket, name)
+)`
+
+// sqlBackend keeps the store in a table of a SQLite or PostgreSQL
+// database. Nothing is cached, so instances sharing a database see each
+// other's changes.
+type sqlBackend struct {
+	db *sql.DB
+	// postgres numbers placeholders ($1, $2) where SQLite takes "?".
+	postgres bool
+}
+
+func openSQLite(path string) (*sqlBackend, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the SQLite DATABASE_URL needs a path, such as sqlite:/data/subtitle-hunter.db")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+	// SQLite takes one writer at a time anyway
+	db.SetMaxOpenConns(1)
+	return newSQLBackend(db, false, "SQLite database "+path)
+}
+
+func openPostgres(databaseURL string) (*sqlBackend, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL database %s: %w", redact(databaseURL), err)
+	}
+	return newSQLBackend(db, true, "PostgreSQL database "+redact(databaseURL))
+}
+
+// newSQLBackend checks that db answers and creates the store's table.
+func newSQLBackend(db *sql.DB, postgres bool, name string) (*sqlBackend, error) {
+	b := &sqlBackend{db: db, postgres: postgres}
+	if err := b.Check(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", name, err)
+	}
+	if _, err := db.Exec(createTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create the store table in %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// query rewrites the "?" placeholders of a query for the database.
+func (b *sqlBackend) query(query string) string {
+	if !b.postgres {
+		return query
+	}
+	return numberPlaceholders(query)
+}
+
+// numberPlaceholders turns the "?" placeholders of a query into $1, $2 and
+// so on. A "?" inside a string literal or a quoted identifier is left
+// alone; quotes doubled to escape them end and reopen the quote, which
+// comes to the same.
+func numberPlaceholders(query string) string {
+	var rewritten strings.Builder
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			rewritten.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		rewritten.WriteRune(r)
+	}
+	return rewritten.String()
+}
+
+func (b *sqlBackend) Get(bucket, key string) ([]byte, bool, error) {
+	var value string
+	err := b.db.QueryRow(b.query("SELECT value FROM store_values WHERE bucket = ? AND name = ?"), bucket, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(value), true, nil
+}
+
+func (b *sqlBackend) Put(bucket, key string, value []byte) error {
+	return b.put(b.db, bucket, key, value)
+}
+
+// put stores a value through db, which may be a transaction.
+func (b *sqlBackend) put(db interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, bucket, key string, value []byte) error {
+	_, err := db.Exec(b.query("INSERT INTO store_values (bucket, name, value) VALUES (?, ?, ?) ON CONFLICT (bucket, name) DO UPDATE SET value = excluded.value"), bucket, key, string(value))
+	if err != nil {
+		return fmt.Errorf("failed to write %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+func (b *sqlBackend) Delete(bucket, key string) error {
+	if _, err := b.db.Exec(b.query("DELETE FROM store_values WHERE bucket = ? AND name = ?"), bucket, key); err != nil {
+		return fmt.Errorf("failed to delete %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+func (b *sqlBackend) Keys(bucket string) ([]string, error) {
+	rows, err := b.db.Query(b.query("SELECT name FROM store_values WHERE bucket = ?"), bucket)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Sorted here rather than by the database, whose collation may not
+	// order bytes
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Check reports whether the database still answers.
+func (b *sqlBackend) Check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	return b.db.PingContext(ctx)
+}
+
+func (b *sqlBackend) Close() error {
+	return b.db.Close()
+}
+
+// importLegacy fills an empty database with the JSON file older versions
+// kept at path, then renames the file so it is not imported again.
+func (b *sqlBackend) importLegacy(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	var count int
+	if err := b.db.QueryRow("SELECT COUNT(*) FROM store_values").Scan(&count); err != nil {
+		return fmt.Errorf("failed to count stored values: %w", err)
+	}
+	if count > 0 {
+		log.Printf("Warning: not importing %s, the database already has data", path)
+		return nil
+	}
+
+	legacy, err := openFile(path)
+	if err != nil {
+		return err
+	}
+	tx, err := b.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", path, err)
+	}
+	defer tx.Rollback()
+	imported := 0
+	for bucket, values := range legacy.buckets {
+		for key, value := range values {
+			if err := b.put(tx, bucket, key, value); err != nil {
+				return fmt.Errorf("failed to import %s: %w", path, err)
+			}
+			imported++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to import %s: %w", path, err)
+	}
+
+	if err := os.Rename(path, path+".imported"); err != nil {
+		return fmt.Errorf("imported %s, but failed to rename it: %w", path, err)
+	}
+	log.Printf("Imported %d values from %s into the database and renamed it to %s", imported, path, path+".imported")
+	return nil
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNumberPlaceholders(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "none", query: "SELECT COUNT(*) FROM store_values", want: "SELECT COUNT(*) FROM store_values"},
+		{name: "one", query: "SELECT name FROM store_values WHERE bucket = ?", want: "SELECT name FROM store_values WHERE bucket = $1"},
+		{name: "several", query: "VALUES (?, ?, ?)", want: "VALUES ($1, $2, $3)"},
+		{name: "string literal", query: "WHERE name = '?' AND bucket = ?", want: "WHERE name = '?' AND bucket = $1"},
+		{name: "escaped quote in a literal", query: "WHERE name = 'it''s ?' AND bucket = ?", want: "WHERE name = 'it''s ?' AND bucket = $1"},
+		{name: "quoted identifier", query: `SELECT "what?" FROM t WHERE a = ?`, want: `SELECT "what?" FROM t WHERE a = $1`},
+		{name: "double quote inside a literal", query: `WHERE a = 'say "hi?"' AND b = ?`, want: `WHERE a = 'say "hi?"' AND b = $1`},
+		{name: "unterminated literal", query: "WHERE a = ? AND b = '?", want: "WHERE a = $1 AND b = '?"},
+		{name: "non-ASCII", query: "WHERE a = '問?' AND b = ?", want: "WHERE a = '問?' AND b = $1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := numberPlaceholders(tt.query); got != tt.want {
+				t.Errorf("numberPlaceholders(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryLeavesSQLiteAlone(t *testing.T) {
+	query := "SELECT value FROM store_values WHERE bucket = ? AND name = ?"
+	if got := (&sqlBackend{}).query(query); got != query {
+		t.Errorf("query() = %q for SQLite, want it unchanged", got)
+	}
+}
+
+func TestSQLiteStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Connect("sqlite:"+filepath.Join(dir, "db", "store.db"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type job struct {
+		Name  string `json:"name"`
+		Cues  int    `json:"cues"`
+		Tries []int  `json:"tries"`
+	}
+	want := job{Name: "Show S01E02 '?'", Cues: 42, Tries: []int{1, 2}}
+	if err := s.Put("jobs", "b", want); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("jobs", "a", job{Name: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("other", "c", true); err != nil {
+		t.Fatal(err)
+	}
+
+	var got job
+	if found, err := s.Get("jobs", "b", &got); err != nil || !found {
+		t.Fatalf("Get() = %v, %v", found, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+	if keys, err := s.Keys("jobs"); err != nil || !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("Keys() = %q, %v, want [a b]", keys, err)
+	}
+
+	// Replacing a value
+	want.Cues = 43
+	if err := s.Put("jobs", "b", want); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("jobs", "b", &got); err != nil || got.Cues != 43 {
+		t.Errorf("Get() after replacing = %+v, %v", got, err)
+	}
+
+	if err := s.Delete("jobs", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("jobs", "missing"); err != nil {
+		t.Errorf("Delete() of a missing key: %v", err)
+	}
+	if found, err := s.Get("jobs", "a", &got); err != nil || found {
+		t.Errorf("Get() after Delete() = %v, %v", found, err)
+	}
+	if keys, err := s.Keys("empty"); err != nil || len(keys) != 0 {
+		t.Errorf("Keys() of an empty bucket = %q, %v", keys, err)
+	}
+	if err := s.Check(); err != nil {
+		t.Errorf("Check(): %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The values outlive the connection
+	reopened, err := Connect("sqlite:"+filepath.Join(dir, "db", "store.db"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	var flag bool
+	if found, err := reopened.Get("other", "c", &flag); err != nil || !found || !flag {
+		t.Errorf("Get() after reopening = %v, %v, %v", flag, found, err)
+	}
+}
+
+func TestSQLiteImportsLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	legacy, err := Open(filepath.Join(dir, legacyFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := legacy.Put("jobs", "1", "done"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Connect("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var value string
+	if found, err := s.Get("jobs", "1", &value); err != nil || !found || value != "done" {
+		t.Errorf("Get() of an imported value = %q, %v, %v", value, found, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, legacyFileName+".imported")); err != nil {
+		t.Errorf("legacy file not renamed after importing: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Store is a small persistent key/value store. Values are JSON documents
+// grouped into buckets and kept by a Backend: a single JSON file, SQLite or
+// PostgreSQL.
+type Store struct {
+	backend Backend
+}
+
+// Backend keeps the encoded values of a store.
+type Backend interface {
+	// Get returns the value under bucket/key and whether it was found.
+	Get(bucket, key string) ([]byte, bool, error)
+	// Put stores value under bucket/key, replacing any previous value.
+	Put(bucket, key string, value []byte) error
+	// Delete removes bucket/key. Deleting a missing key is not an error.
+	Delete(bucket, key string) error
+	// Keys returns the keys in bucket in sorted order.
+	Keys(bucket string) ([]string, error)
+	// Check reports whether values can still be stored.
+	Check() error
+	Close() error
+}
+
+// New returns a store kept in backend.
+func New(backend Backend) *Store {
+	return &Store{backend: backend}
+}
+
+// Connect opens the store named by databaseURL:
+//   - empty, the default, for a SQLite database in dataDir
+//   - "sqlite:PATH" (or "sqlite://PATH") for a SQLite database at PATH
+//   - "postgres://..." (or "postgresql://...") for a PostgreSQL database,
+//     which several instances can share
+//   - "file:PATH" for the single JSON file older versions kept
+//
+// A new, empty database is filled from the JSON file older versions kept
+// in dataDir, which is renamed once it has been imported.
+func Connect(databaseURL, dataDir string) (*Store, error) {
+	legacyPath := filepath.Join(dataDir, legacyFileName)
+
+	var backend *sqlBackend
+	var err error
+	switch scheme, rest, _ := strings.Cut(databaseURL, ":"); {
+	case databaseURL == "":
+		backend, err = openSQLite(filepath.Join(dataDir, "subtitle-hunter.db"))
+	case scheme == "sqlite":
+		backend, err = openSQLite(strings.TrimPrefix(rest, "//"))
+	case scheme == "postgres" || scheme == "postgresql":
+		backend, err = openPostgres(databaseURL)
+	case scheme == "file":
+		return Open(strings.TrimPrefix(rest, "//"))
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_URL %q (expected sqlite:, postgres: or file:)", redact(databaseURL))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := backend.importLegacy(legacyPath); err != nil {
+		backend.Close()
+		return nil, err
+	}
+	return New(backend), nil
+}
+
+// redact hides the password in a database URL so it can be shown in errors.
+func redact(databaseURL string) string {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Redacted()
+}
+
+// Get decodes the value stored under bucket/key into v and reports whether
+// it was found.
+func (s *Store) Get(bucket, key string, v interface{}) (bool, error) {
+	raw, ok, err := s.backend.Get(bucket, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s/%s: %w", bucket, key, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("failed to decode %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// Put stores v under bucket/key and persists the store.
+func (s *Store) Put(bucket, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+	return s.backend.Put(bucket, key, raw)
+}
+
+// Delete removes bucket/key. Deleting a missing key is not an error.
+func (s *Store) Delete(bucket, key string) error {
+	return s.backend.Delete(bucket, key)
+}
+
+// Keys returns the keys in bucket in sorted order.
+func (s *Store) Keys(bucket string) ([]string, error) {
+	keys, err := s.backend.Keys(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", bucket, err)
+	}
+	return keys, nil
+}
+
+// Check reports whether the store can still be persisted.
+func (s *Store) Check() error {
+	return s.backend.Check()
+}
+
+// Close releases the store's database connections.
+func (s *Store) Close() error {
+	return s.backend.Close()
+}
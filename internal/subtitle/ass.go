@@ -0,0 +1,308 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	assTimestampRegex = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})\.(\d{2})$`)
+	assOverrideBlocks = regexp.MustCompile(`\{[^}]*\}`)
+	srtFormatTags     = strings.NewReplacer(`{\i1}`, "<i>", `{\i0}`, "</i>", `{\b1}`, "<b>", `{\b0}`, "</b>", `{\u1}`, "<u>", `{\u0}`, "</u>")
+)
+
+// IsASS reports whether content looks like an SSA or ASS script rather than
+// an SRT file.
+func IsASS(content []byte) bool {
+	text := strings.TrimSpace(strings.TrimPrefix(string(content), utf8BOM))
+	return strings.HasPrefix(strings.ToLower(text), "[script info]")
+}
+
+// ParseASS reads the dialogue of an SSA or ASS script into cues, so it can
+// be handled like an SRT file. Italics, bold and underline are kept as SRT
+// tags and all other styling is dropped. The cues are sorted by start time
+// and numbered from 1.
+func ParseASS(content []byte) ([]SubtitleEntry, error) {
+	text := strings.TrimPrefix(string(content), utf8BOM)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	// The Format line names the fields of each event; Text is always last
+	fields := []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+	inEvents := false
+	var entries []SubtitleEntry
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "Format:"); ok {
+			fields = nil
+			for _, field := range strings.Split(value, ",") {
+				fields = append(fields, strings.ToLower(strings.TrimSpace(field)))
+			}
+			continue
+		}
+		value, ok := strings.CutPrefix(line, "Dialogue:")
+		if !ok {
+			continue
+		}
+
+		values := strings.SplitN(value, ",", len(fields))
+		if len(values) != len(fields) {
+			continue
+		}
+		event := make(map[string]string, len(fields))
+		for i, field := range fields {
+			event[field] = values[i]
+		}
+
+		start, err := parseASSTimestamp(event["start"])
+		if err != nil {
+			continue
+		}
+		end, err := parseASSTimestamp(event["end"])
+		if err != nil {
+			continue
+		}
+		if cueText := srtText(event["text"]); cueText != "" {
+			entries = append(entries, SubtitleEntry{Start: start, End: end, Text: cueText})
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no dialogue found in ASS script")
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Start < entries[j].Start })
+	for i := range entries {
+		entries[i].Index = i + 1
+	}
+	return entries, nil
+}
+
+// parseASSTimestamp parses an ASS timestamp such as 0:01:02.35.
+func parseASSTimestamp(value string) (time.Duration, error) {
+	match := assTimestampRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid ASS timestamp %q", value)
+	}
+	var parts [4]int
+	for i, part := range match[1:] {
+		parts[i], _ = strconv.Atoi(part)
+	}
+	return time.Duration(parts[0])*time.Hour +
+		time.Duration(parts[1])*time.Minute +
+		time.Duration(parts[2])*time.Second +
+		time.Duration(parts[3])*10*time.Millisecond, nil
+}
+
+// srtText converts the text of an ASS event to SRT cue text, the reverse
+// of assText.
+func srtText(text string) string {
+	text = assOverrideBlocks.ReplaceAllString(srtFormatTags.Replace(text), "")
+	text = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(text)
+
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+var (
+	// assKaraokeTags time the syllables of a line; a translation can't keep
+	// them on the right words.
+	assKaraokeTags = regexp.MustCompile(`\\[kK][fo]?\d`)
+	// assDrawingTags switch a line to vector drawing commands, which aren't
+	// text.
+	assDrawingTags = regexp.MustCompile(`\\p[1-9]`)
+)
+
+// ASSScript is an SSA or ASS script kept line for line, so the dialogue can
+// be translated while everything else stays as it was: the script info,
+// the style definitions, comments and the override tags of each line.
+type ASSScript struct {
+	lines  []string
+	events []assEvent
+}
+
+// assEvent is a Dialogue line of an ASS script.
+type assEvent struct {
+	line   int
+	values []string
+	// start, end and text are the positions of those fields in values.
+	start, end, text int
+	// lead and trail are the override blocks before and after the dialogue
+	// text, such as {\an8} or {\pos(320,50)}; body is the text between
+	// them as cue text. Lines without text to translate have an empty
+	// body.
+	lead, body, trail string
+}
+
+// ParseASSScript reads an SSA or ASS script for translation.
+func ParseASSScript(content []byte) (*ASSScript, error) {
+	text := strings.TrimPrefix(string(content), utf8BOM)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	script := &ASSScript{lines: strings.Split(text, "\n")}
+
+	fields := []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+	inEvents := false
+	for i, line := range script.lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			inEvents = strings.EqualFold(trimmed, "[events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(trimmed, "Format:"); ok {
+			fields = nil
+			for _, field := range strings.Split(value, ",") {
+				fields = append(fields, strings.ToLower(strings.TrimSpace(field)))
+			}
+			continue
+		}
+		value, ok := strings.CutPrefix(trimmed, "Dialogue:")
+		if !ok {
+			continue
+		}
+
+		event := assEvent{line: i, values: strings.SplitN(value, ",", len(fields)), start: -1, end: -1, text: -1}
+		if len(event.values) != len(fields) {
+			continue
+		}
+		for index, field := range fields {
+			switch field {
+			case "start":
+				event.start = index
+			case "end":
+				event.end = index
+			case "text":
+				event.text = index
+			}
+		}
+		if event.start < 0 || event.end < 0 || event.text < 0 {
+			continue
+		}
+		if _, err := parseASSTimestamp(event.values[event.start]); err != nil {
+			continue
+		}
+		if _, err := parseASSTimestamp(event.values[event.end]); err != nil {
+			continue
+		}
+		event.lead, event.body, event.trail = splitASSText(event.values[event.text])
+		script.events = append(script.events, event)
+	}
+
+	if len(script.events) == 0 {
+		return nil, fmt.Errorf("no dialogue found in ASS script")
+	}
+	return script, nil
+}
+
+// splitASSText splits the text of a Dialogue line into the override blocks
+// before the dialogue, the dialogue as cue text and the blocks after it.
+// Override blocks in the middle of the line are dropped from the dialogue,
+// since the words they apply to don't survive translation. Karaoke and
+// drawing lines have no dialogue to translate.
+func splitASSText(text string) (lead, body, trail string) {
+	if assKaraokeTags.MatchString(text) || assDrawingTags.MatchString(text) {
+		return text, "", ""
+	}
+
+	start, end := 0, len(text)
+	blocks := assOverrideBlocks.FindAllStringIndex(text, -1)
+	for _, block := range blocks {
+		if block[0] != start {
+			break
+		}
+		start = block[1]
+	}
+	for i := len(blocks) - 1; i >= 0 && blocks[i][0] >= start; i-- {
+		if blocks[i][1] != end {
+			break
+		}
+		end = blocks[i][0]
+	}
+	if start >= end {
+		return text, "", ""
+	}
+
+	middle := assOverrideBlocks.ReplaceAllString(text[start:end], "")
+	middle = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(middle)
+	if strings.TrimSpace(middle) == "" {
+		return text, "", ""
+	}
+	return text[:start], strings.TrimSpace(middle), text[end:]
+}
+
+// Entries returns the dialogue to translate as cues. A cue's Index is the
+// number of its Dialogue line in the script, counting from 1, and lines
+// without dialogue to translate are left out.
+func (s *ASSScript) Entries() []SubtitleEntry {
+	var entries []SubtitleEntry
+	for i, event := range s.events {
+		if event.body == "" {
+			continue
+		}
+		start, _ := parseASSTimestamp(event.values[event.start])
+		end, _ := parseASSTimestamp(event.values[event.end])
+		entries = append(entries, SubtitleEntry{Index: i + 1, Start: start, End: end, Text: event.body})
+	}
+	return entries
+}
+
+// Apply puts the text of the cues, as returned by Entries, back into their
+// Dialogue lines between the override blocks the lines had.
+func (s *ASSScript) Apply(entries []SubtitleEntry) {
+	for _, entry := range entries {
+		if entry.Index < 1 || entry.Index > len(s.events) {
+			continue
+		}
+		event := &s.events[entry.Index-1]
+		text := strings.ReplaceAll(strings.TrimSpace(entry.Text), "\n", `\N`)
+		event.values[event.text] = event.lead + text + event.trail
+	}
+}
+
+// Shift moves every Dialogue line by offset. Lines that would start before
+// zero start at zero.
+func (s *ASSScript) Shift(offset time.Duration) {
+	for i := range s.events {
+		event := &s.events[i]
+		for _, field := range []int{event.start, event.end} {
+			at, _ := parseASSTimestamp(event.values[field])
+			event.values[field] = formatASSTimestamp(max(at+offset, 0))
+		}
+	}
+}
+
+// Format returns the script with the changed Dialogue lines.
+func (s *ASSScript) Format() string {
+	lines := append([]string(nil), s.lines...)
+	for _, event := range s.events {
+		lines[event.line] = "Dialogue:" + strings.Join(event.values, ",")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatASSTimestamp formats a time as an ASS timestamp such as 0:01:02.35.
+func formatASSTimestamp(d time.Duration) string {
+	centiseconds := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", centiseconds/360000, centiseconds/6000%60, centiseconds/100%60, centiseconds%100)
+}
@@ -0,0 +1,50 @@
+package subtitle
+
+import "strings"
+
+// Bilingual pairs each translated cue with the cue it was translated from,
+// so a learner sees the original text with the translation on the lines
+// below it. originals and translated must line up cue for cue. Cues whose
+// translation failed (failed holds their Index) keep the fallback text
+// alone rather than repeating the original.
+func Bilingual(originals, translated []SubtitleEntry, failed []int) []SubtitleEntry {
+	skip := make(map[int]bool, len(failed))
+	for _, index := range failed {
+		skip[index] = true
+	}
+
+	combined := make([]SubtitleEntry, len(translated))
+	for i, entry := range translated {
+		combined[i] = entry
+		if i < len(originals) && !skip[entry.Index] {
+			combined[i].Text = BilingualText(originals[i].Text, entry.Text)
+		}
+	}
+	return combined
+}
+
+// BilingualText is the text of a bilingual cue: original on top of
+// translation. A translation that is empty or the same as the original is
+// not doubled up.
+func BilingualText(original, translation string) string {
+	original = strings.TrimSpace(original)
+	translation = strings.TrimSpace(translation)
+	if original == "" || translation == "" || translation == original {
+		return original + translation
+	}
+	return original + "\n" + translation
+}
+
+// SplitBilingual returns the translation part of text when it is a
+// bilingual cue made from original by BilingualText.
+func SplitBilingual(text, original string) (string, bool) {
+	original = strings.TrimSpace(original)
+	if original == "" {
+		return text, false
+	}
+	translation, ok := strings.CutPrefix(strings.TrimSpace(text), original+"\n")
+	if !ok || strings.TrimSpace(translation) == "" {
+		return text, false
+	}
+	return strings.TrimSpace(translation), true
+}
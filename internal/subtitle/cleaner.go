@@ -0,0 +1,69 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// builtinSpamPatterns match the promotional cues commonly injected by
+// subtitle sites and release groups.
+var builtinSpamPatterns = []string{
+	`(?i)\bdownloaded\s+from\b`,
+	`(?i)\b(ripped|encoded)\s+by\b`,
+	`(?i)\b(subtitles?|subs|captions?|sync(ed)?|resync(ed)?|corrected|transcript|translation)\s+(by|from)\b`,
+	`(?i)\bsync(ed)?\s*(&|and)\s*correct(ed|ions)?\b`,
+	`(?i)opensubtitles|subscene|addic7ed|podnapisi|yify|yts\.(mx|am|lt)|subhd|zimuku|射手网|字幕组`,
+	`(?i)advertise your product or brand here`,
+	`(?i)support us and become vip member`,
+	`(?i)https?://|www\.[a-z0-9-]+\.[a-z]{2,}`,
+}
+
+// Cleaner removes advertising and spam cues from parsed subtitles.
+type Cleaner struct {
+	patterns []*regexp.Regexp
+}
+
+// NewCleaner compiles the built-in spam patterns together with any
+// user-supplied regular expressions.
+func NewCleaner(extraPatterns []string) (*Cleaner, error) {
+	c := &Cleaner{}
+
+	for _, pattern := range append(append([]string{}, builtinSpamPatterns...), extraPatterns...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clean pattern %q: %w", pattern, err)
+		}
+		c.patterns = append(c.patterns, re)
+	}
+
+	return c, nil
+}
+
+// IsSpam reports whether the cue text matches any cleaning pattern.
+func (c *Cleaner) IsSpam(text string) bool {
+	for _, re := range c.patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clean strips spam cues and renumbers the remaining entries sequentially.
+// It returns the cleaned entries and the number of cues removed.
+func (c *Cleaner) Clean(entries []SubtitleEntry) ([]SubtitleEntry, int) {
+	cleaned := make([]SubtitleEntry, 0, len(entries))
+	removed := 0
+
+	for _, entry := range entries {
+		if c.IsSpam(entry.Text) {
+			removed++
+			continue
+		}
+
+		entry.Index = len(cleaned) + 1
+		cleaned = append(cleaned, entry)
+	}
+
+	return cleaned, removed
+}
@@ -0,0 +1,90 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	FallbackOriginal = "original"
+	FallbackEmpty    = "empty"
+	FallbackMark     = "mark"
+)
+
+// FallbackPolicy decides what ends up in a cue whose translation failed,
+// how many failures a job tolerates before it is aborted, and how many make
+// the saved subtitle partial.
+type FallbackPolicy struct {
+	Mode              string
+	Marker            string
+	MaxFailurePercent float64
+	// PartialPercent is the failure rate above which a subtitle is partial.
+	PartialPercent float64
+}
+
+func NewFallbackPolicy(mode, marker string, maxFailurePercent, partialPercent float64) (FallbackPolicy, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		mode = FallbackOriginal
+	case FallbackOriginal, FallbackEmpty, FallbackMark:
+	default:
+		return FallbackPolicy{}, fmt.Errorf("unknown translation fallback mode %q (expected original, empty or mark)", mode)
+	}
+
+	return FallbackPolicy{
+		Mode:              mode,
+		Marker:            marker,
+		MaxFailurePercent: maxFailurePercent,
+		PartialPercent:    partialPercent,
+	}, nil
+}
+
+// Apply returns the text to use for a cue that could not be translated.
+func (p FallbackPolicy) Apply(original string) string {
+	switch p.Mode {
+	case FallbackEmpty:
+		return ""
+	case FallbackMark:
+		return p.Marker + " " + original
+	default:
+		return original
+	}
+}
+
+// Exceeded reports whether the failure rate is above the configured limit.
+func (p FallbackPolicy) Exceeded(report TranslationReport) bool {
+	return report.Total > 0 && report.FailureRate() > p.MaxFailurePercent
+}
+
+// Partial reports whether enough cues fell back for the subtitle to count
+// as only partly translated. Any failure counts when PartialPercent is 0.
+func (p FallbackPolicy) Partial(report TranslationReport) bool {
+	return report.Failed > 0 && report.FailureRate() > p.PartialPercent
+}
+
+// TranslationReport summarizes how a translation run went.
+type TranslationReport struct {
+	Total         int
+	Failed        int
+	FailedIndexes []int
+	// Partial is set when more cues fell back than the policy's
+	// PartialPercent allows.
+	Partial bool
+}
+
+// FailureRate returns the percentage of cues that fell back.
+func (r TranslationReport) FailureRate() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Total) * 100
+}
+
+func (r TranslationReport) String() string {
+	message := fmt.Sprintf("%d of %d cues failed to translate (%.1f%%)", r.Failed, r.Total, r.FailureRate())
+	if r.Partial {
+		message += ", so the subtitle is only partly translated"
+	}
+	return message
+}
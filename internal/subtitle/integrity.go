@@ -0,0 +1,34 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VerifyOutput sanity-checks formatted subtitle content before it is written:
+// it must be non-empty, parse back into cues, and keep at least minRatio of
+// the sourceCues it was produced from. A sourceCues of 0 skips the ratio check.
+// ASS scripts are checked by their dialogue.
+func (p *SRTParser) VerifyOutput(content []byte, sourceCues int, minRatio float64) error {
+	if strings.TrimSpace(string(content)) == "" {
+		return fmt.Errorf("subtitle content is empty")
+	}
+
+	parse := p.Parse
+	if IsASS(content) {
+		parse = ParseASS
+	}
+	entries, err := parse(content)
+	if err != nil {
+		return fmt.Errorf("subtitle content does not parse: %w", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("subtitle content contains no cues")
+	}
+
+	if sourceCues > 0 && float64(len(entries)) < float64(sourceCues)*minRatio {
+		return fmt.Errorf("subtitle has %d cues but was produced from %d (minimum ratio %.2f)", len(entries), sourceCues, minRatio)
+	}
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Readability rules checked by Lint.
+const (
+	RuleTooManyLines = "too-many-lines"
+	RuleTooShort     = "too-short"
+	RuleSmallGap     = "small-gap"
+)
+
+// Fixes ApplyFix can make for a LintIssue.
+const (
+	// FixExtend lengthens a short cue into the gap before the next one.
+	FixExtend = "extend"
+	// FixTrim shortens a cue so the gap to the next one reaches the minimum.
+	FixTrim = "trim"
+	// FixMerge joins a cue with its neighbour.
+	FixMerge = "merge"
+	// FixRewrap rewraps a cue's text into the maximum number of lines.
+	FixRewrap = "rewrap"
+)
+
+// LintRules are the limits Lint checks cues against.
+type LintRules struct {
+	MaxLines    int
+	MinDuration time.Duration
+	MinGap      time.Duration
+}
+
+// DefaultLintRules follow common professional subtitling conventions: two
+// lines at most, at least 700ms on screen and a gap of two frames at 24fps
+// between cues.
+var DefaultLintRules = LintRules{
+	MaxLines:    2,
+	MinDuration: 700 * time.Millisecond,
+	MinGap:      83 * time.Millisecond,
+}
+
+// LintIssue is one readability problem found in a cue.
+type LintIssue struct {
+	// Cue is the position of the cue in the entries, starting at 0.
+	Cue     int      `json:"cue"`
+	Index   int      `json:"index"`
+	Rule    string   `json:"rule"`
+	Message string   `json:"message"`
+	Fixes   []string `json:"fixes"`
+}
+
+// Lint checks every cue against rules and returns the problems found, in
+// cue order.
+func Lint(entries []SubtitleEntry, rules LintRules) []LintIssue {
+	var issues []LintIssue
+
+	for i, entry := range entries {
+		if lines := lineCount(entry.Text); rules.MaxLines > 0 && lines > rules.MaxLines {
+			issues = append(issues, LintIssue{
+				Cue:     i,
+				Index:   entry.Index,
+				Rule:    RuleTooManyLines,
+				Message: fmt.Sprintf("%d lines, at most %d allowed", lines, rules.MaxLines),
+				Fixes:   []string{FixRewrap},
+			})
+		}
+
+		if duration := entry.Duration(); duration < rules.MinDuration {
+			var fixes []string
+			if extendedEnd(entries, i, rules) > entry.End {
+				fixes = append(fixes, FixExtend)
+			}
+			if len(entries) > 1 {
+				fixes = append(fixes, FixMerge)
+			}
+			issues = append(issues, LintIssue{
+				Cue:     i,
+				Index:   entry.Index,
+				Rule:    RuleTooShort,
+				Message: fmt.Sprintf("on screen for %dms, at least %dms needed", duration.Milliseconds(), rules.MinDuration.Milliseconds()),
+				Fixes:   fixes,
+			})
+		}
+
+		if i+1 == len(entries) {
+			continue
+		}
+		next := entries[i+1].Start
+		if gap := next - entry.End; gap < rules.MinGap {
+			fixes := []string{FixMerge}
+			if next-rules.MinGap > entry.Start {
+				fixes = append([]string{FixTrim}, fixes...)
+			}
+			issues = append(issues, LintIssue{
+				Cue:     i,
+				Index:   entry.Index,
+				Rule:    RuleSmallGap,
+				Message: fmt.Sprintf("%dms gap to the next cue, at least %dms needed", gap.Milliseconds(), rules.MinGap.Milliseconds()),
+				Fixes:   fixes,
+			})
+		}
+	}
+
+	return issues
+}
+
+// ApplyFix applies fix to the cue at position cue and returns the fixed
+// entries, renumbered. The entries passed in are not modified.
+func ApplyFix(entries []SubtitleEntry, cue int, fix string, rules LintRules) ([]SubtitleEntry, error) {
+	if cue < 0 || cue >= len(entries) {
+		return nil, fmt.Errorf("cue %d out of range", cue)
+	}
+	fixed := append([]SubtitleEntry{}, entries...)
+
+	switch fix {
+	case FixRewrap:
+		fixed[cue].Text = rewrap(fixed[cue].Text, rules.MaxLines)
+
+	case FixExtend:
+		newEnd := extendedEnd(fixed, cue, rules)
+		if newEnd <= fixed[cue].End {
+			return nil, fmt.Errorf("no room to extend cue %d", fixed[cue].Index)
+		}
+		fixed[cue].End = newEnd
+
+	case FixTrim:
+		if cue+1 == len(fixed) {
+			return nil, fmt.Errorf("cue %d is the last cue", fixed[cue].Index)
+		}
+		start, end, next := fixed[cue].Start, fixed[cue].End, fixed[cue+1].Start
+		newEnd := next - rules.MinGap
+		if newEnd >= end {
+			return nil, fmt.Errorf("cue %d already ends %dms before the next cue", fixed[cue].Index, (next - end).Milliseconds())
+		}
+		if newEnd <= start {
+			return nil, fmt.Errorf("cue %d is too short to trim", fixed[cue].Index)
+		}
+		fixed[cue].End = newEnd
+
+	case FixMerge:
+		if len(fixed) < 2 {
+			return nil, fmt.Errorf("no neighbouring cue to merge with")
+		}
+		// Merge with the next cue, or with the previous one for the last cue.
+		first := cue
+		if cue+1 == len(fixed) {
+			first = cue - 1
+		}
+		merged := fixed[first]
+		merged.End = fixed[first+1].End
+		merged.Text = strings.TrimSpace(merged.Text + "\n" + fixed[first+1].Text)
+		fixed = append(append(fixed[:first:first], merged), fixed[first+2:]...)
+
+	default:
+		return nil, fmt.Errorf("unknown fix %q", fix)
+	}
+
+	for i := range fixed {
+		fixed[i].Index = i + 1
+	}
+	return fixed, nil
+}
+
+// extendedEnd returns the end time cue i can be extended to: the minimum
+// duration, limited by the minimum gap before the next cue.
+func extendedEnd(entries []SubtitleEntry, i int, rules LintRules) time.Duration {
+	newEnd := entries[i].Start + rules.MinDuration
+	if i+1 < len(entries) && entries[i+1].Start-rules.MinGap < newEnd {
+		newEnd = entries[i+1].Start - rules.MinGap
+	}
+	if newEnd < entries[i].End {
+		return entries[i].End
+	}
+	return newEnd
+}
+
+// rewrap joins the text of a cue with more than maxLines lines and splits
+// it again into maxLines lines of similar length.
+func rewrap(text string, maxLines int) string {
+	if maxLines < 1 || lineCount(text) <= maxLines {
+		return text
+	}
+	return balanceLines(text, maxLines)
+}
+
+// textUnit is a piece of text a line may break before.
+type textUnit struct {
+	text string
+	// space is whether a space separates the unit from the one before.
+	space bool
+}
+
+// textUnits splits text into the pieces a line may break between: words,
+// and single characters of text written without spaces, such as Chinese,
+// also where it is mixed with words ("我們走吧 OK"). Line breaks become
+// spaces, except between two such characters.
+func textUnits(text string) []textUnit {
+	var units []textUnit
+	for i, line := range strings.Split(text, "\n") {
+		for j, field := range strings.Fields(line) {
+			space := j > 0
+			if i > 0 && j == 0 && len(units) > 0 {
+				last, _ := utf8.DecodeLastRuneInString(units[len(units)-1].text)
+				first, _ := utf8.DecodeRuneInString(field)
+				space = !breaksAnywhere(last) || !breaksAnywhere(first)
+			}
+
+			start := 0
+			for k, r := range field {
+				if !breaksAnywhere(r) {
+					continue
+				}
+				if k > start {
+					units = append(units, textUnit{text: field[start:k], space: space})
+					space = false
+				}
+				units = append(units, textUnit{text: string(r), space: space})
+				space = false
+				start = k + utf8.RuneLen(r)
+			}
+			if start < len(field) {
+				units = append(units, textUnit{text: field[start:], space: space})
+			}
+		}
+	}
+	return units
+}
+
+// joinUnits returns units as one line.
+func joinUnits(units []textUnit) string {
+	var b strings.Builder
+	for i, unit := range units {
+		if i > 0 && unit.space {
+			b.WriteByte(' ')
+		}
+		b.WriteString(unit.text)
+	}
+	return b.String()
+}
+
+// breaksAnywhere reports whether r is written without spaces, so a line
+// may break before or after it: Chinese and Japanese characters and
+// full-width punctuation.
+func breaksAnywhere(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Bopomofo) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff01 && r <= 0xff60)
+}
+
+// balanceLines joins the lines of text and splits it again into n lines of
+// similar length (fewer when there aren't enough words).
+func balanceLines(text string, n int) string {
+	units := textUnits(text)
+	total := utf8.RuneCountInString(joinUnits(units))
+	lines := make([]string, 0, n)
+	var line []textUnit
+	length := 0
+	for i, unit := range units {
+		size := utf8.RuneCountInString(unit.text)
+		if i > 0 && unit.space {
+			size++
+		}
+		// Break before the unit when most of it would fall past this
+		// line's share of the text.
+		target := total * (len(lines) + 1) / n
+		if len(line) > 0 && len(lines) < n-1 && length+size/2 >= target {
+			lines = append(lines, joinUnits(line))
+			line = nil
+		}
+		line = append(line, unit)
+		length += size
+	}
+	lines = append(lines, joinUnits(line))
+	return strings.Join(lines, "\n")
+}
+
+func lineCount(text string) int {
+	if strings.TrimSpace(text) == "" {
+		return 0
+	}
+	return len(strings.Split(strings.TrimSpace(text), "\n"))
+}
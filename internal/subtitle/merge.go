@@ -0,0 +1,119 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MergedCue is a cue of a subtitle merged from two tracks, with the text of
+// the top track shown above the text of the bottom one. Either side is
+// empty where only one track has a cue.
+type MergedCue struct {
+	Start  time.Duration
+	End    time.Duration
+	Top    string
+	Bottom string
+}
+
+// MergeTracks combines two tracks of the same video, such as an English
+// and a Traditional Chinese subtitle, into bilingual cues. The cues are
+// aligned by time: every bottom cue joins the top cue it overlaps the most
+// and keeps the top cue's timing, so the top track sets the pace. Bottom
+// cues that overlap no top cue are kept on their own.
+func MergeTracks(top, bottom []SubtitleEntry) []MergedCue {
+	cues := make([]MergedCue, 0, len(top)+len(bottom))
+	for _, entry := range top {
+		cues = append(cues, MergedCue{Start: entry.Start, End: entry.End, Top: strings.TrimSpace(entry.Text)})
+	}
+
+	for _, entry := range bottom {
+		text := strings.TrimSpace(entry.Text)
+
+		best, bestOverlap := -1, time.Duration(0)
+		for i, topEntry := range top {
+			if overlap := entry.Overlap(topEntry); overlap > bestOverlap {
+				best, bestOverlap = i, overlap
+			}
+		}
+		if best < 0 {
+			cues = append(cues, MergedCue{Start: entry.Start, End: entry.End, Bottom: text})
+			continue
+		}
+		if cues[best].Bottom != "" {
+			text = cues[best].Bottom + "\n" + text
+		}
+		cues[best].Bottom = text
+	}
+
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+	return cues
+}
+
+// MergedEntries turns merged cues into numbered SRT entries, the top text
+// on the lines above the bottom text.
+func MergedEntries(cues []MergedCue) []SubtitleEntry {
+	entries := make([]SubtitleEntry, len(cues))
+	for i, cue := range cues {
+		text := cue.Top
+		if text != "" && cue.Bottom != "" {
+			text += "\n"
+		}
+		entries[i] = SubtitleEntry{Index: i + 1, Start: cue.Start, End: cue.End, Text: text + cue.Bottom}
+	}
+	return entries
+}
+
+// assHeader sets up a "Bottom" style for the main subtitle and a smaller,
+// dimmer "Top" style for the line above it.
+const assHeader = `[Script Info]
+Title: %s
+ScriptType: v4.00+
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+PlayResX: 1920
+PlayResY: 1080
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Bottom,Arial,64,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,3,1,2,60,60,40,1
+Style: Top,Arial,48,&H00D0D0D0,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,2,60,60,40,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+// FormatASS renders merged cues as an Advanced SubStation Alpha script,
+// which unlike SRT lets players show the two languages in different sizes.
+func FormatASS(title string, cues []MergedCue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, assHeader, strings.ReplaceAll(title, "\n", " "))
+	for _, cue := range cues {
+		var text string
+		if cue.Top != "" {
+			text = `{\rTop}` + assText(cue.Top)
+			if cue.Bottom != "" {
+				text += `\N{\r}`
+			}
+		}
+		text += assText(cue.Bottom)
+		fmt.Fprintf(&b, "Dialogue: 0,%s,%s,Bottom,,0,0,0,,%s\n", FormatASSTimestamp(cue.Start), FormatASSTimestamp(cue.End), text)
+	}
+	return b.String()
+}
+
+var (
+	assFormatTags = strings.NewReplacer("<i>", `{\i1}`, "</i>", `{\i0}`, "<b>", `{\b1}`, "</b>", `{\b0}`, "<u>", `{\u1}`, "</u>", `{\u0}`)
+	otherTags     = regexp.MustCompile(`<[^>]*>`)
+)
+
+// assText converts SRT cue text to an ASS event: line breaks become \N,
+// italics, bold and underline become override tags and anything else that
+// ASS would read as markup is dropped.
+func assText(text string) string {
+	text = strings.NewReplacer("{", "(", "}", ")").Replace(text)
+	text = otherTags.ReplaceAllString(assFormatTags.Replace(text), "")
+	return strings.ReplaceAll(strings.TrimSpace(text), "\n", `\N`)
+}
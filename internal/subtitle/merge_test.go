@@ -0,0 +1,154 @@
+package subtitle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeTracks(t *testing.T) {
+	cue := func(start, end time.Duration, text string) SubtitleEntry {
+		return SubtitleEntry{Start: start, End: end, Text: text}
+	}
+	top := []SubtitleEntry{
+		cue(1*time.Second, 3*time.Second, "Hello."),
+		cue(4*time.Second, 6*time.Second, "How are you?"),
+	}
+
+	tests := []struct {
+		name   string
+		top    []SubtitleEntry
+		bottom []SubtitleEntry
+		want   []MergedCue
+	}{
+		{
+			name:   "same timing",
+			top:    top,
+			bottom: []SubtitleEntry{cue(1*time.Second, 3*time.Second, "你好。"), cue(4*time.Second, 6*time.Second, "你好嗎？")},
+			want: []MergedCue{
+				{Start: 1 * time.Second, End: 3 * time.Second, Top: "Hello.", Bottom: "你好。"},
+				{Start: 4 * time.Second, End: 6 * time.Second, Top: "How are you?", Bottom: "你好嗎？"},
+			},
+		},
+		{
+			name:   "offset track keeps the top timing",
+			top:    top,
+			bottom: []SubtitleEntry{cue(1500*time.Millisecond, 3500*time.Millisecond, "你好。"), cue(4500*time.Millisecond, 6500*time.Millisecond, "你好嗎？")},
+			want: []MergedCue{
+				{Start: 1 * time.Second, End: 3 * time.Second, Top: "Hello.", Bottom: "你好。"},
+				{Start: 4 * time.Second, End: 6 * time.Second, Top: "How are you?", Bottom: "你好嗎？"},
+			},
+		},
+		{
+			name:   "joins the cue it overlaps the most",
+			top:    top,
+			bottom: []SubtitleEntry{cue(2500*time.Millisecond, 5500*time.Millisecond, "你好嗎？")},
+			want: []MergedCue{
+				{Start: 1 * time.Second, End: 3 * time.Second, Top: "Hello."},
+				{Start: 4 * time.Second, End: 6 * time.Second, Top: "How are you?", Bottom: "你好嗎？"},
+			},
+		},
+		{
+			name: "many bottom cues for one top cue",
+			top:  top[:1],
+			bottom: []SubtitleEntry{
+				cue(1*time.Second, 2*time.Second, "你好，"),
+				cue(2*time.Second, 3*time.Second, "朋友。"),
+			},
+			want: []MergedCue{
+				{Start: 1 * time.Second, End: 3 * time.Second, Top: "Hello.", Bottom: "你好，\n朋友。"},
+			},
+		},
+		{
+			name:   "one bottom cue over many top cues",
+			top:    top,
+			bottom: []SubtitleEntry{cue(1500*time.Millisecond, 6*time.Second, "你好，你好嗎？")},
+			want: []MergedCue{
+				{Start: 1 * time.Second, End: 3 * time.Second, Top: "Hello."},
+				{Start: 4 * time.Second, End: 6 * time.Second, Top: "How are you?", Bottom: "你好，你好嗎？"},
+			},
+		},
+		{
+			name:   "equal overlaps join the earlier cue",
+			top:    top,
+			bottom: []SubtitleEntry{cue(1*time.Second, 6*time.Second, "你好，你好嗎？")},
+			want: []MergedCue{
+				{Start: 1 * time.Second, End: 3 * time.Second, Top: "Hello.", Bottom: "你好，你好嗎？"},
+				{Start: 4 * time.Second, End: 6 * time.Second, Top: "How are you?"},
+			},
+		},
+		{
+			name:   "bottom cue without a top cue keeps its timing",
+			top:    top,
+			bottom: []SubtitleEntry{cue(3*time.Second, 4*time.Second, "（音樂）"), cue(7*time.Second, 8*time.Second, "再見。")},
+			want: []MergedCue{
+				{Start: 1 * time.Second, End: 3 * time.Second, Top: "Hello."},
+				{Start: 3 * time.Second, End: 4 * time.Second, Bottom: "（音樂）"},
+				{Start: 4 * time.Second, End: 6 * time.Second, Top: "How are you?"},
+				{Start: 7 * time.Second, End: 8 * time.Second, Bottom: "再見。"},
+			},
+		},
+		{
+			name:   "empty bottom track",
+			top:    top,
+			bottom: nil,
+			want: []MergedCue{
+				{Start: 1 * time.Second, End: 3 * time.Second, Top: "Hello."},
+				{Start: 4 * time.Second, End: 6 * time.Second, Top: "How are you?"},
+			},
+		},
+		{
+			name:   "empty top track",
+			top:    nil,
+			bottom: []SubtitleEntry{cue(1*time.Second, 3*time.Second, " 你好。 ")},
+			want: []MergedCue{
+				{Start: 1 * time.Second, End: 3 * time.Second, Bottom: "你好。"},
+			},
+		},
+		{
+			name: "both empty",
+			want: []MergedCue{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeTracks(tt.top, tt.bottom); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeTracks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergedEntries(t *testing.T) {
+	cues := []MergedCue{
+		{Start: 1 * time.Second, End: 3 * time.Second, Top: "Hello.", Bottom: "你好。"},
+		{Start: 3 * time.Second, End: 4 * time.Second, Bottom: "（音樂）"},
+		{Start: 4 * time.Second, End: 6 * time.Second, Top: "How are you?"},
+	}
+	want := []SubtitleEntry{
+		{Index: 1, Start: 1 * time.Second, End: 3 * time.Second, Text: "Hello.\n你好。"},
+		{Index: 2, Start: 3 * time.Second, End: 4 * time.Second, Text: "（音樂）"},
+		{Index: 3, Start: 4 * time.Second, End: 6 * time.Second, Text: "How are you?"},
+	}
+	if got := MergedEntries(cues); !reflect.DeepEqual(got, want) {
+		t.Errorf("MergedEntries() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFormatASS(t *testing.T) {
+	cues := []MergedCue{
+		{Start: 1 * time.Second, End: 3 * time.Second, Top: "<i>Hello</i> {there}\nfriend", Bottom: "你好。"},
+		{Start: 3 * time.Second, End: 4 * time.Second, Bottom: `<font color="red">（音樂）</font>`},
+	}
+	got := FormatASS("Show\nS01E01", cues)
+	for _, want := range []string{
+		"Title: Show S01E01\n",
+		"Dialogue: 0,0:00:01.00,0:00:03.00,Bottom,,0,0,0,,{\\rTop}{\\i1}Hello{\\i0} (there)\\Nfriend\\N{\\r}你好。\n",
+		"Dialogue: 0,0:00:03.00,0:00:04.00,Bottom,,0,0,0,,（音樂）\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatASS() = %q, want it to contain %q", got, want)
+		}
+	}
+}
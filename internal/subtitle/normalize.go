@@ -0,0 +1,63 @@
+package subtitle
+
+import (
+	"sort"
+	"strings"
+)
+
+// NormalizeStats counts what Normalize changed.
+type NormalizeStats struct {
+	// Duplicates are cues dropped because the cue before had the same text
+	// and overlapped or touched them.
+	Duplicates int
+	// ZeroDuration are cues dropped because they ended before they started.
+	ZeroDuration int
+	// Reordered is set when cues were out of time order.
+	Reordered bool
+	// Renumbered are cues whose index changed.
+	Renumbered int
+}
+
+// Changed reports whether Normalize changed anything.
+func (s NormalizeStats) Changed() bool {
+	return s.Duplicates > 0 || s.ZeroDuration > 0 || s.Reordered || s.Renumbered > 0
+}
+
+// Normalize tidies up a subtitle for players that choke on sloppy files:
+// cues are put in time order, cues with no time on screen are dropped,
+// adjacent cues repeating the same text are joined into one and the rest
+// are numbered from 1.
+func Normalize(entries []SubtitleEntry) ([]SubtitleEntry, NormalizeStats) {
+	var stats NormalizeStats
+
+	sorted := append([]SubtitleEntry{}, entries...)
+	stats.Reordered = !sort.SliceIsSorted(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	if stats.Reordered {
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	}
+
+	normalized := make([]SubtitleEntry, 0, len(sorted))
+	for _, entry := range sorted {
+		if entry.End <= entry.Start {
+			stats.ZeroDuration++
+			continue
+		}
+		if n := len(normalized); n > 0 {
+			previous := &normalized[n-1]
+			if strings.TrimSpace(previous.Text) == strings.TrimSpace(entry.Text) && entry.Start <= previous.End {
+				previous.End = max(previous.End, entry.End)
+				stats.Duplicates++
+				continue
+			}
+		}
+		normalized = append(normalized, entry)
+	}
+
+	for i := range normalized {
+		if normalized[i].Index != i+1 {
+			normalized[i].Index = i + 1
+			stats.Renumbered++
+		}
+	}
+	return normalized, stats
+}
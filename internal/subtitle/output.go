@@ -0,0 +1,26 @@
+package subtitle
+
+import "strings"
+
+const utf8BOM = "\ufeff"
+
+// OutputStyle controls the byte layout of subtitle files as they are
+// written. Some TVs only render CJK subtitles correctly when the file starts
+// with a UTF-8 byte order mark and uses CRLF line endings.
+type OutputStyle struct {
+	BOM  bool
+	CRLF bool
+}
+
+// Apply returns content with the style's line endings and byte order mark.
+func (s OutputStyle) Apply(content []byte) []byte {
+	text := strings.TrimPrefix(string(content), utf8BOM)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if s.CRLF {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	if s.BOM {
+		text = utf8BOM + text
+	}
+	return []byte(text)
+}
@@ -1,6 +1,7 @@
 package subtitle
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"regexp"
@@ -10,26 +11,31 @@ import (
 )
 
 type SubtitleEntry struct {
-	Index     int
-	StartTime string
-	EndTime   string
-	Text      string
+	Index int
+	// Start and End are measured from the start of the video.
+	Start time.Duration
+	End   time.Duration
+	Text  string
 }
 
-type SRTParser struct{}
+type SRTParser struct {
+	// ContextWindow is the number of cues before and after the target cue
+	// sent as context to translators that support it. 0 disables context.
+	ContextWindow int
+}
 
 func NewSRTParser() *SRTParser {
 	return &SRTParser{}
 }
 
 func (p *SRTParser) Parse(content []byte) ([]SubtitleEntry, error) {
-	text := string(content)
+	text := strings.TrimPrefix(string(content), utf8BOM)
 	text = strings.ReplaceAll(text, "\r\n", "\n")
 	
 	blocks := strings.Split(text, "\n\n")
 	var entries []SubtitleEntry
 	
-	timeRegex := regexp.MustCompile(`(\d{2}:\d{2}:\d{2},\d{3})\s*-->\s*(\d{2}:\d{2}:\d{2},\d{3})`)
+	timeRegex := regexp.MustCompile(`(\d+:\d{2}:\d{2}[,.]\d{3})\s*-->\s*(\d+:\d{2}:\d{2}[,.]\d{3})`)
 	
 	for _, block := range blocks {
 		block = strings.TrimSpace(block)
@@ -37,8 +43,9 @@ func (p *SRTParser) Parse(content []byte) ([]SubtitleEntry, error) {
 			continue
 		}
 		
+		// Cues with an index and timing but no text are kept as empty cues
 		lines := strings.Split(block, "\n")
-		if len(lines) < 3 {
+		if len(lines) < 2 {
 			continue
 		}
 		
@@ -51,15 +58,23 @@ func (p *SRTParser) Parse(content []byte) ([]SubtitleEntry, error) {
 		if len(timeMatch) != 3 {
 			continue
 		}
+		start, err := ParseTimestamp(timeMatch[1])
+		if err != nil {
+			continue
+		}
+		end, err := ParseTimestamp(timeMatch[2])
+		if err != nil {
+			continue
+		}
 		
 		text := strings.Join(lines[2:], "\n")
 		text = strings.TrimSpace(text)
 		
 		entries = append(entries, SubtitleEntry{
-			Index:     index,
-			StartTime: timeMatch[1],
-			EndTime:   timeMatch[2],
-			Text:      text,
+			Index: index,
+			Start: start,
+			End:   end,
+			Text:  text,
 		})
 	}
 	
@@ -75,7 +90,7 @@ func (p *SRTParser) Format(entries []SubtitleEntry) string {
 		}
 		
 		result.WriteString(fmt.Sprintf("%d\n", entry.Index))
-		result.WriteString(fmt.Sprintf("%s --> %s\n", entry.StartTime, entry.EndTime))
+		result.WriteString(fmt.Sprintf("%s --> %s\n", FormatSRTTimestamp(entry.Start), FormatSRTTimestamp(entry.End)))
 		result.WriteString(entry.Text)
 		result.WriteString("\n")
 	}
@@ -83,34 +98,121 @@ func (p *SRTParser) Format(entries []SubtitleEntry) string {
 	return result.String()
 }
 
-func (p *SRTParser) TranslateEntries(entries []SubtitleEntry, translator Translator) ([]SubtitleEntry, error) {
+// TranslateEntries translates the cues, applying policy to those that fail,
+// and reports each one to progress, which may be nil. When ctx ends it stops
+// and returns the cues translated so far, in order, with ctx's error.
+func (p *SRTParser) TranslateEntries(ctx context.Context, entries []SubtitleEntry, translator Translator, policy FallbackPolicy, progress Progress) ([]SubtitleEntry, TranslationReport, error) {
 	var translated []SubtitleEntry
+	report := TranslationReport{Total: len(entries)}
+	if progress == nil {
+		progress = noProgress{}
+	}
+	progress.Translating(len(entries))
 	
 	for i, entry := range entries {
 		if i%10 == 0 {
 			log.Printf("Translating entry %d/%d...", i+1, len(entries))
 		}
 		
-		translatedText, err := p.translateWithRetry(translator, entry.Text, 3)
+		translatedText, err := p.translateEntry(ctx, entries, i, translator)
+		if ctx.Err() != nil {
+			// A cancelled job must not fill the remaining cues with
+			// fallbacks; the cues done so far are kept to resume from
+			report.Failed = len(report.FailedIndexes)
+			return translated, report, ctx.Err()
+		}
 		if err != nil {
-			log.Printf("Warning: Failed to translate entry %d ('%s'): %v. Using original text.", entry.Index, entry.Text, err)
-			translatedText = entry.Text // Fallback to original text
+			log.Printf("Warning: Failed to translate entry %d ('%s'): %v. Applying %s fallback.", entry.Index, entry.Text, err, policy.Mode)
+			translatedText = policy.Apply(entry.Text)
+			report.FailedIndexes = append(report.FailedIndexes, entry.Index)
 		}
 		
 		translatedEntry := SubtitleEntry{
-			Index:     entry.Index,
-			StartTime: entry.StartTime,
-			EndTime:   entry.EndTime,
-			Text:      translatedText,
+			Index: entry.Index,
+			Start: entry.Start,
+			End:   entry.End,
+			Text:  translatedText,
 		}
 		
 		translated = append(translated, translatedEntry)
+		progress.CueTranslated()
+	}
+
+	report.Failed = len(report.FailedIndexes)
+	report.Partial = policy.Partial(report)
+	if policy.Exceeded(report) {
+		return nil, report, fmt.Errorf("%d of %d cues (%.1f%%) failed to translate, above the %.1f%% limit", report.Failed, report.Total, report.FailureRate(), policy.MaxFailurePercent)
 	}
 	
-	return translated, nil
+	return translated, report, nil
+}
+
+// TranslateSelected translates only the cues at the given positions of
+// entries, with the cues around them as context, and returns the
+// translation of each position. Cues that fail to translate are left out
+// and their indexes returned instead; no fallback is applied. Each cue is
+// reported to progress, which may be nil.
+func (p *SRTParser) TranslateSelected(ctx context.Context, entries []SubtitleEntry, positions []int, translator Translator, progress Progress) (map[int]string, []int, error) {
+	translated := make(map[int]string, len(positions))
+	var failed []int
+	if progress == nil {
+		progress = noProgress{}
+	}
+	progress.Translating(len(positions))
+
+	for _, i := range positions {
+		text, err := p.translateEntry(ctx, entries, i, translator)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		progress.CueTranslated()
+		if err != nil {
+			log.Printf("Warning: Failed to translate entry %d ('%s'): %v", entries[i].Index, entries[i].Text, err)
+			failed = append(failed, entries[i].Index)
+			continue
+		}
+		translated[i] = text
+	}
+
+	return translated, failed, nil
+}
+
+// translateEntry translates entries[i], with its neighbours as context when
+// the translator supports it, retrying failures.
+func (p *SRTParser) translateEntry(ctx context.Context, entries []SubtitleEntry, i int, translator Translator) (string, error) {
+	entry := entries[i]
+	translate := func() (string, error) {
+		return translator.TranslateToChineseTraditional(ctx, entry.Text)
+	}
+	if contextTranslator, ok := translator.(ContextTranslator); ok && p.ContextWindow > 0 {
+		before, after := contextCues(entries, i, p.ContextWindow)
+		translate = func() (string, error) {
+			text, err := contextTranslator.TranslateWithContext(ctx, before, entry.Text, after)
+			if err != nil {
+				log.Printf("Context translation of entry %d failed, translating it on its own: %v", entry.Index, err)
+				return translator.TranslateToChineseTraditional(ctx, entry.Text)
+			}
+			return text, nil
+		}
+	}
+	return p.translateWithRetry(ctx, translate, 3)
 }
 
-func (p *SRTParser) translateWithRetry(translator Translator, text string, maxRetries int) (string, error) {
+// contextCues returns the text of up to n cues on either side of entries[i].
+func contextCues(entries []SubtitleEntry, i, n int) ([]string, []string) {
+	var before, after []string
+	for j := i - n; j < i; j++ {
+		if j >= 0 {
+			before = append(before, entries[j].Text)
+		}
+	}
+	for j := i + 1; j <= i+n && j < len(entries); j++ {
+		after = append(after, entries[j].Text)
+	}
+	return before, after
+}
+
+func (p *SRTParser) translateWithRetry(ctx context.Context, translate func() (string, error), maxRetries int) (string, error) {
 	var lastErr error
 	
 	for attempt := 1; attempt <= maxRetries; attempt++ {
@@ -118,13 +220,20 @@ func (p *SRTParser) translateWithRetry(translator Translator, text string, maxRe
 			// Wait between retries (exponential backoff)
 			waitTime := time.Duration(attempt-1) * time.Second
 			log.Printf("Retrying translation attempt %d/%d after %v...", attempt, maxRetries, waitTime)
-			time.Sleep(waitTime)
+			select {
+			case <-time.After(waitTime):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
 		}
 		
-		result, err := translator.TranslateToChineseTraditional(text)
+		result, err := translate()
 		if err == nil {
 			return result, nil
 		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		
 		lastErr = err
 		log.Printf("Translation attempt %d failed: %v", attempt, err)
@@ -134,5 +243,24 @@ func (p *SRTParser) translateWithRetry(translator Translator, text string, maxRe
 }
 
 type Translator interface {
-	TranslateToChineseTraditional(text string) (string, error)
+	TranslateToChineseTraditional(ctx context.Context, text string) (string, error)
+}
+
+// Progress follows a translation cue by cue, such as the job it runs in:
+// Translating is called once with the number of cues to translate and
+// CueTranslated after each of them.
+type Progress interface {
+	Translating(total int)
+	CueTranslated()
+}
+
+type noProgress struct{}
+
+func (noProgress) Translating(int) {}
+func (noProgress) CueTranslated()  {}
+
+// ContextTranslator is implemented by translators that can use neighbouring
+// cues to disambiguate the cue being translated.
+type ContextTranslator interface {
+	TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error)
 }
\ No newline at end of file
@@ -0,0 +1,55 @@
+package subtitle
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type upperTranslator struct{}
+
+func (upperTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	return strings.ToUpper(text), nil
+}
+
+type countingProgress struct {
+	total, cues int
+}
+
+func (p *countingProgress) Translating(total int) { p.total = total }
+func (p *countingProgress) CueTranslated()        { p.cues++ }
+
+func TestTranslateReportsProgress(t *testing.T) {
+	entries := []SubtitleEntry{
+		{Index: 1, Start: 0, End: time.Second, Text: "one"},
+		{Index: 2, Start: time.Second, End: 2 * time.Second, Text: "two"},
+		{Index: 3, Start: 2 * time.Second, End: 3 * time.Second, Text: "three"},
+	}
+	parser := NewSRTParser()
+
+	var progress countingProgress
+	if _, _, err := parser.TranslateEntries(context.Background(), entries, upperTranslator{}, FallbackPolicy{}, &progress); err != nil {
+		t.Fatal(err)
+	}
+	if progress != (countingProgress{total: 3, cues: 3}) {
+		t.Errorf("TranslateEntries() reported %+v, want 3 of 3 cues", progress)
+	}
+
+	progress = countingProgress{}
+	translated, _, err := parser.TranslateSelected(context.Background(), entries, []int{0, 2}, upperTranslator{}, &progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress != (countingProgress{total: 2, cues: 2}) {
+		t.Errorf("TranslateSelected() reported %+v, want 2 of 2 cues", progress)
+	}
+	if translated[0] != "ONE" || translated[2] != "THREE" {
+		t.Errorf("TranslateSelected() = %v", translated)
+	}
+
+	// Without progress nothing is reported
+	if _, _, err := parser.TranslateEntries(context.Background(), entries, upperTranslator{}, FallbackPolicy{}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
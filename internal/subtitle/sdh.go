@@ -0,0 +1,54 @@
+package subtitle
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Sound descriptions such as [door slams] or (laughing).
+	sdhAnnotationRegex = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+	// Speaker labels such as "JOHN:" or "MAN #2:" at the start of a line.
+	sdhSpeakerRegex = regexp.MustCompile(`^(\s*-?\s*)(<[^>]+>)?[A-Z][A-Z0-9 .'#&-]*:\s*`)
+	// Lines that contain nothing but music notes, dashes or tags.
+	sdhEmptyLineRegex = regexp.MustCompile(`^(\s|-|♪|♫|#|<[^>]*>)*$`)
+	multiSpaceRegex   = regexp.MustCompile(`\s{2,}`)
+)
+
+// StripSDH removes hearing-impaired annotations (sound descriptions and
+// speaker labels) from the cues, drops cues left without dialogue and
+// renumbers the rest. It returns the stripped entries and the number of
+// cues removed.
+func StripSDH(entries []SubtitleEntry) ([]SubtitleEntry, int) {
+	stripped := make([]SubtitleEntry, 0, len(entries))
+	removed := 0
+
+	for _, entry := range entries {
+		var lines []string
+		for _, line := range strings.Split(entry.Text, "\n") {
+			line = sdhAnnotationRegex.ReplaceAllString(line, "")
+			line = sdhSpeakerRegex.ReplaceAllString(line, "$1$2")
+			line = strings.TrimSpace(multiSpaceRegex.ReplaceAllString(line, " "))
+			if sdhEmptyLineRegex.MatchString(line) {
+				continue
+			}
+			lines = append(lines, line)
+		}
+
+		if len(lines) == 0 {
+			removed++
+			continue
+		}
+
+		// A lone dialogue dash is meaningless once the other speaker is gone.
+		if len(lines) == 1 {
+			lines[0] = strings.TrimSpace(strings.TrimPrefix(lines[0], "-"))
+		}
+
+		entry.Text = strings.Join(lines, "\n")
+		entry.Index = len(stripped) + 1
+		stripped = append(stripped, entry)
+	}
+
+	return stripped, removed
+}
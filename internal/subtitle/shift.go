@@ -0,0 +1,10 @@
+package subtitle
+
+import "time"
+
+// Shift moves every cue by offset, which may be negative, and renumbers the
+// entries sequentially. Cues pushed entirely before the start of the video
+// are dropped; cues pushed partly before it start at zero.
+func Shift(entries []SubtitleEntry, offset time.Duration) []SubtitleEntry {
+	return Retime(entries, 1, offset)
+}
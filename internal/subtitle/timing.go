@@ -0,0 +1,121 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timestampRegex = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})[,.](\d{3})$`)
+
+// ParseTimestamp parses an SRT timestamp such as 00:01:02,345. The WebVTT
+// forms 00:01:02.345 and 01:02.345 are accepted too.
+func ParseTimestamp(value string) (time.Duration, error) {
+	match := timestampRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+	var parts [4]int
+	for i, part := range match[1:] {
+		if part != "" {
+			parts[i], _ = strconv.Atoi(part)
+		}
+	}
+	hours, minutes, seconds, millis := parts[0], parts[1], parts[2], parts[3]
+	if minutes > 59 || seconds > 59 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+	return time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second +
+		time.Duration(millis)*time.Millisecond, nil
+}
+
+// FormatSRTTimestamp formats d as an SRT timestamp such as 00:01:02,345.
+// Negative durations are written as zero.
+func FormatSRTTimestamp(d time.Duration) string {
+	return formatTimestamp(d, ",")
+}
+
+// FormatVTTTimestamp formats d as a WebVTT timestamp such as 00:01:02.345.
+func FormatVTTTimestamp(d time.Duration) string {
+	return formatTimestamp(d, ".")
+}
+
+func formatTimestamp(d time.Duration, separator string) string {
+	if d < 0 {
+		d = 0
+	}
+	millis := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", millis/3600000, millis/60000%60, millis/1000%60, separator, millis%1000)
+}
+
+// FormatASSTimestamp formats d as an ASS timestamp such as 0:01:02.35.
+// ASS counts in centiseconds, so d is rounded to the nearest one.
+func FormatASSTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	centis := d.Round(10*time.Millisecond).Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", centis/360000, centis/6000%60, centis/100%60, centis%100)
+}
+
+// StartTime returns the start of the cue as an SRT timestamp.
+func (e SubtitleEntry) StartTime() string {
+	return FormatSRTTimestamp(e.Start)
+}
+
+// EndTime returns the end of the cue as an SRT timestamp.
+func (e SubtitleEntry) EndTime() string {
+	return FormatSRTTimestamp(e.End)
+}
+
+// Duration is how long the cue is on screen.
+func (e SubtitleEntry) Duration() time.Duration {
+	return e.End - e.Start
+}
+
+// Overlap returns how long e and other are on screen together, zero when
+// they don't overlap.
+func (e SubtitleEntry) Overlap(other SubtitleEntry) time.Duration {
+	return max(min(e.End, other.End)-max(e.Start, other.Start), 0)
+}
+
+// Overlaps reports whether e and other are on screen at the same time.
+func (e SubtitleEntry) Overlaps(other SubtitleEntry) bool {
+	return e.Overlap(other) > 0
+}
+
+// Retime maps every cue's times through t' = t*scale + offset, for example
+// to shift a subtitle (scale 1) or convert it between frame rates (scale
+// 25/23.976 for a 23.976 fps subtitle on a 25 fps video). The entries are
+// renumbered; cues that end up entirely before the start of the video are
+// dropped and cues that end up partly before it start at zero.
+func Retime(entries []SubtitleEntry, scale float64, offset time.Duration) []SubtitleEntry {
+	retime := func(t time.Duration) time.Duration {
+		return time.Duration(float64(t)*scale).Round(time.Millisecond) + offset
+	}
+
+	retimed := make([]SubtitleEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.Start, entry.End = max(retime(entry.Start), 0), retime(entry.End)
+		if entry.End <= 0 {
+			continue
+		}
+		entry.Index = len(retimed) + 1
+		retimed = append(retimed, entry)
+	}
+	return retimed
+}
+
+// FormatVTT renders entries as a WebVTT file.
+func FormatVTT(entries []SubtitleEntry) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "\n%d\n%s --> %s\n%s\n", entry.Index, FormatVTTTimestamp(entry.Start), FormatVTTTimestamp(entry.End), entry.Text)
+	}
+	return b.String()
+}
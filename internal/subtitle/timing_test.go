@@ -0,0 +1,153 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "srt", value: "01:02:03,456", want: time.Hour + 2*time.Minute + 3*time.Second + 456*time.Millisecond},
+		{name: "vtt", value: "00:01:02.345", want: time.Minute + 2*time.Second + 345*time.Millisecond},
+		{name: "vtt without hours", value: "01:02.345", want: time.Minute + 2*time.Second + 345*time.Millisecond},
+		{name: "surrounding space", value: " 00:00:01,000 ", want: time.Second},
+		{name: "hours above 99", value: "100:00:00,000", want: 100 * time.Hour},
+		{name: "largest minutes and seconds", value: "00:59:59,999", want: 59*time.Minute + 59*time.Second + 999*time.Millisecond},
+		{name: "minutes above 59", value: "00:60:00,000", wantErr: true},
+		{name: "seconds above 59", value: "00:00:60,000", wantErr: true},
+		{name: "seconds above 59 without hours", value: "01:75.000", wantErr: true},
+		{name: "two-digit milliseconds", value: "00:00:01,00", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimestamp(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTimestamp(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTimestamp(%q): %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTimestamp(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatASSTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want string
+	}{
+		{name: "zero", d: 0, want: "0:00:00.00"},
+		{name: "whole centiseconds", d: time.Minute + 2*time.Second + 350*time.Millisecond, want: "0:01:02.35"},
+		{name: "rounded down", d: 1234 * time.Millisecond, want: "0:00:01.23"},
+		{name: "rounded up", d: 1236 * time.Millisecond, want: "0:00:01.24"},
+		{name: "half rounded up", d: 1235 * time.Millisecond, want: "0:00:01.24"},
+		{name: "rounded up into the next second", d: 59995 * time.Millisecond, want: "0:01:00.00"},
+		{name: "hours are not padded", d: 10*time.Hour + 5*time.Millisecond, want: "10:00:00.01"},
+		{name: "negative", d: -time.Second, want: "0:00:00.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatASSTimestamp(tt.d); got != tt.want {
+				t.Errorf("FormatASSTimestamp(%v) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetime(t *testing.T) {
+	cue := func(index int, start, end time.Duration, text string) SubtitleEntry {
+		return SubtitleEntry{Index: index, Start: start, End: end, Text: text}
+	}
+	entries := []SubtitleEntry{
+		cue(1, 1*time.Second, 2*time.Second, "one"),
+		cue(2, 3*time.Second, 5*time.Second, "two"),
+		cue(3, 10*time.Second, 12*time.Second, "three"),
+	}
+
+	tests := []struct {
+		name   string
+		scale  float64
+		offset time.Duration
+		want   []SubtitleEntry
+	}{
+		{
+			name:  "unchanged",
+			scale: 1,
+			want:  entries,
+		},
+		{
+			name:   "later",
+			scale:  1,
+			offset: 1500 * time.Millisecond,
+			want: []SubtitleEntry{
+				cue(1, 2500*time.Millisecond, 3500*time.Millisecond, "one"),
+				cue(2, 4500*time.Millisecond, 6500*time.Millisecond, "two"),
+				cue(3, 11500*time.Millisecond, 13500*time.Millisecond, "three"),
+			},
+		},
+		{
+			name:   "cues before zero are dropped or start at zero",
+			scale:  1,
+			offset: -4 * time.Second,
+			want: []SubtitleEntry{
+				cue(1, 0, time.Second, "two"),
+				cue(2, 6*time.Second, 8*time.Second, "three"),
+			},
+		},
+		{
+			name:   "cue ending at zero is dropped",
+			scale:  1,
+			offset: -2 * time.Second,
+			want: []SubtitleEntry{
+				cue(1, time.Second, 3*time.Second, "two"),
+				cue(2, 8*time.Second, 10*time.Second, "three"),
+			},
+		},
+		{
+			name:   "every cue before zero",
+			scale:  1,
+			offset: -time.Minute,
+			want:   []SubtitleEntry{},
+		},
+		{
+			name:  "frame rate",
+			scale: 2,
+			want: []SubtitleEntry{
+				cue(1, 2*time.Second, 4*time.Second, "one"),
+				cue(2, 6*time.Second, 10*time.Second, "two"),
+				cue(3, 20*time.Second, 24*time.Second, "three"),
+			},
+		},
+		{
+			name:  "scaled to the millisecond",
+			scale: 25.0 / 23.976,
+			want: []SubtitleEntry{
+				cue(1, 1043*time.Millisecond, 2085*time.Millisecond, "one"),
+				cue(2, 3128*time.Millisecond, 5214*time.Millisecond, "two"),
+				cue(3, 10427*time.Millisecond, 12513*time.Millisecond, "three"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Retime(entries, tt.scale, tt.offset)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Retime(%v, %v) = %+v, want %+v", tt.scale, tt.offset, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package subtitle
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// WrapRules limit how much text a cue holds on screen.
+type WrapRules struct {
+	// MaxLineChars is the most characters on a line; 0 turns wrapping off.
+	MaxLineChars int
+	// MaxLines is the most lines in a cue. A cue needing more is split into
+	// consecutive cues; 0 never splits.
+	MaxLines int
+	// MinDuration is the least time each part of a split cue stays on
+	// screen. A cue too short to give every part that long gets more lines
+	// instead.
+	MinDuration time.Duration
+}
+
+// Wrap breaks lines longer than MaxLineChars into lines of similar length
+// and splits cues that would need more than MaxLines lines, dividing the
+// cue's time between the parts in proportion to their text, after giving
+// each part MinDuration. Cues that
+// already fit keep their line breaks. It returns the entries, renumbered
+// when a cue was split, and for each one the position of the entry it came
+// from.
+func Wrap(entries []SubtitleEntry, rules WrapRules) ([]SubtitleEntry, []int) {
+	wrapped := make([]SubtitleEntry, 0, len(entries))
+	sources := make([]int, 0, len(entries))
+	split := false
+
+	for i, entry := range entries {
+		parts := wrapCue(entry, rules)
+		split = split || len(parts) > 1
+		for _, part := range parts {
+			wrapped = append(wrapped, part)
+			sources = append(sources, i)
+		}
+	}
+
+	if split {
+		for i := range wrapped {
+			wrapped[i].Index = i + 1
+		}
+	}
+	return wrapped, sources
+}
+
+// wrapCue returns entry wrapped to rules, as one cue or several.
+func wrapCue(entry SubtitleEntry, rules WrapRules) []SubtitleEntry {
+	if rules.MaxLineChars <= 0 || fits(entry.Text, rules) {
+		return []SubtitleEntry{entry}
+	}
+
+	width := utf8.RuneCountInString(joinUnits(textUnits(entry.Text)))
+	lines := ceilDiv(width, rules.MaxLineChars)
+	cues := 1
+	if rules.MaxLines > 0 {
+		cues = ceilDiv(lines, rules.MaxLines)
+	}
+	if cues > 1 && rules.MinDuration > 0 && entry.Duration() < time.Duration(cues)*rules.MinDuration {
+		cues = 1
+	}
+	if cues == 1 {
+		entry.Text = balanceLines(entry.Text, lines)
+		return []SubtitleEntry{entry}
+	}
+
+	// Split the text into parts of similar length first, then wrap each
+	chunks := strings.Split(balanceLines(entry.Text, cues), "\n")
+	sizes := make([]int, len(chunks))
+	total := 0
+	for i, chunk := range chunks {
+		sizes[i] = utf8.RuneCountInString(chunk)
+		total += sizes[i]
+	}
+
+	parts := make([]SubtitleEntry, len(chunks))
+	shared := entry.Duration() - time.Duration(len(chunks))*rules.MinDuration
+	done := 0
+	for i, chunk := range chunks {
+		part := entry
+		part.Start = entry.Start + time.Duration(i)*rules.MinDuration + proportion(shared, done, total)
+		done += sizes[i]
+		part.End = entry.Start + time.Duration(i+1)*rules.MinDuration + proportion(shared, done, total)
+		part.Text = balanceLines(chunk, ceilDiv(sizes[i], rules.MaxLineChars))
+		parts[i] = part
+	}
+	return parts
+}
+
+// fits reports whether text is within the rules as it is.
+func fits(text string, rules WrapRules) bool {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if rules.MaxLines > 0 && len(lines) > rules.MaxLines {
+		return false
+	}
+	for _, line := range lines {
+		if utf8.RuneCountInString(line) > rules.MaxLineChars {
+			return false
+		}
+	}
+	return true
+}
+
+// proportion returns the share done/total of d, to the millisecond.
+func proportion(d time.Duration, done, total int) time.Duration {
+	if total == 0 {
+		return 0
+	}
+	return (d * time.Duration(done) / time.Duration(total)).Round(time.Millisecond)
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,188 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTextUnits(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []textUnit
+	}{
+		{
+			name: "Latin",
+			text: "Where are\nyou going?",
+			want: []textUnit{{"Where", false}, {"are", true}, {"you", true}, {"going?", true}},
+		},
+		{
+			name: "CJK",
+			text: "你好\n世界。",
+			want: []textUnit{{"你", false}, {"好", false}, {"世", false}, {"界", false}, {"。", false}},
+		},
+		{
+			name: "CJK ending in a word",
+			text: "沒問題 OK",
+			want: []textUnit{{"沒", false}, {"問", false}, {"題", false}, {"OK", true}},
+		},
+		{
+			name: "word inside CJK",
+			text: "我用iPhone拍的",
+			want: []textUnit{{"我", false}, {"用", false}, {"iPhone", false}, {"拍", false}, {"的", false}},
+		},
+		{
+			name: "line break between CJK and a word",
+			text: "好的\nOK",
+			want: []textUnit{{"好", false}, {"的", false}, {"OK", true}},
+		},
+		{
+			name: "empty",
+			text: " \n ",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := textUnits(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("textUnits(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBalanceLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		n    int
+		want string
+	}{
+		{
+			name: "Latin",
+			text: "I don't know what you are talking about",
+			n:    2,
+			want: "I don't know what\nyou are talking about",
+		},
+		{
+			name: "CJK",
+			text: "我們明天早上八點在車站見面",
+			n:    2,
+			want: "我們明天早上\n八點在車站見面",
+		},
+		{
+			name: "CJK ending in a word",
+			text: "我們明天早上八點在車站見面 OK",
+			n:    2,
+			want: "我們明天早上八點\n在車站見面 OK",
+		},
+		{
+			name: "joins lines",
+			text: "我們明天\n早上\n八點見",
+			n:    1,
+			want: "我們明天早上八點見",
+		},
+		{
+			name: "fewer words than lines",
+			text: "Hello",
+			n:    3,
+			want: "Hello",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := balanceLines(tt.text, tt.n); got != tt.want {
+				t.Errorf("balanceLines(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	cue := func(start, end time.Duration, text string) SubtitleEntry {
+		return SubtitleEntry{Index: 1, Start: start, End: end, Text: text}
+	}
+	tests := []struct {
+		name  string
+		entry SubtitleEntry
+		rules WrapRules
+		want  []SubtitleEntry
+	}{
+		{
+			name:  "fits",
+			entry: cue(0, time.Second, "Short line"),
+			rules: WrapRules{MaxLineChars: 20, MaxLines: 2},
+			want:  []SubtitleEntry{cue(0, time.Second, "Short line")},
+		},
+		{
+			name:  "wrapped Latin",
+			entry: cue(0, time.Second, "I don't know what you are talking about"),
+			rules: WrapRules{MaxLineChars: 25, MaxLines: 2},
+			want:  []SubtitleEntry{cue(0, time.Second, "I don't know what\nyou are talking about")},
+		},
+		{
+			name:  "wrapped mixed",
+			entry: cue(0, time.Second, "我們明天早上八點在車站見面 OK"),
+			rules: WrapRules{MaxLineChars: 10, MaxLines: 2},
+			want:  []SubtitleEntry{cue(0, time.Second, "我們明天早上八點\n在車站見面 OK")},
+		},
+		{
+			name:  "split CJK",
+			entry: cue(0, 4*time.Second, "我們明天早上八點在車站見面"),
+			rules: WrapRules{MaxLineChars: 7, MaxLines: 1, MinDuration: 700 * time.Millisecond},
+			want: []SubtitleEntry{
+				{Index: 1, Start: 0, End: 1900 * time.Millisecond, Text: "我們明天早上"},
+				{Index: 2, Start: 1900 * time.Millisecond, End: 4 * time.Second, Text: "八點在車站見面"},
+			},
+		},
+		{
+			name:  "split mixed",
+			entry: cue(0, 4*time.Second, "我們明天早上八點在車站見面 OK"),
+			rules: WrapRules{MaxLineChars: 9, MaxLines: 1, MinDuration: 700 * time.Millisecond},
+			want: []SubtitleEntry{
+				{Index: 1, Start: 0, End: 2 * time.Second, Text: "我們明天早上八點"},
+				{Index: 2, Start: 2 * time.Second, End: 4 * time.Second, Text: "在車站見面 OK"},
+			},
+		},
+		{
+			name:  "split gives every part the minimum",
+			entry: cue(0, 2*time.Second, "Supercalifragilistic OK"),
+			rules: WrapRules{MaxLineChars: 20, MaxLines: 1, MinDuration: 700 * time.Millisecond},
+			want: []SubtitleEntry{
+				{Index: 1, Start: 0, End: 1245 * time.Millisecond, Text: "Supercalifragilistic"},
+				{Index: 2, Start: 1245 * time.Millisecond, End: 2 * time.Second, Text: "OK"},
+			},
+		},
+		{
+			name:  "too short to split",
+			entry: cue(0, time.Second, "我們明天早上八點在車站見面"),
+			rules: WrapRules{MaxLineChars: 7, MaxLines: 1, MinDuration: 700 * time.Millisecond},
+			want:  []SubtitleEntry{cue(0, time.Second, "我們明天早上\n八點在車站見面")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := Wrap([]SubtitleEntry{tt.entry}, tt.rules)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Wrap() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapSources(t *testing.T) {
+	entries := []SubtitleEntry{
+		{Index: 1, Start: 0, End: time.Second, Text: "Short"},
+		{Index: 2, Start: time.Second, End: 5 * time.Second, Text: "我們明天早上八點在車站見面"},
+	}
+	got, sources := Wrap(entries, WrapRules{MaxLineChars: 7, MaxLines: 1, MinDuration: 700 * time.Millisecond})
+	if len(got) != 3 || !reflect.DeepEqual(sources, []int{0, 1, 1}) {
+		t.Fatalf("Wrap() = %+v, %v", got, sources)
+	}
+	for i, entry := range got {
+		if entry.Index != i+1 {
+			t.Errorf("entry %d has index %d", i, entry.Index)
+		}
+	}
+}
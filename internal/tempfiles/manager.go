@@ -0,0 +1,170 @@
+package tempfiles
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	probeFileName = ".subtitle-hunter-write-test"
+	// partialPrefix starts the names of files being written by
+	// WriteFileAtomic.
+	partialPrefix = ".subtitle-hunter-partial-"
+	journalName   = "probes.journal"
+)
+
+// Manager owns the application's temp directory. Every temporary artifact is
+// created inside it, and write probes and partial writes in media
+// directories are journaled there, so anything left behind by a crash can be
+// removed on startup.
+type Manager struct {
+	dir string
+
+	mu     sync.Mutex
+	probed map[string]bool
+}
+
+func NewManager(dir string) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	return &Manager{dir: dir, probed: make(map[string]bool)}, nil
+}
+
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// CleanupStale removes leftover write probes and partial writes in the
+// directories recorded in the journal and everything else in the temp
+// directory. Call it once at startup before any
+// work is scheduled.
+func (m *Manager) CleanupStale() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+
+	if journal, err := os.Open(filepath.Join(m.dir, journalName)); err == nil {
+		scanner := bufio.NewScanner(journal)
+		for scanner.Scan() {
+			dir := strings.TrimSpace(scanner.Text())
+			if dir == "" {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, probeFileName)); err == nil {
+				removed++
+			}
+			partials, _ := filepath.Glob(filepath.Join(dir, partialPrefix+"*"))
+			for _, partial := range partials {
+				if err := os.Remove(partial); err == nil {
+					removed++
+				}
+			}
+		}
+		journal.Close()
+	}
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read temp directory: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(m.dir, entry.Name())); err != nil {
+			log.Printf("Warning: could not remove stale temp file %s: %v", entry.Name(), err)
+			continue
+		}
+		if entry.Name() != journalName {
+			removed++
+		}
+	}
+
+	m.probed = make(map[string]bool)
+	if removed > 0 {
+		log.Printf("Removed %d stale temporary artifacts", removed)
+	}
+	return nil
+}
+
+// CreateTemp creates a new temporary file inside the managed directory.
+func (m *Manager) CreateTemp(pattern string) (*os.File, error) {
+	return os.CreateTemp(m.dir, pattern)
+}
+
+// ProbeWritable checks that files can be created in dir by writing and
+// removing a probe file. The directory is journaled first so a probe
+// orphaned by a crash is cleaned up on the next start.
+func (m *Manager) ProbeWritable(dir string) error {
+	if err := m.journal(dir); err != nil {
+		log.Printf("Warning: could not journal write probe: %v", err)
+	}
+
+	probePath := filepath.Join(dir, probeFileName)
+	file, err := os.Create(probePath)
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(probePath)
+}
+
+// WriteFileAtomic writes content to path through a temporary file in the
+// same directory, which is then renamed over path, so that readers see the
+// old file or the new one but never part of it. The directory is journaled
+// first so a partial file orphaned by a crash is cleaned up on the next
+// start.
+func (m *Manager) WriteFileAtomic(path string, content []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := m.journal(dir); err != nil {
+		log.Printf("Warning: could not journal partial write: %v", err)
+	}
+
+	file, err := os.CreateTemp(dir, partialPrefix+"*")
+	if err != nil {
+		return err
+	}
+	// Once renamed, there is nothing left to remove
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (m *Manager) journal(dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.probed[dir] {
+		return nil
+	}
+
+	journal, err := os.OpenFile(filepath.Join(m.dir, journalName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	if _, err := fmt.Fprintln(journal, dir); err != nil {
+		return err
+	}
+	m.probed[dir] = true
+	return nil
+}
@@ -0,0 +1,68 @@
+package translator
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// AlternativeTranslator is implemented by backends that can offer more than
+// one rendering of the same text.
+type AlternativeTranslator interface {
+	AlternativesToChineseTraditional(ctx context.Context, text string) ([]string, error)
+}
+
+type Alternative struct {
+	Backend string `json:"backend"`
+	Text    string `json:"text"`
+}
+
+// Alternatives collects up to max distinct renderings of text from the
+// configured backends.
+func Alternatives(ctx context.Context, backends []Backend, text string, max int) []Alternative {
+	var alternatives []Alternative
+	seen := make(map[string]bool)
+
+	add := func(backend, candidate string) {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" || seen[candidate] || len(alternatives) >= max {
+			return
+		}
+		seen[candidate] = true
+		alternatives = append(alternatives, Alternative{Backend: backend, Text: candidate})
+	}
+
+	// Take each backend's primary translation first so every backend is
+	// represented before extra alternatives fill the remaining slots
+	var extras []Alternative
+	for _, backend := range backends {
+		if alt, ok := backend.Translator.(AlternativeTranslator); ok {
+			candidates, err := alt.AlternativesToChineseTraditional(ctx, text)
+			if err != nil {
+				log.Printf("Backend %s failed to provide alternatives: %v", backend.Name, err)
+				continue
+			}
+			for i, candidate := range candidates {
+				if i == 0 {
+					add(backend.Name, candidate)
+				} else {
+					extras = append(extras, Alternative{Backend: backend.Name, Text: candidate})
+				}
+			}
+			continue
+		}
+
+		translation, err := backend.Translator.TranslateToChineseTraditional(ctx, text)
+		if err != nil {
+			log.Printf("Backend %s failed to translate: %v", backend.Name, err)
+			continue
+		}
+		add(backend.Name, translation)
+	}
+
+	for _, extra := range extras {
+		add(extra.Backend, extra.Text)
+	}
+
+	return alternatives
+}
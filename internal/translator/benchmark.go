@@ -0,0 +1,90 @@
+package translator
+
+import (
+	"context"
+	"time"
+)
+
+// TextTranslator is the minimal interface a backend must satisfy to take
+// part in a comparison run.
+type TextTranslator interface {
+	TranslateToChineseTraditional(ctx context.Context, text string) (string, error)
+}
+
+// ContextTranslator is implemented by translators that can use neighbouring
+// cues as context.
+type ContextTranslator interface {
+	TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error)
+}
+
+// Backend is a configured translation service together with the metadata
+// needed to compare it against other backends.
+type Backend struct {
+	Name string
+	// CostPerMillionChars is the billed price in USD for one million source
+	// characters. Free services use 0.
+	CostPerMillionChars float64
+	Translator          TextTranslator
+	// From returns the backend's translator for text in another language
+	// than English, a language tag or "auto" to detect it. It is nil for
+	// backends that only translate English.
+	From func(sourceLang string) TextTranslator
+}
+
+type CueResult struct {
+	Text    string
+	Error   string
+	Latency time.Duration
+}
+
+type BenchmarkResult struct {
+	Backend       string
+	Cues          []CueResult
+	TotalLatency  time.Duration
+	Characters    int
+	Failures      int
+	EstimatedCost float64
+}
+
+// AverageLatency returns the mean latency per cue.
+func (r BenchmarkResult) AverageLatency() time.Duration {
+	if len(r.Cues) == 0 {
+		return 0
+	}
+	return r.TotalLatency / time.Duration(len(r.Cues))
+}
+
+// Benchmark runs every sample through each backend in turn and records the
+// translation, latency and estimated cost so backends can be compared side
+// by side. Backends are run sequentially to keep latency numbers honest.
+func Benchmark(ctx context.Context, backends []Backend, samples []string) []BenchmarkResult {
+	results := make([]BenchmarkResult, 0, len(backends))
+
+	for _, backend := range backends {
+		result := BenchmarkResult{
+			Backend: backend.Name,
+			Cues:    make([]CueResult, 0, len(samples)),
+		}
+
+		for _, sample := range samples {
+			start := time.Now()
+			text, err := backend.Translator.TranslateToChineseTraditional(ctx, sample)
+			latency := time.Since(start)
+
+			cue := CueResult{Text: text, Latency: latency}
+			if err != nil {
+				cue.Error = err.Error()
+				result.Failures++
+			}
+
+			result.Cues = append(result.Cues, cue)
+			result.TotalLatency += latency
+			result.Characters += len([]rune(sample))
+		}
+
+		result.EstimatedCost = float64(result.Characters) / 1_000_000 * backend.CostPerMillionChars
+		results = append(results, result)
+	}
+
+	return results
+}
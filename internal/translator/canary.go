@@ -0,0 +1,80 @@
+package translator
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// CanaryTranslator tries out a new backend on real content: the canary
+// translates a small share of cues and the stable backend the rest. Cues are
+// picked by a hash of their text, so canary cues are spread through the
+// file, a repeated line always goes to the same backend and a retry never
+// switches backends.
+type CanaryTranslator struct {
+	Stable Backend
+	Canary Backend
+	// Percent is the share of cues sent to the canary, from 0 to 100.
+	Percent float64
+
+	mu     sync.Mutex
+	served map[string]string
+}
+
+func NewCanaryTranslator(stable, canary Backend, percent float64) *CanaryTranslator {
+	return &CanaryTranslator{
+		Stable:  stable,
+		Canary:  canary,
+		Percent: percent,
+		served:  make(map[string]string),
+	}
+}
+
+// pick returns the backend that translates text.
+func (ct *CanaryTranslator) pick(text string) Backend {
+	hash := fnv.New32a()
+	hash.Write([]byte(text))
+	if float64(hash.Sum32()%10000) < ct.Percent*100 {
+		return ct.Canary
+	}
+	return ct.Stable
+}
+
+func (ct *CanaryTranslator) record(text string, backend Backend) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.served[text] = backend.Name
+}
+
+func (ct *CanaryTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	backend := ct.pick(text)
+	translated, err := backend.Translator.TranslateToChineseTraditional(ctx, text)
+	if err == nil {
+		ct.record(text, backend)
+	}
+	return translated, err
+}
+
+func (ct *CanaryTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	backend := ct.pick(text)
+	next, ok := backend.Translator.(ContextTranslator)
+	if !ok {
+		return ct.TranslateToChineseTraditional(ctx, text)
+	}
+
+	translated, err := next.TranslateWithContext(ctx, before, text, after)
+	if err == nil {
+		ct.record(text, backend)
+	}
+	return translated, err
+}
+
+// ServedBy returns the name of the backend that translated text. Cues
+// answered from the translation memory or left untranslated were not served
+// by either backend.
+func (ct *CanaryTranslator) ServedBy(text string) (string, bool) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	name, ok := ct.served[text]
+	return name, ok
+}
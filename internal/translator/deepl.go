@@ -0,0 +1,138 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"subtitle-hunter/internal/httpclient"
+	"subtitle-hunter/internal/lang"
+)
+
+// ErrQuotaExceeded is returned by backends whose character quota is used
+// up. A failover chain skips such a backend straight away.
+var ErrQuotaExceeded = errors.New("translation quota exceeded")
+
+const (
+	deeplURL     = "https://api.deepl.com"
+	deeplFreeURL = "https://api-free.deepl.com"
+	// deeplTargetLang is DeepL's code for Traditional Chinese.
+	deeplTargetLang = "ZH-HANT"
+	// deeplStatusQuotaExceeded is the status DeepL answers with once the
+	// account's character limit is reached.
+	deeplStatusQuotaExceeded = 456
+)
+
+// DeepLTranslator translates with the DeepL API. Keys of free accounts end
+// in ":fx" and are sent to the free endpoint.
+type DeepLTranslator struct {
+	APIKey string
+	URL    string
+	// sourceLang is DeepL's code for the language translated from; empty
+	// lets DeepL detect it.
+	sourceLang string
+	client     *http.Client
+}
+
+func NewDeepLTranslator(apiKey string) *DeepLTranslator {
+	url := deeplURL
+	if strings.HasSuffix(apiKey, ":fx") {
+		url = deeplFreeURL
+	}
+	return &DeepLTranslator{
+		APIKey:     apiKey,
+		URL:        url,
+		sourceLang: deeplLanguageCode(lang.English),
+		client:     httpclient.Default.Client(),
+	}
+}
+
+// deeplLanguageCode maps a language tag to the source language code DeepL
+// understands: the bare language in upper case, "ZH" for either Chinese
+// script.
+func deeplLanguageCode(tag lang.Tag) string {
+	return strings.ToUpper(tag.Normalize().Language)
+}
+
+// From returns a translator for text in sourceLang, a language tag or
+// "auto" to let DeepL detect the language.
+func (dt *DeepLTranslator) From(sourceLang string) TextTranslator {
+	source := *dt
+	source.sourceLang = ""
+	if sourceLang != "auto" {
+		source.sourceLang = deeplLanguageCode(lang.Parse(sourceLang))
+	}
+	return &source
+}
+
+type deeplRequest struct {
+	Text       []string `json:"text"`
+	SourceLang string   `json:"source_lang,omitempty"`
+	TargetLang string   `json:"target_lang"`
+	// Context is text that helps the translation but isn't translated.
+	Context string `json:"context,omitempty"`
+}
+
+type deeplResponse struct {
+	Translations []struct {
+		Text string `json:"text"`
+	} `json:"translations"`
+}
+
+func (dt *DeepLTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	return dt.translate(ctx, text, "")
+}
+
+// TranslateWithContext sends the surrounding cues in DeepL's context field,
+// which shapes the translation without being translated itself.
+func (dt *DeepLTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	return dt.translate(ctx, text, strings.Join(append(append([]string{}, before...), after...), "\n"))
+}
+
+func (dt *DeepLTranslator) translate(ctx context.Context, text, surrounding string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", nil
+	}
+
+	body, err := json.Marshal(deeplRequest{Text: []string{text}, SourceLang: dt.sourceLang, TargetLang: deeplTargetLang, Context: surrounding})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(dt.URL, "/")+"/v2/translate", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+dt.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := dt.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call DeepL API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	answer, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == deeplStatusQuotaExceeded:
+		return "", fmt.Errorf("DeepL: %w", ErrQuotaExceeded)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("DeepL API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(answer)))
+	}
+
+	var result deeplResponse
+	if err := json.Unmarshal(answer, &result); err != nil {
+		return "", fmt.Errorf("failed to parse DeepL response: %w", err)
+	}
+	if len(result.Translations) == 0 {
+		return "", fmt.Errorf("empty DeepL response")
+	}
+	return result.Translations[0].Text, nil
+}
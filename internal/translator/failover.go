@@ -0,0 +1,173 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFailureThreshold is the number of failures in a row after which a
+// backend is taken out of failover chains for the cooldown.
+const DefaultFailureThreshold = 3
+
+// Health tracks how each backend has been doing, across jobs. A backend
+// that fails DefaultFailureThreshold times in a row, or runs out of quota, is
+// skipped by failover chains until its cooldown has passed.
+type Health struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	backends  map[string]*BackendHealth
+}
+
+// BackendHealth is the state of one backend.
+type BackendHealth struct {
+	Backend string `json:"backend"`
+	// Failures counts the failures since the last success.
+	Failures    int       `json:"failures"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	// DownUntil is set while the backend is skipped.
+	DownUntil time.Time `json:"down_until,omitempty"`
+}
+
+// Down reports whether the backend is skipped at now.
+func (b BackendHealth) Down(now time.Time) bool {
+	return now.Before(b.DownUntil)
+}
+
+func NewHealth(cooldown time.Duration) *Health {
+	return &Health{
+		threshold: DefaultFailureThreshold,
+		cooldown:  cooldown,
+		backends:  make(map[string]*BackendHealth),
+	}
+}
+
+// SetCooldown changes how long failing backends are skipped from now on.
+func (h *Health) SetCooldown(cooldown time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cooldown = cooldown
+}
+
+func (h *Health) stateLocked(name string) *BackendHealth {
+	state, ok := h.backends[name]
+	if !ok {
+		state = &BackendHealth{Backend: name}
+		h.backends[name] = state
+	}
+	return state
+}
+
+// Status returns the state of the backend called name.
+func (h *Health) Status(name string) BackendHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return *h.stateLocked(name)
+}
+
+// Available reports whether the backend called name may be tried.
+func (h *Health) Available(name string) bool {
+	return !h.Status(name).Down(time.Now())
+}
+
+// Succeeded clears the backend's failures.
+func (h *Health) Succeeded(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state := h.stateLocked(name)
+	state.Failures = 0
+	state.DownUntil = time.Time{}
+}
+
+// Failed records a failure of the backend and reports whether it took the
+// backend out of the chains.
+func (h *Health) Failed(name string, err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state := h.stateLocked(name)
+	now := time.Now()
+	state.Failures++
+	state.LastError = err.Error()
+	state.LastFailure = now
+	if state.Down(now) || (state.Failures < h.threshold && !errors.Is(err, ErrQuotaExceeded)) {
+		return false
+	}
+	state.DownUntil = now.Add(h.cooldown)
+	return true
+}
+
+// FailoverTranslator translates each cue with the first available backend
+// of an ordered chain, and with the next one when that fails. A backend
+// that keeps failing or runs out of quota mid-file is skipped for the
+// cues after it, so the cues it already translated are kept and the rest
+// are translated by the next backend. When every backend is skipped they
+// are all tried anyway, in order.
+type FailoverTranslator struct {
+	Backends []Backend
+	Health   *Health
+	// OnDown is called when a backend is taken out of the chain.
+	OnDown func(backend string, err error)
+}
+
+func (ft *FailoverTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	return ft.translate(ctx, func(backend Backend) (string, error) {
+		return backend.Translator.TranslateToChineseTraditional(ctx, text)
+	})
+}
+
+func (ft *FailoverTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	return ft.translate(ctx, func(backend Backend) (string, error) {
+		if next, ok := backend.Translator.(ContextTranslator); ok {
+			return next.TranslateWithContext(ctx, before, text, after)
+		}
+		return backend.Translator.TranslateToChineseTraditional(ctx, text)
+	})
+}
+
+func (ft *FailoverTranslator) translate(ctx context.Context, call func(Backend) (string, error)) (string, error) {
+	if len(ft.Backends) == 0 {
+		return "", fmt.Errorf("no translator backend configured")
+	}
+
+	backends := ft.available()
+	var failures []string
+	var lastErr error
+	for _, backend := range backends {
+		translated, err := call(backend)
+		if err == nil {
+			ft.Health.Succeeded(backend.Name)
+			return translated, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if ft.Health.Failed(backend.Name, err) && ft.OnDown != nil {
+			ft.OnDown(backend.Name, err)
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", backend.Name, err))
+		lastErr = err
+	}
+	if len(failures) == 1 {
+		return "", lastErr
+	}
+	return "", errors.New(strings.Join(failures, "; "))
+}
+
+// available returns the backends to try, in order.
+func (ft *FailoverTranslator) available() []Backend {
+	var backends []Backend
+	for _, backend := range ft.Backends {
+		if ft.Health.Available(backend.Name) {
+			backends = append(backends, backend)
+		}
+	}
+	if len(backends) == 0 {
+		return ft.Backends
+	}
+	return backends
+}
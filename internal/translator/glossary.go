@@ -0,0 +1,178 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Glossary maps source terms to their preferred translation. Series entries
+// override global ones for that series.
+//
+//	global:
+//	  Winterfell: 臨冬城
+//	series:
+//	  Game of Thrones:
+//	    Jon Snow: 瓊恩·雪諾
+type Glossary struct {
+	Global map[string]string            `yaml:"global"`
+	Series map[string]map[string]string `yaml:"series"`
+}
+
+// LoadGlossary reads a YAML glossary, returning an empty glossary if the
+// file does not exist.
+func LoadGlossary(path string) (*Glossary, error) {
+	glossary := &Glossary{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return glossary, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, glossary); err != nil {
+		return nil, fmt.Errorf("failed to parse glossary %s: %w", path, err)
+	}
+
+	return glossary, nil
+}
+
+// TermsFor returns the global terms merged with the overrides for a series.
+// Series names are matched case-insensitively.
+func (g *Glossary) TermsFor(series string) map[string]string {
+	terms := make(map[string]string, len(g.Global))
+	for term, translation := range g.Global {
+		terms[term] = translation
+	}
+
+	for name, seriesTerms := range g.Series {
+		if !strings.EqualFold(name, series) {
+			continue
+		}
+		for term, translation := range seriesTerms {
+			terms[term] = translation
+		}
+	}
+
+	return terms
+}
+
+type glossaryTerm struct {
+	pattern     *regexp.Regexp
+	translation string
+}
+
+// GlossaryTranslator swaps glossary terms for placeholders before the text
+// is sent to the wrapped translator and puts the preferred translations back
+// afterwards, so names survive machine translation intact.
+type GlossaryTranslator struct {
+	terms []glossaryTerm
+	Next  TextTranslator
+}
+
+func NewGlossaryTranslator(terms map[string]string, next TextTranslator) *GlossaryTranslator {
+	sources := make([]string, 0, len(terms))
+	for term := range terms {
+		if strings.TrimSpace(term) != "" {
+			sources = append(sources, term)
+		}
+	}
+
+	// Longest terms first so "Jon Snow" wins over "Jon"
+	sort.Slice(sources, func(i, j int) bool {
+		return len(sources[i]) > len(sources[j])
+	})
+
+	gt := &GlossaryTranslator{Next: next}
+	for _, term := range sources {
+		gt.terms = append(gt.terms, glossaryTerm{
+			pattern:     termPattern(term),
+			translation: terms[term],
+		})
+	}
+
+	return gt
+}
+
+// termPattern matches a term case-insensitively, on word boundaries where
+// the term starts or ends with a word character.
+func termPattern(term string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(term)
+	if wordChar.MatchString(term[:1]) {
+		pattern = `\b` + pattern
+	}
+	if wordChar.MatchString(term[len(term)-1:]) {
+		pattern += `\b`
+	}
+	return regexp.MustCompile(`(?i)` + pattern)
+}
+
+var wordChar = regexp.MustCompile(`\w`)
+
+func placeholder(i int) string {
+	return fmt.Sprintf("{{%d}}", i)
+}
+
+func (gt *GlossaryTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	text, used := gt.protect(text)
+
+	translated, err := gt.Next.TranslateToChineseTraditional(ctx, text)
+	if err != nil {
+		return "", err
+	}
+
+	return gt.restore(translated, used), nil
+}
+
+func (gt *GlossaryTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	next, ok := gt.Next.(ContextTranslator)
+	if !ok {
+		return gt.TranslateToChineseTraditional(ctx, text)
+	}
+
+	text, used := gt.protect(text)
+	protectedBefore := make([]string, len(before))
+	for i, cue := range before {
+		protectedBefore[i], _ = gt.protect(cue)
+	}
+	protectedAfter := make([]string, len(after))
+	for i, cue := range after {
+		protectedAfter[i], _ = gt.protect(cue)
+	}
+
+	translated, err := next.TranslateWithContext(ctx, protectedBefore, text, protectedAfter)
+	if err != nil {
+		return "", err
+	}
+
+	return gt.restore(translated, used), nil
+}
+
+// protect replaces glossary terms with placeholders and returns the indexes
+// of the terms that were found.
+func (gt *GlossaryTranslator) protect(text string) (string, []int) {
+	var used []int
+	for i, term := range gt.terms {
+		if term.pattern.MatchString(text) {
+			text = term.pattern.ReplaceAllLiteralString(text, placeholder(i))
+			used = append(used, i)
+		}
+	}
+	return text, used
+}
+
+func (gt *GlossaryTranslator) restore(translated string, used []int) string {
+	for _, i := range used {
+		// Translators sometimes pad placeholders with spaces
+		spaced := regexp.MustCompile(`\s*\{\s*\{\s*` + fmt.Sprint(i) + `\s*\}\s*\}\s*`)
+		translated = spaced.ReplaceAllLiteralString(translated, gt.terms[i].translation)
+	}
+	return translated
+}
@@ -1,15 +1,58 @@
 package translator
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"unicode/utf8"
+
+	"subtitle-hunter/internal/httpclient"
+	"subtitle-hunter/internal/lang"
+)
+
+// Google expects region-style codes for the Chinese scripts.
+var (
+	googleSourceLang = googleLanguageCode(lang.English)
+	googleTargetLang = googleLanguageCode(lang.TraditionalChinese)
+)
+
+// googleLanguageCode maps a language tag to the code Google Translate
+// understands: "zh-TW"/"zh-CN" for Chinese scripts, the bare language
+// otherwise.
+func googleLanguageCode(tag lang.Tag) string {
+	tag = tag.Normalize()
+	if tag.Language == "zh" {
+		switch tag.InferredScript() {
+		case "Hant":
+			return "zh-TW"
+		case "Hans":
+			return "zh-CN"
+		}
+	}
+	return tag.Language
+}
+
+const (
+	// googleMaxTextLength is the most characters sent in one request. Longer
+	// text is split into sentences translated separately and joined again.
+	googleMaxTextLength = 1000
+	// googleMaxInputLength is the most characters translated at all; longer
+	// text isn't subtitle text.
+	googleMaxInputLength = 20000
+	// googleMaxURLLength is the longest request URL sent. The endpoint
+	// rejects long URLs, so longer queries go in a POST form body instead.
+	googleMaxURLLength = 2000
 )
 
+// ErrInputTooLong is returned for text too long to translate.
+var ErrInputTooLong = errors.New("text too long to translate")
+
 type GoogleTranslator struct {
 	client *http.Client
 }
@@ -18,52 +61,155 @@ type TranslateResponse []interface{}
 
 func NewGoogleTranslator() *GoogleTranslator {
 	return &GoogleTranslator{
-		client: &http.Client{},
+		client: httpclient.Default.Client(),
 	}
 }
 
-func (gt *GoogleTranslator) Translate(text, sourceLang, targetLang string) (string, error) {
+func (gt *GoogleTranslator) Translate(ctx context.Context, text, sourceLang, targetLang string) (string, error) {
 	if text == "" {
 		return "", nil
 	}
 
-	// Clean the text before translation
 	cleanText := gt.cleanTextForTranslation(text)
-	
+	if length := utf8.RuneCountInString(cleanText); length > googleMaxInputLength {
+		return "", fmt.Errorf("%w: %d characters, at most %d", ErrInputTooLong, length, googleMaxInputLength)
+	}
+
+	pieces := splitSentences(cleanText, googleMaxTextLength)
+	if len(pieces) == 1 {
+		result, err := gt.query(ctx, cleanText, sourceLang, targetLang, "t")
+		if err != nil {
+			return "", err
+		}
+		return joinTranslation(result)
+	}
+
+	translated := make([]string, 0, len(pieces))
+	for i, piece := range pieces {
+		result, err := gt.query(ctx, piece, sourceLang, targetLang, "t")
+		if err != nil {
+			return "", fmt.Errorf("piece %d of %d: %w", i+1, len(pieces), err)
+		}
+		part, err := joinTranslation(result)
+		if err != nil {
+			return "", err
+		}
+		translated = append(translated, strings.TrimSpace(part))
+	}
+	return joinPieces(translated, targetLang), nil
+}
+
+// TranslateWithContext translates text with the surrounding cues sent along
+// as context. Each cue goes on its own line, which Google preserves, and only
+// the target line of the result is returned.
+func (gt *GoogleTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	return gt.translateInContext(ctx, before, text, after, googleSourceLang, googleTargetLang)
+}
+
+func (gt *GoogleTranslator) translateInContext(ctx context.Context, before []string, text string, after []string, sourceLang, targetLang string) (string, error) {
+	target := gt.cleanTextForTranslation(text)
+	if target == "" {
+		return "", nil
+	}
+
+	var lines []string
+	for _, cue := range before {
+		if cleaned := gt.cleanTextForTranslation(cue); cleaned != "" {
+			lines = append(lines, cleaned)
+		}
+	}
+	targetLine := len(lines)
+	lines = append(lines, target)
+	for _, cue := range after {
+		if cleaned := gt.cleanTextForTranslation(cue); cleaned != "" {
+			lines = append(lines, cleaned)
+		}
+	}
+
+	query := strings.Join(lines, "\n")
+	if utf8.RuneCountInString(query) > googleMaxTextLength {
+		// Too long to send along with its context in one piece
+		return gt.Translate(ctx, text, sourceLang, targetLang)
+	}
+
+	result, err := gt.query(ctx, query, sourceLang, targetLang, "t")
+	if err != nil {
+		return "", err
+	}
+
+	translated, err := joinTranslation(result)
+	if err != nil {
+		return "", err
+	}
+
+	translatedLines := strings.Split(strings.TrimSpace(translated), "\n")
+	if len(translatedLines) != len(lines) {
+		return "", fmt.Errorf("context translation returned %d lines for %d cues", len(translatedLines), len(lines))
+	}
+
+	return strings.TrimSpace(translatedLines[targetLine]), nil
+}
+
+// query calls the translate endpoint with already-cleaned text and returns
+// the decoded response. The dt values select which parts of the response
+// Google fills in.
+func (gt *GoogleTranslator) query(ctx context.Context, cleanText, sourceLang, targetLang string, dt ...string) (TranslateResponse, error) {
 	baseURL := "https://translate.googleapis.com/translate_a/single"
 	params := url.Values{
 		"client": {"gtx"},
 		"sl":     {sourceLang},
 		"tl":     {targetLang},
-		"dt":     {"t"},
+		"dt":     dt,
 		"q":      {cleanText},
 	}
 
-	resp, err := gt.client.Get(baseURL + "?" + params.Encode())
+	encoded := params.Encode()
+	method, requestURL, form := "GET", baseURL+"?"+encoded, io.Reader(nil)
+	if len(requestURL) > googleMaxURLLength {
+		method, requestURL, form = "POST", baseURL, strings.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := gt.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to call Google Translate API: %w", err)
+		return nil, fmt.Errorf("failed to call Google Translate API: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusRequestURITooLong || resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return nil, fmt.Errorf("%w: Google Translate refused %d characters (status %d)", ErrInputTooLong, utf8.RuneCountInString(cleanText), resp.StatusCode)
 	}
 
 	// Check if response is HTML (error page)
 	if strings.HasPrefix(strings.TrimSpace(string(body)), "<") {
-		return "", fmt.Errorf("Google Translate returned HTML error page, possibly rate limited or blocked")
+		return nil, fmt.Errorf("Google Translate returned HTML error page, possibly rate limited or blocked")
 	}
 
 	var result TranslateResponse
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("failed to parse translation response (body: %s): %w", string(body), err)
+		return nil, fmt.Errorf("failed to parse translation response (body: %s): %w", string(body), err)
 	}
 
 	if len(result) == 0 {
-		return "", fmt.Errorf("empty translation response")
+		return nil, fmt.Errorf("empty translation response")
 	}
 
+	return result, nil
+}
+
+func joinTranslation(result TranslateResponse) (string, error) {
 	translations, ok := result[0].([]interface{})
 	if !ok {
 		return "", fmt.Errorf("unexpected response format")
@@ -81,6 +227,46 @@ func (gt *GoogleTranslator) Translate(text, sourceLang, targetLang string) (stri
 	return translatedText.String(), nil
 }
 
+// AlternativesToChineseTraditional returns the primary translation followed
+// by the alternative renderings Google offers. Alternatives are only
+// available when the text is translated as a single segment.
+func (gt *GoogleTranslator) AlternativesToChineseTraditional(ctx context.Context, text string) ([]string, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	result, err := gt.query(ctx, gt.cleanTextForTranslation(text), googleSourceLang, googleTargetLang, "t", "at")
+	if err != nil {
+		return nil, err
+	}
+
+	primary, err := joinTranslation(result)
+	if err != nil {
+		return nil, err
+	}
+	alternatives := []string{primary}
+
+	// result[5] holds one entry per source segment:
+	// [source, null, [[alternative, score, ...], ...], ...]
+	if len(result) > 5 {
+		if segments, ok := result[5].([]interface{}); ok && len(segments) == 1 {
+			if segment, ok := segments[0].([]interface{}); ok && len(segment) > 2 {
+				if candidates, ok := segment[2].([]interface{}); ok {
+					for _, candidate := range candidates {
+						if fields, ok := candidate.([]interface{}); ok && len(fields) > 0 {
+							if text, ok := fields[0].(string); ok && text != primary {
+								alternatives = append(alternatives, text)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return alternatives, nil
+}
+
 func (gt *GoogleTranslator) cleanTextForTranslation(text string) string {
 	// Remove HTML tags but preserve the content
 	htmlTagRegex := regexp.MustCompile(`<[^>]*>`)
@@ -96,6 +282,31 @@ func (gt *GoogleTranslator) cleanTextForTranslation(text string) string {
 	return cleanText
 }
 
-func (gt *GoogleTranslator) TranslateToChineseTraditional(text string) (string, error) {
-	return gt.Translate(text, "en", "zh-TW")
-}
\ No newline at end of file
+func (gt *GoogleTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	return gt.Translate(ctx, text, googleSourceLang, googleTargetLang)
+}
+// SourceTranslator translates from a fixed source language into Traditional
+// Chinese, e.g. to convert Simplified Chinese subtitles.
+type SourceTranslator struct {
+	gt         *GoogleTranslator
+	sourceLang string
+}
+
+func (gt *GoogleTranslator) From(sourceLang string) *SourceTranslator {
+	if sourceLang != "auto" {
+		sourceLang = googleLanguageCode(lang.Parse(sourceLang))
+	}
+	// "und" and the like name no language
+	if sourceLang == "" {
+		sourceLang = "auto"
+	}
+	return &SourceTranslator{gt: gt, sourceLang: sourceLang}
+}
+
+func (st *SourceTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	return st.gt.Translate(ctx, text, st.sourceLang, googleTargetLang)
+}
+
+func (st *SourceTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	return st.gt.translateInContext(ctx, before, text, after, st.sourceLang, googleTargetLang)
+}
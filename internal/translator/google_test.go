@@ -0,0 +1,134 @@
+package translator
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// googleRequest is what a request to the translate endpoint carried.
+type googleRequest struct {
+	method   string
+	urlLen   int
+	query    string
+	textSent string
+}
+
+// recordingGoogle returns a translator whose requests are recorded instead
+// of sent, each answered with a translation of "譯文".
+func recordingGoogle(t *testing.T) (*GoogleTranslator, *[]googleRequest) {
+	var requests []googleRequest
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent := googleRequest{method: req.Method, urlLen: len(req.URL.String()), query: req.URL.RawQuery}
+		values := req.URL.Query()
+		if req.Body != nil {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if values, err = url.ParseQuery(string(body)); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("Content-Type"); got != "application/x-www-form-urlencoded" {
+				t.Errorf("POST Content-Type = %q", got)
+			}
+		}
+		sent.textSent = values.Get("q")
+		requests = append(requests, sent)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`[[["譯文","text",null,null]],null,"en"]`)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+	return &GoogleTranslator{client: client}, &requests
+}
+
+func TestGoogleQueryMethod(t *testing.T) {
+	gt, requests := recordingGoogle(t)
+	if _, err := gt.Translate(context.Background(), "a", "en", "zh-TW"); err != nil {
+		t.Fatal(err)
+	}
+	// Every character past the first adds its URL-encoded length
+	base := (*requests)[0].urlLen - 1
+
+	// Nine URL characters per CJK character, made up with ASCII to reach
+	// exactly the given URL length
+	textFor := func(urlLen int) string {
+		n := urlLen - base
+		return strings.Repeat("你", n/9) + strings.Repeat("a", n%9)
+	}
+
+	tests := []struct {
+		name       string
+		text       string
+		wantMethod string
+	}{
+		{name: "short", text: "Hello there.", wantMethod: http.MethodGet},
+		{name: "at the limit", text: textFor(googleMaxURLLength), wantMethod: http.MethodGet},
+		{name: "past the limit", text: textFor(googleMaxURLLength + 1), wantMethod: http.MethodPost},
+		{name: "long CJK piece", text: strings.Repeat("你", 500), wantMethod: http.MethodPost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*requests = nil
+			if _, err := gt.Translate(context.Background(), tt.text, "zh-TW", "en"); err != nil {
+				t.Fatal(err)
+			}
+			if len(*requests) != 1 {
+				t.Fatalf("Translate() sent %d requests, want 1", len(*requests))
+			}
+			got := (*requests)[0]
+			if got.method != tt.wantMethod {
+				t.Errorf("Translate() of %d characters sent %s, want %s", len(tt.text), got.method, tt.wantMethod)
+			}
+			if got.textSent != tt.text {
+				t.Errorf("Translate() sent q=%q, want %q", got.textSent, tt.text)
+			}
+			if got.method == http.MethodPost && got.query != "" {
+				t.Errorf("POST request still has the query %q in its URL", got.query)
+			}
+		})
+	}
+}
+
+func TestGoogleSplitsLongText(t *testing.T) {
+	gt, requests := recordingGoogle(t)
+	sentence := strings.Repeat("word ", 99) + "end. "
+	text := strings.Repeat(sentence, 3)
+
+	got, err := gt.Translate(context.Background(), text, "en", "zh-TW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("Translate() of %d characters sent %d requests, want 2", len(text), len(*requests))
+	}
+	for _, request := range *requests {
+		if request.method != http.MethodGet {
+			t.Errorf("piece of %d characters sent with %s, want GET", len(request.textSent), request.method)
+		}
+	}
+	if got != "譯文譯文" {
+		t.Errorf("Translate() = %q, want the pieces joined without spaces", got)
+	}
+}
+
+func TestGoogleRejectsTooLongInput(t *testing.T) {
+	gt, requests := recordingGoogle(t)
+	if _, err := gt.Translate(context.Background(), strings.Repeat("a ", googleMaxInputLength), "en", "zh-TW"); err == nil {
+		t.Fatal("Translate() of too long text succeeded")
+	}
+	if len(*requests) != 0 {
+		t.Errorf("Translate() of too long text sent %d requests", len(*requests))
+	}
+}
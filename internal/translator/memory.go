@@ -0,0 +1,188 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Memory stores translations the user has picked by hand so they are reused
+// verbatim whenever the same source text comes up again. Like the glossary,
+// translations picked for a series are only reused for that series; those
+// picked for anything else, such as a movie, are global and reused
+// everywhere the series has none of its own.
+//
+//	{
+//	  "global": {"Winter is coming.": "凜冬將至。"},
+//	  "series": {"game of thrones": {"Hold the door!": "阻門！"}}
+//	}
+type Memory struct {
+	path    string
+	mu      sync.RWMutex
+	entries memoryEntries
+}
+
+type memoryEntries struct {
+	Global map[string]string `json:"global"`
+	// Series is keyed by the series name in lower case.
+	Series map[string]map[string]string `json:"series"`
+}
+
+// LoadMemory reads the translation memory at path, starting empty if the
+// file does not exist yet. A memory written before series were told apart,
+// a flat object of translations, is read as global.
+func LoadMemory(path string) (*Memory, error) {
+	m := &Memory{
+		path:    path,
+		entries: memoryEntries{Global: make(map[string]string), Series: make(map[string]map[string]string)},
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read translation memory: %w", err)
+	}
+
+	var flat map[string]string
+	if err := json.Unmarshal(data, &flat); err == nil {
+		for source, translation := range flat {
+			m.entries.Global[source] = translation
+		}
+		return m, nil
+	}
+	if err := json.Unmarshal(data, &m.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse translation memory %s: %w", path, err)
+	}
+	if m.entries.Global == nil {
+		m.entries.Global = make(map[string]string)
+	}
+	if m.entries.Series == nil {
+		m.entries.Series = make(map[string]map[string]string)
+	}
+
+	return m, nil
+}
+
+func memoryKey(source string) string {
+	return strings.TrimSpace(source)
+}
+
+func seriesKey(series string) string {
+	return strings.ToLower(strings.TrimSpace(series))
+}
+
+// Lookup returns the translation picked for source in series, or the global
+// one. Give no series for anything else.
+func (m *Memory) Lookup(series, source string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if series := seriesKey(series); series != "" {
+		if translation, ok := m.entries.Series[series][memoryKey(source)]; ok {
+			return translation, true
+		}
+	}
+	translation, ok := m.entries.Global[memoryKey(source)]
+	return translation, ok
+}
+
+// Remember stores the preferred translation for series, or globally without
+// one, and persists the memory.
+func (m *Memory) Remember(series, source, translation string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if series := seriesKey(series); series != "" {
+		if m.entries.Series[series] == nil {
+			m.entries.Series[series] = make(map[string]string)
+		}
+		m.entries.Series[series][memoryKey(source)] = translation
+	} else {
+		m.entries.Global[memoryKey(source)] = translation
+	}
+
+	data, err := json.MarshalIndent(m.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode translation memory: %w", err)
+	}
+	return m.writeLocked(data)
+}
+
+// writeLocked replaces the memory file with data through a temporary file,
+// so a crash while writing leaves the previous memory intact.
+func (m *Memory) writeLocked(data []byte) error {
+	dir := filepath.Dir(m.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create translation memory directory: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(dir, filepath.Base(m.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write translation memory: %w", err)
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write translation memory: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write translation memory: %w", err)
+	}
+	if err := os.Chmod(tempFile.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write translation memory: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to replace translation memory: %w", err)
+	}
+	return nil
+}
+
+// Len returns how many translations are remembered, for every series.
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	total := len(m.entries.Global)
+	for _, entries := range m.entries.Series {
+		total += len(entries)
+	}
+	return total
+}
+
+// MemoryTranslator answers from the translation memory for Series (none
+// for anything but an episode) when possible and defers to the wrapped
+// translator otherwise.
+type MemoryTranslator struct {
+	Memory *Memory
+	Series string
+	Next   TextTranslator
+}
+
+func (mt *MemoryTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	if translation, ok := mt.Memory.Lookup(mt.Series, text); ok {
+		return translation, nil
+	}
+	return mt.Next.TranslateToChineseTraditional(ctx, text)
+}
+
+func (mt *MemoryTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	if translation, ok := mt.Memory.Lookup(mt.Series, text); ok {
+		return translation, nil
+	}
+	if next, ok := mt.Next.(ContextTranslator); ok {
+		return next.TranslateWithContext(ctx, before, text, after)
+	}
+	return mt.Next.TranslateToChineseTraditional(ctx, text)
+}
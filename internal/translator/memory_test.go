@@ -0,0 +1,98 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryKeepsSeriesApart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	memory, err := LoadMemory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range [][3]string{
+		{"", "Hold the door!", "擋住門！"},
+		{"Game of Thrones", "Hold the door!", "阻門！"},
+		{"Lost", " We have to go back. ", "我們得回去。"},
+	} {
+		if err := memory.Remember(entry[0], entry[1], entry[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reloaded, err := LoadMemory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", reloaded.Len())
+	}
+
+	tests := []struct {
+		series string
+		source string
+		want   string
+		ok     bool
+	}{
+		{series: "Game of Thrones", source: "Hold the door!", want: "阻門！", ok: true},
+		{series: "game of thrones ", source: "Hold the door!", want: "阻門！", ok: true},
+		{series: "Lost", source: "Hold the door!", want: "擋住門！", ok: true},
+		{series: "", source: "Hold the door!", want: "擋住門！", ok: true},
+		{series: "Lost", source: "We have to go back.", want: "我們得回去。", ok: true},
+		{series: "Game of Thrones", source: "We have to go back.", ok: false},
+		{series: "", source: "We have to go back.", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := reloaded.Lookup(tt.series, tt.source)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Lookup(%q, %q) = %q, %v, want %q, %v", tt.series, tt.source, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLoadMemoryReadsFlatFileAsGlobal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	if err := os.WriteFile(path, []byte(`{"Winter is coming.": "凜冬將至。"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	memory, err := LoadMemory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := memory.Lookup("Game of Thrones", "Winter is coming."); !ok || got != "凜冬將至。" {
+		t.Errorf("Lookup() = %q, %v, want the global translation", got, ok)
+	}
+
+	if err := memory.Remember("Game of Thrones", "Winter is coming.", "寒冬將至。"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("data directory has %d files after saving, want only the memory", len(entries))
+	}
+	reloaded, err := LoadMemory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reloaded.Lookup("", "Winter is coming."); got != "凜冬將至。" {
+		t.Errorf("global translation = %q after saving, want it kept", got)
+	}
+	if got, _ := reloaded.Lookup("Game of Thrones", "Winter is coming."); got != "寒冬將至。" {
+		t.Errorf("series translation = %q after saving, want %q", got, "寒冬將至。")
+	}
+}
+
+func TestLoadMemoryRejectsBrokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	if err := os.WriteFile(path, []byte(`{"global": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMemory(path); err == nil {
+		t.Error("LoadMemory() of a truncated file succeeded, want an error")
+	}
+}
@@ -0,0 +1,271 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NormalizationRules say how machine translations are brought in line with
+// Taiwanese subtitle conventions. Punctuation and spacing are fixed unless
+// turned off; terms and units are replaced only when listed.
+//
+//	punctuation: true
+//	spacing: true
+//	terms:
+//	  視頻: 影片
+//	units:
+//	  千米: 公里
+//	  米: 公尺
+type NormalizationRules struct {
+	// Punctuation turns half-width punctuation next to Chinese text into
+	// full-width punctuation and quotes into 「」.
+	Punctuation bool `yaml:"punctuation"`
+	// Spacing puts a space between Chinese text and Latin letters or digits.
+	Spacing bool `yaml:"spacing"`
+	// Terms are words and idioms replaced wherever they appear.
+	Terms map[string]string `yaml:"terms"`
+	// Units are replaced only right after a number, so "米" in "5米" becomes
+	// "公尺" but the rice in "米飯" stays.
+	Units map[string]string `yaml:"units"`
+}
+
+// DefaultNormalizationRules fix punctuation and spacing and replace nothing.
+func DefaultNormalizationRules() *NormalizationRules {
+	return &NormalizationRules{Punctuation: true, Spacing: true}
+}
+
+// LoadNormalizationRules reads YAML normalization rules, returning the
+// defaults if the file does not exist. Settings left out of the file keep
+// their defaults.
+func LoadNormalizationRules(path string) (*NormalizationRules, error) {
+	rules := DefaultNormalizationRules()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read normalization rules: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("failed to parse normalization rules %s: %w", path, err)
+	}
+
+	return rules, nil
+}
+
+// Empty reports whether the rules change nothing.
+func (r *NormalizationRules) Empty() bool {
+	return !r.Punctuation && !r.Spacing && len(r.Terms) == 0 && len(r.Units) == 0
+}
+
+// Normalizer applies normalization rules to translated text.
+type Normalizer struct {
+	rules *NormalizationRules
+	terms *strings.Replacer
+	units *regexp.Regexp
+}
+
+func NewNormalizer(rules *NormalizationRules) *Normalizer {
+	n := &Normalizer{rules: rules}
+
+	if terms := longestFirst(rules.Terms); len(terms) > 0 {
+		pairs := make([]string, 0, 2*len(terms))
+		for _, term := range terms {
+			pairs = append(pairs, term, rules.Terms[term])
+		}
+		n.terms = strings.NewReplacer(pairs...)
+	}
+
+	if units := longestFirst(rules.Units); len(units) > 0 {
+		quoted := make([]string, len(units))
+		for i, unit := range units {
+			quoted[i] = regexp.QuoteMeta(unit)
+		}
+		n.units = regexp.MustCompile(`(\d)[ \t]*(` + strings.Join(quoted, "|") + `)`)
+	}
+
+	return n
+}
+
+// longestFirst returns the non-blank keys of replacements, longest first so
+// "千米" wins over "米".
+func longestFirst(replacements map[string]string) []string {
+	keys := make([]string, 0, len(replacements))
+	for key := range replacements {
+		if strings.TrimSpace(key) != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// Normalize applies the rules to a translated cue. Line breaks are kept.
+func (n *Normalizer) Normalize(text string) string {
+	if n.terms != nil {
+		text = n.terms.Replace(text)
+	}
+	if n.units != nil {
+		text = n.units.ReplaceAllStringFunc(text, func(match string) string {
+			groups := n.units.FindStringSubmatch(match)
+			return groups[1] + n.rules.Units[groups[2]]
+		})
+	}
+	if n.rules.Punctuation {
+		text = fullWidthPunctuation(text)
+	}
+	if n.rules.Spacing {
+		text = cjkLatinSpacing(text)
+	}
+	return text
+}
+
+var (
+	// fullWidth are the half-width marks turned full-width next to Chinese
+	// text.
+	fullWidth = map[rune]string{',': "，", '!': "！", '?': "？", ':': "：", ';': "；"}
+
+	ellipsis       = regexp.MustCompile(`\.{2,}|…+`)
+	doubleQuoted   = regexp.MustCompile(`"([^"\n]*)"|“([^”\n]*)”`)
+	singleQuoted   = regexp.MustCompile(`‘([^’\n]*)’`)
+	parenthesized  = regexp.MustCompile(`\(([^()\n]*)\)`)
+	punctuationGap = regexp.MustCompile(`[ \t\x{3000}]*([，。！？：；、「」『』（）]|……)[ \t\x{3000}]*`)
+)
+
+// fullWidthPunctuation turns the punctuation of Chinese text full-width.
+// Marks between Latin text, such as "3.5", "10:30" or "Mr. Smith", are
+// left alone.
+func fullWidthPunctuation(text string) string {
+	text = replacePairs(text, doubleQuoted, "「", "」")
+	text = replacePairs(text, singleQuoted, "『", "』")
+	text = replacePairs(text, parenthesized, "（", "）")
+
+	var b strings.Builder
+	last := 0
+	for _, match := range ellipsis.FindAllStringIndex(text, -1) {
+		b.WriteString(text[last:match[0]])
+		if nextToCJK(text, match[0], match[1]) {
+			b.WriteString("……")
+		} else {
+			b.WriteString(text[match[0]:match[1]])
+		}
+		last = match[1]
+	}
+	b.WriteString(text[last:])
+	text = b.String()
+
+	runes := []rune(text)
+	b.Reset()
+	for i, r := range runes {
+		prev, next := neighbours(runes, i)
+		switch {
+		case r == ':' && unicode.IsDigit(prev) && unicode.IsDigit(next):
+			b.WriteRune(r)
+		case fullWidth[r] != "" && (isCJK(prev) || isCJK(next)):
+			b.WriteString(fullWidth[r])
+		case r == '.' && isCJK(prev) && (next == 0 || isCJK(next)):
+			b.WriteString("。")
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return punctuationGap.ReplaceAllString(b.String(), "$1")
+}
+
+// replacePairs replaces the delimiters matched by pattern with open and
+// close when the text between them is Chinese.
+func replacePairs(text string, pattern *regexp.Regexp, open, close string) string {
+	return pattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := pattern.FindStringSubmatch(match)
+		inner := strings.Join(groups[1:], "")
+		if strings.IndexFunc(inner, isCJK) < 0 {
+			return match
+		}
+		return open + inner + close
+	})
+}
+
+// neighbours returns the runes before and after runes[i], skipping spaces
+// but not line breaks, or 0 where there are none.
+func neighbours(runes []rune, i int) (prev, next rune) {
+	for j := i - 1; j >= 0 && runes[j] != '\n'; j-- {
+		if runes[j] != ' ' && runes[j] != '\t' {
+			prev = runes[j]
+			break
+		}
+	}
+	for j := i + 1; j < len(runes) && runes[j] != '\n'; j++ {
+		if runes[j] != ' ' && runes[j] != '\t' {
+			next = runes[j]
+			break
+		}
+	}
+	return prev, next
+}
+
+// nextToCJK reports whether the text around text[start:end] is Chinese.
+func nextToCJK(text string, start, end int) bool {
+	before := []rune(strings.TrimRight(text[:start], " \t"))
+	after := []rune(strings.TrimLeft(text[end:], " \t"))
+	return (len(before) > 0 && isCJK(before[len(before)-1])) || (len(after) > 0 && isCJK(after[0]))
+}
+
+var (
+	cjkBeforeLatin = regexp.MustCompile(`([\p{Han}\p{Hiragana}\p{Katakana}\p{Bopomofo}])[ \t]*([A-Za-z0-9])`)
+	latinBeforeCJK = regexp.MustCompile(`([A-Za-z0-9%])[ \t]*([\p{Han}\p{Hiragana}\p{Katakana}\p{Bopomofo}])`)
+)
+
+// cjkLatinSpacing puts exactly one space between Chinese text and Latin
+// letters or digits.
+func cjkLatinSpacing(text string) string {
+	text = cjkBeforeLatin.ReplaceAllString(text, "$1 $2")
+	return latinBeforeCJK.ReplaceAllString(text, "$1 $2")
+}
+
+// isCJK reports whether r is Chinese or Japanese text or full-width
+// punctuation.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Bopomofo) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff01 && r <= 0xff60)
+}
+
+// NormalizingTranslator normalizes what the wrapped translator returns.
+type NormalizingTranslator struct {
+	Normalizer *Normalizer
+	Next       TextTranslator
+}
+
+func (nt *NormalizingTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	translated, err := nt.Next.TranslateToChineseTraditional(ctx, text)
+	if err != nil {
+		return "", err
+	}
+	return nt.Normalizer.Normalize(translated), nil
+}
+
+func (nt *NormalizingTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	next, ok := nt.Next.(ContextTranslator)
+	if !ok {
+		return nt.TranslateToChineseTraditional(ctx, text)
+	}
+	translated, err := next.TranslateWithContext(ctx, before, text, after)
+	if err != nil {
+		return "", err
+	}
+	return nt.Normalizer.Normalize(translated), nil
+}
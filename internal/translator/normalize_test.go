@@ -0,0 +1,118 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	defaults := DefaultNormalizationRules()
+	taiwan := &NormalizationRules{
+		Punctuation: true,
+		Spacing:     true,
+		Terms:       map[string]string{"視頻": "影片", "軟件": "軟體", "網絡": "網路", "信息": "訊息", "信息量": "資訊量"},
+		Units:       map[string]string{"米": "公尺", "千米": "公里"},
+	}
+
+	tests := []struct {
+		name  string
+		rules *NormalizationRules
+		text  string
+		want  string
+	}{
+		// Punctuation
+		{name: "comma and question mark", rules: defaults, text: "你好,你好嗎?", want: "你好，你好嗎？"},
+		{name: "exclamation, colon and semicolon", rules: defaults, text: "注意:快跑!他們來了;快", want: "注意：快跑！他們來了；快"},
+		{name: "full stop between Chinese", rules: defaults, text: "我走了.你呢.", want: "我走了。你呢。"},
+		{name: "ellipsis", rules: defaults, text: "我...不知道…", want: "我……不知道……"},
+		{name: "double quotes", rules: defaults, text: `他說"你好"`, want: "他說「你好」"},
+		{name: "curly quotes", rules: defaults, text: "他說“你好”", want: "他說「你好」"},
+		{name: "single quotes", rules: defaults, text: "「他說‘走’」", want: "「他說『走』」"},
+		{name: "parentheses", rules: defaults, text: "(笑聲)", want: "（笑聲）"},
+		{name: "spaces around full-width marks", rules: defaults, text: "你好 ， 朋友 。", want: "你好，朋友。"},
+		{name: "line breaks are kept", rules: defaults, text: "你好,\n朋友!", want: "你好，\n朋友！"},
+
+		// Spacing
+		{name: "Latin inside Chinese", rules: defaults, text: "我用iPhone拍的", want: "我用 iPhone 拍的"},
+		{name: "digits inside Chinese", rules: defaults, text: "他有3個", want: "他有 3 個"},
+		{name: "one space only", rules: defaults, text: "我用   iPhone\t拍的", want: "我用 iPhone 拍的"},
+		{name: "percent", rules: defaults, text: "漲了50%左右", want: "漲了 50% 左右"},
+
+		// Terms and units
+		{name: "terms", rules: taiwan, text: "這個視頻軟件", want: "這個影片軟體"},
+		{name: "longest term first", rules: taiwan, text: "信息量很大的信息", want: "資訊量很大的訊息"},
+		{name: "unit after a number", rules: taiwan, text: "跑了5米", want: "跑了 5 公尺"},
+		{name: "unit after a space", rules: taiwan, text: "跑了 5 千米", want: "跑了 5 公里"},
+		{name: "unit without a number", rules: taiwan, text: "吃米飯", want: "吃米飯"},
+		{name: "no rules", rules: &NormalizationRules{}, text: "你好,視頻5米", want: "你好,視頻5米"},
+
+		// Left alone
+		{name: "Latin-only line", rules: taiwan, text: `Hello, "world"... (really?) Yes!`, want: `Hello, "world"... (really?) Yes!`},
+		{name: "decimal number", rules: defaults, text: "3.5", want: "3.5"},
+		{name: "time", rules: defaults, text: "在10:30見", want: "在 10:30 見"},
+		{name: "thousands separator", rules: defaults, text: "1,000", want: "1,000"},
+		{name: "abbreviation", rules: defaults, text: "Mr. Smith", want: "Mr. Smith"},
+		{name: "URL", rules: taiwan, text: "https://example.com/watch?v=abc,def&t=1:30", want: "https://example.com/watch?v=abc,def&t=1:30"},
+		{name: "URL after Chinese", rules: defaults, text: "網址：https://example.com/a?b=1", want: "網址：https://example.com/a?b=1"},
+		{name: "ASS tags", rules: defaults, text: `{\an8}{\i1}你好{\i0}`, want: `{\an8}{\i1}你好{\i0}`},
+		{name: "ASS tags with arguments", rules: defaults, text: `{\pos(960,80)}你好`, want: `{\pos(960,80)}你好`},
+		{name: "already normalized", rules: taiwan, text: "「你好」，我用 iPhone 跑了 5 公尺……", want: "「你好」，我用 iPhone 跑了 5 公尺……"},
+		{name: "empty", rules: taiwan, text: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewNormalizer(tt.rules).Normalize(tt.text); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadNormalizationRules(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    *NormalizationRules
+		wantErr bool
+	}{
+		{name: "missing file", want: DefaultNormalizationRules()},
+		{
+			name:    "settings left out keep their defaults",
+			content: "terms:\n  視頻: 影片\n",
+			want:    &NormalizationRules{Punctuation: true, Spacing: true, Terms: map[string]string{"視頻": "影片"}},
+		},
+		{
+			name:    "turned off",
+			content: "punctuation: false\nspacing: false\n",
+			want:    &NormalizationRules{},
+		},
+		{name: "broken", content: "terms: [", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "rules.yaml")
+			if tt.content != "" {
+				path = filepath.Join(dir, tt.name+".yaml")
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := LoadNormalizationRules(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("LoadNormalizationRules() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadNormalizationRules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
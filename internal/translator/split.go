@@ -0,0 +1,141 @@
+package translator
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sentenceEnds end a sentence when followed by a space or the end of the
+// text; the CJK ones end it wherever they are.
+const (
+	sentenceEnds    = ".!?…"
+	cjkSentenceEnds = "。！？"
+	// clauseEnds are where a sentence that is too long on its own is cut.
+	clauseEnds = ",;:，；：、"
+)
+
+// abbreviations end in a full stop without ending the sentence, so no cut
+// is made after them. Lower case.
+var abbreviations = map[string]bool{
+	"mr.": true, "mrs.": true, "ms.": true, "dr.": true, "prof.": true,
+	"st.": true, "mt.": true, "jr.": true, "sr.": true, "vs.": true,
+	"e.g.": true, "i.e.": true, "lt.": true, "sgt.": true, "capt.": true,
+}
+
+// splitSentences splits text into pieces of at most limit characters to
+// translate separately, packing as many whole sentences into each as fit.
+// A sentence longer than limit is cut after a clause, else between words,
+// and as a last resort anywhere.
+func splitSentences(text string, limit int) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var pieces []string
+	var current strings.Builder
+	flush := func() {
+		if piece := strings.TrimSpace(current.String()); piece != "" {
+			pieces = append(pieces, piece)
+		}
+		current.Reset()
+	}
+	for _, sentence := range sentences(text) {
+		for _, part := range fitSentence(sentence, limit) {
+			if utf8.RuneCountInString(current.String())+utf8.RuneCountInString(part) > limit {
+				flush()
+			}
+			current.WriteString(part)
+		}
+	}
+	flush()
+	return pieces
+}
+
+// sentences cuts text after every sentence, except after abbreviations
+// such as "Mr.".
+func sentences(text string) []string {
+	var sentences []string
+	for _, sentence := range cutAfter(text, isSentenceEnd) {
+		if n := len(sentences); n > 0 && endsWithAbbreviation(sentences[n-1]) {
+			sentences[n-1] += sentence
+			continue
+		}
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+func endsWithAbbreviation(sentence string) bool {
+	words := strings.Fields(sentence)
+	return len(words) > 0 && abbreviations[strings.ToLower(words[len(words)-1])]
+}
+
+// fitSentence cuts a sentence longer than limit into parts that fit.
+func fitSentence(sentence string, limit int) []string {
+	if utf8.RuneCountInString(sentence) <= limit {
+		return []string{sentence}
+	}
+	var parts []string
+	for _, clause := range cutAfter(sentence, func(r, _ rune) bool { return strings.ContainsRune(clauseEnds, r) }) {
+		if utf8.RuneCountInString(clause) <= limit {
+			parts = append(parts, clause)
+			continue
+		}
+		for _, word := range cutAfter(clause, func(r, _ rune) bool { return unicode.IsSpace(r) }) {
+			for utf8.RuneCountInString(word) > limit {
+				runes := []rune(word)
+				parts = append(parts, string(runes[:limit]))
+				word = string(runes[limit:])
+			}
+			parts = append(parts, word)
+		}
+	}
+	return parts
+}
+
+// cutAfter splits text after every rune for which end, given the rune and
+// the one after it (0 at the end), is true. Spaces following a cut stay with
+// the part before it, so joining the parts gives back the text.
+func cutAfter(text string, end func(r, next rune) bool) []string {
+	runes := []rune(text)
+	var parts []string
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if !end(runes[i], next) {
+			continue
+		}
+		for i+1 < len(runes) && unicode.IsSpace(runes[i+1]) {
+			i++
+		}
+		parts = append(parts, string(runes[start:i+1]))
+		start = i + 1
+	}
+	if start < len(runes) {
+		parts = append(parts, string(runes[start:]))
+	}
+	return parts
+}
+
+// isSentenceEnd reports whether a sentence ends after r. A run of
+// terminators such as "?!" or "..." ends it after the last one.
+func isSentenceEnd(r, next rune) bool {
+	if strings.ContainsRune(cjkSentenceEnds, r) {
+		return !strings.ContainsRune(cjkSentenceEnds, next)
+	}
+	return strings.ContainsRune(sentenceEnds, r) && (next == 0 || unicode.IsSpace(next))
+}
+
+// joinPieces joins translated pieces, with spaces unless the language is
+// written without them.
+func joinPieces(pieces []string, targetLang string) string {
+	switch strings.SplitN(targetLang, "-", 2)[0] {
+	case "zh", "ja", "th":
+		return strings.Join(pieces, "")
+	}
+	return strings.Join(pieces, " ")
+}
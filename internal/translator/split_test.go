@@ -0,0 +1,145 @@
+package translator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "fits",
+			text:  "Hello there. How are you?",
+			limit: 100,
+			want:  []string{"Hello there. How are you?"},
+		},
+		{
+			name:  "Latin sentences",
+			text:  "Hello there. How are you? I'm fine!",
+			limit: 15,
+			want:  []string{"Hello there.", "How are you?", "I'm fine!"},
+		},
+		{
+			name:  "sentences packed together",
+			text:  "One. Two. Three. Four.",
+			limit: 10,
+			want:  []string{"One. Two.", "Three.", "Four."},
+		},
+		{
+			name:  "full stop inside a word",
+			text:  "Version 2.5 is out. Get it at example.com now.",
+			limit: 26,
+			want:  []string{"Version 2.5 is out.", "Get it at example.com now."},
+		},
+		{
+			name:  "run of terminators",
+			text:  "You did what?! Why would you?",
+			limit: 16,
+			want:  []string{"You did what?!", "Why would you?"},
+		},
+		{
+			name:  "ellipsis",
+			text:  "Wait... I know him. Really…  He was here.",
+			limit: 12,
+			want:  []string{"Wait...", "I know him.", "Really…", "He was here."},
+		},
+		{
+			name:  "abbreviations",
+			text:  "Mr. Smith met Dr. Jones. They talked.",
+			limit: 25,
+			want:  []string{"Mr. Smith met Dr. Jones.", "They talked."},
+		},
+		{
+			name:  "abbreviations ignore case",
+			text:  "Bring snacks, e.g. chips. Go now.",
+			limit: 26,
+			want:  []string{"Bring snacks, e.g. chips.", "Go now."},
+		},
+		{
+			name:  "CJK sentences",
+			text:  "你好。你好嗎？我很好！",
+			limit: 5,
+			want:  []string{"你好。", "你好嗎？", "我很好！"},
+		},
+		{
+			name:  "run of CJK terminators",
+			text:  "真的嗎？！不會吧。",
+			limit: 5,
+			want:  []string{"真的嗎？！", "不會吧。"},
+		},
+		{
+			name:  "CJK and Latin",
+			text:  "他說。Then he left. 然後走了。",
+			limit: 14,
+			want:  []string{"他說。", "Then he left.", "然後走了。"},
+		},
+		{
+			name:  "long sentence cut after a clause",
+			text:  "When I was young, I lived by the sea, and it was lovely.",
+			limit: 20,
+			want:  []string{"When I was young,", "I lived by the sea,", "and it was lovely."},
+		},
+		{
+			name:  "long clause cut between words",
+			text:  "the quick brown fox jumps over the lazy dog",
+			limit: 15,
+			want:  []string{"the quick", "brown fox", "jumps over the", "lazy dog"},
+		},
+		{
+			name:  "long word cut anywhere",
+			text:  "abcdefghijklmnopqrstuvwxyz",
+			limit: 10,
+			want:  []string{"abcdefghij", "klmnopqrst", "uvwxyz"},
+		},
+		{
+			name:  "long CJK sentence cut after a clause",
+			text:  "我昨天去了市場，買了很多水果，還有一些蔬菜。",
+			limit: 8,
+			want:  []string{"我昨天去了市場，", "買了很多水果，", "還有一些蔬菜。"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitSentences(tt.text, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSentences(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+			for _, piece := range got {
+				if utf8.RuneCountInString(piece) > tt.limit {
+					t.Errorf("piece %q is longer than %d characters", piece, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitSentencesKeepsText(t *testing.T) {
+	text := strings.Repeat("Mr. Smith said hello, then left... 他走了。真的？！ ", 40)
+	pieces := splitSentences(text, 100)
+	if got, want := strings.Join(strings.Fields(strings.Join(pieces, " ")), " "), strings.Join(strings.Fields(text), " "); got != want {
+		t.Errorf("splitSentences() lost text: %q", got)
+	}
+}
+
+func TestJoinPieces(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{lang: "zh-TW", want: "一二"},
+		{lang: "ja", want: "一二"},
+		{lang: "en", want: "一 二"},
+	}
+	for _, tt := range tests {
+		if got := joinPieces([]string{"一", "二"}, tt.lang); got != tt.want {
+			t.Errorf("joinPieces(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package translator
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// CountingTranslator reports the number of characters sent to the wrapped
+// translator, including context cues, so usage can be compared against
+// provider budgets.
+type CountingTranslator struct {
+	Next  TextTranslator
+	Count func(chars int)
+}
+
+func (ct *CountingTranslator) TranslateToChineseTraditional(ctx context.Context, text string) (string, error) {
+	ct.Count(utf8.RuneCountInString(text))
+	return ct.Next.TranslateToChineseTraditional(ctx, text)
+}
+
+func (ct *CountingTranslator) TranslateWithContext(ctx context.Context, before []string, text string, after []string) (string, error) {
+	next, ok := ct.Next.(ContextTranslator)
+	if !ok {
+		return ct.TranslateToChineseTraditional(ctx, text)
+	}
+
+	chars := utf8.RuneCountInString(text)
+	for _, cue := range append(append([]string{}, before...), after...) {
+		chars += utf8.RuneCountInString(cue)
+	}
+	ct.Count(chars)
+	return next.TranslateWithContext(ctx, before, text, after)
+}
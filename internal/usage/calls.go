@@ -0,0 +1,78 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+)
+
+const callsBucket = "calls"
+
+// Calls is when calls to an external service last succeeded and failed.
+// How many calls were made is counted by CallsCounter and ErrorsCounter.
+type Calls struct {
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// CallsCounter is the counter of calls made to service.
+func CallsCounter(service string) string {
+	return "calls:" + service
+}
+
+// ErrorsCounter is the counter of calls to service that failed.
+func ErrorsCounter(service string) string {
+	return "call-errors:" + service
+}
+
+// RecordCall counts a call to service, and a failure when err is not nil.
+func (t *Tracker) RecordCall(service string, err error) {
+	t.Add(CallsCounter(service), 1)
+	if err != nil {
+		t.Add(ErrorsCounter(service), 1)
+	}
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	calls := t.loadCallsLocked(service)
+	if err != nil {
+		calls.LastFailure = now
+		calls.LastError = err.Error()
+	} else {
+		calls.LastSuccess = now
+	}
+	t.calls[service] = calls
+	t.dirtyCalls[service] = true
+}
+
+// Calls returns when calls to service last succeeded and failed.
+func (t *Tracker) Calls(service string) Calls {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.loadCallsLocked(service)
+}
+
+func (t *Tracker) loadCallsLocked(service string) Calls {
+	if calls, ok := t.calls[service]; ok {
+		return calls
+	}
+
+	var calls Calls
+	if _, err := t.store.Get(callsBucket, service, &calls); err != nil {
+		calls = Calls{}
+	}
+	t.calls[service] = calls
+	return calls
+}
+
+func (t *Tracker) flushCallsLocked() error {
+	for service := range t.dirtyCalls {
+		if err := t.store.Put(callsBucket, service, t.calls[service]); err != nil {
+			return fmt.Errorf("failed to save calls: %w", err)
+		}
+		delete(t.dirtyCalls, service)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package usage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"subtitle-hunter/internal/store"
+)
+
+const bucket = "usage"
+
+// Tracker counts usage (translated characters, downloads, calls to external
+// services, ...) per day and per month. Counts are kept in memory and
+// written to the store on Flush, so counting every cue doesn't rewrite the
+// store each time.
+type Tracker struct {
+	store *store.Store
+	now   func() time.Time
+
+	mu     sync.Mutex
+	counts map[string]int
+	dirty  map[string]bool
+
+	calls      map[string]Calls
+	dirtyCalls map[string]bool
+}
+
+func NewTracker(s *store.Store) *Tracker {
+	return &Tracker{
+		store:      s,
+		now:        time.Now,
+		counts:     make(map[string]int),
+		dirty:      make(map[string]bool),
+		calls:      make(map[string]Calls),
+		dirtyCalls: make(map[string]bool),
+	}
+}
+
+func dayKey(t time.Time, counter string) string {
+	return "day:" + t.Format("2006-01-02") + ":" + counter
+}
+
+func monthKey(t time.Time, counter string) string {
+	return "month:" + t.Format("2006-01") + ":" + counter
+}
+
+// Add increases counter by n for today and this month.
+func (t *Tracker) Add(counter string, n int) {
+	if n == 0 {
+		return
+	}
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range []string{dayKey(now, counter), monthKey(now, counter)} {
+		t.counts[key] = t.loadLocked(key) + n
+		t.dirty[key] = true
+	}
+}
+
+// AddWithin increases counter by n like Add, unless that would take today's
+// count above limit. It reports whether the count was increased. A limit of
+// 0 or less means no limit.
+func (t *Tracker) AddWithin(counter string, n, limit int) bool {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if limit > 0 && t.loadLocked(dayKey(now, counter))+n > limit {
+		return false
+	}
+	for _, key := range []string{dayKey(now, counter), monthKey(now, counter)} {
+		t.counts[key] = t.loadLocked(key) + n
+		t.dirty[key] = true
+	}
+	return true
+}
+
+// Today returns the count for counter so far today.
+func (t *Tracker) Today(counter string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.loadLocked(dayKey(t.now(), counter))
+}
+
+// ThisMonth returns the count for counter so far this month.
+func (t *Tracker) ThisMonth(counter string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.loadLocked(monthKey(t.now(), counter))
+}
+
+// Flush writes changed counts to the store.
+func (t *Tracker) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key := range t.dirty {
+		if err := t.store.Put(bucket, key, t.counts[key]); err != nil {
+			return fmt.Errorf("failed to save usage: %w", err)
+		}
+		delete(t.dirty, key)
+	}
+	return t.flushCallsLocked()
+}
+
+func (t *Tracker) loadLocked(key string) int {
+	if count, ok := t.counts[key]; ok {
+		return count
+	}
+
+	var count int
+	if _, err := t.store.Get(bucket, key, &count); err != nil {
+		count = 0
+	}
+	t.counts[key] = count
+	return count
+}
@@ -0,0 +1,431 @@
+package wanted
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/lang"
+	"subtitle-hunter/internal/store"
+)
+
+// Status is the state of one target language for one item.
+type Status string
+
+const (
+	StatusEmbedded   Status = "embedded"
+	StatusExternal   Status = "external"
+	StatusDownloaded Status = "downloaded"
+	StatusTranslated Status = "translated"
+	StatusMissing    Status = "missing"
+	StatusIgnored    Status = "ignored"
+)
+
+// Statuses lists every status in display order.
+var Statuses = []Status{StatusEmbedded, StatusExternal, StatusDownloaded, StatusTranslated, StatusMissing, StatusIgnored}
+
+const (
+	resultsBucket = "subtitle-results"
+	ignoredBucket = "ignored-languages"
+)
+
+// Result records how a subtitle was obtained for an item.
+type Result struct {
+	Status    Status    `json:"status"`
+	Source    string    `json:"source"`
+	Path      string    `json:"path"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// FailedCues are the numbers of the cues in a translated subtitle that
+	// kept their fallback text, and Partial is set when there are enough of
+	// them for the subtitle to count as only partly translated.
+	FailedCues []int `json:"failed_cues,omitempty"`
+	Partial    bool  `json:"partial,omitempty"`
+	// SubtitleID is the OpenSubtitles subtitle the file was made from, when
+	// it came from a search.
+	SubtitleID string `json:"subtitle_id,omitempty"`
+	// VideoPath and VideoSize are the video file the subtitle was saved
+	// for, to tell when the item's file is replaced by another release.
+	VideoPath string `json:"video_path,omitempty"`
+	VideoSize int64  `json:"video_size,omitempty"`
+	// Versions are the files of the item's versions when the subtitle was
+	// saved, if it had more than one, to tell which versions were added
+	// since.
+	Versions []string `json:"versions,omitempty"`
+}
+
+// Replaced reports whether the item's video is no longer the file the
+// subtitle was saved for: it was moved or renamed, or swapped for a file of
+// another size. Such a subtitle likely needs re-syncing or fetching again.
+// Results recorded before the video was remembered never count as replaced,
+// and neither do those whose video is still one of the item's versions.
+func (r Result) Replaced(item jellyfin.MediaItem) bool {
+	if r.VideoPath == "" {
+		return false
+	}
+	for _, version := range item.Versions("") {
+		if version.Path == r.VideoPath {
+			return version.Size > 0 && r.VideoSize > 0 && version.Size != r.VideoSize
+		}
+	}
+	return true
+}
+
+// NewResult describes a subtitle just saved for the item's current video.
+func NewResult(item jellyfin.MediaItem, status Status, source, path string) Result {
+	videoPath, videoSize := item.VideoFile()
+	result := Result{Status: status, Source: source, Path: path, VideoPath: videoPath, VideoSize: videoSize}
+	if versions := item.Versions(""); len(versions) > 1 {
+		for _, version := range versions {
+			result.Versions = append(result.Versions, version.Path)
+		}
+	}
+	return result
+}
+
+// ForcedSuffix marks forced subtitles in file names, as in
+// "Movie.zh-Hant.forced.srt", which is how Jellyfin recognises them.
+const ForcedSuffix = ".forced"
+
+// Target is one subtitle the wanted list tracks for each item: a language,
+// either as a full subtitle or as a forced one that only covers dialogue
+// in another language (signs, a few lines of a foreign language).
+type Target struct {
+	Language lang.Tag
+	Forced   bool
+}
+
+// String returns the tag used in file names and store keys, e.g. "zh-Hant"
+// or "zh-Hant.forced".
+func (t Target) String() string {
+	if t.Forced {
+		return t.Language.String() + ForcedSuffix
+	}
+	return t.Language.String()
+}
+
+// DisplayName is the language name, marked "(forced)" for forced targets.
+func (t Target) DisplayName() string {
+	if t.Forced {
+		return t.Language.DisplayName() + " (forced)"
+	}
+	return t.Language.DisplayName()
+}
+
+// Cell is the status of one target for an item.
+type Cell struct {
+	Target
+	Status Status
+	Result *Result
+	// Replaced is set when the recorded subtitle was saved for a video file
+	// the item no longer has.
+	Replaced bool
+	// NewVersions are the files of versions added to the item since the
+	// recorded subtitle was saved, which lack it. The target is missing
+	// until they get it.
+	NewVersions []string
+}
+
+// Row is one item of the wanted list with a cell per target.
+type Row struct {
+	Item  jellyfin.MediaItem
+	Cells []Cell
+}
+
+// Missing reports whether any target still needs a subtitle.
+func (r Row) Missing() bool {
+	for _, cell := range r.Cells {
+		if cell.Status == StatusMissing {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaced reports whether any target's subtitle was saved for a video
+// file the item no longer has.
+func (r Row) Replaced() bool {
+	for _, cell := range r.Cells {
+		if cell.Replaced {
+			return true
+		}
+	}
+	return false
+}
+
+// Service computes per-language subtitle status by combining the streams
+// Jellyfin reports with the results and ignore flags kept in the store.
+type Service struct {
+	store *store.Store
+
+	mu        sync.RWMutex
+	languages []lang.Tag
+	forced    []lang.Tag
+	preferred string
+}
+
+func NewService(s *store.Store, languages []lang.Tag) *Service {
+	return &Service{store: s, languages: languages}
+}
+
+// Languages returns the target languages shown in the matrix.
+func (s *Service) Languages() []lang.Tag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.languages
+}
+
+// SetLanguages replaces the target languages, e.g. after a config reload.
+func (s *Service) SetLanguages(languages []lang.Tag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.languages = languages
+}
+
+// ForcedLanguages returns the languages whose forced subtitles are tracked
+// as well.
+func (s *Service) ForcedLanguages() []lang.Tag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.forced
+}
+
+// SetForcedLanguages replaces the forced-subtitle languages.
+func (s *Service) SetForcedLanguages(languages []lang.Tag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forced = languages
+}
+
+// PreferredVersion returns the text picking the version of an item with
+// several that gets subtitles; empty means every version does.
+func (s *Service) PreferredVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.preferred
+}
+
+// SetPreferredVersion replaces the preferred version.
+func (s *Service) SetPreferredVersion(preferred string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preferred = preferred
+}
+
+// Versions returns the versions of the item that get subtitles, the
+// preferred one or every one.
+func (s *Service) Versions(item jellyfin.MediaItem) []jellyfin.MediaSource {
+	return item.Versions(s.PreferredVersion())
+}
+
+// Targets returns the columns of the matrix: a full subtitle for every
+// target language, followed by the forced ones.
+func (s *Service) Targets() []Target {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	targets := make([]Target, 0, len(s.languages)+len(s.forced))
+	for _, language := range s.languages {
+		targets = append(targets, Target{Language: language})
+	}
+	for _, language := range s.forced {
+		targets = append(targets, Target{Language: language, Forced: true})
+	}
+	return targets
+}
+
+// Status returns the status of one target for an item.
+func (s *Service) Status(item jellyfin.MediaItem, target Target) (Status, error) {
+	cell, err := s.cell(item, target)
+	return cell.Status, err
+}
+
+// Cell returns the status of one target for an item with what it was
+// decided from.
+func (s *Service) Cell(item jellyfin.MediaItem, target Target) (Cell, error) {
+	return s.cell(item, target)
+}
+
+func recordKey(itemID string, target Target) string {
+	return itemID + "/" + target.String()
+}
+
+// RecordResult remembers that a subtitle was downloaded or translated.
+// Results for a new subtitle should come from NewResult, so they remember
+// the video they were saved for.
+func (s *Service) RecordResult(itemID string, target Target, result Result) error {
+	if result.UpdatedAt.IsZero() {
+		result.UpdatedAt = time.Now()
+	}
+	if err := s.store.Put(resultsBucket, recordKey(itemID, target), result); err != nil {
+		return fmt.Errorf("failed to record subtitle result: %w", err)
+	}
+	return nil
+}
+
+// Result returns the recorded result of a target for an item, if any.
+func (s *Service) Result(itemID string, target Target) (Result, bool, error) {
+	var result Result
+	recorded, err := s.store.Get(resultsBucket, recordKey(itemID, target), &result)
+	return result, recorded, err
+}
+
+// ReplacedTargets returns the targets of an item whose recorded subtitle
+// was saved for a video file the item no longer has.
+func (s *Service) ReplacedTargets(item jellyfin.MediaItem) ([]Target, error) {
+	var replaced []Target
+	for _, target := range s.Targets() {
+		cell, err := s.cell(item, target)
+		if err != nil {
+			return nil, err
+		}
+		if cell.Replaced {
+			replaced = append(replaced, target)
+		}
+	}
+	return replaced, nil
+}
+
+// ForgetResult drops the recorded result of a target for an item, once the
+// subtitle it describes is gone.
+func (s *Service) ForgetResult(itemID string, target Target) error {
+	return s.store.Delete(resultsBucket, recordKey(itemID, target))
+}
+
+// SetIgnored marks or unmarks a target as not wanted for an item.
+func (s *Service) SetIgnored(itemID string, target Target, ignored bool) error {
+	key := recordKey(itemID, target)
+	if !ignored {
+		return s.store.Delete(ignoredBucket, key)
+	}
+	return s.store.Put(ignoredBucket, key, time.Now())
+}
+
+// Compute returns a row per item, sorted by series, season, episode and name.
+func (s *Service) Compute(items []jellyfin.MediaItem) ([]Row, error) {
+	targets := s.Targets()
+	rows := make([]Row, 0, len(items))
+
+	for _, item := range items {
+		row := Row{Item: item, Cells: make([]Cell, 0, len(targets))}
+		for _, target := range targets {
+			cell, err := s.cell(item, target)
+			if err != nil {
+				return nil, err
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		rows = append(rows, row)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i].Item, rows[j].Item
+		if a.SeriesName != b.SeriesName {
+			return a.SeriesName < b.SeriesName
+		}
+		if a.ParentIndexNumber != b.ParentIndexNumber {
+			return a.ParentIndexNumber < b.ParentIndexNumber
+		}
+		if a.IndexNumber != b.IndexNumber {
+			return a.IndexNumber < b.IndexNumber
+		}
+		return a.Name < b.Name
+	})
+
+	return rows, nil
+}
+
+// cell decides the status of one target. Ignored wins over everything,
+// an embedded track over anything on disk, and our own recorded result over
+// the external stream Jellyfin reports for the file we wrote. Only forced
+// streams count for a forced target, and only full ones otherwise.
+func (s *Service) cell(item jellyfin.MediaItem, target Target) (Cell, error) {
+	cell := Cell{Target: target, Status: StatusMissing}
+	key := recordKey(item.ID, target)
+
+	var ignoredAt time.Time
+	ignored, err := s.store.Get(ignoredBucket, key, &ignoredAt)
+	if err != nil {
+		return cell, err
+	}
+	if ignored {
+		cell.Status = StatusIgnored
+		return cell, nil
+	}
+
+	embedded, external := item.SubtitleStatus(target.Language, target.Forced)
+	if embedded {
+		cell.Status = StatusEmbedded
+		return cell, nil
+	}
+
+	var result Result
+	recorded, err := s.store.Get(resultsBucket, key, &result)
+	if err != nil {
+		return cell, err
+	}
+	if recorded {
+		cell.Status = result.Status
+		cell.Result = &result
+		cell.Replaced = result.Replaced(item)
+		if !cell.Replaced {
+			cell.NewVersions = s.newVersions(item, target, result)
+		}
+		if len(cell.NewVersions) > 0 {
+			cell.Status = StatusMissing
+		}
+		return cell, nil
+	}
+
+	if external {
+		cell.Status = StatusExternal
+	}
+	return cell, nil
+}
+
+// newVersions returns the files of the item's versions that get subtitles
+// but were added after result's subtitle was saved, leaving out those with
+// a stream of the target of their own. Results recorded before versions
+// were remembered were saved for their video alone.
+func (s *Service) newVersions(item jellyfin.MediaItem, target Target, result Result) []string {
+	known := result.Versions
+	if len(known) == 0 {
+		if result.VideoPath == "" {
+			return nil
+		}
+		known = []string{result.VideoPath}
+	}
+
+	var added []string
+	for _, version := range s.Versions(item) {
+		if containsPath(known, version.Path) {
+			continue
+		}
+		if embedded, external := version.SubtitleStatus(target.Language, target.Forced); embedded || external {
+			continue
+		}
+		added = append(added, version.Path)
+	}
+	return added
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary counts cells per status.
+func Summary(rows []Row) map[Status]int {
+	counts := make(map[Status]int)
+	for _, row := range rows {
+		for _, cell := range row.Cells {
+			counts[cell.Status]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,171 @@
+// Package watcher notices files dropped into directories, such as videos a
+// torrent client or an import script moves into place, once they are done
+// being written.
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ReadyFunc is called with the path of each new file once it has gone
+// unchanged for the settle delay.
+type ReadyFunc func(ctx context.Context, path string)
+
+// Watcher watches directories and their subdirectories for new files. A
+// file counts as new when it is created in or moved into a watched
+// directory, or arrives inside a directory moved in. Each write to it
+// restarts its settle delay, so a file still downloading is reported once,
+// after the download ends.
+type Watcher struct {
+	dirs   []string
+	settle time.Duration
+	match  func(path string) bool
+	ready  ReadyFunc
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+}
+
+// New returns a watcher of dirs that reports the files match accepts.
+func New(dirs []string, settle time.Duration, match func(path string) bool, ready ReadyFunc) *Watcher {
+	return &Watcher{
+		dirs:    dirs,
+		settle:  settle,
+		match:   match,
+		ready:   ready,
+		pending: make(map[string]*time.Timer),
+	}
+}
+
+// Start watches the directories in the background until ctx is done. Files
+// already there are left alone. It fails when a directory can't be watched.
+func (w *Watcher) Start(ctx context.Context) error {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start the directory watcher: %w", err)
+	}
+	for _, dir := range w.dirs {
+		if err := w.addTree(ctx, notify, dir, false); err != nil {
+			notify.Close()
+			return err
+		}
+	}
+	log.Printf("Watching %s for new videos", strings.Join(w.dirs, ", "))
+
+	go func() {
+		defer notify.Close()
+		for {
+			select {
+			case event, ok := <-notify.Events:
+				if !ok {
+					return
+				}
+				w.handle(ctx, notify, event)
+			case err, ok := <-notify.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Warning: directory watcher: %v", err)
+			case <-ctx.Done():
+				w.mu.Lock()
+				for path, timer := range w.pending {
+					timer.Stop()
+					delete(w.pending, path)
+				}
+				w.mu.Unlock()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// addTree watches dir and every directory under it, since fsnotify only
+// reports the direct contents of a directory. With schedule set, the files
+// found are treated as new, for a directory that was just moved in.
+func (w *Watcher) addTree(ctx context.Context, notify *fsnotify.Watcher, dir string, schedule bool) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if err := notify.Add(path); err != nil {
+				return fmt.Errorf("failed to watch %s: %w", path, err)
+			}
+			return nil
+		}
+		if schedule && w.match(path) {
+			w.schedule(ctx, path)
+		}
+		return nil
+	})
+}
+
+func (w *Watcher) handle(ctx context.Context, notify *fsnotify.Watcher, event fsnotify.Event) {
+	switch {
+	case event.Has(fsnotify.Create) || event.Has(fsnotify.Write):
+		info, err := os.Stat(event.Name)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			if event.Has(fsnotify.Create) {
+				if err := w.addTree(ctx, notify, event.Name, true); err != nil {
+					log.Printf("Warning: %v", err)
+				}
+			}
+			return
+		}
+		if w.match(event.Name) {
+			w.schedule(ctx, event.Name)
+		}
+
+	case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+		// Renamed files show up again under their new name
+		w.mu.Lock()
+		if timer, ok := w.pending[event.Name]; ok {
+			timer.Stop()
+			delete(w.pending, event.Name)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// schedule (re)starts the settle delay of path.
+func (w *Watcher) schedule(ctx context.Context, path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if timer, ok := w.pending[path]; ok && timer.Stop() {
+		timer.Reset(w.settle)
+		return
+	}
+	// A timer that fired while this waited for the lock finds itself
+	// replaced and leaves the file to the new one
+	var timer *time.Timer
+	timer = time.AfterFunc(w.settle, func() {
+		w.mu.Lock()
+		current := w.pending[path] == timer
+		if current {
+			delete(w.pending, path)
+		}
+		w.mu.Unlock()
+		if !current || ctx.Err() != nil {
+			return
+		}
+		if _, err := os.Stat(path); err != nil {
+			return
+		}
+		w.ready(ctx, path)
+	})
+	w.pending[path] = timer
+}
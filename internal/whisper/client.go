@@ -0,0 +1,95 @@
+package whisper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"subtitle-hunter/internal/httpclient"
+)
+
+// Client talks to a local speech recognition server. Both whisper.cpp's
+// /inference endpoint and OpenAI-compatible /v1/audio/transcriptions
+// endpoints (faster-whisper-server, etc.) accept the same form fields.
+type Client struct {
+	URL      string
+	Model    string
+	Language string
+	client   *http.Client
+}
+
+func NewClient(url, model, language string) *Client {
+	return &Client{
+		URL:      url,
+		Model:    model,
+		Language: language,
+		client:   httpclient.Default.Direct(),
+	}
+}
+
+// TranscribeToSRT uploads the audio file and returns the transcription as SRT.
+func (c *Client) TranscribeToSRT(ctx context.Context, audioPath string) ([]byte, error) {
+	file, err := os.Open(audioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer file.Close()
+
+	// Stream the multipart body so long recordings are not held in memory
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
+
+	go func() {
+		err := func() error {
+			part, err := writer.CreateFormFile("file", filepath.Base(audioPath))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(part, file); err != nil {
+				return err
+			}
+			fields := map[string]string{
+				"response_format": "srt",
+				"model":           c.Model,
+				"language":        c.Language,
+			}
+			for name, value := range fields {
+				if value == "" {
+					continue
+				}
+				if err := writer.WriteField(name, value); err != nil {
+					return err
+				}
+			}
+			return writer.Close()
+		}()
+		pipeWriter.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.URL, pipeReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call whisper server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("whisper server error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
@@ -1,46 +1,449 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"subtitle-hunter/config"
 	"subtitle-hunter/internal/handlers"
+	"subtitle-hunter/internal/httpclient"
 	"subtitle-hunter/internal/jellyfin"
+	"subtitle-hunter/internal/jobs"
+	"subtitle-hunter/internal/lang"
 	"subtitle-hunter/internal/opensubtitles"
+	"subtitle-hunter/internal/scheduler"
+	"subtitle-hunter/internal/watcher"
+	"subtitle-hunter/web"
 )
 
 func main() {
-	cfg := config.Load()
+	cmd, _ := findCommand("serve")
+	var args []string
+	if len(os.Args) > 1 {
+		name := os.Args[1]
+		if name == "help" || name == "-h" || name == "--help" {
+			printUsage(os.Stdout)
+			return
+		}
+		found, ok := findCommand(name)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+			printUsage(os.Stderr)
+			os.Exit(2)
+		}
+		cmd, args = found, os.Args[2:]
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if missing := cfg.Credentials().Missing(); cmd.run != nil && len(missing) > 0 {
+		log.Fatalf("%s environment variable is required; or run the server and open /setup", missing[0])
+	}
+
+	configureHTTP(cfg)
+	web.SetThemeDirectory(cfg.ThemeDirectory)
+	web.SetLanguage(cfg.InterfaceLanguage)
+	web.SetBasePath(cfg.BasePath)
+
+	// Cancelled on shutdown, which stops running jobs and scheduled hunts
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cmd.run == nil {
+		if problem := credentialsProblem(ctx, cfg); problem != "" {
+			cfg = runSetup(ctx, cfg, problem)
+			configureHTTP(cfg)
+			web.SetLanguage(cfg.InterfaceLanguage)
+		}
+	}
+
+	jellyfinClient := newJellyfinClient(cfg.Credentials())
+	instances := openSubtitlesInstances(cfg)
+	openSubtitlesClient := opensubtitles.NewRegistry(instances)
+	log.Printf("Configured %d OpenSubtitles instance(s)", len(instances))
 
-	if cfg.JellyfinAPIKey == "" {
-		log.Fatal("JELLYFIN_API_KEY environment variable is required")
+	settings := config.NewReloader(cfg)
+	handler, err := handlers.NewHandler(jellyfinClient, openSubtitlesClient, settings)
+	if err != nil {
+		log.Fatalf("Failed to initialize handler: %v", err)
+	}
+	if err := handler.CheckOwnership(); err != nil {
+		log.Fatalf("Failed to check subtitle ownership: %v", err)
 	}
+	httpclient.Default.Observe = handler.RecordCall
 
-	if cfg.OpenSubtitlesKey == "" {
-		log.Fatal("OPENSUBTITLES_API_KEY environment variable is required")
+	openSubtitlesClient.SetSearchStore(handler.Store, cfg.SearchStoreTTL)
+	jellyfin.SetScriptDetector(handler.DetectSubtitleScript)
+
+	if (cfg.EnableEmbeddedExtraction || cfg.EnableWhisper) && !handler.Extractor.Available() {
+		log.Printf("Warning: embedded extraction or whisper is enabled but %s was not found", cfg.FFmpegPath)
 	}
 
-	if cfg.JellyfinUserID == "" {
-		log.Fatal("JELLYFIN_USER_ID environment variable is required")
+	if cmd.run != nil {
+		code := runCommand(ctx, handler, cmd, args)
+		stop()
+		handler.Usage.Flush()
+		handler.Store.Close()
+		os.Exit(code)
 	}
 
-	jellyfinClient := jellyfin.NewClient(cfg.JellyfinURL, cfg.JellyfinAPIKey, cfg.JellyfinUserID)
-	openSubtitlesClient := opensubtitles.NewClient(cfg.OpenSubtitlesKey)
+	autoHunt := scheduler.New(jellyfinClient, handler.Store, func(ctx context.Context, item *jellyfin.MediaItem) error {
+		if handler.HuntingPaused(item) {
+			return scheduler.ErrSkipped
+		}
+		if handler.BudgetExhausted() {
+			return fmt.Errorf("%w: %w", scheduler.ErrDeferred, handlers.ErrBudgetExhausted)
+		}
+		hunt := handler.Hunt
+		if handler.Config().AutoHuntReplaced && handler.MediaReplaced(item) {
+			hunt = handler.Recheck
+		}
+		_, _, err := hunt(ctx, item, jobs.TriggerAuto)
+		if errors.Is(err, handlers.ErrBudgetExhausted) {
+			return fmt.Errorf("%w: %w", scheduler.ErrDeferred, err)
+		}
+		return err
+	}, cfg.AutoHuntInterval, autoHuntWindow(cfg))
+	autoHunt.SetFullScanInterval(cfg.AutoHuntFullScanInterval)
+	autoHunt.SetRecheck(func(item jellyfin.MediaItem) bool {
+		return handler.Config().AutoHuntReplaced && handler.MediaReplaced(&item)
+	})
+	autoHunt.SetConcurrency(cfg.WorkerPoolSize)
+	if handler.SchedulerPaused() {
+		autoHunt.Pause()
+	}
+	autoHunt.SetEvents(handler.Events)
+	handler.Scheduler = autoHunt
+	handler.Context = ctx
+	autoHunt.Start(ctx)
+	go handler.RunDownloadsMover(ctx)
+	go handler.RunCampaigns(ctx)
+	if len(cfg.WatchDirectories) > 0 {
+		dropped := watcher.New(cfg.WatchDirectories, cfg.WatchSettleDelay, handlers.IsVideoFile, handler.HuntDroppedFile)
+		if err := dropped.Start(ctx); err != nil {
+			log.Fatalf("Failed to watch directories: %v", err)
+		}
+	}
 
-	handler := handlers.NewHandler(jellyfinClient, openSubtitlesClient, cfg)
+	settings.OnReload(func(cfg *config.Config) {
+		if len(cfg.OpenSubtitlesInstances) > 0 {
+			openSubtitlesClient.SetInstances(openSubtitlesInstances(cfg))
+		}
+		openSubtitlesClient.SetSearchStore(handler.Store, cfg.SearchStoreTTL)
+		autoHunt.SetFullScanInterval(cfg.AutoHuntFullScanInterval)
+		autoHunt.SetConcurrency(cfg.WorkerPoolSize)
+		autoHunt.Reconfigure(cfg.AutoHuntInterval, autoHuntWindow(cfg))
+		web.SetLanguage(cfg.InterfaceLanguage)
+	})
+	settings.Start()
 
 	http.HandleFunc("/", handler.IndexHandler)
-	http.HandleFunc("/process/", handler.ProcessHandler)
-	http.HandleFunc("/status", handler.StatusHandler)
+	http.Handle("/static/", web.StaticHandler())
+	http.HandleFunc("/items/", handler.ItemsHandler)
+	http.HandleFunc("/series/", handler.SeriesHandler)
+	http.HandleFunc("/benchmark", handler.BenchmarkHandler)
+	http.HandleFunc("/wanted", handler.WantedHandler)
+	http.HandleFunc("/settings", handler.SettingsHandler)
+	http.HandleFunc("/quota", handler.QuotaHandler)
+	http.HandleFunc("/providers", handler.ProvidersHandler)
+	http.HandleFunc("/downloads", handler.DownloadsHandler)
+	http.HandleFunc("/campaigns", handler.CampaignsHandler)
+	http.HandleFunc("/jobs", handler.JobsHandler)
+	http.HandleFunc("/jobs/", handler.JobsHandler)
+	http.HandleFunc("/stats", handler.StatsHandler)
+	http.HandleFunc("/requests", handler.RequestsHandler)
+	http.HandleFunc("/login", handler.LoginHandler)
+	http.HandleFunc("/logout", handler.LogoutHandler)
+	http.HandleFunc("/api/docs", handler.APIDocsHandler)
+	http.HandleFunc("/api/docs/", handler.APIDocsHandler)
+	for _, route := range handler.APIRoutes() {
+		http.Handle(route.Pattern, route.Handler)
+	}
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
+	scheme := "http"
+	if cfg.TLSCertFile != "" {
+		scheme = "https"
+	}
 	log.Printf("Starting subtitle-hunter server on %s", addr)
 	log.Printf("Jellyfin URL: %s", cfg.JellyfinURL)
-	log.Printf("Web interface: http://localhost%s", addr)
+	log.Printf("Web interface: %s://localhost%s%s/", scheme, addr, cfg.BasePath)
+	if cfg.AdminPassword != "" {
+		log.Printf("Admin password set: visitors who haven't logged in can only browse the wanted list and request subtitles")
+	}
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: handlers.LogRequests(rootHandler(cfg.BasePath, handler.Roles(http.DefaultServeMux))),
+		// Requests share the shutdown context, so jobs started from the web
+		// interface stop on shutdown as well
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		log.Printf("Shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}()
+
+	if cfg.GRPCPort != 0 {
+		serveGRPC(ctx, cfg, handler)
+	}
+
+	serve := server.ListenAndServe
+	if cfg.TLSCertFile != "" {
+		serve = func() error { return server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile) }
+	}
+	if err := serve(); !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Server failed to start: %v", err)
 	}
+	<-shutdownDone
+	if err := handler.Usage.Flush(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if err := handler.Store.Close(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// serveGRPC serves the gRPC API on the configured port until ctx ends,
+// which also stops the jobs its calls started.
+func serveGRPC(ctx context.Context, cfg *config.Config, handler *handlers.Handler) {
+	options := handler.GRPCOptions()
+	if cfg.TLSCertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			log.Fatalf("gRPC server failed to start: %v", err)
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+	server := grpc.NewServer(options...)
+	handler.RegisterGRPC(server)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+	if err != nil {
+		log.Fatalf("gRPC server failed to start: %v", err)
+	}
+	log.Printf("gRPC API: %s", listener.Addr())
+	go func() {
+		<-ctx.Done()
+		server.Stop()
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			log.Printf("Warning: gRPC server stopped: %v", err)
+		}
+	}()
+}
+
+// rootHandler serves routes under basePath. Requests a reverse proxy
+// already stripped the base path from are served as well, so it works
+// whether or not the proxy strips it; links always include it.
+func rootHandler(basePath string, routes http.Handler) http.Handler {
+	if basePath == "" {
+		return routes
+	}
+	mux := http.NewServeMux()
+	mux.Handle(basePath+"/", http.StripPrefix(basePath, routes))
+	mux.Handle("/", routes)
+	return mux
+}
+
+// credentialsProblem says why the service can't start with the configured
+// credentials: some are missing, or Jellyfin or OpenSubtitles rejects them.
+// Jellyfin or OpenSubtitles being unreachable is only warned about, since
+// that usually passes.
+func credentialsProblem(ctx context.Context, cfg *config.Config) string {
+	creds := cfg.Credentials()
+	if err := creds.Validate(); err != nil {
+		return fmt.Sprintf("The configuration is incomplete: %v.", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, credentialsCheckTimeout)
+	defer cancel()
+	if err := newJellyfinClient(creds).Ping(ctx); err != nil {
+		if errors.Is(err, jellyfin.ErrRejected) {
+			return fmt.Sprintf("Jellyfin rejected the configuration: %v.", err)
+		}
+		log.Printf("Warning: Jellyfin is unreachable: %v", err)
+	}
+	if err := opensubtitles.NewClient(creds.OpenSubtitlesKey).Ping(ctx); err != nil {
+		if errors.Is(err, opensubtitles.ErrAPIKeyRejected) {
+			return fmt.Sprintf("OpenSubtitles rejected the configuration: %v.", err)
+		}
+		log.Printf("Warning: OpenSubtitles is unreachable: %v", err)
+	}
+	return ""
+}
+
+// credentialsCheckTimeout bounds the startup check of the credentials.
+const credentialsCheckTimeout = 15 * time.Second
+
+// newJellyfinClient returns a Jellyfin client using the API key, or signing
+// in when a username is configured.
+func newJellyfinClient(creds config.Credentials) *jellyfin.Client {
+	client := jellyfin.NewClient(creds.JellyfinURL, creds.JellyfinAPIKey, creds.JellyfinUserID)
+	client.Username = creds.JellyfinUsername
+	client.Password = creds.JellyfinPassword
+	return client
+}
+
+// runSetup serves the setup wizard on the configured address until working
+// credentials are saved, then returns the configuration loaded again with
+// them. It exits on shutdown.
+func runSetup(ctx context.Context, cfg *config.Config, problem string) *config.Config {
+	log.Printf("%s Open the web interface to set it up.", problem)
+
+	setup := handlers.NewSetup(cfg, problem)
+	mux := http.NewServeMux()
+	mux.Handle("/static/", web.StaticHandler())
+	mux.Handle("/", setup)
+
+	addr := fmt.Sprintf(":%d", cfg.Port)
+	scheme := "http"
+	if cfg.TLSCertFile != "" {
+		scheme = "https"
+	}
+	log.Printf("Setup: %s://localhost%s%s/setup", scheme, addr, cfg.BasePath)
+
+	server := &http.Server{
+		Addr:        addr,
+		Handler:     handlers.LogRequests(rootHandler(cfg.BasePath, mux)),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	served := make(chan error, 1)
+	go func() {
+		if cfg.TLSCertFile != "" {
+			served <- server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+			return
+		}
+		served <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-served:
+		log.Fatalf("Server failed to start: %v", err)
+	case <-ctx.Done():
+		log.Printf("Shutting down")
+		os.Exit(0)
+	case <-setup.Done():
+	}
+
+	// The page confirming the save is still being written
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	<-served
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	log.Printf("Setup complete, starting")
+	return cfg
+}
+
+// shutdownTimeout is how long cancelled requests get to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
+func openSubtitlesInstances(cfg *config.Config) []*opensubtitles.Instance {
+	var instances []*opensubtitles.Instance
+	for _, instanceCfg := range cfg.OpenSubtitlesInstances {
+		client := opensubtitles.NewClient(instanceCfg.APIKey)
+		client.Username = instanceCfg.Username
+		client.Password = instanceCfg.Password
+		client.HearingImpaired = cfg.HearingImpaired
+		client.AITranslated = cfg.AITranslated
+		client.MachineTranslated = cfg.MachineTranslated
+		client.Weights = scoringWeights(cfg.ScoringWeightsFor(instanceCfg.Name, ""))
+		client.LanguageWeights = make(map[string]opensubtitles.Weights)
+		for language := range cfg.LanguageScoringWeights {
+			code := opensubtitles.LanguageCode(lang.Parse(language))
+			client.LanguageWeights[code] = scoringWeights(cfg.ScoringWeightsFor(instanceCfg.Name, language))
+		}
+		client.SetSearchCacheTTL(cfg.SearchCacheTTL)
+		instances = append(instances, &opensubtitles.Instance{
+			Name:     instanceCfg.Name,
+			Priority: instanceCfg.Priority,
+			Client:   client,
+		})
+	}
+	return instances
+}
+
+// scoringWeights applies the configured weight overrides to the default
+// weights.
+func scoringWeights(overrides config.ScoringWeights) opensubtitles.Weights {
+	weights := opensubtitles.DefaultWeights
+	for _, override := range []struct {
+		value  *float64
+		weight *float64
+	}{
+		{overrides.HashMatch, &weights.HashMatch},
+		{overrides.ReleaseMatch, &weights.ReleaseMatch},
+		{overrides.DownloadCount, &weights.DownloadCount},
+		{overrides.Rating, &weights.Rating},
+		{overrides.HearingImpaired, &weights.HearingImpaired},
+		{overrides.ProviderTrust, &weights.ProviderTrust},
+		{overrides.HumanMade, &weights.HumanMade},
+	} {
+		if override.value != nil {
+			*override.weight = *override.value
+		}
+	}
+	return weights
+}
+
+func autoHuntWindow(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.AutoHuntWindowDays) * 24 * time.Hour
+}
+
+// configureHTTP applies the connection, rate limit, retry and circuit
+// breaker settings to the transport shared by the API clients. Jellyfin and
+// the whisper server are usually on the local network, so they are never
+// reached through the proxy.
+func configureHTTP(cfg *config.Config) {
+	bypass := cfg.ProxyBypass
+	for _, local := range []string{cfg.JellyfinURL, cfg.WhisperURL} {
+		if parsed, err := url.Parse(local); err == nil && parsed.Hostname() != "" {
+			bypass = append(bypass, parsed.Hostname())
+		}
+	}
+	err := httpclient.Default.Configure(httpclient.Options{
+		Timeout:  cfg.HTTPTimeout,
+		ProxyURL: cfg.ProxyURL,
+		Bypass:   bypass,
+		CAFile:   cfg.HTTPCAFile,
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure HTTP clients: %v", err)
+	}
+	httpclient.Default.MaxRetries = cfg.HTTPMaxRetries
+	httpclient.Default.FailureThreshold = cfg.CircuitBreakerThreshold
+	httpclient.Default.Cooldown = cfg.CircuitBreakerCooldown
+	for host, perSecond := range cfg.HTTPRateLimits {
+		httpclient.Default.SetRateLimit(host, perSecond)
+	}
 }
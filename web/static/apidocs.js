@@ -0,0 +1,10 @@
+// Shows the OpenAPI document of the JSON API in Swagger UI, whose
+// "Try it out" calls go out with the admin's login cookie.
+window.addEventListener('DOMContentLoaded', function () {
+    const ui = document.getElementById('swagger-ui');
+    window.SwaggerUIBundle({
+        url: ui.dataset.spec,
+        domNode: ui,
+        deepLinking: true,
+    });
+});
@@ -0,0 +1,454 @@
+const announcer = document.getElementById('announcer');
+
+// Search functionality
+function filterContent(searchTerm) {
+    const term = searchTerm.toLowerCase();
+    const series = document.querySelectorAll('.series');
+    const movies = document.querySelectorAll('.movie-card');
+    let hasResults = false;
+
+    // Filter series and episodes
+    series.forEach(seriesEl => {
+        const seriesName = seriesEl.dataset.series.toLowerCase();
+        const episodes = seriesEl.querySelectorAll('.episode');
+        let hasMatchingEpisodes = false;
+
+        episodes.forEach(episode => {
+            const episodeName = episode.dataset.episode.toLowerCase();
+            if (episodeName.includes(term) || seriesName.includes(term)) {
+                episode.style.display = 'flex';
+                hasMatchingEpisodes = true;
+                hasResults = true;
+            } else {
+                episode.style.display = 'none';
+            }
+        });
+
+        if (hasMatchingEpisodes || seriesName.includes(term)) {
+            seriesEl.style.display = 'block';
+            // Auto-expand if there's a match, unless that would load all of
+            // a series' episodes on each key press
+            if (term && !seriesEl.dataset.src) {
+                seriesEl.open = true;
+            }
+        } else {
+            seriesEl.style.display = 'none';
+        }
+    });
+
+    // Filter movies
+    movies.forEach(movie => {
+        const movieName = movie.dataset.movie.toLowerCase();
+        if (movieName.includes(term)) {
+            movie.style.display = 'flex';
+            hasResults = true;
+        } else {
+            movie.style.display = 'none';
+        }
+    });
+
+    // Filter posters
+    document.querySelectorAll('.poster-card').forEach(card => {
+        if (card.dataset.title.toLowerCase().includes(term)) {
+            card.style.display = 'flex';
+            hasResults = true;
+        } else {
+            card.style.display = 'none';
+        }
+    });
+
+    // Show/hide no results message
+    document.getElementById('no-results').classList.toggle('hidden', hasResults || !term);
+}
+
+// Subtitle processing. Without JavaScript the form posts normally
+// and the server answers with a result page.
+async function findSubtitle(event, form) {
+    event.preventDefault();
+    const button = form.querySelector('button');
+    const originalText = button.textContent;
+    button.disabled = true;
+    button.classList.remove('done', 'failed');
+    button.textContent = announcer.dataset.busy;
+    announcer.textContent = announcer.dataset.processing.replace('%s', button.getAttribute('aria-label'));
+
+    try {
+        const response = await fetch(form.action, {
+            method: 'POST'
+        });
+
+        const result = await response.text();
+
+        if (response.ok) {
+            button.textContent = announcer.dataset.success;
+            button.classList.add('done');
+            announcer.textContent = result;
+            // With live updates the item drops off the list by itself
+            if (!liveConnected()) {
+                setTimeout(() => {
+                    location.reload();
+                }, 2000);
+            }
+        } else {
+            button.textContent = announcer.dataset.error.replace('%s', result);
+            button.classList.add('failed');
+            button.disabled = false;
+            announcer.textContent = button.textContent;
+            button.focus();
+        }
+    } catch (error) {
+        button.textContent = announcer.dataset.networkError;
+        button.classList.add('failed');
+        button.disabled = false;
+        announcer.textContent = announcer.dataset.networkError;
+        button.focus();
+    }
+    return false;
+}
+
+// Collapsible series. Which ones are open survives the reload after a hunt,
+// so on a phone you don't have to find your place again.
+const openSeriesKey = 'open-series';
+
+function saveOpenSeries() {
+    const open = [...document.querySelectorAll('.series[open]')].map(el => el.dataset.series);
+    try {
+        sessionStorage.setItem(openSeriesKey, JSON.stringify(open));
+    } catch (error) {
+        // storage disabled
+    }
+}
+
+function restoreOpenSeries() {
+    let open = [];
+    try {
+        open = JSON.parse(sessionStorage.getItem(openSeriesKey)) || [];
+    } catch (error) {
+        return;
+    }
+    document.querySelectorAll('.series').forEach(el => {
+        if (open.includes(el.dataset.series)) {
+            el.open = true;
+        }
+    });
+}
+
+// Lazy loading. At first the list only has the series and a page of
+// movies; a series' episodes are fetched from the items API when it is
+// opened, and more movies when the end of the list comes into view.
+// Without JavaScript, the links in their place load a page listing them.
+const seasonTemplate = document.getElementById('season-template');
+const episodeTemplate = document.getElementById('episode-template');
+const movieTemplate = document.getElementById('movie-template');
+
+// fill puts values in place of the %s and %d of a translated text, in order.
+function fill(text, ...values) {
+    let next = 0;
+    return text.replace(/%[sd]/g, () => values[next++]);
+}
+
+async function fetchItems(url) {
+    const response = await fetch(url);
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.json();
+}
+
+function itemLink(template, item, action) {
+    return `${template.dataset.base}/${action}/${encodeURIComponent(item.id)}`;
+}
+
+// fillItem fills in a list entry cloned from a template. label are the
+// values of its aria-label texts.
+function fillItem(el, template, item, label, title, details) {
+    el.dataset.id = item.id;
+    const select = el.querySelector('.select');
+    select.value = item.id;
+    select.setAttribute('aria-label', fill(template.dataset.select, ...label));
+    const name = el.querySelector('.episode-name a');
+    name.textContent = title;
+    name.href = itemLink(template, item, 'items');
+    el.querySelector('.episode-details').textContent = item.languages ? `${details} · ${item.languages.join(', ')}` : details;
+    const search = el.querySelector('.actions a');
+    search.href = itemLink(template, item, 'items') + '/search';
+    search.setAttribute('aria-label', fill(template.dataset.search, ...label));
+    el.querySelector('form').action = itemLink(template, item, 'process');
+    el.querySelector('form button').setAttribute('aria-label', fill(template.dataset.find, ...label));
+    return el;
+}
+
+function addEpisode(seriesEl, item, before) {
+    if (seriesEl.querySelector(`[data-id="${CSS.escape(item.id)}"]`)) {
+        return;
+    }
+    let season = seriesEl.querySelector(`.season[data-season="${item.season}"]`);
+    if (!season) {
+        season = seasonTemplate.content.firstElementChild.cloneNode(true);
+        season.dataset.season = item.season;
+        const title = fill(seasonTemplate.dataset.title, item.season);
+        season.querySelector('h3').textContent = item.season_name ? `${title} - ${item.season_name}` : title;
+        season.querySelector('.select-all .sr-only').textContent = ' ' + fill(seasonTemplate.dataset.selectAll, seriesEl.dataset.series, item.season);
+        // A series with one season has no per-season counts in its header
+        const count = seriesEl.querySelector(`.season-count[data-season="${item.season}"]`) || seriesEl.querySelector('.series-count');
+        season.querySelector('.season-missing').textContent = fill(seasonTemplate.dataset.missing, count.dataset.missing, count.dataset.total);
+        before.before(season);
+    }
+
+    const label = [seriesEl.dataset.series, item.season, item.episode, item.name];
+    const el = episodeTemplate.content.firstElementChild.cloneNode(true);
+    el.dataset.episode = item.name;
+    season.querySelector('.episodes').append(fillItem(el, episodeTemplate, item, label,
+        `${item.episode}. ${item.name}`, fill(episodeTemplate.dataset.details, item.episode)));
+}
+
+// loadEpisodes lists a series' episodes, a page at a time, in place of
+// its placeholder.
+async function loadEpisodes(seriesEl) {
+    const src = seriesEl.dataset.src;
+    if (!src) {
+        return;
+    }
+    delete seriesEl.dataset.src;
+    const placeholder = seriesEl.querySelector('.episodes-placeholder');
+    const link = placeholder.querySelector('a');
+    placeholder.textContent = announcer.dataset.loading;
+
+    const url = new URL(src, location.href);
+    try {
+        for (let page = 1; ; page++) {
+            url.searchParams.set('page', page);
+            const result = await fetchItems(url);
+            result.items.forEach(item => addEpisode(seriesEl, item, placeholder));
+            if (page >= result.pages) {
+                break;
+            }
+        }
+        placeholder.remove();
+    } catch (error) {
+        // Opening the series again tries again
+        seriesEl.dataset.src = src;
+        placeholder.replaceChildren(fill(announcer.dataset.loadFailed, error.message), ' ', link);
+    }
+}
+
+function movieItem(item) {
+    const el = movieTemplate.content.firstElementChild.cloneNode(true);
+    if (el.classList.contains('poster-card')) {
+        el.dataset.title = item.name;
+        el.dataset.id = item.id;
+        el.querySelector('.poster-link').href = itemLink(movieTemplate, item, 'items');
+        el.querySelector('.poster-fallback').textContent = item.name;
+        el.querySelector('img').src = itemLink(movieTemplate, item, 'items') + '/poster';
+        el.querySelector('.poster-title').textContent = item.name;
+        el.querySelector('form').action = itemLink(movieTemplate, item, 'process');
+        el.querySelector('form button').setAttribute('aria-label', fill(movieTemplate.dataset.find, item.name));
+        return el;
+    }
+    el.dataset.movie = item.name;
+    return fillItem(el, movieTemplate, item, [item.name], item.name, movieTemplate.dataset.details);
+}
+
+// loadMoreMovies appends the next page of movies to the list and points
+// the link at the page after it.
+async function loadMoreMovies(link, observer) {
+    if (link.dataset.loading) {
+        return;
+    }
+    link.dataset.loading = 'true';
+    const list = link.closest('section').querySelector('ul');
+    const url = new URL(link.dataset.src, location.href);
+    try {
+        const result = await fetchItems(url);
+        result.items.forEach(item => {
+            if (!list.querySelector(`[data-id="${CSS.escape(item.id)}"]`)) {
+                list.append(movieItem(item));
+            }
+        });
+        const search = document.getElementById('search');
+        if (search.value) {
+            filterContent(search.value);
+        }
+        if (result.page >= result.pages) {
+            observer?.unobserve(link);
+            link.parentElement.remove();
+            return;
+        }
+        url.searchParams.set('page', result.page + 1);
+        link.dataset.src = url.pathname + url.search;
+        const pageURL = new URL(link.href);
+        pageURL.searchParams.set('page', result.page + 1);
+        link.href = pageURL;
+    } catch (error) {
+        announcer.textContent = fill(announcer.dataset.loadFailed, error.message);
+    }
+    delete link.dataset.loading;
+    // Observing again reports the link once more if it is still in view
+    if (observer) {
+        observer.unobserve(link);
+        observer.observe(link);
+    }
+}
+
+document.querySelectorAll('.load-more a').forEach(link => {
+    let observer = null;
+    if ('IntersectionObserver' in window) {
+        observer = new IntersectionObserver(entries => {
+            if (entries.some(entry => entry.isIntersecting)) {
+                loadMoreMovies(link, observer);
+            }
+        }, { rootMargin: '400px' });
+        observer.observe(link);
+    }
+    link.addEventListener('click', event => {
+        event.preventDefault();
+        loadMoreMovies(link, observer);
+    });
+});
+
+document.querySelectorAll('.series').forEach(el => {
+    el.addEventListener('toggle', () => {
+        saveOpenSeries();
+        if (el.open) {
+            loadEpisodes(el);
+        }
+    });
+});
+restoreOpenSeries();
+
+const expandControls = document.querySelector('.expand-controls');
+if (expandControls) {
+    expandControls.hidden = false;
+    expandControls.querySelectorAll('button').forEach(button => {
+        button.addEventListener('click', () => {
+            const expand = button.dataset.expand === 'true';
+            document.querySelectorAll('.series').forEach(el => {
+                if (el.style.display !== 'none') {
+                    el.open = expand;
+                }
+            });
+            announcer.textContent = expand ? announcer.dataset.expanded : announcer.dataset.collapsed;
+        });
+    });
+}
+
+// Batch actions. The checkboxes belong to the batch form, so they work
+// without JavaScript too; this adds the selection count and a "Select all"
+// per season that leaves out episodes hidden by the search. Seasons loaded
+// later have theirs too, so the boxes are handled where the changes bubble
+// up to.
+const batchForm = document.getElementById('batch');
+if (batchForm) {
+    const count = batchForm.querySelector('.batch-count');
+    const updateCount = () => {
+        const selected = document.querySelectorAll('input[name="item_id"]:checked').length;
+        count.textContent = count.dataset.count.replace('%d', selected);
+    };
+
+    document.querySelectorAll('[data-select-all]').forEach(box => {
+        box.closest('.select-all').hidden = false;
+    });
+    document.addEventListener('change', event => {
+        const box = event.target;
+        if (box.matches('[data-select-all]')) {
+            box.closest('section').querySelectorAll('input[name="item_id"]').forEach(item => {
+                if (item.closest('li').style.display !== 'none') {
+                    item.checked = box.checked;
+                }
+            });
+            updateCount();
+        } else if (box.name === 'item_id') {
+            updateCount();
+        }
+    });
+    updateCount();
+}
+
+// Live updates. The server pushes what happens elsewhere (hunts from other
+// pages, the scheduler, the batch actions) over a WebSocket, so found items
+// drop off the list and scheduler and quota changes show up without a
+// reload. Without it the page simply stays as it was loaded.
+const liveStatus = document.getElementById('live-status');
+let live = null;
+let reconnectDelay = 5000;
+
+function liveConnected() {
+    return live !== null && live.readyState === WebSocket.OPEN;
+}
+
+function showLiveStatus(text, withReload) {
+    liveStatus.textContent = text;
+    if (withReload) {
+        const link = document.createElement('a');
+        link.href = location.href;
+        link.textContent = liveStatus.dataset.reload;
+        liveStatus.append(link);
+    }
+    liveStatus.hidden = false;
+    announcer.textContent = text;
+}
+
+function handleEvent(event) {
+    const data = event.data || {};
+    switch (event.type) {
+    case 'job-finished':
+        if (data.succeeded) {
+            document.querySelectorAll(`[data-id="${CSS.escape(data.item_id)}"]`).forEach(el => el.remove());
+            announcer.textContent = liveStatus.dataset.found.replace('%s', data.name);
+        }
+        break;
+    case 'run-started':
+        showLiveStatus(liveStatus.dataset.runStarted.replace('%d', data.items));
+        break;
+    case 'run-finished':
+        showLiveStatus(liveStatus.dataset.runFinished.replace('%d', data.processed).replace('%d', data.failed));
+        break;
+    case 'scheduler-paused':
+        showLiveStatus(liveStatus.dataset.schedulerPaused);
+        break;
+    case 'scheduler-resumed':
+        showLiveStatus(liveStatus.dataset.schedulerResumed);
+        break;
+    case 'quota-exhausted':
+        showLiveStatus(liveStatus.dataset.quotaExhausted);
+        break;
+    case 'budget-exhausted':
+        showLiveStatus(liveStatus.dataset.budgetExhausted);
+        break;
+    case 'library-rescanned':
+        showLiveStatus(liveStatus.dataset.libraryRescanned, true);
+        break;
+    case 'approval-requested': {
+        showLiveStatus(liveStatus.dataset.approvalRequested.replace('%s', data.name));
+        const link = document.createElement('a');
+        link.href = `${liveStatus.dataset.jobs}/${encodeURIComponent(data.job_id)}`;
+        link.textContent = liveStatus.dataset.review;
+        liveStatus.append(' ', link);
+        break;
+    }
+    }
+}
+
+function connectLive() {
+    const url = new URL(liveStatus.dataset.events, location.href);
+    url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:';
+    live = new WebSocket(url);
+    live.addEventListener('open', () => {
+        reconnectDelay = 5000;
+    });
+    live.addEventListener('message', message => {
+        try {
+            handleEvent(JSON.parse(message.data));
+        } catch (error) {
+            // ignore malformed events
+        }
+    });
+    live.addEventListener('close', () => {
+        setTimeout(connectLive, reconnectDelay);
+        reconnectDelay = Math.min(reconnectDelay * 2, 60000);
+    });
+}
+
+if (liveStatus && 'WebSocket' in window) {
+    connectLive();
+}
@@ -0,0 +1,55 @@
+// Applies the saved colour theme before the page is drawn, so it doesn't
+// flash light first, and adds a toggle for it. Until a theme is picked the
+// system setting is followed (by base.css, which also covers browsers
+// without JavaScript).
+(function () {
+    const root = document.documentElement;
+    const dark = window.matchMedia('(prefers-color-scheme: dark)');
+
+    function load() {
+        try {
+            return localStorage.getItem('theme');
+        } catch (error) {
+            return null; // storage disabled
+        }
+    }
+
+    function save(theme) {
+        try {
+            localStorage.setItem('theme', theme);
+        } catch (error) {
+            // The choice just won't survive a reload
+        }
+    }
+
+    const saved = load();
+    if (saved === 'dark' || saved === 'light') {
+        root.dataset.theme = saved;
+    }
+
+    function isDark() {
+        return root.dataset.theme ? root.dataset.theme === 'dark' : dark.matches;
+    }
+
+    document.addEventListener('DOMContentLoaded', () => {
+        const container = document.querySelector('.container');
+        if (!container) {
+            return;
+        }
+
+        const toggle = document.createElement('button');
+        toggle.type = 'button';
+        toggle.className = 'theme-toggle';
+        toggle.textContent = root.dataset.themeLabel || 'Dark theme';
+        const update = () => toggle.setAttribute('aria-pressed', String(isDark()));
+        update();
+        dark.addEventListener('change', update);
+
+        toggle.addEventListener('click', () => {
+            root.dataset.theme = isDark() ? 'light' : 'dark';
+            save(root.dataset.theme);
+            update();
+        });
+        container.prepend(toggle);
+    });
+})();
@@ -0,0 +1,35 @@
+// The forms work without JavaScript; with it they are submitted in
+// the background and the result is announced before reloading.
+const announcer = document.getElementById('announcer');
+
+document.querySelectorAll('.actions form, form.actions').forEach(form => {
+    form.addEventListener('submit', async event => {
+        event.preventDefault();
+        const button = form.querySelector('button');
+        const label = button.textContent;
+        button.disabled = true;
+        if (form.dataset.busy) {
+            button.textContent = form.dataset.busy;
+            announcer.textContent = form.dataset.busy;
+        }
+
+        let message;
+        try {
+            const response = await fetch(form.action, { method: 'POST', body: new FormData(form) });
+            if (response.ok) {
+                announcer.textContent = announcer.dataset.done;
+                location.reload();
+                return;
+            }
+            message = await response.text();
+        } catch (error) {
+            message = announcer.dataset.networkError;
+        }
+
+        button.disabled = false;
+        button.textContent = label;
+        announcer.textContent = message;
+        alert(message);
+        button.focus();
+    });
+});
@@ -0,0 +1,156 @@
+// Package web holds the web interface's page templates, static files and
+// translations. They are compiled into the binary; a theme directory with
+// the same layout (templates/, static/, locales/) can replace any of them
+// without recompiling.
+package web
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"subtitle-hunter/internal/i18n"
+	"subtitle-hunter/internal/lang"
+)
+
+//go:embed templates static locales
+var builtin embed.FS
+
+var (
+	mu       sync.RWMutex
+	themeDir string
+	language lang.Tag
+	basePath string
+)
+
+// SetThemeDirectory makes files in dir take precedence over the built-in
+// ones. An empty dir uses only the built-in files.
+func SetThemeDirectory(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+	themeDir = dir
+}
+
+// FS returns the web interface files: the theme directory's where it has
+// them, the built-in ones otherwise.
+func FS() fs.FS {
+	mu.RLock()
+	defer mu.RUnlock()
+	if themeDir == "" {
+		return builtin
+	}
+	return overlayFS{top: os.DirFS(themeDir), bottom: builtin}
+}
+
+// overlayFS opens files from top, falling back to bottom for files top
+// doesn't have.
+type overlayFS struct {
+	top, bottom fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	file, err := o.top.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.bottom.Open(name)
+	}
+	return file, err
+}
+
+// SetLanguage makes the interface use language regardless of the browser's
+// preference. An empty language follows the browser.
+func SetLanguage(tag string) {
+	mu.Lock()
+	defer mu.Unlock()
+	language = lang.Parse(tag)
+}
+
+// SetBasePath sets the path the interface is served under behind a reverse
+// proxy, such as "/subhunter". Links in pages and redirects start with it.
+func SetBasePath(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	basePath = path
+}
+
+// BasePath returns the path set by SetBasePath, empty when the interface is
+// served from the root.
+func BasePath() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return basePath
+}
+
+// URL returns the address of the page at path, a path from the root of the
+// interface such as "/wanted", under the base path.
+func URL(path string) string {
+	return BasePath() + path
+}
+
+// Languages lists the interface languages: English and one per catalog in
+// locales/.
+func Languages() []lang.Tag {
+	languages := []lang.Tag{lang.English}
+	names, _ := fs.Glob(FS(), "locales/*.yaml")
+	for _, name := range names {
+		languages = append(languages, lang.Parse(strings.TrimSuffix(path.Base(name), ".yaml")))
+	}
+	return languages
+}
+
+// Locale returns the interface language for a request with the given
+// Accept-Language header and its catalog. English has no catalog.
+func Locale(acceptLanguage string) (lang.Tag, i18n.Catalog) {
+	mu.RLock()
+	locale := language
+	mu.RUnlock()
+
+	available := Languages()
+	if locale.IsZero() {
+		locale = i18n.Negotiate(acceptLanguage, available)
+	}
+	for _, tag := range available {
+		if !locale.Matches(tag) {
+			continue
+		}
+		if tag == lang.English {
+			break
+		}
+
+		data, err := fs.ReadFile(FS(), "locales/"+tag.String()+".yaml")
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			break
+		}
+		catalog, err := i18n.ParseCatalog(data)
+		if err != nil {
+			log.Printf("Warning: locales/%s.yaml: %v", tag, err)
+			break
+		}
+		return tag, catalog
+	}
+	return lang.English, nil
+}
+
+// StaticHandler serves the static files under /static/.
+func StaticHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// No directory listings
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		static, err := fs.Sub(FS(), "static")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.StripPrefix("/static/", http.FileServer(http.FS(static))).ServeHTTP(w, r)
+	})
+}